@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	// ALERTS_FILE in the state directory holds the alert rules
+	ALERTS_FILE = "alerts.json"
+	// How far back liquidity_drop and price_change look
+	DEFAULT_ALERT_WINDOW   = time.Hour
+	DEFAULT_ALERT_COOLDOWN = 10 * time.Minute
+)
+
+// AlertRule is a condition on a pool and where to send it when it fires
+type AlertRule struct {
+	ID           int       `json:"id"`
+	Pool         string    `json:"pool"`
+	When         string    `json:"when"`
+	Webhook      string    `json:"webhook,omitempty"`
+	TelegramChat int64     `json:"telegram_chat,omitempty"`
+	Cooldown     string    `json:"cooldown"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// AlertNotification is the JSON body POSTed to an alert's webhook
+type AlertNotification struct {
+	Event         string    `json:"event"` // alert.fired
+	AlertID       int       `json:"alert_id"`
+	Pool          string    `json:"pool_address"`
+	When          string    `json:"when"`
+	Price         float64   `json:"price"` // SOL per token
+	LiquiditySOL  float64   `json:"liquidity_sol"`
+	LiquidityDrop float64   `json:"liquidity_drop"`
+	Slot          uint64    `json:"slot"`
+	SentAt        time.Time `json:"sent_at"`
+}
+
+// alertEnv adds the liquidity history of a pool to the watch variables
+type alertEnv struct {
+	watchEnv
+	window  time.Duration
+	history []liquiditySample
+}
+
+type liquiditySample struct {
+	Time  time.Time
+	SOL   float64
+	Price float64
+}
+
+// record adds the pool's current state and forgets samples outside the window
+func (e *alertEnv) record(now time.Time) {
+	price, solReserve, _ := poolPrice(e.pool)
+	e.indicators.Add(PriceSample{Time: now, Price: price})
+	e.history = append(e.history, liquiditySample{Time: now, SOL: solReserve, Price: price})
+	cutoff := now.Add(-e.window)
+	e.history = slices.DeleteFunc(e.history, func(s liquiditySample) bool { return s.Time.Before(cutoff) })
+}
+
+// Var adds liquidity_drop, the fraction the SOL reserve fell from its high
+// within the window, and price_change, the fractional change since its start
+func (e *alertEnv) Var(name string) (float64, bool) {
+	if len(e.history) == 0 {
+		return e.watchEnv.Var(name)
+	}
+	latest := e.history[len(e.history)-1]
+	switch name {
+	case "liquidity_drop":
+		var peak float64
+		for _, s := range e.history {
+			peak = max(peak, s.SOL)
+		}
+		if peak == 0 {
+			return 0, true
+		}
+		return (peak - latest.SOL) / peak, true
+	case "price_change":
+		first := e.history[0].Price
+		if first == 0 {
+			return 0, false
+		}
+		return (latest.Price - first) / first, true
+	}
+	return e.watchEnv.Var(name)
+}
+
+// loadAlertRules reads the alert rules, returning none when the file does not exist
+func loadAlertRules() ([]AlertRule, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, ALERTS_FILE)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var rules []AlertRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// runAlert dispatches the alert subcommands
+func runAlert(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "add":
+			runAlertAdd(args[1:])
+			return
+		case "list":
+			runAlertList()
+			return
+		case "remove":
+			runAlertRemove(args[1:])
+			return
+		case "monitor":
+			runAlertMonitor(args[1:])
+			return
+		}
+	}
+	fmt.Println("Usage: go run . alert add -pool POOL -when EXPR [-webhook URL] [-telegram-chat ID] | list | remove -id ID | monitor")
+	os.Exit(1)
+}
+
+// runAlertAdd validates and stores an alert rule
+func runAlertAdd(args []string) {
+	fs := newCommandFlagSet("alert add")
+	poolAddress := fs.String("pool", "", "Pool to watch")
+	when := fs.String("when", "", "Condition, e.g. \"price < 0.5 || liquidity_drop > 30%\"")
+	webhook := fs.String("webhook", "", "POST the alert to this URL")
+	chat := fs.Int64("telegram-chat", 0, "Send the alert to this Telegram chat (the monitor needs "+TELEGRAM_TOKEN_ENV_VAR+")")
+	cooldown := fs.Duration("cooldown", DEFAULT_ALERT_COOLDOWN, "Minimum time between two notifications of the alert")
+	fs.Parse(args)
+
+	if *poolAddress == "" || *when == "" {
+		fmt.Println("Usage: go run . alert add -pool POOL -when EXPR [-webhook URL] [-telegram-chat ID] [-cooldown 10m]")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	if _, err := solana.PublicKeyFromBase58(*poolAddress); err != nil {
+		log.Fatalf("Invalid pool address: %v", err)
+	}
+	if _, err := compileTrigger(*when); err != nil {
+		log.Fatalf("Invalid condition: %v", err)
+	}
+	if *webhook == "" && *chat == 0 {
+		fmt.Println("No -webhook or -telegram-chat: the monitor will only print this alert")
+	}
+
+	rule := AlertRule{Pool: *poolAddress, When: *when, Webhook: *webhook, TelegramChat: *chat, Cooldown: cooldown.String(), CreatedAt: time.Now().UTC()}
+	var rules []AlertRule
+	err := updateStateFile(ALERTS_FILE, &rules, func() {
+		for _, existing := range rules {
+			rule.ID = max(rule.ID, existing.ID)
+		}
+		rule.ID++
+		rules = append(rules, rule)
+	})
+	if err != nil {
+		log.Fatalf("Failed to save alert: %v", err)
+	}
+	fmt.Printf("Alert %d added; it is evaluated by a running `alert monitor` after a restart\n", rule.ID)
+}
+
+// runAlertList prints the alert rules
+func runAlertList() {
+	rules, err := loadAlertRules()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(rules) == 0 {
+		fmt.Println("No alerts")
+		return
+	}
+	for _, rule := range rules {
+		fmt.Printf("%d  %s  %s", rule.ID, rule.Pool, rule.When)
+		if rule.Webhook != "" {
+			fmt.Printf("  webhook %s", rule.Webhook)
+		}
+		if rule.TelegramChat != 0 {
+			fmt.Printf("  telegram %d", rule.TelegramChat)
+		}
+		fmt.Printf("  cooldown %s\n", rule.Cooldown)
+	}
+}
+
+// runAlertRemove deletes an alert rule
+func runAlertRemove(args []string) {
+	fs := newCommandFlagSet("alert remove")
+	id := fs.Int("id", 0, "Alert to remove")
+	fs.Parse(args)
+
+	var rules []AlertRule
+	var found bool
+	err := updateStateFile(ALERTS_FILE, &rules, func() {
+		rules = slices.DeleteFunc(rules, func(rule AlertRule) bool {
+			found = found || rule.ID == *id
+			return rule.ID == *id
+		})
+	})
+	if err != nil {
+		log.Fatalf("Failed to remove alert: %v", err)
+	}
+	if !found {
+		log.Fatalf("No alert %d", *id)
+	}
+	fmt.Printf("Alert %d removed\n", *id)
+}
+
+// alertSink delivers fired alerts to their webhook and Telegram chat
+type alertSink struct {
+	secret   string
+	telegram *telegramBot // only its token and HTTP client are used
+}
+
+// compiledAlert is a rule being evaluated by the monitor
+type compiledAlert struct {
+	AlertRule
+	trigger  *Trigger
+	cooldown time.Duration
+	active   bool // the condition held at the last update
+	lastSent time.Time
+}
+
+// runAlertMonitor evaluates every alert rule on each vault update of its pool
+func runAlertMonitor(args []string) {
+	fs := newCommandFlagSet("alert monitor")
+	window := fs.Duration("window", DEFAULT_ALERT_WINDOW, "How far back liquidity_drop and price_change look")
+	token := fs.String("telegram-token", os.Getenv(TELEGRAM_TOKEN_ENV_VAR), "Telegram bot token for -telegram-chat alerts (or "+TELEGRAM_TOKEN_ENV_VAR+")")
+	secret := fs.String("notify-secret", os.Getenv(NOTIFY_SECRET_ENV_VAR), "HMAC-SHA256 key used to sign webhook payloads (or "+NOTIFY_SECRET_ENV_VAR+")")
+	fs.Parse(args)
+
+	if *window <= 0 {
+		log.Fatal("-window must be positive")
+	}
+	rules, err := loadAlertRules()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(rules) == 0 {
+		log.Fatal("No alerts; add one with alert add")
+	}
+
+	byPool := make(map[string][]*compiledAlert)
+	var pools []string
+	for _, rule := range rules {
+		alert, err := compileAlert(rule)
+		if err != nil {
+			log.Fatalf("Alert %d: %v", rule.ID, err)
+		}
+		if alert.TelegramChat != 0 && *token == "" {
+			log.Fatalf("Alert %d sends to Telegram; set -telegram-token or %s", rule.ID, TELEGRAM_TOKEN_ENV_VAR)
+		}
+		if _, ok := byPool[rule.Pool]; !ok {
+			pools = append(pools, rule.Pool)
+		}
+		byPool[rule.Pool] = append(byPool[rule.Pool], alert)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sink := &alertSink{secret: *secret}
+	if *token != "" {
+		sink.telegram = &telegramBot{token: *token, httpClient: &http.Client{Timeout: NOTIFY_TIMEOUT}}
+	}
+
+	client := newChainClient()
+	mux := newUpdateStream(resolveWSURL())
+	for _, address := range pools {
+		pool, err := loadPool(ctx, client, address)
+		if err != nil {
+			log.Fatalf("Failed to load pool %s: %v", address, err)
+		}
+		env := &alertEnv{watchEnv: watchEnv{indicators: newIndicatorEngine(DEFAULT_INDICATOR_HISTORY), pool: pool}, window: *window}
+		go monitorPoolAlerts(ctx, mux, env, byPool[address], sink)
+		fmt.Printf("Watching %s (%d alerts)\n", address, len(byPool[address]))
+	}
+
+	go func() {
+		if err := mux.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Fatalf("Websocket stream failed: %v", err)
+		}
+	}()
+	<-ctx.Done()
+	fmt.Println("\nStopped monitoring alerts")
+}
+
+// compileAlert parses a stored rule for evaluation
+func compileAlert(rule AlertRule) (*compiledAlert, error) {
+	trigger, err := compileTrigger(rule.When)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition: %w", err)
+	}
+	cooldown, err := time.ParseDuration(rule.Cooldown)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cooldown: %w", err)
+	}
+	return &compiledAlert{AlertRule: rule, trigger: trigger, cooldown: cooldown}, nil
+}
+
+// monitorPoolAlerts evaluates a pool's alerts on every vault update. An alert
+// notifies when its condition starts to hold, at most once per cooldown.
+func monitorPoolAlerts(ctx context.Context, mux UpdateStream, env *alertEnv, alerts []*compiledAlert, sink *alertSink) {
+	baseUpdates := mux.SubscribeAccount(env.pool.BaseVault)
+	quoteUpdates := mux.SubscribeAccount(env.pool.QuoteVault)
+
+	evaluate := func(slot uint64) {
+		now := time.Now()
+		env.record(now)
+		for _, alert := range alerts {
+			fired, err := alert.trigger.Eval(env)
+			if err != nil {
+				continue
+			}
+			rising := fired && !alert.active
+			alert.active = fired
+			if !rising || now.Sub(alert.lastSent) < alert.cooldown {
+				continue
+			}
+			alert.lastSent = now
+			sink.send(ctx, alert, env, slot)
+		}
+	}
+	evaluate(0)
+
+	for {
+		var u StreamUpdate
+		select {
+		case <-ctx.Done():
+			return
+		case u = <-baseUpdates:
+		case u = <-quoteUpdates:
+		}
+		amount, err := decodeTokenAmount(u.Data)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			continue
+		}
+		if u.Key.Equals(env.pool.BaseVault) {
+			env.pool.BaseAmount = amount
+		} else {
+			env.pool.QuoteAmount = amount
+		}
+		evaluate(u.Slot)
+	}
+}
+
+// send prints a fired alert and delivers it, printing delivery failures as warnings
+func (s *alertSink) send(ctx context.Context, alert *compiledAlert, env *alertEnv, slot uint64) {
+	price, solReserve, _ := poolPrice(env.pool)
+	drop, _ := env.Var("liquidity_drop")
+	fmt.Printf("[%s] 🔔 Alert %d on %s: %s (price %.12f SOL, liquidity %.4f SOL, drop %.1f%%)\n",
+		time.Now().Format(time.TimeOnly), alert.ID, alert.Pool, alert.When, price, solReserve, drop*100)
+
+	if alert.Webhook != "" {
+		notification := AlertNotification{
+			Event:         "alert.fired",
+			AlertID:       alert.ID,
+			Pool:          alert.Pool,
+			When:          alert.When,
+			Price:         price,
+			LiquiditySOL:  solReserve,
+			LiquidityDrop: drop,
+			Slot:          slot,
+			SentAt:        time.Now().UTC(),
+		}
+		if err := newWebhookNotifier(alert.Webhook, s.secret).deliver(ctx, notification); err != nil {
+			fmt.Printf("Warning: Could not send alert %d to its webhook: %v\n", alert.ID, err)
+		}
+	}
+	if alert.TelegramChat != 0 && s.telegram != nil {
+		text := fmt.Sprintf("🔔 Alert %d: %s\nPool: %s\nPrice: %.12f SOL\nLiquidity: %.4f SOL (down %.1f%% in the window)",
+			alert.ID, alert.When, alert.Pool, price, solReserve, drop*100)
+		s.telegram.send(ctx, alert.TelegramChat, text)
+	}
+}
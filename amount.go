@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+// Lamports kept back when an amount is a percentage of the SOL balance, for
+// the transaction fee and the rent of the temporary WSOL account
+const AMOUNT_SOL_RESERVE = 10_000_000
+
+// Units accepted after an -amount value
+const (
+	AMOUNT_UNIT_SOL      = "sol"
+	AMOUNT_UNIT_LAMPORTS = "lamports"
+	AMOUNT_UNIT_PERCENT  = "%"
+)
+
+// Magnitude suffixes accepted on an -amount number, e.g. 1.5k
+var amountMagnitudes = map[byte]int{'k': 3, 'm': 6, 'b': 9}
+
+// AmountSpec is an -amount as typed: a number in UI units of the input
+// token, in SOL or lamports, or a percentage of the wallet's balance
+type AmountSpec struct {
+	Value *big.Rat
+	Unit  string // "", AMOUNT_UNIT_SOL, AMOUNT_UNIT_LAMPORTS or AMOUNT_UNIT_PERCENT
+}
+
+// parseAmountSpec parses amounts like 0.5, 0.5sol, 1.5k, 25% or 5000000lamports
+func parseAmountSpec(s string) (*AmountSpec, error) {
+	text := strings.ToLower(strings.TrimSpace(s))
+	spec := &AmountSpec{}
+	for _, unit := range []string{AMOUNT_UNIT_LAMPORTS, AMOUNT_UNIT_SOL, AMOUNT_UNIT_PERCENT} {
+		if strings.HasSuffix(text, unit) {
+			spec.Unit = unit
+			text = strings.TrimSpace(strings.TrimSuffix(text, unit))
+			break
+		}
+	}
+
+	exponent := 0
+	if n := len(text); n > 0 {
+		if e, ok := amountMagnitudes[text[n-1]]; ok {
+			exponent = e
+			text = text[:n-1]
+		}
+	}
+
+	// big.Rat also parses signs, fractions, exponents and base prefixes, which are not amounts
+	if text == "" || strings.Trim(text, "0123456789.") != "" {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	value, ok := new(big.Rat).SetString(text)
+	if !ok || value.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	spec.Value = value.Mul(value, new(big.Rat).SetInt(atoms.Pow10(exponent)))
+
+	switch spec.Unit {
+	case AMOUNT_UNIT_LAMPORTS:
+		if !spec.Value.IsInt() {
+			return nil, fmt.Errorf("invalid amount %q: lamports must be a whole number", s)
+		}
+	case AMOUNT_UNIT_PERCENT:
+		if spec.Value.Cmp(big.NewRat(100, 1)) > 0 {
+			return nil, fmt.Errorf("invalid amount %q: at most 100%% of the balance can be swapped", s)
+		}
+	}
+	return spec, nil
+}
+
+// String renders the spec as it was typed, normalized
+func (a *AmountSpec) String() string {
+	return strings.TrimRight(strings.TrimRight(a.Value.FloatString(SOL_DECIMALS), "0"), ".") + a.Unit
+}
+
+// Resolve returns the swap amount in UI units of the input token, which is
+// SOL for buys and the pool's token for sells. Percentages are taken of
+// holder's balance, keeping AMOUNT_SOL_RESERVE back when spending SOL.
+func (a *AmountSpec) Resolve(ctx context.Context, client ChainClient, side string, tokenAddress string, poolAddress string, holder solana.PublicKey) (float64, error) {
+	switch a.Unit {
+	case "":
+		f, _ := a.Value.Float64()
+		return f, nil
+
+	case AMOUNT_UNIT_SOL, AMOUNT_UNIT_LAMPORTS:
+		if side != "buy" {
+			return 0, fmt.Errorf("amounts in %s only apply to buys, which spend SOL", a.Unit)
+		}
+		raw := new(big.Rat).Set(a.Value)
+		if a.Unit == AMOUNT_UNIT_SOL {
+			raw.Mul(raw, new(big.Rat).SetInt(atoms.Pow10(SOL_DECIMALS)))
+		}
+		lamports, err := atoms.Floor(raw)
+		if err != nil {
+			return 0, err
+		}
+		return atoms.ToAmount(lamports, SOL_DECIMALS), nil
+	}
+
+	if holder.IsZero() {
+		return 0, fmt.Errorf("a percentage amount needs the wallet's balance; set %s", PRIVATE_KEY_ENV_VAR)
+	}
+	balance, decimals, err := inputBalance(ctx, client, side, tokenAddress, poolAddress, holder)
+	if err != nil {
+		return 0, err
+	}
+	r := new(big.Rat).Mul(new(big.Rat).SetInt(new(big.Int).SetUint64(balance)), a.Value)
+	r.Quo(r, big.NewRat(100, 1))
+	raw, err := atoms.Floor(r)
+	if err != nil {
+		return 0, err
+	}
+	if raw == 0 {
+		return 0, fmt.Errorf("%s of the wallet's balance is nothing to swap", a)
+	}
+	return atoms.ToAmount(raw, decimals), nil
+}
+
+// inputBalance returns holder's raw balance of the token a swap spends and its decimals
+func inputBalance(ctx context.Context, client ChainClient, side string, tokenAddress string, poolAddress string, holder solana.PublicKey) (uint64, int, error) {
+	if side == "buy" {
+		balance, err := client.GetBalance(ctx, holder, rpc.CommitmentConfirmed)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get SOL balance: %w", err)
+		}
+		if balance.Value <= AMOUNT_SOL_RESERVE {
+			return 0, SOL_DECIMALS, nil
+		}
+		return balance.Value - AMOUNT_SOL_RESERVE, SOL_DECIMALS, nil
+	}
+
+	mint, err := poolTokenMint(ctx, client, tokenAddress, poolAddress)
+	if err != nil {
+		return 0, 0, err
+	}
+	ata, _, err := solana.FindAssociatedTokenAddress(holder, mint)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find ATA: %w", err)
+	}
+	balance, err := client.GetTokenAccountBalance(ctx, ata, rpc.CommitmentConfirmed)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get %s balance: %w", mint, err)
+	}
+	if balance.Value == nil {
+		return 0, 0, fmt.Errorf("wallet holds no %s", mint)
+	}
+	raw, err := strconv.ParseUint(balance.Value.Amount, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid token balance %q: %w", balance.Value.Amount, err)
+	}
+	return raw, int(balance.Value.Decimals), nil
+}
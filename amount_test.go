@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"awesomeProject/internal/atoms"
+)
+
+func TestCalculateMinAmountOut(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected uint64
+		slippage float64
+		want     uint64
+	}{
+		{"9 decimals", 1_000_000_001, 1, 990_000_000},
+		{"9 decimals no slippage", 1_000_000_001, 0, 1_000_000_001},
+		{"18 decimals", 12_345_678_901_234_567_000, 0.5, 12_283_950_506_728_394_165},
+		{"18 decimals beyond float precision", 12_345_678_901_234_567_891, 0, 12_345_678_901_234_567_891},
+		{"18 decimals odd atoms", 12_345_678_901_234_567_891, 1, 12_222_222_112_222_222_212},
+		{"full slippage", 1_000_000_001, 100, 0},
+		{"negative slippage ignored", 1_000_000_001, -1, 1_000_000_001},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateMinAmountOut(tt.expected, tt.slippage); got != tt.want {
+				t.Errorf("calculateMinAmountOut(%d, %v) = %d, want %d", tt.expected, tt.slippage, got, tt.want)
+			}
+		})
+	}
+}
+
+// Shares of a balance are taken with integer math, so an 18-decimal balance
+// keeps its low atoms
+func TestMulDivShares(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b, c uint64
+		want    uint64
+	}{
+		{"honeypot sell share of 9 decimals", 1_000_000_001, HONEYPOT_SELL_SHARE_BPS, 10_000, 500_000_000},
+		{"honeypot sell share of 18 decimals", 12_345_678_901_234_567_891, HONEYPOT_SELL_SHARE_BPS, 10_000, 6_172_839_450_617_283_945},
+		{"copied share of 18 decimals", 12_345_678_901_234_567_891, 1, 3, 4_115_226_300_411_522_630},
+		{"whole balance", 12_345_678_901_234_567_891, 7, 7, 12_345_678_901_234_567_891},
+		{"no source", 1_000_000_000, 1, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := atoms.MulDiv(tt.a, tt.b, tt.c, false); got != tt.want {
+				t.Errorf("atoms.MulDiv(%d, %d, %d) = %d, want %d", tt.a, tt.b, tt.c, got, tt.want)
+			}
+		})
+	}
+}
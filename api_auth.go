@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API request authentication headers
+const (
+	API_KEY_HEADER        = "X-Api-Key"
+	API_TIMESTAMP_HEADER  = "X-Timestamp"
+	API_NONCE_HEADER      = "X-Nonce"
+	API_SIGNATURE_HEADER  = "X-Signature"
+	API_KEYS_ENV_VAR      = "API_CLIENT_KEYS"
+	DEFAULT_API_MAX_AGE   = 30 * time.Second
+	MAX_API_REQUEST_BYTES = 1 << 20
+)
+
+// ErrRequestTooLarge rejects bodies over MAX_API_REQUEST_BYTES. They are
+// refused rather than cut, so a signature over a prefix never authenticates
+// a longer body.
+var ErrRequestTooLarge = errors.New("request body too large")
+
+// RequestVerifier authenticates signed API swap requests and rejects
+// stale or replayed ones.
+//
+// Clients sign "timestamp\nnonce\nMETHOD\npath\nbody" with HMAC-SHA256 using
+// their client key and send the hex digest in X-Signature along with the
+// key ID, unix timestamp and a unique nonce.
+type RequestVerifier struct {
+	keys   map[string][]byte
+	maxAge time.Duration
+	now    func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time // nonce -> expiry
+}
+
+// newRequestVerifier creates a verifier for the given client keys (key ID -> secret)
+func newRequestVerifier(keys map[string][]byte, maxAge time.Duration) *RequestVerifier {
+	if maxAge <= 0 {
+		maxAge = DEFAULT_API_MAX_AGE
+	}
+	return &RequestVerifier{
+		keys:   keys,
+		maxAge: maxAge,
+		now:    time.Now,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// loadAPIClientKeys parses client keys from the API_CLIENT_KEYS environment
+// variable in the form "id1:secret1,id2:secret2"
+func loadAPIClientKeys() (map[string][]byte, error) {
+	raw := os.Getenv(API_KEYS_ENV_VAR)
+	if raw == "" {
+		return nil, fmt.Errorf("%s environment variable not set. Please set it with: export %s=client_id:secret",
+			API_KEYS_ENV_VAR, API_KEYS_ENV_VAR)
+	}
+
+	keys := make(map[string][]byte)
+	for _, entry := range strings.Split(raw, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("invalid client key entry %q, expected id:secret", entry)
+		}
+		keys[id] = []byte(secret)
+	}
+
+	return keys, nil
+}
+
+// signAPIRequest computes the hex HMAC signature for a request. uri is the
+// path with its query, so query parameters are signed too.
+func signAPIRequest(secret []byte, timestamp string, nonce string, method string, uri string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n", timestamp, nonce, strings.ToUpper(method), uri)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the request signature, timestamp and nonce. The request body
+// is read and restored so handlers can still consume it.
+func (v *RequestVerifier) Verify(r *http.Request) error {
+	keyID := r.Header.Get(API_KEY_HEADER)
+	secret, ok := v.keys[keyID]
+	if keyID == "" || !ok {
+		return fmt.Errorf("unknown client key")
+	}
+
+	timestamp := r.Header.Get(API_TIMESTAMP_HEADER)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+
+	now := v.now()
+	age := now.Sub(time.Unix(unix, 0))
+	if age > v.maxAge || age < -v.maxAge {
+		return fmt.Errorf("request timestamp outside allowed window of %s", v.maxAge)
+	}
+
+	nonce := r.Header.Get(API_NONCE_HEADER)
+	if nonce == "" {
+		return fmt.Errorf("missing nonce")
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, err = io.ReadAll(io.LimitReader(r.Body, MAX_API_REQUEST_BYTES+1))
+		if err != nil {
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+		if len(body) > MAX_API_REQUEST_BYTES {
+			return fmt.Errorf("%w: over %d bytes", ErrRequestTooLarge, MAX_API_REQUEST_BYTES)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	expected := signAPIRequest(secret, timestamp, nonce, r.Method, r.URL.RequestURI(), body)
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get(API_SIGNATURE_HEADER))) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	// Only record nonces of authentic requests so forged traffic can't fill the cache
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for seenNonce, expiry := range v.seen {
+		if now.After(expiry) {
+			delete(v.seen, seenNonce)
+		}
+	}
+
+	replayKey := keyID + ":" + nonce
+	if _, replayed := v.seen[replayKey]; replayed {
+		return fmt.Errorf("replayed request")
+	}
+	// Keep nonces until any request carrying them would be rejected as stale anyway
+	v.seen[replayKey] = now.Add(2 * v.maxAge)
+
+	return nil
+}
+
+// Middleware rejects unauthenticated, stale or replayed requests before they reach next
+func (v *RequestVerifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.Verify(r); errors.Is(err, ErrRequestTooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			http.Error(w, fmt.Sprintf("unauthorized: %v", err), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
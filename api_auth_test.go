@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifierBodyLimit(t *testing.T) {
+	secret := []byte("secret")
+	verifier := newRequestVerifier(map[string][]byte{"client": secret}, 0)
+	handler := verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name     string
+		size     int
+		wantCode int
+	}{
+		{"at the limit", MAX_API_REQUEST_BYTES, http.StatusNoContent},
+		{"one byte over", MAX_API_REQUEST_BYTES + 1, http.StatusRequestEntityTooLarge},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := bytes.Repeat([]byte("a"), tt.size)
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			nonce := strconv.Itoa(i)
+			// Signed over the first MAX_API_REQUEST_BYTES only, which a
+			// truncating reader would have accepted
+			signed := body[:min(len(body), MAX_API_REQUEST_BYTES)]
+
+			r := httptest.NewRequest(http.MethodPost, "/swap", bytes.NewReader(body))
+			r.Header.Set(API_KEY_HEADER, "client")
+			r.Header.Set(API_TIMESTAMP_HEADER, timestamp)
+			r.Header.Set(API_NONCE_HEADER, nonce)
+			r.Header.Set(API_SIGNATURE_HEADER, signAPIRequest(secret, timestamp, nonce, http.MethodPost, "/swap", signed))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.wantCode {
+				t.Errorf("got status %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+const (
+	// Accounts closed per transaction; ten burn-and-close pairs still fit a legacy transaction
+	DEFAULT_ATA_CLEAN_BATCH = 10
+
+	// Token account layout offsets past the amount
+	TOKEN_ACCOUNT_STATE_OFFSET           = 108
+	TOKEN_ACCOUNT_CLOSE_AUTHORITY_OFFSET = 129
+	TOKEN_ACCOUNT_STATE_FROZEN           = 2
+)
+
+// ownedTokenAccount is one of the wallet's token accounts
+type ownedTokenAccount struct {
+	Address  solana.PublicKey
+	Program  solana.PublicKey
+	Mint     solana.PublicKey
+	Symbol   string
+	Raw      uint64
+	Decimals int
+	Lamports uint64  // rent returned when closed
+	ValueSOL float64 // 0 when empty or unpriced
+	Priced   bool
+	Burn     bool   // the balance is burned before closing
+	Skip     string // why the account cannot be closed, if it cannot
+}
+
+// runAtas dispatches the token account subcommands
+func runAtas(args []string, opts BuildOptions) {
+	if len(args) > 0 && args[0] == "clean" {
+		runAtasClean(args[1:], opts)
+		return
+	}
+	fmt.Println("Usage: go run . atas clean [-dust SOL] [-burn-unpriced] [-batch N] [-execute]")
+	os.Exit(1)
+}
+
+// runAtasClean closes the wallet's empty token accounts, and optionally burns
+// and closes dust, to reclaim their rent. Without -execute it only lists them.
+func runAtasClean(args []string, opts BuildOptions) {
+	fs := newCommandFlagSet("atas clean")
+	ownerAddr := fs.String("address", "", "Wallet to list without -execute (or "+WALLET_ADDRESS_ENV_VAR+", defaults to the "+PRIVATE_KEY_ENV_VAR+" wallet)")
+	fs.StringVar(ownerAddr, "owner", "", "Same as -address")
+	dust := fs.Float64("dust", 0, "Also burn and close balances worth less than this many SOL at their pool mid price")
+	burnUnpriced := fs.Bool("burn-unpriced", false, "With -dust, also burn balances of tokens without a priced pool")
+	batch := fs.Int("batch", DEFAULT_ATA_CLEAN_BATCH, "Accounts closed per transaction")
+	execute := fs.Bool("execute", false, "Close the listed accounts (requires "+PRIVATE_KEY_ENV_VAR+")")
+	fs.Parse(args)
+
+	if *batch <= 0 {
+		log.Fatal("-batch must be positive")
+	}
+
+	var wallet solana.PrivateKey
+	var owner solana.PublicKey
+	if *execute {
+		var err error
+		if wallet, err = loadWallet(); err != nil {
+			log.Fatalf("Failed to load wallet: %v", err)
+		}
+		owner = wallet.PublicKey()
+		if *ownerAddr != "" && *ownerAddr != owner.String() {
+			log.Fatal("-execute closes accounts of the " + PRIVATE_KEY_ENV_VAR + " wallet; drop -address")
+		}
+	} else {
+		var err error
+		if owner, err = loadWalletAddress(*ownerAddr); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	client := newChainClient()
+
+	accounts, err := fetchOwnedTokenAccounts(ctx, client, owner)
+	if err != nil {
+		log.Fatalf("Failed to list token accounts: %v", err)
+	}
+	closable := selectClosableAccounts(ctx, client, accounts, *dust, *burnUnpriced)
+
+	printAtaCleanPlan(owner, closable)
+	if len(closable) == 0 || !*execute {
+		if len(closable) > 0 {
+			fmt.Println("\nDry run: pass -execute to close these accounts")
+		}
+		return
+	}
+	if !confirmPrompt(fmt.Sprintf("Close %d token accounts?", len(closable))) {
+		fmt.Println("Cancelled.")
+		return
+	}
+
+	var steps []creationStep
+	for start := 0; start < len(closable); start += *batch {
+		end := min(start+*batch, len(closable))
+		var instructions []solana.Instruction
+		for _, account := range closable[start:end] {
+			instructions = append(instructions, closeTokenAccountInstructions(account, owner)...)
+		}
+		steps = append(steps, creationStep{Label: fmt.Sprintf("Close accounts %d-%d", start+1, end), Instructions: instructions})
+	}
+	if err := executeCreationSteps(interruptContext(), client, wallet, steps, opts); err != nil {
+		log.Fatalf("Cleanup stopped: %v", err)
+	}
+	fmt.Printf("\n✅ Closed %d token accounts\n", len(closable))
+}
+
+// fetchOwnedTokenAccounts lists the owner's SPL Token and Token-2022 accounts
+// with mint decimals and symbols
+func fetchOwnedTokenAccounts(ctx context.Context, client ChainClient, owner solana.PublicKey) ([]*ownedTokenAccount, error) {
+	var accounts []*ownedTokenAccount
+	var mints []solana.PublicKey
+	seen := make(map[solana.PublicKey]bool)
+
+	for _, program := range []solana.PublicKey{solana.TokenProgramID, TOKEN_2022_PROGRAM} {
+		result, err := client.GetTokenAccountsByOwner(ctx, owner,
+			&rpc.GetTokenAccountsConfig{ProgramId: &program},
+			&rpc.GetTokenAccountsOpts{Encoding: solana.EncodingBase64, Commitment: rpc.CommitmentConfirmed},
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, keyed := range result.Value {
+			data := keyed.Account.Data.GetBinary()
+			if len(data) < TOKEN_ACCOUNT_SIZE {
+				continue
+			}
+			account := &ownedTokenAccount{
+				Address:  keyed.Pubkey,
+				Program:  program,
+				Mint:     solana.PublicKeyFromBytes(data[0:32]),
+				Raw:      binary.LittleEndian.Uint64(data[TOKEN_ACCOUNT_AMOUNT_OFFSET:]),
+				Lamports: keyed.Account.Lamports,
+			}
+
+			// Frozen accounts and accounts another key may close cannot be closed by the owner
+			if data[TOKEN_ACCOUNT_STATE_OFFSET] == TOKEN_ACCOUNT_STATE_FROZEN {
+				account.Skip = "frozen"
+			} else if binary.LittleEndian.Uint32(data[TOKEN_ACCOUNT_CLOSE_AUTHORITY_OFFSET:]) == 1 &&
+				!solana.PublicKeyFromBytes(data[TOKEN_ACCOUNT_CLOSE_AUTHORITY_OFFSET+4:TOKEN_ACCOUNT_CLOSE_AUTHORITY_OFFSET+36]).Equals(owner) {
+				account.Skip = "close authority is another wallet"
+			}
+			accounts = append(accounts, account)
+			if !seen[account.Mint] {
+				seen[account.Mint] = true
+				mints = append(mints, account.Mint)
+			}
+		}
+	}
+
+	// Decimals live at offset 44 of both mint layouts
+	decimals := make(map[solana.PublicKey]int)
+	for start := 0; start < len(mints); start += MAX_MULTIPLE_ACCOUNTS {
+		end := min(start+MAX_MULTIPLE_ACCOUNTS, len(mints))
+		result, err := client.GetMultipleAccounts(ctx, mints[start:end]...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch mints: %w", err)
+		}
+		for i, account := range result.Value {
+			if account == nil {
+				continue
+			}
+			if data := account.Data.GetBinary(); len(data) > 44 {
+				decimals[mints[start+i]] = int(data[44])
+			}
+		}
+	}
+
+	metadata, err := fetchTokenMetadata(ctx, client, mints)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	for _, account := range accounts {
+		account.Decimals = decimals[account.Mint]
+		if meta, ok := metadata[account.Mint]; ok {
+			account.Symbol = meta.Symbol
+		}
+		if account.Mint.Equals(WSOL_MINT) {
+			account.Symbol = "WSOL"
+		}
+	}
+	return accounts, nil
+}
+
+// selectClosableAccounts returns the empty accounts and, with dust above 0,
+// the accounts worth less than dust SOL, which are burned first. Wrapped SOL
+// is never burned, and unpriced tokens only with burnUnpriced.
+func selectClosableAccounts(ctx context.Context, client ChainClient, accounts []*ownedTokenAccount, dust float64, burnUnpriced bool) []*ownedTokenAccount {
+	prices := make(map[solana.PublicKey]float64)
+	var closable []*ownedTokenAccount
+	for _, account := range accounts {
+		if account.Skip != "" {
+			if account.Raw == 0 {
+				fmt.Printf("Skipping %s (%s): %s\n", account.Address, tokenLabel("TOKEN", account.Symbol), account.Skip)
+			}
+			continue
+		}
+		if account.Raw == 0 {
+			closable = append(closable, account)
+			continue
+		}
+		if dust <= 0 || account.Mint.Equals(WSOL_MINT) {
+			continue
+		}
+
+		price, ok := prices[account.Mint]
+		if !ok {
+			if pool, err := findPoolsOnChain(ctx, client, account.Mint.String()); err == nil {
+				price, _, _ = poolPrice(pool)
+			}
+			prices[account.Mint] = price
+		}
+		account.Priced = price > 0
+		account.ValueSOL = atoms.ToAmount(account.Raw, account.Decimals) * price
+		if (account.Priced && account.ValueSOL < dust) || (!account.Priced && burnUnpriced) {
+			account.Burn = true
+			closable = append(closable, account)
+		}
+	}
+
+	sort.SliceStable(closable, func(i, j int) bool {
+		return !closable[i].Burn && closable[j].Burn
+	})
+	return closable
+}
+
+// closeTokenAccountInstructions burns the account's balance when it is dust,
+// then closes it with its rent going to the owner. Token-2022 accounts use
+// the same instruction layouts under their own program.
+func closeTokenAccountInstructions(account *ownedTokenAccount, owner solana.PublicKey) []solana.Instruction {
+	var instructions []solana.Instruction
+	if account.Burn {
+		burn := token.NewBurnInstruction(account.Raw, account.Address, account.Mint, owner, []solana.PublicKey{}).Build()
+		instructions = append(instructions, burn)
+	}
+	closeIx := token.NewCloseAccountInstruction(account.Address, owner, owner, []solana.PublicKey{}).Build()
+	instructions = append(instructions, closeIx)
+
+	if account.Program.Equals(solana.TokenProgramID) {
+		return instructions
+	}
+	for i, ix := range instructions {
+		data, _ := ix.Data()
+		instructions[i] = solana.NewInstruction(account.Program, ix.Accounts(), data)
+	}
+	return instructions
+}
+
+// printAtaCleanPlan lists the accounts to close and the rent they return
+func printAtaCleanPlan(owner solana.PublicKey, accounts []*ownedTokenAccount) {
+	var rent uint64
+	var burned int
+	fmt.Printf("\n=== TOKEN ACCOUNT CLEANUP ===\n")
+	fmt.Printf("Owner: %s\n\n", owner)
+	if len(accounts) == 0 {
+		fmt.Println("No token accounts to close")
+		fmt.Printf("=============================\n")
+		return
+	}
+	fmt.Printf("%-44s %-10s %20s %14s %-14s %14s\n", "Account", "Symbol", "Balance", "Value (SOL)", "Action", "Rent (SOL)")
+	for _, account := range accounts {
+		action, value := "close", "-"
+		if account.Burn {
+			action = "burn + close"
+			burned++
+		}
+		if account.Priced {
+			value = fmt.Sprintf("%.9f", account.ValueSOL)
+		}
+		fmt.Printf("%-44s %-10s %20s %14s %-14s %14s\n", account.Address, truncate(tokenLabel("TOKEN", account.Symbol), 10),
+			atoms.Format(account.Raw, account.Decimals), value, action, atoms.Format(account.Lamports, SOL_DECIMALS))
+		rent += account.Lamports
+	}
+	fmt.Printf("\n%d accounts (%d burned), %s SOL of rent reclaimed\n", len(accounts), burned, atoms.Format(rent, SOL_DECIMALS))
+	fmt.Printf("=============================\n")
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+const (
+	AUDIT_TOP_HOLDERS   = 10
+	AUDIT_CONCENTRATION = 0.5 // one wallet holding more than half the LP unlocked can pull most of the liquidity
+)
+
+// INCINERATOR is the conventional burn address; tokens sent to it can never move
+var INCINERATOR = solana.MustPublicKeyFromBase58("1nc1nerator11111111111111111111111111111111")
+
+// KNOWN_LP_LOCKERS are programs that hold LP tokens in time locks; an LP
+// account owned by one of their PDAs counts as locked
+var KNOWN_LP_LOCKERS = map[solana.PublicKey]string{
+	solana.MustPublicKeyFromBase58("strmRqUCoQUgGUan5YhzUZa6KqdzwX5L6FpUxfmKg5m"): "Streamflow",
+}
+
+// LpHolder is one of the largest LP token accounts
+type LpHolder struct {
+	Account solana.PublicKey `json:"account"`
+	Owner   solana.PublicKey `json:"owner"`
+	Amount  float64          `json:"amount"`
+	Share   float64          `json:"share"`            // of LP issued by the pool
+	Status  string           `json:"status,omitempty"` // burned or locked
+}
+
+// PoolAudit summarizes how safely a pool's liquidity is held
+type PoolAudit struct {
+	Pool            solana.PublicKey  `json:"pool"`
+	TokenMint       solana.PublicKey  `json:"token_mint"`
+	LpMint          solana.PublicKey  `json:"lp_mint"`
+	LpIssued        float64           `json:"lp_issued"` // tracked by the pool, unaffected by burns
+	LpSupply        float64           `json:"lp_supply"`
+	BurnedShare     float64           `json:"burned_share"`
+	LockedShare     float64           `json:"locked_share"`
+	Holders         []LpHolder        `json:"holders"`
+	PoolAuthority   solana.PublicKey  `json:"pool_authority"`
+	PoolOwner       solana.PublicKey  `json:"pool_owner"`
+	MintAuthority   *solana.PublicKey `json:"mint_authority"`
+	FreezeAuthority *solana.PublicKey `json:"freeze_authority"`
+	RiskScore       int               `json:"risk_score"` // 0 (safe) to 100
+	Findings        []string          `json:"findings"`
+}
+
+// parseMintAuthorities reads the optional mint and freeze authorities of an
+// SPL or Token-2022 mint: each is a u32 option tag followed by a key
+func parseMintAuthorities(data []byte) (mint *solana.PublicKey, freeze *solana.PublicKey, err error) {
+	if len(data) < MINT_ACCOUNT_SIZE {
+		return nil, nil, fmt.Errorf("invalid mint data size")
+	}
+	option := func(offset int) *solana.PublicKey {
+		if binary.LittleEndian.Uint32(data[offset:]) == 0 {
+			return nil
+		}
+		key := solana.PublicKeyFromBytes(data[offset+4 : offset+36])
+		return &key
+	}
+	return option(0), option(46), nil
+}
+
+// classifyLpHolders marks holders whose LP is burned or sits in a known locker
+func classifyLpHolders(ctx context.Context, client ChainClient, holders []LpHolder) error {
+	if len(holders) == 0 {
+		return nil
+	}
+	accounts := make([]solana.PublicKey, len(holders))
+	for i, holder := range holders {
+		accounts[i] = holder.Account
+	}
+	tokenAccounts, err := client.GetMultipleAccounts(ctx, accounts...)
+	if err != nil {
+		return fmt.Errorf("failed to get LP token accounts: %w", err)
+	}
+	owners := make([]solana.PublicKey, len(holders))
+	for i, account := range tokenAccounts.Value {
+		if account != nil && len(account.Data.GetBinary()) >= 64 {
+			holders[i].Owner = solana.PublicKeyFromBytes(account.Data.GetBinary()[32:64])
+		}
+		owners[i] = holders[i].Owner
+	}
+
+	// A locker holds LP in accounts owned by its PDAs
+	ownerAccounts, err := client.GetMultipleAccounts(ctx, owners...)
+	if err != nil {
+		return fmt.Errorf("failed to get LP holder accounts: %w", err)
+	}
+	for i := range holders {
+		if holders[i].Owner.Equals(INCINERATOR) {
+			holders[i].Status = "burned"
+			continue
+		}
+		if account := ownerAccounts.Value[i]; account != nil {
+			if name, ok := KNOWN_LP_LOCKERS[account.Owner]; ok {
+				holders[i].Status = "locked (" + name + ")"
+			}
+		}
+	}
+	return nil
+}
+
+// auditPool inspects a pool's LP distribution and token authorities and
+// scores how easily its liquidity could be pulled
+func auditPool(ctx context.Context, client ChainClient, poolAddress string) (*PoolAudit, error) {
+	pool, err := loadPool(ctx, client, poolAddress)
+	if err != nil {
+		return nil, err
+	}
+	info, err := client.GetAccountInfo(ctx, pool.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool account: %w", err)
+	}
+	audit := &PoolAudit{
+		Pool:          pool.Address,
+		TokenMint:     pool.BaseMint,
+		LpMint:        pool.LpMint,
+		PoolAuthority: pool.Authority,
+		PoolOwner:     solana.PublicKeyFromBytes(info.Value.Data.GetBinary()[688:720]), // amm_owner
+	}
+	if pool.BaseMint.Equals(WSOL_MINT) {
+		audit.TokenMint = pool.QuoteMint
+	}
+
+	supply, err := client.GetTokenSupply(ctx, pool.LpMint, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get LP supply: %w", err)
+	}
+	lpDecimals := int(supply.Value.Decimals)
+	lpSupply, err := strconv.ParseUint(supply.Value.Amount, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LP supply: %w", err)
+	}
+	issued := max(pool.LpAmount, lpSupply)
+	audit.LpIssued = atoms.ToAmount(issued, lpDecimals)
+	audit.LpSupply = atoms.ToAmount(lpSupply, lpDecimals)
+	if issued == 0 {
+		return nil, fmt.Errorf("pool %s has no liquidity", pool.Address)
+	}
+	// LP burned with the token program leaves the pool's count unchanged
+	audit.BurnedShare = float64(issued-lpSupply) / float64(issued)
+
+	largest, err := client.GetTokenLargestAccounts(ctx, pool.LpMint, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get LP holders: %w", err)
+	}
+	for _, account := range largest.Value[:min(len(largest.Value), AUDIT_TOP_HOLDERS)] {
+		raw, err := strconv.ParseUint(account.Amount, 10, 64)
+		if err != nil || raw == 0 {
+			continue
+		}
+		audit.Holders = append(audit.Holders, LpHolder{
+			Account: account.Address,
+			Amount:  atoms.ToAmount(raw, lpDecimals),
+			Share:   float64(raw) / float64(issued),
+		})
+	}
+	if err := classifyLpHolders(ctx, client, audit.Holders); err != nil {
+		return nil, err
+	}
+
+	mintInfo, err := client.GetAccountInfo(ctx, audit.TokenMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token mint: %w", err)
+	}
+	if audit.MintAuthority, audit.FreezeAuthority, err = parseMintAuthorities(mintInfo.Value.Data.GetBinary()); err != nil {
+		return nil, err
+	}
+
+	scorePoolAudit(audit)
+	return audit, nil
+}
+
+// scorePoolAudit sets the risk score and findings. Unlocked LP weighs most,
+// since its holders can withdraw the liquidity at any time.
+func scorePoolAudit(audit *PoolAudit) {
+	var largestFree float64
+	for _, holder := range audit.Holders {
+		switch {
+		case holder.Status == "burned":
+			audit.BurnedShare += holder.Share
+		case holder.Status != "":
+			audit.LockedShare += holder.Share
+		default:
+			largestFree = max(largestFree, holder.Share)
+		}
+	}
+	free := max(1-audit.BurnedShare-audit.LockedShare, 0)
+
+	score := free * 50
+	audit.Findings = append(audit.Findings, fmt.Sprintf("%.1f%% of LP is burned, %.1f%% locked, %.1f%% free to withdraw",
+		audit.BurnedShare*100, audit.LockedShare*100, free*100))
+	if largestFree > AUDIT_CONCENTRATION {
+		score += 15
+		audit.Findings = append(audit.Findings, fmt.Sprintf("one wallet holds %.1f%% of LP unlocked", largestFree*100))
+	}
+	if audit.MintAuthority != nil {
+		score += 20
+		audit.Findings = append(audit.Findings, fmt.Sprintf("mint authority %s can create more tokens", audit.MintAuthority))
+	}
+	if audit.FreezeAuthority != nil {
+		score += 15
+		audit.Findings = append(audit.Findings, fmt.Sprintf("freeze authority %s can freeze holders' tokens", audit.FreezeAuthority))
+	}
+	audit.RiskScore = int(min(score, 100) + 0.5)
+}
+
+// riskLabel names a risk score band
+func riskLabel(score int) string {
+	switch {
+	case score < 25:
+		return "low"
+	case score < 60:
+		return "medium"
+	}
+	return "high"
+}
+
+// printPoolAudit prints an audit for the terminal
+func printPoolAudit(audit *PoolAudit) {
+	fmt.Printf("Pool: %s\n", audit.Pool)
+	fmt.Printf("Token: %s\n", audit.TokenMint)
+	fmt.Printf("LP mint: %s (%.6f issued, %.6f in supply)\n", audit.LpMint, audit.LpIssued, audit.LpSupply)
+	fmt.Printf("Pool authority: %s (Raydium program PDA)\n", audit.PoolAuthority)
+	fmt.Printf("Pool owner: %s\n", audit.PoolOwner)
+	fmt.Printf("\nLargest LP holders:\n")
+	for _, holder := range audit.Holders {
+		fmt.Printf("  %-44s %6.2f%%  %s\n", holder.Owner, holder.Share*100, holder.Status)
+	}
+	fmt.Printf("\nFindings:\n")
+	for _, finding := range audit.Findings {
+		fmt.Printf("  - %s\n", finding)
+	}
+	fmt.Printf("\nRug risk: %d/100 (%s)\n", audit.RiskScore, riskLabel(audit.RiskScore))
+}
+
+// runPoolAudit reports LP burns, locks and token authorities of a pool
+func runPoolAudit(args []string) {
+	fs := newCommandFlagSet("pool audit")
+	poolAddr := fs.String("pool", "", "Pool to audit")
+	asJSON := fs.Bool("json", false, "Print the audit as JSON")
+	fs.Parse(args)
+	if *poolAddr == "" {
+		log.Fatal("Usage: go run . pool audit -pool POOL [-json]")
+	}
+
+	audit, err := auditPool(context.Background(), newChainClient(), *poolAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *asJSON {
+		data, _ := json.MarshalIndent(audit, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	printPoolAudit(audit)
+}
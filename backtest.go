@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"awesomeProject/internal/atoms"
+)
+
+const DEFAULT_BACKTEST_LP_FEE_BPS = 25 // Raydium V4 trade fee
+
+// BacktestConfig models the costs of simulated fills
+type BacktestConfig struct {
+	LPFeeBps     float64
+	LiquiditySOL float64 // SOL reserve for constant-product price impact, 0 fills at the candle price
+	NetworkFee   float64 // SOL per fill
+}
+
+// BacktestFill is one simulated trade
+type BacktestFill struct {
+	Time        time.Time `json:"time"`
+	MarketPrice float64   `json:"market_price"` // SOL per token
+	FillPrice   float64   `json:"fill_price"`   // SOL per token, after fees and impact
+	AmountIn    float64   `json:"amount_in"`
+	AmountOut   float64   `json:"amount_out"`
+	LPFee       float64   `json:"lp_fee"` // in the input token
+	NetworkFee  float64   `json:"network_fee"`
+}
+
+// BacktestResult is the hypothetical outcome of a strategy over a price history
+type BacktestResult struct {
+	Strategy    string         `json:"strategy"`
+	Side        string         `json:"side"`
+	Source      string         `json:"source"`
+	Start       time.Time      `json:"start"`
+	End         time.Time      `json:"end"`
+	Candles     int            `json:"candles"`
+	Completed   bool           `json:"completed"`
+	Fills       []BacktestFill `json:"fills"`
+	TotalIn     float64        `json:"total_in"`
+	TotalOut    float64        `json:"total_out"`
+	InputToken  string         `json:"input_token"`
+	OutputToken string         `json:"output_token"`
+	VWAP        float64        `json:"vwap"` // SOL per token
+	LPFees      float64        `json:"lp_fees"`
+	NetworkFees float64        `json:"network_fees"`
+	FinalPrice  float64        `json:"final_price"`
+	PnLSOL      float64        `json:"pnl_sol"`
+	PnLPct      float64        `json:"pnl_pct"`
+}
+
+// runBacktest replays a strategy against recorded or exported candles
+func runBacktest(args []string) {
+	fs := newCommandFlagSet("backtest")
+	var strategyConfig strategyFlags
+	strategyConfig.register(fs)
+	poolAddress := fs.String("pool", "", "Pool whose candles recorded by the daemon are replayed")
+	interval := fs.String("interval", "1m", "Recorded candle interval: "+strings.Join(candleIntervalNames, ", "))
+	hours := fs.Float64("hours", 24*7, "How far back recorded candles go")
+	file := fs.String("file", "", "CSV or JSON candles exported by price history or candles, instead of -pool")
+	lpFeeBps := fs.Float64("lp-fee-bps", DEFAULT_BACKTEST_LP_FEE_BPS, "Pool trade fee in basis points")
+	liquidity := fs.Float64("liquidity", 0, "SOL reserve of the pool for price impact, 0 to fill at the candle price")
+	priorityFee := fs.Float64("priority-fee", 0, "Priority fee in SOL added to the network fee of each fill")
+	jsonOutput := fs.Bool("json", false, "Output the result as JSON")
+	fs.Parse(args)
+
+	if (*poolAddress == "") == (*file == "") || strategyConfig.name == "" {
+		fmt.Println("Usage: go run . backtest (-pool POOL | -file CANDLES) -strategy dca|twap|limit|stop -side buy|sell -amount AMOUNT [strategy flags]")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	strategy, err := strategyConfig.build()
+	if err != nil {
+		log.Fatalf("Invalid strategy: %v", err)
+	}
+	if *lpFeeBps < 0 || *lpFeeBps >= 10_000 || *liquidity < 0 || *priorityFee < 0 {
+		log.Fatal("-lp-fee-bps must be below 10000 and -liquidity and -priority-fee must not be negative")
+	}
+
+	var candles []Candle
+	var source string
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatalf("Failed to open candles: %v", err)
+		}
+		candles, err = readCandles(f)
+		f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		source = *file
+	} else {
+		pool, err := solana.PublicKeyFromBase58(*poolAddress)
+		if err != nil {
+			log.Fatalf("Invalid pool address: %v", err)
+		}
+		if _, ok := candleIntervals[*interval]; !ok {
+			log.Fatalf("-interval must be one of %s", strings.Join(candleIntervalNames, ", "))
+		}
+		store, err := openCandleStore()
+		if err != nil {
+			log.Fatal(err)
+		}
+		since := time.Now().Add(-time.Duration(*hours * float64(time.Hour)))
+		if candles, err = store.Query(pool, *interval, since); err != nil {
+			log.Fatal(err)
+		}
+		source = fmt.Sprintf("%s (%s candles)", pool, *interval)
+	}
+	if len(candles) == 0 {
+		log.Fatal("No candles to replay; record some with daemon -candles or export them with price history")
+	}
+
+	config := BacktestConfig{
+		LPFeeBps:     *lpFeeBps,
+		LiquiditySOL: *liquidity,
+		NetworkFee:   atoms.ToAmount(LAMPORTS_PER_SIGNATURE, SOL_DECIMALS) + *priorityFee,
+	}
+	result := backtestStrategy(strategy, candles, config)
+	result.Source = source
+
+	if *jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode result: %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	printBacktestResult(result)
+}
+
+// backtestStrategy feeds each candle to the strategy as a tick at its open
+// price, so no fill sees the rest of its candle, and fills its orders at that
+// price less fees and impact. Holdings are marked at the last close.
+func backtestStrategy(strategy Strategy, candles []Candle, config BacktestConfig) *BacktestResult {
+	result := &BacktestResult{
+		Strategy:    strategy.Name(),
+		Side:        strategy.Side(),
+		Start:       candles[0].Time,
+		End:         candles[len(candles)-1].Time,
+		Candles:     len(candles),
+		InputToken:  getInputToken(strategy.Side()),
+		OutputToken: getOutputToken(strategy.Side()),
+		FinalPrice:  candles[len(candles)-1].Close,
+		Fills:       []BacktestFill{},
+	}
+
+	var inputValueSOL float64
+	for _, candle := range candles {
+		if strategy.Done() {
+			break
+		}
+		tick := MarketTick{Time: candle.Time, Price: candle.Open}
+		amount := strategy.Next(tick)
+		if amount <= 0 || tick.Price <= 0 {
+			continue
+		}
+		fill := simulateFill(strategy.Side(), amount, tick, config)
+		result.Fills = append(result.Fills, fill)
+		result.TotalIn += fill.AmountIn
+		result.TotalOut += fill.AmountOut
+		result.LPFees += fill.LPFee
+		result.NetworkFees += fill.NetworkFee
+		if strategy.Side() == "buy" {
+			inputValueSOL += fill.AmountIn
+		} else {
+			inputValueSOL += fill.AmountIn * fill.MarketPrice
+		}
+	}
+	result.Completed = strategy.Done()
+
+	// PnL values the SOL and tokens the fills moved at the final price
+	solChange, tokenChange := result.TotalOut, -result.TotalIn
+	if strategy.Side() == "buy" {
+		solChange, tokenChange = -result.TotalIn, result.TotalOut
+	}
+	result.PnLSOL = solChange + tokenChange*result.FinalPrice - result.NetworkFees
+	if inputValueSOL > 0 {
+		result.PnLPct = result.PnLSOL / inputValueSOL * 100
+	}
+	if result.TotalIn > 0 && result.TotalOut > 0 {
+		if strategy.Side() == "buy" {
+			result.VWAP = result.TotalIn / result.TotalOut
+		} else {
+			result.VWAP = result.TotalOut / result.TotalIn
+		}
+	}
+	return result
+}
+
+// simulateFill trades amount of the side's input token at the tick price. The
+// fee comes off the input, as on Raydium V4; with a liquidity the rest moves a
+// constant-product pool holding that much SOL at the tick price.
+func simulateFill(side string, amount float64, tick MarketTick, config BacktestConfig) BacktestFill {
+	fee := amount * config.LPFeeBps / 10_000
+	net := amount - fee
+
+	var out float64
+	solReserve := config.LiquiditySOL
+	tokenReserve := solReserve / tick.Price
+	switch {
+	case side == "buy" && solReserve > 0:
+		out = tokenReserve * net / (solReserve + net)
+	case side == "buy":
+		out = net / tick.Price
+	case solReserve > 0:
+		out = solReserve * net / (tokenReserve + net)
+	default:
+		out = net * tick.Price
+	}
+
+	fill := BacktestFill{
+		Time:        tick.Time,
+		MarketPrice: tick.Price,
+		AmountIn:    amount,
+		AmountOut:   out,
+		LPFee:       fee,
+		NetworkFee:  config.NetworkFee,
+	}
+	if side == "buy" && out > 0 {
+		fill.FillPrice = amount / out
+	} else if side == "sell" {
+		fill.FillPrice = out / amount
+	}
+	return fill
+}
+
+// printBacktestResult prints the fills and the hypothetical PnL
+func printBacktestResult(result *BacktestResult) {
+	fmt.Printf("\n=== BACKTEST ===\n")
+	fmt.Printf("Strategy: %s\n", result.Strategy)
+	fmt.Printf("Operation: %s\n", strings.ToUpper(result.Side))
+	fmt.Printf("Data: %s\n", result.Source)
+	fmt.Printf("Period: %s - %s (%d candles)\n", result.Start.Format(time.DateTime), result.End.Format(time.DateTime), result.Candles)
+
+	fmt.Printf("\n%-19s %16s %16s %18s %18s\n", "Time", "Market Price", "Fill Price", "In ("+result.InputToken+")", "Out ("+result.OutputToken+")")
+	for _, fill := range result.Fills {
+		fmt.Printf("%-19s %16.9f %16.9f %18.9f %18.9f\n", fill.Time.Format(time.DateTime), fill.MarketPrice, fill.FillPrice, fill.AmountIn, fill.AmountOut)
+	}
+	if !result.Completed {
+		fmt.Println("\nThe strategy did not finish within the data")
+	}
+
+	fmt.Printf("\nFills: %d\n", len(result.Fills))
+	fmt.Printf("  Total In: %.9f %s\n", result.TotalIn, result.InputToken)
+	fmt.Printf("  Total Out: %.9f %s\n", result.TotalOut, result.OutputToken)
+	fmt.Printf("  VWAP: %.9f SOL per token\n", result.VWAP)
+	fmt.Printf("  LP Fees: %.9f %s\n", result.LPFees, result.InputToken)
+	fmt.Printf("  Network Fees: %.9f SOL\n", result.NetworkFees)
+	fmt.Printf("\nFinal Price: %.9f SOL per token\n", result.FinalPrice)
+	fmt.Printf("PnL: %+.9f SOL (%+.2f%%)\n", result.PnLSOL, result.PnLPct)
+	fmt.Printf("================\n")
+}
@@ -0,0 +1,529 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Telegram bot settings
+const (
+	TELEGRAM_TOKEN_ENV_VAR = "TELEGRAM_BOT_TOKEN"
+	TELEGRAM_CHATS_ENV_VAR = "TELEGRAM_ALLOWED_CHATS"
+	TELEGRAM_API_URL       = "https://api.telegram.org/bot"
+	TELEGRAM_POLL_TIMEOUT  = 30 // seconds
+	BOT_CONFIRM_TIMEOUT    = 60 * time.Second
+)
+
+// Telegram Bot API types, limited to the fields the bot uses
+type telegramUpdate struct {
+	UpdateID      int64                  `json:"update_id"`
+	Message       *telegramMessage       `json:"message"`
+	CallbackQuery *telegramCallbackQuery `json:"callback_query"`
+}
+
+type telegramMessage struct {
+	MessageID int64         `json:"message_id"`
+	From      *telegramUser `json:"from"`
+	Chat      telegramChat  `json:"chat"`
+	Text      string        `json:"text"`
+}
+
+type telegramUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type telegramChat struct {
+	ID int64 `json:"id"`
+}
+
+type telegramCallbackQuery struct {
+	ID      string           `json:"id"`
+	From    telegramUser     `json:"from"`
+	Message *telegramMessage `json:"message"`
+	Data    string           `json:"data"`
+}
+
+type telegramButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// pendingSwap is a quoted swap waiting for the user to press confirm or cancel
+type pendingSwap struct {
+	key       string // idempotency key of the /swap message
+	userID    int64
+	quote     *SwapQuote
+	slippage  float64
+	expiresAt time.Time
+}
+
+// telegramBot serves quotes and swaps to authorized chats
+type telegramBot struct {
+	token        string
+	allowedChats map[int64]bool
+	client       ChainClient
+	wallet       solana.PrivateKey
+	guard        *SpendGuard // the wallet's limits, shared with every other command
+	opts         BuildOptions
+	maxTrade     float64 // SOL per swap, 0 = unlimited
+	dailyLimit   float64 // SOL per user per UTC day, 0 = unlimited
+	httpClient   *http.Client
+	quotes       *QuoteCache
+
+	mu      sync.Mutex
+	pending map[string]*pendingSwap
+	spent   map[int64]float64 // SOL spent per user on spentOn
+	spentOn string
+}
+
+// call invokes a Bot API method with a JSON body and decodes its result
+func (b *telegramBot) call(ctx context.Context, method string, params any, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, TELEGRAM_API_URL+b.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		// Never leak the bot token through the request URL in errors
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("telegram %s: %v", method, urlErr.Err)
+		}
+		return fmt.Errorf("telegram %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("telegram %s: invalid response: %w", method, err)
+	}
+	if !envelope.OK {
+		return fmt.Errorf("telegram %s: %s", method, envelope.Description)
+	}
+	if result != nil {
+		return json.Unmarshal(envelope.Result, result)
+	}
+	return nil
+}
+
+// send posts a message, optionally with one row of inline buttons
+func (b *telegramBot) send(ctx context.Context, chatID int64, text string, buttons ...telegramButton) {
+	params := map[string]any{"chat_id": chatID, "text": text}
+	if len(buttons) > 0 {
+		params["reply_markup"] = map[string]any{"inline_keyboard": [][]telegramButton{buttons}}
+	}
+	if err := b.call(ctx, "sendMessage", params, nil); err != nil {
+		log.Printf("Failed to send message to chat %d: %v", chatID, err)
+	}
+}
+
+// edit replaces the text of a message and removes its buttons
+func (b *telegramBot) edit(ctx context.Context, msg *telegramMessage, text string) {
+	params := map[string]any{"chat_id": msg.Chat.ID, "message_id": msg.MessageID, "text": text}
+	if err := b.call(ctx, "editMessageText", params, nil); err != nil {
+		log.Printf("Failed to edit message in chat %d: %v", msg.Chat.ID, err)
+	}
+}
+
+// run long-polls for updates until ctx is cancelled
+func (b *telegramBot) run(ctx context.Context) error {
+	var offset int64
+	for {
+		var updates []telegramUpdate
+		params := map[string]any{
+			"offset":          offset,
+			"timeout":         TELEGRAM_POLL_TIMEOUT,
+			"allowed_updates": []string{"message", "callback_query"},
+		}
+		if err := b.call(ctx, "getUpdates", params, &updates); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Printf("Warning: %v (retrying in 5s)", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			switch {
+			case update.Message != nil:
+				b.handleMessage(ctx, update.Message)
+			case update.CallbackQuery != nil:
+				b.handleCallback(ctx, update.CallbackQuery)
+			}
+		}
+	}
+}
+
+// handleMessage answers commands from authorized chats
+func (b *telegramBot) handleMessage(ctx context.Context, msg *telegramMessage) {
+	if !b.allowedChats[msg.Chat.ID] || msg.From == nil {
+		log.Printf("Ignoring message from unauthorized chat %d", msg.Chat.ID)
+		return
+	}
+
+	fields := strings.Fields(msg.Text)
+	if len(fields) == 0 {
+		return
+	}
+	command, _, _ := strings.Cut(fields[0], "@") // "/quote@my_bot" in groups
+
+	switch command {
+	case "/quote", "/swap":
+		quote, slippage, err := b.parseSwapCommand(ctx, fields)
+		if err != nil {
+			b.send(ctx, msg.Chat.ID, fmt.Sprintf("❌ %v", err))
+			return
+		}
+
+		text := fmt.Sprintf("%s %.9f %s\nPool: %s\nExpected Out: %.9f %s\nPrice: %.9f SOL per token",
+			strings.ToUpper(quote.Side), quote.AmountIn, tokenLabel(getInputToken(quote.Side), quote.TokenSymbol),
+			quote.PoolAddress, quote.ExpectedOut, tokenLabel(getOutputToken(quote.Side), quote.TokenSymbol), quote.Price())
+		if b.opts.PlatformFee.Enabled() {
+			text += fmt.Sprintf("\nPlatform Fee: ~%.9f (%d bps)", b.opts.PlatformFee.Estimate(quote.Side, quote.AmountIn, quote.ExpectedOut), b.opts.PlatformFee.Bps)
+		}
+		if command == "/quote" {
+			b.send(ctx, msg.Chat.ID, text)
+			return
+		}
+
+		if err := b.checkLimits(msg.From.ID, quote.SOLAmount()); err != nil {
+			b.send(ctx, msg.Chat.ID, fmt.Sprintf("%s\n\n❌ %v", text, err))
+			return
+		}
+
+		id := newPendingID()
+		b.mu.Lock()
+		for pendingID, p := range b.pending {
+			if time.Now().After(p.expiresAt) {
+				delete(b.pending, pendingID)
+			}
+		}
+		key := fmt.Sprintf("telegram:%d:%d", msg.Chat.ID, msg.MessageID)
+		b.pending[id] = &pendingSwap{key: key, userID: msg.From.ID, quote: quote, slippage: slippage, expiresAt: time.Now().Add(BOT_CONFIRM_TIMEOUT)}
+		b.mu.Unlock()
+
+		b.send(ctx, msg.Chat.ID,
+			fmt.Sprintf("%s\nSlippage Tolerance: %.2f%%\n\nConfirm within %s.", text, slippage, BOT_CONFIRM_TIMEOUT),
+			telegramButton{Text: "✅ Confirm", CallbackData: "confirm:" + id},
+			telegramButton{Text: "✖️ Cancel", CallbackData: "cancel:" + id},
+		)
+
+	case "/limits":
+		b.send(ctx, msg.Chat.ID, b.describeLimits(msg.From.ID))
+
+	default:
+		b.send(ctx, msg.Chat.ID, "Commands:\n"+
+			"/quote <pool|token> <amount> <buy|sell>\n"+
+			"/swap <pool|token> <amount> <buy|sell> [slippage%]\n"+
+			"/limits")
+	}
+}
+
+// handleCallback executes or cancels a pending swap from its inline buttons
+func (b *telegramBot) handleCallback(ctx context.Context, query *telegramCallbackQuery) {
+	action, id, _ := strings.Cut(query.Data, ":")
+
+	// Only the user who requested the swap may confirm or cancel it
+	b.mu.Lock()
+	pending := b.pending[id]
+	if pending != nil && pending.userID == query.From.ID {
+		delete(b.pending, id)
+	} else {
+		pending = nil
+	}
+	b.mu.Unlock()
+
+	answer := func(text string) {
+		if err := b.call(ctx, "answerCallbackQuery", map[string]any{"callback_query_id": query.ID, "text": text}, nil); err != nil {
+			log.Printf("Failed to answer callback: %v", err)
+		}
+	}
+
+	if pending == nil || query.Message == nil {
+		answer("This swap is not yours or no longer pending")
+		return
+	}
+
+	if action != "confirm" {
+		answer("Cancelled")
+		b.edit(ctx, query.Message, "Swap cancelled.")
+		return
+	}
+	if time.Now().After(pending.expiresAt) {
+		answer("Expired")
+		b.edit(ctx, query.Message, "Swap expired; request a new quote.")
+		return
+	}
+
+	// The confirm button already covers the wallet's confirmation threshold
+	solAmount := pending.quote.SOLAmount()
+	if err := b.guard.Reserve(ctx, pending.quote.GuardedTrade(b.opts.Burn)); err != nil {
+		answer("Limit exceeded")
+		b.edit(ctx, query.Message, fmt.Sprintf("❌ %v", err))
+		return
+	}
+	if err := b.reserveSpend(pending.userID, solAmount); err != nil {
+		b.guard.Release(pending.quote.GuardedTrade(b.opts.Burn))
+		answer("Limit exceeded")
+		b.edit(ctx, query.Message, fmt.Sprintf("❌ %v", err))
+		return
+	}
+
+	// Telegram redelivers updates a restarted bot had not acknowledged, so one
+	// /swap message can be quoted twice; its key lets it execute only once
+	used, err := claimIdempotencyKey(pending.key, pending.quote.PoolAddress, pending.quote.Side, pending.quote.AmountIn)
+	if err != nil || used != nil {
+		b.releaseSpend(pending.userID, solAmount)
+		b.guard.Release(pending.quote.GuardedTrade(b.opts.Burn))
+		answer("Already handled")
+		if err == nil {
+			err = fmt.Errorf("this swap was already requested: %s", used.describe())
+		}
+		b.edit(ctx, query.Message, fmt.Sprintf("❌ %v", err))
+		return
+	}
+
+	answer("Sending...")
+	b.edit(ctx, query.Message, fmt.Sprintf("⏳ Sending %s of %.9f %s...", pending.quote.Side, pending.quote.AmountIn, tokenLabel(getInputToken(pending.quote.Side), pending.quote.TokenSymbol)))
+
+	// Confirmation takes a while; keep polling for other users meanwhile
+	go func() {
+		q := pending.quote
+		txHash, err := executeSwap(ctx, b.client, b.wallet, q.PoolAddress, q.Side, q.AmountIn, q.MinAmountOut(pending.slippage), b.opts)
+		if err := completeIdempotencyKey(pending.key, txHash, err); err != nil {
+			log.Printf("Could not record idempotency key %s: %v", pending.key, err)
+		}
+		if err != nil {
+			b.releaseSpend(pending.userID, solAmount)
+			b.guard.Release(q.GuardedTrade(b.opts.Burn))
+			b.send(ctx, query.Message.Chat.ID, fmt.Sprintf("❌ Swap failed: %v", err))
+			return
+		}
+		if err := b.guard.Record(q.GuardedTrade(b.opts.Burn)); err != nil {
+			log.Printf("Could not record trade for spend limits: %v", err)
+		}
+		b.send(ctx, query.Message.Chat.ID, fmt.Sprintf("✅ Swap executed\nTransaction: %s\n%s", txHash, explorerTxURL(txHash)))
+	}()
+}
+
+// parseSwapCommand parses "/cmd <pool|token> <amount> <buy|sell> [slippage]" and quotes it
+func (b *telegramBot) parseSwapCommand(ctx context.Context, fields []string) (*SwapQuote, float64, error) {
+	if len(fields) < 4 || len(fields) > 5 {
+		return nil, 0, fmt.Errorf("usage: %s <pool|token> <amount> <buy|sell> [slippage%%]", fields[0])
+	}
+
+	address, err := solana.PublicKeyFromBase58(fields[1])
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid address: %v", err)
+	}
+	amount, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid amount: %v", err)
+	}
+
+	slippage := DEFAULT_SLIPPAGE
+	if len(fields) == 5 {
+		slippage, err = strconv.ParseFloat(strings.TrimSuffix(fields[4], "%"), 64)
+		if err != nil || slippage < 0 || slippage > MAX_SLIPPAGE {
+			return nil, 0, fmt.Errorf("slippage must be between 0 and %.0f", MAX_SLIPPAGE)
+		}
+	}
+
+	params := QuoteParams{Amount: amount, Side: strings.ToLower(fields[3])}
+
+	// Accept either a pool or a token: pools are owned by the Raydium program
+	accountInfo, err := b.client.GetAccountInfo(ctx, address)
+	if err == nil && accountInfo.Value != nil && accountInfo.Value.Owner.Equals(RAYDIUM_AMM_V4) {
+		params.PoolAddress = address.String()
+	} else {
+		params.TokenAddress = address.String()
+	}
+
+	quote, err := cachedSwapQuote(ctx, b.client, b.quotes, params)
+	if err != nil {
+		return nil, 0, err
+	}
+	return quote, slippage, nil
+}
+
+// rollDay resets spend tracking at UTC midnight; callers must hold the lock
+func (b *telegramBot) rollDay() {
+	today := time.Now().UTC().Format(time.DateOnly)
+	if b.spentOn != today {
+		b.spentOn = today
+		b.spent = make(map[int64]float64)
+	}
+}
+
+// checkLimits reports whether a swap of solAmount would fit the user's limits
+func (b *telegramBot) checkLimits(userID int64, solAmount float64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollDay()
+
+	if b.maxTrade > 0 && solAmount > b.maxTrade {
+		return fmt.Errorf("swap of %.4f SOL exceeds the per-trade limit of %.4f SOL", solAmount, b.maxTrade)
+	}
+	if b.dailyLimit > 0 && b.spent[userID]+solAmount > b.dailyLimit {
+		return fmt.Errorf("swap of %.4f SOL exceeds your daily limit (%.4f of %.4f SOL used)", solAmount, b.spent[userID], b.dailyLimit)
+	}
+	return nil
+}
+
+// reserveSpend checks the limits and books solAmount against the user
+func (b *telegramBot) reserveSpend(userID int64, solAmount float64) error {
+	if err := b.checkLimits(userID, solAmount); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.spent[userID] += solAmount
+	return nil
+}
+
+// releaseSpend returns a reservation after a failed swap
+func (b *telegramBot) releaseSpend(userID int64, solAmount float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.spent[userID] = max(b.spent[userID]-solAmount, 0)
+}
+
+func (b *telegramBot) describeLimits(userID int64) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollDay()
+
+	describe := func(limit float64) string {
+		if limit <= 0 {
+			return "unlimited"
+		}
+		return fmt.Sprintf("%.4f SOL", limit)
+	}
+	return fmt.Sprintf("Per trade: %s\nDaily: %s\nUsed today: %.4f SOL",
+		describe(b.maxTrade), describe(b.dailyLimit), b.spent[userID])
+}
+
+func newPendingID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// parseChatIDs parses a comma-separated list of Telegram chat IDs
+func parseChatIDs(raw string) (map[int64]bool, error) {
+	chats := make(map[int64]bool)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(entry, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chat ID %q", entry)
+		}
+		chats[id] = true
+	}
+	return chats, nil
+}
+
+// runBot serves quotes and swaps over Telegram until interrupted
+func runBot(args []string, opts BuildOptions) {
+	fs := newCommandFlagSet("bot")
+	var token string
+	var allowed string
+	var maxTrade float64
+	var dailyLimit float64
+	fs.StringVar(&token, "telegram-token", os.Getenv(TELEGRAM_TOKEN_ENV_VAR), "Telegram bot token (or "+TELEGRAM_TOKEN_ENV_VAR+")")
+	fs.StringVar(&allowed, "allowed-chats", os.Getenv(TELEGRAM_CHATS_ENV_VAR), "Comma-separated chat IDs allowed to use the bot (or "+TELEGRAM_CHATS_ENV_VAR+")")
+	fs.Float64Var(&maxTrade, "max-trade", 0, "Maximum SOL per swap (0 = unlimited)")
+	fs.Float64Var(&dailyLimit, "daily-limit", 0, "Maximum SOL swapped per user per UTC day (0 = unlimited)")
+	quoteTTL := fs.Duration("quote-ttl", DEFAULT_QUOTE_CACHE_TTL, "Serve repeated quotes from memory for this long unless the pool changes, 0 to disable")
+	fs.Parse(args)
+
+	if token == "" {
+		fmt.Println("Usage: go run . bot -telegram-token TOKEN -allowed-chats ID[,ID...] [-max-trade SOL] [-daily-limit SOL]")
+		fs.PrintDefaults()
+		return
+	}
+
+	allowedChats, err := parseChatIDs(allowed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(allowedChats) == 0 {
+		log.Fatal("At least one chat ID must be allowed with -allowed-chats")
+	}
+
+	wallet, err := loadWallet()
+	if err != nil {
+		log.Fatalf("Failed to load wallet: %v", err)
+	}
+	client := newChainClient()
+	guard, err := loadSpendGuard(client, wallet.PublicKey())
+	if err != nil {
+		log.Fatalf("Failed to load spend limits: %v", err)
+	}
+
+	bot := &telegramBot{
+		token:        token,
+		allowedChats: allowedChats,
+		client:       client,
+		wallet:       wallet,
+		guard:        guard,
+		opts:         opts,
+		maxTrade:     maxTrade,
+		dailyLimit:   dailyLimit,
+		httpClient:   &http.Client{Timeout: (TELEGRAM_POLL_TIMEOUT + 10) * time.Second},
+		pending:      make(map[string]*pendingSwap),
+		quotes:       newQuoteCache(*quoteTTL, newUpdateStream(resolveWSURL())),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		if err := bot.quotes.Run(ctx); err != nil && ctx.Err() == nil {
+			fmt.Printf("Warning: quote cache stream stopped: %v\n", err)
+		}
+	}()
+	defer printQuoteCacheStats(bot.quotes)
+
+	fmt.Printf("Telegram bot running for wallet %s (%d allowed chats, Ctrl-C to stop)\n", wallet.PublicKey(), len(allowedChats))
+	if err := bot.run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
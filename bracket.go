@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"awesomeProject/internal/atoms"
+)
+
+// BracketExits are the exits a bracket order registers after its buy, as
+// percentages of the entry price
+type BracketExits struct {
+	TakeProfitPct float64
+	StopLossPct   float64
+}
+
+// Validate checks at least one exit is set and the stop stays above zero
+func (e BracketExits) Validate() error {
+	if e.TakeProfitPct <= 0 && e.StopLossPct <= 0 {
+		return fmt.Errorf("set -take-profit, -stop-loss or both")
+	}
+	if e.TakeProfitPct < 0 || e.StopLossPct < 0 || e.StopLossPct >= 100 {
+		return fmt.Errorf("-take-profit must be positive and -stop-loss between 0 and 100")
+	}
+	return nil
+}
+
+// jobs returns the exit jobs selling tokens bought at entry
+func (e BracketExits) jobs(pool string, tokens float64, entry float64, slippage float64) []*Job {
+	var jobs []*Job
+	if e.TakeProfitPct > 0 {
+		jobs = append(jobs, newJob(pool, strategyFlags{name: STRATEGY_LIMIT, side: "sell", amount: tokens, limit: entry * (1 + e.TakeProfitPct/100)}, slippage))
+	}
+	if e.StopLossPct > 0 {
+		jobs = append(jobs, newJob(pool, strategyFlags{name: STRATEGY_STOP, side: "sell", amount: tokens, stop: entry * (1 - e.StopLossPct/100)}, slippage))
+	}
+	return jobs
+}
+
+// runBracket buys and registers take-profit and stop-loss exits for the
+// tokens received as one bracket order: the daemon's jobs sell them, the
+// first exit to fill cancels the other, and the ledger tags the buy and the
+// exit with the bracket's number
+func runBracket(args []string, opts BuildOptions) {
+	fs := newCommandFlagSet("bracket")
+	poolAddress := fs.String("pool", "", "Pool to buy on")
+	amount := fs.Float64("amount", 0, "SOL to buy with")
+	var exits BracketExits
+	fs.Float64Var(&exits.TakeProfitPct, "take-profit", 0, "Sell everything bought once the price is this percent above the entry")
+	fs.Float64Var(&exits.StopLossPct, "stop-loss", 0, "Sell everything bought once the price is this percent below the entry")
+	slippage := fs.Float64("slippage", DEFAULT_JOB_SLIPPAGE, "Slippage tolerance in percent of the buy")
+	exitSlippage := fs.Float64("exit-slippage", DEFAULT_JOB_SLIPPAGE, "Slippage tolerance in percent of the exits")
+	yes := fs.Bool("yes", false, "Buy without asking for confirmation")
+	fs.Parse(args)
+
+	if *poolAddress == "" || *amount <= 0 {
+		fmt.Println("Usage: go run . bracket -pool POOL -amount SOL [-take-profit PCT] [-stop-loss PCT] [-slippage PCT] [-exit-slippage PCT] [-yes]")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	if err := exits.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	for _, pct := range []float64{*slippage, *exitSlippage} {
+		if pct <= 0 || pct > MAX_SLIPPAGE {
+			log.Fatalf("-slippage and -exit-slippage must be between 0 and %.0f", MAX_SLIPPAGE)
+		}
+	}
+	wallet, err := loadWallet()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := interruptContext()
+	client := newChainClient()
+	quote, err := resolveSwapQuote(ctx, client, QuoteParams{PoolAddress: *poolAddress, Side: "buy", Amount: *amount})
+	if err != nil {
+		log.Fatal(err)
+	}
+	minAmountOut := quote.MinAmountOut(*slippage)
+	entry := quote.Price()
+
+	fmt.Printf("\n=== BRACKET ORDER ===\n")
+	fmt.Printf("Pool: %s\n", *poolAddress)
+	fmt.Printf("Buy: %g SOL for ~%.9f %s at %.9f SOL\n", *amount, quote.ExpectedOut, tokenLabel("tokens", quote.TokenSymbol), entry)
+	if exits.TakeProfitPct > 0 {
+		fmt.Printf("Take Profit: +%g%% (~%.9f SOL)\n", exits.TakeProfitPct, entry*(1+exits.TakeProfitPct/100))
+	}
+	if exits.StopLossPct > 0 {
+		fmt.Printf("Stop Loss: -%g%% (~%.9f SOL)\n", exits.StopLossPct, entry*(1-exits.StopLossPct/100))
+	}
+	fmt.Printf("=====================\n")
+	if !*yes && !confirmPrompt("Place this bracket order?") {
+		fmt.Println("Bracket order cancelled.")
+		return
+	}
+
+	guard, err := loadSpendGuard(client, wallet.PublicKey())
+	if err != nil {
+		log.Fatalf("Failed to load spend limits: %v", err)
+	}
+	trade := quote.GuardedTrade(opts.Burn)
+	if err := guard.Enforce(ctx, trade, !*yes); err != nil {
+		log.Fatalf("Spend limits: %v", err)
+	}
+	txHash, err := executeSwap(ctx, client, wallet, *poolAddress, "buy", *amount, minAmountOut, opts)
+	if err != nil {
+		guard.Release(trade)
+		log.Fatalf("Bracket buy failed: %v", err)
+	}
+	fmt.Printf("\n✅ Bought: %s\n", explorerTxURL(txHash))
+
+	// The exits sell what the buy actually returned, priced from its fill
+	tokens := opts.Burn.kept("buy", atoms.ToAmount(minAmountOut, quote.OutputDecimals))
+	if pool, err := loadPool(ctx, client, *poolAddress); err != nil {
+		fmt.Printf("Warning: Could not load the pool to read the fill (%v); exits use the minimum out\n", err)
+	} else if spent, received, _, err := parseSwapResult(ctx, client, txHash, wallet.PublicKey(), pool); err != nil || received <= 0 {
+		fmt.Printf("Warning: Could not read the fill (%v); exits use the minimum out\n", err)
+	} else {
+		tokens, entry = opts.Burn.kept("buy", received), spent/received
+		trade.SOL, trade.Tokens = spent, tokens
+	}
+
+	jobs := exits.jobs(*poolAddress, tokens, entry, *exitSlippage)
+	if err := addJobs(true, jobs...); err != nil {
+		guard.Record(trade)
+		log.Fatalf("The buy landed but its exits were not saved: %v; add them with `jobs add`", err)
+	}
+	trade.Bracket = jobs[0].Bracket
+	if err := guard.Record(trade); err != nil {
+		fmt.Printf("Warning: Could not record trade for spend limits: %v\n", err)
+	}
+
+	fmt.Printf("Bracket %d: %.9f tokens bought at %.9f SOL\n", trade.Bracket, tokens, entry)
+	for _, job := range jobs {
+		fmt.Printf("  Job %d: %s %s\n", job.ID, job.Strategy, job.describe())
+	}
+	fmt.Println("The exits run while `go run . daemon -jobs` is running; the first to fill cancels the other.")
+}
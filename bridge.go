@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gorilla/websocket"
+
+	"awesomeProject/internal/atoms"
+)
+
+// Signing bridge paths, served by grpc -bridge
+const (
+	BRIDGE_PATH          = "/bridge"
+	BRIDGE_WS_PATH       = "/bridge/ws"
+	BRIDGE_MAX_MESSAGE   = 64 << 10
+	BRIDGE_MAX_IN_FLIGHT = 4 // unsigned swaps a page may hold at once
+)
+
+// bridgePage discovers browser wallets through the Wallet Standard, the
+// interface wallet adapters use, and relays the bridge's messages to them
+//
+//go:embed bridge.html
+var bridgePage []byte
+
+// bridgeMessage is one message either way over the bridge's WebSocket
+type bridgeMessage struct {
+	Type        string  `json:"type"`
+	ID          int     `json:"id,omitempty"`
+	PublicKey   string  `json:"publicKey,omitempty"`
+	Cluster     string  `json:"cluster,omitempty"`
+	Pool        string  `json:"pool,omitempty"`
+	Token       string  `json:"token,omitempty"`
+	Side        string  `json:"side,omitempty"`
+	Amount      float64 `json:"amount,omitempty"`
+	Slippage    float64 `json:"slippage,omitempty"`
+	Transaction string  `json:"transaction,omitempty"` // base64
+	Summary     string  `json:"summary,omitempty"`
+	Signature   string  `json:"signature,omitempty"`
+	Explorer    string  `json:"explorer,omitempty"`
+	Message     string  `json:"message,omitempty"`
+}
+
+// SigningBridge lets browser wallets sign swaps the server prepares: the
+// page connects a wallet and asks for a swap, the server quotes and builds
+// it for that wallet, the wallet signs it, and the server checks it is the
+// transaction it built before sending it. Keys stay in the browser wallet.
+type SigningBridge struct {
+	client   ChainClient
+	opts     BuildOptions       // without a fee payer or delegation, which the wallets cannot sign for
+	upgrader websocket.Upgrader // refuses pages of other origins
+}
+
+func newSigningBridge(client ChainClient, opts BuildOptions) *SigningBridge {
+	return &SigningBridge{client: client, opts: opts.walletSigned()}
+}
+
+// bridgeSession is one page's connection and the swaps awaiting its wallet
+type bridgeSession struct {
+	bridge  *SigningBridge
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	wallet   solana.PublicKey
+	prepared map[int]*bridgeSwap
+	building int // swaps holding an in-flight slot while they are quoted and built
+}
+
+// bridgeSwap is a swap sent to the wallet to sign
+type bridgeSwap struct {
+	tx      *solana.Transaction
+	message []byte
+	trade   GuardedTrade
+}
+
+// ServeHTTP serves the page and its WebSocket
+func (b *SigningBridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case BRIDGE_PATH:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(bridgePage)
+	case BRIDGE_WS_PATH:
+		conn, err := b.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return // the upgrader answered
+		}
+		session := &bridgeSession{bridge: b, conn: conn, prepared: make(map[int]*bridgeSwap)}
+		session.run(r.Context())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// send writes a message; the connection allows one writer at a time
+func (s *bridgeSession) send(msg bridgeMessage) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.WriteJSON(msg)
+}
+
+func (s *bridgeSession) fail(id int, format string, args ...any) {
+	s.send(bridgeMessage{Type: "error", ID: id, Message: fmt.Sprintf(format, args...)})
+}
+
+// run reads the page's messages until it disconnects
+func (s *bridgeSession) run(ctx context.Context) {
+	defer s.conn.Close()
+	s.conn.SetReadLimit(BRIDGE_MAX_MESSAGE)
+	s.send(bridgeMessage{Type: "hello", Cluster: activeCluster.Name})
+	for {
+		var msg bridgeMessage
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case "connect":
+			wallet, err := solana.PublicKeyFromBase58(msg.PublicKey)
+			if err != nil {
+				s.fail(0, "invalid public key: %v", err)
+				continue
+			}
+			s.mu.Lock()
+			s.wallet = wallet
+			s.prepared = make(map[int]*bridgeSwap)
+			s.mu.Unlock()
+			log.Printf("Bridge: wallet %s connected", wallet)
+		case "swap":
+			go s.prepare(ctx, msg)
+		case "signed":
+			go s.submit(ctx, msg)
+		case "rejected":
+			s.mu.Lock()
+			delete(s.prepared, msg.ID)
+			s.mu.Unlock()
+		default:
+			s.fail(msg.ID, "unknown message type %q", msg.Type)
+		}
+	}
+}
+
+// prepare quotes and builds the requested swap for the connected wallet and
+// sends it to be signed
+func (s *bridgeSession) prepare(ctx context.Context, msg bridgeMessage) {
+	if msg.Slippage <= 0 || msg.Slippage > MAX_SLIPPAGE {
+		s.fail(msg.ID, "slippage must be between 0 and %.0f", MAX_SLIPPAGE)
+		return
+	}
+
+	// The slot is taken in the same critical section as the check, so
+	// concurrent requests cannot all pass it; it is held until the swap is
+	// stored or its build fails
+	s.mu.Lock()
+	wallet, pending := s.wallet, len(s.prepared)+s.building
+	if !wallet.IsZero() && pending < BRIDGE_MAX_IN_FLIGHT {
+		s.building++
+	}
+	s.mu.Unlock()
+	switch {
+	case wallet.IsZero():
+		s.fail(msg.ID, "connect a wallet first")
+		return
+	case pending >= BRIDGE_MAX_IN_FLIGHT:
+		s.fail(msg.ID, "%d swaps are already waiting to be signed", pending)
+		return
+	}
+	held := true
+	defer func() {
+		if held {
+			s.mu.Lock()
+			s.building--
+			s.mu.Unlock()
+		}
+	}()
+
+	quote, err := resolveSwapQuote(ctx, s.bridge.client, QuoteParams{
+		PoolAddress:  msg.Pool,
+		TokenAddress: msg.Token,
+		Amount:       msg.Amount,
+		Side:         msg.Side,
+	})
+	if err != nil {
+		s.fail(msg.ID, "%v", err)
+		return
+	}
+	minAmountOut := quote.MinAmountOut(msg.Slippage)
+	tx, err := buildSwapTransaction(ctx, s.bridge.client, wallet, quote.PoolAddress, msg.Side, msg.Amount, minAmountOut, s.bridge.opts)
+	if err != nil {
+		s.fail(msg.ID, "failed to build the swap: %v", err)
+		return
+	}
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		s.fail(msg.ID, "failed to serialize the swap: %v", err)
+		return
+	}
+	raw, err := marshalUnsigned(tx)
+	if err != nil {
+		s.fail(msg.ID, "%v", err)
+		return
+	}
+
+	s.mu.Lock()
+	s.building--
+	held = false
+	if !s.wallet.Equals(wallet) {
+		s.mu.Unlock()
+		s.fail(msg.ID, "the wallet changed while the swap was built")
+		return
+	}
+	s.prepared[msg.ID] = &bridgeSwap{tx: tx, message: message, trade: quote.GuardedTrade(s.bridge.opts.Burn)}
+	s.mu.Unlock()
+
+	s.send(bridgeMessage{
+		Type:        "sign",
+		ID:          msg.ID,
+		Transaction: base64.StdEncoding.EncodeToString(raw),
+		Summary: fmt.Sprintf("%s %g on %s: ~%.9f %s out, at least %s", strings.ToUpper(msg.Side), msg.Amount, quote.PoolAddress,
+			quote.ExpectedOut, tokenLabel(getOutputToken(msg.Side), quote.TokenSymbol), atoms.Format(minAmountOut, quote.OutputDecimals)),
+	})
+}
+
+// submit sends a swap the wallet signed, if it is the one prepared for it
+func (s *bridgeSession) submit(ctx context.Context, msg bridgeMessage) {
+	s.mu.Lock()
+	swap := s.prepared[msg.ID]
+	delete(s.prepared, msg.ID)
+	wallet := s.wallet
+	s.mu.Unlock()
+	if swap == nil {
+		s.fail(msg.ID, "no swap %d is waiting to be signed", msg.ID)
+		return
+	}
+
+	signed, err := decodeTransaction(msg.Transaction)
+	if err != nil {
+		s.fail(msg.ID, "%v", err)
+		return
+	}
+	// The server only sends what it built, so the bridge relays nothing else
+	message, err := signed.Message.MarshalBinary()
+	if err != nil || !bytes.Equal(message, swap.message) {
+		s.fail(msg.ID, "the wallet returned a different transaction than the one prepared")
+		return
+	}
+	if err := signed.VerifySignatures(); err != nil {
+		s.fail(msg.ID, "transaction is not fully signed: %v", err)
+		return
+	}
+
+	s.send(bridgeMessage{Type: "status", ID: msg.ID, Message: "Sending..."})
+	sig, err := sendAndConfirmTransaction(ctx, s.bridge.client, signed, s.bridge.opts)
+	if err != nil {
+		s.fail(msg.ID, "swap failed: %v", err)
+		return
+	}
+	log.Printf("Bridge: swap %s sent for %s", sig, wallet)
+	// The ledger keeps the wallet's positions for spend limits
+	if err := appendLedger(wallet.String(), swap.trade); err != nil {
+		log.Printf("Bridge: could not record the trade: %v", err)
+	}
+	s.send(bridgeMessage{Type: "result", ID: msg.ID, Signature: sig.String(), Explorer: explorerTxURL(sig.String())})
+}
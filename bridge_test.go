@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gorilla/websocket"
+)
+
+// blockingClient holds every account read until release is closed, keeping
+// the bridge's swaps in flight while they are being quoted
+type blockingClient struct {
+	ChainClient
+	release chan struct{}
+}
+
+func (c *blockingClient) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	<-c.release
+	return c.ChainClient.GetAccountInfo(ctx, account)
+}
+
+func TestBridgeInFlightCap(t *testing.T) {
+	client := &blockingClient{ChainClient: fixtureTestClient(t), release: make(chan struct{})}
+	server := httptest.NewServer(&SigningBridge{client: client})
+	defer server.Close()
+	defer close(client.release)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+BRIDGE_WS_PATH, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var hello bridgeMessage
+	if err := conn.ReadJSON(&hello); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteJSON(bridgeMessage{Type: "connect", PublicKey: solana.NewWallet().PublicKey().String()}); err != nil {
+		t.Fatal(err)
+	}
+
+	const extra = 3
+	for id := 1; id <= BRIDGE_MAX_IN_FLIGHT+extra; id++ {
+		swap := bridgeMessage{Type: "swap", ID: id, Pool: FIXTURE_SOL_TOKEN6_POOL.String(), Side: "buy", Amount: 1, Slippage: 1}
+		if err := conn.WriteJSON(swap); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the requests beyond the cap are answered while the others are held
+	for i := 0; i < extra; i++ {
+		var msg bridgeMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type != "error" || !strings.Contains(msg.Message, "already waiting to be signed") {
+			t.Fatalf("got %s %q, want a refusal over the in-flight cap", msg.Type, msg.Message)
+		}
+	}
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	var msg bridgeMessage
+	if err := conn.ReadJSON(&msg); err == nil {
+		t.Fatalf("got %s %q for swap %d, want no answer while %d swaps are in flight", msg.Type, msg.Message, msg.ID, BRIDGE_MAX_IN_FLIGHT)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// BuildOptions are the settings a swap is built and sent with: who pays for
+// and whose accounts trade it, its compute budget, the fee and burn added to
+// it, and how it is broadcast
+type BuildOptions struct {
+	FeePayer    FeePayerConfig
+	Delegation  DelegationConfig
+	Compute     ComputeLimitConfig
+	MEV         MEVProtection
+	Sender      SenderConfig
+	Spam        SpamConfig
+	PlatformFee PlatformFeeConfig
+	Burn        BuybackBurnConfig
+	Reference   solana.PublicKey // Solana Pay reference the swap carries, zero for none
+}
+
+// loadBuildOptions returns the options chosen by the environment. The main
+// flags refine them; commands pass the result down to every swap they build.
+func loadBuildOptions() (BuildOptions, error) {
+	opts := BuildOptions{
+		Compute: ComputeLimitConfig{Auto: true, MarginPct: DEFAULT_CU_MARGIN},
+		MEV: MEVProtection{
+			Epsilon:         DEFAULT_MEV_EPSILON,
+			MinComputePrice: DEFAULT_MEV_MIN_COMPUTE_PRICE,
+			MaxComputePrice: DEFAULT_MEV_MAX_COMPUTE_PRICE,
+			RelayURL:        envOrDefault(PRIVATE_RELAY_URL_ENV_VAR, DEFAULT_PRIVATE_RELAY_URL),
+		},
+		Sender: SenderConfig{
+			Name:       SENDER_RPC,
+			URL:        os.Getenv(SENDER_URL_ENV_VAR),
+			Tip:        DEFAULT_SENDER_TIP,
+			TipAccount: os.Getenv(SENDER_TIP_ACCOUNT_ENV_VAR),
+		},
+		Spam: SpamConfig{
+			Endpoints: parseEndpointList(os.Getenv(SPAM_RPC_URLS_ENV_VAR)),
+			Interval:  DEFAULT_REBROADCAST_INTERVAL,
+		},
+	}
+
+	var err error
+	if opts.PlatformFee, err = loadPlatformFee(); err != nil {
+		return opts, err
+	}
+	if opts.Burn, err = loadBuybackBurn(); err != nil {
+		return opts, err
+	}
+	if opts.FeePayer, err = loadFeePayer(); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// walletSigned returns the options for transactions a connected wallet signs
+// for itself. That wallet cannot add the fee payer's signature and trades its
+// own accounts, so neither a separate fee payer nor a delegation applies.
+func (o BuildOptions) walletSigned() BuildOptions {
+	o.FeePayer = FeePayerConfig{}
+	o.Delegation = DelegationConfig{}
+	return o
+}
+
+// validateDelegation rejects options that move the signer's own funds, which
+// a delegated swap does not touch
+func (o BuildOptions) validateDelegation() error {
+	if !o.Delegation.Enabled() {
+		return nil
+	}
+	if o.PlatformFee.Enabled() {
+		return fmt.Errorf("-delegate-for cannot be combined with a platform fee")
+	}
+	if o.FeePayer.Enabled() {
+		return fmt.Errorf("-delegate-for cannot be combined with a separate fee payer")
+	}
+	return nil
+}
+
+// validateBroadcast rejects spamming transactions that are meant to stay out
+// of the public mempool: anti-MEV sends and the bundles and relays of the
+// other senders would be sandwichable once copied to public RPCs
+func (o BuildOptions) validateBroadcast() error {
+	if len(o.Spam.Endpoints) == 0 {
+		return nil
+	}
+	if o.MEV.Enabled {
+		return fmt.Errorf("-spam-rpcs cannot be combined with -anti-mev")
+	}
+	if o.Sender.Name != SENDER_RPC && o.Sender.Name != "" {
+		return fmt.Errorf("-spam-rpcs cannot be combined with -sender %s", o.Sender.Name)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// A connected wallet signs only for itself, so its swaps must not need the
+// fee payer's signature or trade another wallet's accounts
+func TestWalletSignedOptions(t *testing.T) {
+	opts := BuildOptions{
+		FeePayer:    FeePayerConfig{Key: solana.NewWallet().PrivateKey},
+		Delegation:  DelegationConfig{Owner: solana.MustPublicKeyFromBase58("9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM")},
+		Compute:     ComputeLimitConfig{Price: 5000},
+		PlatformFee: PlatformFeeConfig{Bps: 50, Recipient: "EPjFWdd5AufqSSqeM2qNrmqrfWwzkQfD6h2t9P1aHA8v"},
+	}
+	signed := opts.walletSigned()
+	if signed.FeePayer.Enabled() {
+		t.Error("wallet-signed options keep the fee payer")
+	}
+	if signed.Delegation.Enabled() {
+		t.Error("wallet-signed options keep the delegation")
+	}
+	owner := solana.NewWallet().PublicKey()
+	if payer := signed.FeePayer.payer(owner); !payer.Equals(owner) {
+		t.Errorf("payer is %s, want the signing wallet %s", payer, owner)
+	}
+	if signed.Compute.Price != 5000 || !signed.PlatformFee.Enabled() {
+		t.Error("wallet-signed options dropped settings the wallet can sign for")
+	}
+	if !opts.FeePayer.Enabled() || !opts.Delegation.Enabled() {
+		t.Error("walletSigned changed the options it was called on")
+	}
+}
+
+// Transactions sent privately or as bundles must never be copied to public RPCs
+func TestValidateBroadcast(t *testing.T) {
+	spam := SpamConfig{Endpoints: []string{"https://rpc.example.com"}}
+	tests := []struct {
+		name    string
+		opts    BuildOptions
+		wantErr string // empty when the combination is allowed
+	}{
+		{"spam through the RPC", BuildOptions{Sender: SenderConfig{Name: SENDER_RPC}, Spam: spam}, ""},
+		{"anti-MEV without spam", BuildOptions{MEV: MEVProtection{Enabled: true}}, ""},
+		{"jito without spam", BuildOptions{Sender: SenderConfig{Name: SENDER_JITO}}, ""},
+		{"spam with anti-MEV", BuildOptions{MEV: MEVProtection{Enabled: true}, Spam: spam}, "-anti-mev"},
+		{"spam with a jito bundle", BuildOptions{Sender: SenderConfig{Name: SENDER_JITO}, Spam: spam}, "-sender jito"},
+		{"spam with helius", BuildOptions{Sender: SenderConfig{Name: SENDER_HELIUS}, Spam: spam}, "-sender helius"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.validateBroadcast()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want one naming %q", err, tt.wantErr)
+			}
+		})
+	}
+}
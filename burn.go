@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+
+	"awesomeProject/internal/atoms"
+)
+
+// BUYBACK_BURN_PCT_ENV_VAR configures the buyback burn; the -burn-pct flag overrides it
+const BUYBACK_BURN_PCT_ENV_VAR = "BUYBACK_BURN_PCT"
+
+// BuybackBurnConfig burns a share of every buy's output in the swap's own
+// transaction, for buyback-and-burn programs. The share is taken of the
+// minimum out, which the swap guarantees is in the token account.
+type BuybackBurnConfig struct {
+	Pct float64
+}
+
+// loadBuybackBurn reads the burn share from the environment
+func loadBuybackBurn() (BuybackBurnConfig, error) {
+	var burn BuybackBurnConfig
+	if value := os.Getenv(BUYBACK_BURN_PCT_ENV_VAR); value != "" {
+		pct, err := parseFloat(value)
+		if err != nil {
+			return burn, fmt.Errorf("invalid %s %q", BUYBACK_BURN_PCT_ENV_VAR, value)
+		}
+		burn.Pct = pct
+	}
+	return burn, nil
+}
+
+// Validate checks the share is a percentage
+func (c BuybackBurnConfig) Validate() error {
+	if c.Pct < 0 || c.Pct > 100 || math.IsNaN(c.Pct) {
+		return fmt.Errorf("burn share of %s%% must be between 0 and 100", strconv.FormatFloat(c.Pct, 'f', -1, 64))
+	}
+	return nil
+}
+
+// Enabled reports whether buys burn part of their output
+func (c BuybackBurnConfig) Enabled() bool {
+	return c.Pct > 0
+}
+
+// rawBurn returns the tokens a swap burns in raw units: the share of a buy's
+// minimum out, in steps of 0.01%
+func (c BuybackBurnConfig) rawBurn(side string, minAmountOut uint64) uint64 {
+	if !c.Enabled() || side != "buy" {
+		return 0
+	}
+	return atoms.MulDiv(minAmountOut, uint64(math.Round(c.Pct*100)), 10_000, false)
+}
+
+// kept returns the tokens of a buy's output left after the burn, in UI units
+func (c BuybackBurnConfig) kept(side string, out float64) float64 {
+	if !c.Enabled() || side != "buy" {
+		return out
+	}
+	return out * (1 - c.Pct/100)
+}
+
+// burnInstruction burns the share of a buy's output from the destination
+// token account, or returns nil without a burn
+func (c BuybackBurnConfig) burnInstruction(pool *OnChainPool, owner solana.PublicKey, side string, minAmountOut uint64, accounts swapAccounts) solana.Instruction {
+	amount := c.rawBurn(side, minAmountOut)
+	if amount == 0 {
+		return nil
+	}
+	_, mint, _ := swapMints(pool, side)
+	return token.NewBurnCheckedInstruction(amount, tokenDecimals(pool, mint), accounts.Destination, mint, owner, []solana.PublicKey{}).Build()
+}
+
+// print itemizes the burn in a confirmation
+func (c BuybackBurnConfig) print(side string, expectedOut float64, symbol string) {
+	if !c.Enabled() || side != "buy" {
+		return
+	}
+	fmt.Printf(tr("Burn: %.2f%% of the minimum out (under %.9f %s)\n"), c.Pct, c.Pct/100*expectedOut, tokenLabel("TOKEN", symbol))
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"awesomeProject/internal/raylog"
+)
+
+// Candles are stored per pool as InfluxDB line protocol in the state directory
+const (
+	CANDLES_DIR         = "candles"
+	CANDLE_MEASUREMENT  = "candle"
+	CANDLE_FLUSH_PERIOD = 5 * time.Second
+)
+
+// Candle intervals recorded by the daemon, shortest first
+var candleIntervalNames = []string{"1m", "5m", "1h"}
+
+var candleIntervals = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+}
+
+// ClosedCandle is a finished candle of one interval
+type ClosedCandle struct {
+	Interval string
+	Candle   Candle
+}
+
+// CandleAggregator folds a pool's trades into open candles of every interval
+type CandleAggregator struct {
+	open map[string]*Candle
+}
+
+func newCandleAggregator() *CandleAggregator {
+	return &CandleAggregator{open: make(map[string]*Candle)}
+}
+
+// Add folds a trade into the open candles and returns those it closed
+func (a *CandleAggregator) Add(trade PoolTrade) []ClosedCandle {
+	closed := a.Close(trade.Time)
+	for _, name := range candleIntervalNames {
+		candle := a.open[name]
+		if candle == nil {
+			candle = &Candle{Time: trade.Time.Truncate(candleIntervals[name]), Open: trade.Price, High: trade.Price, Low: trade.Price}
+			a.open[name] = candle
+		}
+		candle.High = max(candle.High, trade.Price)
+		candle.Low = min(candle.Low, trade.Price)
+		candle.Close = trade.Price
+		candle.VolumeSOL += trade.VolumeSOL
+		candle.Trades++
+	}
+	return closed
+}
+
+// Close returns and forgets the open candles whose interval ended by now
+func (a *CandleAggregator) Close(now time.Time) []ClosedCandle {
+	var closed []ClosedCandle
+	for _, name := range candleIntervalNames {
+		candle := a.open[name]
+		if candle != nil && !now.Before(candle.Time.Add(candleIntervals[name])) {
+			closed = append(closed, ClosedCandle{Interval: name, Candle: *candle})
+			delete(a.open, name)
+		}
+	}
+	return closed
+}
+
+// CandleStore appends and reads candles in line protocol files, one per pool
+type CandleStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// openCandleStore opens the candle directory in the state directory
+func openCandleStore() (*CandleStore, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	dir = filepath.Join(dir, CANDLES_DIR)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create candle directory: %w", err)
+	}
+	return &CandleStore{dir: dir}, nil
+}
+
+func (s *CandleStore) path(pool solana.PublicKey) string {
+	return filepath.Join(s.dir, pool.String()+".lp")
+}
+
+// Append writes closed candles of a pool, which can be imported into InfluxDB as is
+func (s *CandleStore) Append(pool solana.PublicKey, candles []ClosedCandle) error {
+	if len(candles) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path(pool), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open candle file: %w", err)
+	}
+	defer file.Close()
+
+	var lines strings.Builder
+	for _, c := range candles {
+		fmt.Fprintf(&lines, "%s,pool=%s,interval=%s open=%s,high=%s,low=%s,close=%s,volume_sol=%s,trades=%di %d\n",
+			CANDLE_MEASUREMENT, pool, c.Interval,
+			formatLineFloat(c.Candle.Open), formatLineFloat(c.Candle.High), formatLineFloat(c.Candle.Low),
+			formatLineFloat(c.Candle.Close), formatLineFloat(c.Candle.VolumeSOL), c.Candle.Trades, c.Candle.Time.UnixNano())
+	}
+	if _, err := file.WriteString(lines.String()); err != nil {
+		return fmt.Errorf("failed to write candles: %w", err)
+	}
+	return nil
+}
+
+func formatLineFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Query returns a pool's candles of one interval starting at or after since,
+// oldest first. A candle written twice keeps its last value.
+func (s *CandleStore) Query(pool solana.PublicKey, interval string, since time.Time) ([]Candle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path(pool))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open candle file: %w", err)
+	}
+	defer file.Close()
+
+	byTime := make(map[int64]Candle)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, candle, err := parseCandleLine(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		if name == interval && !candle.Time.Before(since) {
+			byTime[candle.Time.UnixNano()] = candle
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read candles: %w", err)
+	}
+
+	candles := make([]Candle, 0, len(byTime))
+	for _, candle := range byTime {
+		candles = append(candles, candle)
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Time.Before(candles[j].Time) })
+	return candles, nil
+}
+
+// parseCandleLine decodes a line written by Append
+func parseCandleLine(line string) (string, Candle, error) {
+	parts := strings.Fields(line)
+	if len(parts) != 3 {
+		return "", Candle{}, fmt.Errorf("invalid candle line %q", line)
+	}
+
+	var interval string
+	for _, tag := range strings.Split(parts[0], ",")[1:] {
+		if value, ok := strings.CutPrefix(tag, "interval="); ok {
+			interval = value
+		}
+	}
+
+	var candle Candle
+	for _, field := range strings.Split(parts[1], ",") {
+		key, value, _ := strings.Cut(field, "=")
+		var err error
+		switch key {
+		case "open":
+			candle.Open, err = strconv.ParseFloat(value, 64)
+		case "high":
+			candle.High, err = strconv.ParseFloat(value, 64)
+		case "low":
+			candle.Low, err = strconv.ParseFloat(value, 64)
+		case "close":
+			candle.Close, err = strconv.ParseFloat(value, 64)
+		case "volume_sol":
+			candle.VolumeSOL, err = strconv.ParseFloat(value, 64)
+		case "trades":
+			candle.Trades, err = strconv.Atoi(strings.TrimSuffix(value, "i"))
+		}
+		if err != nil {
+			return "", Candle{}, fmt.Errorf("invalid candle field %q: %w", field, err)
+		}
+	}
+
+	nanos, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", Candle{}, fmt.Errorf("invalid candle timestamp %q: %w", parts[2], err)
+	}
+	candle.Time = time.Unix(0, nanos).UTC()
+	return interval, candle, nil
+}
+
+// recordCandles decodes the swaps on a pool loaded in the engine from its
+// transaction logs and stores closed candles until ctx is cancelled.
+// Transactions swapping on several Raydium pools are skipped.
+func recordCandles(ctx context.Context, engine *Engine, store *CandleStore, address solana.PublicKey) {
+	updates := engine.mux.SubscribeLogs(address)
+	defer engine.mux.Unsubscribe(updates)
+	aggregator := newCandleAggregator()
+	ticker := time.NewTicker(CANDLE_FLUSH_PERIOD)
+	defer ticker.Stop()
+
+	for {
+		var closed []ClosedCandle
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			closed = aggregator.Close(now)
+		case u := <-updates:
+			if u.Failed {
+				continue
+			}
+			swapLogs, err := raylog.FindSwaps(u.Logs)
+			if err != nil || len(swapLogs) != 1 {
+				continue
+			}
+			pool, err := engine.pool(address.String())
+			if err != nil {
+				continue
+			}
+			if trade, ok := poolTradeFromLog(pool, swapLogs[0], time.Now()); ok {
+				closed = aggregator.Add(trade)
+			}
+		}
+		if err := store.Append(address, closed); err != nil {
+			fmt.Printf("Warning: candles for %s: %v\n", address, err)
+		}
+	}
+}
+
+// runCandles prints candles recorded by the daemon
+func runCandles(args []string) {
+	fs := newCommandFlagSet("candles")
+	var poolAddress string
+	var interval string
+	var hours float64
+	var format string
+	fs.StringVar(&poolAddress, "pool", "", "Pool address")
+	fs.StringVar(&interval, "interval", "1m", "Candle interval: "+strings.Join(candleIntervalNames, ", "))
+	fs.Float64Var(&hours, "hours", 24, "How far back to go")
+	fs.StringVar(&format, "format", "csv", "Output format: csv or json")
+	fs.Parse(args)
+
+	if poolAddress == "" {
+		fmt.Println("Usage: go run . candles -pool POOL [-interval 1m|5m|1h] [-hours 24] [-format csv|json]")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	pool, err := solana.PublicKeyFromBase58(poolAddress)
+	if err != nil {
+		log.Fatalf("Invalid pool address: %v", err)
+	}
+	if _, ok := candleIntervals[interval]; !ok {
+		log.Fatalf("-interval must be one of %s", strings.Join(candleIntervalNames, ", "))
+	}
+	if format != "csv" && format != "json" {
+		log.Fatal("-format must be csv or json")
+	}
+
+	store, err := openCandleStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	since := time.Now().Add(-time.Duration(hours * float64(time.Hour)))
+	candles, err := store.Query(pool, interval, since)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := writeCandles(os.Stdout, candles, format); err != nil {
+		log.Fatal(err)
+	}
+}
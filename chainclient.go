@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// Environment variables selecting recorded RPC fixtures
+const (
+	RPC_RECORD_ENV_VAR = "RPC_RECORD"
+	RPC_REPLAY_ENV_VAR = "RPC_REPLAY"
+)
+
+// ChainClient is the subset of the Solana RPC API the tool uses. *rpc.Client
+// implements it; fixtureClient replays recorded responses offline.
+type ChainClient interface {
+	GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error)
+	GetMultipleAccounts(ctx context.Context, accounts ...solana.PublicKey) (*rpc.GetMultipleAccountsResult, error)
+	GetProgramAccountsWithOpts(ctx context.Context, program solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error)
+	GetTokenAccountsByOwner(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (*rpc.GetTokenAccountsResult, error)
+	GetBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error)
+	GetTokenAccountBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetTokenAccountBalanceResult, error)
+	GetTokenSupply(ctx context.Context, mint solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetTokenSupplyResult, error)
+	GetTokenLargestAccounts(ctx context.Context, mint solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetTokenLargestAccountsResult, error)
+	GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error)
+	GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error)
+	GetSlot(ctx context.Context, commitment rpc.CommitmentType) (uint64, error)
+	GetBlockTime(ctx context.Context, slot uint64) (*solana.UnixTimeSeconds, error)
+	GetVersion(ctx context.Context) (*rpc.GetVersionResult, error)
+	GetTransaction(ctx context.Context, signature solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error)
+	GetSignatureStatuses(ctx context.Context, searchHistory bool, signatures ...solana.Signature) (*rpc.GetSignatureStatusesResult, error)
+	GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error)
+	SendTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error)
+	SimulateTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error)
+}
+
+var _ ChainClient = (*rpc.Client)(nil)
+
+// newChainClient returns the RPC client for the active cluster, retrying and
+// pacing requests according to rpcPolicy. RPC_REPLAY
+// serves responses from a fixture file instead of the network; RPC_RECORD
+// forwards to the network and saves every response to a fixture file.
+func newChainClient() ChainClient {
+	if path := os.Getenv(RPC_REPLAY_ENV_VAR); path != "" {
+		client, err := loadFixtureClient(path)
+		if err != nil {
+			log.Fatalf("Failed to load RPC fixtures: %v", err)
+		}
+		return client
+	}
+
+	httpClient := newRetryHTTPClient(rpcPolicy)
+	var client ChainClient = rpc.NewWithCustomRPCClient(jsonrpc.NewClientWithOpts(resolveRPCURL(), &jsonrpc.RPCClientOpts{HTTPClient: httpClient}))
+	if path := os.Getenv(RPC_RECORD_ENV_VAR); path != "" {
+		client = &fixtureClient{next: client, path: path, fixtures: make(map[string]json.RawMessage)}
+	}
+	return client
+}
+
+// fixtureClient records responses from next, or replays them when next is nil.
+// Fixtures are keyed by method and arguments. Transactions are keyed by method
+// only, since their signatures change with every blockhash.
+type fixtureClient struct {
+	next     ChainClient
+	path     string
+	mu       sync.Mutex
+	fixtures map[string]json.RawMessage
+}
+
+// loadFixtureClient reads a fixture file written by a recording run
+func loadFixtureClient(path string) (*fixtureClient, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	fixtures := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &fixtureClient{path: path, fixtures: fixtures}, nil
+}
+
+// fixtureKey identifies a call by method name and JSON-encoded arguments
+func fixtureKey(method string, args ...interface{}) string {
+	parts := []string{method}
+	for _, arg := range args {
+		encoded, _ := json.Marshal(arg)
+		parts = append(parts, string(encoded))
+	}
+	return strings.Join(parts, " ")
+}
+
+// call replays the fixture for key into out, or runs fetch and records its result
+func (c *fixtureClient) call(key string, out interface{}, fetch func() (interface{}, error)) error {
+	if c.next == nil {
+		c.mu.Lock()
+		raw, ok := c.fixtures[key]
+		c.mu.Unlock()
+		if !ok {
+			return fmt.Errorf("no recorded RPC response for %s in %s", key, c.path)
+		}
+		return json.Unmarshal(raw, out)
+	}
+
+	result, err := fetch()
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to record %s: %w", key, err)
+	}
+
+	// Save after every call so fatal exits keep what was recorded
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fixtures[key] = encoded
+	data, err := json.MarshalIndent(c.fixtures, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write RPC fixtures: %w", err)
+	}
+	return json.Unmarshal(encoded, out)
+}
+
+func (c *fixtureClient) GetAccountInfo(ctx context.Context, account solana.PublicKey) (out *rpc.GetAccountInfoResult, err error) {
+	err = c.call(fixtureKey("getAccountInfo", account), &out, func() (interface{}, error) {
+		return c.next.GetAccountInfo(ctx, account)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) GetMultipleAccounts(ctx context.Context, accounts ...solana.PublicKey) (out *rpc.GetMultipleAccountsResult, err error) {
+	err = c.call(fixtureKey("getMultipleAccounts", accounts), &out, func() (interface{}, error) {
+		return c.next.GetMultipleAccounts(ctx, accounts...)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) GetProgramAccountsWithOpts(ctx context.Context, program solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (out rpc.GetProgramAccountsResult, err error) {
+	err = c.call(fixtureKey("getProgramAccounts", program, opts), &out, func() (interface{}, error) {
+		return c.next.GetProgramAccountsWithOpts(ctx, program, opts)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) GetTokenAccountsByOwner(ctx context.Context, owner solana.PublicKey, conf *rpc.GetTokenAccountsConfig, opts *rpc.GetTokenAccountsOpts) (out *rpc.GetTokenAccountsResult, err error) {
+	err = c.call(fixtureKey("getTokenAccountsByOwner", owner, conf), &out, func() (interface{}, error) {
+		return c.next.GetTokenAccountsByOwner(ctx, owner, conf, opts)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) GetBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (out *rpc.GetBalanceResult, err error) {
+	err = c.call(fixtureKey("getBalance", account, commitment), &out, func() (interface{}, error) {
+		return c.next.GetBalance(ctx, account, commitment)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) GetTokenAccountBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (out *rpc.GetTokenAccountBalanceResult, err error) {
+	err = c.call(fixtureKey("getTokenAccountBalance", account, commitment), &out, func() (interface{}, error) {
+		return c.next.GetTokenAccountBalance(ctx, account, commitment)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) GetTokenSupply(ctx context.Context, mint solana.PublicKey, commitment rpc.CommitmentType) (out *rpc.GetTokenSupplyResult, err error) {
+	err = c.call(fixtureKey("getTokenSupply", mint, commitment), &out, func() (interface{}, error) {
+		return c.next.GetTokenSupply(ctx, mint, commitment)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) GetTokenLargestAccounts(ctx context.Context, mint solana.PublicKey, commitment rpc.CommitmentType) (out *rpc.GetTokenLargestAccountsResult, err error) {
+	err = c.call(fixtureKey("getTokenLargestAccounts", mint, commitment), &out, func() (interface{}, error) {
+		return c.next.GetTokenLargestAccounts(ctx, mint, commitment)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (out uint64, err error) {
+	err = c.call(fixtureKey("getMinimumBalanceForRentExemption", dataSize, commitment), &out, func() (interface{}, error) {
+		return c.next.GetMinimumBalanceForRentExemption(ctx, dataSize, commitment)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (out *rpc.GetLatestBlockhashResult, err error) {
+	err = c.call(fixtureKey("getLatestBlockhash", commitment), &out, func() (interface{}, error) {
+		return c.next.GetLatestBlockhash(ctx, commitment)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) GetSlot(ctx context.Context, commitment rpc.CommitmentType) (out uint64, err error) {
+	err = c.call(fixtureKey("getSlot", commitment), &out, func() (interface{}, error) {
+		return c.next.GetSlot(ctx, commitment)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) GetBlockTime(ctx context.Context, slot uint64) (out *solana.UnixTimeSeconds, err error) {
+	err = c.call(fixtureKey("getBlockTime", slot), &out, func() (interface{}, error) {
+		return c.next.GetBlockTime(ctx, slot)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) GetVersion(ctx context.Context) (out *rpc.GetVersionResult, err error) {
+	err = c.call(fixtureKey("getVersion"), &out, func() (interface{}, error) {
+		return c.next.GetVersion(ctx)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) GetTransaction(ctx context.Context, signature solana.Signature, opts *rpc.GetTransactionOpts) (out *rpc.GetTransactionResult, err error) {
+	err = c.call(fixtureKey("getTransaction", signature), &out, func() (interface{}, error) {
+		return c.next.GetTransaction(ctx, signature, opts)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) GetSignatureStatuses(ctx context.Context, searchHistory bool, signatures ...solana.Signature) (out *rpc.GetSignatureStatusesResult, err error) {
+	err = c.call(fixtureKey("getSignatureStatuses", signatures), &out, func() (interface{}, error) {
+		return c.next.GetSignatureStatuses(ctx, searchHistory, signatures...)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) (out []*rpc.TransactionSignature, err error) {
+	err = c.call(fixtureKey("getSignaturesForAddress", account, opts), &out, func() (interface{}, error) {
+		return c.next.GetSignaturesForAddressWithOpts(ctx, account, opts)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) SendTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (out solana.Signature, err error) {
+	err = c.call(fixtureKey("sendTransaction"), &out, func() (interface{}, error) {
+		return c.next.SendTransactionWithOpts(ctx, tx, opts)
+	})
+	return out, err
+}
+
+func (c *fixtureClient) SimulateTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts *rpc.SimulateTransactionOpts) (out *rpc.SimulateTransactionResponse, err error) {
+	err = c.call(fixtureKey("simulateTransaction"), &out, func() (interface{}, error) {
+		return c.next.SimulateTransactionWithOpts(ctx, tx, opts)
+	})
+	return out, err
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const CLUSTER_ENV_VAR = "SOLANA_CLUSTER"
+
+// Cluster holds the endpoints and program IDs that differ between Solana clusters
+type Cluster struct {
+	Name          string
+	DefaultRPCURL string
+	RaydiumAmmV4  solana.PublicKey
+	Openbook      solana.PublicKey
+	USDCMint      solana.PublicKey
+	PoolFeeWallet solana.PublicKey // receives Raydium's pool creation fee
+}
+
+// Programs without a separate devnet deployment (pump.fun, Meteora DLMM,
+// Phoenix, OpenBook v2, Squads v4) share their mainnet IDs. Raydium is not
+// deployed on testnet, so testnet keeps the mainnet IDs and pool lookups fail.
+var clusters = map[string]Cluster{
+	"mainnet": {
+		Name:          "mainnet",
+		DefaultRPCURL: DEFAULT_RPC_URL,
+		RaydiumAmmV4:  RAYDIUM_AMM_V4,
+		Openbook:      OPENBOOK_PROGRAM,
+		USDCMint:      USDC_MINT,
+		PoolFeeWallet: RAYDIUM_POOL_FEE_WALLET,
+	},
+	"devnet": {
+		Name:          "devnet",
+		DefaultRPCURL: "https://api.devnet.solana.com",
+		RaydiumAmmV4:  solana.MustPublicKeyFromBase58("HWy1jotHpo6UqeQxx49dpYYdQB8wj9Qk9MdxwjLvDHB8"),
+		Openbook:      solana.MustPublicKeyFromBase58("EoTcMgcDRTJVZDMZWBoU6rhYHZfkNTVEAfz3uUJRcYGj"),
+		USDCMint:      solana.MustPublicKeyFromBase58("4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU"),
+		PoolFeeWallet: solana.MustPublicKeyFromBase58("3XMrhbv989VxAMi3DErLV9eJht1pHppW5LbKxe9fkEFR"),
+	},
+	"testnet": {
+		Name:          "testnet",
+		DefaultRPCURL: "https://api.testnet.solana.com",
+		RaydiumAmmV4:  RAYDIUM_AMM_V4,
+		Openbook:      OPENBOOK_PROGRAM,
+		USDCMint:      USDC_MINT,
+		PoolFeeWallet: RAYDIUM_POOL_FEE_WALLET,
+	},
+}
+
+// activeCluster is the cluster selected with -cluster or SOLANA_CLUSTER
+var activeCluster = clusters["mainnet"]
+
+// selectCluster switches program IDs, the default RPC and explorer links to the
+// named cluster. "custom" keeps mainnet program IDs and requires SOLANA_RPC_URL,
+// for local validators and forks.
+func selectCluster(name string) error {
+	if name == "" {
+		name = "mainnet"
+	}
+
+	cluster, ok := clusters[name]
+	if !ok {
+		if name != "custom" {
+			return fmt.Errorf("unknown cluster %q (supported: mainnet, devnet, testnet, custom)", name)
+		}
+		if os.Getenv(RPC_URL_ENV_VAR) == "" {
+			return fmt.Errorf("-cluster custom requires %s", RPC_URL_ENV_VAR)
+		}
+		cluster = clusters["mainnet"]
+		cluster.Name = "custom"
+	}
+
+	activeCluster = cluster
+	RAYDIUM_AMM_V4 = cluster.RaydiumAmmV4
+	OPENBOOK_PROGRAM = cluster.Openbook
+	USDC_MINT = cluster.USDCMint
+	RAYDIUM_POOL_FEE_WALLET = cluster.PoolFeeWallet
+	return nil
+}
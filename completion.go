@@ -0,0 +1,363 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	COMPLETE_COMMAND     = "__complete" // hidden command the completion scripts call
+	DEFAULT_PROGRAM_NAME = "awesomeProject"
+)
+
+// commandHelp documents a command for its --help output and shell completion
+type commandHelp struct {
+	Name     string // with its subcommand, e.g. "pool create"
+	Summary  string
+	Flags    bool // parses its arguments with a flag set
+	Examples []string
+}
+
+// COMMAND_HELP lists every command; the swap mode's examples come first
+var COMMAND_HELP = []commandHelp{
+	{Name: "", Summary: "Quote or execute a Raydium swap", Flags: true, Examples: []string{
+		"go run . -pool <POOL> -amount 0.1 -side buy -dry-run",
+		"go run . -pool <POOL> -amount 0.1 -side buy -slippage auto -execute",
+		"go run . -token <TOKEN> -amount 0.1 -side buy -execute -sender jito -tip 0.0005",
+		"go run . -pool <POOL> -amount 0.1 -side buy -slippage 1 -solana-pay https://<TUNNEL_HOST>",
+	}},
+	{Name: "doctor", Summary: "Validate the local configuration", Flags: true, Examples: []string{"go run . doctor"}},
+	{Name: "broadcast", Summary: "Send an externally signed transaction", Flags: true, Examples: []string{"go run . broadcast -file swap.signed.tx"}},
+	{Name: "watch", Summary: "Stream price, reserves and depth of a pool", Flags: true, Examples: []string{
+		"go run . watch -pool <POOL>",
+		`go run . watch -pool <POOL> -trigger "price < 0.9*ema(5m) && liquidity_sol > 50"`,
+	}},
+	{Name: "lp add", Summary: "Deposit liquidity into a pool", Flags: true, Examples: []string{"go run . lp add -pool <POOL> -amount 1 -side base -slippage 1"}},
+	{Name: "lp remove", Summary: "Withdraw liquidity from a pool", Flags: true, Examples: []string{"go run . lp remove -pool <POOL> -percent 50"}},
+	{Name: "grpc", Summary: "Serve the gRPC API", Flags: true, Examples: []string{
+		"go run . grpc -addr 127.0.0.1:50051",
+		"go run . grpc -bridge",
+		"API_CLIENT_KEYS=bot:secret go run . grpc -signed-quotes",
+	}},
+	{Name: "bot", Summary: "Serve quotes and swaps over Telegram", Flags: true, Examples: []string{"go run . bot -allowed-chats 123456789 -max-trade 1 -daily-limit 5"}},
+	{Name: "e2e accounts", Summary: "List the accounts a test validator clones", Flags: true, Examples: []string{"go run . e2e accounts -pool <POOL>"}},
+	{Name: "e2e run", Summary: "Run a round-trip swap against a test validator", Flags: true, Examples: []string{"SOLANA_CLUSTER=custom go run . e2e run"}},
+	{Name: "portfolio", Summary: "List wallet balances and their value", Flags: true, Examples: []string{
+		"go run . portfolio -address <WALLET> -currency usd",
+		"go run . portfolio -no-price",
+	}},
+	{Name: "daemon", Summary: "Serve low-latency quotes and swaps from stdin", Flags: true, Examples: []string{"go run . daemon -pools <POOL>,<POOL> -candles", "go run . daemon -jobs", "go run . daemon -watchlist -watchlist-file watchlist.yaml"}},
+	{Name: "template nonce", Summary: "Create a durable nonce account"},
+	{Name: "template build", Summary: "Pre-sign a swap template on a durable nonce", Flags: true, Examples: []string{"go run . template build -pool <POOL> -side buy -nonce <NONCE_ACCOUNT> -out snipe.json"}},
+	{Name: "template fire", Summary: "Send a swap template", Flags: true, Examples: []string{"go run . template fire -file snipe.json -amount 0.5 -min-out 12000"}},
+	{Name: "limits", Summary: "Show the spend limits"},
+	{Name: "limits set", Summary: "Update the spend limits", Flags: true, Examples: []string{
+		"go run . limits set -max-trade 1 -max-trades-per-hour 10 -confirm-above 0.5",
+		"go run . limits set -max-token-pct 25 -max-young-pct 10 -young-days 7 -max-daily-loss 2",
+	}},
+	{Name: "limits positions", Summary: "List the ledger's positions and the daily loss circuit breaker", Flags: true, Examples: []string{"go run . limits positions"}},
+	{Name: "tx status", Summary: "Check pending or given transactions"},
+	{Name: "tx report", Summary: "Rebuild the swap report of past transactions", Flags: true, Examples: []string{"go run . tx report -json <SIGNATURE>", "HELIUS_API_KEY=<KEY> go run . tx report <SIGNATURE>"}},
+	{Name: "copy", Summary: "Mirror a wallet's Raydium swaps", Flags: true, Examples: []string{"go run . copy -follow <WALLET> -execute -slippage 2"}},
+	{Name: "depth", Summary: "Print a quote ladder and depth curve", Flags: true, Examples: []string{"go run . depth -token <TOKEN> -sizes 0.5,2,20 -chart"}},
+	{Name: "price history", Summary: "Backfill OHLCV candles from on-chain swaps", Flags: true, Examples: []string{"go run . price history -pool <POOL> -hours 24 -interval 15m > candles.csv"}},
+	{Name: "candles", Summary: "Print candles recorded by the daemon", Flags: true, Examples: []string{"go run . candles -pool <POOL> -interval 5m -hours 6 -format json"}},
+	{Name: "lookup-table create", Summary: "Create an address lookup table", Flags: true, Examples: []string{"go run . lookup-table create"}},
+	{Name: "lookup-table extend", Summary: "Add a pool's accounts to a lookup table", Flags: true, Examples: []string{"go run . lookup-table extend -table <TABLE> -pool <POOL>"}},
+	{Name: "lookup-table show", Summary: "Print a lookup table's addresses", Flags: true, Examples: []string{"go run . lookup-table show -table <TABLE>"}},
+	{Name: "pool create", Summary: "Create a Raydium V4 pool and its market", Flags: true, Examples: []string{"go run . pool create -base <MINT> -base-amount 1000000 -quote-amount 10 -execute"}},
+	{Name: "pool audit", Summary: "Score a pool's rug risk", Flags: true, Examples: []string{"go run . pool audit -pool <POOL> -json"}},
+	{Name: "pool stats", Summary: "Show a pool's 24h volume, fees and TVL", Flags: true, Examples: []string{"go run . pool stats -pool <POOL>", "go run . pools stats -pool <POOL> -hours 6 -json"}},
+	{Name: "launch", Summary: "Create a token and its seeded pool", Flags: true, Examples: []string{
+		`go run . launch -name "My Token" -symbol MYT -uri https://example.com/myt.json -supply 1000000000 -sol 10 -dry-run`,
+	}},
+	{Name: "token holders", Summary: "Report a token's holder concentration", Flags: true, Examples: []string{"go run . token holders -mint <MINT> -team <WALLET>"}},
+	{Name: "atas clean", Summary: "Close empty or dust token accounts to reclaim rent", Flags: true, Examples: []string{
+		"go run . atas clean",
+		"go run . atas clean -dust 0.0005 -execute",
+	}},
+	{Name: "approve", Summary: "Let a delegate key swap up to an allowance of a token", Flags: true, Examples: []string{
+		"go run . approve -mint <MINT> -delegate <KEY> -amount 5000 -execute",
+		"go run . approve -mint sol -delegate <KEY> -amount 2 -execute",
+	}},
+	{Name: "revoke", Summary: "Remove the delegate of a token account", Flags: true, Examples: []string{"go run . revoke -mint <MINT> -execute"}},
+	{Name: "backtest", Summary: "Replay a DCA, TWAP, limit or stop strategy against historical candles", Flags: true, Examples: []string{
+		"go run . backtest -pool <POOL> -interval 1h -strategy dca -amount 0.1 -every 4h -orders 12",
+		"go run . backtest -file candles.csv -strategy limit -side sell -amount 5000 -limit 0.00002 -liquidity 300",
+	}},
+	{Name: "alert add", Summary: "Add a price or liquidity alert on a pool", Flags: true, Examples: []string{
+		`go run . alert add -pool <POOL> -when "price < 0.5 || liquidity_drop > 30%" -telegram-chat 123456789`,
+		`go run . alert add -pool <POOL> -when "liquidity_drop > 50%" -webhook https://example.com/hook -cooldown 1h`,
+	}},
+	{Name: "alert list", Summary: "List the alerts"},
+	{Name: "alert remove", Summary: "Remove an alert", Flags: true, Examples: []string{"go run . alert remove -id 2"}},
+	{Name: "alert monitor", Summary: "Evaluate the alerts on every pool update and notify", Flags: true, Examples: []string{"TELEGRAM_BOT_TOKEN=<TOKEN> go run . alert monitor -window 30m"}},
+	{Name: "rug-guard", Summary: "Show the rug guard settings"},
+	{Name: "rug-guard set", Summary: "Set what the rug guard does for all tokens or one", Flags: true, Examples: []string{
+		"go run . rug-guard set -lp-drop 40",
+		"go run . rug-guard set -mint <MINT> -auto-sell true -sell-pct 100 -compute-price 2000000",
+	}},
+	{Name: "rug-guard monitor", Summary: "Watch held tokens for LP withdrawals, authority changes and freezes", Flags: true, Examples: []string{"go run . rug-guard monitor -webhook https://example.com/hook"}},
+	{Name: "receipts list", Summary: "List the signed receipts of executed swaps"},
+	{Name: "receipts show", Summary: "Print a signed receipt as JSON", Examples: []string{"go run . receipts show <SIGNATURE> > receipt.json"}},
+	{Name: "receipts verify", Summary: "Check the signatures of receipts", Flags: true, Examples: []string{"go run . receipts verify -file receipt.json -signer <WALLET>"}},
+	{Name: "size suggest", Summary: "Suggest a trade size from the pool's depth and recorded volatility", Flags: true, Examples: []string{
+		"go run . size suggest -token <TOKEN> -max-impact 1%",
+		"go run . size suggest -pool <POOL> -max-impact 0.5% -risk 0.2 -horizon 4h",
+	}},
+	{Name: "jobs add", Summary: "Queue a limit, DCA, TWAP or stop order for the daemon", Flags: true, Examples: []string{
+		"go run . jobs add -pool <POOL> -strategy dca -side buy -amount 0.1 -every 1h -orders 24",
+		"go run . jobs add -pool <POOL> -strategy stop -side sell -amount 1000 -stop 0.00001",
+	}},
+	{Name: "jobs list", Summary: "List the queued jobs and their progress", Flags: true},
+	{Name: "jobs cancel", Summary: "Cancel a job", Flags: true, Examples: []string{"go run . jobs cancel -id 3"}},
+	{Name: "jobs resume", Summary: "Resume an interrupted or failed job", Flags: true, Examples: []string{"go run . jobs resume -id 3"}},
+	{Name: "watchlist status", Summary: "Show what the daemon reports about each watched token", Flags: true},
+	{Name: "watchlist check", Summary: "Validate a watchlist file", Flags: true, Examples: []string{"go run . watchlist check -file watchlist.yaml"}},
+	{Name: "retry list", Summary: "List the swaps that failed to send or land", Flags: true},
+	{Name: "retry", Summary: "Re-quote and resend a failed swap with a higher priority fee", Flags: true, Examples: []string{"go run . retry 4", "go run . retry 4 -slippage 2 -cu-price 50000"}},
+	{Name: "overrides list", Summary: "Check and list per-token and per-pool trade settings", Flags: true, Examples: []string{"go run . overrides list"}},
+	{Name: "overrides show", Summary: "Show the settings a token or pool trades with", Flags: true, Examples: []string{"go run . overrides show -token <TOKEN> -pool <POOL>"}},
+	{Name: "bracket", Summary: "Buy with take-profit and stop-loss exits run by the daemon", Flags: true, Examples: []string{"go run . bracket -pool <POOL> -amount 0.5 -take-profit 50 -stop-loss 20"}},
+	{Name: "ledger index", Summary: "Index the wallet's transactions into the ledger", Flags: true, Examples: []string{
+		"go run . ledger index",
+		"go run . ledger index -follow -interval 1m",
+	}},
+	{Name: "ledger list", Summary: "List the wallet's ledger entries", Flags: true, Examples: []string{"go run . ledger list -mint <MINT>"}},
+	{Name: "completion", Summary: "Print a shell completion script", Flags: true, Examples: []string{
+		"source <(go run . completion bash)",
+		"go run . completion fish > ~/.config/fish/completions/" + DEFAULT_PROGRAM_NAME + ".fish",
+	}},
+}
+
+// Flags whose values complete to addresses seen in local state
+var (
+	POOL_FLAGS = []string{"-pool"}
+	MINT_FLAGS = []string{"-token", "-mint", "-base", "-quote"}
+)
+
+// The partial flag being completed, if any: usage then prints the matching
+// flag names alone
+var completingFlag string
+
+// findCommandHelp returns the help of a command, or nil if it has none
+func findCommandHelp(name string) *commandHelp {
+	for i := range COMMAND_HELP {
+		if COMMAND_HELP[i].Name == name {
+			return &COMMAND_HELP[i]
+		}
+	}
+	return nil
+}
+
+// newCommandFlagSet creates a command's flag set, whose -h/--help output
+// ends with the command's examples
+func newCommandFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() { printCommandUsage(fs, name) }
+	return fs
+}
+
+// printCommandUsage prints a command's summary, flags and examples
+func printCommandUsage(fs *flag.FlagSet, name string) {
+	if completingFlag != "" {
+		fs.VisitAll(func(f *flag.Flag) {
+			if strings.HasPrefix("-"+f.Name, completingFlag) {
+				fmt.Println("-" + f.Name)
+			}
+		})
+		return
+	}
+	out := fs.Output()
+	help := findCommandHelp(name)
+	if name == "" {
+		fmt.Fprintln(out, "Usage: go run . [flags] | go run . COMMAND [flags]")
+	} else {
+		fmt.Fprintf(out, "Usage: go run . %s [flags]\n", name)
+	}
+	if help != nil {
+		fmt.Fprintf(out, "%s\n", help.Summary)
+	}
+	fmt.Fprintln(out, "\nFlags:")
+	fs.PrintDefaults()
+	if name == "" {
+		fmt.Fprintln(out, "\nCommands:")
+		for _, command := range COMMAND_HELP[1:] {
+			fmt.Fprintf(out, "  %-20s %s\n", command.Name, command.Summary)
+		}
+	}
+	if help != nil && len(help.Examples) > 0 {
+		fmt.Fprintln(out, "\nExamples:")
+		for _, example := range help.Examples {
+			fmt.Fprintf(out, "  %s\n", example)
+		}
+	}
+}
+
+// commandWords returns the commands, or the subcommands of parent, in help order
+func commandWords(parent string) []string {
+	var words []string
+	for _, command := range COMMAND_HELP[1:] {
+		name := command.Name
+		if parent != "" {
+			if !strings.HasPrefix(name, parent+" ") {
+				continue
+			}
+			name = strings.TrimPrefix(name, parent+" ")
+		}
+		word, _, _ := strings.Cut(name, " ")
+		if !slices.Contains(words, word) {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// cachedAddresses returns pools and mints seen in sessions and recorded candles
+func cachedAddresses() (pools []string, mints []string) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, nil
+	}
+	add := func(list []string, address string) []string {
+		if address == "" || slices.Contains(list, address) {
+			return list
+		}
+		return append(list, address)
+	}
+
+	candleFiles, _ := filepath.Glob(filepath.Join(dir, CANDLES_DIR, "*.lp"))
+	for _, file := range candleFiles {
+		pools = add(pools, strings.TrimSuffix(filepath.Base(file), ".lp"))
+	}
+	sessionFiles, _ := filepath.Glob(filepath.Join(dir, SESSIONS_DIR, "*.json"))
+	for _, file := range sessionFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var session Session
+		if json.Unmarshal(data, &session) != nil {
+			continue
+		}
+		pools = add(pools, session.Pool)
+		for _, fill := range session.Fills {
+			pools = add(pools, fill.PoolAddress)
+			mints = add(mints, fill.TokenMint)
+		}
+	}
+	return pools, mints
+}
+
+// runComplete prints the completions of the last word, given the words typed
+// after the program name. It returns true when the swap mode's own flags are
+// wanted, which main lists through its flag set's usage.
+func runComplete(words []string) bool {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	typed, current := words[:len(words)-1], words[len(words)-1]
+	suggest := func(candidates []string) {
+		for _, candidate := range candidates {
+			if strings.HasPrefix(candidate, current) {
+				fmt.Println(candidate)
+			}
+		}
+	}
+
+	if len(typed) > 0 {
+		previous := typed[len(typed)-1]
+		pools, mints := cachedAddresses()
+		switch {
+		case slices.Contains(POOL_FLAGS, previous):
+			suggest(pools)
+			return false
+		case slices.Contains(MINT_FLAGS, previous):
+			suggest(mints)
+			return false
+		}
+	}
+
+	// The command is the leading words that are not flags
+	var path []string
+	for _, word := range typed {
+		if strings.HasPrefix(word, "-") {
+			break
+		}
+		path = append(path, word)
+	}
+	name := strings.Join(path, " ")
+
+	if strings.HasPrefix(current, "-") {
+		completingFlag = current
+		if name == "" {
+			return true
+		}
+		if help := findCommandHelp(name); help != nil && help.Flags {
+			// Flag sets exit once -h has listed their flags
+			runCommand(path[0], append(path[1:], "-h"), BuildOptions{})
+		}
+		return false
+	}
+	if len(path) == len(typed) && len(path) < 2 {
+		switch {
+		case len(path) == 0:
+			suggest(commandWords(""))
+		case name == "completion":
+			suggest([]string{"bash", "zsh", "fish"})
+		default:
+			suggest(commandWords(name))
+		}
+	}
+	return false
+}
+
+// completionScript returns the completion script of a shell for program
+func completionScript(shell, program string) (string, error) {
+	function := "_" + regexp.MustCompile(`\W`).ReplaceAllString(program, "_")
+	switch shell {
+	case "bash":
+		return fmt.Sprintf(`%[1]s() {
+	local IFS=$'\n'
+	COMPREPLY=($(%[2]s %[3]s "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F %[1]s %[2]s
+`, function, program, COMPLETE_COMMAND), nil
+	case "zsh":
+		return fmt.Sprintf(`#compdef %[2]s
+%[1]s() {
+	local -a candidates
+	candidates=("${(@f)$(%[2]s %[3]s "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	compadd -a candidates
+}
+compdef %[1]s %[2]s
+`, function, program, COMPLETE_COMMAND), nil
+	case "fish":
+		return fmt.Sprintf("complete -c %[1]s -f -a '(%[1]s %[2]s (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)'\n",
+			program, COMPLETE_COMMAND), nil
+	}
+	return "", fmt.Errorf("unsupported shell %q (supported: bash, zsh, fish)", shell)
+}
+
+// runCompletion prints the completion script of a shell
+func runCompletion(args []string) {
+	fs := newCommandFlagSet("completion")
+	program := fs.String("name", DEFAULT_PROGRAM_NAME, "Name of the installed binary the script completes")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal("Usage: go run . completion [-name BINARY] bash|zsh|fish")
+	}
+
+	script, err := completionScript(fs.Arg(0), *program)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(script)
+}
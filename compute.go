@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	DEFAULT_CU_MARGIN      = 10 // percent added to the simulated usage
+	MAX_COMPUTE_UNIT_LIMIT = 1_400_000
+)
+
+// ComputeLimitConfig controls the compute unit limit and price set on swaps.
+// Priority fees are charged per requested unit, so a limit close to actual
+// usage is cheaper than the 200k-per-instruction default.
+type ComputeLimitConfig struct {
+	Auto      bool    // simulate and set the limit to usage plus MarginPct
+	MarginPct float64 // percent
+	Price     uint64  // micro-lamports per unit, 0 for no priority fee
+}
+
+// Validate checks the configuration is within sane bounds
+func (c ComputeLimitConfig) Validate() error {
+	if c.MarginPct < 0 {
+		return fmt.Errorf("compute unit margin must not be negative")
+	}
+	return nil
+}
+
+// computePriceInstruction returns the compute price instruction, or nil
+// without a price
+func (c ComputeLimitConfig) computePriceInstruction() solana.Instruction {
+	if c.Price == 0 {
+		return nil
+	}
+	return computebudget.NewSetComputeUnitPriceInstruction(c.Price).Build()
+}
+
+// withReference adds a read-only reference account to the compute price
+// instruction, which ignores its accounts, so the transaction can be found by
+// that account. Raydium's swap refuses extra accounts. Without a price, a zero
+// price instruction carries it.
+func withReference(priceIx solana.Instruction, reference solana.PublicKey) (solana.Instruction, error) {
+	if priceIx == nil {
+		priceIx = computebudget.NewSetComputeUnitPriceInstruction(0).Build()
+	}
+	data, err := priceIx.Data()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode compute price instruction: %w", err)
+	}
+	accounts := append(priceIx.Accounts(), solana.Meta(reference))
+	return solana.NewInstruction(priceIx.ProgramID(), accounts, data), nil
+}
+
+// estimateComputeUnits simulates the instructions under the maximum limit
+// and returns the units they consumed. Signatures are not verified and the
+// blockhash is replaced, so unsigned transactions can be estimated.
+func estimateComputeUnits(ctx context.Context, client ChainClient, instructions []solana.Instruction, blockhash solana.Hash, payer solana.PublicKey) (uint64, error) {
+	limit := computebudget.NewSetComputeUnitLimitInstruction(MAX_COMPUTE_UNIT_LIMIT).Build()
+	tx, err := solana.NewTransaction(append([]solana.Instruction{limit}, instructions...), blockhash, solana.TransactionPayer(payer))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	sim, err := client.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		SigVerify:              false,
+		Commitment:             rpc.CommitmentConfirmed,
+		ReplaceRecentBlockhash: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to simulate transaction: %w", err)
+	}
+	if sim.Value.Err != nil {
+		return 0, fmt.Errorf("simulation failed: %v", sim.Value.Err)
+	}
+	if sim.Value.UnitsConsumed == nil {
+		return 0, fmt.Errorf("simulation did not report compute units")
+	}
+	return *sim.Value.UnitsConsumed, nil
+}
+
+// withComputeLimit prepends a compute unit limit of the simulated usage plus
+// the margin. When the estimate fails the instructions are returned
+// unchanged and the send-time preflight reports any error.
+func (c ComputeLimitConfig) withComputeLimit(ctx context.Context, client ChainClient, instructions []solana.Instruction, blockhash solana.Hash, payer solana.PublicKey) []solana.Instruction {
+	if !c.Auto {
+		return instructions
+	}
+	used, err := estimateComputeUnits(ctx, client, instructions, blockhash, payer)
+	if err != nil {
+		fmt.Printf("Warning: Could not estimate compute units, using the default limit: %v\n", err)
+		return instructions
+	}
+
+	limit := uint32(min(math.Ceil(float64(used)*(1+c.MarginPct/100)), MAX_COMPUTE_UNIT_LIMIT))
+	if rpcPolicy.Verbose {
+		fmt.Printf("Compute units: %d simulated, limit set to %d (+%.0f%%)\n", used, limit, c.MarginPct)
+	}
+	return append([]solana.Instruction{computebudget.NewSetComputeUnitLimitInstruction(limit).Build()}, instructions...)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+)
+
+func TestWithReference(t *testing.T) {
+	reference := solana.NewWallet().PublicKey()
+	tests := []struct {
+		name    string
+		priceIx solana.Instruction
+		price   uint64
+	}{
+		{"without a compute price", nil, 0},
+		{"with a compute price", computebudget.NewSetComputeUnitPriceInstruction(5000).Build(), 5000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ix, err := withReference(tt.priceIx, reference)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ix.ProgramID().Equals(computebudget.ProgramID) {
+				t.Errorf("got program %s, want the compute budget program", ix.ProgramID())
+			}
+			accounts := ix.Accounts()
+			if len(accounts) != 1 || !accounts[0].PublicKey.Equals(reference) {
+				t.Fatalf("got accounts %v, want only the reference", accounts)
+			}
+			if accounts[0].IsSigner || accounts[0].IsWritable {
+				t.Errorf("reference is signer=%v writable=%v, want read-only", accounts[0].IsSigner, accounts[0].IsWritable)
+			}
+
+			data, err := ix.Data()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := computebudget.NewSetComputeUnitPriceInstruction(tt.price).Build().Data()
+			if !bytes.Equal(data, want) {
+				t.Errorf("got data %x, want a compute price of %d", data, tt.price)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Environment variables controlling endpoints and local state
+const (
+	RPC_URL_ENV_VAR   = "SOLANA_RPC_URL"
+	WS_URL_ENV_VAR    = "SOLANA_WS_URL"
+	STATE_DIR_ENV_VAR = "RAYDIUM_CLI_HOME"
+	STATE_DIR_NAME    = "raydium-cli"
+	DEFAULT_RPC_URL   = "https://mainnet.helius-rpc.com/?api-key=4a5313a6-8380-4882-ad4e-e745ec00d629"
+)
+
+// resolveRPCURL returns the RPC endpoint from the environment or the active cluster's default
+func resolveRPCURL() string {
+	rpcURL := os.Getenv(RPC_URL_ENV_VAR)
+	if rpcURL == "" {
+		rpcURL = activeCluster.DefaultRPCURL
+	}
+	return rpcURL
+}
+
+// resolveWSURL returns the websocket endpoint from the environment, or derives
+// it from the RPC endpoint by switching the scheme
+func resolveWSURL() string {
+	if wsURL := os.Getenv(WS_URL_ENV_VAR); wsURL != "" {
+		return wsURL
+	}
+
+	rpcURL := resolveRPCURL()
+	switch {
+	case strings.HasPrefix(rpcURL, "https://"):
+		return "wss://" + strings.TrimPrefix(rpcURL, "https://")
+	case strings.HasPrefix(rpcURL, "http://"):
+		return "ws://" + strings.TrimPrefix(rpcURL, "http://")
+	}
+	return rpcURL
+}
+
+// stateDir returns the directory used for local state (history, limits, caches),
+// creating it if needed
+func stateDir() (string, error) {
+	dir := os.Getenv(STATE_DIR_ENV_VAR)
+	if dir == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to locate user config directory: %w", err)
+		}
+		dir = filepath.Join(configDir, STATE_DIR_NAME)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create state directory %s: %w", dir, err)
+	}
+
+	return dir, nil
+}
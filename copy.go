@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"awesomeProject/internal/atoms"
+	"awesomeProject/internal/raylog"
+)
+
+// CopyConfig controls how a followed wallet's swaps are mirrored
+type CopyConfig struct {
+	Target   solana.PublicKey
+	Ratio    float64  // fixed fraction of the target's trade size; 0 mirrors the share of balance they traded
+	Slippage float64  // percent
+	Tokens   []string // mints to copy; empty copies every token
+	Execute  bool
+}
+
+// CopiedSwap is a Raydium swap signed by the followed wallet
+type CopiedSwap struct {
+	Signature solana.Signature
+	Pool      *OnChainPool
+	Side      string
+	TokenMint solana.PublicKey
+	AmountIn  float64
+	Spent     uint64 // raw input the swap spent
+	Source    uint64 // raw input balance the target held before the swap
+}
+
+// Share returns the share of the target's input balance the swap spent
+func (s *CopiedSwap) Share() float64 {
+	if s.Source == 0 {
+		return 0
+	}
+	return min(float64(s.Spent)/float64(s.Source), 1)
+}
+
+// decodeCopiedSwap loads a transaction mentioning the target and decodes the
+// Raydium swap in it. It returns nil for transactions the target did not
+// sign or that failed.
+func decodeCopiedSwap(ctx context.Context, client ChainClient, target solana.PublicKey, sig solana.Signature) (*CopiedSwap, error) {
+	tx, err := fetchTransaction(ctx, client, sig.String())
+	if err != nil {
+		return nil, err
+	}
+	if tx.Meta.Err != nil {
+		return nil, nil
+	}
+	parsed, err := tx.Transaction.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+
+	signers := parsed.Message.AccountKeys[:parsed.Message.Header.NumRequiredSignatures]
+	signerIndex := slices.IndexFunc(signers, target.Equals)
+	if signerIndex < 0 {
+		return nil, nil
+	}
+
+	poolAddress, err := findRaydiumSwapPool(parsed, tx.Meta)
+	if err != nil {
+		return nil, err
+	}
+	swapLog, err := raylog.FindSwap(tx.Meta.LogMessages)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := loadPool(ctx, client, poolAddress.String())
+	if err != nil {
+		return nil, err
+	}
+
+	swap := &CopiedSwap{
+		Signature: sig,
+		Pool:      pool,
+		Side:      swapSide(pool, swapLog),
+		TokenMint: pool.BaseMint,
+	}
+	if pool.BaseMint.Equals(WSOL_MINT) || pool.BaseMint.Equals(SOL_MINT) {
+		swap.TokenMint = pool.QuoteMint
+	}
+
+	inDecimals := int(pool.QuoteDecimals)
+	if swapLog.BaseIn() {
+		inDecimals = int(pool.BaseDecimals)
+	}
+	swap.AmountIn = atoms.ToAmount(swapLog.AmountIn, inDecimals)
+
+	// Sells spend part of the token position the log reports; buys spend part
+	// of the SOL the target held before the transaction
+	swap.Spent, swap.Source = swapLog.AmountIn, swapLog.UserSource
+	if swap.Side == "buy" && signerIndex < len(tx.Meta.PreBalances) {
+		swap.Source = tx.Meta.PreBalances[signerIndex]
+	}
+	return swap, nil
+}
+
+// mirrorAmount sizes the local trade: a fixed ratio of the target's size, or
+// the same share of the local wallet's balance the target spent
+func mirrorAmount(ctx context.Context, client ChainClient, wallet solana.PublicKey, cfg CopyConfig, swap *CopiedSwap) (float64, error) {
+	if cfg.Ratio > 0 {
+		return swap.AmountIn * cfg.Ratio, nil
+	}
+	balance, decimals, err := inputBalance(ctx, client, swap.Side, swap.TokenMint.String(), swap.Pool.Address.String(), wallet)
+	if err != nil {
+		return 0, err
+	}
+	return atoms.ToAmount(atoms.MulDiv(balance, min(swap.Spent, swap.Source), swap.Source, false), decimals), nil
+}
+
+// mirrorSwap quotes and, with Execute set, sends the local copy of a swap.
+// Spend limits apply without confirmation prompts, so trades above the
+// confirmation threshold are refused.
+func mirrorSwap(ctx context.Context, client ChainClient, wallet solana.PrivateKey, guard *SpendGuard, cfg CopyConfig, swap *CopiedSwap, opts BuildOptions) error {
+	if len(cfg.Tokens) > 0 && !slices.Contains(cfg.Tokens, swap.TokenMint.String()) {
+		fmt.Printf("Skipping: %s is not in -tokens\n", swap.TokenMint)
+		return nil
+	}
+
+	amount, err := mirrorAmount(ctx, client, wallet.PublicKey(), cfg, swap)
+	if err != nil {
+		return err
+	}
+	if amount < MIN_SWAP_AMOUNT {
+		fmt.Printf("Skipping: mirrored amount %.9f is below the %.3f minimum\n", amount, MIN_SWAP_AMOUNT)
+		return nil
+	}
+
+	quote, err := resolveSwapQuote(ctx, client, QuoteParams{
+		PoolAddress: swap.Pool.Address.String(),
+		Side:        swap.Side,
+		Amount:      amount,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Mirror: %s %.9f %s -> expected %.9f %s\n", swap.Side, amount,
+		tokenLabel(getInputToken(swap.Side), quote.TokenSymbol), quote.ExpectedOut, tokenLabel(getOutputToken(swap.Side), quote.TokenSymbol))
+	if !cfg.Execute {
+		return nil
+	}
+
+	if err := guard.Enforce(ctx, quote.GuardedTrade(opts.Burn), false); err != nil {
+		return fmt.Errorf("spend limits: %w", err)
+	}
+	defer guard.Release(quote.GuardedTrade(opts.Burn))
+	txHash, err := executeSwap(ctx, client, wallet, quote.PoolAddress, swap.Side, amount, quote.MinAmountOut(cfg.Slippage), opts)
+	if err != nil {
+		return err
+	}
+	if err := guard.Record(quote.GuardedTrade(opts.Burn)); err != nil {
+		fmt.Printf("Warning: Could not record trade for spend limits: %v\n", err)
+	}
+	fmt.Printf("✅ Mirrored: %s\n", explorerTxURL(txHash))
+	return nil
+}
+
+// runCopy follows a wallet's transactions over the websocket and mirrors the
+// Raydium swaps it signs
+func runCopy(args []string, opts BuildOptions) {
+	fs := newCommandFlagSet("copy")
+	var follow string
+	var tokens string
+	var override bool
+	cfg := CopyConfig{Slippage: DEFAULT_SLIPPAGE}
+	fs.StringVar(&follow, "follow", "", "Wallet whose Raydium swaps are mirrored")
+	fs.Float64Var(&cfg.Ratio, "ratio", 0, "Trade this fraction of the target's size instead of the same share of balance")
+	fs.Float64Var(&cfg.Slippage, "slippage", cfg.Slippage, "Slippage tolerance in percent for mirrored swaps")
+	fs.StringVar(&tokens, "tokens", "", "Comma-separated mints to copy (default: all)")
+	fs.BoolVar(&cfg.Execute, "execute", false, "Send the mirrored swaps; without it they are only quoted")
+	fs.BoolVar(&override, "override", false, "Mirror trades even when the wallet's spend limits would refuse them")
+	fs.Parse(args)
+
+	if follow == "" {
+		fmt.Println("Usage: go run . copy -follow WALLET [-ratio R] [-slippage PCT] [-tokens MINT,...] [-execute]")
+		fs.PrintDefaults()
+		return
+	}
+
+	var err error
+	if cfg.Target, err = solana.PublicKeyFromBase58(follow); err != nil {
+		log.Fatalf("Invalid -follow wallet: %v", err)
+	}
+	if tokens != "" {
+		if cfg.Tokens, err = parseMintList(tokens); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if cfg.Ratio < 0 {
+		log.Fatal("-ratio must not be negative")
+	}
+	if cfg.Slippage <= 0 || cfg.Slippage > MAX_SLIPPAGE {
+		log.Fatalf("Slippage must be between 0 and %.0f", MAX_SLIPPAGE)
+	}
+
+	// Proportional sizing needs the local balances even when only quoting
+	wallet, err := loadWallet()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if wallet.PublicKey().Equals(cfg.Target) {
+		log.Fatal("Cannot follow the local wallet")
+	}
+	client := newChainClient()
+	var guard *SpendGuard
+	if cfg.Execute {
+		if guard, err = loadSpendGuard(client, wallet.PublicKey()); err != nil {
+			log.Fatalf("Failed to load spend limits: %v", err)
+		}
+		guard.Override = override
+	}
+
+	ctx := interruptContext()
+
+	mux := newUpdateStream(resolveWSURL())
+	updates := mux.SubscribeLogs(cfg.Target)
+	go func() {
+		if err := mux.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Fatalf("Websocket stream failed: %v", err)
+		}
+	}()
+
+	fmt.Printf("Following %s (Ctrl-C to stop)...\n", cfg.Target)
+	if !cfg.Execute {
+		fmt.Println("Quoting only; pass -execute to send mirrored swaps")
+	}
+
+	// Reconnects can redeliver notifications
+	seen := make(map[solana.Signature]bool)
+	for {
+		var u StreamUpdate
+		select {
+		case <-ctx.Done():
+			return
+		case u = <-updates:
+		}
+		if u.Failed || seen[u.Signature] {
+			continue
+		}
+		seen[u.Signature] = true
+		// The logs show a swap before the transaction is fetched
+		if _, err := raylog.FindSwap(u.Logs); err != nil {
+			continue
+		}
+
+		swap, err := decodeCopiedSwap(ctx, client, cfg.Target, u.Signature)
+		if err != nil {
+			fmt.Printf("[%s] Skipping %s: %v\n", time.Now().Format(time.TimeOnly), u.Signature, err)
+			continue
+		}
+		if swap == nil {
+			continue
+		}
+		fmt.Printf("\n[%s] Target %s %.9f %s in pool %s (%.1f%% of balance)\n", time.Now().Format(time.TimeOnly),
+			swap.Side, swap.AmountIn, getInputToken(swap.Side), swap.Pool.Address, swap.Share()*100)
+		if err := mirrorSwap(ctx, client, wallet, guard, cfg, swap, opts); err != nil {
+			fmt.Printf("Mirror failed: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+const (
+	// DEAD_LETTERS_FILE in the state directory holds the swaps that failed to send or land
+	DEAD_LETTERS_FILE = "dead_letters.json"
+
+	// A retry pays at least twice the failed swap's compute unit price, and
+	// at least RETRY_MIN_COMPUTE_PRICE micro-lamports
+	RETRY_PRIORITY_MULTIPLIER = 2
+	RETRY_MIN_COMPUTE_PRICE   = 10_000
+	DEFAULT_RETRY_SLIPPAGE    = 1.0 // percent
+)
+
+// Dead letter stages: where the swap failed
+const (
+	DEAD_LETTER_SUBMIT  = "submit"  // the RPC node or sender refused it
+	DEAD_LETTER_ONCHAIN = "onchain" // it landed and failed
+)
+
+// Dead letter statuses
+const (
+	DEAD_LETTER_FAILED  = "failed"
+	DEAD_LETTER_RETRIED = "retried" // a retry was sent and confirmed
+	DEAD_LETTER_LANDED  = "landed"  // the original turned out to have landed
+	// DEAD_LETTER_SUPERSEDED letters were retried and failed again, as a new letter
+	DEAD_LETTER_SUPERSEDED = "superseded"
+)
+
+// ErrTransactionFailed marks a transaction that was confirmed with an error
+var ErrTransactionFailed = errors.New("transaction failed on-chain")
+
+// DeadLetterError is a failed swap's error with the dead letter it was saved as
+type DeadLetterError struct {
+	ID  int
+	Err error
+}
+
+func (e *DeadLetterError) Error() string {
+	return fmt.Sprintf("%v (saved as dead letter %d; retry with: go run . retry %d)", e.Err, e.ID, e.ID)
+}
+
+func (e *DeadLetterError) Unwrap() error {
+	return e.Err
+}
+
+// DeadLetter is a failed Raydium swap with everything needed to retry it
+type DeadLetter struct {
+	ID           int       `json:"id"`
+	Wallet       string    `json:"wallet"`
+	Cluster      string    `json:"cluster"`
+	Route        string    `json:"route"` // the venue and pool the swap went through
+	Pool         string    `json:"pool"`
+	Side         string    `json:"side"`
+	Amount       float64   `json:"amount"`
+	MinAmountOut uint64    `json:"min_amount_out"` // raw
+	ComputePrice uint64    `json:"compute_price"`  // micro-lamports per unit
+	Stage        string    `json:"stage"`
+	Error        string    `json:"error"`
+	Signature    string    `json:"signature,omitempty"`
+	Transaction  string    `json:"transaction,omitempty"` // signed, base64
+	FailedAt     time.Time `json:"failed_at"`
+
+	Status      string `json:"status"`
+	RetryTx     string `json:"retry_tx,omitempty"`     // the retry that landed
+	RetryLetter int    `json:"retry_letter,omitempty"` // the dead letter of the retry that failed
+}
+
+// deadLetterSwap records a swap sendAndConfirmTransaction failed and returns
+// its error, naming the dead letter to retry
+func deadLetterSwap(wallet solana.PublicKey, poolAddress string, side string, amount float64, minAmountOut uint64, tx *solana.Transaction, sendErr error) error {
+	letter := &DeadLetter{
+		Wallet:       wallet.String(),
+		Cluster:      activeCluster.Name,
+		Route:        "raydium-v4:" + poolAddress,
+		Pool:         poolAddress,
+		Side:         side,
+		Amount:       amount,
+		MinAmountOut: minAmountOut,
+		ComputePrice: transactionComputePrice(tx),
+		Stage:        DEAD_LETTER_SUBMIT,
+		Error:        sendErr.Error(),
+		FailedAt:     time.Now().UTC(),
+		Status:       DEAD_LETTER_FAILED,
+	}
+	if errors.Is(sendErr, ErrTransactionFailed) {
+		letter.Stage = DEAD_LETTER_ONCHAIN
+	}
+	if len(tx.Signatures) > 0 {
+		letter.Signature = tx.Signatures[0].String()
+	}
+	if encoded, err := tx.ToBase64(); err == nil {
+		letter.Transaction = encoded
+	}
+
+	var letters []*DeadLetter
+	err := updateStateFile(DEAD_LETTERS_FILE, &letters, func() {
+		for _, existing := range letters {
+			letter.ID = max(letter.ID, existing.ID)
+		}
+		letter.ID++
+		letters = append(letters, letter)
+	})
+	if err != nil {
+		fmt.Printf("Warning: Could not record the failed swap: %v\n", err)
+		return sendErr
+	}
+	return &DeadLetterError{ID: letter.ID, Err: sendErr}
+}
+
+// transactionComputePrice returns the compute unit price a transaction was
+// sent with, 0 without one
+func transactionComputePrice(tx *solana.Transaction) uint64 {
+	for _, ix := range tx.Message.Instructions {
+		programID, err := tx.Message.Program(ix.ProgramIDIndex)
+		if err != nil || !programID.Equals(solana.ComputeBudget) {
+			continue
+		}
+		if len(ix.Data) >= 9 && ix.Data[0] == computebudget.Instruction_SetComputeUnitPrice {
+			return binary.LittleEndian.Uint64(ix.Data[1:9])
+		}
+	}
+	return 0
+}
+
+// readDeadLetters loads the dead letters
+func readDeadLetters() ([]*DeadLetter, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, DEAD_LETTERS_FILE)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var letters []*DeadLetter
+	if err := json.Unmarshal(data, &letters); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return letters, nil
+}
+
+// updateDeadLetter applies update to the stored dead letter with the given ID
+func updateDeadLetter(id int, update func(letter *DeadLetter)) error {
+	var letters []*DeadLetter
+	found := false
+	err := updateStateFile(DEAD_LETTERS_FILE, &letters, func() {
+		for _, letter := range letters {
+			if letter.ID == id {
+				update(letter)
+				found = true
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("no dead letter %d", id)
+	}
+	return nil
+}
+
+// retryComputePrice bumps a failed swap's compute unit price
+func retryComputePrice(previous uint64) uint64 {
+	return max(previous*RETRY_PRIORITY_MULTIPLIER, RETRY_MIN_COMPUTE_PRICE)
+}
+
+// runRetry lists the dead letters or retries one
+func runRetry(args []string, opts BuildOptions) {
+	if len(args) > 0 && args[0] == "list" {
+		runRetryList(args[1:])
+		return
+	}
+	if len(args) == 0 {
+		fmt.Println("Usage: go run . retry list [-json]")
+		fmt.Println("       go run . retry ID [-slippage PCT] [-cu-price MICRO_LAMPORTS] [-yes]")
+		os.Exit(1)
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil || id <= 0 {
+		log.Fatalf("Invalid dead letter ID %q", args[0])
+	}
+
+	fs := newCommandFlagSet("retry")
+	slippage := fs.Float64("slippage", DEFAULT_RETRY_SLIPPAGE, "Slippage tolerance in percent applied to the fresh quote")
+	computePrice := fs.Uint64("cu-price", 0, fmt.Sprintf("Compute unit price in micro-lamports (default %dx the failed swap's, at least %d)", RETRY_PRIORITY_MULTIPLIER, RETRY_MIN_COMPUTE_PRICE))
+	yes := fs.Bool("yes", false, "Retry without asking for confirmation")
+	fs.Parse(args[1:])
+
+	if *slippage <= 0 || *slippage > MAX_SLIPPAGE {
+		log.Fatalf("-slippage must be between 0 and %.0f", MAX_SLIPPAGE)
+	}
+	letters, err := readDeadLetters()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var letter *DeadLetter
+	for _, l := range letters {
+		if l.ID == id {
+			letter = l
+		}
+	}
+	if letter == nil {
+		log.Fatalf("No dead letter %d", id)
+	}
+	if letter.Status != DEAD_LETTER_FAILED {
+		log.Fatalf("Dead letter %d is already %s", id, letter.Status)
+	}
+	if letter.Cluster != activeCluster.Name {
+		log.Fatalf("Dead letter %d failed on %s; select that cluster to retry it", id, letter.Cluster)
+	}
+	wallet, err := loadWallet()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if wallet.PublicKey().String() != letter.Wallet {
+		log.Fatalf("Dead letter %d was sent by %s, not the loaded wallet %s", id, letter.Wallet, wallet.PublicKey())
+	}
+
+	ctx := interruptContext()
+	client := newChainClient()
+
+	// A swap refused at submission can still have been broadcast by a
+	// sender; one that landed must not be sent again
+	if letter.Signature != "" {
+		if landed, err := signatureLanded(ctx, client, letter.Signature); err != nil {
+			log.Fatalf("Could not check whether the failed swap landed: %v", err)
+		} else if landed {
+			updateDeadLetter(id, func(stored *DeadLetter) { stored.Status = DEAD_LETTER_LANDED })
+			log.Fatalf("The original swap %s landed after all; nothing to retry", letter.Signature)
+		}
+	}
+
+	quote, err := resolveSwapQuote(ctx, client, QuoteParams{PoolAddress: letter.Pool, Side: letter.Side, Amount: letter.Amount})
+	if err != nil {
+		log.Fatalf("Failed to quote the retry: %v", err)
+	}
+	minAmountOut := quote.MinAmountOut(*slippage)
+	opts.Compute.Price = *computePrice
+	if opts.Compute.Price == 0 {
+		opts.Compute.Price = retryComputePrice(letter.ComputePrice)
+	}
+
+	fmt.Printf("\n=== RETRY DEAD LETTER %d ===\n", id)
+	fmt.Printf("Failed: %s at %s (%s)\n", letter.Stage, letter.FailedAt.Local().Format(time.DateTime), letter.Error)
+	fmt.Printf("Pool: %s\n", letter.Pool)
+	fmt.Printf("Operation: %s %g\n", letter.Side, letter.Amount)
+	fmt.Printf("Expected Out: %.9f\n", quote.ExpectedOut)
+	fmt.Printf("Minimum Out: %s (was %s)\n", atoms.Format(minAmountOut, quote.OutputDecimals), atoms.Format(letter.MinAmountOut, quote.OutputDecimals))
+	fmt.Printf("Compute Unit Price: %d micro-lamports (was %d)\n", opts.Compute.Price, letter.ComputePrice)
+	fmt.Printf("=============================\n")
+	if !*yes && !confirmPrompt("Retry this swap?") {
+		fmt.Println("Retry cancelled.")
+		return
+	}
+
+	guard, err := loadSpendGuard(client, wallet.PublicKey())
+	if err != nil {
+		log.Fatalf("Failed to load spend limits: %v", err)
+	}
+	if err := guard.Enforce(ctx, quote.GuardedTrade(opts.Burn), !*yes); err != nil {
+		log.Fatalf("Spend limits: %v", err)
+	}
+
+	txHash, err := executeSwap(ctx, client, wallet, letter.Pool, letter.Side, letter.Amount, minAmountOut, opts)
+	// A retry that fails again is its own dead letter, retried in turn
+	var retryLetter *DeadLetterError
+	updateErr := updateDeadLetter(id, func(stored *DeadLetter) {
+		switch {
+		case errors.As(err, &retryLetter):
+			stored.Status = DEAD_LETTER_SUPERSEDED
+			stored.RetryLetter = retryLetter.ID
+		case err == nil:
+			stored.Status = DEAD_LETTER_RETRIED
+			stored.RetryTx = txHash
+		}
+	})
+	if updateErr != nil {
+		fmt.Printf("Warning: Could not update dead letter %d: %v\n", id, updateErr)
+	}
+	if err != nil {
+		guard.Release(quote.GuardedTrade(opts.Burn))
+		log.Fatalf("Retry failed: %v", err)
+	}
+	if err := guard.Record(quote.GuardedTrade(opts.Burn)); err != nil {
+		fmt.Printf("Warning: Could not record trade for spend limits: %v\n", err)
+	}
+	fmt.Printf("\n✅ Retry of dead letter %d executed\n", id)
+	fmt.Printf("Transaction: %s\n", txHash)
+	fmt.Printf("Explorer: %s\n", explorerTxURL(txHash))
+}
+
+// signatureLanded reports whether a signature was confirmed without error
+func signatureLanded(ctx context.Context, client ChainClient, signature string) (bool, error) {
+	sig, err := solana.SignatureFromBase58(signature)
+	if err != nil {
+		return false, fmt.Errorf("invalid signature: %w", err)
+	}
+	statuses, err := client.GetSignatureStatuses(ctx, true, sig)
+	if err != nil {
+		return false, err
+	}
+	if statuses == nil || len(statuses.Value) == 0 || statuses.Value[0] == nil {
+		return false, nil
+	}
+	status := statuses.Value[0]
+	confirmed := status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed || status.ConfirmationStatus == rpc.ConfirmationStatusFinalized
+	return confirmed && status.Err == nil, nil
+}
+
+// runRetryList prints the dead letters
+func runRetryList(args []string) {
+	fs := newCommandFlagSet("retry list")
+	jsonOutput := fs.Bool("json", false, "Output the dead letters as JSON")
+	fs.Parse(args)
+
+	letters, err := readDeadLetters()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *jsonOutput {
+		data, err := json.MarshalIndent(letters, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode dead letters: %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	if len(letters) == 0 {
+		fmt.Println("No failed swaps")
+		return
+	}
+	fmt.Printf("%-4s %-10s %-8s %-19s %-44s %s\n", "ID", "Status", "Stage", "Failed", "Pool", "Swap")
+	for _, letter := range letters {
+		fmt.Printf("%-4d %-10s %-8s %-19s %-44s %s %g\n", letter.ID, letter.Status, letter.Stage, letter.FailedAt.Local().Format(time.DateTime), letter.Pool, letter.Side, letter.Amount)
+		fmt.Printf("     error: %s\n", letter.Error)
+		if letter.RetryTx != "" {
+			fmt.Printf("     retried: %s\n", letter.RetryTx)
+		}
+		if letter.RetryLetter != 0 {
+			fmt.Printf("     retry failed as dead letter %d\n", letter.RetryLetter)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+
+	"awesomeProject/internal/atoms"
+)
+
+// Token account layout offsets of the delegate, a COption<Pubkey>, and its allowance
+const (
+	TOKEN_ACCOUNT_DELEGATE_OFFSET         = 72
+	TOKEN_ACCOUNT_DELEGATED_AMOUNT_OFFSET = 121
+)
+
+// DelegationConfig lets a delegate key trade the owner's token accounts.
+// The owner approves the delegate for an allowance on each account; the
+// delegate signs the swaps and pays their fees, and the output lands in the
+// owner's accounts. SOL is traded as wrapped SOL, since a delegate cannot
+// move the owner's lamports.
+type DelegationConfig struct {
+	Owner solana.PublicKey
+}
+
+// Enabled reports whether swaps trade another wallet's accounts
+func (c DelegationConfig) Enabled() bool {
+	return !c.Owner.IsZero()
+}
+
+// holder returns the wallet whose token accounts a swap signed by signer trades
+func (c DelegationConfig) holder(signer solana.PublicKey) solana.PublicKey {
+	if !c.Enabled() {
+		return signer
+	}
+	return c.Owner
+}
+
+// tokenDelegation is the delegate and allowance of a token account
+type tokenDelegation struct {
+	Balance   uint64
+	Delegate  solana.PublicKey // zero without a delegate
+	Allowance uint64
+}
+
+// parseTokenDelegation reads the delegate fields of a token account
+func parseTokenDelegation(data []byte) (tokenDelegation, error) {
+	if len(data) < TOKEN_ACCOUNT_DELEGATED_AMOUNT_OFFSET+8 {
+		return tokenDelegation{}, fmt.Errorf("token account data too short: %d bytes", len(data))
+	}
+	d := tokenDelegation{Balance: binary.LittleEndian.Uint64(data[TOKEN_ACCOUNT_AMOUNT_OFFSET:])}
+	if binary.LittleEndian.Uint32(data[TOKEN_ACCOUNT_DELEGATE_OFFSET:]) == 1 {
+		d.Delegate = solana.PublicKeyFromBytes(data[TOKEN_ACCOUNT_DELEGATE_OFFSET+4 : TOKEN_ACCOUNT_DELEGATE_OFFSET+36])
+		d.Allowance = binary.LittleEndian.Uint64(data[TOKEN_ACCOUNT_DELEGATED_AMOUNT_OFFSET:])
+	}
+	return d, nil
+}
+
+// fetchTokenDelegation loads the delegate of a token account
+func fetchTokenDelegation(ctx context.Context, client ChainClient, account solana.PublicKey) (tokenDelegation, error) {
+	info, err := client.GetAccountInfo(ctx, account)
+	if err != nil || info == nil || info.Value == nil {
+		return tokenDelegation{}, fmt.Errorf("token account %s not found", account)
+	}
+	return parseTokenDelegation(info.Value.Data.GetBinary())
+}
+
+// delegateAccounts prepares a swap signed by delegate on the owner's
+// accounts: the source must exist and allow the amount, and a missing
+// destination is created at the delegate's expense
+func (c DelegationConfig) delegateAccounts(ctx context.Context, client ChainClient, delegate solana.PublicKey, destinationMint solana.PublicKey, amountInRaw uint64, accounts *swapAccounts) error {
+	if accounts.CreateSource != nil {
+		return fmt.Errorf("%s has no token account to trade from; approve it with `approve` first", c.Owner)
+	}
+	source, err := fetchTokenDelegation(ctx, client, accounts.Source)
+	if err != nil {
+		return err
+	}
+	if !source.Delegate.Equals(delegate) {
+		return fmt.Errorf("%s is not the delegate of %s", delegate, accounts.Source)
+	}
+	if source.Allowance < amountInRaw {
+		return fmt.Errorf("delegate allowance of %d is below the swap amount of %d", source.Allowance, amountInRaw)
+	}
+	if source.Balance < amountInRaw {
+		return fmt.Errorf("balance of %d is below the swap amount of %d", source.Balance, amountInRaw)
+	}
+	if accounts.CreateDestination != nil {
+		accounts.CreateDestination = associatedtokenaccount.NewCreateInstruction(delegate, c.Owner, destinationMint).Build()
+	}
+	accounts.Delegated = true
+	return nil
+}
+
+// parseDelegationMint resolves a mint argument; sol means wrapped SOL
+func parseDelegationMint(value string) (solana.PublicKey, error) {
+	if strings.EqualFold(value, "sol") {
+		return WSOL_MINT, nil
+	}
+	mint, err := solana.PublicKeyFromBase58(value)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("invalid mint: %w", err)
+	}
+	return mint, nil
+}
+
+// printTokenDelegation prints the current delegate of an account
+func printTokenDelegation(ctx context.Context, client ChainClient, account solana.PublicKey, decimals int) {
+	current, err := fetchTokenDelegation(ctx, client, account)
+	if err != nil {
+		fmt.Printf("Account: %s (not created yet)\n", account)
+		return
+	}
+	fmt.Printf("Account: %s\n", account)
+	fmt.Printf("Balance: %s\n", atoms.Format(current.Balance, decimals))
+	if current.Delegate.IsZero() {
+		fmt.Println("Delegate: none")
+		return
+	}
+	fmt.Printf("Delegate: %s (allowance %s)\n", current.Delegate, atoms.Format(current.Allowance, decimals))
+}
+
+// runApprove approves a delegate to swap up to an amount of one of the
+// wallet's tokens. For SOL the amount is wrapped first, since delegates
+// trade wrapped SOL.
+func runApprove(args []string, opts BuildOptions) {
+	fs := newCommandFlagSet("approve")
+	mintArg := fs.String("mint", "", "Token mint whose account the delegate may trade, or sol for wrapped SOL")
+	delegateArg := fs.String("delegate", "", "Public key of the trading key")
+	amountArg := fs.Float64("amount", 0, "Allowance in tokens (SOL is wrapped into the account)")
+	execute := fs.Bool("execute", false, "Send the approval (requires "+PRIVATE_KEY_ENV_VAR+")")
+	fs.Parse(args)
+
+	if *mintArg == "" || *delegateArg == "" || *amountArg <= 0 {
+		fmt.Println("Usage: go run . approve -mint MINT|sol -delegate KEY -amount N [-execute]")
+		os.Exit(1)
+	}
+	mint, err := parseDelegationMint(*mintArg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	delegate, err := solana.PublicKeyFromBase58(*delegateArg)
+	if err != nil {
+		log.Fatalf("Invalid delegate: %v", err)
+	}
+
+	wallet, err := loadWallet()
+	if err != nil {
+		log.Fatalf("Failed to load wallet: %v", err)
+	}
+	owner := wallet.PublicKey()
+	if delegate.Equals(owner) {
+		log.Fatal("The delegate must be a different key than the wallet")
+	}
+
+	ctx := context.Background()
+	client := newChainClient()
+	decimals, err := getTokenDecimals(ctx, client, mint.String())
+	if err != nil {
+		log.Fatalf("Failed to get decimals for %s: %v", mint, err)
+	}
+	amountRaw, err := atoms.FromAmount(*amountArg, int(decimals))
+	if err != nil {
+		log.Fatal(err)
+	}
+	account, createIx, err := getOrCreateATA(ctx, client, owner, mint)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var instructions []solana.Instruction
+	if createIx != nil {
+		instructions = append(instructions, createIx)
+	}
+	if mint.Equals(WSOL_MINT) {
+		instructions = append(instructions,
+			system.NewTransferInstruction(amountRaw, owner, account).Build(),
+			token.NewSyncNativeInstruction(account).Build(),
+		)
+	}
+	instructions = append(instructions, token.NewApproveCheckedInstruction(amountRaw, decimals, account, mint, delegate, owner, []solana.PublicKey{}).Build())
+
+	fmt.Printf("\n=== APPROVE DELEGATE ===\n")
+	fmt.Printf("Owner: %s\n", owner)
+	printTokenDelegation(ctx, client, account, int(decimals))
+	fmt.Printf("New Delegate: %s (allowance %s)\n", delegate, atoms.Format(amountRaw, int(decimals)))
+	if mint.Equals(WSOL_MINT) {
+		fmt.Printf("Wrapping: %s SOL\n", atoms.Format(amountRaw, SOL_DECIMALS))
+	}
+	fmt.Printf("========================\n")
+
+	if !*execute {
+		fmt.Println("\nDry run: pass -execute to send the approval")
+		return
+	}
+	steps := []creationStep{{Label: "Approve delegate", Instructions: instructions}}
+	if err := executeCreationSteps(interruptContext(), client, wallet, steps, opts); err != nil {
+		log.Fatalf("Approval failed: %v", err)
+	}
+}
+
+// runRevoke removes the delegate of one of the wallet's token accounts
+func runRevoke(args []string, opts BuildOptions) {
+	fs := newCommandFlagSet("revoke")
+	mintArg := fs.String("mint", "", "Token mint whose delegate is revoked, or sol for wrapped SOL")
+	execute := fs.Bool("execute", false, "Send the revocation (requires "+PRIVATE_KEY_ENV_VAR+")")
+	fs.Parse(args)
+
+	if *mintArg == "" {
+		fmt.Println("Usage: go run . revoke -mint MINT|sol [-execute]")
+		os.Exit(1)
+	}
+	mint, err := parseDelegationMint(*mintArg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wallet, err := loadWallet()
+	if err != nil {
+		log.Fatalf("Failed to load wallet: %v", err)
+	}
+	owner := wallet.PublicKey()
+
+	ctx := context.Background()
+	client := newChainClient()
+	decimals, err := getTokenDecimals(ctx, client, mint.String())
+	if err != nil {
+		log.Fatalf("Failed to get decimals for %s: %v", mint, err)
+	}
+	account, _, err := solana.FindAssociatedTokenAddress(owner, mint)
+	if err != nil {
+		log.Fatalf("Failed to find ATA: %v", err)
+	}
+	current, err := fetchTokenDelegation(ctx, client, account)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("\n=== REVOKE DELEGATE ===\n")
+	fmt.Printf("Owner: %s\n", owner)
+	printTokenDelegation(ctx, client, account, int(decimals))
+	fmt.Printf("=======================\n")
+	if current.Delegate.IsZero() {
+		fmt.Println("\nNothing to revoke")
+		return
+	}
+
+	if !*execute {
+		fmt.Println("\nDry run: pass -execute to send the revocation")
+		return
+	}
+	steps := []creationStep{{Label: "Revoke delegate", Instructions: []solana.Instruction{
+		token.NewRevokeInstruction(account, owner, []solana.PublicKey{}).Build(),
+	}}}
+	if err := executeCreationSteps(interruptContext(), client, wallet, steps, opts); err != nil {
+		log.Fatalf("Revocation failed: %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"awesomeProject/internal/atoms"
+)
+
+// Quote ladder and depth chart settings
+const (
+	DEFAULT_LADDER_SIZES = "0.1,0.5,1,5,10" // SOL
+	DEPTH_CHART_WIDTH    = 30               // characters per side
+	// Spread above which a size is flagged as too large for the pool
+	DEFAULT_MAX_SPREAD_PCT = 2.0
+)
+
+// Price moves plotted by the depth chart
+var depthChartMoves = []float64{0.005, 0.01, 0.02, 0.05, 0.1, 0.2, 0.5}
+
+// LadderRung is the quote for one trade size in both directions
+type LadderRung struct {
+	SizeSOL    float64
+	BuyOut     float64 // tokens received for SizeSOL
+	BuyPrice   float64 // average SOL per token paid
+	BuyImpact  float64 // percent
+	SellIn     float64 // tokens worth SizeSOL at the spot price
+	SellOut    float64 // SOL received for SellIn
+	SellPrice  float64 // average SOL per token received
+	SellImpact float64 // percent
+	Spread     float64 // percent of the mid price between BuyPrice and SellPrice
+}
+
+// quoteLadder quotes buys of each size in SOL and sells of the same value in
+// tokens at the pool's spot price, the zero-size mid, without fetching anything
+func quoteLadder(pool *OnChainPool, sizes []float64) []LadderRung {
+	spot, _, _ := poolPrice(pool)
+	_, _, tokenDecimals := swapMints(pool, "sell")
+
+	rungs := make([]LadderRung, 0, len(sizes))
+	for _, size := range sizes {
+		rung := LadderRung{SizeSOL: size}
+		rung.BuyOut = atoms.ToAmount(poolNetOutput(pool, "buy", size), tokenDecimals)
+		if rung.BuyOut > 0 {
+			rung.BuyPrice = size / rung.BuyOut
+		}
+		rung.BuyImpact = quotedPriceImpact(pool, "buy", size)
+		if spot > 0 {
+			rung.SellIn = size / spot
+			rung.SellOut = atoms.ToAmount(poolNetOutput(pool, "sell", rung.SellIn), SOL_DECIMALS)
+			rung.SellImpact = quotedPriceImpact(pool, "sell", rung.SellIn)
+			rung.SellPrice = rung.SellOut / rung.SellIn
+		}
+		// A round trip at this size loses the spread, fees included
+		if spot > 0 && rung.BuyPrice > 0 && rung.SellPrice > 0 {
+			rung.Spread = (rung.BuyPrice - rung.SellPrice) / spot * 100
+		}
+		rungs = append(rungs, rung)
+	}
+	return rungs
+}
+
+// parseSizeList parses comma-separated positive trade sizes
+func parseSizeList(list string) ([]float64, error) {
+	var sizes []float64
+	for _, entry := range strings.Split(list, ",") {
+		size, err := strconv.ParseFloat(strings.TrimSpace(entry), 64)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid size %q", entry)
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
+
+// printDepthChart draws the SOL needed to move the price by each of
+// depthChartMoves, sells to the left and buys to the right
+func printDepthChart(solReserve float64) {
+	var scale float64
+	for _, move := range depthChartMoves {
+		buySOL, sellSOL := impliedDepth(solReserve, move)
+		scale = math.Max(scale, math.Max(buySOL, sellSOL))
+	}
+	if scale == 0 {
+		fmt.Println("Pool has no SOL liquidity")
+		return
+	}
+
+	bar := func(sol float64) string {
+		return strings.Repeat("█", int(math.Round(sol/scale*DEPTH_CHART_WIDTH)))
+	}
+	fmt.Printf("\n%*s │ %6s │ %s\n", DEPTH_CHART_WIDTH+13, "SOL to push price down", "move", "SOL to push price up")
+	for _, move := range depthChartMoves {
+		buySOL, sellSOL := impliedDepth(solReserve, move)
+		fmt.Printf("%12.4f %*s │ %5.1f%% │ %-*s %.4f\n",
+			sellSOL, DEPTH_CHART_WIDTH, bar(sellSOL), move*100, DEPTH_CHART_WIDTH, bar(buySOL), buySOL)
+	}
+}
+
+// runDepth prints a quote ladder for a pool and optionally its depth curve
+func runDepth(args []string) {
+	fs := newCommandFlagSet("depth")
+	var poolAddress string
+	var tokenAddress string
+	var sizeList string
+	var chart bool
+	var maxSpread float64
+	fs.StringVar(&poolAddress, "pool", "", "Pool address")
+	fs.StringVar(&tokenAddress, "token", "", "Token address (uses its deepest pool)")
+	fs.StringVar(&sizeList, "sizes", DEFAULT_LADDER_SIZES, "Comma-separated trade sizes in SOL")
+	fs.BoolVar(&chart, "chart", false, "Also draw an ASCII depth curve")
+	fs.Float64Var(&maxSpread, "max-spread", DEFAULT_MAX_SPREAD_PCT, "Flag sizes whose buy/sell spread exceeds this percent")
+	fs.Parse(args)
+
+	if poolAddress == "" && tokenAddress == "" {
+		fmt.Println("Usage: go run . depth [-pool POOL | -token TOKEN] [-sizes 0.1,0.5,1,5,10] [-chart]")
+		fs.PrintDefaults()
+		return
+	}
+	sizes, err := parseSizeList(sizeList)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	client := newChainClient()
+	if poolAddress == "" {
+		pool, err := findPoolsOnChain(ctx, client, tokenAddress)
+		if err != nil {
+			log.Fatalf("Failed to find pool: %v", err)
+		}
+		poolAddress = pool.Address.String()
+	}
+	pool, err := loadPool(ctx, client, poolAddress)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tokenMint := pool.BaseMint
+	if pool.BaseMint.Equals(WSOL_MINT) || pool.BaseMint.Equals(SOL_MINT) {
+		tokenMint = pool.QuoteMint
+	}
+	symbol := tokenLabel("TOKEN", tokenSymbol(ctx, client, tokenMint))
+	spot, solReserve, tokenReserve := poolPrice(pool)
+
+	fmt.Printf("\n=== DEPTH ===\n")
+	fmt.Printf("Pool: %s\n", pool.Address)
+	fmt.Printf("Mid Price: %.12f SOL per %s\n", spot, symbol)
+	fmt.Printf("Reserves: %.4f SOL / %.4f %s\n", solReserve, tokenReserve, symbol)
+	if err := pool.checkTradable(time.Now()); err != nil {
+		fmt.Printf("⚠️  Warning: %v\n", err)
+	}
+
+	fmt.Printf("\n%10s │ %20s %16s %9s │ %20s %16s %16s %9s │ %8s\n", "Size (SOL)", "Buy Out", "Avg Price", "Impact", "Sell In", "SOL Out", "Avg Price", "Impact", "Spread")
+	var tooWide []string
+	for _, rung := range quoteLadder(pool, sizes) {
+		fmt.Printf("%10.4f │ %20.6f %16.12f %8.4f%% │ %20.6f %16.9f %16.12f %8.4f%% │ %7.3f%%\n",
+			rung.SizeSOL, rung.BuyOut, rung.BuyPrice, rung.BuyImpact, rung.SellIn, rung.SellOut, rung.SellPrice, rung.SellImpact, rung.Spread)
+		if rung.Spread > maxSpread || rung.BuyOut == 0 || rung.SellOut == 0 {
+			tooWide = append(tooWide, strconv.FormatFloat(rung.SizeSOL, 'f', -1, 64))
+		}
+	}
+	if len(tooWide) > 0 {
+		fmt.Printf("\n⚠️  The pool is too thin for %s SOL: a round trip loses more than %.1f%%\n", strings.Join(tooWide, ", "), maxSpread)
+	}
+	if chart {
+		printDepthChart(solReserve)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	MAX_MULTIPLE_ACCOUNTS = 100 // getMultipleAccounts key limit
+	DISCOVERY_WORKERS     = 8
+)
+
+// DISCOVERED_POOLS_FILE in the state directory keeps the pools discovery
+// found for each token, which runs short of RPC budget fall back to
+const DISCOVERED_POOLS_FILE = "discovered_pools.json"
+
+// scanPoolAccounts returns the Raydium V4 pool accounts that may hold the
+// token: the whole program when the RPC budget allows, else the pools with
+// the token as base or quote mint through memcmp filters, else the pools
+// discovery found for it before
+func scanPoolAccounts(ctx context.Context, client ChainClient, token solana.PublicKey) ([]*rpc.KeyedAccount, error) {
+	switch {
+	case rpcCosts.Allows(RPC_CREDITS_SCAN):
+		fmt.Println("Searching for pools on-chain using getProgramAccounts...")
+		fmt.Println("This may take 10-30 seconds...")
+		accounts, err := client.GetProgramAccountsWithOpts(ctx, RAYDIUM_AMM_V4, &rpc.GetProgramAccountsOpts{
+			Filters: []rpc.RPCFilter{{DataSize: uint64(RAYDIUM_V4_LAYOUT.Size)}},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get program accounts: %w", err)
+		}
+		fmt.Printf("Found %d Raydium V4 accounts, filtering for token %s...\n", len(accounts), token)
+		return accounts, nil
+
+	case rpcCosts.Allows(2 * RPC_CREDITS_FILTERED_SCAN):
+		fmt.Printf("RPC budget: %d credits left, searching pools by mint instead of scanning the program\n", rpcCosts.Remaining())
+		var accounts []*rpc.KeyedAccount
+		for _, offset := range []int{RAYDIUM_V4_LAYOUT.BaseMint, RAYDIUM_V4_LAYOUT.QuoteMint} {
+			found, err := client.GetProgramAccountsWithOpts(ctx, RAYDIUM_AMM_V4, &rpc.GetProgramAccountsOpts{
+				Filters: []rpc.RPCFilter{
+					{DataSize: uint64(RAYDIUM_V4_LAYOUT.Size)},
+					{Memcmp: &rpc.RPCFilterMemcmp{Offset: uint64(offset), Bytes: token.Bytes()}},
+				},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get program accounts: %w", err)
+			}
+			accounts = append(accounts, found...)
+		}
+		return accounts, nil
+	}
+
+	known, err := readDiscoveredPools()
+	if err != nil {
+		return nil, err
+	}
+	keys := known[token.String()]
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: %d credits left cannot pay for pool discovery and no pools of %s were found before; pass -pool or raise -rpc-budget", ErrRPCBudget, rpcCosts.Remaining(), token)
+	}
+	fmt.Printf("RPC budget: %d credits left, using the %d pools found for this token before\n", rpcCosts.Remaining(), len(keys))
+	loaded, err := fetchAccountsBatched(ctx, client, keys, nil)
+	if err != nil {
+		return nil, err
+	}
+	var accounts []*rpc.KeyedAccount
+	for i, account := range loaded {
+		if account != nil && account.Owner.Equals(RAYDIUM_AMM_V4) {
+			accounts = append(accounts, &rpc.KeyedAccount{Pubkey: keys[i], Account: account})
+		}
+	}
+	return accounts, nil
+}
+
+// readDiscoveredPools loads the pools found before, by token
+func readDiscoveredPools() (map[string][]solana.PublicKey, error) {
+	known := make(map[string][]solana.PublicKey)
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, DISCOVERED_POOLS_FILE)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return known, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &known); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return known, nil
+}
+
+// rememberPools adds pools of the token to the discovered pools
+func rememberPools(token solana.PublicKey, pools []solana.PublicKey) error {
+	known := make(map[string][]solana.PublicKey)
+	return updateStateFile(DISCOVERED_POOLS_FILE, &known, func() {
+		for _, pool := range pools {
+			if !slices.Contains(known[token.String()], pool) {
+				known[token.String()] = append(known[token.String()], pool)
+			}
+		}
+	})
+}
+
+// fetchAccountsBatched loads accounts with concurrent getMultipleAccounts
+// calls, returning them in key order. Missing accounts and accounts in failed
+// batches are nil; the first batch error is returned alongside the partial
+// result. progress, when set, is called after each batch with the number of
+// keys done.
+func fetchAccountsBatched(ctx context.Context, client ChainClient, keys []solana.PublicKey, progress func(done int)) ([]*rpc.Account, error) {
+	accounts := make([]*rpc.Account, len(keys))
+	batches := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		done     atomic.Int64
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	for range DISCOVERY_WORKERS {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range batches {
+				end := min(start+MAX_MULTIPLE_ACCOUNTS, len(keys))
+				result, err := client.GetMultipleAccounts(ctx, keys[start:end]...)
+				if err != nil {
+					errOnce.Do(func() { firstErr = fmt.Errorf("failed to fetch accounts: %w", err) })
+				} else {
+					copy(accounts[start:end], result.Value)
+				}
+				n := done.Add(int64(end - start))
+				if progress != nil {
+					progress(int(n))
+				}
+			}
+		}()
+	}
+
+	// An interrupt stops handing out batches; workers finish the calls in flight
+feed:
+	for start := 0; start < len(keys); start += MAX_MULTIPLE_ACCOUNTS {
+		select {
+		case batches <- start:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = fmt.Errorf("failed to fetch accounts: %w", ctx.Err())
+	}
+	return accounts, firstErr
+}
+
+// enrichPools fills in mint decimals and vault balances for candidate pools
+// with batched account loads, dropping pools whose accounts could not be read
+func enrichPools(ctx context.Context, client ChainClient, candidates []*OnChainPool) []*OnChainPool {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// Mints first, then both vaults of every pool, in one key list
+	decimals := map[solana.PublicKey]uint8{WSOL_MINT: SOL_DECIMALS, SOL_MINT: SOL_DECIMALS}
+	var keys []solana.PublicKey
+	for _, pool := range candidates {
+		for _, mint := range []solana.PublicKey{pool.BaseMint, pool.QuoteMint} {
+			if _, ok := decimals[mint]; !ok {
+				decimals[mint] = 0
+				keys = append(keys, mint)
+			}
+		}
+	}
+	mintCount := len(keys)
+	for _, pool := range candidates {
+		keys = append(keys, pool.BaseVault, pool.QuoteVault)
+	}
+
+	var mu sync.Mutex
+	progress := func(done int) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Printf("\rEnriching %d candidate pools: %d/%d accounts", len(candidates), done, len(keys))
+	}
+	accounts, err := fetchAccountsBatched(ctx, client, keys, progress)
+	fmt.Println()
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
+	// Decimals live at offset 44 of the mint
+	resolved := map[solana.PublicKey]bool{WSOL_MINT: true, SOL_MINT: true}
+	for i, mint := range keys[:mintCount] {
+		if account := accounts[i]; account != nil {
+			if data := account.Data.GetBinary(); len(data) >= 82 {
+				decimals[mint] = data[44]
+				resolved[mint] = true
+			}
+		}
+	}
+
+	var pools []*OnChainPool
+	for i, pool := range candidates {
+		if !resolved[pool.BaseMint] || !resolved[pool.QuoteMint] {
+			fmt.Printf("Warning: Failed to get decimals for pool %s\n", pool.Address)
+			continue
+		}
+		baseVault, quoteVault := accounts[mintCount+2*i], accounts[mintCount+2*i+1]
+		if baseVault == nil || quoteVault == nil {
+			fmt.Printf("Warning: Failed to fetch vault balances for pool %s\n", pool.Address)
+			continue
+		}
+		baseAmount, err := decodeTokenAmount(baseVault.Data.GetBinary())
+		if err != nil {
+			fmt.Printf("Warning: Failed to decode base vault for pool %s: %v\n", pool.Address, err)
+			continue
+		}
+		quoteAmount, err := decodeTokenAmount(quoteVault.Data.GetBinary())
+		if err != nil {
+			fmt.Printf("Warning: Failed to decode quote vault for pool %s: %v\n", pool.Address, err)
+			continue
+		}
+
+		pool.BaseDecimals, pool.QuoteDecimals = decimals[pool.BaseMint], decimals[pool.QuoteMint]
+		pool.BaseAmount, pool.QuoteAmount = baseAmount, quoteAmount
+		pools = append(pools, pool)
+	}
+	return pools
+}
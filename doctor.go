@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+// Doctor thresholds
+const (
+	DOCTOR_CHECK_TIMEOUT  = 10 * time.Second
+	DOCTOR_MIN_BALANCE    = 0.01 // SOL needed for fees and ATA rent
+	DOCTOR_MAX_CLOCK_SKEW = 60 * time.Second
+)
+
+// doctorCheck is the outcome of a single self-check
+type doctorCheck struct {
+	Name   string
+	Status string // "OK", "WARN" or "FAIL"
+	Detail string
+	Fix    string
+}
+
+// runDoctor validates the local configuration and prints actionable fixes
+func runDoctor(args []string) {
+	fs := newCommandFlagSet("doctor")
+	fs.Parse(args)
+
+	ctx := context.Background()
+	rpcURL := resolveRPCURL()
+	client := newChainClient()
+
+	checks := []doctorCheck{
+		checkRPC(ctx, client, rpcURL),
+		checkWebsocket(ctx),
+		checkWallet(ctx, client),
+		checkProgram(ctx, client, "Raydium AMM V4 program", RAYDIUM_AMM_V4),
+		checkProgram(ctx, client, "OpenBook program", OPENBOOK_PROGRAM),
+		checkStateDir(),
+		checkClockSkew(ctx, client),
+	}
+
+	failed := 0
+	fmt.Printf("\n=== DOCTOR ===\n")
+	for _, check := range checks {
+		fmt.Printf("[%-4s] %s: %s\n", check.Status, check.Name, check.Detail)
+		if check.Status != "OK" && check.Fix != "" {
+			fmt.Printf("       Fix: %s\n", check.Fix)
+		}
+		if check.Status == "FAIL" {
+			failed++
+		}
+	}
+	fmt.Printf("==============\n")
+
+	if failed > 0 {
+		fmt.Printf("\n%d check(s) failed\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("\nAll required checks passed")
+}
+
+// checkRPC verifies the RPC endpoint responds and reports its version
+func checkRPC(ctx context.Context, client ChainClient, rpcURL string) doctorCheck {
+	ctx, cancel := context.WithTimeout(ctx, DOCTOR_CHECK_TIMEOUT)
+	defer cancel()
+
+	version, err := client.GetVersion(ctx)
+	if err != nil {
+		return doctorCheck{
+			Name:   "RPC endpoint",
+			Status: "FAIL",
+			Detail: fmt.Sprintf("%s unreachable: %v", rpcURL, err),
+			Fix:    fmt.Sprintf("check network access or set %s to a working endpoint", RPC_URL_ENV_VAR),
+		}
+	}
+
+	return doctorCheck{
+		Name:   "RPC endpoint",
+		Status: "OK",
+		Detail: fmt.Sprintf("reachable, solana-core %s", version.SolanaCore),
+	}
+}
+
+// checkWebsocket verifies the websocket endpoint accepts subscriptions
+func checkWebsocket(ctx context.Context) doctorCheck {
+	ctx, cancel := context.WithTimeout(ctx, DOCTOR_CHECK_TIMEOUT)
+	defer cancel()
+
+	wsURL := resolveWSURL()
+	fix := fmt.Sprintf("set %s to your provider's websocket endpoint (wss://...)", WS_URL_ENV_VAR)
+
+	wsClient, err := ws.Connect(ctx, wsURL)
+	if err != nil {
+		return doctorCheck{
+			Name:   "Websocket",
+			Status: "WARN",
+			Detail: fmt.Sprintf("cannot connect to %s: %v", wsURL, err),
+			Fix:    fix,
+		}
+	}
+	defer wsClient.Close()
+
+	sub, err := wsClient.SlotSubscribe()
+	if err != nil {
+		return doctorCheck{
+			Name:   "Websocket",
+			Status: "WARN",
+			Detail: fmt.Sprintf("subscriptions rejected: %v", err),
+			Fix:    fix,
+		}
+	}
+	defer sub.Unsubscribe()
+
+	if _, err := sub.Recv(ctx); err != nil {
+		return doctorCheck{
+			Name:   "Websocket",
+			Status: "WARN",
+			Detail: fmt.Sprintf("no slot notification received: %v", err),
+			Fix:    fix,
+		}
+	}
+
+	return doctorCheck{Name: "Websocket", Status: "OK", Detail: "slot subscription working"}
+}
+
+// checkWallet verifies the private key decodes and the wallet can pay fees
+func checkWallet(ctx context.Context, client ChainClient) doctorCheck {
+	if os.Getenv(PRIVATE_KEY_ENV_VAR) == "" {
+		return doctorCheck{
+			Name:   "Wallet",
+			Status: "WARN",
+			Detail: fmt.Sprintf("%s not set, quotes only", PRIVATE_KEY_ENV_VAR),
+			Fix:    fmt.Sprintf("export %s=<base58 private key> to enable -execute", PRIVATE_KEY_ENV_VAR),
+		}
+	}
+
+	wallet, err := loadWallet()
+	if err != nil {
+		return doctorCheck{
+			Name:   "Wallet",
+			Status: "FAIL",
+			Detail: err.Error(),
+			Fix:    fmt.Sprintf("%s must hold the base58-encoded 64-byte secret key", PRIVATE_KEY_ENV_VAR),
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DOCTOR_CHECK_TIMEOUT)
+	defer cancel()
+
+	balance, err := client.GetBalance(ctx, wallet.PublicKey(), rpc.CommitmentConfirmed)
+	if err != nil {
+		return doctorCheck{
+			Name:   "Wallet",
+			Status: "WARN",
+			Detail: fmt.Sprintf("%s loaded, balance unavailable: %v", wallet.PublicKey(), err),
+			Fix:    "verify RPC connectivity",
+		}
+	}
+
+	sol := float64(balance.Value) / math.Pow(10, SOL_DECIMALS)
+	if sol < DOCTOR_MIN_BALANCE {
+		return doctorCheck{
+			Name:   "Wallet",
+			Status: "WARN",
+			Detail: fmt.Sprintf("%s balance %.9f SOL", wallet.PublicKey(), sol),
+			Fix:    fmt.Sprintf("fund the wallet with at least %.2f SOL for fees and token account rent", DOCTOR_MIN_BALANCE),
+		}
+	}
+
+	return doctorCheck{
+		Name:   "Wallet",
+		Status: "OK",
+		Detail: fmt.Sprintf("%s balance %.9f SOL", wallet.PublicKey(), sol),
+	}
+}
+
+// checkProgram verifies a program ID exists on the cluster and is executable
+func checkProgram(ctx context.Context, client ChainClient, name string, programID solana.PublicKey) doctorCheck {
+	ctx, cancel := context.WithTimeout(ctx, DOCTOR_CHECK_TIMEOUT)
+	defer cancel()
+
+	fix := "make sure the RPC endpoint points at the cluster the program is deployed on"
+
+	accountInfo, err := client.GetAccountInfo(ctx, programID)
+	if err != nil || accountInfo == nil || accountInfo.Value == nil {
+		return doctorCheck{
+			Name:   name,
+			Status: "FAIL",
+			Detail: fmt.Sprintf("%s not found: %v", programID, err),
+			Fix:    fix,
+		}
+	}
+
+	if !accountInfo.Value.Executable {
+		return doctorCheck{
+			Name:   name,
+			Status: "FAIL",
+			Detail: fmt.Sprintf("%s is not an executable program", programID),
+			Fix:    fix,
+		}
+	}
+
+	return doctorCheck{Name: name, Status: "OK", Detail: programID.String()}
+}
+
+// checkStateDir verifies the local state directory is writable
+func checkStateDir() doctorCheck {
+	fix := fmt.Sprintf("set %s to a writable directory", STATE_DIR_ENV_VAR)
+
+	dir, err := stateDir()
+	if err != nil {
+		return doctorCheck{Name: "State directory", Status: "FAIL", Detail: err.Error(), Fix: fix}
+	}
+
+	probe := filepath.Join(dir, ".doctor")
+	if err := os.WriteFile(probe, []byte("ok"), 0600); err != nil {
+		return doctorCheck{
+			Name:   "State directory",
+			Status: "FAIL",
+			Detail: fmt.Sprintf("%s is not writable: %v", dir, err),
+			Fix:    fix,
+		}
+	}
+	os.Remove(probe)
+
+	return doctorCheck{Name: "State directory", Status: "OK", Detail: dir}
+}
+
+// checkClockSkew compares the local clock with the cluster's latest block time
+func checkClockSkew(ctx context.Context, client ChainClient) doctorCheck {
+	ctx, cancel := context.WithTimeout(ctx, DOCTOR_CHECK_TIMEOUT)
+	defer cancel()
+
+	slot, err := client.GetSlot(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return doctorCheck{
+			Name:   "Clock skew",
+			Status: "WARN",
+			Detail: fmt.Sprintf("failed to get slot: %v", err),
+			Fix:    "verify RPC connectivity",
+		}
+	}
+
+	blockTime, err := client.GetBlockTime(ctx, slot)
+	if err != nil || blockTime == nil {
+		return doctorCheck{
+			Name:   "Clock skew",
+			Status: "WARN",
+			Detail: fmt.Sprintf("block time unavailable for slot %d: %v", slot, err),
+			Fix:    "retry later or use an RPC that serves getBlockTime",
+		}
+	}
+
+	skew := time.Since(blockTime.Time()).Round(time.Second)
+	if skew > DOCTOR_MAX_CLOCK_SKEW || skew < -DOCTOR_MAX_CLOCK_SKEW {
+		return doctorCheck{
+			Name:   "Clock skew",
+			Status: "WARN",
+			Detail: fmt.Sprintf("local clock differs from cluster by %s", skew),
+			Fix:    "enable NTP time synchronization (e.g. timedatectl set-ntp true)",
+		}
+	}
+
+	return doctorCheck{Name: "Clock skew", Status: "OK", Detail: fmt.Sprintf("%s from cluster time", skew)}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+// E2E_FIXTURE_POOL is the Raydium V4 SOL/USDC pool cloned into the local validator
+const (
+	E2E_FIXTURE_POOL    = "58oQChx4yWmvKdwLLZzBi4ChoCc2fqCUWBkwMihLYQo2"
+	E2E_DEFAULT_AMOUNT  = 0.05
+	E2E_SWAP_ACCOUNTS   = 18
+	E2E_SWAP_DATA_LEN   = 17
+	E2E_SLIPPAGE        = 1.0
+	E2E_BALANCE_TIMEOUT = 30 * time.Second
+)
+
+// runE2E drives the end-to-end checks used against a local test validator:
+// `e2e accounts` lists the mainnet accounts to clone, `e2e run` quotes, builds,
+// simulates and executes a round-trip swap against the fixture pool
+func runE2E(args []string, opts BuildOptions) {
+	if len(args) == 0 {
+		log.Fatal("Usage: e2e <accounts|run> [flags]")
+	}
+
+	fs := newCommandFlagSet("e2e " + args[0])
+	poolAddr := fs.String("pool", E2E_FIXTURE_POOL, "Raydium V4 pool used as the fixture")
+	amount := fs.Float64("amount", E2E_DEFAULT_AMOUNT, "SOL spent by the round-trip swap")
+	keypairPath := fs.String("keypair", "", "solana-keygen JSON keypair (defaults to "+PRIVATE_KEY_ENV_VAR+")")
+	allowMainnet := fs.Bool("allow-mainnet", false, "Allow `e2e run` to send transactions on mainnet")
+	fs.Parse(args[1:])
+
+	ctx := context.Background()
+	client := newChainClient()
+
+	switch args[0] {
+	case "accounts":
+		accounts, err := e2eCloneAccounts(ctx, client, *poolAddr)
+		if err != nil {
+			log.Fatalf("Failed to list fixture accounts: %v", err)
+		}
+		for _, account := range accounts {
+			fmt.Println(account)
+		}
+	case "run":
+		if activeCluster.Name == "mainnet" && !*allowMainnet {
+			log.Fatalf("e2e run sends real transactions; point %s at a test validator with %s=custom, or pass -allow-mainnet",
+				RPC_URL_ENV_VAR, CLUSTER_ENV_VAR)
+		}
+
+		wallet, err := e2eWallet(*keypairPath)
+		if err != nil {
+			log.Fatalf("Failed to load wallet: %v", err)
+		}
+
+		checks := runE2EChecks(ctx, client, wallet, *poolAddr, *amount, opts)
+
+		failed := 0
+		fmt.Printf("\n=== E2E ===\n")
+		for _, check := range checks {
+			fmt.Printf("[%-4s] %s: %s\n", check.Status, check.Name, check.Detail)
+			if check.Status == "FAIL" {
+				failed++
+			}
+		}
+		fmt.Printf("===========\n")
+
+		if failed > 0 {
+			fmt.Printf("\n%d check(s) failed\n", failed)
+			os.Exit(1)
+		}
+		fmt.Println("\nAll end-to-end checks passed")
+	default:
+		log.Fatalf("Unknown e2e command %q (available: accounts, run)", args[0])
+	}
+}
+
+// e2eWallet loads the wallet from a keygen file or the environment
+func e2eWallet(keypairPath string) (solana.PrivateKey, error) {
+	if keypairPath == "" {
+		return loadWallet()
+	}
+	return solana.PrivateKeyFromSolanaKeygenFile(keypairPath)
+}
+
+// e2eCloneAccounts returns every account a swap against the pool touches, so
+// the validator can clone them from mainnet
+func e2eCloneAccounts(ctx context.Context, client ChainClient, poolAddress string) ([]solana.PublicKey, error) {
+	poolPubkey, err := solana.PublicKeyFromBase58(poolAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool address: %w", err)
+	}
+
+	accountInfo, err := client.GetAccountInfo(ctx, poolPubkey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool account: %w", err)
+	}
+
+	pool, err := parsePoolAccount(poolPubkey, accountInfo.Value.Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pool data: %w", err)
+	}
+	if err := fetchMarketData(ctx, client, pool); err != nil {
+		return nil, err
+	}
+
+	accounts := []solana.PublicKey{
+		pool.Address, pool.BaseMint, pool.QuoteMint, pool.BaseVault, pool.QuoteVault,
+		pool.LpMint, pool.OpenOrders, pool.TargetOrders,
+	}
+	if !pool.Market.IsZero() {
+		accounts = append(accounts,
+			pool.Market, pool.MarketBids, pool.MarketAsks, pool.MarketEventQueue,
+			pool.MarketBaseVault, pool.MarketQuoteVault,
+		)
+	}
+
+	// Native mint is built into the validator and cannot be cloned
+	var cloneable []solana.PublicKey
+	seen := make(map[solana.PublicKey]bool)
+	for _, account := range accounts {
+		if account.IsZero() || account.Equals(WSOL_MINT) || account.Equals(solana.SystemProgramID) || seen[account] {
+			continue
+		}
+		seen[account] = true
+		cloneable = append(cloneable, account)
+	}
+	return cloneable, nil
+}
+
+// runE2EChecks exercises the layout, quote, instruction encoding, simulation
+// and execution paths, stopping at the first failure since later steps depend on it
+func runE2EChecks(ctx context.Context, client ChainClient, wallet solana.PrivateKey, poolAddress string, amount float64, opts BuildOptions) []doctorCheck {
+	var checks []doctorCheck
+	fail := func(name string, format string, args ...interface{}) []doctorCheck {
+		return append(checks, doctorCheck{Name: name, Status: "FAIL", Detail: fmt.Sprintf(format, args...)})
+	}
+	pass := func(name string, format string, args ...interface{}) {
+		checks = append(checks, doctorCheck{Name: name, Status: "OK", Detail: fmt.Sprintf(format, args...)})
+	}
+
+	// Pool layout: the parsed mints, vaults and reserves must be coherent
+	pool, err := loadPool(ctx, client, poolAddress)
+	if err != nil {
+		return fail("pool layout", "%v", err)
+	}
+	if !pool.BaseMint.Equals(WSOL_MINT) && !pool.QuoteMint.Equals(WSOL_MINT) {
+		return fail("pool layout", "fixture pool must be SOL-paired, got %s/%s", pool.BaseMint, pool.QuoteMint)
+	}
+	if pool.BaseAmount == 0 || pool.QuoteAmount == 0 {
+		return fail("pool layout", "empty reserves %d/%d", pool.BaseAmount, pool.QuoteAmount)
+	}
+	pass("pool layout", "%s/%s reserves %d/%d", pool.BaseMint, pool.QuoteMint, pool.BaseAmount, pool.QuoteAmount)
+
+	token := pool.BaseMint
+	if token.Equals(WSOL_MINT) {
+		token = pool.QuoteMint
+	}
+
+	// Quote
+	quote, err := resolveSwapQuote(ctx, client, QuoteParams{PoolAddress: poolAddress, Amount: amount, Side: "buy"})
+	if err != nil {
+		return fail("quote", "%v", err)
+	}
+	if quote.ExpectedOut <= 0 {
+		return fail("quote", "non-positive output %.9f", quote.ExpectedOut)
+	}
+	pass("quote", "%.9f SOL -> %.9f", amount, quote.ExpectedOut)
+
+	// Instruction encoding
+	minAmountOut := quote.MinAmountOut(E2E_SLIPPAGE)
+	tx, err := buildSwapTransaction(ctx, client, wallet.PublicKey(), poolAddress, "buy", amount, minAmountOut, opts)
+	if err != nil {
+		return fail("instruction encoding", "%v", err)
+	}
+	amountInRaw, err := atoms.FromAmount(amount, SOL_DECIMALS)
+	if err != nil {
+		return fail("instruction encoding", "%v", err)
+	}
+	if err := checkSwapEncoding(tx, pool.Address, amountInRaw, minAmountOut); err != nil {
+		return fail("instruction encoding", "%v", err)
+	}
+	pass("instruction encoding", "swap instruction %d, %d accounts", RAYDIUM_SWAP_INSTRUCTION, E2E_SWAP_ACCOUNTS)
+
+	// Simulation
+	if err := signTransaction(tx, opts.FeePayer.signers(wallet)...); err != nil {
+		return fail("simulation", "%v", err)
+	}
+	sim, err := client.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{SigVerify: true, Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return fail("simulation", "%v", err)
+	}
+	if sim.Value.Err != nil {
+		return fail("simulation", "%v (logs: %v)", sim.Value.Err, sim.Value.Logs)
+	}
+	pass("simulation", "succeeded")
+
+	// Execution: buy, then sell what was received
+	tokenATA, _, err := solana.FindAssociatedTokenAddress(wallet.PublicKey(), token)
+	if err != nil {
+		return fail("execute buy", "%v", err)
+	}
+	before := e2eTokenBalance(ctx, client, tokenATA)
+
+	if _, err := executeSwap(ctx, client, wallet, poolAddress, "buy", amount, minAmountOut, opts); err != nil {
+		return fail("execute buy", "%v", err)
+	}
+	after, err := e2eWaitForBalance(ctx, client, tokenATA, before)
+	if err != nil {
+		return fail("execute buy", "%v", err)
+	}
+	received := after - before
+	if received < minAmountOut {
+		return fail("execute buy", "received %d, below minimum %d", received, minAmountOut)
+	}
+	pass("execute buy", "received %d (minimum %d)", received, minAmountOut)
+
+	decimals := pool.BaseDecimals
+	if token.Equals(pool.QuoteMint) {
+		decimals = pool.QuoteDecimals
+	}
+	sellAmount := atoms.ToAmount(received, int(decimals))
+	if _, err := executeSwap(ctx, client, wallet, poolAddress, "sell", sellAmount, 0, opts); err != nil {
+		return fail("execute sell", "%v", err)
+	}
+	pass("execute sell", "sold %.9f", sellAmount)
+
+	return checks
+}
+
+// checkSwapEncoding verifies the Raydium swap instruction matches the V4 wire format
+func checkSwapEncoding(tx *solana.Transaction, pool solana.PublicKey, amountIn, minAmountOut uint64) error {
+	for _, ix := range tx.Message.Instructions {
+		programID, err := tx.Message.Program(ix.ProgramIDIndex)
+		if err != nil {
+			return err
+		}
+		if !programID.Equals(RAYDIUM_AMM_V4) {
+			continue
+		}
+
+		if len(ix.Data) != E2E_SWAP_DATA_LEN || ix.Data[0] != RAYDIUM_SWAP_INSTRUCTION {
+			return fmt.Errorf("unexpected instruction data %x", []byte(ix.Data))
+		}
+		if got := binary.LittleEndian.Uint64(ix.Data[1:9]); got != amountIn {
+			return fmt.Errorf("amount in encoded as %d, want %d", got, amountIn)
+		}
+		if got := binary.LittleEndian.Uint64(ix.Data[9:17]); got != minAmountOut {
+			return fmt.Errorf("minimum out encoded as %d, want %d", got, minAmountOut)
+		}
+		if len(ix.Accounts) != E2E_SWAP_ACCOUNTS {
+			return fmt.Errorf("%d accounts, want %d", len(ix.Accounts), E2E_SWAP_ACCOUNTS)
+		}
+		if got := tx.Message.AccountKeys[ix.Accounts[1]]; !got.Equals(pool) {
+			return fmt.Errorf("account 1 is %s, want pool %s", got, pool)
+		}
+		return nil
+	}
+	return fmt.Errorf("no Raydium V4 instruction in transaction")
+}
+
+// e2eTokenBalance returns the raw balance of a token account, 0 if it does not exist
+func e2eTokenBalance(ctx context.Context, client ChainClient, account solana.PublicKey) uint64 {
+	balance, err := client.GetTokenAccountBalance(ctx, account, rpc.CommitmentConfirmed)
+	if err != nil || balance.Value == nil {
+		return 0
+	}
+	var amount uint64
+	fmt.Sscan(balance.Value.Amount, &amount)
+	return amount
+}
+
+// e2eWaitForBalance polls until the token balance moves past before
+func e2eWaitForBalance(ctx context.Context, client ChainClient, account solana.PublicKey, before uint64) (uint64, error) {
+	deadline := time.Now().Add(E2E_BALANCE_TIMEOUT)
+	for time.Now().Before(deadline) {
+		if balance := e2eTokenBalance(ctx, client, account); balance > before {
+			return balance, nil
+		}
+		time.Sleep(time.Second)
+	}
+	return 0, fmt.Errorf("token balance of %s did not increase within %s", account, E2E_BALANCE_TIMEOUT)
+}
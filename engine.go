@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+// How often the engine refreshes its cached blockhash. Blockhashes stay valid
+// for about 60 seconds, so a few seconds keeps one comfortably fresh.
+const ENGINE_BLOCKHASH_REFRESH = 5 * time.Second
+
+// Engine keeps everything a swap needs warm in memory: pool layouts with
+// market accounts, decimals, vault balances, the wallet's token accounts and
+// a recent blockhash. Quote is pure computation and Swap is a single
+// sendTransaction call. Swaps are legacy transactions and use no address
+// lookup tables, so there is no lookup table state to keep.
+type Engine struct {
+	client ChainClient
+	wallet solana.PrivateKey
+	mux    UpdateStream
+	quotes *QuoteCache  // invalidated by follow, nil to quote every request
+	opts   BuildOptions // fee and burn added to swaps
+
+	mu        sync.RWMutex
+	pools     map[solana.PublicKey]*OnChainPool // replaced, never mutated, on update
+	atas      map[solana.PublicKey]bool         // mint -> the wallet's ATA exists
+	blockhash solana.Hash
+	hashTime  time.Time
+}
+
+// NewEngine creates an engine; wallet may be nil for a quote-only engine.
+// Call Run to start the subscriptions and AddPool for every pool to serve.
+func NewEngine(client ChainClient, wallet solana.PrivateKey, wsURL string, opts BuildOptions) *Engine {
+	return &Engine{
+		client: client,
+		wallet: wallet,
+		mux:    newUpdateStream(wsURL),
+		opts:   opts,
+		pools:  make(map[solana.PublicKey]*OnChainPool),
+		atas:   make(map[solana.PublicKey]bool),
+	}
+}
+
+// AddPool loads a pool with its market accounts and the wallet's token
+// accounts, then keeps the pool state and vault balances current
+func (e *Engine) AddPool(ctx context.Context, poolAddress string) error {
+	pool, err := loadPool(ctx, e.client, poolAddress)
+	if err != nil {
+		return err
+	}
+	if err := fetchMarketData(ctx, e.client, pool); err != nil {
+		return fmt.Errorf("failed to fetch market data: %w", err)
+	}
+
+	if e.wallet != nil {
+		for _, mint := range []solana.PublicKey{pool.BaseMint, pool.QuoteMint} {
+			_, createIx, err := getOrCreateATA(ctx, e.client, e.wallet.PublicKey(), mint)
+			if err != nil {
+				return fmt.Errorf("failed to check ATA for %s: %w", mint, err)
+			}
+			e.mu.Lock()
+			e.atas[mint] = createIx == nil
+			e.mu.Unlock()
+		}
+	}
+
+	e.mu.Lock()
+	e.pools[pool.Address] = pool
+	e.mu.Unlock()
+
+	go e.follow(ctx, pool.Address)
+	return nil
+}
+
+// follow applies pool and vault account updates to the cached pool
+func (e *Engine) follow(ctx context.Context, address solana.PublicKey) {
+	e.mu.RLock()
+	pool := e.pools[address]
+	e.mu.RUnlock()
+
+	poolUpdates := e.mux.SubscribeAccount(address)
+	baseUpdates := e.mux.SubscribeAccount(pool.BaseVault)
+	quoteUpdates := e.mux.SubscribeAccount(pool.QuoteVault)
+	defer e.mux.Unsubscribe(poolUpdates)
+	defer e.mux.Unsubscribe(baseUpdates)
+	defer e.mux.Unsubscribe(quoteUpdates)
+
+	for {
+		var apply func(*OnChainPool) error
+		select {
+		case <-ctx.Done():
+			return
+		case u := <-poolUpdates:
+			apply = func(p *OnChainPool) error {
+				// Only the PnL and fee fields move; keep the loaded market accounts
+				fresh, err := parsePoolAccount(address, u.Data)
+				if err != nil {
+					return err
+				}
+				p.NeedTakePnlBase, p.NeedTakePnlQuote = fresh.NeedTakePnlBase, fresh.NeedTakePnlQuote
+				p.SwapFeeNumerator, p.SwapFeeDenominator = fresh.SwapFeeNumerator, fresh.SwapFeeDenominator
+				return nil
+			}
+		case u := <-baseUpdates:
+			apply = func(p *OnChainPool) (err error) {
+				p.BaseAmount, err = decodeTokenAmount(u.Data)
+				return err
+			}
+		case u := <-quoteUpdates:
+			apply = func(p *OnChainPool) (err error) {
+				p.QuoteAmount, err = decodeTokenAmount(u.Data)
+				return err
+			}
+		}
+
+		// Copy on write so readers never see a half-updated pool
+		e.mu.Lock()
+		next := *e.pools[address]
+		if err := apply(&next); err != nil {
+			fmt.Printf("Warning: engine update for %s: %v\n", address, err)
+		} else {
+			e.pools[address] = &next
+		}
+		e.mu.Unlock()
+		e.quotes.Invalidate(address.String())
+	}
+}
+
+// Run keeps the subscriptions and the blockhash fresh until ctx is cancelled
+func (e *Engine) Run(ctx context.Context) error {
+	if err := e.refreshBlockhash(ctx); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := e.mux.Run(ctx); err != nil && ctx.Err() == nil {
+			fmt.Printf("Warning: engine stream stopped: %v\n", err)
+		}
+	}()
+
+	ticker := time.NewTicker(ENGINE_BLOCKHASH_REFRESH)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := e.refreshBlockhash(ctx); err != nil && ctx.Err() == nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		}
+	}
+}
+
+func (e *Engine) refreshBlockhash(ctx context.Context) error {
+	latest, err := e.client.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to refresh blockhash: %w", err)
+	}
+	e.mu.Lock()
+	e.blockhash, e.hashTime = latest.Value.Blockhash, time.Now()
+	e.mu.Unlock()
+	return nil
+}
+
+// pool returns the current snapshot of a loaded pool
+func (e *Engine) pool(poolAddress string) (*OnChainPool, error) {
+	address, err := solana.PublicKeyFromBase58(poolAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool address: %w", err)
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	pool, ok := e.pools[address]
+	if !ok {
+		return nil, fmt.Errorf("pool %s is not loaded in the engine", poolAddress)
+	}
+	return pool, nil
+}
+
+// Quote prices a swap from the cached pool state without any RPC call
+func (e *Engine) Quote(poolAddress string, side string, amount float64) (*SwapQuote, error) {
+	if side != "buy" && side != "sell" {
+		return nil, fmt.Errorf("side must be 'buy' or 'sell'")
+	}
+	if quote := e.quotes.Get(poolAddress, side, amount); quote != nil {
+		return quote, nil
+	}
+	pool, err := e.pool(poolAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceMint, destinationMint, inputDecimals := swapMints(pool, side)
+	amountIn, err := atoms.FromAmount(amount, inputDecimals)
+	if err != nil {
+		return nil, err
+	}
+	amountOut, _ := raydiumSwapBaseIn(pool, amountIn, sourceMint.Equals(pool.BaseMint))
+
+	outputDecimals := int(pool.BaseDecimals)
+	if destinationMint.Equals(pool.QuoteMint) {
+		outputDecimals = int(pool.QuoteDecimals)
+	}
+	tokenMint := destinationMint
+	if side == "sell" {
+		tokenMint = sourceMint
+	}
+	quote := &SwapQuote{
+		PoolAddress:    poolAddress,
+		Side:           side,
+		AmountIn:       amount,
+		ExpectedOut:    atoms.ToAmount(amountOut, outputDecimals),
+		ExpectedOutRaw: amountOut,
+		OutputDecimals: outputDecimals,
+		TokenMint:      tokenMint,
+	}
+	// An update since the snapshot was read has already invalidated the pool
+	e.mu.RLock()
+	if e.pools[pool.Address] == pool {
+		e.quotes.Put(poolAddress, quote)
+	}
+	e.mu.RUnlock()
+	return quote, nil
+}
+
+// Swap signs and sends a quoted swap with the cached blockhash and accounts,
+// returning as soon as the RPC node accepts it. Confirmation is up to the caller.
+func (e *Engine) Swap(ctx context.Context, quote *SwapQuote, slippage float64) (solana.Signature, error) {
+	if e.wallet == nil {
+		return solana.Signature{}, fmt.Errorf("engine has no wallet; set %s", PRIVATE_KEY_ENV_VAR)
+	}
+	pool, err := e.pool(quote.PoolAddress)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	if err := pool.checkTradable(time.Now()); err != nil {
+		return solana.Signature{}, err
+	}
+
+	owner := e.wallet.PublicKey()
+	sourceMint, destinationMint, inputDecimals := swapMints(pool, quote.Side)
+	amountIn, err := atoms.FromAmount(quote.AmountIn, inputDecimals)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+
+	e.mu.RLock()
+	sourceExists, destinationExists := e.atas[sourceMint], e.atas[destinationMint]
+	blockhash, hashTime := e.blockhash, e.hashTime
+	e.mu.RUnlock()
+	if hashTime.IsZero() {
+		return solana.Signature{}, fmt.Errorf("engine has no blockhash yet; is Run started?")
+	}
+
+	var accounts swapAccounts
+	if accounts.Source, err = e.ata(owner, sourceMint, sourceExists, &accounts.CreateSource); err != nil {
+		return solana.Signature{}, err
+	}
+	if accounts.Destination, err = e.ata(owner, destinationMint, destinationExists, &accounts.CreateDestination); err != nil {
+		return solana.Signature{}, err
+	}
+
+	instructions, err := swapInstructions(pool, owner, quote.Side, amountIn, quote.MinAmountOut(slippage), accounts, e.opts)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	tx, err := solana.NewTransaction(instructions, blockhash, solana.TransactionPayer(owner))
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	if err := signTransaction(tx, e.wallet); err != nil {
+		return solana.Signature{}, err
+	}
+
+	sig, err := e.client.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{
+		SkipPreflight:       true,
+		PreflightCommitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to send transaction: %w", err)
+	}
+
+	// The swap creates missing ATAs; a sell closes the WSOL account it unwrapped into
+	e.mu.Lock()
+	e.atas[sourceMint] = true
+	e.atas[destinationMint] = !(destinationMint.Equals(WSOL_MINT) && quote.Side == "sell")
+	e.mu.Unlock()
+
+	return sig, nil
+}
+
+// ata derives the owner's ATA for mint and sets create when it does not exist yet
+func (e *Engine) ata(owner solana.PublicKey, mint solana.PublicKey, exists bool, create *solana.Instruction) (solana.PublicKey, error) {
+	address, _, err := solana.FindAssociatedTokenAddress(owner, mint)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to find ATA: %w", err)
+	}
+	if !exists {
+		*create = newCreateATAInstruction(owner, mint)
+	}
+	return address, nil
+}
+
+// runDaemon warms an Engine for the given pools and serves quote and swap
+// commands from stdin, one per line, printing the latency of each
+func runDaemon(args []string, opts BuildOptions) {
+	fs := newCommandFlagSet("daemon")
+	poolList := fs.String("pools", "", "Comma-separated pool addresses to keep warm")
+	recordPoolCandles := fs.Bool("candles", false, "Record 1m/5m/1h candles of the pools' swaps (query them with the candles command)")
+	quoteTTL := fs.Duration("quote-ttl", DEFAULT_QUOTE_CACHE_TTL, "Serve repeated quotes from memory for this long unless the pool changes, 0 to disable")
+	runQueuedJobs := fs.Bool("jobs", false, "Run the queued orders and schedules (add them with the jobs command)")
+	watch := fs.Bool("watchlist", false, "Monitor the watchlist's tokens and run their actions, reloading the file when it changes")
+	watchlistPath := fs.String("watchlist-file", "", "Watchlist file (defaults to "+WATCHLIST_FILE+" in the state directory)")
+	fs.Parse(args)
+
+	if *poolList == "" && !*runQueuedJobs && !*watch {
+		fmt.Println("Usage: go run . daemon -pools POOL[,POOL...] [-jobs] [-watchlist [-watchlist-file FILE]]")
+		fmt.Println("Then type: quote POOL buy|sell AMOUNT | swap POOL buy|sell AMOUNT SLIPPAGE | quit")
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := newChainClient()
+	wallet, err := loadWallet()
+	var guard *SpendGuard
+	if err != nil {
+		fmt.Printf("No wallet loaded (%v); swaps are disabled\n", err)
+	} else if guard, err = loadSpendGuard(client, wallet.PublicKey()); err != nil {
+		log.Fatalf("Failed to load spend limits: %v", err)
+	}
+	if *runQueuedJobs && wallet == nil {
+		log.Fatal("-jobs requires a wallet")
+	}
+	if *runQueuedJobs || *watch {
+		lock, err := acquireDaemonLock()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer lock.Close()
+	}
+	if *runQueuedJobs {
+		if err := recoverInterruptedJobs(); err != nil {
+			log.Fatalf("Failed to load jobs: %v", err)
+		}
+	}
+
+	engine := NewEngine(client, wallet, resolveWSURL(), opts)
+	engine.quotes = newQuoteCache(*quoteTTL, nil)
+	defer printQuoteCacheStats(engine.quotes)
+	var pools []string
+	if *poolList != "" {
+		pools = strings.Split(*poolList, ",")
+	}
+	for _, address := range pools {
+		address = strings.TrimSpace(address)
+		start := time.Now()
+		if err := engine.AddPool(ctx, address); err != nil {
+			log.Fatalf("Failed to load pool %s: %v", address, err)
+		}
+		fmt.Printf("Loaded pool %s in %s\n", address, time.Since(start).Round(time.Millisecond))
+	}
+
+	if *recordPoolCandles {
+		store, err := openCandleStore()
+		if err != nil {
+			log.Fatalf("Failed to open candle store: %v", err)
+		}
+		for _, address := range pools {
+			go recordCandles(ctx, engine, store, solana.MustPublicKeyFromBase58(strings.TrimSpace(address)))
+		}
+		fmt.Printf("Recording candles in %s\n", store.dir)
+	}
+
+	go func() {
+		if err := engine.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Fatalf("Engine stopped: %v", err)
+		}
+	}()
+	if *runQueuedJobs {
+		scheduler := &jobScheduler{engine: engine, guard: guard}
+		go scheduler.Run(ctx)
+		fmt.Printf("Running queued jobs every %s\n", JOB_TICK)
+	}
+	if *watch {
+		if *watchlistPath == "" {
+			if *watchlistPath, err = defaultWatchlistPath(); err != nil {
+				log.Fatal(err)
+			}
+		}
+		monitor, err := newWatchlistMonitor(engine, guard, wallet, *watchlistPath)
+		if err != nil {
+			log.Fatalf("Failed to load watchlist: %v", err)
+		}
+		go monitor.Run(ctx)
+		fmt.Printf("Watching %d tokens from %s every %s\n", len(monitor.watchlist.Tokens), *watchlistPath, WATCHLIST_TICK)
+	}
+
+	fmt.Println("Engine ready. Commands: quote POOL buy|sell AMOUNT | swap POOL buy|sell AMOUNT SLIPPAGE | quit")
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	for {
+		var line string
+		select {
+		case <-ctx.Done():
+			return
+		case l, ok := <-lines:
+			if !ok {
+				return
+			}
+			line = l
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "exit" {
+			return
+		}
+		if (fields[0] != "quote" || len(fields) != 4) && (fields[0] != "swap" || len(fields) != 5) {
+			fmt.Println("Usage: quote POOL buy|sell AMOUNT | swap POOL buy|sell AMOUNT SLIPPAGE | quit")
+			continue
+		}
+
+		amount, err := parseFloat(fields[3])
+		if err != nil {
+			fmt.Printf("Invalid amount: %v\n", err)
+			continue
+		}
+
+		start := time.Now()
+		quote, err := engine.Quote(fields[1], fields[2], amount)
+		if err != nil {
+			fmt.Printf("Quote failed: %v\n", err)
+			continue
+		}
+		if fields[0] == "quote" {
+			fmt.Printf("Expected Out: %.9f (%s)\n", quote.ExpectedOut, time.Since(start))
+			continue
+		}
+
+		slippage, err := parseFloat(fields[4])
+		if err != nil || slippage <= 0 || slippage > MAX_SLIPPAGE {
+			fmt.Printf("Slippage must be between 0 and %.0f\n", MAX_SLIPPAGE)
+			continue
+		}
+		// stdin is owned by the command reader, so trades needing confirmation are refused
+		if err := guard.Enforce(ctx, quote.GuardedTrade(opts.Burn), false); err != nil {
+			fmt.Printf("Spend limits: %v\n", err)
+			continue
+		}
+		sig, err := engine.Swap(ctx, quote, slippage)
+		if err != nil {
+			guard.Release(quote.GuardedTrade(opts.Burn))
+			fmt.Printf("Swap failed: %v\n", err)
+			continue
+		}
+		if err := guard.Record(quote.GuardedTrade(opts.Burn)); err != nil {
+			fmt.Printf("Warning: Could not record trade for spend limits: %v\n", err)
+		}
+		fmt.Printf("Sent %s in %s (expected out %.9f)\n", sig, time.Since(start).Round(time.Microsecond), quote.ExpectedOut)
+		fmt.Printf("Explorer: %s\n", explorerTxURL(sig.String()))
+	}
+}
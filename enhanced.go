@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+// HELIUS_API_KEY_ENV_VAR enables Helius enhanced transaction parsing in `tx report`
+const HELIUS_API_KEY_ENV_VAR = "HELIUS_API_KEY"
+
+const (
+	HELIUS_ENHANCED_TX_URL = "https://api.helius.xyz/v0/transactions"
+	HELIUS_TIMEOUT         = 10 * time.Second
+)
+
+// Sources of TransactionDetails
+const (
+	DETAILS_SOURCE_HELIUS = "helius"
+	DETAILS_SOURCE_RPC    = "rpc"
+)
+
+// TransactionDetails is a readable breakdown of a transaction: what moved
+// and which programs ran. Helius labels the transaction and pairs token
+// transfers; parsed from RPC data, only each owner's balance changes are known.
+type TransactionDetails struct {
+	Source         string             `json:"source"`
+	Description    string             `json:"description,omitempty"`
+	Type           string             `json:"type,omitempty"`
+	Transfers      []TxTokenTransfer  `json:"transfers,omitempty"`
+	BalanceChanges []TxBalanceChange  `json:"balance_changes,omitempty"`
+	Instructions   []TxInstructionRun `json:"instructions"`
+}
+
+// TxTokenTransfer is one token or SOL transfer between wallets
+type TxTokenTransfer struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Mint   string  `json:"mint"` // SOL_MINT for native transfers
+	Amount float64 `json:"amount"`
+}
+
+// TxBalanceChange is the net change of one owner's balance of a mint
+type TxBalanceChange struct {
+	Owner  string  `json:"owner"`
+	Mint   string  `json:"mint"`
+	Change float64 `json:"change"`
+}
+
+// TxInstructionRun is a program invocation with the ones it made
+type TxInstructionRun struct {
+	Program string             `json:"program"`
+	Name    string             `json:"name,omitempty"` // known program label
+	Inner   []TxInstructionRun `json:"inner,omitempty"`
+}
+
+// programLabels names the programs swaps commonly touch
+var programLabels = map[solana.PublicKey]string{
+	solana.SystemProgramID:                    "System",
+	solana.TokenProgramID:                     "Token",
+	TOKEN_2022_PROGRAM:                        "Token-2022",
+	solana.SPLAssociatedTokenAccountProgramID: "Associated Token",
+	solana.ComputeBudget:                      "Compute Budget",
+	solana.MemoProgramID:                      "Memo",
+	RAYDIUM_AMM_V4:                            "Raydium AMM V4",
+	LAUNCHLAB_PROGRAM:                         "Raydium LaunchLab",
+	PUMPFUN_PROGRAM:                           "pump.fun",
+	METEORA_DLMM_PROGRAM:                      "Meteora DLMM",
+	PHOENIX_PROGRAM:                           "Phoenix",
+	OPENBOOK_PROGRAM:                          "OpenBook",
+	OPENBOOK_V2_PROGRAM:                       "OpenBook v2",
+	SQUADS_V4_PROGRAM:                         "Squads v4",
+}
+
+// heliusAPIKey returns the Helius API key from HELIUS_API_KEY, or from the
+// RPC URL when it is a Helius endpoint
+func heliusAPIKey() string {
+	if key := os.Getenv(HELIUS_API_KEY_ENV_VAR); key != "" {
+		return key
+	}
+	endpoint, err := url.Parse(resolveRPCURL())
+	if err != nil || !strings.HasSuffix(endpoint.Hostname(), "helius-rpc.com") {
+		return ""
+	}
+	return endpoint.Query().Get("api-key")
+}
+
+// newInstructionRun labels an invocation of program
+func newInstructionRun(program solana.PublicKey) TxInstructionRun {
+	return TxInstructionRun{Program: program.String(), Name: programLabels[program]}
+}
+
+// heliusEnhancedTransaction is the part of Helius' enhanced transaction used here
+type heliusEnhancedTransaction struct {
+	Description    string `json:"description"`
+	Type           string `json:"type"`
+	TokenTransfers []struct {
+		FromUserAccount string  `json:"fromUserAccount"`
+		ToUserAccount   string  `json:"toUserAccount"`
+		Mint            string  `json:"mint"`
+		TokenAmount     float64 `json:"tokenAmount"`
+	} `json:"tokenTransfers"`
+	NativeTransfers []struct {
+		FromUserAccount string `json:"fromUserAccount"`
+		ToUserAccount   string `json:"toUserAccount"`
+		Amount          uint64 `json:"amount"` // lamports
+	} `json:"nativeTransfers"`
+	Instructions []heliusInstruction `json:"instructions"`
+}
+
+type heliusInstruction struct {
+	ProgramID         string              `json:"programId"`
+	InnerInstructions []heliusInstruction `json:"innerInstructions"`
+}
+
+// fetchHeliusDetails parses a transaction with Helius' enhanced transactions API
+func fetchHeliusDetails(ctx context.Context, apiKey string, sig solana.Signature) (*TransactionDetails, error) {
+	body, err := json.Marshal(map[string][]string{"transactions": {sig.String()}})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, HELIUS_TIMEOUT)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, HELIUS_ENHANCED_TX_URL+"?api-key="+url.QueryEscape(apiKey), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Helius: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("Helius returned %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	var parsed []heliusEnhancedTransaction
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to decode Helius response: %w", err)
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("Helius does not know the transaction yet")
+	}
+
+	tx := parsed[0]
+	details := &TransactionDetails{Source: DETAILS_SOURCE_HELIUS, Description: tx.Description, Type: tx.Type}
+	for _, t := range tx.NativeTransfers {
+		details.Transfers = append(details.Transfers, TxTokenTransfer{From: t.FromUserAccount, To: t.ToUserAccount, Mint: SOL_MINT.String(), Amount: atoms.ToAmount(t.Amount, SOL_DECIMALS)})
+	}
+	for _, t := range tx.TokenTransfers {
+		details.Transfers = append(details.Transfers, TxTokenTransfer{From: t.FromUserAccount, To: t.ToUserAccount, Mint: t.Mint, Amount: t.TokenAmount})
+	}
+	var convert func([]heliusInstruction) []TxInstructionRun
+	convert = func(instructions []heliusInstruction) []TxInstructionRun {
+		var runs []TxInstructionRun
+		for _, ix := range instructions {
+			run := TxInstructionRun{Program: ix.ProgramID}
+			if program, err := solana.PublicKeyFromBase58(ix.ProgramID); err == nil {
+				run = newInstructionRun(program)
+			}
+			run.Inner = convert(ix.InnerInstructions)
+			runs = append(runs, run)
+		}
+		return runs
+	}
+	details.Instructions = convert(tx.Instructions)
+	return details, nil
+}
+
+// rawTransactionDetails breaks a transaction down from its RPC data: the
+// token balance changes per owner, the fee payer's SOL change and the
+// programs invoked, with inner instructions under the instruction that made them
+func rawTransactionDetails(tx *rpc.GetTransactionResult, parsed *solana.Transaction) *TransactionDetails {
+	details := &TransactionDetails{Source: DETAILS_SOURCE_RPC}
+
+	keys := append(solana.PublicKeySlice{}, parsed.Message.AccountKeys...)
+	keys = append(keys, tx.Meta.LoadedAddresses.Writable...)
+	keys = append(keys, tx.Meta.LoadedAddresses.ReadOnly...)
+	program := func(index uint16) TxInstructionRun {
+		if int(index) >= len(keys) {
+			return TxInstructionRun{Program: "?"}
+		}
+		return newInstructionRun(keys[index])
+	}
+	for i, ix := range parsed.Message.Instructions {
+		run := program(ix.ProgramIDIndex)
+		for _, inner := range tx.Meta.InnerInstructions {
+			if int(inner.Index) != i {
+				continue
+			}
+			for _, innerIx := range inner.Instructions {
+				run.Inner = append(run.Inner, program(innerIx.ProgramIDIndex))
+			}
+		}
+		details.Instructions = append(details.Instructions, run)
+	}
+
+	// Sum the raw token balances by owner and mint before and after
+	type holding struct{ owner, mint string }
+	changes := make(map[holding]int64)
+	decimals := make(map[string]uint8)
+	add := func(balances []rpc.TokenBalance, sign int64) {
+		for _, balance := range balances {
+			if balance.Owner == nil || balance.UiTokenAmount == nil {
+				continue
+			}
+			amount, err := strconv.ParseInt(balance.UiTokenAmount.Amount, 10, 64)
+			if err != nil {
+				continue
+			}
+			changes[holding{balance.Owner.String(), balance.Mint.String()}] += sign * amount
+			decimals[balance.Mint.String()] = balance.UiTokenAmount.Decimals
+		}
+	}
+	add(tx.Meta.PreTokenBalances, -1)
+	add(tx.Meta.PostTokenBalances, 1)
+	for key, change := range changes {
+		if change != 0 {
+			details.BalanceChanges = append(details.BalanceChanges, TxBalanceChange{Owner: key.owner, Mint: key.mint, Change: float64(change) / math.Pow(10, float64(decimals[key.mint]))})
+		}
+	}
+	if len(tx.Meta.PreBalances) > 0 && len(tx.Meta.PostBalances) > 0 && len(keys) > 0 {
+		lamports := int64(tx.Meta.PostBalances[0]) - int64(tx.Meta.PreBalances[0])
+		details.BalanceChanges = append(details.BalanceChanges, TxBalanceChange{Owner: keys[0].String(), Mint: SOL_MINT.String(), Change: float64(lamports) / math.Pow(10, SOL_DECIMALS)})
+	}
+	sort.Slice(details.BalanceChanges, func(i, j int) bool {
+		a, b := details.BalanceChanges[i], details.BalanceChanges[j]
+		if a.Owner != b.Owner {
+			return a.Owner < b.Owner
+		}
+		return a.Mint < b.Mint
+	})
+	return details
+}
+
+// transactionDetails parses a transaction with Helius when an API key is
+// set, and from the RPC's data otherwise or when Helius fails
+func transactionDetails(ctx context.Context, client ChainClient, heliusKey string, sig solana.Signature) (*TransactionDetails, error) {
+	if heliusKey != "" {
+		details, err := fetchHeliusDetails(ctx, heliusKey, sig)
+		if err == nil {
+			return details, nil
+		}
+		fmt.Printf("Warning: Helius parsing failed, using raw parsing: %v\n", err)
+	}
+	tx, err := fetchTransaction(ctx, client, sig.String())
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := tx.Transaction.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	return rawTransactionDetails(tx, parsed), nil
+}
+
+// printTransactionDetails prints the transfers and the instruction tree
+func printTransactionDetails(ctx context.Context, client ChainClient, details *TransactionDetails) {
+	label := func(mint string) string {
+		if mint == SOL_MINT.String() || mint == WSOL_MINT.String() {
+			return "SOL"
+		}
+		key, err := solana.PublicKeyFromBase58(mint)
+		if err != nil {
+			return mint
+		}
+		if symbol := tokenSymbol(ctx, client, key); symbol != "" {
+			return symbol
+		}
+		return mint
+	}
+
+	fmt.Printf("\n=== TRANSACTION DETAILS (%s) ===\n", details.Source)
+	if details.Description != "" {
+		fmt.Printf("Description: %s\n", details.Description)
+	}
+	if details.Type != "" {
+		fmt.Printf("Type: %s\n", details.Type)
+	}
+	if len(details.Transfers) > 0 {
+		fmt.Println("Transfers:")
+		for _, t := range details.Transfers {
+			fmt.Printf("  %s -> %s: %.9g %s\n", t.From, t.To, t.Amount, label(t.Mint))
+		}
+	}
+	if len(details.BalanceChanges) > 0 {
+		fmt.Println("Balance Changes:")
+		for _, c := range details.BalanceChanges {
+			fmt.Printf("  %s: %+.9g %s\n", c.Owner, c.Change, label(c.Mint))
+		}
+	}
+	fmt.Println("Instructions:")
+	var printRuns func(runs []TxInstructionRun, indent string)
+	printRuns = func(runs []TxInstructionRun, indent string) {
+		for i, run := range runs {
+			name := run.Program
+			if run.Name != "" {
+				name = fmt.Sprintf("%s (%s)", run.Name, run.Program)
+			}
+			fmt.Printf("%s%d. %s\n", indent, i+1, name)
+			printRuns(run.Inner, indent+"   ")
+		}
+	}
+	printRuns(details.Instructions, "  ")
+	fmt.Println("================================")
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// EXPLORER_ENV_VAR selects the explorer of printed links; the -explorer flag overrides it
+const EXPLORER_ENV_VAR = "SOLANA_EXPLORER"
+
+const DEFAULT_EXPLORER = "solscan"
+
+// ExplorerProvider formats links to a block explorer. Clusters maps each
+// cluster the explorer shows to its query string; other clusters link to the
+// Solana Explorer, which also follows custom RPCs.
+type ExplorerProvider struct {
+	Name        string
+	BaseURL     string
+	TxPath      string
+	AccountPath string
+	Clusters    map[string]string
+	CustomRPC   bool // shows a custom RPC given as customUrl
+}
+
+var explorers = map[string]ExplorerProvider{
+	"solscan": {
+		Name:        "solscan",
+		BaseURL:     "https://solscan.io",
+		TxPath:      "tx",
+		AccountPath: "account",
+		Clusters:    map[string]string{"mainnet": "", "devnet": "cluster=devnet", "testnet": "cluster=testnet"},
+		CustomRPC:   true,
+	},
+	"solanafm": {
+		Name:        "solanafm",
+		BaseURL:     "https://solana.fm",
+		TxPath:      "tx",
+		AccountPath: "address",
+		Clusters:    map[string]string{"mainnet": "", "devnet": "cluster=devnet-solana", "testnet": "cluster=testnet-solana"},
+	},
+	"xray": {
+		Name:        "xray",
+		BaseURL:     "https://xray.helius.xyz",
+		TxPath:      "tx",
+		AccountPath: "account",
+		Clusters:    map[string]string{"mainnet": "", "devnet": "network=devnet"},
+	},
+	"explorer": {
+		Name:        "explorer",
+		BaseURL:     "https://explorer.solana.com",
+		TxPath:      "tx",
+		AccountPath: "address",
+		Clusters:    map[string]string{"mainnet": "", "devnet": "cluster=devnet", "testnet": "cluster=testnet"},
+		CustomRPC:   true,
+	},
+}
+
+// activeExplorer is the explorer selected with -explorer or SOLANA_EXPLORER
+var activeExplorer = explorers[DEFAULT_EXPLORER]
+
+// selectExplorer switches printed links to the named explorer
+func selectExplorer(name string) error {
+	if name == "" {
+		name = DEFAULT_EXPLORER
+	}
+	explorer, ok := explorers[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(explorers))
+		for name := range explorers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown explorer %q (supported: %s)", name, strings.Join(names, ", "))
+	}
+	activeExplorer = explorer
+	return nil
+}
+
+// link formats a transaction or account link on the explorer for the active cluster
+func (e ExplorerProvider) link(account bool, id string) string {
+	query, ok := e.Clusters[activeCluster.Name]
+	if activeCluster.Name == "custom" && e.CustomRPC {
+		query, ok = "cluster=custom&customUrl="+url.QueryEscape(resolveRPCURL()), true
+	}
+	if !ok {
+		return explorers["explorer"].link(account, id)
+	}
+
+	path := e.TxPath
+	if account {
+		path = e.AccountPath
+	}
+	link := e.BaseURL + "/" + path + "/" + id
+	if query != "" {
+		link += "?" + query
+	}
+	return link
+}
+
+// explorerTxURL returns the link for a transaction on the active cluster
+func explorerTxURL(signature string) string {
+	return activeExplorer.link(false, signature)
+}
+
+// explorerAccountURL returns the link for a wallet, mint or pool on the active cluster
+func explorerAccountURL(address string) string {
+	return activeExplorer.link(true, address)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/mr-tron/base58"
+)
+
+// exportTransaction writes a transaction (unsigned or partially signed) to a
+// file for offline or multisig signing
+func exportTransaction(tx *solana.Transaction, path string, encoding string) error {
+	raw, err := marshalUnsigned(tx)
+	if err != nil {
+		return err
+	}
+
+	var encoded string
+	switch encoding {
+	case "base64":
+		encoded = base64.StdEncoding.EncodeToString(raw)
+	case "base58":
+		encoded = base58.Encode(raw)
+	default:
+		return fmt.Errorf("unsupported encoding %q (supported: base64, base58)", encoding)
+	}
+
+	if err := os.WriteFile(path, []byte(encoded+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write transaction file: %w", err)
+	}
+
+	return nil
+}
+
+// marshalUnsigned serializes a transaction that still lacks signatures
+func marshalUnsigned(tx *solana.Transaction) ([]byte, error) {
+	// Wallets expect one signature slot per required signer, zero-filled when unsigned
+	required := int(tx.Message.Header.NumRequiredSignatures)
+	for len(tx.Signatures) < required {
+		tx.Signatures = append(tx.Signatures, solana.Signature{})
+	}
+
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize transaction: %w", err)
+	}
+	return raw, nil
+}
+
+// decodeTransaction parses a serialized transaction in base64 or base58.
+// Every base58 string of a length divisible by four is also valid base64, so
+// both decodings are tried and the one that parses as a transaction is kept.
+func decodeTransaction(encoded string) (*solana.Transaction, error) {
+	encoded = strings.TrimSpace(encoded)
+
+	var parseErr error
+	for _, decode := range []func(string) ([]byte, error){base64.StdEncoding.DecodeString, base58.Decode} {
+		raw, err := decode(encoded)
+		if err != nil {
+			continue
+		}
+		tx, err := solana.TransactionFromBytes(raw)
+		if err == nil {
+			return tx, nil
+		}
+		parseErr = err
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", parseErr)
+	}
+	return nil, fmt.Errorf("transaction is neither valid base64 nor base58")
+}
+
+// runBroadcast sends an externally signed transaction
+func runBroadcast(args []string, opts BuildOptions) {
+	fs := newCommandFlagSet("broadcast")
+	var file string
+	fs.StringVar(&file, "file", "", "File containing the signed transaction (base64 or base58)")
+	fs.Parse(args)
+
+	var encoded string
+	switch {
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Fatalf("Failed to read transaction file: %v", err)
+		}
+		encoded = string(data)
+	case fs.NArg() == 1:
+		encoded = fs.Arg(0)
+	default:
+		fmt.Println("Usage: go run . broadcast [-file TX_FILE | SIGNED_TX]")
+		fs.PrintDefaults()
+		return
+	}
+
+	tx, err := decodeTransaction(encoded)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := tx.VerifySignatures(); err != nil {
+		log.Fatalf("Transaction is not fully signed: %v", err)
+	}
+
+	fmt.Printf("\n=== BROADCAST ===\n")
+	fmt.Printf("Fee payer: %s\n", tx.Message.AccountKeys[0])
+	fmt.Printf("Signatures: %d\n", len(tx.Signatures))
+	fmt.Printf("Instructions: %d\n", len(tx.Message.Instructions))
+	fmt.Printf("=================\n")
+
+	ctx := interruptContext()
+	client := newChainClient()
+
+	sig, err := sendAndConfirmTransaction(ctx, client, tx, opts)
+	if err != nil {
+		log.Fatalf("Broadcast failed: %v", err)
+	}
+
+	fmt.Printf("\n✅ Transaction broadcast successfully!\n")
+	fmt.Printf("Transaction: %s\n", sig)
+	fmt.Printf("Explorer: %s\n", explorerTxURL(sig.String()))
+}
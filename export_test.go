@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+)
+
+// unsignedTransfer builds transfers whose base58 encoding has the given
+// length modulo four, by varying their number
+func unsignedTransfer(t *testing.T, base58Mod int) *solana.Transaction {
+	t.Helper()
+	from := solana.MustPublicKeyFromBase58("9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM")
+	to := solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qNrmqrfWwzkQfD6h2t9P1aHA8v")
+	dir := t.TempDir()
+	var instructions []solana.Instruction
+	for len(instructions) < 16 {
+		instructions = append(instructions, system.NewTransferInstruction(uint64(len(instructions)+1), from, to).Build())
+		tx, err := solana.NewTransaction(
+			instructions,
+			solana.Hash{1, 2, 3},
+			solana.TransactionPayer(from),
+		)
+		if err != nil {
+			t.Fatalf("NewTransaction: %v", err)
+		}
+		path := filepath.Join(dir, "tx.b58")
+		if err := exportTransaction(tx, path, "base58"); err != nil {
+			t.Fatalf("exportTransaction: %v", err)
+		}
+		data, _ := os.ReadFile(path)
+		if len(strings.TrimSpace(string(data)))%4 == base58Mod {
+			return tx
+		}
+	}
+	t.Fatalf("no transfer with a base58 length of %d mod 4", base58Mod)
+	return nil
+}
+
+func TestDecodeTransaction(t *testing.T) {
+	tests := []struct {
+		name      string
+		encoding  string
+		base58Mod int
+	}{
+		{"base64", "base64", 0},
+		{"base58 that is also valid base64", "base58", 0},
+		{"base58", "base58", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := unsignedTransfer(t, tt.base58Mod)
+			path := filepath.Join(t.TempDir(), "tx")
+			if err := exportTransaction(tx, path, tt.encoding); err != nil {
+				t.Fatalf("exportTransaction: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := decodeTransaction(string(data))
+			if err != nil {
+				t.Fatalf("decodeTransaction: %v", err)
+			}
+			want, _ := tx.Message.MarshalBinary()
+			got, _ := decoded.Message.MarshalBinary()
+			if string(got) != string(want) {
+				t.Errorf("decoded a different message")
+			}
+		})
+	}
+
+	if _, err := decodeTransaction("not a transaction!"); err == nil {
+		t.Errorf("decodeTransaction accepted garbage")
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/gagliardetto/solana-go"
+	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// FEE_PAYER_KEY_ENV_VAR holds the base58 key of a separate fee payer
+const FEE_PAYER_KEY_ENV_VAR = "FEE_PAYER_PRIVATE_KEY"
+
+const (
+	DEFAULT_COMPUTE_UNITS_PER_IX = 200_000
+	MICRO_LAMPORTS_PER_LAMPORT   = 1_000_000
+)
+
+// FeePayerConfig is a separate key that pays a swap's network fee, priority
+// fee and account rent, so a wallet holding only tokens can still sell. The
+// wallet reimburses the fee payer out of the swap's proceeds in the same
+// transaction, so the sponsor never ends up out of pocket.
+type FeePayerConfig struct {
+	Key solana.PrivateKey
+}
+
+// loadFeePayer reads the fee payer key from the environment; the main
+// -fee-payer flag can point at a key file instead
+func loadFeePayer() (FeePayerConfig, error) {
+	value := os.Getenv(FEE_PAYER_KEY_ENV_VAR)
+	if value == "" {
+		return FeePayerConfig{}, nil
+	}
+	key, err := solana.PrivateKeyFromBase58(value)
+	if err != nil {
+		return FeePayerConfig{}, fmt.Errorf("invalid %s: %w", FEE_PAYER_KEY_ENV_VAR, err)
+	}
+	return FeePayerConfig{Key: key}, nil
+}
+
+// loadFeePayerFile reads the fee payer key from a Solana CLI keypair file
+func loadFeePayerFile(path string) (FeePayerConfig, error) {
+	key, err := solana.PrivateKeyFromSolanaKeygenFile(path)
+	if err != nil {
+		return FeePayerConfig{}, fmt.Errorf("failed to read fee payer key: %w", err)
+	}
+	return FeePayerConfig{Key: key}, nil
+}
+
+// Enabled reports whether swaps are paid for by a separate key
+func (c FeePayerConfig) Enabled() bool {
+	return len(c.Key) > 0
+}
+
+// payer returns the account paying for a swap by owner
+func (c FeePayerConfig) payer(owner solana.PublicKey) solana.PublicKey {
+	if !c.sponsored(owner) {
+		return owner
+	}
+	return c.Key.PublicKey()
+}
+
+// signers returns the keys a swap by wallet is signed with
+func (c FeePayerConfig) signers(wallet solana.PrivateKey) []solana.PrivateKey {
+	if !c.Enabled() || c.Key.PublicKey().Equals(wallet.PublicKey()) {
+		return []solana.PrivateKey{wallet}
+	}
+	return []solana.PrivateKey{wallet, c.Key}
+}
+
+// sponsored reports whether the fee payer pays for owner's swaps
+func (c FeePayerConfig) sponsored(owner solana.PublicKey) bool {
+	return c.Enabled() && !c.Key.PublicKey().Equals(owner)
+}
+
+// sponsorAccounts has the fee payer fund the owner's missing token accounts
+func (c FeePayerConfig) sponsorAccounts(owner solana.PublicKey, sourceMint solana.PublicKey, destinationMint solana.PublicKey, accounts *swapAccounts) {
+	payer := c.Key.PublicKey()
+	if accounts.CreateSource != nil {
+		accounts.CreateSource = associatedtokenaccount.NewCreateInstruction(payer, owner, sourceMint).Build()
+	}
+	if accounts.CreateDestination != nil {
+		accounts.CreateDestination = associatedtokenaccount.NewCreateInstruction(payer, owner, destinationMint).Build()
+	}
+}
+
+// rentSponsored returns the rent of the token accounts the fee payer funds
+// for a swap. Accounts closed in the same transaction hand their rent to the
+// owner, so they are reimbursed like the ones that stay open.
+func (c FeePayerConfig) rentSponsored(ctx context.Context, client ChainClient, accounts swapAccounts) (uint64, error) {
+	created := uint64(0)
+	if accounts.CreateSource != nil {
+		created++
+	}
+	if accounts.CreateDestination != nil {
+		created++
+	}
+	if created == 0 {
+		return 0, nil
+	}
+	rent, err := client.GetMinimumBalanceForRentExemption(ctx, TOKEN_ACCOUNT_SIZE, rpc.CommitmentConfirmed)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get token account rent: %w", err)
+	}
+	return created * rent, nil
+}
+
+// reimbursementInstruction transfers what the fee payer spent on a swap back
+// from the owner: the signature fees, the priority fee implied by the
+// compute budget instructions and the rent it funded
+func (c FeePayerConfig) reimbursementInstruction(owner solana.PublicKey, instructions []solana.Instruction, rent uint64) solana.Instruction {
+	limit, price := computeBudgetOf(instructions)
+	priority := (uint64(limit)*price + MICRO_LAMPORTS_PER_LAMPORT - 1) / MICRO_LAMPORTS_PER_LAMPORT
+	amount := 2*LAMPORTS_PER_SIGNATURE + priority + rent
+	return system.NewTransferInstruction(amount, owner, c.Key.PublicKey()).Build()
+}
+
+// computeBudgetOf returns the compute unit limit and price (micro-lamports
+// per unit) a set of instructions runs with. Without a limit instruction the
+// runtime grants the default per non compute budget instruction.
+func computeBudgetOf(instructions []solana.Instruction) (uint32, uint64) {
+	var limit uint32
+	var price uint64
+	hasLimit := false
+	others := uint32(0)
+	for _, ix := range instructions {
+		if !ix.ProgramID().Equals(solana.ComputeBudget) {
+			others++
+			continue
+		}
+		data, err := ix.Data()
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		switch data[0] {
+		case computebudget.Instruction_SetComputeUnitLimit:
+			if len(data) >= 5 {
+				limit = binary.LittleEndian.Uint32(data[1:5])
+				hasLimit = true
+			}
+		case computebudget.Instruction_SetComputeUnitPrice:
+			if len(data) >= 9 {
+				price = binary.LittleEndian.Uint64(data[1:9])
+			}
+		}
+	}
+	if !hasLimit {
+		limit = min(others*DEFAULT_COMPUTE_UNITS_PER_IX, MAX_COMPUTE_UNIT_LIMIT)
+	}
+	return limit, price
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Environment variables selecting a Yellowstone Geyser gRPC endpoint instead of the websocket
+const (
+	GEYSER_URL_ENV_VAR   = "GEYSER_URL"
+	GEYSER_TOKEN_ENV_VAR = "GEYSER_TOKEN"
+)
+
+const (
+	GEYSER_SUBSCRIBE_PATH       = "/geyser.Geyser/Subscribe"
+	GEYSER_PING_INTERVAL        = 10 * time.Second // keeps load balancers from closing idle streams
+	GEYSER_COMMITMENT_CONFIRMED = 1
+	GEYSER_MAX_MESSAGE_LEN      = 64 << 20 // account updates carry the full account data
+)
+
+// GeyserStream implements UpdateStream over a Yellowstone gRPC Subscribe
+// stream. Every subscription becomes a named filter; each update lists the
+// filters it matched, so it is routed without inspecting its contents.
+// Like StreamMux, it resubscribes after reconnects and drops the oldest
+// update when a subscriber falls behind.
+type GeyserStream struct {
+	url   string
+	token string
+	http  *http.Client
+
+	mu          sync.Mutex
+	subs        map[string]*streamSub
+	resubscribe chan struct{} // signals the current connection to resend the filters
+	reconnects  int
+}
+
+// newGeyserStream creates a stream for the given endpoint; token is sent as x-token when set
+func newGeyserStream(url string, token string) *GeyserStream {
+	// Geyser endpoints speak HTTP/2 only, over TLS or in cleartext
+	protocols := new(http.Protocols)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+
+	return &GeyserStream{
+		url:   strings.TrimSuffix(url, "/"),
+		token: token,
+		http:  &http.Client{Transport: &http.Transport{Protocols: protocols}},
+		subs:  make(map[string]*streamSub),
+	}
+}
+
+// SubscribeAccount registers interest in an account and returns a new update channel
+func (g *GeyserStream) SubscribeAccount(key solana.PublicKey) <-chan StreamUpdate {
+	return g.subscribe("account", key)
+}
+
+// SubscribeLogs registers interest in transactions mentioning an address
+func (g *GeyserStream) SubscribeLogs(key solana.PublicKey) <-chan StreamUpdate {
+	return g.subscribe("logs", key)
+}
+
+func (g *GeyserStream) subscribe(kind string, key solana.PublicKey) <-chan StreamUpdate {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := kind + ":" + key.String()
+	if sub, ok := g.subs[id]; ok {
+		return sub.listen()
+	}
+	sub := newStreamSub(kind, key)
+	g.subs[id] = sub
+	g.resendFilters()
+	return sub.listen()
+}
+
+// Unsubscribe stops delivering to a channel returned by SubscribeAccount or
+// SubscribeLogs. The filter is dropped with its last subscriber.
+func (g *GeyserStream) Unsubscribe(updates <-chan StreamUpdate) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if removeListener(g.subs, updates) {
+		g.resendFilters()
+	}
+}
+
+// resendFilters asks the current connection to send the filters again; g.mu
+// must be held
+func (g *GeyserStream) resendFilters() {
+	if g.resubscribe != nil {
+		select {
+		case g.resubscribe <- struct{}{}:
+		default: // a resend is already pending and will include the change
+		}
+	}
+}
+
+// Stats reports reconnect count and updates dropped due to backpressure per subscription
+func (g *GeyserStream) Stats() (reconnects int, dropped map[string]uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	dropped = make(map[string]uint64)
+	for id, sub := range g.subs {
+		if n := sub.dropped.Load(); n > 0 {
+			dropped[id] = n
+		}
+	}
+	return g.reconnects, dropped
+}
+
+// Run maintains the stream until ctx is cancelled, reconnecting with
+// exponential backoff
+func (g *GeyserStream) Run(ctx context.Context) error {
+	backoff := STREAM_RECONNECT_MIN
+	for {
+		connected, err := g.session(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if connected {
+			backoff = STREAM_RECONNECT_MIN
+			g.mu.Lock()
+			g.reconnects++
+			g.mu.Unlock()
+		}
+		fmt.Printf("Warning: geyser stream failed: %v (retrying in %s)\n", err, backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, STREAM_RECONNECT_MAX)
+	}
+}
+
+// session runs one Subscribe call until it fails. connected reports whether
+// the server accepted the stream.
+func (g *GeyserStream) session(ctx context.Context) (connected bool, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resubscribe := make(chan struct{}, 1)
+	resubscribe <- struct{}{}
+	pong := make(chan struct{}, 1)
+	g.mu.Lock()
+	g.resubscribe = resubscribe
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		g.resubscribe = nil
+		g.mu.Unlock()
+	}()
+
+	body, writer := io.Pipe()
+	go g.writeRequests(ctx, writer, resubscribe, pong)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url+GEYSER_SUBSCRIBE_PATH, body)
+	if err != nil {
+		return false, fmt.Errorf("invalid geyser URL: %w", err)
+	}
+	req.Header.Set("Content-Type", GRPC_CONTENT_TYPE)
+	req.Header.Set("TE", "trailers")
+	if g.token != "" {
+		req.Header.Set("x-token", g.token)
+	}
+
+	resp, err := g.http.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected HTTP status %s", resp.Status)
+	}
+	// Errors before any message come back as a headers-only response
+	if status := resp.Header.Get("Grpc-Status"); status != "" && status != "0" {
+		return false, fmt.Errorf("subscribe refused (status %s): %s", status, resp.Header.Get("Grpc-Message"))
+	}
+
+	for {
+		msg, err := readGRPCFrame(resp.Body)
+		if errors.Is(err, io.EOF) {
+			return true, fmt.Errorf("stream closed (status %s): %s", resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message"))
+		}
+		if err != nil {
+			return true, err
+		}
+		if err := g.dispatch(msg, pong); err != nil {
+			fmt.Printf("Warning: geyser update: %v\n", err)
+		}
+	}
+}
+
+// writeRequests sends the filters whenever they change and pings the server
+// until ctx is cancelled or the request body is closed
+func (g *GeyserStream) writeRequests(ctx context.Context, w *io.PipeWriter, resubscribe <-chan struct{}, pong <-chan struct{}) {
+	defer w.Close()
+	ticker := time.NewTicker(GEYSER_PING_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		ping := false
+		select {
+		case <-ctx.Done():
+			return
+		case <-resubscribe:
+		case <-ticker.C:
+			ping = true
+		case <-pong:
+			ping = true
+		}
+
+		// Pings repeat the filters, since servers may treat any request as a replacement
+		g.mu.Lock()
+		msg := g.subscribeRequest()
+		g.mu.Unlock()
+		if ping {
+			var id protoEncoder
+			id.Uint64(1, 1) // SubscribeRequestPing.id
+			msg.Message(9, id)
+		}
+		if err := writeGRPCFrame(w, msg); err != nil {
+			return
+		}
+	}
+}
+
+// subscribeRequest encodes a SubscribeRequest with one named filter per
+// subscription. Callers hold g.mu.
+func (g *GeyserStream) subscribeRequest() protoEncoder {
+	var req protoEncoder
+	for id, sub := range g.subs {
+		var filter, entry protoEncoder
+		entry.String(1, id) // map key
+		switch sub.kind {
+		case "account":
+			filter.String(2, sub.key.String()) // SubscribeRequestFilterAccounts.account
+			entry.Message(2, filter)
+			req.Message(1, entry) // accounts
+		case "logs":
+			filter.String(3, sub.key.String()) // SubscribeRequestFilterTransactions.account_include
+			entry.Message(2, filter)
+			req.Message(3, entry) // transactions
+		}
+	}
+	req.Uint64(6, GEYSER_COMMITMENT_CONFIRMED) // commitment
+	return req
+}
+
+// dispatch routes a SubscribeUpdate to the subscriptions whose filters it matched
+func (g *GeyserStream) dispatch(data []byte, pong chan<- struct{}) error {
+	var filters []string
+	var account, transaction []byte
+	err := walkProto(data, func(field int, value any) {
+		switch field {
+		case 1: // filters
+			filters = append(filters, string(value.([]byte)))
+		case 2: // account
+			account, _ = value.([]byte)
+		case 4: // transaction
+			transaction, _ = value.([]byte)
+		case 6: // ping: answer so the server keeps the stream open
+			select {
+			case pong <- struct{}{}:
+			default:
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	var update StreamUpdate
+	switch {
+	case account != nil:
+		msg, err := decodeProto(account) // SubscribeUpdateAccount
+		if err != nil {
+			return err
+		}
+		info, err := decodeProto(msg.Bytes(1)) // SubscribeUpdateAccountInfo
+		if err != nil {
+			return err
+		}
+		update = StreamUpdate{Kind: "account", Slot: msg.Uint64(2), Data: info.Bytes(6)}
+
+	case transaction != nil:
+		msg, err := decodeProto(transaction) // SubscribeUpdateTransaction
+		if err != nil {
+			return err
+		}
+		info, err := decodeProto(msg.Bytes(1)) // SubscribeUpdateTransactionInfo
+		if err != nil {
+			return err
+		}
+		update = StreamUpdate{Kind: "logs", Slot: msg.Uint64(2), Signature: solana.SignatureFromBytes(info.Bytes(1))}
+		// TransactionStatusMeta: err = 1, log_messages = 6 (repeated)
+		err = walkProto(info.Bytes(4), func(field int, value any) {
+			switch field {
+			case 1:
+				update.Failed = true
+			case 6:
+				update.Logs = append(update.Logs, string(value.([]byte)))
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+	default:
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, id := range filters {
+		if sub, ok := g.subs[id]; ok {
+			update.Key = sub.key
+			sub.deliver(update)
+		}
+	}
+	return nil
+}
+
+// readGRPCFrame reads one length-prefixed message from a gRPC stream
+func readGRPCFrame(r io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if header[0] != 0 {
+		return nil, fmt.Errorf("compressed messages are not supported")
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > GEYSER_MAX_MESSAGE_LEN {
+		return nil, fmt.Errorf("message too large: %d bytes", length)
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, fmt.Errorf("truncated message: %w", err)
+	}
+	return msg, nil
+}
+
+// writeGRPCFrame writes one length-prefixed message to a gRPC stream
+func writeGRPCFrame(w io.Writer, msg protoEncoder) error {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	_, err := w.Write(append(frame, msg...))
+	return err
+}
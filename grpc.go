@@ -0,0 +1,527 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// The gRPC service defined in proto/raydium.proto is served with the standard
+// library: gRPC is length-prefixed protobuf messages over HTTP/2 with the
+// status carried in trailers, and the messages are small enough to encode by hand.
+
+// gRPC settings
+const (
+	DEFAULT_GRPC_ADDR    = "127.0.0.1:50051"
+	GRPC_SERVICE_PATH    = "/raydium.v1.Raydium/"
+	GRPC_CONTENT_TYPE    = "application/grpc"
+	GRPC_MAX_MESSAGE_LEN = 1 << 20
+)
+
+// gRPC status codes used by the server
+const (
+	GRPC_OK                  = 0
+	GRPC_INVALID_ARGUMENT    = 3
+	GRPC_ALREADY_EXISTS      = 6
+	GRPC_PERMISSION_DENIED   = 7
+	GRPC_FAILED_PRECONDITION = 9
+	GRPC_UNIMPLEMENTED       = 12
+	GRPC_INTERNAL            = 13
+)
+
+// grpcError is an error carrying a gRPC status code
+type grpcError struct {
+	code    int
+	message string
+}
+
+func (e *grpcError) Error() string {
+	return e.message
+}
+
+func grpcErrorf(code int, format string, args ...any) error {
+	return &grpcError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// protoMessage is a decoded protobuf message: field number -> last raw value.
+// Varints are stored as uint64, fixed64 as their bits and bytes as []byte.
+type protoMessage map[int]any
+
+// decodeProto decodes the scalar and length-delimited fields of a message
+func decodeProto(data []byte) (protoMessage, error) {
+	msg := make(protoMessage)
+	err := walkProto(data, func(field int, value any) {
+		msg[field] = value
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// walkProto calls fn for every field of a message in order, so repeated
+// fields are seen once per element. Values are typed as in protoMessage;
+// fixed32 fields are skipped.
+func walkProto(data []byte, fn func(field int, value any)) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("invalid field key")
+		}
+		data = data[n:]
+		field, wireType := int(key>>3), key&7
+
+		switch wireType {
+		case 0: // varint
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return fmt.Errorf("invalid varint in field %d", field)
+			}
+			fn(field, v)
+			data = data[n:]
+		case 1: // fixed64
+			if len(data) < 8 {
+				return fmt.Errorf("truncated fixed64 in field %d", field)
+			}
+			fn(field, binary.LittleEndian.Uint64(data[:8]))
+			data = data[8:]
+		case 2: // length-delimited
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return fmt.Errorf("truncated bytes in field %d", field)
+			}
+			fn(field, data[n:n+int(length)])
+			data = data[n+int(length):]
+		case 5: // fixed32
+			if len(data) < 4 {
+				return fmt.Errorf("truncated fixed32 in field %d", field)
+			}
+			data = data[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d in field %d", wireType, field)
+		}
+	}
+	return nil
+}
+
+func (m protoMessage) String(field int) string {
+	if b, ok := m[field].([]byte); ok {
+		return string(b)
+	}
+	return ""
+}
+
+func (m protoMessage) Bytes(field int) []byte {
+	b, _ := m[field].([]byte)
+	return b
+}
+
+func (m protoMessage) Uint64(field int) uint64 {
+	v, _ := m[field].(uint64)
+	return v
+}
+
+func (m protoMessage) Double(field int) float64 {
+	if v, ok := m[field].(uint64); ok {
+		return math.Float64frombits(v)
+	}
+	return 0
+}
+
+// protoEncoder appends fields in proto3 encoding, skipping default values
+type protoEncoder []byte
+
+func (e *protoEncoder) String(field int, s string) {
+	if s == "" {
+		return
+	}
+	*e = binary.AppendUvarint(*e, uint64(field)<<3|2)
+	*e = binary.AppendUvarint(*e, uint64(len(s)))
+	*e = append(*e, s...)
+}
+
+func (e *protoEncoder) Double(field int, v float64) {
+	if v == 0 {
+		return
+	}
+	*e = binary.AppendUvarint(*e, uint64(field)<<3|1)
+	*e = binary.LittleEndian.AppendUint64(*e, math.Float64bits(v))
+}
+
+// Message appends an embedded message; it is written even when empty
+func (e *protoEncoder) Message(field int, msg protoEncoder) {
+	*e = binary.AppendUvarint(*e, uint64(field)<<3|2)
+	*e = binary.AppendUvarint(*e, uint64(len(msg)))
+	*e = append(*e, msg...)
+}
+
+func (e *protoEncoder) Uint64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	*e = binary.AppendUvarint(*e, uint64(field)<<3)
+	*e = binary.AppendUvarint(*e, v)
+}
+
+// readGRPCMessage reads one length-prefixed request message
+func readGRPCMessage(r io.Reader) (protoMessage, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, grpcErrorf(GRPC_INVALID_ARGUMENT, "missing request message: %v", err)
+	}
+	if header[0] != 0 {
+		return nil, grpcErrorf(GRPC_UNIMPLEMENTED, "compressed messages are not supported")
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > GRPC_MAX_MESSAGE_LEN {
+		return nil, grpcErrorf(GRPC_INVALID_ARGUMENT, "message too large: %d bytes", length)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, grpcErrorf(GRPC_INVALID_ARGUMENT, "truncated request message: %v", err)
+	}
+
+	msg, err := decodeProto(body)
+	if err != nil {
+		return nil, grpcErrorf(GRPC_INVALID_ARGUMENT, "invalid request message: %v", err)
+	}
+	return msg, nil
+}
+
+// writeGRPCMessage writes one length-prefixed response message and flushes it
+func writeGRPCMessage(w http.ResponseWriter, msg protoEncoder) error {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	if _, err := w.Write(append(frame, msg...)); err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// grpcServer implements the Raydium service
+type grpcServer struct {
+	client ChainClient
+	wallet solana.PrivateKey // nil when SOLANA_PRIVATE_KEY is unset; ExecuteSwap is then refused
+	guard  *SpendGuard       // the wallet's spend limits
+	quotes *QuoteCache       // serves GetQuote bursts; swaps always quote fresh
+	stream UpdateStream      // shared by the quote cache and every StreamPrice call
+	opts   BuildOptions
+}
+
+// ServeHTTP dispatches gRPC calls and reports their status in trailers. The
+// Jupiter-shaped quote endpoint is plain JSON over HTTP/1.1 or HTTP/2.
+func (s *grpcServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == JUPITER_QUOTE_PATH {
+		s.serveJupiterQuote(w, r)
+		return
+	}
+	if r.Method != http.MethodPost || r.ProtoMajor != 2 {
+		http.Error(w, "gRPC requires HTTP/2 POST", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", GRPC_CONTENT_TYPE)
+	w.WriteHeader(http.StatusOK)
+
+	var err error
+	switch r.URL.Path {
+	case GRPC_SERVICE_PATH + "GetQuote":
+		err = s.getQuote(w, r)
+	case GRPC_SERVICE_PATH + "ExecuteSwap":
+		err = s.executeSwap(w, r)
+	case GRPC_SERVICE_PATH + "StreamPrice":
+		err = s.streamPrice(w, r)
+	default:
+		err = grpcErrorf(GRPC_UNIMPLEMENTED, "unknown method %s", r.URL.Path)
+	}
+
+	code, message := GRPC_OK, ""
+	if err != nil {
+		var gerr *grpcError
+		if errors.As(err, &gerr) {
+			code, message = gerr.code, gerr.message
+		} else {
+			code, message = GRPC_INTERNAL, err.Error()
+		}
+		log.Printf("gRPC %s failed: %s", r.URL.Path, message)
+	}
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", url.PathEscape(message))
+	}
+}
+
+// resolveQuote quotes a QuoteRequest or SwapRequest; both share fields 1-4.
+// A nil cache quotes on chain.
+func (s *grpcServer) resolveQuote(ctx context.Context, req protoMessage, cache *QuoteCache) (*SwapQuote, error) {
+	quote, err := cachedSwapQuote(ctx, s.client, cache, QuoteParams{
+		PoolAddress:  req.String(1),
+		TokenAddress: req.String(2),
+		Amount:       req.Double(3),
+		Side:         req.String(4),
+	})
+	if err != nil {
+		return nil, grpcErrorf(GRPC_FAILED_PRECONDITION, "%v", err)
+	}
+	return quote, nil
+}
+
+func (s *grpcServer) getQuote(w http.ResponseWriter, r *http.Request) error {
+	req, err := readGRPCMessage(r.Body)
+	if err != nil {
+		return err
+	}
+
+	quote, err := s.resolveQuote(r.Context(), req, s.quotes)
+	if err != nil {
+		return err
+	}
+
+	var resp protoEncoder
+	resp.String(1, quote.PoolAddress)
+	resp.Double(2, quote.AmountIn)
+	resp.Double(3, quote.ExpectedOut)
+	resp.Double(4, quote.Price())
+	return writeGRPCMessage(w, resp)
+}
+
+func (s *grpcServer) executeSwap(w http.ResponseWriter, r *http.Request) error {
+	if s.wallet == nil {
+		return grpcErrorf(GRPC_FAILED_PRECONDITION, "server has no wallet; set %s", PRIVATE_KEY_ENV_VAR)
+	}
+
+	req, err := readGRPCMessage(r.Body)
+	if err != nil {
+		return err
+	}
+
+	slippage := req.Double(5)
+	if slippage <= 0 || slippage > MAX_SLIPPAGE {
+		return grpcErrorf(GRPC_INVALID_ARGUMENT, "slippage must be between 0 and %.0f", MAX_SLIPPAGE)
+	}
+
+	ctx := r.Context()
+	quote, err := s.resolveQuote(ctx, req, nil)
+	if err != nil {
+		return err
+	}
+
+	// There is nobody to ask, so trades needing confirmation are refused
+	if err := s.guard.Enforce(ctx, quote.GuardedTrade(s.opts.Burn), false); err != nil {
+		return grpcErrorf(GRPC_PERMISSION_DENIED, "%v", err)
+	}
+	defer s.guard.Release(quote.GuardedTrade(s.opts.Burn))
+
+	// A retried request with the same key is refused rather than sent twice
+	key := req.String(6)
+	if key != "" {
+		used, err := claimIdempotencyKey("grpc:"+key, quote.PoolAddress, quote.Side, quote.AmountIn)
+		if err != nil {
+			return err
+		}
+		if used != nil {
+			return grpcErrorf(GRPC_ALREADY_EXISTS, "idempotency key %q was already used: %s", key, used.describe())
+		}
+	}
+
+	minAmountOut := quote.MinAmountOut(slippage)
+	txHash, err := executeSwap(ctx, s.client, s.wallet, quote.PoolAddress, quote.Side, quote.AmountIn, minAmountOut, s.opts)
+	if key != "" {
+		if err := completeIdempotencyKey("grpc:"+key, txHash, err); err != nil {
+			log.Printf("Could not record idempotency key %q: %v", key, err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.guard.Record(quote.GuardedTrade(s.opts.Burn)); err != nil {
+		log.Printf("Could not record trade for spend limits: %v", err)
+	}
+
+	var resp protoEncoder
+	resp.String(1, txHash)
+	resp.String(2, quote.PoolAddress)
+	resp.Double(3, quote.ExpectedOut)
+	minOut := float64(minAmountOut) / math.Pow(10, float64(quote.OutputDecimals))
+	resp.Double(4, minOut)
+	resp.Double(5, s.opts.PlatformFee.Estimate(quote.Side, quote.AmountIn, minOut))
+	return writeGRPCMessage(w, resp)
+}
+
+func (s *grpcServer) streamPrice(w http.ResponseWriter, r *http.Request) error {
+	req, err := readGRPCMessage(r.Body)
+	if err != nil {
+		return err
+	}
+	if req.String(1) == "" {
+		return grpcErrorf(GRPC_INVALID_ARGUMENT, "pool must be specified")
+	}
+
+	ctx := r.Context()
+	pool, err := loadPool(ctx, s.client, req.String(1))
+	if err != nil {
+		return grpcErrorf(GRPC_FAILED_PRECONDITION, "%v", err)
+	}
+
+	send := func(slot uint64) error {
+		price, solReserve, tokenReserve := poolPrice(pool)
+		var update protoEncoder
+		update.Uint64(1, slot)
+		update.Double(2, price)
+		update.Double(3, solReserve)
+		update.Double(4, tokenReserve)
+		update.Uint64(5, uint64(time.Now().UnixMilli()))
+		return writeGRPCMessage(w, update)
+	}
+	if err := send(0); err != nil {
+		return err
+	}
+
+	baseUpdates := s.stream.SubscribeAccount(pool.BaseVault)
+	quoteUpdates := s.stream.SubscribeAccount(pool.QuoteVault)
+	defer s.stream.Unsubscribe(baseUpdates)
+	defer s.stream.Unsubscribe(quoteUpdates)
+
+	for {
+		var u StreamUpdate
+		select {
+		case <-ctx.Done():
+			return nil
+		case u = <-baseUpdates:
+		case u = <-quoteUpdates:
+		}
+
+		amount, err := decodeTokenAmount(u.Data)
+		if err != nil {
+			continue
+		}
+		if u.Key.Equals(pool.BaseVault) {
+			pool.BaseAmount = amount
+		} else {
+			pool.QuoteAmount = amount
+		}
+
+		if err := send(u.Slot); err != nil {
+			return nil // client went away
+		}
+	}
+}
+
+// runGRPC serves the Raydium gRPC API until interrupted
+func runGRPC(args []string, opts BuildOptions) {
+	fs := newCommandFlagSet("grpc")
+	var addr string
+	fs.StringVar(&addr, "addr", DEFAULT_GRPC_ADDR, "Listen address")
+	quoteTTL := fs.Duration("quote-ttl", DEFAULT_QUOTE_CACHE_TTL, "Serve repeated quotes from memory for this long unless the pool changes, 0 to disable")
+	bridge := fs.Bool("bridge", false, "Serve "+BRIDGE_PATH+", a page where browser wallets sign swaps the server prepares")
+	signedQuotes := fs.Bool("signed-quotes", false, "Require signed requests on "+JUPITER_QUOTE_PATH+" too when "+API_KEYS_ENV_VAR+" is set")
+	fs.Parse(args)
+
+	stream := newUpdateStream(resolveWSURL())
+	server := &grpcServer{
+		client: newChainClient(),
+		quotes: newQuoteCache(*quoteTTL, stream),
+		stream: stream,
+		opts:   opts,
+	}
+
+	if os.Getenv(PRIVATE_KEY_ENV_VAR) != "" {
+		wallet, err := loadWallet()
+		if err != nil {
+			log.Fatalf("Failed to load wallet: %v", err)
+		}
+		server.wallet = wallet
+		if server.guard, err = loadSpendGuard(server.client, wallet.PublicKey()); err != nil {
+			log.Fatalf("Failed to load spend limits: %v", err)
+		}
+		fmt.Printf("Wallet loaded: %s (ExecuteSwap enabled)\n", wallet.PublicKey())
+	} else {
+		fmt.Println("No wallet configured; ExecuteSwap is disabled")
+	}
+
+	// Paths outside the request verifier are mounted next to it
+	mux := http.NewServeMux()
+	var handler http.Handler = server
+	if os.Getenv(API_KEYS_ENV_VAR) != "" {
+		keys, err := loadAPIClientKeys()
+		if err != nil {
+			log.Fatal(err)
+		}
+		handler = newRequestVerifier(keys, DEFAULT_API_MAX_AGE).Middleware(server)
+		fmt.Printf("Request signing required (%d client keys)\n", len(keys))
+		// Jupiter clients cannot sign requests, and quotes move no funds
+		if !*signedQuotes {
+			mux.Handle(JUPITER_QUOTE_PATH, server)
+			fmt.Printf("%s is served without signing; pass -signed-quotes to require it\n", JUPITER_QUOTE_PATH)
+		}
+	} else if server.wallet != nil {
+		fmt.Printf("Warning: ExecuteSwap is unauthenticated; set %s to require signed requests\n", API_KEYS_ENV_VAR)
+	}
+	// Browsers cannot sign API requests; the bridge's wallets sign the swaps instead
+	if *bridge {
+		if opts.FeePayer.Enabled() {
+			fmt.Printf("The bridge's wallets pay their own fees; %s only applies to ExecuteSwap\n", FEE_PAYER_KEY_ENV_VAR)
+		}
+		signingBridge := newSigningBridge(server.client, opts)
+		mux.Handle(BRIDGE_PATH, signingBridge)
+		mux.Handle(BRIDGE_WS_PATH, signingBridge)
+	}
+	mux.Handle("/", handler)
+
+	// gRPC clients speak HTTP/2 with prior knowledge over plaintext; the
+	// Jupiter quote endpoint is also served over HTTP/1.1
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	protocols.SetHTTP1(true)
+	httpServer := &http.Server{
+		Addr:      addr,
+		Handler:   mux,
+		Protocols: &protocols,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// The quote cache runs the stream when there is one, to stop its watchers with it
+		run := stream.Run
+		if server.quotes != nil {
+			run = server.quotes.Run
+		}
+		if err := run(ctx); err != nil && ctx.Err() == nil {
+			fmt.Printf("Warning: update stream stopped: %v\n", err)
+		}
+	}()
+	defer printQuoteCacheStats(server.quotes)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Serving gRPC (raydium.v1.Raydium) on %s\n", addr)
+	fmt.Printf("Serving Jupiter-compatible quotes on http://%s%s\n", addr, JUPITER_QUOTE_PATH)
+	if *bridge {
+		fmt.Printf("Serving the signing bridge on http://%s%s\n", addr, BRIDGE_PATH)
+	}
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("gRPC server failed: %v", err)
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+	"awesomeProject/internal/raylog"
+)
+
+// Price history backfill settings
+const (
+	HISTORY_SIGNATURE_PAGE = 1000 // maximum getSignaturesForAddress page
+	HISTORY_FETCH_WORKERS  = 8
+	DEFAULT_HISTORY_MAX_TX = 5000
+)
+
+var candleCSVHeader = []string{"time", "open", "high", "low", "close", "volume_sol", "trades"}
+
+// PoolTrade is one decoded swap on a SOL-paired pool
+type PoolTrade struct {
+	Time      time.Time
+	Side      string
+	Price     float64 // SOL per token
+	VolumeSOL float64
+}
+
+// Candle is an OHLCV bar of SOL-per-token prices
+type Candle struct {
+	Time      time.Time `json:"time"` // start of the interval
+	Open      float64   `json:"open"`
+	High      float64   `json:"high"`
+	Low       float64   `json:"low"`
+	Close     float64   `json:"close"`
+	VolumeSOL float64   `json:"volume_sol"`
+	Trades    int       `json:"trades"`
+}
+
+// poolTradeFromLog prices a logged swap from the amounts it moved. It
+// reports false when the swap moved no tokens.
+func poolTradeFromLog(pool *OnChainPool, swapLog *raylog.Swap, at time.Time) (PoolTrade, bool) {
+	baseRaw, quoteRaw := swapLog.AmountOut, swapLog.AmountIn
+	if swapLog.BaseIn() {
+		baseRaw, quoteRaw = swapLog.AmountIn, swapLog.AmountOut
+	}
+	base := atoms.ToAmount(baseRaw, int(pool.BaseDecimals))
+	quote := atoms.ToAmount(quoteRaw, int(pool.QuoteDecimals))
+
+	sol, token := quote, base
+	if pool.BaseMint.Equals(WSOL_MINT) || pool.BaseMint.Equals(SOL_MINT) {
+		sol, token = base, quote
+	}
+	if token == 0 {
+		return PoolTrade{}, false
+	}
+	return PoolTrade{Time: at, Side: swapSide(pool, swapLog), Price: sol / token, VolumeSOL: sol}, true
+}
+
+// buildCandles aggregates trades into candles of the given interval. Trades
+// must be sorted by time; intervals without trades are left out.
+func buildCandles(trades []PoolTrade, interval time.Duration) []Candle {
+	var candles []Candle
+	for _, trade := range trades {
+		start := trade.Time.Truncate(interval)
+		if len(candles) == 0 || !candles[len(candles)-1].Time.Equal(start) {
+			candles = append(candles, Candle{Time: start, Open: trade.Price, High: trade.Price, Low: trade.Price})
+		}
+		candle := &candles[len(candles)-1]
+		candle.High = max(candle.High, trade.Price)
+		candle.Low = min(candle.Low, trade.Price)
+		candle.Close = trade.Price
+		candle.VolumeSOL += trade.VolumeSOL
+		candle.Trades++
+	}
+	return candles
+}
+
+// poolSignaturesSince pages backwards through the pool's successful
+// transactions until since, returning at most maxTx of them
+func poolSignaturesSince(ctx context.Context, client ChainClient, pool solana.PublicKey, since time.Time, maxTx int) ([]*rpc.TransactionSignature, error) {
+	var signatures []*rpc.TransactionSignature
+	limit := HISTORY_SIGNATURE_PAGE
+	opts := &rpc.GetSignaturesForAddressOpts{Limit: &limit, Commitment: rpc.CommitmentConfirmed}
+	for {
+		page, err := client.GetSignaturesForAddressWithOpts(ctx, pool, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pool signatures: %w", err)
+		}
+		for _, sig := range page {
+			if sig.BlockTime == nil || sig.BlockTime.Time().Before(since) {
+				return signatures, nil
+			}
+			if sig.Err != nil {
+				continue
+			}
+			if len(signatures) == maxTx {
+				fmt.Printf("Warning: Stopped at %d transactions (-max-tx); history starts at %s\n", maxTx, sig.BlockTime.Time().Format(time.RFC3339))
+				return signatures, nil
+			}
+			signatures = append(signatures, sig)
+		}
+		if len(page) < limit {
+			return signatures, nil
+		}
+		opts.Before = page[len(page)-1].Signature
+	}
+}
+
+// fetchPoolTrades decodes the swaps in the pool's transactions since the
+// given time, sorted oldest first. Transactions swapping on several Raydium
+// pools are skipped, since their logs do not say which pool each swap hit.
+func fetchPoolTrades(ctx context.Context, client ChainClient, pool *OnChainPool, since time.Time, maxTx int) ([]PoolTrade, error) {
+	signatures, err := poolSignaturesSince(ctx, client, pool.Address, since, maxTx)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "Decoding %d transactions...\n", len(signatures))
+
+	var mu sync.Mutex
+	var trades []PoolTrade
+	var skipped int
+	jobs := make(chan *rpc.TransactionSignature)
+	var wg sync.WaitGroup
+	for range HISTORY_FETCH_WORKERS {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sig := range jobs {
+				tx, err := fetchTransaction(ctx, client, sig.Signature.String())
+				var swapLogs []*raylog.Swap
+				if err == nil {
+					swapLogs, err = raylog.FindSwaps(tx.Meta.LogMessages)
+				}
+
+				mu.Lock()
+				if err != nil || len(swapLogs) > 1 {
+					skipped++
+				} else if len(swapLogs) == 1 {
+					if trade, ok := poolTradeFromLog(pool, swapLogs[0], sig.BlockTime.Time()); ok {
+						trades = append(trades, trade)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, sig := range signatures {
+		select {
+		case jobs <- sig:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("stopped decoding transactions: %w", err)
+	}
+
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d transactions that failed to load or routed through several pools\n", skipped)
+	}
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].Time.Before(trades[j].Time) })
+	return trades, nil
+}
+
+// writeCandles writes candles as a JSON array or as CSV
+func writeCandles(w io.Writer, candles []Candle, format string) error {
+	if format == "json" {
+		data, err := json.MarshalIndent(candles, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode candles: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(candleCSVHeader); err != nil {
+		return fmt.Errorf("failed to write candles: %w", err)
+	}
+	for _, c := range candles {
+		record := []string{
+			c.Time.UTC().Format(time.RFC3339),
+			strconv.FormatFloat(c.Open, 'g', -1, 64),
+			strconv.FormatFloat(c.High, 'g', -1, 64),
+			strconv.FormatFloat(c.Low, 'g', -1, 64),
+			strconv.FormatFloat(c.Close, 'g', -1, 64),
+			strconv.FormatFloat(c.VolumeSOL, 'f', 9, 64),
+			strconv.Itoa(c.Trades),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write candles: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// readCandles reads candles written by writeCandles, detecting JSON by its
+// leading bracket, and sorts them oldest first
+func readCandles(r io.Reader) ([]Candle, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read candles: %w", err)
+	}
+
+	var candles []Candle
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(data, &candles); err != nil {
+			return nil, fmt.Errorf("failed to decode candles: %w", err)
+		}
+	} else {
+		records, err := csv.NewReader(strings.NewReader(trimmed)).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode candles: %w", err)
+		}
+		for i, record := range records {
+			if i == 0 && len(record) > 0 && record[0] == candleCSVHeader[0] {
+				continue
+			}
+			candle, err := parseCandleRecord(record)
+			if err != nil {
+				return nil, fmt.Errorf("candle row %d: %w", i+1, err)
+			}
+			candles = append(candles, candle)
+		}
+	}
+	sort.SliceStable(candles, func(i, j int) bool { return candles[i].Time.Before(candles[j].Time) })
+	return candles, nil
+}
+
+// parseCandleRecord decodes a CSV row in candleCSVHeader order
+func parseCandleRecord(record []string) (Candle, error) {
+	if len(record) != len(candleCSVHeader) {
+		return Candle{}, fmt.Errorf("expected %d columns, got %d", len(candleCSVHeader), len(record))
+	}
+	var candle Candle
+	var err error
+	if candle.Time, err = time.Parse(time.RFC3339, record[0]); err != nil {
+		return Candle{}, fmt.Errorf("invalid time %q", record[0])
+	}
+	for i, field := range []*float64{&candle.Open, &candle.High, &candle.Low, &candle.Close, &candle.VolumeSOL} {
+		if *field, err = strconv.ParseFloat(record[i+1], 64); err != nil {
+			return Candle{}, fmt.Errorf("invalid %s %q", candleCSVHeader[i+1], record[i+1])
+		}
+	}
+	if candle.Trades, err = strconv.Atoi(record[6]); err != nil {
+		return Candle{}, fmt.Errorf("invalid trades %q", record[6])
+	}
+	return candle, nil
+}
+
+// runPrice dispatches the price subcommands
+func runPrice(args []string) {
+	if len(args) > 0 && args[0] == "history" {
+		runPriceHistory(args[1:])
+		return
+	}
+	fmt.Println("Usage: go run . price history -pool POOL [-hours 24] [-interval 1h] [-format csv|json] [-out FILE]")
+	os.Exit(1)
+}
+
+// runPriceHistory backfills OHLCV candles for a pool from its on-chain swaps
+func runPriceHistory(args []string) {
+	fs := newCommandFlagSet("price history")
+	var poolAddress string
+	var hours float64
+	var interval time.Duration
+	var format string
+	var outPath string
+	var maxTx int
+	fs.StringVar(&poolAddress, "pool", "", "Pool address")
+	fs.Float64Var(&hours, "hours", 24, "How far back to go")
+	fs.DurationVar(&interval, "interval", time.Hour, "Candle interval (e.g. 1m, 5m, 1h)")
+	fs.StringVar(&format, "format", "csv", "Output format: csv or json")
+	fs.StringVar(&outPath, "out", "", "Write candles to this file instead of stdout")
+	fs.IntVar(&maxTx, "max-tx", DEFAULT_HISTORY_MAX_TX, "Maximum pool transactions to decode")
+	fs.Parse(args)
+
+	if poolAddress == "" {
+		fmt.Println("Usage: go run . price history -pool POOL [-hours 24] [-interval 1h] [-format csv|json] [-out FILE]")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	if format != "csv" && format != "json" {
+		log.Fatal("-format must be csv or json")
+	}
+	if hours <= 0 || interval <= 0 || maxTx <= 0 {
+		log.Fatal("-hours, -interval and -max-tx must be positive")
+	}
+
+	ctx := context.Background()
+	client := newChainClient()
+	pool, err := loadPool(ctx, client, poolAddress)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	since := time.Now().Add(-time.Duration(hours * float64(time.Hour)))
+	trades, err := fetchPoolTrades(ctx, client, pool, since, maxTx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	candles := buildCandles(trades, interval)
+
+	out := io.Writer(os.Stdout)
+	if outPath != "" {
+		file, err := os.Create(outPath)
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	if err := writeCandles(out, candles, format); err != nil {
+		log.Fatal(err)
+	}
+	if outPath != "" {
+		fmt.Printf("Wrote %d candles from %d swaps to %s\n", len(candles), len(trades), outPath)
+	}
+}
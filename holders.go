@@ -0,0 +1,248 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+const (
+	DEFAULT_HOLDERS_TOP        = 20
+	TOKEN_ACCOUNT_OWNER_OFFSET = 32 // owner(32) + amount(8) follow the mint
+)
+
+// TokenHolder is the combined balance of one wallet across its token accounts
+type TokenHolder struct {
+	Owner  solana.PublicKey `json:"owner"`
+	Amount float64          `json:"amount"`
+	Share  float64          `json:"share"`           // of supply
+	Label  string           `json:"label,omitempty"` // pool vault, team or burned
+}
+
+// HolderReport is the distribution of a mint's supply
+type HolderReport struct {
+	Mint        solana.PublicKey `json:"mint"`
+	Supply      float64          `json:"supply"`
+	Holders     int              `json:"holders"` // wallets with a balance; 0 when only the largest accounts could be read
+	Top         []TokenHolder    `json:"top"`
+	Top10Share  float64          `json:"top10_share"` // excluding pool vaults and burns
+	PoolShare   float64          `json:"pool_share"`
+	TeamShare   float64          `json:"team_share"`
+	BurnedShare float64          `json:"burned_share"`
+}
+
+// tokenBalancesByOwner sums every token account of mint by owner with one
+// getProgramAccounts call, reading only the owner and amount of each account
+func tokenBalancesByOwner(ctx context.Context, client ChainClient, mint solana.PublicKey, program solana.PublicKey) (map[solana.PublicKey]uint64, error) {
+	offset, length := uint64(TOKEN_ACCOUNT_OWNER_OFFSET), uint64(40)
+	filters := []rpc.RPCFilter{{Memcmp: &rpc.RPCFilterMemcmp{Offset: 0, Bytes: mint.Bytes()}}}
+	if program.Equals(solana.TokenProgramID) {
+		// Token-2022 accounts grow with extensions, so only legacy ones have a fixed size
+		filters = append(filters, rpc.RPCFilter{DataSize: TOKEN_ACCOUNT_SIZE})
+	}
+	accounts, err := client.GetProgramAccountsWithOpts(ctx, program, &rpc.GetProgramAccountsOpts{
+		Encoding:  solana.EncodingBase64,
+		DataSlice: &rpc.DataSlice{Offset: &offset, Length: &length},
+		Filters:   filters,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[solana.PublicKey]uint64)
+	for _, account := range accounts {
+		data := account.Account.Data.GetBinary()
+		if len(data) < 40 {
+			continue
+		}
+		if amount := binary.LittleEndian.Uint64(data[32:40]); amount > 0 {
+			balances[solana.PublicKeyFromBytes(data[:32])] += amount
+		}
+	}
+	return balances, nil
+}
+
+// largestBalancesByOwner falls back to the largest token accounts, for RPCs
+// that refuse getProgramAccounts on the token programs
+func largestBalancesByOwner(ctx context.Context, client ChainClient, mint solana.PublicKey) (map[solana.PublicKey]uint64, error) {
+	largest, err := client.GetTokenLargestAccounts(ctx, mint, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get largest accounts: %w", err)
+	}
+	keys := make([]solana.PublicKey, len(largest.Value))
+	for i, account := range largest.Value {
+		keys[i] = account.Address
+	}
+	accounts, err := client.GetMultipleAccounts(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token accounts: %w", err)
+	}
+
+	balances := make(map[solana.PublicKey]uint64)
+	for i, account := range accounts.Value {
+		if account == nil || len(account.Data.GetBinary()) < TOKEN_ACCOUNT_OWNER_OFFSET+32 {
+			continue
+		}
+		amount, err := strconv.ParseUint(largest.Value[i].Amount, 10, 64)
+		if err != nil || amount == 0 {
+			continue
+		}
+		data := account.Data.GetBinary()
+		balances[solana.PublicKeyFromBytes(data[TOKEN_ACCOUNT_OWNER_OFFSET:TOKEN_ACCOUNT_OWNER_OFFSET+32])] += amount
+	}
+	return balances, nil
+}
+
+// analyzeHolders reports how a mint's supply is spread over wallets. Tokens in
+// Raydium pool vaults, owned by the AMM authority, and in team wallets are
+// labelled so the concentration among traders stands out. The mint authority,
+// when still set, is treated as a team wallet.
+func analyzeHolders(ctx context.Context, client ChainClient, mint solana.PublicKey, team []solana.PublicKey, top int) (*HolderReport, error) {
+	mintInfo, err := client.GetAccountInfo(ctx, mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mint: %w", err)
+	}
+	supply, err := client.GetTokenSupply(ctx, mint, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supply: %w", err)
+	}
+	rawSupply, err := strconv.ParseUint(supply.Value.Amount, 10, 64)
+	if err != nil || rawSupply == 0 {
+		return nil, fmt.Errorf("mint %s has no supply", mint)
+	}
+	decimals := int(supply.Value.Decimals)
+	// A live mint authority is usually the deployer, so it counts as team
+	if mintAuthority, _, err := parseMintAuthorities(mintInfo.Value.Data.GetBinary()); err == nil && mintAuthority != nil {
+		team = append(team, *mintAuthority)
+	}
+
+	report := &HolderReport{Mint: mint, Supply: atoms.ToAmount(rawSupply, decimals)}
+	balances, err := tokenBalancesByOwner(ctx, client, mint, mintInfo.Value.Owner)
+	if err == nil {
+		report.Holders = len(balances)
+	} else {
+		fmt.Printf("Warning: Could not list every holder (%v); using the largest accounts only\n", err)
+		if balances, err = largestBalancesByOwner(ctx, client, mint); err != nil {
+			return nil, err
+		}
+	}
+
+	authority, _, err := solana.FindProgramAddress([][]byte{[]byte(AUTHORITY_AMM_SEED)}, RAYDIUM_AMM_V4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive pool authority: %w", err)
+	}
+	holders := make([]TokenHolder, 0, len(balances))
+	for owner, amount := range balances {
+		holder := TokenHolder{Owner: owner, Amount: atoms.ToAmount(amount, decimals), Share: float64(amount) / float64(rawSupply)}
+		switch {
+		case owner.Equals(authority):
+			holder.Label = "pool vault"
+			report.PoolShare += holder.Share
+		case owner.Equals(INCINERATOR):
+			holder.Label = "burned"
+			report.BurnedShare += holder.Share
+		case slices.ContainsFunc(team, owner.Equals):
+			holder.Label = "team"
+			report.TeamShare += holder.Share
+		}
+		holders = append(holders, holder)
+	}
+	slices.SortFunc(holders, func(a, b TokenHolder) int { return cmp.Compare(b.Share, a.Share) })
+
+	traders := 0
+	for _, holder := range holders {
+		if holder.Label == "pool vault" || holder.Label == "burned" {
+			continue
+		}
+		if traders < 10 {
+			report.Top10Share += holder.Share
+		}
+		traders++
+	}
+	report.Top = holders[:min(len(holders), top)]
+	return report, nil
+}
+
+// printHolderReport prints a holder report for the terminal
+func printHolderReport(report *HolderReport, name string) {
+	fmt.Printf("Token: %s\n", name)
+	fmt.Printf("Supply: %.6f\n", report.Supply)
+	if report.Holders > 0 {
+		fmt.Printf("Holders: %d\n", report.Holders)
+	} else {
+		fmt.Println("Holders: unknown (largest accounts only)")
+	}
+	fmt.Printf("\n%-4s %-44s %14s %8s\n", "#", "Owner", "Amount", "Share")
+	for i, holder := range report.Top {
+		fmt.Printf("%-4d %-44s %14.2f %7.2f%%  %s\n", i+1, holder.Owner, holder.Amount, holder.Share*100, holder.Label)
+	}
+	fmt.Printf("\nTop 10 wallets: %.1f%% of supply, excluding pool vaults and burns\n", report.Top10Share*100)
+	fmt.Printf("Raydium pool vaults: %.1f%%\n", report.PoolShare*100)
+	fmt.Printf("Team wallets: %.1f%%\n", report.TeamShare*100)
+	if report.BurnedShare > 0 {
+		fmt.Printf("Burned: %.1f%%\n", report.BurnedShare*100)
+	}
+}
+
+// runToken dispatches the token subcommands
+func runToken(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "holders":
+			runTokenHolders(args[1:])
+			return
+		}
+	}
+	fmt.Println("Usage: go run . token holders -mint MINT [-team WALLET,...] [-top N] [-json]")
+	os.Exit(1)
+}
+
+// runTokenHolders reports the holder distribution of a mint
+func runTokenHolders(args []string) {
+	fs := newCommandFlagSet("token holders")
+	mintAddr := fs.String("mint", "", "Token mint")
+	teamList := fs.String("team", "", "Comma-separated team wallets whose share is reported separately")
+	top := fs.Int("top", DEFAULT_HOLDERS_TOP, "Number of largest holders to list")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(args)
+	if *mintAddr == "" {
+		log.Fatal("Usage: go run . token holders -mint MINT [-team WALLET,...] [-top N] [-json]")
+	}
+
+	mint, err := solana.PublicKeyFromBase58(*mintAddr)
+	if err != nil {
+		log.Fatalf("Invalid mint: %v", err)
+	}
+	var team []solana.PublicKey
+	for _, address := range parseEndpointList(*teamList) {
+		wallet, err := solana.PublicKeyFromBase58(address)
+		if err != nil {
+			log.Fatalf("Invalid team wallet %q: %v", address, err)
+		}
+		team = append(team, wallet)
+	}
+
+	ctx := context.Background()
+	client := newChainClient()
+	report, err := analyzeHolders(ctx, client, mint, team, max(*top, 0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *asJSON {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	printHolderReport(report, tokenDisplayName(ctx, client, mint))
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+// HONEYPOT_SELL_SHARE_BPS is the share of a simulated buy's output sold back
+// in the honeypot check, leaving room for reserves moving before the simulation
+const HONEYPOT_SELL_SHARE_BPS = 5_000
+
+// ErrHoneypot marks a token whose simulated sell fails after a buy
+var ErrHoneypot = errors.New("token cannot be sold")
+
+// checkHoneypot simulates the buy followed by a sell of part of its output,
+// as owner, in one unsigned transaction. A failing sell means the tokens
+// could not be sold after buying and is reported as ErrHoneypot; any other
+// failure leaves the check inconclusive. A freeze authority is only warned
+// about, since it can freeze the bought tokens later and no simulation shows
+// that.
+func checkHoneypot(ctx context.Context, client ChainClient, owner solana.PublicKey, poolAddress string, amountIn float64, opts BuildOptions) error {
+	pool, err := loadPool(ctx, client, poolAddress)
+	if err != nil {
+		return err
+	}
+	if err := fetchMarketData(ctx, client, pool); err != nil {
+		return fmt.Errorf("failed to fetch market data: %w", err)
+	}
+	sourceMint, tokenMint, inputDecimals := swapMints(pool, "buy")
+	isBaseToQuote := sourceMint.Equals(pool.BaseMint)
+
+	if mintInfo, err := client.GetAccountInfo(ctx, tokenMint); err == nil && mintInfo != nil && mintInfo.Value != nil {
+		if _, freeze, err := parseMintAuthorities(mintInfo.Value.Data.GetBinary()); err == nil && freeze != nil {
+			fmt.Printf(tr("⚠️  Warning: freeze authority %s can freeze the tokens after you buy them\n"), freeze)
+		}
+	}
+
+	amountInRaw, err := atoms.FromAmount(amountIn, inputDecimals)
+	if err != nil {
+		return err
+	}
+	tokensOut, _ := raydiumSwapBaseIn(pool, amountInRaw, isBaseToQuote)
+	sellRaw := atoms.MulDiv(tokensOut, HONEYPOT_SELL_SHARE_BPS, 10_000, false)
+	if sellRaw == 0 {
+		return fmt.Errorf("the buy is too small to probe a sell")
+	}
+
+	source, createSource, err := getOrCreateATA(ctx, client, owner, sourceMint)
+	if err != nil {
+		return err
+	}
+	destination, createDestination, err := getOrCreateATA(ctx, client, owner, tokenMint)
+	if err != nil {
+		return err
+	}
+	accounts := swapAccounts{Source: source, Destination: destination, CreateSource: createSource, CreateDestination: createDestination}
+	instructions, err := swapInstructions(pool, owner, "buy", amountInRaw, 0, accounts, opts)
+	if err != nil {
+		return err
+	}
+	sellIx, err := createSwapInstruction(pool, destination, source, owner, sellRaw, 0, !isBaseToQuote)
+	if err != nil {
+		return err
+	}
+	instructions = append(instructions, sellIx)
+
+	tx, err := solana.NewTransaction(instructions, solana.Hash{}, solana.TransactionPayer(owner))
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+	sim, err := client.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		SigVerify:              false,
+		Commitment:             rpc.CommitmentConfirmed,
+		ReplaceRecentBlockhash: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to simulate transaction: %w", err)
+	}
+	if sim.Value.Err == nil {
+		return nil
+	}
+	if index, ok := failedInstruction(sim.Value.Err); ok && index == len(instructions)-1 {
+		return fmt.Errorf("%w: the simulated sell of %s failed after the buy (%v)", ErrHoneypot, atoms.Format(sellRaw, int(tokenDecimals(pool, tokenMint))), sim.Value.Err)
+	}
+	return fmt.Errorf("simulated buy failed: %v", sim.Value.Err)
+}
+
+// tokenDecimals returns the decimals of one of the pool's mints
+func tokenDecimals(pool *OnChainPool, mint solana.PublicKey) uint8 {
+	if mint.Equals(pool.BaseMint) {
+		return pool.BaseDecimals
+	}
+	return pool.QuoteDecimals
+}
+
+// failedInstruction returns the index of the instruction a simulation error
+// names, as in {"InstructionError": [2, {"Custom": 30}]}
+func failedInstruction(simErr interface{}) (int, bool) {
+	fields, ok := simErr.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	details, ok := fields["InstructionError"].([]interface{})
+	if !ok || len(details) == 0 {
+		return 0, false
+	}
+	index, ok := details[0].(float64)
+	if !ok {
+		return 0, false
+	}
+	return int(index), true
+}
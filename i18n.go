@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Languages of prompts and reports, selected with -lang or the LANG environment variable
+const (
+	LANG_ENV_VAR = "LANG"
+	LANG_EN      = "en"
+	LANG_RU      = "ru"
+)
+
+// activeLanguage is the language tr translates into
+var activeLanguage = LANG_EN
+
+// MESSAGES translates the English format strings of prompts, reports and
+// errors. The English string is the key, so a missing translation falls back
+// to English; verbs must stay in the same order.
+var MESSAGES = map[string]map[string]string{
+	LANG_RU: {
+		// Confirmation
+		"\n=== SWAP CONFIRMATION ===\n":                             "\n=== ПОДТВЕРЖДЕНИЕ ОБМЕНА ===\n",
+		"Pool: %s\n":                                                "Пул: %s\n",
+		"Operation: %s\n":                                           "Операция: %s\n",
+		"Amount In: %.9f %s\n":                                      "Отдаёте: %.9f %s\n",
+		"Expected Out: %.9f %s\n":                                   "Ожидаемо получите: %.9f %s\n",
+		"Price: %.9f SOL per %s\n":                                  "Цена: %.9f SOL за %s\n",
+		"Platform Fee: ~%.9f %s (%d bps to %s)\n":                   "Комиссия платформы: ~%.9f %s (%d б.п. на %s)\n",
+		"Burn: %.2f%% of the minimum out (under %.9f %s)\n":         "Сжигание: %.2f%% минимального выхода (до %.9f %s)\n",
+		"Fee Payer: %s (reimbursed from the swap)\n":                "Плательщик комиссий: %s (возмещается из сделки)\n",
+		"Do you want to execute this swap? (y/n): ":                 "Выполнить обмен? (д/н): ",
+		"%s (y/n): ":                                                "%s (д/н): ",
+		"\nSwap cancelled.":                                         "\nОбмен отменён.",
+		"\nSwap cancelled by user.":                                 "\nОбмен отменён пользователем.",
+		"Send this trade anyway?":                                   "Всё равно отправить сделку?",
+		"⚠️  %v. Continue?":                                         "⚠️  %v. Продолжить?",
+		"trade cancelled":                                           "сделка отменена",
+		"\nEnter maximum slippage tolerance (%%) [default: %.1f]: ": "\nВведите максимальное проскальзывание (%%) [по умолчанию: %.1f]: ",
+		"invalid slippage value: %w":                                "неверное значение проскальзывания: %w",
+		"slippage must be between 0 and %.0f":                       "проскальзывание должно быть от 0 до %.0f",
+		"\n⚠️  A near-identical trade was executed %s ago: %s %g on %s (%s)\n": "\n⚠️  Почти такая же сделка была выполнена %s назад: %s %g в %s (%s)\n",
+
+		// Report
+		"\n=== TRANSACTION REPORT ===\n":         "\n=== ОТЧЁТ О ТРАНЗАКЦИИ ===\n",
+		"Transaction: %s\n":                      "Транзакция: %s\n",
+		"Status: %s\n":                           "Статус: %s\n",
+		"Success":                                "Успешно",
+		"Discrepancy":                            "Расхождение",
+		"Slot: %d (%s)\n":                        "Слот: %d (%s)\n",
+		"\nSwap Details:\n":                      "\nДетали обмена:\n",
+		"  Amount In: %.9f %s\n":                 "  Отдано: %.9f %s\n",
+		"  Amount Out: %.9f %s\n":                "  Получено: %.9f %s\n",
+		"  Minimum Out: %.9f %s\n":               "  Минимум к получению: %.9f %s\n",
+		"  Wallet Sent: %.9f %s\n":               "  Списано с кошелька: %.9f %s\n",
+		"  Wallet Received: %.9f %s\n":           "  Зачислено на кошелёк: %.9f %s\n",
+		"\nPrice Analysis:\n":                    "\nАнализ цены:\n",
+		"  Expected Price: %.9f SOL per token\n": "  Ожидаемая цена: %.9f SOL за токен\n",
+		"  Actual Price: %.9f SOL per token\n":   "  Фактическая цена: %.9f SOL за токен\n",
+		"  Price Impact (quoted): %.4f%%\n":      "  Влияние на цену (в котировке): %.4f%%\n",
+		"  Realized Slippage: %+.4f%%\n":         "  Фактическое проскальзывание: %+.4f%%\n",
+		"  Slippage Tolerance: %.2f%%\n":         "  Допустимое проскальзывание: %.2f%%\n",
+		"\nFees:\n":                              "\nКомиссии:\n",
+		"  Network Fee: %.9f SOL\n":              "  Комиссия сети: %.9f SOL\n",
+		"  Priority Fee: %.9f SOL\n":             "  Приоритетная комиссия: %.9f SOL\n",
+		"  LP Fee: %.9f %s\n":                    "  Комиссия LP: %.9f %s\n",
+		"  Platform Fee: %.9f %s\n":              "  Комиссия платформы: %.9f %s\n",
+		"  Burned: %.9f %s\n":                    "  Сожжено: %.9f %s\n",
+		"  Rent Deposited: %.9f SOL\n":           "  Внесённая рента: %.9f SOL\n",
+		"  Rent Recovered: %.9f SOL\n":           "  Возвращённая рента: %.9f SOL\n",
+		"  Net SOL Change: %+.9f SOL\n":          "  Итоговое изменение SOL: %+.9f SOL\n",
+		"\nValue: $%.2f\n":                       "\nСтоимость: $%.2f\n",
+		"\n⚠️  Balance Discrepancy:\n":           "\n⚠️  Расхождение баланса:\n",
+		"the wallet received %.9f, below the minimum out of %.9f although the pool paid out %.9f; a token transfer fee or another instruction took the difference": "кошелёк получил %.9f, меньше минимума %.9f, хотя пул выплатил %.9f; разницу удержала комиссия за перевод токена или другая инструкция",
+		"Explorer: %s\n":                    "Обозреватель: %s\n",
+		"Report appended to %s\n":           "Отчёт добавлен в %s\n",
+		"\nFetching transaction details...": "\nЗагрузка данных транзакции...",
+
+		// Quote and swap flow
+		"\n=== SWAP PARAMETERS ===\n":  "\n=== ПАРАМЕТРЫ ОБМЕНА ===\n",
+		"\n=== QUOTE RESULT ===\n":     "\n=== РЕЗУЛЬТАТ КОТИРОВКИ ===\n",
+		"Token: %s\n":                  "Токен: %s\n",
+		"Amount: %s = %.9f %s\n":       "Сумма: %s = %.9f %s\n",
+		"Amount In: %.9f\n":            "Отдаёте: %.9f\n",
+		"Expected Out: %.9f\n":         "Ожидаемо получите: %.9f\n",
+		"%s %s Expected Out: %.9f\n":   "%s %s ожидаемо получите: %.9f\n",
+		"Minimum Out: %s\n":            "Минимум к получению: %s\n",
+		"New Minimum Out: %s\n":        "Новый минимум к получению: %s\n",
+		"Price Impact: %.4f%%\n":       "Влияние на цену: %.4f%%\n",
+		"Slippage Tolerance: %.2f%%\n": "Допустимое проскальзывание: %.2f%%\n",
+		"Protocol: %s\n":               "Протокол: %s\n",
+		"Found pool: %s\n":             "Найден пул: %s\n",
+		"Paid Out In: %s\n":            "Выплата в: %s\n",
+		"Value In: %s\n":               "Стоимость на входе: %s\n",
+		"Value Out: %s\n":              "Стоимость на выходе: %s\n",
+		"Value Out: $%.2f\n":           "Стоимость на выходе: $%.2f\n",
+		"Wallet loaded: %s\n":          "Кошелёк загружен: %s\n",
+		"Randomized amount: %.9f\n":    "Случайная сумма: %.9f\n",
+		"Auto slippage: only %d price moves in the last %s, using %.2f%%\n":                       "Авто-проскальзывание: всего %d изменений цены за последние %s, используется %.2f%%\n",
+		"Auto slippage: %.2f%% (%.1fσ of %.3f%% over %s from %d price moves, size factor %.2f)\n": "Авто-проскальзывание: %.2f%% (%.1fσ от %.3f%% за %s по %d изменениям цены, коэффициент размера %.2f)\n",
+		"Overrides: %s\n":                                                                                "Переопределения: %s\n",
+		"Anti-MEV: slippage capped at %.2f%%\n":                                                          "Анти-MEV: проскальзывание ограничено %.2f%%\n",
+		"Waiting %s before sending (send jitter)...\n":                                                   "Ожидание %s перед отправкой (случайная задержка)...\n",
+		"%s fills better than the AMM (%.9f vs %.9f)\n":                                                  "%s исполняет лучше AMM (%.9f против %.9f)\n",
+		"Token %s is still on the pump.fun bonding curve\n":                                              "Токен %s ещё на кривой связывания pump.fun\n",
+		"Token %s has migrated off the pump.fun bonding curve\n":                                         "Токен %s ушёл с кривой связывания pump.fun\n",
+		"Token %s is still on its Raydium LaunchLab bonding curve\n":                                     "Токен %s ещё на кривой связывания Raydium LaunchLab\n",
+		"Token %s has migrated off its LaunchLab bonding curve\n":                                        "Токен %s ушёл с кривой связывания LaunchLab\n",
+		"Token %s has migrated off its LaunchLab bonding curve to a CPMM pool, which is not supported\n": "Токен %s ушёл с кривой связывания LaunchLab в пул CPMM, который не поддерживается\n",
+		"\n✅ Swap executed successfully!\n":                                                              "\n✅ Обмен успешно выполнен!\n",
+		"\n✅ Swap executed successfully on %s!\n":                                                        "\n✅ Обмен успешно выполнен в %s!\n",
+		"\n✅ Swap proposed to multisig!\n":                                                               "\n✅ Обмен предложен мультиподписи!\n",
+		"Remaining members must approve and execute the proposal in Squads":                              "Остальные участники должны одобрить и исполнить предложение в Squads",
+		"\nUnsigned transaction written to %s (%s)\n":                                                    "\nНеподписанная транзакция записана в %s (%s)\n",
+		"Blockhash expires in ~60-90 seconds; sign and run `broadcast -file %s` promptly\n":              "Blockhash истекает через ~60-90 секунд; подпишите и запустите `broadcast -file %s` без промедления\n",
+
+		// Errors and warnings
+		"Invalid amount: %v":                                                          "Неверная сумма: %v",
+		"Invalid token address: %v":                                                   "Неверный адрес токена: %v",
+		"Amount too small. Minimum swap amount is %.3f":                               "Сумма слишком мала. Минимальная сумма обмена %.3f",
+		"No pools with liquidity found for token %s":                                  "Пулы с ликвидностью для токена %s не найдены",
+		"Failed to load pool: %v":                                                     "Не удалось загрузить пул: %v",
+		"Failed to load wallet: %v":                                                   "Не удалось загрузить кошелёк: %v",
+		"Failed to get slippage: %v":                                                  "Не удалось получить проскальзывание: %v",
+		"Failed to build transaction: %v":                                             "Не удалось собрать транзакцию: %v",
+		"Failed to load spend limits: %v":                                             "Не удалось загрузить лимиты расходов: %v",
+		"Failed to resolve the pool's token for spend limits: %v":                     "Не удалось определить токен пула для лимитов расходов: %v",
+		"-export-tx requires -address, %s or %s: %v":                                  "-export-tx требует -address, %s или %s: %v",
+		"Pool Explorer: %s\n":                                                         "Пул в обозревателе: %s\n",
+		"Token Explorer: %s\n":                                                        "Токен в обозревателе: %s\n",
+		"Delegate: %s\n":                                                              "Делегат: %s\n",
+		"Signed receipt stored (verify with `receipts verify`)\n":                     "Подписанная квитанция сохранена (проверка: `receipts verify`)\n",
+		"Warning: Could not store signed receipt: %v\n":                               "Предупреждение: не удалось сохранить подписанную квитанцию: %v\n",
+		"\n=== POOL COMPARISON ===\n":                                                 "\n=== СРАВНЕНИЕ ПУЛОВ ===\n",
+		"Choose a pool [1-%d, Enter for the selected one]: ":                          "Выберите пул [1-%d, Enter — выбранный]: ",
+		"Enter a number between 1 and %d\n":                                           "Введите число от 1 до %d\n",
+		"Pool %d cannot be traded: %s\n":                                              "Пулом %d нельзя торговать: %s\n",
+		"Watch-only wallet: %s\n":                                                     "Кошелёк только для просмотра: %s\n",
+		"Spend limits: %v":                                                            "Лимиты расходов: %v",
+		"Simulating a sell after the buy (honeypot check)...\n":                       "Симуляция продажи после покупки (проверка на honeypot)...\n",
+		"Refusing to buy: %v; pass -skip-honeypot-check to buy anyway":                "Покупка отклонена: %v; передайте -skip-honeypot-check, чтобы купить всё равно",
+		"⚠️  Warning: honeypot check inconclusive: %v\n":                              "⚠️  Предупреждение: проверка на honeypot не дала результата: %v\n",
+		"⚠️  Warning: freeze authority %s can freeze the tokens after you buy them\n": "⚠️  Предупреждение: freeze authority %s может заморозить токены после покупки\n",
+		"Refusing to trade: %v":                                                       "Сделка отклонена: %v",
+		"Dry run failed: %v":                                                          "Пробный запуск не удался: %v",
+		"Export failed: %v":                                                           "Экспорт не удался: %v",
+		"Solana Pay failed: %v":                                                       "Solana Pay не удался: %v",
+		"%s %.9f on %s, minimum out %s":                                               "%s %.9f в пуле %s, минимум на выходе %s",
+		"Multisig proposal failed: %v":                                                "Предложение мультиподписи не удалось: %v",
+		"Swap failed: %v":                                                             "Обмен не удался: %v",
+		"%s swap failed: %v":                                                          "Обмен через %s не удался: %v",
+		"Swap aborted: %v":                                                            "Обмен прерван: %v",
+		"⚠️  Warning: %v\n":                                                           "⚠️  Предупреждение: %v\n",
+		"Warning: Could not check for duplicate trades: %v\n":                         "Предупреждение: не удалось проверить повторные сделки: %v\n",
+		"Warning: Could not fetch SOL/USD price: %v\n":                                "Предупреждение: не удалось получить цену SOL/USD: %v\n",
+		"Warning: Could not generate full report: %v\n":                               "Предупреждение: не удалось сформировать полный отчёт: %v\n",
+		"Warning: Could not record trade for spend limits: %v\n":                      "Предупреждение: не удалось учесть сделку в лимитах расходов: %v\n",
+		"Warning: Could not record trade for the duplicate check: %v\n":               "Предупреждение: не удалось записать сделку для проверки повторов: %v\n",
+		"Warning: Could not value quote: %v\n":                                        "Предупреждение: не удалось оценить котировку: %v\n",
+		"Warning: Could not value report: %v\n":                                       "Предупреждение: не удалось оценить отчёт: %v\n",
+		"Warning: Could not write CSV report: %v\n":                                   "Предупреждение: не удалось записать CSV-отчёт: %v\n",
+	},
+}
+
+// selectLanguage sets the active language from a -lang value or a locale
+// such as ru_RU.UTF-8. "", C and POSIX mean English.
+func selectLanguage(lang string) error {
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "_.-@"); i >= 0 {
+		lang = lang[:i]
+	}
+	switch lang {
+	case "", "c", "posix", LANG_EN:
+		activeLanguage = LANG_EN
+		return nil
+	}
+	if _, ok := MESSAGES[lang]; !ok {
+		return fmt.Errorf("unsupported language %q (supported: %s, %s)", lang, LANG_EN, LANG_RU)
+	}
+	activeLanguage = lang
+	return nil
+}
+
+// tr translates an English message into the active language
+func tr(message string) string {
+	if translated, ok := MESSAGES[activeLanguage][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// isYes reports whether a prompt answer accepts, in any supported language
+func isYes(response string) bool {
+	switch strings.TrimSpace(strings.ToLower(response)) {
+	case "y", "yes", "д", "да":
+		return true
+	}
+	return false
+}
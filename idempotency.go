@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	// Files in the state directory holding used idempotency keys and the CLI's recent trades
+	IDEMPOTENCY_FILE   = "idempotency.json"
+	RECENT_TRADES_FILE = "recent-trades.json"
+	// How long a used key is remembered
+	IDEMPOTENCY_KEY_TTL = 24 * time.Hour
+	// Trades within this window and relative amount difference count as near-identical
+	DEFAULT_DUPLICATE_WINDOW   = 30 * time.Second
+	DUPLICATE_AMOUNT_TOLERANCE = 0.01
+)
+
+// IdempotencyRecord is the outcome of the swap first sent with a key
+type IdempotencyRecord struct {
+	Status    string    `json:"status"` // "pending", "sent" or "failed"
+	TxHash    string    `json:"tx_hash,omitempty"`
+	Pool      string    `json:"pool"`
+	Side      string    `json:"side"`
+	Amount    float64   `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RecentTrade is a swap executed from the CLI
+type RecentTrade struct {
+	Pool   string    `json:"pool"`
+	Side   string    `json:"side"`
+	Amount float64   `json:"amount"`
+	TxHash string    `json:"tx_hash"`
+	At     time.Time `json:"at"`
+}
+
+// updateStateFile reads a JSON state file into value, applies update and
+// writes it back under the file's lock; a missing file leaves value as is
+func updateStateFile(name string, value any, update func()) error {
+	dir, err := stateDir()
+	if err != nil {
+		return err
+	}
+	return updateJSONFile(filepath.Join(dir, name), value, func() error {
+		update()
+		return nil
+	})
+}
+
+// claimIdempotencyKey reserves a key for a swap. When the key was already
+// used, its record is returned and the swap must not be sent.
+func claimIdempotencyKey(key string, pool string, side string, amount float64) (*IdempotencyRecord, error) {
+	var existing *IdempotencyRecord
+	records := make(map[string]IdempotencyRecord)
+	err := updateStateFile(IDEMPOTENCY_FILE, &records, func() {
+		cutoff := time.Now().Add(-IDEMPOTENCY_KEY_TTL)
+		for k, record := range records {
+			if record.CreatedAt.Before(cutoff) {
+				delete(records, k)
+			}
+		}
+		if record, ok := records[key]; ok {
+			existing = &record
+			return
+		}
+		records[key] = IdempotencyRecord{Status: "pending", Pool: pool, Side: side, Amount: amount, CreatedAt: time.Now().UTC()}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// completeIdempotencyKey records the outcome of a claimed key. Failed keys
+// stay used, since a swap that failed to confirm may still have landed.
+func completeIdempotencyKey(key string, txHash string, swapErr error) error {
+	records := make(map[string]IdempotencyRecord)
+	return updateStateFile(IDEMPOTENCY_FILE, &records, func() {
+		record := records[key]
+		record.Status, record.TxHash = "sent", txHash
+		if swapErr != nil {
+			record.Status = "failed"
+		}
+		records[key] = record
+	})
+}
+
+// describe summarizes a used key for the refusal message
+func (r *IdempotencyRecord) describe() string {
+	text := fmt.Sprintf("%s %g on %s at %s, %s", r.Side, r.Amount, r.Pool, r.CreatedAt.Format(time.RFC3339), r.Status)
+	if r.TxHash != "" {
+		text += " as " + r.TxHash
+	}
+	return text
+}
+
+// recordRecentTrade logs a CLI swap for the duplicate check
+func recordRecentTrade(pool string, side string, amount float64, txHash string) error {
+	var trades []RecentTrade
+	return updateStateFile(RECENT_TRADES_FILE, &trades, func() {
+		// Only the longest plausible window matters, so an hour of history is plenty
+		cutoff := time.Now().Add(-time.Hour)
+		trades = slices.DeleteFunc(trades, func(t RecentTrade) bool { return t.At.Before(cutoff) })
+		trades = append(trades, RecentTrade{Pool: pool, Side: side, Amount: amount, TxHash: txHash, At: time.Now().UTC()})
+	})
+}
+
+// findDuplicateTrade returns the latest CLI trade on the same pool and side
+// with an amount within DUPLICATE_AMOUNT_TOLERANCE in the last window, or nil
+func findDuplicateTrade(pool string, side string, amount float64, window time.Duration) (*RecentTrade, error) {
+	if window <= 0 {
+		return nil, nil
+	}
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, RECENT_TRADES_FILE)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var trades []RecentTrade
+	if err := json.Unmarshal(data, &trades); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	cutoff := time.Now().Add(-window)
+	for i := len(trades) - 1; i >= 0; i-- {
+		t := trades[i]
+		if t.At.After(cutoff) && t.Pool == pool && t.Side == side &&
+			math.Abs(t.Amount-amount) <= DUPLICATE_AMOUNT_TOLERANCE*math.Max(t.Amount, amount) {
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+// confirmDuplicateTrade warns about a near-identical recent CLI trade and
+// asks whether to send this one anyway
+func confirmDuplicateTrade(pool string, side string, amount float64, window time.Duration) bool {
+	duplicate, err := findDuplicateTrade(pool, side, amount, window)
+	if err != nil {
+		fmt.Printf(tr("Warning: Could not check for duplicate trades: %v\n"), err)
+		return true
+	}
+	if duplicate == nil {
+		return true
+	}
+	fmt.Printf(tr("\n⚠️  A near-identical trade was executed %s ago: %s %g on %s (%s)\n"),
+		time.Since(duplicate.At).Round(time.Second), duplicate.Side, duplicate.Amount, duplicate.Pool, duplicate.TxHash)
+	return confirmPrompt(tr("Send this trade anyway?"))
+}
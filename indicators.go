@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// DEFAULT_INDICATOR_HISTORY bounds how much of the price stream is retained
+const DEFAULT_INDICATOR_HISTORY = 24 * time.Hour
+
+// PriceSample is a single observation from a pool price/trade stream.
+// Volume is the traded size in SOL, 0 for pure price updates.
+type PriceSample struct {
+	Time   time.Time
+	Price  float64
+	Volume float64
+}
+
+// IndicatorEngine computes rolling indicators (EMA, VWAP, RSI, realized
+// volatility) over a recorded price stream. It is safe for concurrent use,
+// so a stream subscriber can feed it while strategies and alerts read it.
+type IndicatorEngine struct {
+	mu      sync.RWMutex
+	samples []PriceSample
+	history time.Duration
+}
+
+// newIndicatorEngine creates an engine retaining the given amount of history
+func newIndicatorEngine(history time.Duration) *IndicatorEngine {
+	if history <= 0 {
+		history = DEFAULT_INDICATOR_HISTORY
+	}
+	return &IndicatorEngine{history: history}
+}
+
+// Add records a sample and drops samples older than the retained history
+func (e *IndicatorEngine) Add(sample PriceSample) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.samples = append(e.samples, sample)
+
+	cutoff := sample.Time.Add(-e.history)
+	drop := 0
+	for drop < len(e.samples) && e.samples[drop].Time.Before(cutoff) {
+		drop++
+	}
+	if drop > 0 {
+		e.samples = append(e.samples[:0], e.samples[drop:]...)
+	}
+}
+
+// ReplaceLast overwrites the most recent sample, for a later observation of
+// the same moment such as the second vault update of a swap
+func (e *IndicatorEngine) ReplaceLast(sample PriceSample) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.samples) == 0 {
+		e.samples = append(e.samples, sample)
+		return
+	}
+	e.samples[len(e.samples)-1] = sample
+}
+
+// window returns the samples within the given duration of the latest sample
+func (e *IndicatorEngine) window(d time.Duration) []PriceSample {
+	if len(e.samples) == 0 {
+		return nil
+	}
+
+	cutoff := e.samples[len(e.samples)-1].Time.Add(-d)
+	start := len(e.samples)
+	for start > 0 && !e.samples[start-1].Time.Before(cutoff) {
+		start--
+	}
+	return e.samples[start:]
+}
+
+// Last returns the most recent price
+func (e *IndicatorEngine) Last() (float64, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if len(e.samples) == 0 {
+		return 0, false
+	}
+	return e.samples[len(e.samples)-1].Price, true
+}
+
+// EMA returns the time-weighted exponential moving average with the given time constant
+func (e *IndicatorEngine) EMA(period time.Duration) (float64, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if len(e.samples) == 0 {
+		return 0, fmt.Errorf("no price data")
+	}
+
+	// Seed from samples within a few time constants; older ones contribute < 5%
+	samples := e.window(3 * period)
+	ema := samples[0].Price
+	for i := 1; i < len(samples); i++ {
+		dt := samples[i].Time.Sub(samples[i-1].Time)
+		alpha := 1 - math.Exp(-float64(dt)/float64(period))
+		ema += alpha * (samples[i].Price - ema)
+	}
+
+	return ema, nil
+}
+
+// VWAP returns the volume-weighted average price over the window
+func (e *IndicatorEngine) VWAP(d time.Duration) (float64, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var notional, volume float64
+	for _, sample := range e.window(d) {
+		notional += sample.Price * sample.Volume
+		volume += sample.Volume
+	}
+
+	if volume == 0 {
+		return 0, fmt.Errorf("no traded volume in the last %s", d)
+	}
+	return notional / volume, nil
+}
+
+// RSI returns the relative strength index (0-100) of price changes over the window
+func (e *IndicatorEngine) RSI(d time.Duration) (float64, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	samples := e.window(d)
+	if len(samples) < 2 {
+		return 0, fmt.Errorf("not enough price data for RSI over %s", d)
+	}
+
+	var gains, losses float64
+	for i := 1; i < len(samples); i++ {
+		change := samples[i].Price - samples[i-1].Price
+		if change > 0 {
+			gains += change
+		} else {
+			losses -= change
+		}
+	}
+
+	if losses == 0 {
+		if gains == 0 {
+			return 50, nil
+		}
+		return 100, nil
+	}
+	return 100 - 100/(1+gains/losses), nil
+}
+
+// Volatility returns the realized volatility over the window as the square
+// root of summed squared log returns (e.g. 0.05 == 5% over the window)
+func (e *IndicatorEngine) Volatility(d time.Duration) (float64, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	samples := e.window(d)
+	if len(samples) < 2 {
+		return 0, fmt.Errorf("not enough price data for volatility over %s", d)
+	}
+
+	var variance float64
+	for i := 1; i < len(samples); i++ {
+		if samples[i-1].Price <= 0 || samples[i].Price <= 0 {
+			continue
+		}
+		r := math.Log(samples[i].Price / samples[i-1].Price)
+		variance += r * r
+	}
+
+	return math.Sqrt(variance), nil
+}
+
+// Snapshot returns the common indicators over a window, omitting unavailable ones
+func (e *IndicatorEngine) Snapshot(d time.Duration) map[string]float64 {
+	snapshot := make(map[string]float64)
+	if price, ok := e.Last(); ok {
+		snapshot["price"] = price
+	}
+	if value, err := e.EMA(d); err == nil {
+		snapshot["ema"] = value
+	}
+	if value, err := e.VWAP(d); err == nil {
+		snapshot["vwap"] = value
+	}
+	if value, err := e.RSI(d); err == nil {
+		snapshot["rsi"] = value
+	}
+	if value, err := e.Volatility(d); err == nil {
+		snapshot["volatility"] = value
+	}
+	return snapshot
+}
+
+// Var exposes the latest price to trigger expressions
+func (e *IndicatorEngine) Var(name string) (float64, bool) {
+	if name == "price" {
+		return e.Last()
+	}
+	return 0, false
+}
+
+// Call exposes the indicators to trigger expressions, e.g. ema(5m) or rsi(1h)
+func (e *IndicatorEngine) Call(name string, args []TriggerValue) (float64, error) {
+	if len(args) != 1 || args[0].Kind != "duration" {
+		return 0, fmt.Errorf("expects a single duration argument, e.g. %s(5m)", name)
+	}
+	window := args[0].Duration
+
+	switch name {
+	case "ema":
+		return e.EMA(window)
+	case "vwap":
+		return e.VWAP(window)
+	case "rsi":
+		return e.RSI(window)
+	case "volatility":
+		return e.Volatility(window)
+	}
+	return 0, fmt.Errorf("unknown indicator")
+}
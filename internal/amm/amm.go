@@ -0,0 +1,38 @@
+// Package amm holds the integer swap math of Raydium V4's constant product
+// pools and Saber's StableSwap pools, rounding exactly as the programs do
+package amm
+
+import (
+	"math/big"
+
+	"awesomeProject/internal/atoms"
+)
+
+// SwapBaseIn quotes an exact-input swap the way the V4 program does: the
+// fee is taken from the input, rounded up, and the rest is swapped against
+// the reserves. Reserves are the vault balances net of PnL owed to the
+// protocol.
+func SwapBaseIn(reserveIn, reserveOut, feeNumerator, feeDenominator, amountIn uint64) (amountOut uint64, fee uint64) {
+	fee = atoms.MulDiv(amountIn, feeNumerator, feeDenominator, true)
+	return ConstantProductOut(reserveOut, reserveIn, amountIn-fee), fee
+}
+
+// ConstantProductOut returns the output of swapping amountIn against the
+// reserves, keeping x * y = k and rounding down:
+// amountOut = (reserveOut * amountIn) / (reserveIn + amountIn)
+func ConstantProductOut(reserveOut, reserveIn, amountIn uint64) uint64 {
+	numerator := new(big.Int).Mul(
+		new(big.Int).SetUint64(reserveOut),
+		new(big.Int).SetUint64(amountIn),
+	)
+
+	denominator := new(big.Int).Add(
+		new(big.Int).SetUint64(reserveIn),
+		new(big.Int).SetUint64(amountIn),
+	)
+	if denominator.Sign() == 0 {
+		return 0
+	}
+
+	return new(big.Int).Div(numerator, denominator).Uint64()
+}
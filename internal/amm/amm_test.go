@@ -0,0 +1,73 @@
+package amm
+
+import "testing"
+
+func TestSwapBaseIn(t *testing.T) {
+	tests := []struct {
+		name                  string
+		reserveIn, reserveOut uint64
+		amountIn              uint64
+		wantOut, wantFee      uint64
+	}{
+		{"1 SOL into SOL/USDC", 1_000_000_000_000, 150_000_000_000, 1_000_000_000, 149_475_897, 2_500_000},
+		{"18-decimal output", 500_000_000_000, 10_000_000_000_000_000_000, 1_000_000_000, 19_910_278_993_408_150, 2_500_000},
+		{"fee rounds up", 1_000_000_000_000, 150_000_000_000, 1, 0, 1},
+		{"empty pool", 0, 0, 1_000_000_000, 0, 2_500_000},
+		{"nothing in", 1_000_000_000_000, 150_000_000_000, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, fee := SwapBaseIn(tt.reserveIn, tt.reserveOut, 25, 10_000, tt.amountIn)
+			if out != tt.wantOut || fee != tt.wantFee {
+				t.Errorf("SwapBaseIn(%d) = %d, fee %d; want %d, fee %d", tt.amountIn, out, fee, tt.wantOut, tt.wantFee)
+			}
+		})
+	}
+}
+
+func TestStableSwapOut(t *testing.T) {
+	tests := []struct {
+		name                  string
+		reserveIn, reserveOut uint64
+		amp                   uint64
+		feeNumerator          uint64
+		amountIn              uint64
+		wantOut, wantFee      uint64
+	}{
+		{"1000 USDC into a balanced pool", 1_000_000_000_000, 1_000_000_000_000, 100, 4, 1_000_000_000, 999_590_104, 399_996},
+		{"into the heavy side", 1_000_000_000_000, 500_000_000_000, 100, 4, 1_000_000_000, 991_296_479, 396_677},
+		{"10% of the pool stays near par", 1_000_000_000_000, 1_000_000_000_000, 100, 0, 100_000_000_000, 99_900_110_865, 0},
+		{"low amplification slips", 1_000_000_000_000, 1_000_000_000_000, 1, 0, 100_000_000_000, 95_227_299_778, 0},
+		{"empty pool", 0, 0, 100, 4, 1_000_000_000, 0, 0},
+		{"nothing in", 1_000_000_000_000, 1_000_000_000_000, 100, 4, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, fee := StableSwapOut(tt.reserveIn, tt.reserveOut, tt.amp, tt.feeNumerator, 10_000, tt.amountIn)
+			if out != tt.wantOut || fee != tt.wantFee {
+				t.Errorf("StableSwapOut(%d) = %d, fee %d; want %d, fee %d", tt.amountIn, out, fee, tt.wantOut, tt.wantFee)
+			}
+		})
+	}
+}
+
+func TestStableAmp(t *testing.T) {
+	tests := []struct {
+		name            string
+		initial, target uint64
+		now             int64
+		want            uint64
+	}{
+		{"before the ramp", 100, 200, 500, 100},
+		{"ramping up", 100, 200, 1_250, 125},
+		{"ramping down", 200, 100, 1_250, 175},
+		{"after the ramp", 100, 200, 3_000, 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StableAmp(tt.initial, tt.target, 1_000, 2_000, tt.now); got != tt.want {
+				t.Errorf("StableAmp at %d = %d, want %d", tt.now, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package amm
+
+import (
+	"math/big"
+
+	"awesomeProject/internal/atoms"
+)
+
+// STABLE_MAX_ITERATIONS bounds the Newton iterations for D and y, as the
+// StableSwap programs do
+const STABLE_MAX_ITERATIONS = 256
+
+// stableCoins is the number of tokens in a StableSwap pool
+var stableCoins = big.NewInt(2)
+
+// StableAmp returns the amplification coefficient at now, ramping linearly
+// from initial to target between startRamp and stopRamp
+func StableAmp(initial, target uint64, startRamp, stopRamp, now int64) uint64 {
+	if now >= stopRamp || stopRamp <= startRamp {
+		return target
+	}
+	if now <= startRamp {
+		return initial
+	}
+	elapsed := uint64(now - startRamp)
+	duration := uint64(stopRamp - startRamp)
+	if target > initial {
+		return initial + (target-initial)*elapsed/duration
+	}
+	return initial - (initial-target)*elapsed/duration
+}
+
+// StableSwapOut quotes an exact-input swap on a two-token StableSwap pool
+// the way Saber's program does: the invariant D is solved for the current
+// reserves, the new output reserve for the grown input reserve, and the
+// trade fee is taken from the output, rounded down.
+func StableSwapOut(reserveIn, reserveOut, amp, feeNumerator, feeDenominator, amountIn uint64) (amountOut uint64, fee uint64) {
+	if reserveIn == 0 || reserveOut == 0 || amp == 0 || amountIn == 0 {
+		return 0, 0
+	}
+
+	d := stableD(amp, reserveIn, reserveOut)
+	x := new(big.Int).Add(new(big.Int).SetUint64(reserveIn), new(big.Int).SetUint64(amountIn))
+	y := stableY(amp, x, d)
+
+	dy := new(big.Int).Sub(new(big.Int).SetUint64(reserveOut), y)
+	if dy.Sign() <= 0 {
+		return 0, 0
+	}
+	gross := dy.Uint64()
+	fee = atoms.MulDiv(gross, feeNumerator, feeDenominator, false)
+	return gross - fee, fee
+}
+
+// stableD solves the invariant
+// A*n^n*(x+y) + D = A*n^n*D + D^(n+1) / (n^n*x*y)
+// for D by Newton's method
+func stableD(amp, reserveA, reserveB uint64) *big.Int {
+	a := new(big.Int).SetUint64(reserveA)
+	b := new(big.Int).SetUint64(reserveB)
+	sum := new(big.Int).Add(a, b)
+	leverage := new(big.Int).Mul(new(big.Int).SetUint64(amp), stableCoins)
+	aTimesCoins := new(big.Int).Mul(a, stableCoins)
+	bTimesCoins := new(big.Int).Mul(b, stableCoins)
+
+	d := new(big.Int).Set(sum)
+	for i := 0; i < STABLE_MAX_ITERATIONS; i++ {
+		product := new(big.Int).Set(d)
+		product.Mul(product, d).Quo(product, aTimesCoins)
+		product.Mul(product, d).Quo(product, bTimesCoins)
+
+		// d = (leverage*sum + product*n) * d / ((leverage-1)*d + (n+1)*product)
+		numerator := new(big.Int).Mul(leverage, sum)
+		numerator.Add(numerator, new(big.Int).Mul(product, stableCoins))
+		numerator.Mul(numerator, d)
+		denominator := new(big.Int).Mul(d, new(big.Int).Sub(leverage, big.NewInt(1)))
+		denominator.Add(denominator, new(big.Int).Mul(product, big.NewInt(3)))
+
+		previous := d
+		d = numerator.Quo(numerator, denominator)
+		if new(big.Int).Sub(d, previous).CmpAbs(big.NewInt(1)) <= 0 {
+			break
+		}
+	}
+	return d
+}
+
+// stableY solves the invariant for the other reserve given reserve x and D
+func stableY(amp uint64, x, d *big.Int) *big.Int {
+	ann := new(big.Int).Mul(new(big.Int).SetUint64(amp), stableCoins)
+
+	// c = D^3 / (x*n * ann*n), b = D/ann + x
+	c := new(big.Int).Mul(d, d)
+	c.Quo(c, new(big.Int).Mul(x, stableCoins))
+	c.Mul(c, d).Quo(c, new(big.Int).Mul(ann, stableCoins))
+	b := new(big.Int).Quo(d, ann)
+	b.Add(b, x)
+
+	y := new(big.Int).Set(d)
+	for i := 0; i < STABLE_MAX_ITERATIONS; i++ {
+		// y = (y^2 + c) / (2y + b - D)
+		numerator := new(big.Int).Mul(y, y)
+		numerator.Add(numerator, c)
+		denominator := new(big.Int).Lsh(y, 1)
+		denominator.Add(denominator, b).Sub(denominator, d)
+
+		previous := y
+		y = numerator.Quo(numerator, denominator)
+		if new(big.Int).Sub(y, previous).CmpAbs(big.NewInt(1)) <= 0 {
+			break
+		}
+	}
+	return y
+}
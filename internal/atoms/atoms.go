@@ -0,0 +1,139 @@
+// Package atoms converts token amounts between the decimals they are entered
+// and displayed in and the raw integer atoms they settle in on chain.
+//
+// Converting through float64 multiplication loses atoms (8.2 * 1e9 is
+// 8199999999.999999), so conversions go through exact decimal rationals and
+// all arithmetic on atoms stays in integers.
+package atoms
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Pow10 returns 10^n as a big.Int
+func Pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// decimalRat returns the decimal x was written as, i.e. its shortest
+// round-trip representation, rather than its binary approximation
+func decimalRat(x float64) (*big.Rat, error) {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return nil, fmt.Errorf("invalid amount %v", x)
+	}
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(x, 'f', -1, 64))
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %v", x)
+	}
+	return r, nil
+}
+
+// Floor truncates a non-negative rational to uint64
+func Floor(r *big.Rat) (uint64, error) {
+	q := new(big.Int).Quo(r.Num(), r.Denom())
+	if !q.IsUint64() {
+		return 0, fmt.Errorf("amount %s overflows u64", q)
+	}
+	return q.Uint64(), nil
+}
+
+// FromAmount converts a UI amount to raw atoms for a mint with the given
+// decimals. Digits beyond the mint's precision are truncated, never rounded up.
+func FromAmount(amount float64, decimals int) (uint64, error) {
+	r, err := decimalRat(amount)
+	if err != nil {
+		return 0, err
+	}
+	if r.Sign() < 0 {
+		return 0, fmt.Errorf("negative amount %v", amount)
+	}
+	r.Mul(r, new(big.Rat).SetInt(Pow10(decimals)))
+	return Floor(r)
+}
+
+// Format renders raw atoms as an exact decimal string
+func Format(raw uint64, decimals int) string {
+	r := new(big.Rat).SetFrac(new(big.Int).SetUint64(raw), Pow10(decimals))
+	return r.FloatString(decimals)
+}
+
+// ToAmount converts raw atoms to a UI amount for display and pricing
+func ToAmount(raw uint64, decimals int) float64 {
+	f, _ := new(big.Rat).SetFrac(new(big.Int).SetUint64(raw), Pow10(decimals)).Float64()
+	return f
+}
+
+// ToSignedAmount converts a raw balance change to a UI amount
+func ToSignedAmount(raw int64, decimals int) float64 {
+	f, _ := new(big.Rat).SetFrac(big.NewInt(raw), Pow10(decimals)).Float64()
+	return f
+}
+
+// MulDiv computes a*b/c without overflow, rounding down or up. It returns 0
+// when c is 0.
+func MulDiv(a, b, c uint64, roundUp bool) uint64 {
+	if c == 0 {
+		return 0
+	}
+	product := new(big.Int).Mul(new(big.Int).SetUint64(a), new(big.Int).SetUint64(b))
+	divisor := new(big.Int).SetUint64(c)
+	if roundUp {
+		product.Add(product, new(big.Int).Sub(divisor, big.NewInt(1)))
+	}
+	return new(big.Int).Div(product, divisor).Uint64()
+}
+
+// ApplySlippage returns raw reduced by slippagePercent, rounded down
+func ApplySlippage(raw uint64, slippagePercent float64) uint64 {
+	pct, err := decimalRat(slippagePercent)
+	if err != nil || pct.Sign() < 0 {
+		return raw
+	}
+	keep := new(big.Rat).Sub(big.NewRat(100, 1), pct)
+	if keep.Sign() <= 0 {
+		return 0
+	}
+	r := new(big.Rat).Mul(new(big.Rat).SetInt(new(big.Int).SetUint64(raw)), keep)
+	r.Quo(r, big.NewRat(100, 1))
+	out, _ := Floor(r)
+	return out
+}
+
+// Scale returns raw scaled by the ratio of two UI amounts, rounded down
+func Scale(raw uint64, to float64, from float64) uint64 {
+	num, err := decimalRat(to)
+	if err != nil || num.Sign() < 0 {
+		return 0
+	}
+	den, err := decimalRat(from)
+	if err != nil || den.Sign() <= 0 {
+		return 0
+	}
+	r := new(big.Rat).Mul(new(big.Rat).SetInt(new(big.Int).SetUint64(raw)), num)
+	out, err := Floor(r.Quo(r, den))
+	if err != nil {
+		return math.MaxUint64
+	}
+	return out
+}
+
+// AddSlippage returns raw increased by slippagePercent, rounded up, for maximum-in bounds
+func AddSlippage(raw uint64, slippagePercent float64) uint64 {
+	pct, err := decimalRat(slippagePercent)
+	if err != nil || pct.Sign() < 0 {
+		return raw
+	}
+	r := new(big.Rat).Mul(new(big.Rat).SetInt(new(big.Int).SetUint64(raw)), new(big.Rat).Add(big.NewRat(100, 1), pct))
+	r.Quo(r, big.NewRat(100, 1))
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() > 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if !q.IsUint64() {
+		return math.MaxUint64
+	}
+	return q.Uint64()
+}
@@ -0,0 +1,75 @@
+package atoms
+
+import "testing"
+
+// Amounts of 9- and 18-decimal tokens exceed float64's 53-bit mantissa in
+// raw atoms, so conversions and bounds must be exact
+func TestFromAmount(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		decimals int
+		want     uint64
+		wantErr  bool
+	}{
+		{"9 decimals", 8.2, 9, 8_200_000_000, false},
+		{"9 decimals truncated", 1.23456789012, 9, 1_234_567_890, false},
+		{"9 decimals below one atom", 1e-10, 9, 0, false},
+		{"18 decimals", 12.345678901234567, 18, 12_345_678_901_234_567_000, false},
+		{"18 decimals one atom", 1e-18, 18, 1, false},
+		{"18 decimals overflows u64", 18.5, 18, 0, true},
+		{"negative", -1, 9, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromAmount(tt.amount, tt.decimals)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromAmount(%v, %d) error = %v, want error %v", tt.amount, tt.decimals, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("FromAmount(%v, %d) = %d, want %d", tt.amount, tt.decimals, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		raw      uint64
+		decimals int
+		want     string
+	}{
+		{8_200_000_000, 9, "8.200000000"},
+		{1, 9, "0.000000001"},
+		{12_345_678_901_234_567_891, 18, "12.345678901234567891"},
+		{1, 18, "0.000000000000000001"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.raw, tt.decimals); got != tt.want {
+			t.Errorf("Format(%d, %d) = %s, want %s", tt.raw, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+// Cached quotes are rescaled to the requested amount in raw atoms
+func TestScale(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      uint64
+		to, from float64
+		want     uint64
+	}{
+		{"9 decimals", 1_000_000_001, 0.3, 0.7, 428_571_429},
+		{"18 decimals", 999_999_999_999_999_999, 0.3, 0.7, 428_571_428_571_428_571},
+		{"same amount", 12_345_678_901_234_567_891, 0.1, 0.1, 12_345_678_901_234_567_891},
+		{"zero from", 1_000_000_000, 1, 0, 0},
+		{"negative to", 1_000_000_000, -1, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Scale(tt.raw, tt.to, tt.from); got != tt.want {
+				t.Errorf("Scale(%d, %v, %v) = %d, want %d", tt.raw, tt.to, tt.from, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Package raylog decodes the "ray_log" lines Raydium V4 writes to a
+// transaction's log messages
+package raylog
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Raydium V4 emits a base64 "ray_log" line for every swap with the exact
+// amounts the program moved
+const (
+	PREFIX            = "Program log: ray_log: "
+	SWAP_BASE_IN      = 3
+	SWAP_BASE_OUT     = 4
+	SWAP_SIZE         = 57 // log type + 7 u64 fields
+	DIRECTION_PC2COIN = 1  // SwapDirection::PC2Coin, quote in
+	DIRECTION_COIN2PC = 2  // SwapDirection::Coin2PC, base in
+)
+
+// Swap is a decoded SwapBaseIn or SwapBaseOut log
+type Swap struct {
+	LogType    uint8
+	Direction  uint64 // 1 = quote (pc) in, 2 = base (coin) in
+	UserSource uint64 // user's source balance before the swap
+	PoolCoin   uint64 // base reserve before the swap
+	PoolPc     uint64 // quote reserve before the swap
+	AmountIn   uint64 // input actually taken, including the fee
+	AmountOut  uint64 // output actually sent
+	Limit      uint64 // minimum_out (base in) or max_in (base out)
+}
+
+// BaseIn reports whether the swap spent the pool's base token
+func (l *Swap) BaseIn() bool {
+	return l.Direction == DIRECTION_COIN2PC
+}
+
+// Parse decodes one ray_log payload, returning nil for non-swap logs
+func Parse(payload string) (*Swap, error) {
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ray_log encoding: %w", err)
+	}
+	if len(data) == 0 || (data[0] != SWAP_BASE_IN && data[0] != SWAP_BASE_OUT) {
+		return nil, nil
+	}
+	if len(data) < SWAP_SIZE {
+		return nil, fmt.Errorf("ray_log swap too short: %d bytes", len(data))
+	}
+
+	field := func(i int) uint64 {
+		return binary.LittleEndian.Uint64(data[1+i*8:])
+	}
+
+	log := &Swap{
+		LogType:    data[0],
+		Direction:  field(2),
+		UserSource: field(3),
+		PoolCoin:   field(4),
+		PoolPc:     field(5),
+	}
+	if data[0] == SWAP_BASE_IN {
+		// amount_in, minimum_out, direction, user_source, pool_coin, pool_pc, out_amount
+		log.AmountIn, log.Limit, log.AmountOut = field(0), field(1), field(6)
+	} else {
+		// max_in, amount_out, direction, user_source, pool_coin, pool_pc, deduct_in
+		log.Limit, log.AmountOut, log.AmountIn = field(0), field(1), field(6)
+	}
+	return log, nil
+}
+
+// FindSwap returns the first swap ray_log in a transaction's log messages
+func FindSwap(logs []string) (*Swap, error) {
+	for _, line := range logs {
+		payload, ok := strings.CutPrefix(line, PREFIX)
+		if !ok {
+			continue
+		}
+		log, err := Parse(strings.TrimSpace(payload))
+		if err != nil {
+			return nil, err
+		}
+		if log != nil {
+			return log, nil
+		}
+	}
+	return nil, fmt.Errorf("no Raydium swap log in transaction")
+}
+
+// FindSwaps returns every swap ray_log in a transaction's log messages,
+// one per Raydium swap including those routed through other programs
+func FindSwaps(logs []string) ([]*Swap, error) {
+	var swaps []*Swap
+	for _, line := range logs {
+		payload, ok := strings.CutPrefix(line, PREFIX)
+		if !ok {
+			continue
+		}
+		log, err := Parse(strings.TrimSpace(payload))
+		if err != nil {
+			return nil, err
+		}
+		if log != nil {
+			swaps = append(swaps, log)
+		}
+	}
+	return swaps, nil
+}
@@ -0,0 +1,70 @@
+package raylog
+
+import "testing"
+
+// Payloads of the ray_log line on the SOL/USDC pool, SOL the base (coin) and
+// USDC the quote (pc). Directions follow the program's SwapDirection enum:
+// PC2Coin = 1, Coin2PC = 2.
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    *Swap
+		baseIn  bool
+	}{
+		{
+			name:    "base in sells SOL",
+			payload: "AwDKmjsAAAAAADtYCAAAAAACAAAAAAAAAICk7DgBAAAAAEAPhLWjAAAAoMOYpRcAANvWkwgAAAAA",
+			want: &Swap{
+				LogType: SWAP_BASE_IN, Direction: DIRECTION_COIN2PC, UserSource: 5_250_000_000,
+				PoolCoin: 180_000_000_000_000, PoolPc: 26_000_000_000_000,
+				AmountIn: 1_000_000_000, AmountOut: 143_906_523, Limit: 140_000_000,
+			},
+			baseIn: true,
+		},
+		{
+			name:    "base in buys SOL with USDC",
+			payload: "A4DR8AgAAAAAAMqaOwAAAAABAAAAAAAAAADQEhMAAAAAAEAPhLWjAAAAoMOYpRcAAM4boj0AAAAA",
+			want: &Swap{
+				LogType: SWAP_BASE_IN, Direction: DIRECTION_PC2COIN, UserSource: 320_000_000,
+				PoolCoin: 180_000_000_000_000, PoolPc: 26_000_000_000_000,
+				AmountIn: 150_000_000, AmountOut: 1_034_034_126, Limit: 1_000_000_000,
+			},
+			baseIn: false,
+		},
+		{
+			name:    "base out",
+			payload: "BACUNXcAAAAAAOH1BQAAAAACAAAAAAAAAICk7DgBAAAAAEAPhLWjAAAAoMOYpRcAAP+YXSkAAAAA",
+			want: &Swap{
+				LogType: SWAP_BASE_OUT, Direction: DIRECTION_COIN2PC, UserSource: 5_250_000_000,
+				PoolCoin: 180_000_000_000_000, PoolPc: 26_000_000_000_000,
+				AmountIn: 693_999_871, AmountOut: 100_000_000, Limit: 2_000_000_000,
+			},
+			baseIn: true,
+		},
+		{
+			name:    "not a swap",
+			payload: "AAEAAAAAAAAAAgAAAAAAAAADAAAAAAAAAA==",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.payload)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("got %+v, want nil", got)
+				}
+				return
+			}
+			if *got != *tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			if got.BaseIn() != tt.baseIn {
+				t.Errorf("BaseIn() = %v, want %v", got.BaseIn(), tt.baseIn)
+			}
+		})
+	}
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	// JOBS_FILE in the state directory holds the daemon's orders and schedules
+	JOBS_FILE = "jobs.json"
+	// DAEMON_LOCK_FILE is held by the daemon running the jobs, so a second
+	// instance cannot trade them twice
+	DAEMON_LOCK_FILE = "daemon.lock"
+
+	JOB_TICK             = 5 * time.Second
+	JOB_MAX_FAILURES     = 3
+	DEFAULT_JOB_SLIPPAGE = 1.0 // percent
+)
+
+// Job statuses
+const (
+	JOB_ACTIVE      = "active"
+	JOB_DONE        = "done"
+	JOB_FAILED      = "failed"
+	JOB_CANCELLED   = "cancelled"
+	JOB_INTERRUPTED = "interrupted" // the daemon stopped while sending an order
+)
+
+// Job is a strategy the daemon runs against a pool. Its progress is saved
+// before each order is sent, so a restarted daemon resumes the schedule
+// instead of starting it over, and never repeats an order.
+type Job struct {
+	ID       int       `json:"id"`
+	Pool     string    `json:"pool"`
+	Strategy string    `json:"strategy"`
+	Side     string    `json:"side"`
+	Amount   float64   `json:"amount"`
+	Every    string    `json:"every,omitempty"`    // dca
+	Orders   int       `json:"orders,omitempty"`   // dca
+	Duration string    `json:"duration,omitempty"` // twap
+	Slices   int       `json:"slices,omitempty"`   // twap
+	Limit    float64   `json:"limit,omitempty"`    // limit
+	Stop     float64   `json:"stop,omitempty"`     // stop
+	Slippage float64   `json:"slippage"`
+	Bracket  int       `json:"bracket,omitempty"` // exits of one bracket order cancel each other
+	Created  time.Time `json:"created"`
+
+	Status    string    `json:"status"`
+	Placed    int       `json:"placed,omitempty"`     // dca orders or twap slices sent
+	NextAt    time.Time `json:"next_at,omitempty"`    // next dca order
+	StartedAt time.Time `json:"started_at,omitempty"` // first twap tick
+	InFlight  float64   `json:"in_flight,omitempty"`  // amount of the order being sent
+	Failures  int       `json:"failures,omitempty"`   // consecutive failed orders
+	LastTx    string    `json:"last_tx,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// newJob records the strategy flags of a job
+func newJob(pool string, f strategyFlags, slippage float64) *Job {
+	job := &Job{Pool: pool, Strategy: f.name, Side: f.side, Amount: f.amount, Slippage: slippage, Status: JOB_ACTIVE, Created: time.Now().UTC()}
+	switch f.name {
+	case STRATEGY_DCA:
+		job.Every, job.Orders = f.every.String(), f.orders
+	case STRATEGY_TWAP:
+		job.Duration, job.Slices = f.duration.String(), f.slices
+	case STRATEGY_LIMIT:
+		job.Limit = f.limit
+	case STRATEGY_STOP:
+		job.Stop = f.stop
+	}
+	return job
+}
+
+// strategy rebuilds the job's strategy with its saved progress
+func (j *Job) strategy() (Strategy, error) {
+	f := strategyFlags{name: j.Strategy, side: j.Side, amount: j.Amount, orders: j.Orders, slices: j.Slices, limit: j.Limit, stop: j.Stop}
+	var err error
+	if j.Every != "" {
+		if f.every, err = time.ParseDuration(j.Every); err != nil {
+			return nil, fmt.Errorf("invalid every: %w", err)
+		}
+	}
+	if j.Duration != "" {
+		if f.duration, err = time.ParseDuration(j.Duration); err != nil {
+			return nil, fmt.Errorf("invalid duration: %w", err)
+		}
+	}
+	strategy, err := f.build()
+	if err != nil {
+		return nil, err
+	}
+	switch s := strategy.(type) {
+	case *DCAStrategy:
+		s.placed, s.next = j.Placed, j.NextAt
+	case *TWAPStrategy:
+		s.traded, s.start = j.Placed, j.StartedAt
+	case *LimitStrategy:
+		s.filled = j.Status == JOB_DONE
+	case *StopStrategy:
+		s.filled = j.Status == JOB_DONE
+	}
+	return strategy, nil
+}
+
+// saveProgress copies the strategy's progress into the job
+func (j *Job) saveProgress(strategy Strategy) {
+	switch s := strategy.(type) {
+	case *DCAStrategy:
+		j.Placed, j.NextAt = s.placed, s.next
+	case *TWAPStrategy:
+		j.Placed, j.StartedAt = s.traded, s.start
+	}
+}
+
+// describe summarizes the job's parameters
+func (j *Job) describe() string {
+	switch j.Strategy {
+	case STRATEGY_DCA:
+		return fmt.Sprintf("%s %g every %s, %d/%d placed", j.Side, j.Amount, j.Every, j.Placed, j.Orders)
+	case STRATEGY_TWAP:
+		return fmt.Sprintf("%s %g over %s, %d/%d slices", j.Side, j.Amount, j.Duration, j.Placed, j.Slices)
+	case STRATEGY_LIMIT:
+		return fmt.Sprintf("%s %g at %.9f", j.Side, j.Amount, j.Limit)
+	case STRATEGY_STOP:
+		return fmt.Sprintf("sell %g at or below %.9f", j.Amount, j.Stop)
+	}
+	return j.Strategy
+}
+
+// readJobs loads the jobs
+func readJobs() ([]*Job, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, JOBS_FILE)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var jobs []*Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return jobs, nil
+}
+
+// updateJob applies update to the stored job with the given ID
+func updateJob(id int, update func(job *Job)) error {
+	var jobs []*Job
+	found := false
+	err := updateStateFile(JOBS_FILE, &jobs, func() {
+		for _, job := range jobs {
+			if job.ID == id {
+				update(job)
+				found = true
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("no job %d", id)
+	}
+	return nil
+}
+
+// addJobs stores new jobs, numbering them after the existing ones. With
+// bracket, they form a bracket order numbered after the first of them.
+func addJobs(bracket bool, added ...*Job) error {
+	var jobs []*Job
+	return updateStateFile(JOBS_FILE, &jobs, func() {
+		id := 0
+		for _, existing := range jobs {
+			id = max(id, existing.ID)
+		}
+		for _, job := range added {
+			id++
+			job.ID = id
+			if bracket {
+				job.Bracket = added[0].ID
+			}
+			jobs = append(jobs, job)
+		}
+	})
+}
+
+// acquireDaemonLock takes the single-instance lock; it is released when the
+// returned file is closed or the process exits, so a crash never leaves a
+// stale lock behind
+func acquireDaemonLock() (*os.File, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, DAEMON_LOCK_FILE)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		owner, _ := os.ReadFile(path)
+		f.Close()
+		return nil, fmt.Errorf("another daemon (pid %s) is running the jobs or watchlist", string(owner))
+	}
+	f.Truncate(0)
+	f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	return f, nil
+}
+
+// jobScheduler runs the active jobs on the engine's live pool prices
+type jobScheduler struct {
+	engine *Engine
+	guard  *SpendGuard
+}
+
+// recoverInterruptedJobs marks jobs that were mid-send when the daemon
+// stopped. Whether their order landed is unknown, so they wait for the user
+// to check the wallet and resume or cancel them.
+func recoverInterruptedJobs() error {
+	var jobs []*Job
+	return updateStateFile(JOBS_FILE, &jobs, func() {
+		for _, job := range jobs {
+			if job.Status == JOB_ACTIVE && job.InFlight > 0 {
+				job.Status = JOB_INTERRUPTED
+				fmt.Printf("Job %d was interrupted sending %g; check `tx status` and run `jobs resume -id %d` or `jobs cancel -id %d`\n", job.ID, job.InFlight, job.ID, job.ID)
+			}
+		}
+	})
+}
+
+// Run ticks the active jobs until ctx is done
+func (s *jobScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(JOB_TICK)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.tick(ctx); err != nil {
+			fmt.Printf("Warning: job scheduler: %v\n", err)
+		}
+	}
+}
+
+// tick gives every active job the current price of its pool. Jobs are read
+// each tick, so ones added with `jobs add` start without a restart.
+func (s *jobScheduler) tick(ctx context.Context) error {
+	jobs, err := readJobs()
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if job.Status != JOB_ACTIVE {
+			continue
+		}
+		if _, err := s.engine.pool(job.Pool); err != nil {
+			if err := s.engine.AddPool(ctx, job.Pool); err != nil {
+				fmt.Printf("Job %d: failed to load pool %s: %v\n", job.ID, job.Pool, err)
+				continue
+			}
+			fmt.Printf("Loaded pool %s for job %d\n", job.Pool, job.ID)
+		}
+		s.run(ctx, job)
+	}
+	return nil
+}
+
+// run advances one job. Progress is saved with the order marked in flight
+// before it is sent; an order that fails before reaching the network is
+// rolled back and retried on the next tick.
+func (s *jobScheduler) run(ctx context.Context, job *Job) {
+	pool, err := s.engine.pool(job.Pool)
+	if err != nil {
+		return
+	}
+	strategy, err := job.strategy()
+	if err != nil {
+		s.fail(job, err)
+		return
+	}
+	price, _, _ := poolPrice(pool)
+	amount := strategy.Next(MarketTick{Time: time.Now(), Price: price})
+	if amount <= 0 {
+		return
+	}
+
+	before := *job
+	cancelled := false
+	err = updateJob(job.ID, func(stored *Job) {
+		if stored.Status != JOB_ACTIVE {
+			cancelled = true
+			return
+		}
+		stored.saveProgress(strategy)
+		stored.InFlight = amount
+	})
+	if err != nil {
+		fmt.Printf("Job %d: failed to save progress, order not sent: %v\n", job.ID, err)
+		return
+	}
+	if cancelled {
+		return
+	}
+
+	fmt.Printf("Job %d (%s): %s %g at %.9f\n", job.ID, job.Strategy, job.Side, amount, price)
+	sig, err := s.send(ctx, job, amount)
+	if err != nil {
+		fmt.Printf("Job %d: order failed: %v\n", job.ID, err)
+		updateJob(job.ID, func(stored *Job) {
+			stored.Placed, stored.NextAt, stored.StartedAt = before.Placed, before.NextAt, before.StartedAt
+			stored.InFlight = 0
+			stored.Failures++
+			stored.LastError = err.Error()
+			if stored.Failures >= JOB_MAX_FAILURES && stored.Status == JOB_ACTIVE {
+				stored.Status = JOB_FAILED
+			}
+		})
+		return
+	}
+
+	fmt.Printf("Job %d: sent %s\n", job.ID, explorerTxURL(sig.String()))
+	err = updateJob(job.ID, func(stored *Job) {
+		stored.InFlight = 0
+		stored.Failures = 0
+		stored.LastTx = sig.String()
+		stored.LastError = ""
+		if strategy.Done() && stored.Status == JOB_ACTIVE {
+			stored.Status = JOB_DONE
+		}
+	})
+	if err != nil {
+		fmt.Printf("Warning: job %d: failed to record %s: %v\n", job.ID, sig, err)
+	}
+	if strategy.Done() && job.Bracket > 0 {
+		if err := cancelBracket(job); err != nil {
+			fmt.Printf("Warning: job %d: failed to cancel the other exits of bracket %d: %v\n", job.ID, job.Bracket, err)
+		}
+	}
+}
+
+// cancelBracket cancels the exits of a bracket order other than the one that filled
+func cancelBracket(filled *Job) error {
+	var jobs []*Job
+	return updateStateFile(JOBS_FILE, &jobs, func() {
+		for _, job := range jobs {
+			if job.Bracket == filled.Bracket && job.ID != filled.ID && job.Status == JOB_ACTIVE {
+				job.Status = JOB_CANCELLED
+				job.LastError = fmt.Sprintf("bracket %d exited through job %d", filled.Bracket, filled.ID)
+				fmt.Printf("Job %d cancelled: %s\n", job.ID, job.LastError)
+			}
+		}
+	})
+}
+
+// send quotes and sends one order of a job within the spend limits
+func (s *jobScheduler) send(ctx context.Context, job *Job, amount float64) (solana.Signature, error) {
+	quote, err := s.engine.Quote(job.Pool, job.Side, amount)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	trade := quote.GuardedTrade(s.engine.opts.Burn)
+	trade.Bracket = job.Bracket
+	if err := s.guard.Enforce(ctx, trade, false); err != nil {
+		return solana.Signature{}, fmt.Errorf("spend limits: %w", err)
+	}
+	defer s.guard.Release(trade)
+	sig, err := s.engine.Swap(ctx, quote, job.Slippage)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	if err := s.guard.Record(trade); err != nil {
+		fmt.Printf("Warning: Could not record trade for spend limits: %v\n", err)
+	}
+	return sig, nil
+}
+
+// fail marks a job that cannot run
+func (s *jobScheduler) fail(job *Job, err error) {
+	fmt.Printf("Job %d failed: %v\n", job.ID, err)
+	updateJob(job.ID, func(stored *Job) {
+		stored.Status = JOB_FAILED
+		stored.LastError = err.Error()
+	})
+}
+
+// runJobs dispatches the job subcommands
+func runJobs(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "add":
+			runJobsAdd(args[1:])
+			return
+		case "list":
+			runJobsList(args[1:])
+			return
+		case "cancel", "resume":
+			runJobsSetStatus(args[0], args[1:])
+			return
+		}
+	}
+	fmt.Println("Usage: go run . jobs add -pool POOL -strategy dca|twap|limit|stop -side buy|sell -amount AMOUNT [strategy flags] [-slippage PCT]")
+	fmt.Println("       go run . jobs list [-json]")
+	fmt.Println("       go run . jobs cancel|resume -id N")
+	fmt.Println("Jobs run in `daemon -jobs`.")
+	os.Exit(1)
+}
+
+// runJobsAdd queues a job for the daemon
+func runJobsAdd(args []string) {
+	fs := newCommandFlagSet("jobs add")
+	var strategyConfig strategyFlags
+	strategyConfig.register(fs)
+	poolAddress := fs.String("pool", "", "Pool the job trades")
+	slippage := fs.Float64("slippage", DEFAULT_JOB_SLIPPAGE, "Slippage tolerance in percent for each order")
+	fs.Parse(args)
+
+	if *poolAddress == "" || strategyConfig.name == "" {
+		fmt.Println("Usage: go run . jobs add -pool POOL -strategy dca|twap|limit|stop -side buy|sell -amount AMOUNT [strategy flags] [-slippage PCT]")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	if _, err := solana.PublicKeyFromBase58(*poolAddress); err != nil {
+		log.Fatalf("Invalid pool address: %v", err)
+	}
+	if _, err := strategyConfig.build(); err != nil {
+		log.Fatalf("Invalid strategy: %v", err)
+	}
+	if *slippage <= 0 || *slippage > MAX_SLIPPAGE {
+		log.Fatalf("-slippage must be between 0 and %.0f", MAX_SLIPPAGE)
+	}
+
+	job := newJob(*poolAddress, strategyConfig, *slippage)
+	if err := addJobs(false, job); err != nil {
+		log.Fatalf("Failed to save job: %v", err)
+	}
+	fmt.Printf("Job %d added: %s %s on %s\n", job.ID, job.Strategy, job.describe(), job.Pool)
+	fmt.Println("It runs while `go run . daemon -jobs` is running.")
+}
+
+// runJobsList prints the jobs
+func runJobsList(args []string) {
+	fs := newCommandFlagSet("jobs list")
+	jsonOutput := fs.Bool("json", false, "Output the jobs as JSON")
+	fs.Parse(args)
+
+	jobs, err := readJobs()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *jsonOutput {
+		data, err := json.MarshalIndent(jobs, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode jobs: %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	if len(jobs) == 0 {
+		fmt.Println("No jobs")
+		return
+	}
+	fmt.Printf("%-4s %-11s %-6s %-44s %s\n", "ID", "Status", "Kind", "Pool", "Order")
+	for _, job := range jobs {
+		fmt.Printf("%-4d %-11s %-6s %-44s %s\n", job.ID, job.Status, job.Strategy, job.Pool, job.describe())
+		if job.Bracket > 0 {
+			fmt.Printf("     bracket: %d\n", job.Bracket)
+		}
+		if job.InFlight > 0 {
+			fmt.Printf("     in flight: %g\n", job.InFlight)
+		}
+		if job.LastError != "" {
+			fmt.Printf("     last error: %s\n", job.LastError)
+		}
+	}
+}
+
+// runJobsSetStatus cancels a job, or resumes an interrupted or failed one.
+// Resuming counts an interrupted order as sent.
+func runJobsSetStatus(action string, args []string) {
+	fs := newCommandFlagSet("jobs " + action)
+	id := fs.Int("id", 0, "Job ID")
+	fs.Parse(args)
+	if *id <= 0 {
+		log.Fatalf("Usage: go run . jobs %s -id N", action)
+	}
+
+	var refused error
+	err := updateJob(*id, func(job *Job) {
+		switch {
+		case job.Status == JOB_DONE || job.Status == JOB_CANCELLED:
+			refused = fmt.Errorf("job %d is already %s", job.ID, job.Status)
+		case action == "cancel":
+			job.Status = JOB_CANCELLED
+		case job.Status == JOB_ACTIVE:
+			refused = fmt.Errorf("job %d is already active", job.ID)
+		default:
+			job.Status = JOB_ACTIVE
+			job.InFlight = 0
+			job.Failures = 0
+		}
+	})
+	if err == nil {
+		err = refused
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if action == "cancel" {
+		fmt.Printf("Job %d cancelled\n", *id)
+		return
+	}
+	fmt.Printf("Job %d resumed\n", *id)
+}
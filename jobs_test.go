@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+	"testing"
+)
+
+const jobsHelperEnvVar = "JOBS_TEST_HELPER"
+
+// TestJobsHelper adds jobs and cancels its first one when run as a helper
+// process of TestJobsAcrossProcesses
+func TestJobsHelper(t *testing.T) {
+	name := os.Getenv(jobsHelperEnvVar)
+	if name == "" {
+		t.Skip("helper process only")
+	}
+	for i := range 10 {
+		if err := addJobs(false, &Job{Pool: fmt.Sprintf("%s-%d", name, i), Status: JOB_ACTIVE}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jobs, err := readJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range jobs {
+		if job.Pool == name+"-0" {
+			if err := updateJob(job.ID, func(job *Job) { job.Status = JOB_CANCELLED }); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// Separate processes, like `jobs add`, `jobs cancel` and the daemon, must
+// not lose each other's changes to jobs.json
+func TestJobsAcrossProcesses(t *testing.T) {
+	const processes = 4
+	t.Setenv(STATE_DIR_ENV_VAR, t.TempDir())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, processes)
+	for i := range processes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestJobsHelper$")
+			cmd.Env = append(os.Environ(), fmt.Sprintf("%s=p%d", jobsHelperEnvVar, i))
+			if out, err := cmd.CombinedOutput(); err != nil {
+				errs <- fmt.Errorf("helper failed: %v\n%s", err, out)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	jobs, err := readJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != processes*10 {
+		t.Fatalf("%d jobs stored, want %d", len(jobs), processes*10)
+	}
+	var ids []int
+	cancelled := 0
+	for _, job := range jobs {
+		ids = append(ids, job.ID)
+		if job.Status == JOB_CANCELLED {
+			cancelled++
+		}
+	}
+	slices.Sort(ids)
+	if ids = slices.Compact(ids); len(ids) != len(jobs) {
+		t.Errorf("job IDs were reused")
+	}
+	if cancelled != processes {
+		t.Errorf("%d jobs cancelled, want %d", cancelled, processes)
+	}
+}
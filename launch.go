@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+const (
+	METAPLEX_CREATE_METADATA_V3 = uint8(33)
+	METAPLEX_METADATA_SIZE      = 679 // largest metadata account, used for the rent estimate
+	METAPLEX_MAX_NAME_LEN       = 32
+	METAPLEX_MAX_SYMBOL_LEN     = 10
+	METAPLEX_MAX_URI_LEN        = 200
+	DEFAULT_LAUNCH_DECIMALS     = 6
+)
+
+// LaunchParams describes a new token and the pool seeded with it
+type LaunchParams struct {
+	Name         string
+	Symbol       string
+	URI          string
+	Decimals     uint8
+	Supply       float64 // tokens minted to the wallet
+	Liquidity    float64 // tokens deposited in the pool
+	SOL          float64 // SOL deposited in the pool
+	RevokeMint   bool
+	RevokeFreeze bool
+	OpenTime     uint64
+}
+
+// Validate checks the metadata fits Metaplex's limits and the amounts add up
+func (p LaunchParams) Validate() error {
+	switch {
+	case p.Name == "" || p.Symbol == "":
+		return fmt.Errorf("name and symbol are required")
+	case len(p.Name) > METAPLEX_MAX_NAME_LEN:
+		return fmt.Errorf("name is longer than %d bytes", METAPLEX_MAX_NAME_LEN)
+	case len(p.Symbol) > METAPLEX_MAX_SYMBOL_LEN:
+		return fmt.Errorf("symbol is longer than %d bytes", METAPLEX_MAX_SYMBOL_LEN)
+	case len(p.URI) > METAPLEX_MAX_URI_LEN:
+		return fmt.Errorf("URI is longer than %d bytes", METAPLEX_MAX_URI_LEN)
+	case p.Decimals > 9:
+		return fmt.Errorf("decimals must be between 0 and 9")
+	case p.Supply <= 0 || p.SOL <= 0:
+		return fmt.Errorf("supply and SOL liquidity must be positive")
+	case p.Liquidity <= 0 || p.Liquidity > p.Supply:
+		return fmt.Errorf("pool liquidity must be positive and at most the supply")
+	}
+	return nil
+}
+
+// createMetadataInstruction creates a Metaplex CreateMetadataAccountV3
+// instruction with the wallet as mint authority, payer and update authority
+func createMetadataInstruction(metadata, mint, authority solana.PublicKey, name, symbol, uri string) solana.Instruction {
+	borshString := func(data []byte, value string) []byte {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(value)))
+		return append(data, value...)
+	}
+	data := []byte{METAPLEX_CREATE_METADATA_V3}
+	data = borshString(data, name)
+	data = borshString(data, symbol)
+	data = borshString(data, uri)
+	data = binary.LittleEndian.AppendUint16(data, 0) // seller fee basis points
+	data = append(data,
+		0, // no creators
+		0, // no collection
+		0, // no uses
+		1, // mutable
+		0, // no collection details
+	)
+
+	metas := []*solana.AccountMeta{
+		{PublicKey: metadata, IsWritable: true},
+		{PublicKey: mint},
+		{PublicKey: authority, IsSigner: true},                   // mint authority
+		{PublicKey: authority, IsSigner: true, IsWritable: true}, // payer
+		{PublicKey: authority, IsSigner: true},                   // update authority
+		{PublicKey: solana.SystemProgramID},
+		{PublicKey: solana.SysVarRentPubkey},
+	}
+	return solana.NewInstruction(METAPLEX_METADATA_PROGRAM, metas, data)
+}
+
+// planTokenCreation plans the transaction creating the mint with its
+// metadata and minting the supply to the wallet's ATA. Authorities are
+// revoked in the same transaction, after the last instruction needing them.
+func planTokenCreation(ctx context.Context, client ChainClient, owner solana.PublicKey, params LaunchParams) (solana.PublicKey, creationStep, error) {
+	mintKey, err := solana.NewRandomPrivateKey()
+	if err != nil {
+		return solana.PublicKey{}, creationStep{}, fmt.Errorf("failed to generate mint key: %w", err)
+	}
+	mint := mintKey.PublicKey()
+	ata, _, err := solana.FindAssociatedTokenAddress(owner, mint)
+	if err != nil {
+		return solana.PublicKey{}, creationStep{}, fmt.Errorf("failed to find ATA: %w", err)
+	}
+	metadata, err := deriveMetaplexMetadata(mint)
+	if err != nil {
+		return solana.PublicKey{}, creationStep{}, fmt.Errorf("failed to derive metadata address: %w", err)
+	}
+	supply, err := atoms.FromAmount(params.Supply, int(params.Decimals))
+	if err != nil {
+		return solana.PublicKey{}, creationStep{}, fmt.Errorf("invalid supply: %w", err)
+	}
+
+	step := creationStep{Label: "Create token", Signers: []solana.PrivateKey{mintKey}}
+	var mintRent uint64
+	for _, size := range []uint64{MINT_ACCOUNT_SIZE, TOKEN_ACCOUNT_SIZE, METAPLEX_METADATA_SIZE} {
+		lamports, err := client.GetMinimumBalanceForRentExemption(ctx, size, rpc.CommitmentConfirmed)
+		if err != nil {
+			return solana.PublicKey{}, creationStep{}, fmt.Errorf("failed to get rent for %d bytes: %w", size, err)
+		}
+		if size == MINT_ACCOUNT_SIZE {
+			mintRent = lamports
+		}
+		step.Rent += lamports
+	}
+
+	// Without a freeze authority no holder's account can ever be frozen
+	initialize := token.NewInitializeMint2InstructionBuilder().
+		SetDecimals(params.Decimals).
+		SetMintAuthority(owner).
+		SetMintAccount(mint)
+	if !params.RevokeFreeze {
+		initialize.SetFreezeAuthority(owner)
+	}
+	step.Instructions = []solana.Instruction{
+		system.NewCreateAccountInstruction(mintRent, MINT_ACCOUNT_SIZE, token.ProgramID, owner, mint).Build(),
+		initialize.Build(),
+		newCreateATAInstruction(owner, mint),
+		token.NewMintToInstruction(supply, mint, ata, owner, nil).Build(),
+		createMetadataInstruction(metadata, mint, owner, params.Name, params.Symbol, params.URI),
+	}
+	if params.RevokeMint {
+		// No new authority revokes it, fixing the supply
+		step.Instructions = append(step.Instructions, token.NewSetAuthorityInstructionBuilder().
+			SetAuthorityType(token.AuthorityMintTokens).
+			SetSubjectAccount(mint).
+			SetAuthorityAccount(owner).
+			Build())
+	}
+	return mint, step, nil
+}
+
+// runLaunch creates a token with metadata, then a Raydium pool seeded with
+// part of its supply and SOL. The plan and its cost are printed before
+// anything is sent.
+func runLaunch(args []string, opts BuildOptions) {
+	fs := newCommandFlagSet("launch")
+	params := LaunchParams{Decimals: DEFAULT_LAUNCH_DECIMALS}
+	var decimals uint
+	fs.StringVar(&params.Name, "name", "", "Token name")
+	fs.StringVar(&params.Symbol, "symbol", "", "Token symbol")
+	fs.StringVar(&params.URI, "uri", "", "Metadata JSON URI (image, description)")
+	fs.UintVar(&decimals, "decimals", DEFAULT_LAUNCH_DECIMALS, "Token decimals")
+	fs.Float64Var(&params.Supply, "supply", 0, "Tokens minted to the wallet")
+	fs.Float64Var(&params.Liquidity, "liquidity", 0, "Tokens deposited in the pool (default: the whole supply)")
+	fs.Float64Var(&params.SOL, "sol", 0, "SOL deposited in the pool")
+	fs.BoolVar(&params.RevokeMint, "revoke-mint", false, "Revoke the mint authority once the supply is minted")
+	fs.BoolVar(&params.RevokeFreeze, "revoke-freeze", false, "Create the mint without a freeze authority")
+	openIn := fs.Duration("open-in", 0, "Delay before swaps open (default: immediately)")
+	dryRun := fs.Bool("dry-run", false, "Print the plan and cost estimate without sending anything")
+	fs.Parse(args)
+
+	if params.Liquidity == 0 {
+		params.Liquidity = params.Supply
+	}
+	params.Decimals = uint8(min(decimals, 255))
+	if err := params.Validate(); err != nil {
+		fmt.Printf("Invalid launch: %v\n", err)
+		fmt.Println("Usage: go run . launch -name NAME -symbol SYM -uri URI -supply N -sol N [-liquidity N] [-revoke-mint] [-revoke-freeze] [-dry-run]")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	if *openIn > 0 {
+		params.OpenTime = uint64(time.Now().Add(*openIn).Unix())
+	}
+
+	ctx := interruptContext()
+	client := newChainClient()
+	wallet, err := loadWallet()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mint, tokenStep, err := planTokenCreation(ctx, client, wallet.PublicKey(), params)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pool, poolSteps, err := planPoolCreation(ctx, client, wallet.PublicKey(), PoolCreateParams{
+		BaseMint:      mint,
+		QuoteMint:     WSOL_MINT,
+		BaseDecimals:  params.Decimals,
+		QuoteDecimals: SOL_DECIMALS,
+		BaseAmount:    params.Liquidity,
+		QuoteAmount:   params.SOL,
+		LotSize:       DEFAULT_MARKET_LOT_SIZE,
+		TickSize:      DEFAULT_MARKET_TICK_SIZE,
+		OpenTime:      params.OpenTime,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	steps := append([]creationStep{tokenStep}, poolSteps...)
+
+	fmt.Printf("Token: %s (%s), %g minted, %d decimals\n", params.Name, params.Symbol, params.Supply, params.Decimals)
+	fmt.Printf("Mint: %s\nPool: %s, %g %s + %g SOL\n", mint, pool, params.Liquidity, params.Symbol, params.SOL)
+	fmt.Printf("Mint authority: %s, freeze authority: %s\n", revokedLabel(params.RevokeMint), revokedLabel(params.RevokeFreeze))
+	total := printCreationPlan(steps)
+
+	balance, err := client.GetBalance(ctx, wallet.PublicKey(), rpc.CommitmentConfirmed)
+	if err != nil {
+		log.Fatalf("Failed to get wallet balance: %v", err)
+	}
+	if balance.Value < total {
+		log.Fatalf("Wallet holds %s SOL, less than the estimated %s SOL", atoms.Format(balance.Value, SOL_DECIMALS), atoms.Format(total, SOL_DECIMALS))
+	}
+	if *dryRun {
+		return
+	}
+	if !confirmPrompt("Launch?") {
+		fmt.Println("Launch cancelled")
+		return
+	}
+
+	if err := executeCreationSteps(ctx, client, wallet, steps, opts); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("\n✅ Launched %s\n", params.Symbol)
+	fmt.Printf("Mint: %s\n", explorerAccountURL(mint.String()))
+	fmt.Printf("Pool: %s\n", explorerAccountURL(pool.String()))
+}
+
+func revokedLabel(revoked bool) string {
+	if revoked {
+		return "revoked"
+	}
+	return "kept by the wallet"
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+// Raydium LaunchLab program accounts
+var LAUNCHLAB_PROGRAM = solana.MustPublicKeyFromBase58("LanMV9sAd7wArD4vJFi2qDdfnVhFxYSUg6eADduJ3uj")
+
+// LaunchLab constants
+const (
+	LAUNCHLAB_POOL_SEED            = "pool"
+	LAUNCHLAB_AUTHORITY_SEED       = "vault_auth_seed"
+	LAUNCHLAB_EVENT_AUTHORITY_SEED = "__event_authority"
+	LAUNCHLAB_FEE_DENOMINATOR      = 1_000_000
+	LAUNCHLAB_POOL_MIN_SIZE        = 365
+	LAUNCHLAB_CONFIG_MIN_SIZE      = 35
+	LAUNCHLAB_PLATFORM_MIN_SIZE    = 112
+	LAUNCHLAB_STATUS_TRADING       = 0 // still on the bonding curve
+	LAUNCHLAB_CURVE_CONSTANT       = 0 // constant product curve
+	LAUNCHLAB_MIGRATE_AMM          = 0 // migrates to an AMM V4 pool; 1 is CPMM
+)
+
+// Anchor discriminators of the LaunchLab pool account and swap instructions
+var (
+	LAUNCHLAB_POOL_DISCRIMINATOR = []byte{247, 237, 227, 245, 215, 195, 222, 70}
+	LAUNCHLAB_BUY_DISCRIMINATOR  = []byte{250, 234, 13, 123, 213, 156, 19, 236}
+	LAUNCHLAB_SELL_DISCRIMINATOR = []byte{149, 39, 222, 155, 211, 124, 152, 26}
+)
+
+// LaunchLabPool is a Raydium LaunchLab bonding curve pool. Mint A is the
+// launched token and mint B the SOL it is bought with. Prices follow a
+// constant product of the virtual reserves adjusted by the real ones.
+type LaunchLabPool struct {
+	Address     solana.PublicKey
+	Status      uint8
+	DecimalsA   uint8
+	MigrateType uint8
+	TotalSellA  uint64 // tokens the curve sells before migrating
+	VirtualA    uint64
+	VirtualB    uint64
+	RealA       uint64 // tokens sold
+	RealB       uint64 // SOL raised
+	FundraiseB  uint64 // SOL that completes the curve
+	Config      solana.PublicKey
+	Platform    solana.PublicKey
+	MintA       solana.PublicKey
+	MintB       solana.PublicKey
+	VaultA      solana.PublicKey
+	VaultB      solana.PublicKey
+	CurveType   uint8
+	FeeRate     uint64 // trade plus platform fee, per LAUNCHLAB_FEE_DENOMINATOR
+}
+
+// Trading reports whether the pool still trades on its curve
+func (p *LaunchLabPool) Trading() bool {
+	return p.Status == LAUNCHLAB_STATUS_TRADING
+}
+
+// deriveLaunchLabPool returns the pool PDA of a token launched against SOL
+func deriveLaunchLabPool(mint solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress(
+		[][]byte{[]byte(LAUNCHLAB_POOL_SEED), mint.Bytes(), WSOL_MINT.Bytes()},
+		LAUNCHLAB_PROGRAM,
+	)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to derive LaunchLab pool: %w", err)
+	}
+	return address, nil
+}
+
+// parseLaunchLabPool decodes a LaunchLab pool account
+func parseLaunchLabPool(address solana.PublicKey, data []byte) (*LaunchLabPool, error) {
+	if len(data) < LAUNCHLAB_POOL_MIN_SIZE {
+		return nil, fmt.Errorf("invalid LaunchLab pool data size: %d", len(data))
+	}
+	if !bytes.Equal(data[:8], LAUNCHLAB_POOL_DISCRIMINATOR) {
+		return nil, fmt.Errorf("account %s is not a LaunchLab pool", address)
+	}
+	return &LaunchLabPool{
+		Address:     address,
+		Status:      data[17],
+		DecimalsA:   data[18],
+		MigrateType: data[20],
+		TotalSellA:  binary.LittleEndian.Uint64(data[29:37]),
+		VirtualA:    binary.LittleEndian.Uint64(data[37:45]),
+		VirtualB:    binary.LittleEndian.Uint64(data[45:53]),
+		RealA:       binary.LittleEndian.Uint64(data[53:61]),
+		RealB:       binary.LittleEndian.Uint64(data[61:69]),
+		FundraiseB:  binary.LittleEndian.Uint64(data[69:77]),
+		Config:      solana.PublicKeyFromBytes(data[141:173]),
+		Platform:    solana.PublicKeyFromBytes(data[173:205]),
+		MintA:       solana.PublicKeyFromBytes(data[205:237]),
+		MintB:       solana.PublicKeyFromBytes(data[237:269]),
+		VaultA:      solana.PublicKeyFromBytes(data[269:301]),
+		VaultB:      solana.PublicKeyFromBytes(data[301:333]),
+	}, nil
+}
+
+// fetchLaunchLabPool loads the LaunchLab pool of a mint with its curve type
+// and fee rates, returning nil if the mint was never launched on LaunchLab
+func fetchLaunchLabPool(ctx context.Context, client ChainClient, mint solana.PublicKey) (*LaunchLabPool, error) {
+	address, err := deriveLaunchLabPool(mint)
+	if err != nil {
+		return nil, err
+	}
+	accountInfo, err := client.GetAccountInfo(ctx, address)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get LaunchLab pool account: %w", err)
+	}
+	if accountInfo == nil || accountInfo.Value == nil || !accountInfo.Value.Owner.Equals(LAUNCHLAB_PROGRAM) {
+		return nil, nil
+	}
+	pool, err := parseLaunchLabPool(address, accountInfo.Value.Data.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+	if !pool.MintB.Equals(WSOL_MINT) {
+		return nil, nil
+	}
+
+	accounts, err := client.GetMultipleAccounts(ctx, pool.Config, pool.Platform, pool.MintA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get LaunchLab configs: %w", err)
+	}
+	config, platform, mintAccount := accounts.Value[0], accounts.Value[1], accounts.Value[2]
+	if config == nil || len(config.Data.GetBinary()) < LAUNCHLAB_CONFIG_MIN_SIZE {
+		return nil, fmt.Errorf("LaunchLab config %s not found", pool.Config)
+	}
+	if platform == nil || len(platform.Data.GetBinary()) < LAUNCHLAB_PLATFORM_MIN_SIZE {
+		return nil, fmt.Errorf("LaunchLab platform config %s not found", pool.Platform)
+	}
+	if mintAccount == nil || !mintAccount.Owner.Equals(token.ProgramID) {
+		return nil, fmt.Errorf("LaunchLab token %s is not an SPL Token mint; Token-2022 launches are not supported", pool.MintA)
+	}
+	configData := config.Data.GetBinary()
+	pool.CurveType = configData[16]
+	pool.FeeRate = binary.LittleEndian.Uint64(configData[27:35]) + binary.LittleEndian.Uint64(platform.Data.GetBinary()[104:112])
+	return pool, nil
+}
+
+// launchLabReserves returns the effective token and SOL reserves of the curve
+func launchLabReserves(pool *LaunchLabPool) (tokens uint64, sol uint64) {
+	return pool.VirtualA - min(pool.RealA, pool.VirtualA), pool.VirtualB + pool.RealB
+}
+
+// quoteLaunchLabBuy returns the tokens received for solIn lamports; the fee
+// is taken from the SOL before it enters the curve
+func quoteLaunchLabBuy(pool *LaunchLabPool, solIn uint64) uint64 {
+	solToCurve := solIn - atoms.MulDiv(solIn, pool.FeeRate, LAUNCHLAB_FEE_DENOMINATOR, true)
+	tokenReserve, solReserve := launchLabReserves(pool)
+	tokensOut := atoms.MulDiv(tokenReserve, solToCurve, solReserve+solToCurve, false)
+	return min(tokensOut, pool.TotalSellA-min(pool.RealA, pool.TotalSellA))
+}
+
+// quoteLaunchLabSell returns the lamports received for tokensIn, fee deducted
+func quoteLaunchLabSell(pool *LaunchLabPool, tokensIn uint64) uint64 {
+	tokenReserve, solReserve := launchLabReserves(pool)
+	solOut := atoms.MulDiv(solReserve, tokensIn, tokenReserve+tokensIn, false)
+	solOut -= atoms.MulDiv(solOut, pool.FeeRate, LAUNCHLAB_FEE_DENOMINATOR, true)
+	return min(solOut, pool.RealB)
+}
+
+// createLaunchLabSwapInstruction builds buy_exact_in or sell_exact_in; amounts
+// are SOL in and tokens out for buys, tokens in and SOL out for sells
+func createLaunchLabSwapInstruction(pool *LaunchLabPool, userToken solana.PublicKey, userWSOL solana.PublicKey, user solana.PublicKey, side string, amountIn uint64, minAmountOut uint64) (solana.Instruction, error) {
+	authority, _, err := solana.FindProgramAddress([][]byte{[]byte(LAUNCHLAB_AUTHORITY_SEED)}, LAUNCHLAB_PROGRAM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive LaunchLab authority: %w", err)
+	}
+	eventAuthority, _, err := solana.FindProgramAddress([][]byte{[]byte(LAUNCHLAB_EVENT_AUTHORITY_SEED)}, LAUNCHLAB_PROGRAM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive LaunchLab event authority: %w", err)
+	}
+
+	data := make([]byte, 32)
+	if side == "buy" {
+		copy(data[0:8], LAUNCHLAB_BUY_DISCRIMINATOR)
+	} else {
+		copy(data[0:8], LAUNCHLAB_SELL_DISCRIMINATOR)
+	}
+	binary.LittleEndian.PutUint64(data[8:16], amountIn)
+	binary.LittleEndian.PutUint64(data[16:24], minAmountOut)
+	// data[24:32] is the share fee rate, zero without a referrer
+
+	accounts := []*solana.AccountMeta{
+		// 0. Payer (signer)
+		{PublicKey: user, IsSigner: true, IsWritable: true},
+		// 1. Vault authority
+		{PublicKey: authority, IsSigner: false, IsWritable: false},
+		// 2. Global config
+		{PublicKey: pool.Config, IsSigner: false, IsWritable: false},
+		// 3. Platform config
+		{PublicKey: pool.Platform, IsSigner: false, IsWritable: false},
+		// 4. Pool state
+		{PublicKey: pool.Address, IsSigner: false, IsWritable: true},
+		// 5. User token account
+		{PublicKey: userToken, IsSigner: false, IsWritable: true},
+		// 6. User WSOL account
+		{PublicKey: userWSOL, IsSigner: false, IsWritable: true},
+		// 7. Token vault
+		{PublicKey: pool.VaultA, IsSigner: false, IsWritable: true},
+		// 8. SOL vault
+		{PublicKey: pool.VaultB, IsSigner: false, IsWritable: true},
+		// 9. Token mint
+		{PublicKey: pool.MintA, IsSigner: false, IsWritable: false},
+		// 10. WSOL mint
+		{PublicKey: pool.MintB, IsSigner: false, IsWritable: false},
+		// 11. Token program of the token
+		{PublicKey: token.ProgramID, IsSigner: false, IsWritable: false},
+		// 12. Token program of WSOL
+		{PublicKey: token.ProgramID, IsSigner: false, IsWritable: false},
+		// 13. Event authority
+		{PublicKey: eventAuthority, IsSigner: false, IsWritable: false},
+		// 14. Program
+		{PublicKey: LAUNCHLAB_PROGRAM, IsSigner: false, IsWritable: false},
+	}
+	return solana.NewInstruction(LAUNCHLAB_PROGRAM, accounts, data), nil
+}
+
+// buildLaunchLabTransaction builds a curve buy or sell for owner. SOL is
+// wrapped into the owner's WSOL account for the swap and unwrapped after.
+func buildLaunchLabTransaction(
+	ctx context.Context,
+	client ChainClient,
+	owner solana.PublicKey,
+	pool *LaunchLabPool,
+	side string,
+	amountIn float64,
+	slippage float64,
+) (*solana.Transaction, error) {
+	userToken, createToken, err := getOrCreateATA(ctx, client, owner, pool.MintA)
+	if err != nil {
+		return nil, err
+	}
+	userWSOL, createWSOL, err := getOrCreateATA(ctx, client, owner, WSOL_MINT)
+	if err != nil {
+		return nil, err
+	}
+
+	var instructions []solana.Instruction
+	if createToken != nil {
+		fmt.Printf("Creating ATA for token %s\n", pool.MintA)
+		instructions = append(instructions, createToken)
+	}
+	if createWSOL != nil {
+		instructions = append(instructions, createWSOL)
+	}
+
+	var swapIx solana.Instruction
+	if side == "buy" {
+		solIn, err := atoms.FromAmount(amountIn, SOL_DECIMALS)
+		if err != nil {
+			return nil, err
+		}
+		minTokens := atoms.ApplySlippage(quoteLaunchLabBuy(pool, solIn), slippage)
+		instructions = append(instructions, wrapSOLInstructions(owner, userWSOL, solIn)...)
+		swapIx, err = createLaunchLabSwapInstruction(pool, userToken, userWSOL, owner, side, solIn, minTokens)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		tokensIn, err := atoms.FromAmount(amountIn, int(pool.DecimalsA))
+		if err != nil {
+			return nil, err
+		}
+		minSol := atoms.ApplySlippage(quoteLaunchLabSell(pool, tokensIn), slippage)
+		swapIx, err = createLaunchLabSwapInstruction(pool, userToken, userWSOL, owner, side, tokensIn, minSol)
+		if err != nil {
+			return nil, err
+		}
+	}
+	instructions = append(instructions, swapIx, closeWSOLInstruction(owner, userWSOL))
+
+	latestBlockhash, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+	tx, err := solana.NewTransaction(
+		instructions,
+		latestBlockhash.Value.Blockhash,
+		solana.TransactionPayer(owner),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// runLaunchLabSwap quotes and optionally executes a swap against a LaunchLab curve
+func runLaunchLabSwap(
+	ctx context.Context,
+	client ChainClient,
+	wallet solana.PrivateKey,
+	pool *LaunchLabPool,
+	side string,
+	amount float64,
+	execute bool,
+	dryRun bool,
+	guard *SpendGuard,
+	opts BuildOptions,
+) error {
+	if pool.CurveType != LAUNCHLAB_CURVE_CONSTANT {
+		return fmt.Errorf("LaunchLab curve type %d is not supported, only constant product curves", pool.CurveType)
+	}
+
+	var quote float64
+	if side == "buy" {
+		solIn, err := atoms.FromAmount(amount, SOL_DECIMALS)
+		if err != nil {
+			return err
+		}
+		quote = atoms.ToAmount(quoteLaunchLabBuy(pool, solIn), int(pool.DecimalsA))
+	} else {
+		tokensIn, err := atoms.FromAmount(amount, int(pool.DecimalsA))
+		if err != nil {
+			return err
+		}
+		quote = atoms.ToAmount(quoteLaunchLabSell(pool, tokensIn), SOL_DECIMALS)
+	}
+
+	fmt.Printf("\n=== QUOTE RESULT ===\n")
+	fmt.Printf("Protocol: Raydium LaunchLab bonding curve\n")
+	fmt.Printf("Pool: %s\n", pool.Address)
+	if pool.FundraiseB > 0 {
+		fmt.Printf("Curve Progress: %.2f of %.2f SOL raised\n", float64(pool.RealB)/math.Pow(10, SOL_DECIMALS), float64(pool.FundraiseB)/math.Pow(10, SOL_DECIMALS))
+	}
+	fmt.Printf("Operation: %s\n", strings.ToUpper(side))
+	fmt.Printf("Amount In: %.9f\n", amount)
+	fmt.Printf("Expected Out: %.9f\n", quote)
+	fmt.Printf("====================\n")
+
+	if !execute && !dryRun {
+		return nil
+	}
+	if quote <= 0 {
+		return fmt.Errorf("bonding curve returns nothing for this amount")
+	}
+
+	trade := newGuardedTrade(pool.MintA, side, amount, quote, opts.Burn)
+	if execute && !dryRun {
+		if err := guard.Enforce(ctx, trade, true); err != nil {
+			return err
+		}
+		defer guard.Release(trade)
+	}
+	if execute && !dryRun && !confirmQuote(pool.Address.String(), side, amount, quote, tokenSymbol(ctx, client, pool.MintA), opts) {
+		fmt.Println("\nSwap cancelled by user.")
+		return nil
+	}
+
+	slippage, err := getSlippageFromUser()
+	if err != nil {
+		return fmt.Errorf("failed to get slippage: %w", err)
+	}
+	tx, err := buildLaunchLabTransaction(ctx, client, wallet.PublicKey(), pool, side, amount, slippage)
+	if err != nil {
+		return err
+	}
+	if err := signTransaction(tx, wallet); err != nil {
+		return err
+	}
+	if dryRun {
+		return simulateTransaction(ctx, client, tx)
+	}
+
+	sig, err := sendAndConfirmTransaction(ctx, client, tx, opts)
+	if err != nil {
+		return err
+	}
+	if err := guard.Record(trade); err != nil {
+		fmt.Printf("Warning: Could not record trade for spend limits: %v\n", err)
+	}
+	fmt.Printf("\n✅ Swap executed successfully!\n")
+	fmt.Printf("Transaction: %s\n", sig)
+	fmt.Printf("Explorer: %s\n", explorerTxURL(sig.String()))
+	return nil
+}
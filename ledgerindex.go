@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+const (
+	// LEDGER_INDEX_FILE in the state directory holds how far the indexer
+	// read each wallet's history
+	LEDGER_INDEX_FILE = "ledger_index.json"
+
+	DEFAULT_LEDGER_INDEX_MAX_TX   = 1000
+	DEFAULT_LEDGER_INDEX_INTERVAL = 30 * time.Second
+	// SOL moves below this are fees and rent, not a swap's SOL leg
+	LEDGER_INDEX_SOL_DUST = 0.0001
+	// A trade this tool recorded matches an indexed swap of the same mint
+	// and side this close in time
+	LEDGER_INDEX_MATCH_WINDOW = 2 * time.Minute
+)
+
+// Ledger sides besides buy and sell, recorded by the indexer. Tokens sent
+// out or deposited as liquidity leave the position at their average cost;
+// tokens received or withdrawn have no known cost and are not tracked.
+const (
+	LEDGER_TRANSFER_IN  = "transfer_in"
+	LEDGER_TRANSFER_OUT = "transfer_out"
+	LEDGER_LP_ADD       = "lp_add"
+	LEDGER_LP_REMOVE    = "lp_remove"
+)
+
+// infrastructurePrograms run in transfers as well as swaps
+var infrastructurePrograms = map[string]bool{
+	"System":           true,
+	"Token":            true,
+	"Token-2022":       true,
+	"Associated Token": true,
+	"Compute Budget":   true,
+	"Memo":             true,
+}
+
+// LedgerIndexCursor is how far a wallet's history was indexed. New
+// transactions are read down to Newest; the backfill continues below Oldest.
+type LedgerIndexCursor struct {
+	Newest    string    `json:"newest,omitempty"`
+	Oldest    string    `json:"oldest,omitempty"`
+	Complete  bool      `json:"complete,omitempty"` // the backfill reached the wallet's first transaction
+	Indexed   int       `json:"indexed"`            // transactions read
+	Recorded  int       `json:"recorded"`           // ledger entries added
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// readLedgerIndex loads the cursors by wallet
+func readLedgerIndex() (map[string]*LedgerIndexCursor, error) {
+	cursors := make(map[string]*LedgerIndexCursor)
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, LEDGER_INDEX_FILE)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cursors, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &cursors); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return cursors, nil
+}
+
+// walletSignatures pages backwards from before (zero for the newest) down to
+// until (zero for none), returning up to limit successful transactions, newest
+// first, and whether the wallet's first transaction was reached
+func walletSignatures(ctx context.Context, client ChainClient, wallet solana.PublicKey, before, until solana.Signature, limit int) ([]*rpc.TransactionSignature, bool, error) {
+	var signatures []*rpc.TransactionSignature
+	pageSize := HISTORY_SIGNATURE_PAGE
+	opts := &rpc.GetSignaturesForAddressOpts{Limit: &pageSize, Before: before, Until: until, Commitment: rpc.CommitmentConfirmed}
+	for {
+		page, err := client.GetSignaturesForAddressWithOpts(ctx, wallet, opts)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get wallet signatures: %w", err)
+		}
+		for _, sig := range page {
+			if limit > 0 && len(signatures) == limit {
+				return signatures, false, nil
+			}
+			if sig.Err == nil && sig.BlockTime != nil {
+				signatures = append(signatures, sig)
+			}
+		}
+		if len(page) < pageSize {
+			return signatures, until.IsZero(), nil
+		}
+		opts.Before = page[len(page)-1].Signature
+	}
+}
+
+// classifyWalletTransaction turns the wallet's side of a transaction into
+// ledger entries. One token against SOL, with a program beyond the token and
+// system programs running, is a swap; one token alone is a transfer; a token
+// moving with SOL against a second token is a liquidity deposit or
+// withdrawal. Anything else, such as token-to-token swaps, has no SOL leg
+// for the ledger and reports false.
+func classifyWalletTransaction(tx *rpc.GetTransactionResult, wallet solana.PublicKey, at time.Time, signature string) ([]LedgerEntry, bool) {
+	parsed, err := tx.Transaction.GetTransaction()
+	if err != nil {
+		return nil, false
+	}
+	details := rawTransactionDetails(tx, parsed)
+
+	var sol float64
+	tokens := make(map[string]float64)
+	for _, change := range details.BalanceChanges {
+		if change.Owner != wallet.String() {
+			continue
+		}
+		if change.Mint == SOL_MINT.String() || change.Mint == WSOL_MINT.String() {
+			sol += change.Change
+			continue
+		}
+		tokens[change.Mint] += change.Change
+	}
+	// The fee is a cost of the transaction, not part of its SOL leg
+	if len(parsed.Message.AccountKeys) > 0 && parsed.Message.AccountKeys[0].Equals(wallet) {
+		sol += atoms.ToAmount(tx.Meta.Fee, SOL_DECIMALS)
+	}
+	if math.Abs(sol) < LEDGER_INDEX_SOL_DUST {
+		sol = 0
+	}
+
+	entry := func(side string, mint string, amount float64) LedgerEntry {
+		return LedgerEntry{At: at, Mint: mint, Side: side, SOL: math.Abs(sol), Tokens: math.Abs(amount), Signature: signature}
+	}
+	switch len(tokens) {
+	case 0:
+		return nil, true
+	case 1:
+		for mint, amount := range tokens {
+			switch {
+			case sol != 0 && (sol > 0) != (amount > 0) && runsTradingProgram(details.Instructions):
+				if amount > 0 {
+					return []LedgerEntry{entry("buy", mint, amount)}, true
+				}
+				return []LedgerEntry{entry("sell", mint, amount)}, true
+			case amount > 0:
+				return []LedgerEntry{{At: at, Mint: mint, Side: LEDGER_TRANSFER_IN, Tokens: amount, Signature: signature}}, true
+			default:
+				return []LedgerEntry{{At: at, Mint: mint, Side: LEDGER_TRANSFER_OUT, Tokens: -amount, Signature: signature}}, true
+			}
+		}
+	case 2:
+		// The token moves with the SOL, the LP token against both
+		if sol == 0 {
+			return nil, false
+		}
+		var token string
+		var amount float64
+		for mint, change := range tokens {
+			if (change > 0) == (sol > 0) {
+				if token != "" {
+					return nil, false
+				}
+				token, amount = mint, change
+			}
+		}
+		if token == "" {
+			return nil, false
+		}
+		if sol < 0 {
+			return []LedgerEntry{entry(LEDGER_LP_ADD, token, amount)}, true
+		}
+		return []LedgerEntry{entry(LEDGER_LP_REMOVE, token, amount)}, true
+	}
+	return nil, false
+}
+
+// runsTradingProgram reports whether any program besides the token, system
+// and other infrastructure programs ran
+func runsTradingProgram(runs []TxInstructionRun) bool {
+	for _, run := range runs {
+		if !infrastructurePrograms[run.Name] || runsTradingProgram(run.Inner) {
+			return true
+		}
+	}
+	return false
+}
+
+// LedgerIndexResult summarizes one indexing pass
+type LedgerIndexResult struct {
+	Indexed     int
+	Recorded    int
+	Known       int // already in the ledger
+	Unsupported int
+	Failed      int
+	Complete    bool
+}
+
+// indexWallet reads the wallet's transactions the ledger has not seen, up to
+// maxTx: new ones since the last pass first, oldest first so a cut-off pass
+// leaves no gap, then older ones the backfill has not reached. Each pass
+// records its entries and advances the cursor together, and entries are
+// keyed by signature, so an interrupted pass is simply repeated.
+func indexWallet(ctx context.Context, client ChainClient, wallet solana.PublicKey, maxTx int) (LedgerIndexResult, error) {
+	var result LedgerIndexResult
+	cursors, err := readLedgerIndex()
+	if err != nil {
+		return result, err
+	}
+	cursor := cursors[wallet.String()]
+	if cursor == nil {
+		cursor = &LedgerIndexCursor{}
+	}
+	next := *cursor
+
+	var batch []*rpc.TransactionSignature
+	if cursor.Newest != "" {
+		until, err := solana.SignatureFromBase58(cursor.Newest)
+		if err != nil {
+			return result, fmt.Errorf("invalid cursor signature: %w", err)
+		}
+		newer, _, err := walletSignatures(ctx, client, wallet, solana.Signature{}, until, 0)
+		if err != nil {
+			return result, err
+		}
+		if len(newer) > maxTx {
+			newer = newer[len(newer)-maxTx:]
+		}
+		if len(newer) > 0 {
+			next.Newest = newer[0].Signature.String()
+		}
+		batch = newer
+	}
+	if !cursor.Complete && len(batch) < maxTx {
+		var before solana.Signature
+		if cursor.Oldest != "" {
+			if before, err = solana.SignatureFromBase58(cursor.Oldest); err != nil {
+				return result, fmt.Errorf("invalid cursor signature: %w", err)
+			}
+		}
+		older, complete, err := walletSignatures(ctx, client, wallet, before, solana.Signature{}, maxTx-len(batch))
+		if err != nil {
+			return result, err
+		}
+		if len(older) > 0 {
+			if next.Newest == "" {
+				next.Newest = older[0].Signature.String()
+			}
+			next.Oldest = older[len(older)-1].Signature.String()
+		}
+		next.Complete = complete
+		batch = append(batch, older...)
+	}
+	result.Complete = next.Complete
+	if len(batch) > 0 {
+		fmt.Printf("Indexing %d transactions...\n", len(batch))
+	}
+
+	var mu sync.Mutex
+	var entries []LedgerEntry
+	jobs := make(chan *rpc.TransactionSignature)
+	var wg sync.WaitGroup
+	for range HISTORY_FETCH_WORKERS {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sig := range jobs {
+				tx, err := fetchTransaction(ctx, client, sig.Signature.String())
+				var classified []LedgerEntry
+				ok := false
+				if err == nil {
+					classified, ok = classifyWalletTransaction(tx, wallet, sig.BlockTime.Time().UTC(), sig.Signature.String())
+				}
+
+				mu.Lock()
+				switch {
+				case err != nil:
+					result.Failed++
+				case !ok:
+					result.Unsupported++
+				default:
+					entries = append(entries, classified...)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, sig := range batch {
+		select {
+		case jobs <- sig:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return result, fmt.Errorf("stopped indexing: %w", err)
+	}
+	// Transactions that failed to load are read again by the next pass
+	if result.Failed > 0 {
+		return result, fmt.Errorf("%d transactions failed to load; nothing was recorded", result.Failed)
+	}
+	result.Indexed = len(batch)
+
+	ledger := make(map[string][]LedgerEntry)
+	err = updateStateFile(LEDGER_FILE, &ledger, func() {
+		existing := ledger[wallet.String()]
+		known := make(map[string]bool)
+		for _, entry := range existing {
+			if entry.Signature != "" {
+				known[entry.Signature+entry.Side+entry.Mint] = true
+			}
+		}
+		for _, entry := range entries {
+			if known[entry.Signature+entry.Side+entry.Mint] || recordedByTool(existing, entry) {
+				result.Known++
+				continue
+			}
+			existing = append(existing, entry)
+			result.Recorded++
+		}
+		// Replays need the trades in order
+		sort.SliceStable(existing, func(i, j int) bool { return existing[i].At.Before(existing[j].At) })
+		ledger[wallet.String()] = existing
+	})
+	if err != nil {
+		return result, err
+	}
+
+	next.Indexed += result.Indexed
+	next.Recorded += result.Recorded
+	next.UpdatedAt = time.Now().UTC()
+	cursors = make(map[string]*LedgerIndexCursor)
+	err = updateStateFile(LEDGER_INDEX_FILE, &cursors, func() {
+		cursors[wallet.String()] = &next
+	})
+	return result, err
+}
+
+// recordedByTool reports whether a trade this tool recorded is the indexed swap
+func recordedByTool(entries []LedgerEntry, indexed LedgerEntry) bool {
+	for _, entry := range entries {
+		if indexedMatch(indexed, entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexedMatch reports whether an indexed entry and one this tool recorded,
+// which has no signature, are the same swap
+func indexedMatch(indexed LedgerEntry, recorded LedgerEntry) bool {
+	return indexed.Signature != "" && recorded.Signature == "" && indexed.Mint == recorded.Mint &&
+		indexed.Side == recorded.Side && indexed.At.Sub(recorded.At).Abs() <= LEDGER_INDEX_MATCH_WINDOW
+}
+
+// runLedger dispatches the ledger subcommands
+func runLedger(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "index":
+			runLedgerIndex(args[1:])
+			return
+		case "list":
+			runLedgerList(args[1:])
+			return
+		}
+	}
+	fmt.Println("Usage: go run . ledger index [-address WALLET] [-max-tx 1000] [-follow] [-interval 30s]")
+	fmt.Println("       go run . ledger list [-address WALLET] [-mint MINT] [-json]")
+	os.Exit(1)
+}
+
+// runLedgerIndex backfills the wallet's history into the ledger and, with
+// -follow, keeps tailing it
+func runLedgerIndex(args []string) {
+	fs := newCommandFlagSet("ledger index")
+	walletAddr := fs.String("address", "", "Wallet to index (or "+WALLET_ADDRESS_ENV_VAR+", defaults to the "+PRIVATE_KEY_ENV_VAR+" wallet)")
+	maxTx := fs.Int("max-tx", DEFAULT_LEDGER_INDEX_MAX_TX, "Maximum transactions read per pass")
+	follow := fs.Bool("follow", false, "Keep indexing new transactions until interrupted")
+	interval := fs.Duration("interval", DEFAULT_LEDGER_INDEX_INTERVAL, "Time between passes with -follow")
+	fs.Parse(args)
+	if *maxTx <= 0 || *interval <= 0 {
+		log.Fatal("-max-tx and -interval must be positive")
+	}
+	wallet, err := loadWalletAddress(*walletAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := interruptContext()
+	client := newChainClient()
+	for {
+		result, err := indexWallet(ctx, client, wallet, *maxTx)
+		if err != nil && ctx.Err() == nil {
+			if !*follow {
+				log.Fatal(err)
+			}
+			fmt.Printf("Warning: %v\n", err)
+		} else if err == nil {
+			fmt.Printf("Indexed %d transactions: %d recorded, %d already in the ledger, %d without a SOL leg\n", result.Indexed, result.Recorded, result.Known, result.Unsupported)
+			if !result.Complete {
+				fmt.Println("The backfill has not reached the wallet's first transaction yet; run again to continue")
+			}
+		}
+		if !*follow || ctx.Err() != nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// runLedgerList prints the wallet's ledger
+func runLedgerList(args []string) {
+	fs := newCommandFlagSet("ledger list")
+	walletAddr := fs.String("address", "", "Wallet to list (or "+WALLET_ADDRESS_ENV_VAR+", defaults to the "+PRIVATE_KEY_ENV_VAR+" wallet)")
+	mint := fs.String("mint", "", "Only list entries of this token")
+	asJSON := fs.Bool("json", false, "Print the entries as JSON")
+	fs.Parse(args)
+	wallet, err := loadWalletAddress(*walletAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ledger, err := readLedger()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var entries []LedgerEntry
+	for _, entry := range ledger[wallet.String()] {
+		if *mint == "" || entry.Mint == *mint {
+			entries = append(entries, entry)
+		}
+	}
+	if *asJSON {
+		data, _ := json.MarshalIndent(entries, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	if len(entries) == 0 {
+		fmt.Println("No ledger entries")
+		return
+	}
+	fmt.Printf("%-20s %-12s %-44s %20s %14s %s\n", "Time", "Side", "Mint", "Tokens", "SOL", "Source")
+	for _, entry := range entries {
+		source := "recorded"
+		if entry.Signature != "" {
+			source = "indexed"
+		}
+		fmt.Printf("%-20s %-12s %-44s %20.6f %14.9f %s\n", entry.At.Local().Format(time.DateTime), entry.Side, entry.Mint, entry.Tokens, entry.SOL, source)
+	}
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Files in the state directory holding the limits, the trade log they are
+// checked against and the trades reserved while they are sent
+const (
+	LIMITS_FILE          = "limits.json"
+	LIMITS_STATE_FILE    = "limits-state.json"
+	LIMITS_RESERVED_FILE = "limits-reserved.json"
+)
+
+// RESERVATION_TTL bounds how long a reserved trade counts against the limits,
+// so a process that dies while sending does not hold it forever
+const RESERVATION_TTL = 10 * time.Minute
+
+// SpendLimits are the safety rails applied to a wallet's trades. Zero values
+// disable a rail; an empty allowlist allows every mint not on the denylist.
+type SpendLimits struct {
+	MaxTradeSOL      float64  `json:"max_trade_sol,omitempty"`
+	MaxTradesPerHour int      `json:"max_trades_per_hour,omitempty"`
+	ConfirmAboveSOL  float64  `json:"confirm_above_sol,omitempty"`
+	AllowMints       []string `json:"allow_mints,omitempty"`
+	DenyMints        []string `json:"deny_mints,omitempty"`
+
+	// Portfolio rules over the positions in the ledger
+	MaxTokenPct     float64 `json:"max_token_pct,omitempty"`      // of the portfolio in one token
+	MaxYoungPct     float64 `json:"max_young_pct,omitempty"`      // of the portfolio in young tokens
+	YoungTokenDays  int     `json:"young_token_days,omitempty"`   // age below which a token is young
+	MaxDailyLossSOL float64 `json:"max_daily_loss_sol,omitempty"` // realized loss that stops trading until midnight UTC
+}
+
+// limitsConfig is the limits file: defaults plus per-wallet replacements
+type limitsConfig struct {
+	Default SpendLimits            `json:"default"`
+	Wallets map[string]SpendLimits `json:"wallets,omitempty"`
+}
+
+// ErrConfirmationRequired is returned for trades above the confirmation
+// threshold; interactive callers may ask the user and continue
+var ErrConfirmationRequired = errors.New("trade requires confirmation")
+
+// reservation is a trade that passed the limits and is being sent. It counts
+// against them like a recorded trade until it is recorded or released.
+type reservation struct {
+	ID string `json:"id"`
+	LedgerEntry
+}
+
+// SpendGuard checks trades of one wallet against its limits and records them
+type SpendGuard struct {
+	Override bool // skip every check, for -override
+
+	wallet       string
+	owner        solana.PublicKey
+	client       ChainClient
+	limits       SpendLimits
+	statePath    string
+	reservedPath string
+	youngMints   map[string]bool
+	reserved     []reservation // this guard's reservations not yet recorded or released
+	mu           sync.Mutex
+}
+
+// loadLimitsConfig reads the limits file, returning an empty config when it does not exist
+func loadLimitsConfig() (*limitsConfig, string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, "", err
+	}
+	path := filepath.Join(dir, LIMITS_FILE)
+
+	config := &limitsConfig{Wallets: make(map[string]SpendLimits)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, path, nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if config.Wallets == nil {
+		config.Wallets = make(map[string]SpendLimits)
+	}
+	return config, path, nil
+}
+
+// loadSpendGuard returns the guard for a wallet, using its own limits when
+// set. The client values the portfolio for its rules.
+func loadSpendGuard(client ChainClient, wallet solana.PublicKey) (*SpendGuard, error) {
+	config, path, err := loadLimitsConfig()
+	if err != nil {
+		return nil, err
+	}
+	limits, ok := config.Wallets[wallet.String()]
+	if !ok {
+		limits = config.Default
+	}
+	return &SpendGuard{
+		wallet:       wallet.String(),
+		owner:        wallet,
+		client:       client,
+		limits:       limits,
+		statePath:    filepath.Join(filepath.Dir(path), LIMITS_STATE_FILE),
+		reservedPath: filepath.Join(filepath.Dir(path), LIMITS_RESERVED_FILE),
+		youngMints:   make(map[string]bool),
+	}, nil
+}
+
+// Check validates a trade against the limits and the portfolio rules. It
+// returns ErrConfirmationRequired when the trade is within limits but above
+// the confirmation threshold. Trades reserved by other callers count as sent.
+func (g *SpendGuard) Check(ctx context.Context, trade GuardedTrade) error {
+	reserved, err := g.readReserved()
+	if err != nil {
+		return err
+	}
+	return g.check(ctx, trade, reserved)
+}
+
+// check is Check against the given reservations of the wallet
+func (g *SpendGuard) check(ctx context.Context, trade GuardedTrade, reserved []reservation) error {
+	l := g.limits
+	mint, solAmount := trade.Mint, trade.SOL
+	if slices.Contains(l.DenyMints, mint.String()) {
+		return fmt.Errorf("mint %s is on the denylist", mint)
+	}
+	if len(l.AllowMints) > 0 && !slices.Contains(l.AllowMints, mint.String()) {
+		return fmt.Errorf("mint %s is not on the allowlist", mint)
+	}
+	if l.MaxTradeSOL > 0 && solAmount > l.MaxTradeSOL {
+		return fmt.Errorf("trade of %.4f SOL exceeds the per-trade limit of %.4f SOL", solAmount, l.MaxTradeSOL)
+	}
+	if l.MaxTradesPerHour > 0 {
+		trades, err := g.recentTrades()
+		if err != nil {
+			return err
+		}
+		if n := len(trades) + len(reserved); n >= l.MaxTradesPerHour {
+			return fmt.Errorf("%d trades in the last hour (%d being sent) reaches the limit of %d", n, len(reserved), l.MaxTradesPerHour)
+		}
+	}
+	if l.riskEnabled() {
+		if err := g.checkRisk(ctx, trade, reserved); err != nil {
+			return err
+		}
+	}
+	if l.ConfirmAboveSOL > 0 && solAmount > l.ConfirmAboveSOL {
+		return fmt.Errorf("%w: %.4f SOL is above the %.4f SOL threshold", ErrConfirmationRequired, solAmount, l.ConfirmAboveSOL)
+	}
+	return nil
+}
+
+// Reserve checks a trade again while holding the limits lock and, when it
+// passes, reserves it, so concurrent trades of the wallet in this or another
+// process count it before it is sent. The trade is taken as confirmed. The
+// caller must Record the trade once sent or Release it.
+func (g *SpendGuard) Reserve(ctx context.Context, trade GuardedTrade) error {
+	if g == nil || g.Override {
+		return nil
+	}
+	unlock, err := lockFile(g.statePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	reserved := make(map[string][]reservation)
+	if err := readJSONFile(g.reservedPath, &reserved); err != nil {
+		return err
+	}
+	pending := pruneReserved(reserved[g.wallet])
+	if err := g.check(ctx, trade, pending); err != nil && !errors.Is(err, ErrConfirmationRequired) {
+		return err
+	}
+	now := time.Now().UTC()
+	r := reservation{
+		ID:          fmt.Sprintf("%d-%d", os.Getpid(), now.UnixNano()),
+		LedgerEntry: LedgerEntry{At: now, Mint: trade.Mint.String(), Side: trade.Side, SOL: trade.SOL, Tokens: trade.Tokens},
+	}
+	reserved[g.wallet] = append(pending, r)
+	if err := writeJSONFile(g.reservedPath, reserved); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	g.reserved = append(g.reserved, r)
+	g.mu.Unlock()
+	return nil
+}
+
+// Release drops the reservation of a trade that was not sent. Once the trade
+// is recorded it does nothing, so callers may defer it.
+func (g *SpendGuard) Release(trade GuardedTrade) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	held := slices.ContainsFunc(g.reserved, reservedFor(trade))
+	g.mu.Unlock()
+	if !held {
+		return
+	}
+	unlock, err := lockFile(g.statePath)
+	if err == nil {
+		defer unlock()
+		err = g.dropReservation(trade)
+	}
+	if err != nil {
+		log.Printf("Could not release a reserved trade, it expires in %s: %v", RESERVATION_TTL, err)
+	}
+}
+
+// Record logs a sent trade for the hourly limit and the ledger, replacing
+// its reservation
+func (g *SpendGuard) Record(trade GuardedTrade) error {
+	if g == nil {
+		return nil
+	}
+	unlock, err := lockFile(g.statePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state := make(map[string][]time.Time)
+	if err := readJSONFile(g.statePath, &state); err != nil {
+		return err
+	}
+	state[g.wallet] = append(pruneTrades(state[g.wallet]), time.Now().UTC())
+	if err := writeJSONFile(g.statePath, state); err != nil {
+		return err
+	}
+	if err := appendLedger(g.wallet, trade); err != nil {
+		return err
+	}
+	return g.dropReservation(trade)
+}
+
+// dropReservation removes this guard's oldest reservation of the trade's
+// mint and side; callers must hold the limits lock
+func (g *SpendGuard) dropReservation(trade GuardedTrade) error {
+	g.mu.Lock()
+	i := slices.IndexFunc(g.reserved, reservedFor(trade))
+	if i < 0 {
+		g.mu.Unlock()
+		return nil
+	}
+	id := g.reserved[i].ID
+	g.reserved = slices.Delete(g.reserved, i, i+1)
+	g.mu.Unlock()
+
+	reserved := make(map[string][]reservation)
+	if err := readJSONFile(g.reservedPath, &reserved); err != nil {
+		return err
+	}
+	reserved[g.wallet] = slices.DeleteFunc(pruneReserved(reserved[g.wallet]), func(r reservation) bool { return r.ID == id })
+	if len(reserved[g.wallet]) == 0 {
+		delete(reserved, g.wallet)
+	}
+	return writeJSONFile(g.reservedPath, reserved)
+}
+
+// reservedFor matches the reservations of a trade's mint and side
+func reservedFor(trade GuardedTrade) func(reservation) bool {
+	mint := trade.Mint.String()
+	return func(r reservation) bool { return r.Mint == mint && r.Side == trade.Side }
+}
+
+// recentTrades returns the wallet's trades in the last hour
+func (g *SpendGuard) recentTrades() ([]time.Time, error) {
+	state := make(map[string][]time.Time)
+	if err := readJSONFile(g.statePath, &state); err != nil {
+		return nil, err
+	}
+	return pruneTrades(state[g.wallet]), nil
+}
+
+// readReserved returns the wallet's unexpired reservations
+func (g *SpendGuard) readReserved() ([]reservation, error) {
+	reserved := make(map[string][]reservation)
+	if err := readJSONFile(g.reservedPath, &reserved); err != nil {
+		return nil, err
+	}
+	return pruneReserved(reserved[g.wallet]), nil
+}
+
+// pruneTrades drops trades older than an hour
+func pruneTrades(trades []time.Time) []time.Time {
+	cutoff := time.Now().Add(-time.Hour)
+	return slices.DeleteFunc(trades, func(t time.Time) bool { return t.Before(cutoff) })
+}
+
+// pruneReserved drops reservations older than RESERVATION_TTL
+func pruneReserved(reserved []reservation) []reservation {
+	cutoff := time.Now().Add(-RESERVATION_TTL)
+	return slices.DeleteFunc(reserved, func(r reservation) bool { return r.At.Before(cutoff) })
+}
+
+// Enforce applies the limits to a trade and reserves it. Trades above the
+// confirmation threshold are confirmed interactively when interactive is set
+// and refused otherwise. The caller must Record the trade once sent or
+// Release it. A nil guard or Override skips every check.
+func (g *SpendGuard) Enforce(ctx context.Context, trade GuardedTrade, interactive bool) error {
+	if g == nil || g.Override {
+		return nil
+	}
+	err := g.Check(ctx, trade)
+	if errors.Is(err, ErrConfirmationRequired) && interactive {
+		if !confirmPrompt(fmt.Sprintf(tr("⚠️  %v. Continue?"), err)) {
+			return fmt.Errorf("%s", tr("trade cancelled"))
+		}
+		err = nil
+	}
+	if err == nil {
+		// Other trades may have been reserved since the check or the prompt
+		err = g.Reserve(ctx, trade)
+	}
+	if err != nil {
+		return fmt.Errorf("%w (pass -override to trade anyway)", err)
+	}
+	return nil
+}
+
+// runLimits shows or updates the spend limits, or lists the ledger's positions
+func runLimits(args []string) {
+	if len(args) > 0 && args[0] == "positions" {
+		runLimitsPositions(args[1:])
+		return
+	}
+	if len(args) == 0 || args[0] != "set" {
+		config, path, err := loadLimitsConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
+		data, _ := json.MarshalIndent(config, "", "  ")
+		fmt.Printf("Limits (%s):\n%s\n", path, data)
+		return
+	}
+
+	fs := newCommandFlagSet("limits set")
+	walletAddr := fs.String("wallet", "", "Wallet the limits apply to (default: all wallets without their own limits)")
+	maxTrade := fs.Float64("max-trade", -1, "Maximum SOL per trade (0 = unlimited)")
+	maxPerHour := fs.Int("max-trades-per-hour", -1, "Maximum trades per rolling hour (0 = unlimited)")
+	confirmAbove := fs.Float64("confirm-above", -1, "Ask for confirmation above this many SOL (0 = never)")
+	allow := fs.String("allow", "", "Comma-separated mints allowed to trade (\"none\" clears)")
+	deny := fs.String("deny", "", "Comma-separated mints never to trade (\"none\" clears)")
+	maxTokenPct := fs.Float64("max-token-pct", -1, "Maximum percent of the portfolio a buy may leave in one token (0 = unlimited)")
+	maxYoungPct := fs.Float64("max-young-pct", -1, "Maximum percent of the portfolio a buy may leave in tokens younger than -young-days (0 = unlimited)")
+	youngDays := fs.Int("young-days", -1, "Age in days below which a token counts as young")
+	maxDailyLoss := fs.Float64("max-daily-loss", -1, "Realized SOL loss that stops trading until midnight UTC (0 = never)")
+	fs.Parse(args[1:])
+
+	// Held until the limits are written, so concurrent edits are not lost
+	unlock, err := lockStateFile(LIMITS_FILE)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer unlock()
+	config, path, err := loadLimitsConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	limits := config.Default
+	if *walletAddr != "" {
+		if _, err := solana.PublicKeyFromBase58(*walletAddr); err != nil {
+			log.Fatalf("Invalid wallet address: %v", err)
+		}
+		if existing, ok := config.Wallets[*walletAddr]; ok {
+			limits = existing
+		}
+	}
+
+	// Only flags that were given change the stored limits
+	if *maxTrade >= 0 {
+		limits.MaxTradeSOL = *maxTrade
+	}
+	if *maxPerHour >= 0 {
+		limits.MaxTradesPerHour = *maxPerHour
+	}
+	if *confirmAbove >= 0 {
+		limits.ConfirmAboveSOL = *confirmAbove
+	}
+	if *maxTokenPct >= 0 {
+		limits.MaxTokenPct = *maxTokenPct
+	}
+	if *maxYoungPct >= 0 {
+		limits.MaxYoungPct = *maxYoungPct
+	}
+	if *youngDays >= 0 {
+		limits.YoungTokenDays = *youngDays
+	}
+	if *maxDailyLoss >= 0 {
+		limits.MaxDailyLossSOL = *maxDailyLoss
+	}
+	if limits.MaxYoungPct > 0 && limits.YoungTokenDays <= 0 {
+		log.Fatal("-max-young-pct requires -young-days")
+	}
+	if *allow != "" {
+		if limits.AllowMints, err = parseMintList(*allow); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *deny != "" {
+		if limits.DenyMints, err = parseMintList(*deny); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *walletAddr != "" {
+		config.Wallets[*walletAddr] = limits
+	} else {
+		config.Default = limits
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Limits saved to %s\n", path)
+}
+
+// parseMintList validates a comma-separated mint list; "none" yields an empty list
+func parseMintList(list string) ([]string, error) {
+	if list == "none" {
+		return nil, nil
+	}
+	var mints []string
+	for _, mint := range strings.Split(list, ",") {
+		mint = strings.TrimSpace(mint)
+		if _, err := solana.PublicKeyFromBase58(mint); err != nil {
+			return nil, fmt.Errorf("invalid mint %q: %w", mint, err)
+		}
+		mints = append(mints, mint)
+	}
+	return mints, nil
+}
+
+// runLimitsPositions prints the positions the portfolio rules see
+func runLimitsPositions(args []string) {
+	fs := newCommandFlagSet("limits positions")
+	walletAddr := fs.String("wallet", "", "Wallet to list (or "+WALLET_ADDRESS_ENV_VAR+", defaults to the "+PRIVATE_KEY_ENV_VAR+" wallet)")
+	fs.StringVar(walletAddr, "address", "", "Same as -wallet")
+	fs.Parse(args)
+
+	wallet, err := loadWalletAddress(*walletAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	config, _, err := loadLimitsConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	limits, ok := config.Wallets[wallet.String()]
+	if !ok {
+		limits = config.Default
+	}
+	if err := printPositions(wallet.String(), limits); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Concurrent trades must not all pass the hourly limit between their check
+// and their record
+func TestEnforceReservesTrades(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(STATE_DIR_ENV_VAR, dir)
+	config := limitsConfig{Default: SpendLimits{MaxTradesPerHour: 2}}
+	if err := writeJSONFile(filepath.Join(dir, LIMITS_FILE), config); err != nil {
+		t.Fatal(err)
+	}
+	wallet := solana.MustPublicKeyFromBase58("9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM")
+	trade := GuardedTrade{Mint: solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qNrmqrfWwzkQfD6h2t9P1aHA8v"), Side: "buy", SOL: 0.1, Tokens: 10}
+
+	// Each guard stands in for a separate process
+	guards := make([]*SpendGuard, 8)
+	for i := range guards {
+		guard, err := loadSpendGuard(nil, wallet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		guards[i] = guard
+	}
+	passed := make([]bool, len(guards))
+	var wg sync.WaitGroup
+	for i, guard := range guards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			passed[i] = guard.Enforce(context.Background(), trade, false) == nil
+		}()
+	}
+	wg.Wait()
+
+	var holders []*SpendGuard
+	for i, ok := range passed {
+		if ok {
+			holders = append(holders, guards[i])
+		}
+	}
+	if len(holders) != 2 {
+		t.Fatalf("%d of %d concurrent trades passed a limit of 2", len(holders), len(guards))
+	}
+
+	// A released trade frees its place, a recorded one keeps it
+	holders[0].Release(trade)
+	if err := holders[1].Record(trade); err != nil {
+		t.Fatal(err)
+	}
+	reserved, err := holders[1].readReserved()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reserved) != 0 {
+		t.Errorf("%d reservations left after release and record", len(reserved))
+	}
+	if err := guards[0].Check(context.Background(), trade); err != nil {
+		t.Errorf("trade refused after a release: %v", err)
+	}
+	if err := guards[0].Enforce(context.Background(), trade, false); err != nil {
+		t.Fatalf("trade refused after a release: %v", err)
+	}
+	if err := guards[1].Enforce(context.Background(), trade, false); err == nil {
+		t.Errorf("trade passed with one recorded and one reserved against a limit of 2")
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gagliardetto/solana-go"
+	lookup "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// LOOKUP_TABLE_ENV_VAR names the address lookup table used when a swap does not fit a legacy transaction
+const LOOKUP_TABLE_ENV_VAR = "SOLANA_LOOKUP_TABLE"
+
+const (
+	MAX_TRANSACTION_SIZE      = 1232 // bytes, the packet data limit
+	LOOKUP_TABLE_EXTEND_BATCH = 20   // addresses per extend transaction
+)
+
+var ADDRESS_LOOKUP_TABLE_PROGRAM = solana.MustPublicKeyFromBase58("AddressLookupTab1e1111111111111111111111111")
+
+// lookupTableAddress is the table buildSwapTransaction falls back to; set by -lookup-table
+var lookupTableAddress = os.Getenv(LOOKUP_TABLE_ENV_VAR)
+
+// transactionSize returns the serialized size of tx once signed, whether or
+// not it has been signed yet
+func transactionSize(tx *solana.Transaction) (int, error) {
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return 0, fmt.Errorf("failed to serialize message: %w", err)
+	}
+	signatures := int(tx.Message.Header.NumRequiredSignatures)
+	// A one-byte signature count prefix, which holds up to 127 signatures
+	return 1 + signatures*solana.SignatureLength + len(message), nil
+}
+
+// sizeReport breaks down what a transaction of the given size spends its bytes on
+func sizeReport(tx *solana.Transaction, size int) string {
+	loaded := 0
+	for _, table := range tx.Message.AddressTableLookups {
+		loaded += len(table.WritableIndexes) + len(table.ReadonlyIndexes)
+	}
+	data := 0
+	for _, ix := range tx.Message.Instructions {
+		data += len(ix.Data)
+	}
+	return fmt.Sprintf("%d bytes (limit %d): %d signatures, %d static accounts, %d lookup table accounts, %d instructions with %d bytes of data",
+		size, MAX_TRANSACTION_SIZE, tx.Message.Header.NumRequiredSignatures, len(tx.Message.AccountKeys), loaded, len(tx.Message.Instructions), data)
+}
+
+// checkTransactionSize fails when tx would not fit in a packet
+func checkTransactionSize(tx *solana.Transaction) error {
+	size, err := transactionSize(tx)
+	if err != nil {
+		return err
+	}
+	if size > MAX_TRANSACTION_SIZE {
+		return fmt.Errorf("transaction too large: %s", sizeReport(tx, size))
+	}
+	return nil
+}
+
+// countAccountMetas returns how many account metas the instructions list and
+// how many distinct keys remain once the message merges them
+func countAccountMetas(instructions []solana.Instruction) (metas int, unique int) {
+	keys := make(map[solana.PublicKey]bool)
+	for _, ix := range instructions {
+		keys[ix.ProgramID()] = true
+		for _, meta := range ix.Accounts() {
+			keys[meta.PublicKey] = true
+			metas++
+		}
+	}
+	return metas, len(keys)
+}
+
+// fitTransaction builds a legacy transaction, or a v0 transaction loading
+// non-signer accounts from the configured lookup table when the legacy one
+// is over the size limit
+func fitTransaction(ctx context.Context, client ChainClient, instructions []solana.Instruction, blockhash solana.Hash, payer solana.PublicKey) (*solana.Transaction, error) {
+	tx, err := solana.NewTransaction(instructions, blockhash, solana.TransactionPayer(payer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	size, err := transactionSize(tx)
+	if err != nil {
+		return nil, err
+	}
+	if rpcPolicy.Verbose {
+		metas, unique := countAccountMetas(instructions)
+		fmt.Printf("Transaction size: %s (%d account metas merged into %d keys)\n", sizeReport(tx, size), metas, unique)
+	}
+	if size <= MAX_TRANSACTION_SIZE {
+		return tx, nil
+	}
+	if lookupTableAddress == "" {
+		return nil, fmt.Errorf("transaction too large: %s; create a lookup table with the lookup-table command and pass it with -lookup-table", sizeReport(tx, size))
+	}
+
+	tableKey, err := solana.PublicKeyFromBase58(lookupTableAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lookup table address: %w", err)
+	}
+	table, err := loadLookupTable(ctx, client, tableKey)
+	if err != nil {
+		return nil, err
+	}
+	tx, err = solana.NewTransaction(instructions, blockhash, solana.TransactionPayer(payer),
+		solana.TransactionAddressTables(map[solana.PublicKey]solana.PublicKeySlice{tableKey: table.Addresses}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	fitted, err := transactionSize(tx)
+	if err != nil {
+		return nil, err
+	}
+	if fitted > MAX_TRANSACTION_SIZE {
+		return nil, fmt.Errorf("transaction too large even with lookup table %s: %s", tableKey, sizeReport(tx, fitted))
+	}
+	fmt.Printf("Transaction is %d bytes as legacy, %d bytes with lookup table %s\n", size, fitted, tableKey)
+	return tx, nil
+}
+
+// loadLookupTable fetches an address lookup table and checks it is usable
+func loadLookupTable(ctx context.Context, client ChainClient, address solana.PublicKey) (*lookup.AddressLookupTableState, error) {
+	info, err := client.GetAccountInfo(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lookup table %s: %w", address, err)
+	}
+	if !info.Value.Owner.Equals(ADDRESS_LOOKUP_TABLE_PROGRAM) {
+		return nil, fmt.Errorf("%s is not an address lookup table", address)
+	}
+	table, err := lookup.DecodeAddressLookupTableState(info.Value.Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode lookup table %s: %w", address, err)
+	}
+	if !table.IsActive() {
+		return nil, fmt.Errorf("lookup table %s is deactivated", address)
+	}
+	return table, nil
+}
+
+// poolStaticAccounts lists the accounts every swap on the pool uses besides
+// the trader's own: the Raydium pool and OpenBook market accounts and the
+// programs they call. The pool's market data must be loaded.
+func poolStaticAccounts(pool *OnChainPool) ([]solana.PublicKey, error) {
+	vaultSigner, err := deriveMarketVaultSigner(pool)
+	if err != nil {
+		return nil, err
+	}
+	candidates := []solana.PublicKey{
+		solana.TokenProgramID, solana.SystemProgramID, RAYDIUM_AMM_V4, pool.MarketProgram,
+		pool.Address, pool.Authority, pool.OpenOrders, pool.TargetOrders, pool.BaseVault, pool.QuoteVault,
+		pool.Market, pool.MarketBids, pool.MarketAsks, pool.MarketEventQueue,
+		pool.MarketBaseVault, pool.MarketQuoteVault, vaultSigner,
+		pool.BaseMint, pool.QuoteMint,
+	}
+	var accounts solana.PublicKeySlice
+	for _, key := range candidates {
+		if !key.IsZero() {
+			accounts.UniqueAppend(key)
+		}
+	}
+	return accounts, nil
+}
+
+// createLookupTableInstruction creates a table owned by authority; the
+// address derives from the authority and a recent slot
+func createLookupTableInstruction(authority solana.PublicKey, recentSlot uint64) (solana.Instruction, solana.PublicKey, error) {
+	slot := binary.LittleEndian.AppendUint64(nil, recentSlot)
+	table, bump, err := solana.FindProgramAddress([][]byte{authority.Bytes(), slot}, ADDRESS_LOOKUP_TABLE_PROGRAM)
+	if err != nil {
+		return nil, solana.PublicKey{}, fmt.Errorf("failed to derive lookup table address: %w", err)
+	}
+
+	var data bytes.Buffer
+	data.Write(binary.LittleEndian.AppendUint32(nil, 0)) // CreateLookupTable
+	data.Write(slot)
+	data.WriteByte(bump)
+	return solana.NewInstruction(ADDRESS_LOOKUP_TABLE_PROGRAM, lookupTableAccounts(table, authority), data.Bytes()), table, nil
+}
+
+// extendLookupTableInstruction appends addresses to a table, with the authority paying the rent
+func extendLookupTableInstruction(table solana.PublicKey, authority solana.PublicKey, addresses []solana.PublicKey) solana.Instruction {
+	var data bytes.Buffer
+	data.Write(binary.LittleEndian.AppendUint32(nil, 2)) // ExtendLookupTable
+	data.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(addresses))))
+	for _, address := range addresses {
+		data.Write(address.Bytes())
+	}
+	return solana.NewInstruction(ADDRESS_LOOKUP_TABLE_PROGRAM, lookupTableAccounts(table, authority), data.Bytes())
+}
+
+func lookupTableAccounts(table solana.PublicKey, authority solana.PublicKey) solana.AccountMetaSlice {
+	return solana.AccountMetaSlice{
+		{PublicKey: table, IsWritable: true},
+		{PublicKey: authority, IsSigner: true},
+		{PublicKey: authority, IsSigner: true, IsWritable: true}, // payer
+		{PublicKey: solana.SystemProgramID},
+	}
+}
+
+// sendLookupTableInstruction signs and sends a single lookup table instruction
+func sendLookupTableInstruction(ctx context.Context, client ChainClient, wallet solana.PrivateKey, ix solana.Instruction, opts BuildOptions) error {
+	latestBlockhash, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+	tx, err := solana.NewTransaction([]solana.Instruction{ix}, latestBlockhash.Value.Blockhash, solana.TransactionPayer(wallet.PublicKey()))
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+	if err := signTransaction(tx, wallet); err != nil {
+		return err
+	}
+	sig, err := sendAndConfirmTransaction(ctx, client, tx, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ %s\n", explorerTxURL(sig.String()))
+	return nil
+}
+
+// runLookupTable creates, extends and shows the lookup table swaps fall back
+// to when they exceed the transaction size limit
+func runLookupTable(args []string, opts BuildOptions) {
+	usage := "Usage: go run . lookup-table create | extend -pool POOL [-table TABLE] | show [-table TABLE]"
+	if len(args) == 0 {
+		log.Fatal(usage)
+	}
+	fs := newCommandFlagSet("lookup-table " + args[0])
+	tableAddr := fs.String("table", lookupTableAddress, "Lookup table address (or "+LOOKUP_TABLE_ENV_VAR+")")
+	poolAddr := fs.String("pool", "", "Pool whose static accounts are added to the table")
+	fs.Parse(args[1:])
+
+	ctx := interruptContext()
+	client := newChainClient()
+
+	switch args[0] {
+	case "create":
+		wallet, err := loadWallet()
+		if err != nil {
+			log.Fatal(err)
+		}
+		slot, err := client.GetSlot(ctx, rpc.CommitmentFinalized)
+		if err != nil {
+			log.Fatalf("Failed to get slot: %v", err)
+		}
+		ix, table, err := createLookupTableInstruction(wallet.PublicKey(), slot)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := sendLookupTableInstruction(ctx, client, wallet, ix, opts); err != nil {
+			log.Fatalf("Failed to create lookup table: %v", err)
+		}
+		fmt.Printf("Lookup table: %s\nAdd pools with: go run . lookup-table extend -table %s -pool POOL\n", table, table)
+
+	case "extend":
+		if *tableAddr == "" || *poolAddr == "" {
+			log.Fatal("extend needs -pool and -table (or " + LOOKUP_TABLE_ENV_VAR + ")")
+		}
+		table, err := solana.PublicKeyFromBase58(*tableAddr)
+		if err != nil {
+			log.Fatalf("Invalid lookup table address: %v", err)
+		}
+		wallet, err := loadWallet()
+		if err != nil {
+			log.Fatal(err)
+		}
+		state, err := loadLookupTable(ctx, client, table)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if state.Authority == nil || !state.Authority.Equals(wallet.PublicKey()) {
+			log.Fatalf("Lookup table %s is not owned by the wallet %s", table, wallet.PublicKey())
+		}
+		pool, err := loadPool(ctx, client, *poolAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := fetchMarketData(ctx, client, pool); err != nil {
+			log.Fatalf("Failed to fetch market data: %v", err)
+		}
+		accounts, err := poolStaticAccounts(pool)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var missing []solana.PublicKey
+		for _, key := range accounts {
+			if !state.Addresses.Contains(key) {
+				missing = append(missing, key)
+			}
+		}
+		if len(state.Addresses)+len(missing) > 256 {
+			log.Fatalf("Lookup table %s is full; create another one", table)
+		}
+		if len(missing) == 0 {
+			fmt.Printf("Lookup table %s already holds every static account of pool %s\n", table, pool.Address)
+			return
+		}
+		for start := 0; start < len(missing); start += LOOKUP_TABLE_EXTEND_BATCH {
+			batch := missing[start:min(start+LOOKUP_TABLE_EXTEND_BATCH, len(missing))]
+			if err := sendLookupTableInstruction(ctx, client, wallet, extendLookupTableInstruction(table, wallet.PublicKey(), batch), opts); err != nil {
+				log.Fatalf("Failed to extend lookup table: %v", err)
+			}
+		}
+		fmt.Printf("Added %d accounts of pool %s; usable from the next slot\n", len(missing), pool.Address)
+
+	case "show":
+		if *tableAddr == "" {
+			log.Fatal("show needs -table (or " + LOOKUP_TABLE_ENV_VAR + ")")
+		}
+		table, err := solana.PublicKeyFromBase58(*tableAddr)
+		if err != nil {
+			log.Fatalf("Invalid lookup table address: %v", err)
+		}
+		state, err := loadLookupTable(ctx, client, table)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Lookup table %s (authority %v, %d addresses)\n", table, state.Authority, len(state.Addresses))
+		for i, address := range state.Addresses {
+			fmt.Printf("%3d. %s\n", i, address)
+		}
+
+	default:
+		log.Fatal(usage)
+	}
+}
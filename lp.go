@@ -0,0 +1,468 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+// Raydium V4 liquidity instructions
+const (
+	RAYDIUM_DEPOSIT_INSTRUCTION  = uint8(3)
+	RAYDIUM_WITHDRAW_INSTRUCTION = uint8(4)
+)
+
+// poolReserves returns the vault balances net of PnL owed to the protocol
+func poolReserves(pool *OnChainPool) (base uint64, quote uint64) {
+	base, quote = pool.BaseAmount, pool.QuoteAmount
+	if pool.NeedTakePnlBase < base {
+		base -= pool.NeedTakePnlBase
+	}
+	if pool.NeedTakePnlQuote < quote {
+		quote -= pool.NeedTakePnlQuote
+	}
+	return base, quote
+}
+
+// createDepositInstruction creates a Raydium V4 deposit (add liquidity) instruction
+func createDepositInstruction(
+	pool *OnChainPool,
+	userBase solana.PublicKey,
+	userQuote solana.PublicKey,
+	userLp solana.PublicKey,
+	userOwner solana.PublicKey,
+	maxBaseAmount uint64,
+	maxQuoteAmount uint64,
+	fixedSide uint64, // 0 = base amount is fixed, 1 = quote amount is fixed
+) solana.Instruction {
+	data := make([]byte, 25)
+	data[0] = RAYDIUM_DEPOSIT_INSTRUCTION
+	binary.LittleEndian.PutUint64(data[1:9], maxBaseAmount)
+	binary.LittleEndian.PutUint64(data[9:17], maxQuoteAmount)
+	binary.LittleEndian.PutUint64(data[17:25], fixedSide)
+
+	accounts := []*solana.AccountMeta{
+		// 0. Token program
+		{PublicKey: token.ProgramID, IsSigner: false, IsWritable: false},
+		// 1. AMM pool
+		{PublicKey: pool.Address, IsSigner: false, IsWritable: true},
+		// 2. AMM authority
+		{PublicKey: pool.Authority, IsSigner: false, IsWritable: false},
+		// 3. AMM open orders
+		{PublicKey: pool.OpenOrders, IsSigner: false, IsWritable: false},
+		// 4. AMM target orders
+		{PublicKey: pool.TargetOrders, IsSigner: false, IsWritable: true},
+		// 5. LP mint
+		{PublicKey: pool.LpMint, IsSigner: false, IsWritable: true},
+		// 6. Pool base vault
+		{PublicKey: pool.BaseVault, IsSigner: false, IsWritable: true},
+		// 7. Pool quote vault
+		{PublicKey: pool.QuoteVault, IsSigner: false, IsWritable: true},
+		// 8. Market
+		{PublicKey: pool.Market, IsSigner: false, IsWritable: false},
+		// 9. User base token account
+		{PublicKey: userBase, IsSigner: false, IsWritable: true},
+		// 10. User quote token account
+		{PublicKey: userQuote, IsSigner: false, IsWritable: true},
+		// 11. User LP token account
+		{PublicKey: userLp, IsSigner: false, IsWritable: true},
+		// 12. User owner (signer)
+		{PublicKey: userOwner, IsSigner: true, IsWritable: false},
+		// 13. Market event queue
+		{PublicKey: pool.MarketEventQueue, IsSigner: false, IsWritable: false},
+	}
+
+	return solana.NewInstruction(RAYDIUM_AMM_V4, accounts, data)
+}
+
+// createWithdrawInstruction creates a Raydium V4 withdraw (remove liquidity) instruction
+func createWithdrawInstruction(
+	pool *OnChainPool,
+	marketVaultSigner solana.PublicKey,
+	userLp solana.PublicKey,
+	userBase solana.PublicKey,
+	userQuote solana.PublicKey,
+	userOwner solana.PublicKey,
+	lpAmount uint64,
+) solana.Instruction {
+	data := make([]byte, 9)
+	data[0] = RAYDIUM_WITHDRAW_INSTRUCTION
+	binary.LittleEndian.PutUint64(data[1:9], lpAmount)
+
+	accounts := []*solana.AccountMeta{
+		// 0. Token program
+		{PublicKey: token.ProgramID, IsSigner: false, IsWritable: false},
+		// 1. AMM pool
+		{PublicKey: pool.Address, IsSigner: false, IsWritable: true},
+		// 2. AMM authority
+		{PublicKey: pool.Authority, IsSigner: false, IsWritable: false},
+		// 3. AMM open orders
+		{PublicKey: pool.OpenOrders, IsSigner: false, IsWritable: true},
+		// 4. AMM target orders
+		{PublicKey: pool.TargetOrders, IsSigner: false, IsWritable: true},
+		// 5. LP mint
+		{PublicKey: pool.LpMint, IsSigner: false, IsWritable: true},
+		// 6. Pool base vault
+		{PublicKey: pool.BaseVault, IsSigner: false, IsWritable: true},
+		// 7. Pool quote vault
+		{PublicKey: pool.QuoteVault, IsSigner: false, IsWritable: true},
+		// 8. Market program
+		{PublicKey: pool.MarketProgram, IsSigner: false, IsWritable: false},
+		// 9. Market
+		{PublicKey: pool.Market, IsSigner: false, IsWritable: true},
+		// 10. Market base vault
+		{PublicKey: pool.MarketBaseVault, IsSigner: false, IsWritable: true},
+		// 11. Market quote vault
+		{PublicKey: pool.MarketQuoteVault, IsSigner: false, IsWritable: true},
+		// 12. Market vault signer
+		{PublicKey: marketVaultSigner, IsSigner: false, IsWritable: false},
+		// 13. User LP token account
+		{PublicKey: userLp, IsSigner: false, IsWritable: true},
+		// 14. User base token account
+		{PublicKey: userBase, IsSigner: false, IsWritable: true},
+		// 15. User quote token account
+		{PublicKey: userQuote, IsSigner: false, IsWritable: true},
+		// 16. User owner (signer)
+		{PublicKey: userOwner, IsSigner: true, IsWritable: false},
+		// 17. Market event queue
+		{PublicKey: pool.MarketEventQueue, IsSigner: false, IsWritable: true},
+		// 18. Market bids
+		{PublicKey: pool.MarketBids, IsSigner: false, IsWritable: true},
+		// 19. Market asks
+		{PublicKey: pool.MarketAsks, IsSigner: false, IsWritable: true},
+	}
+
+	return solana.NewInstruction(RAYDIUM_AMM_V4, accounts, data)
+}
+
+// lpTokenAccounts resolves (and plans creation of) the user's base, quote and LP token accounts
+func lpTokenAccounts(
+	ctx context.Context,
+	client ChainClient,
+	owner solana.PublicKey,
+	pool *OnChainPool,
+) (base solana.PublicKey, quote solana.PublicKey, lp solana.PublicKey, instructions []solana.Instruction, err error) {
+	mints := []solana.PublicKey{pool.BaseMint, pool.QuoteMint, pool.LpMint}
+	atas := make([]solana.PublicKey, len(mints))
+
+	for i, mint := range mints {
+		ata, createIx, err := getOrCreateATA(ctx, client, owner, mint)
+		if err != nil {
+			return base, quote, lp, nil, fmt.Errorf("failed to get ATA for %s: %w", mint, err)
+		}
+		if createIx != nil {
+			fmt.Printf("Creating ATA for mint %s\n", mint)
+			instructions = append(instructions, createIx)
+		}
+		atas[i] = ata
+	}
+
+	return atas[0], atas[1], atas[2], instructions, nil
+}
+
+// sendLpTransaction signs and sends a liquidity transaction
+func sendLpTransaction(ctx context.Context, client ChainClient, wallet solana.PrivateKey, instructions []solana.Instruction, opts BuildOptions) (solana.Signature, error) {
+	latestBlockhash, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+
+	tx, err := solana.NewTransaction(
+		instructions,
+		latestBlockhash.Value.Blockhash,
+		solana.TransactionPayer(wallet.PublicKey()),
+	)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if err := signTransaction(tx, wallet); err != nil {
+		return solana.Signature{}, err
+	}
+
+	return sendAndConfirmTransaction(ctx, client, tx, opts)
+}
+
+// loadLpPool loads a pool with the market accounts needed by liquidity instructions
+func loadLpPool(ctx context.Context, client ChainClient, poolAddress string) (*OnChainPool, error) {
+	pool, err := loadPool(ctx, client, poolAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fetchMarketData(ctx, client, pool); err != nil {
+		return nil, fmt.Errorf("failed to fetch market data: %w", err)
+	}
+
+	if pool.LpAmount == 0 {
+		return nil, fmt.Errorf("pool %s has no LP supply", pool.Address)
+	}
+
+	return pool, nil
+}
+
+// runLp dispatches the lp add/remove subcommands
+func runLp(args []string, opts BuildOptions) {
+	if len(args) == 0 {
+		fmt.Println("Usage: go run . lp add|remove [flags]")
+		return
+	}
+
+	switch args[0] {
+	case "add":
+		runLpAdd(args[1:], opts)
+	case "remove":
+		runLpRemove(args[1:], opts)
+	default:
+		log.Fatalf("Unknown lp command %q (available: add, remove)", args[0])
+	}
+}
+
+// runLpAdd deposits liquidity proportionally to the pool reserves
+func runLpAdd(args []string, opts BuildOptions) {
+	fs := newCommandFlagSet("lp add")
+	var poolAddress string
+	var amount float64
+	var side string
+	var slippage float64
+	fs.StringVar(&poolAddress, "pool", "", "Pool address")
+	fs.Float64Var(&amount, "amount", 0, "Amount of the fixed side token to deposit")
+	fs.StringVar(&side, "side", "base", "Which token amount is fixed: base or quote")
+	fs.Float64Var(&slippage, "slippage", DEFAULT_SLIPPAGE, "Maximum extra percent of the other token to deposit")
+	fs.Parse(args)
+
+	if poolAddress == "" || amount <= 0 {
+		fmt.Println("Usage: go run . lp add -pool POOL -amount AMOUNT [-side base|quote] [-slippage PCT]")
+		fs.PrintDefaults()
+		return
+	}
+	if side != "base" && side != "quote" {
+		log.Fatal("Side must be 'base' or 'quote'")
+	}
+	if slippage < 0 || slippage > MAX_SLIPPAGE {
+		log.Fatalf("Slippage must be between 0 and %.0f", MAX_SLIPPAGE)
+	}
+
+	wallet, err := loadWallet()
+	if err != nil {
+		log.Fatalf("Failed to load wallet: %v", err)
+	}
+
+	ctx := interruptContext()
+	client := newChainClient()
+
+	pool, err := loadLpPool(ctx, client, poolAddress)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	baseReserve, quoteReserve := poolReserves(pool)
+	if baseReserve == 0 || quoteReserve == 0 {
+		log.Fatalf("Pool %s has empty reserves", pool.Address)
+	}
+
+	// Compute the other side proportionally to the current reserves
+	var baseRaw, quoteRaw, maxBase, maxQuote, fixedSide, expectedLp uint64
+	if side == "base" {
+		baseRaw, err = atoms.FromAmount(amount, int(pool.BaseDecimals))
+		if err != nil {
+			log.Fatalf("Invalid amount: %v", err)
+		}
+		quoteRaw = atoms.MulDiv(baseRaw, quoteReserve, baseReserve, true)
+		maxBase = baseRaw
+		maxQuote = atoms.AddSlippage(quoteRaw, slippage)
+		fixedSide = 0
+		expectedLp = atoms.MulDiv(baseRaw, pool.LpAmount, baseReserve, false)
+	} else {
+		quoteRaw, err = atoms.FromAmount(amount, int(pool.QuoteDecimals))
+		if err != nil {
+			log.Fatalf("Invalid amount: %v", err)
+		}
+		baseRaw = atoms.MulDiv(quoteRaw, baseReserve, quoteReserve, true)
+		maxBase = atoms.AddSlippage(baseRaw, slippage)
+		maxQuote = quoteRaw
+		fixedSide = 1
+		expectedLp = atoms.MulDiv(quoteRaw, pool.LpAmount, quoteReserve, false)
+	}
+
+	lpDecimals, err := getTokenDecimals(ctx, client, pool.LpMint.String())
+	if err != nil {
+		log.Fatalf("Failed to get LP decimals: %v", err)
+	}
+
+	fmt.Printf("\n=== ADD LIQUIDITY ===\n")
+	fmt.Printf("Pool: %s\n", pool.Address)
+	fmt.Printf("Base Deposit: %.9f (max %.9f) %s\n",
+		float64(baseRaw)/math.Pow(10, float64(pool.BaseDecimals)), float64(maxBase)/math.Pow(10, float64(pool.BaseDecimals)), pool.BaseMint)
+	fmt.Printf("Quote Deposit: %.9f (max %.9f) %s\n",
+		float64(quoteRaw)/math.Pow(10, float64(pool.QuoteDecimals)), float64(maxQuote)/math.Pow(10, float64(pool.QuoteDecimals)), pool.QuoteMint)
+	fmt.Printf("Expected LP: %.9f %s\n", float64(expectedLp)/math.Pow(10, float64(lpDecimals)), pool.LpMint)
+	fmt.Printf("=====================\n\n")
+
+	if !confirmPrompt("Do you want to add this liquidity?") {
+		fmt.Println("\nCancelled by user.")
+		return
+	}
+
+	owner := wallet.PublicKey()
+	userBase, userQuote, userLp, instructions, err := lpTokenAccounts(ctx, client, owner, pool)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Wrap the SOL side up to its maximum; leftovers are returned when the WSOL account is closed
+	var wsolATA solana.PublicKey
+	if pool.BaseMint.Equals(WSOL_MINT) {
+		wsolATA = userBase
+		instructions = append(instructions, wrapSOLInstructions(owner, userBase, maxBase)...)
+	} else if pool.QuoteMint.Equals(WSOL_MINT) {
+		wsolATA = userQuote
+		instructions = append(instructions, wrapSOLInstructions(owner, userQuote, maxQuote)...)
+	}
+
+	instructions = append(instructions,
+		createDepositInstruction(pool, userBase, userQuote, userLp, owner, maxBase, maxQuote, fixedSide))
+
+	if !wsolATA.IsZero() {
+		instructions = append(instructions, closeWSOLInstruction(owner, wsolATA))
+	}
+
+	sig, err := sendLpTransaction(ctx, client, wallet, instructions, opts)
+	if err != nil {
+		log.Fatalf("Add liquidity failed: %v", err)
+	}
+
+	fmt.Printf("\n✅ Liquidity added successfully!\n")
+	fmt.Printf("Transaction: %s\n", sig)
+	fmt.Printf("Explorer: %s\n", explorerTxURL(sig.String()))
+}
+
+// runLpRemove burns LP tokens for a proportional share of the reserves
+func runLpRemove(args []string, opts BuildOptions) {
+	fs := newCommandFlagSet("lp remove")
+	var poolAddress string
+	var lpAmount float64
+	var percent float64
+	fs.StringVar(&poolAddress, "pool", "", "Pool address")
+	fs.Float64Var(&lpAmount, "lp-amount", 0, "Amount of LP tokens to burn")
+	fs.Float64Var(&percent, "percent", 0, "Percent of the wallet's LP balance to burn (alternative to -lp-amount)")
+	fs.Parse(args)
+
+	if poolAddress == "" || (lpAmount <= 0 && percent <= 0) {
+		fmt.Println("Usage: go run . lp remove -pool POOL [-lp-amount AMOUNT | -percent PCT]")
+		fs.PrintDefaults()
+		return
+	}
+	if percent > 100 {
+		log.Fatal("Percent must be at most 100")
+	}
+
+	wallet, err := loadWallet()
+	if err != nil {
+		log.Fatalf("Failed to load wallet: %v", err)
+	}
+
+	ctx := interruptContext()
+	client := newChainClient()
+
+	pool, err := loadLpPool(ctx, client, poolAddress)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	owner := wallet.PublicKey()
+	userBase, userQuote, userLp, instructions, err := lpTokenAccounts(ctx, client, owner, pool)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	lpDecimals, err := getTokenDecimals(ctx, client, pool.LpMint.String())
+	if err != nil {
+		log.Fatalf("Failed to get LP decimals: %v", err)
+	}
+
+	var lpRaw uint64
+	if percent > 0 {
+		balance, err := client.GetTokenAccountBalance(ctx, userLp, rpc.CommitmentConfirmed)
+		if err != nil {
+			log.Fatalf("Failed to get LP balance: %v", err)
+		}
+		var held uint64
+		fmt.Sscan(balance.Value.Amount, &held)
+		pct, err := atoms.FromAmount(percent, 2)
+		if err != nil {
+			log.Fatalf("Invalid percent: %v", err)
+		}
+		lpRaw = atoms.MulDiv(held, pct, 10000, false)
+	} else {
+		lpRaw, err = atoms.FromAmount(lpAmount, int(lpDecimals))
+		if err != nil {
+			log.Fatalf("Invalid LP amount: %v", err)
+		}
+	}
+	if lpRaw == 0 {
+		log.Fatal("Nothing to withdraw: LP amount is zero")
+	}
+
+	baseReserve, quoteReserve := poolReserves(pool)
+	expectedBase := atoms.MulDiv(lpRaw, baseReserve, pool.LpAmount, false)
+	expectedQuote := atoms.MulDiv(lpRaw, quoteReserve, pool.LpAmount, false)
+
+	fmt.Printf("\n=== REMOVE LIQUIDITY ===\n")
+	fmt.Printf("Pool: %s\n", pool.Address)
+	fmt.Printf("LP Burned: %.9f %s\n", float64(lpRaw)/math.Pow(10, float64(lpDecimals)), pool.LpMint)
+	fmt.Printf("Expected Base: %.9f %s\n", float64(expectedBase)/math.Pow(10, float64(pool.BaseDecimals)), pool.BaseMint)
+	fmt.Printf("Expected Quote: %.9f %s\n", float64(expectedQuote)/math.Pow(10, float64(pool.QuoteDecimals)), pool.QuoteMint)
+	fmt.Printf("========================\n\n")
+
+	if !confirmPrompt("Do you want to remove this liquidity?") {
+		fmt.Println("\nCancelled by user.")
+		return
+	}
+
+	marketVaultSigner, err := deriveMarketVaultSigner(pool)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	instructions = append(instructions,
+		createWithdrawInstruction(pool, marketVaultSigner, userLp, userBase, userQuote, owner, lpRaw))
+
+	// Unwrap SOL proceeds
+	if pool.BaseMint.Equals(WSOL_MINT) {
+		instructions = append(instructions, closeWSOLInstruction(owner, userBase))
+	} else if pool.QuoteMint.Equals(WSOL_MINT) {
+		instructions = append(instructions, closeWSOLInstruction(owner, userQuote))
+	}
+
+	sig, err := sendLpTransaction(ctx, client, wallet, instructions, opts)
+	if err != nil {
+		log.Fatalf("Remove liquidity failed: %v", err)
+	}
+
+	fmt.Printf("\n✅ Liquidity removed successfully!\n")
+	fmt.Printf("Transaction: %s\n", sig)
+	fmt.Printf("Explorer: %s\n", explorerTxURL(sig.String()))
+}
+
+// wrapSOLInstructions transfers lamports into a WSOL account and syncs its balance
+func wrapSOLInstructions(owner solana.PublicKey, wsolATA solana.PublicKey, lamports uint64) []solana.Instruction {
+	return []solana.Instruction{
+		system.NewTransferInstruction(lamports, owner, wsolATA).Build(),
+		token.NewSyncNativeInstruction(wsolATA).Build(),
+	}
+}
+
+// closeWSOLInstruction closes a WSOL account, returning its lamports to the owner
+func closeWSOLInstruction(owner solana.PublicKey, wsolATA solana.PublicKey) solana.Instruction {
+	return token.NewCloseAccountInstruction(wsolATA, owner, owner, []solana.PublicKey{}).Build()
+}
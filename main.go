@@ -5,14 +5,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"math"
-	"math/big"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gagliardetto/solana-go"
@@ -20,6 +22,25 @@ import (
 	"github.com/gagliardetto/solana-go/programs/system"
 	"github.com/gagliardetto/solana-go/programs/token"
 	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/amm"
+	"awesomeProject/internal/atoms"
+	"awesomeProject/internal/raylog"
+)
+
+// OpenBook/Serum V3 market layout: a 5-byte "serum" prefix and account flags,
+// then the fields below
+const (
+	MARKET_MIN_SIZE                  = 388
+	MARKET_OWN_ADDRESS_OFFSET        = 13
+	MARKET_VAULT_SIGNER_NONCE_OFFSET = 45 // u64
+	MARKET_BASE_MINT_OFFSET          = 53
+	MARKET_QUOTE_MINT_OFFSET         = 85
+	MARKET_BASE_VAULT_OFFSET         = 117
+	MARKET_QUOTE_VAULT_OFFSET        = 165
+	MARKET_EVENT_QUEUE_OFFSET        = 253
+	MARKET_BIDS_OFFSET               = 285
+	MARKET_ASKS_OFFSET               = 317
 )
 
 // Configuration constants
@@ -33,16 +54,23 @@ const (
 	MAX_SLIPPAGE             = 100.0
 	MIN_SWAP_AMOUNT          = 0.001
 	PRIVATE_KEY_ENV_VAR      = "SOLANA_PRIVATE_KEY"
+	WALLET_ADDRESS_ENV_VAR   = "SOLANA_ADDRESS"
 	RPC_ENDPOINT             = "https://mainnet.helius-rpc.com/?api-key=YOUR_API_KEY"
 	TRANSACTION_TIMEOUT      = 30 * time.Second
+	LAMPORTS_PER_SIGNATURE   = 5000
+	SOL_USDC_POOL_ENV_VAR    = "SOL_USDC_POOL"
+	DEFAULT_REPORT_FILE      = "swap_reports.csv"
 )
 
 // Program IDs
 var (
 	RAYDIUM_AMM_V4   = solana.MustPublicKeyFromBase58("675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8")
 	OPENBOOK_PROGRAM = solana.MustPublicKeyFromBase58("srmqPvymJeFKQ4zGQed1GFppgkRHL9kaELCbyksJtPX")
-	SOL_MINT         = solana.SolMint
-	WSOL_MINT        = solana.MustPublicKeyFromBase58("So11111111111111111111111111111111111111112")
+	// Raydium's StableSwap AMM; its pools use a stable invariant, not x*y=k
+	RAYDIUM_STABLE_AMM = solana.MustPublicKeyFromBase58("5quBtoiQqxF9Jv6KYKctB59NT3gtJD2Y65kdnB1Uev3h")
+	SOL_MINT           = solana.SolMint
+	WSOL_MINT          = solana.MustPublicKeyFromBase58("So11111111111111111111111111111111111111112")
+	USDC_MINT          = solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
 )
 
 // SwapInstructionData represents the data for a Raydium V4 swap instruction
@@ -54,16 +82,45 @@ type SwapInstructionData struct {
 
 // TransactionReport contains the swap execution details
 type TransactionReport struct {
-	TxHash        string
-	Status        string
-	AmountIn      float64
-	AmountOut     float64
-	ExpectedPrice float64
-	ActualPrice   float64
-	Slippage      float64
-	ExplorerURL   string
-	InputToken    string
-	OutputToken   string
+	TxHash        string  `json:"tx_hash"`
+	Status        string  `json:"status"`
+	AmountIn      float64 `json:"amount_in"`
+	AmountOut     float64 `json:"amount_out"`
+	ExpectedPrice float64 `json:"expected_price"`
+	ActualPrice   float64 `json:"actual_price"`
+	// Quoted price impact from pool depth, the tolerance the swap was sent
+	// with, and how far the executed price fell from the quote (positive is worse)
+	PriceImpact       float64             `json:"price_impact"`
+	SlippageTolerance float64             `json:"slippage_tolerance"`
+	RealizedSlippage  float64             `json:"realized_slippage"`
+	ExplorerURL       string              `json:"explorer_url"`
+	PoolURL           string              `json:"pool_url,omitempty"`
+	TokenURL          string              `json:"token_url,omitempty"`
+	InputToken        string              `json:"input_token"`
+	OutputToken       string              `json:"output_token"`
+	Side              string              `json:"side"`
+	PoolAddress       string              `json:"pool_address"`
+	TokenMint         string              `json:"token_mint"`
+	TokenSymbol       string              `json:"token_symbol"`
+	SwapFee           float64             `json:"swap_fee"`           // pool fee in input token units, 0 if unknown
+	NetworkFee        float64             `json:"network_fee"`        // base signature fee in SOL
+	PriorityFee       float64             `json:"priority_fee"`       // prioritization fee in SOL
+	PlatformFee       float64             `json:"platform_fee"`       // integrator fee in the SOL or stablecoin leg, 0 if none
+	Burned            float64             `json:"burned,omitempty"`   // tokens of a buy's output burned by -burn-pct
+	RentSpent         float64             `json:"rent_spent"`         // SOL deposited into token accounts opened by the swap
+	RentRecovered     float64             `json:"rent_recovered"`     // SOL returned from token accounts it closed
+	NetSOLChange      float64             `json:"net_sol_change"`     // the wallet's SOL balance change, everything included
+	Delegate          string              `json:"delegate,omitempty"` // the signing delegate when it traded another wallet's accounts
+	Details           *TransactionDetails `json:"details,omitempty"`  // transfers and instructions, from tx report
+	ValueUSD          float64             `json:"value_usd"`          // USD value of the SOL leg at execution time, 0 if unknown
+	Slot              uint64              `json:"slot,omitempty"`
+	Timestamp         time.Time           `json:"timestamp"`
+	// The wallet's own balance changes of both mints read from the transaction
+	// meta, and why the amount received fell below the minimum out, if it did
+	WalletSent     float64 `json:"wallet_sent,omitempty"`
+	WalletReceived float64 `json:"wallet_received,omitempty"`
+	MinAmountOut   float64 `json:"min_amount_out,omitempty"`
+	Discrepancy    string  `json:"discrepancy,omitempty"`
 }
 
 type QuoteParams struct {
@@ -84,6 +141,17 @@ type OnChainPool struct {
 	QuoteAmount   uint64
 	BaseDecimals  uint8
 	QuoteDecimals uint8
+	LpMint        solana.PublicKey
+	LpAmount      uint64
+	// PnL owed to the protocol, still held in the vaults but not part of the reserves
+	NeedTakePnlBase  uint64
+	NeedTakePnlQuote uint64
+	// Swap fee charged on the input amount, e.g. 25/10000
+	SwapFeeNumerator   uint64
+	SwapFeeDenominator uint64
+	// AmmStatus and the time swaps open, see checkTradable
+	Status   uint64
+	OpenTime uint64
 	// Additional fields for swap instruction
 	Authority        solana.PublicKey
 	OpenOrders       solana.PublicKey
@@ -96,7 +164,7 @@ type OnChainPool struct {
 	MarketBaseVault  solana.PublicKey
 	MarketQuoteVault solana.PublicKey
 	Nonce            uint8
-	MarketNonce      uint8
+	MarketNonce      uint64 // vault_signer_nonce of the market
 }
 
 // loadWallet loads a wallet from the SOLANA_PRIVATE_KEY environment variable
@@ -121,8 +189,30 @@ func loadWallet() (solana.PrivateKey, error) {
 	return privateKey, nil
 }
 
-// confirmQuote asks the user to confirm the quote before execution
-func confirmQuote(poolAddress string, side string, amountIn float64, expectedOut float64) bool {
+// loadWalletAddress resolves the wallet of a read-only run: the given address,
+// then SOLANA_ADDRESS, then the SOLANA_PRIVATE_KEY wallet
+func loadWalletAddress(address string) (solana.PublicKey, error) {
+	if address == "" {
+		address = os.Getenv(WALLET_ADDRESS_ENV_VAR)
+	}
+	if address != "" {
+		key, err := solana.PublicKeyFromBase58(address)
+		if err != nil {
+			return solana.PublicKey{}, fmt.Errorf("invalid wallet address: %w", err)
+		}
+		return key, nil
+	}
+
+	wallet, err := loadWallet()
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("no wallet address in -address or %s: %w", WALLET_ADDRESS_ENV_VAR, err)
+	}
+	return wallet.PublicKey(), nil
+}
+
+// confirmQuote asks the user to confirm the quote, and what opts add to the
+// swap, before execution
+func confirmQuote(poolAddress string, side string, amountIn float64, expectedOut float64, symbol string, opts BuildOptions) bool {
 	scanner := bufio.NewScanner(os.Stdin)
 
 	// Calculate price
@@ -133,22 +223,38 @@ func confirmQuote(poolAddress string, side string, amountIn float64, expectedOut
 		price = expectedOut / amountIn // SOL per token
 	}
 
-	fmt.Printf("\n=== SWAP CONFIRMATION ===\n")
-	fmt.Printf("Pool: %s\n", poolAddress)
-	fmt.Printf("Operation: %s\n", strings.ToUpper(side))
-	fmt.Printf("Amount In: %.9f %s\n", amountIn, getInputToken(side))
-	fmt.Printf("Expected Out: %.9f %s\n", expectedOut, getOutputToken(side))
-	fmt.Printf("Price: %.9f SOL per token\n", price)
+	fmt.Print(tr("\n=== SWAP CONFIRMATION ===\n"))
+	fmt.Printf(tr("Pool: %s\n"), poolAddress)
+	fmt.Printf(tr("Operation: %s\n"), strings.ToUpper(side))
+	fmt.Printf(tr("Amount In: %.9f %s\n"), amountIn, tokenLabel(getInputToken(side), symbol))
+	fmt.Printf(tr("Expected Out: %.9f %s\n"), expectedOut, tokenLabel(getOutputToken(side), symbol))
+	fmt.Printf(tr("Price: %.9f SOL per %s\n"), price, tokenLabel("TOKEN", symbol))
+	opts.PlatformFee.print(side, amountIn, expectedOut)
+	opts.Burn.print(side, expectedOut, symbol)
+	if opts.FeePayer.Enabled() {
+		fmt.Printf(tr("Fee Payer: %s (reimbursed from the swap)\n"), opts.FeePayer.Key.PublicKey())
+	}
 	fmt.Printf("========================\n\n")
 
-	fmt.Print("Do you want to execute this swap? (y/n): ")
+	fmt.Print(tr("Do you want to execute this swap? (y/n): "))
+	if !scanner.Scan() {
+		fmt.Println(tr("\nSwap cancelled."))
+		return false
+	}
+
+	return isYes(scanner.Text())
+}
+
+// confirmPrompt asks the user a yes/no question
+func confirmPrompt(question string) bool {
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Printf(tr("%s (y/n): "), question)
 	if !scanner.Scan() {
-		fmt.Println("\nSwap cancelled.")
 		return false
 	}
 
-	response := strings.TrimSpace(strings.ToLower(scanner.Text()))
-	return response == "y" || response == "yes"
+	return isYes(scanner.Text())
 }
 
 // Helper functions to get token names based on side
@@ -159,6 +265,14 @@ func getInputToken(side string) string {
 	return "TOKEN"
 }
 
+// tokenLabel replaces the generic "TOKEN" label with the token's symbol when known
+func tokenLabel(token string, symbol string) string {
+	if token == "TOKEN" && symbol != "" {
+		return symbol
+	}
+	return token
+}
+
 func getOutputToken(side string) string {
 	if side == "buy" {
 		return "TOKEN"
@@ -170,7 +284,7 @@ func getOutputToken(side string) string {
 func getSlippageFromUser() (float64, error) {
 	scanner := bufio.NewScanner(os.Stdin)
 
-	fmt.Printf("\nEnter maximum slippage tolerance (%%) [default: %.1f]: ", DEFAULT_SLIPPAGE)
+	fmt.Printf(tr("\nEnter maximum slippage tolerance (%%) [default: %.1f]: "), DEFAULT_SLIPPAGE)
 	if !scanner.Scan() {
 		return DEFAULT_SLIPPAGE, nil
 	}
@@ -182,18 +296,7 @@ func getSlippageFromUser() (float64, error) {
 		return DEFAULT_SLIPPAGE, nil
 	}
 
-	// Parse the input
-	slippage, err := parseFloat(input)
-	if err != nil {
-		return 0, fmt.Errorf("invalid slippage value: %w", err)
-	}
-
-	// Validate range
-	if slippage < 0 || slippage > MAX_SLIPPAGE {
-		return 0, fmt.Errorf("slippage must be between 0 and %.0f", MAX_SLIPPAGE)
-	}
-
-	return slippage, nil
+	return parseSlippage(input)
 }
 
 // parseFloat parses a string to float64, handling both decimal and percentage formats
@@ -203,21 +306,16 @@ func parseFloat(s string) (float64, error) {
 	return strconv.ParseFloat(s, 64)
 }
 
-// calculateMinAmountOut calculates minimum amount out based on slippage
-func calculateMinAmountOut(expectedOut float64, slippagePercent float64, outputDecimals int) uint64 {
-	// Calculate minimum amount with slippage
-	minAmount := expectedOut * (1 - slippagePercent/100)
-
-	// Convert to raw amount
-	minAmountRaw := uint64(minAmount * math.Pow(10, float64(outputDecimals)))
-
-	return minAmountRaw
+// calculateMinAmountOut calculates the raw minimum amount out of a raw
+// expected output based on slippage
+func calculateMinAmountOut(expectedOutRaw uint64, slippagePercent float64) uint64 {
+	return atoms.ApplySlippage(expectedOutRaw, slippagePercent)
 }
 
 // getOrCreateATA gets or creates an Associated Token Account
 func getOrCreateATA(
 	ctx context.Context,
-	client *rpc.Client,
+	client ChainClient,
 	wallet solana.PublicKey,
 	mint solana.PublicKey,
 ) (solana.PublicKey, solana.Instruction, error) {
@@ -230,18 +328,52 @@ func getOrCreateATA(
 	accountInfo, err := client.GetAccountInfo(ctx, ata)
 	if err != nil || accountInfo == nil || accountInfo.Value == nil {
 		// ATA doesn't exist, create instruction to create it
-		createATAIx := associatedtokenaccount.NewCreateInstruction(
-			wallet,
-			wallet,
-			mint,
-		).Build()
-		return ata, createATAIx, nil
+		return ata, newCreateATAInstruction(wallet, mint), nil
 	}
 
 	// ATA exists
 	return ata, nil, nil
 }
 
+// newCreateATAInstruction creates the wallet's ATA for mint, paid by the wallet
+func newCreateATAInstruction(wallet solana.PublicKey, mint solana.PublicKey) solana.Instruction {
+	return associatedtokenaccount.NewCreateInstruction(
+		wallet,
+		wallet,
+		mint,
+	).Build()
+}
+
+// marketVaultSigners memoizes vault signer PDAs per market
+var marketVaultSigners sync.Map // solana.PublicKey -> solana.PublicKey
+
+// deriveMarketVaultSigner returns the market vault signer PDA if the pool has
+// a real market. The market stores the nonce its signer was created with, so
+// the PDA comes from a single CreateProgramAddress call; fetchMarketData must
+// have loaded MarketNonce.
+func deriveMarketVaultSigner(pool *OnChainPool) (solana.PublicKey, error) {
+	if pool.Market.IsZero() || !pool.MarketProgram.Equals(OPENBOOK_PROGRAM) {
+		// Use a dummy account if no real market
+		return solana.SystemProgramID, nil
+	}
+	if signer, ok := marketVaultSigners.Load(pool.Market); ok {
+		return signer.(solana.PublicKey), nil
+	}
+
+	signer, err := solana.CreateProgramAddress(
+		[][]byte{
+			pool.Market.Bytes(),
+			binary.LittleEndian.AppendUint64(nil, pool.MarketNonce),
+		},
+		pool.MarketProgram,
+	)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("market %s vault signer nonce %d does not derive a PDA: %w", pool.Market, pool.MarketNonce, err)
+	}
+	marketVaultSigners.Store(pool.Market, signer)
+	return signer, nil
+}
+
 // createSwapInstruction creates a Raydium V4 swap instruction
 func createSwapInstruction(
 	pool *OnChainPool,
@@ -268,42 +400,9 @@ func createSwapInstruction(
 	binary.LittleEndian.PutUint64(minAmountOutBytes, minAmountOut)
 	buf.Write(minAmountOutBytes)
 
-	// Get market vault signer PDA if we have a real market
-	var marketVaultSigner solana.PublicKey
-	if !pool.Market.IsZero() && pool.MarketProgram.String() == OPENBOOK_PROGRAM.String() {
-		// For OpenBook/Serum markets, the vault signer is derived differently
-		// We need to find the correct nonce that produces a valid PDA
-		var err error
-		var found bool
-		for nonce := uint8(0); nonce < 255; nonce++ {
-			candidate, err := solana.CreateProgramAddress(
-				[][]byte{
-					pool.Market.Bytes(),
-					{nonce},
-				},
-				pool.MarketProgram,
-			)
-			if err == nil {
-				marketVaultSigner = candidate
-				found = true
-				break
-			}
-		}
-		if !found {
-			// If we can't find it, use the pool's stored nonce
-			marketVaultSigner, _, err = solana.FindProgramAddress(
-				[][]byte{
-					pool.Market.Bytes(),
-				},
-				pool.MarketProgram,
-			)
-		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to find market vault signer PDA: %w", err)
-		}
-	} else {
-		// Use a dummy account if no real market
-		marketVaultSigner = solana.SystemProgramID
+	marketVaultSigner, err := deriveMarketVaultSigner(pool)
+	if err != nil {
+		return nil, err
 	}
 
 	// Build full account list for Raydium V4 swap
@@ -374,59 +473,153 @@ func createSwapInstruction(
 		accounts,
 		buf.Bytes(),
 	)
+	if err := validateSwapInstruction(instruction, pool, userOwner); err != nil {
+		return nil, fmt.Errorf("refusing malformed swap instruction: %w", err)
+	}
 
 	return instruction, nil
 }
 
-// executeSwap builds and executes the swap transaction
-func executeSwap(
-	ctx context.Context,
-	client *rpc.Client,
-	wallet solana.PrivateKey,
-	poolAddress string,
+// swapMints returns the input and output mints of a swap and the input decimals
+func swapMints(pool *OnChainPool, side string) (source solana.PublicKey, destination solana.PublicKey, inputDecimals int) {
+	isBaseSol := isBaseCurrency(pool)
+
+	if side == "buy" {
+		// Buying: SOL -> Token
+		if isBaseSol {
+			return pool.BaseMint, pool.QuoteMint, currencyDecimals(pool)
+		}
+		return pool.QuoteMint, pool.BaseMint, currencyDecimals(pool)
+	}
+
+	// Selling: Token -> SOL
+	if isBaseSol {
+		return pool.QuoteMint, pool.BaseMint, int(pool.QuoteDecimals)
+	}
+	return pool.BaseMint, pool.QuoteMint, int(pool.BaseDecimals)
+}
+
+// swapAccounts are the owner's token accounts for a swap. The create
+// instructions are nil when the account already exists.
+type swapAccounts struct {
+	Source            solana.PublicKey
+	Destination       solana.PublicKey
+	CreateSource      solana.Instruction
+	CreateDestination solana.Instruction
+	Delegated         bool // signed by the accounts' delegate: SOL stays wrapped
+}
+
+// swapInstructions assembles the swap's instructions from an already loaded
+// pool: ATA creation, SOL wrapping, the swap itself, the buyback burn, WSOL
+// unwrapping and the platform fee
+func swapInstructions(
+	pool *OnChainPool,
+	owner solana.PublicKey,
 	side string,
-	amountIn float64,
+	amountInRaw uint64,
 	minAmountOut uint64,
-) (string, error) {
-	poolPubkey, err := solana.PublicKeyFromBase58(poolAddress)
-	if err != nil {
-		return "", fmt.Errorf("invalid pool address: %w", err)
+	accounts swapAccounts,
+	opts BuildOptions,
+) ([]solana.Instruction, error) {
+	sourceMint, destinationMint, _ := swapMints(pool, side)
+	isBaseToQuote := sourceMint.Equals(pool.BaseMint)
+
+	instructions := []solana.Instruction{}
+	if accounts.CreateSource != nil {
+		instructions = append(instructions, accounts.CreateSource)
 	}
 
-	// Fetch pool data
-	accountInfo, err := client.GetAccountInfo(ctx, poolPubkey)
-	if err != nil {
-		return "", fmt.Errorf("failed to get pool account: %w", err)
+	// For WSOL, we need to create a wrapped SOL account and transfer SOL
+	if sourceMint.Equals(WSOL_MINT) && side == "buy" && !accounts.Delegated {
+		// Transfer SOL to the WSOL ATA
+		transferIx := system.NewTransferInstruction(
+			amountInRaw,
+			owner,
+			accounts.Source,
+		).Build()
+		instructions = append(instructions, transferIx)
+
+		// Sync native to update the WSOL balance
+		syncIx := token.NewSyncNativeInstruction(accounts.Source).Build()
+		instructions = append(instructions, syncIx)
 	}
 
-	pool, err := parsePoolAccount(poolPubkey, accountInfo.Value.Data.GetBinary())
+	if accounts.CreateDestination != nil {
+		instructions = append(instructions, accounts.CreateDestination)
+	}
+
+	// Create swap instruction
+	swapIx, err := createSwapInstruction(
+		pool,
+		accounts.Source,
+		accounts.Destination,
+		owner,
+		amountInRaw,
+		minAmountOut,
+		isBaseToQuote,
+	)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse pool data: %w", err)
+		return nil, fmt.Errorf("failed to create swap instruction: %w", err)
 	}
+	instructions = append(instructions, swapIx)
 
-	// Debug mints
-	fmt.Printf("\n=== DEBUG - Token mints ===\n")
-	fmt.Printf("BaseMint: %s\n", pool.BaseMint)
-	fmt.Printf("QuoteMint: %s\n", pool.QuoteMint)
+	// A buyback burn destroys its share of the output as soon as it arrives
+	if burnIx := opts.Burn.burnInstruction(pool, owner, side, minAmountOut, accounts); burnIx != nil {
+		instructions = append(instructions, burnIx)
+	}
 
-	// Get decimals
-	pool.BaseDecimals, err = getTokenDecimals(ctx, client, pool.BaseMint.String())
-	if err != nil {
-		return "", fmt.Errorf("failed to get base decimals for %s: %w", pool.BaseMint, err)
+	// For WSOL output, close the account to unwrap
+	if destinationMint.Equals(WSOL_MINT) && side == "sell" && !accounts.Delegated {
+		closeIx := token.NewCloseAccountInstruction(
+			accounts.Destination,
+			owner,
+			owner,
+			[]solana.PublicKey{},
+		).Build()
+		instructions = append(instructions, closeIx)
 	}
-	pool.QuoteDecimals, err = getTokenDecimals(ctx, client, pool.QuoteMint.String())
+
+	// The platform fee comes last, once a sell's output is in the wallet
+	feeIx, err := opts.PlatformFee.feeInstruction(pool, owner, side, amountInRaw, minAmountOut, accounts)
 	if err != nil {
-		return "", fmt.Errorf("failed to get quote decimals for %s: %w", pool.QuoteMint, err)
+		return nil, err
+	}
+	if feeIx != nil {
+		instructions = append(instructions, feeIx)
 	}
 
-	// Fetch actual vault balances
-	err = fetchVaultBalances(ctx, client, pool)
+	return instructions, nil
+}
+
+// buildSwapTransaction builds the unsigned swap transaction for the given owner
+func buildSwapTransaction(
+	ctx context.Context,
+	client ChainClient,
+	owner solana.PublicKey,
+	poolAddress string,
+	side string,
+	amountIn float64,
+	minAmountOut uint64,
+	opts BuildOptions,
+) (*solana.Transaction, error) {
+	pool, err := loadPool(ctx, client, poolAddress)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch vault balances: %w", err)
+		return nil, err
 	}
+	if err := pool.checkTradable(time.Now()); err != nil {
+		return nil, err
+	}
+
+	// Debug mints
+	fmt.Printf("\n=== DEBUG - Token mints ===\n")
+	fmt.Printf("BaseMint: %s\n", pool.BaseMint)
+	fmt.Printf("QuoteMint: %s\n", pool.QuoteMint)
 
-	// Fetch market data for the pool
+	// Fetch market data for the pool; a market that disagrees with the pool is not worked around
 	err = fetchMarketData(ctx, client, pool)
+	if errors.Is(err, ErrPoolMismatch) {
+		return nil, err
+	}
 	if err != nil {
 		// If we can't fetch market data, use fallback values
 		fmt.Printf("Warning: Failed to fetch market data: %v\n", err)
@@ -458,150 +651,131 @@ func executeSwap(
 	fmt.Printf("Nonce: %d\n", pool.Nonce)
 	fmt.Printf("=============================\n")
 
-	// Determine swap direction and mints
-	var (
-		sourceMint      solana.PublicKey
-		destinationMint solana.PublicKey
-		isBaseToQuote   bool
-		inputDecimals   int
-	)
-
-	isBaseSol := pool.BaseMint.Equals(WSOL_MINT) || pool.BaseMint.Equals(SOL_MINT)
-
-	if side == "buy" {
-		// Buying: SOL -> Token
-		inputDecimals = SOL_DECIMALS
-		if isBaseSol {
-			sourceMint = pool.BaseMint
-			destinationMint = pool.QuoteMint
-			isBaseToQuote = true
-		} else {
-			sourceMint = pool.QuoteMint
-			destinationMint = pool.BaseMint
-			isBaseToQuote = false
-		}
-	} else {
-		// Selling: Token -> SOL
-		if isBaseSol {
-			sourceMint = pool.QuoteMint
-			destinationMint = pool.BaseMint
-			inputDecimals = int(pool.QuoteDecimals)
-			isBaseToQuote = false
-		} else {
-			sourceMint = pool.BaseMint
-			destinationMint = pool.QuoteMint
-			inputDecimals = int(pool.BaseDecimals)
-			isBaseToQuote = true
-		}
-	}
+	sourceMint, destinationMint, inputDecimals := swapMints(pool, side)
 
 	// Convert amount to raw
-	amountInRaw := uint64(amountIn * math.Pow(10, float64(inputDecimals)))
+	amountInRaw, err := atoms.FromAmount(amountIn, inputDecimals)
+	if err != nil {
+		return nil, err
+	}
 
 	// Get or create ATAs
-	instructions := []solana.Instruction{}
-	signers := []solana.PrivateKey{wallet}
-
-	// Skip compute budget for now to simplify debugging
-
-	// Source ATA
-	sourceATA, createSourceIx, err := getOrCreateATA(ctx, client, wallet.PublicKey(), sourceMint)
+	// A delegate trades the accounts of the wallet that approved it
+	holder := opts.Delegation.holder(owner)
+	var accounts swapAccounts
+	accounts.Source, accounts.CreateSource, err = getOrCreateATA(ctx, client, holder, sourceMint)
 	if err != nil {
-		return "", fmt.Errorf("failed to get source ATA: %w", err)
+		return nil, fmt.Errorf("failed to get source ATA: %w", err)
 	}
-	if createSourceIx != nil {
+	if accounts.CreateSource != nil {
 		fmt.Printf("Creating source ATA for mint %s\n", sourceMint)
-		instructions = append(instructions, createSourceIx)
 	}
-
-	// For WSOL, we need to create a wrapped SOL account and transfer SOL
-	if sourceMint.Equals(WSOL_MINT) && side == "buy" {
-		fmt.Printf("Wrapping SOL: transferring %d lamports to WSOL ATA %s\n", amountInRaw, sourceATA)
-		// Transfer SOL to the WSOL ATA
-		transferIx := system.NewTransferInstruction(
-			amountInRaw,
-			wallet.PublicKey(),
-			sourceATA,
-		).Build()
-		instructions = append(instructions, transferIx)
-
-		// Sync native to update the WSOL balance
-		syncIx := token.NewSyncNativeInstruction(sourceATA).Build()
-		instructions = append(instructions, syncIx)
+	if sourceMint.Equals(WSOL_MINT) && side == "buy" && !opts.Delegation.Enabled() {
+		fmt.Printf("Wrapping SOL: transferring %d lamports to WSOL ATA %s\n", amountInRaw, accounts.Source)
 	}
 
-	// Destination ATA
-	destinationATA, createDestIx, err := getOrCreateATA(ctx, client, wallet.PublicKey(), destinationMint)
+	accounts.Destination, accounts.CreateDestination, err = getOrCreateATA(ctx, client, holder, destinationMint)
 	if err != nil {
-		return "", fmt.Errorf("failed to get destination ATA: %w", err)
+		return nil, fmt.Errorf("failed to get destination ATA: %w", err)
 	}
-	if createDestIx != nil {
+	if accounts.CreateDestination != nil {
 		fmt.Printf("Creating destination ATA for mint %s\n", destinationMint)
-		instructions = append(instructions, createDestIx)
+	}
+
+	if opts.FeePayer.sponsored(owner) {
+		opts.FeePayer.sponsorAccounts(owner, sourceMint, destinationMint, &accounts)
+	}
+	if opts.Delegation.Enabled() {
+		if err := opts.Delegation.delegateAccounts(ctx, client, owner, destinationMint, amountInRaw, &accounts); err != nil {
+			return nil, err
+		}
 	}
 
 	fmt.Printf("\n=== DEBUG - Token Accounts ===\n")
 	fmt.Printf("Source mint: %s\n", sourceMint)
-	fmt.Printf("Source ATA: %s\n", sourceATA)
+	fmt.Printf("Source ATA: %s\n", accounts.Source)
 	fmt.Printf("Destination mint: %s\n", destinationMint)
-	fmt.Printf("Destination ATA: %s\n", destinationATA)
+	fmt.Printf("Destination ATA: %s\n", accounts.Destination)
 	fmt.Printf("==============================\n")
 
-	// Create swap instruction
-	swapIx, err := createSwapInstruction(
-		pool,
-		sourceATA,
-		destinationATA,
-		wallet.PublicKey(),
-		amountInRaw,
-		minAmountOut,
-		isBaseToQuote,
-	)
+	instructions, err := swapInstructions(pool, owner, side, amountInRaw, minAmountOut, accounts, opts)
 	if err != nil {
-		return "", fmt.Errorf("failed to create swap instruction: %w", err)
+		return nil, err
+	}
+	priceIx := opts.MEV.computePriceInstruction()
+	if priceIx == nil {
+		priceIx = opts.Compute.computePriceInstruction()
+	}
+	if !opts.Reference.IsZero() {
+		if priceIx, err = withReference(priceIx, opts.Reference); err != nil {
+			return nil, err
+		}
+	}
+	if priceIx != nil {
+		instructions = append([]solana.Instruction{priceIx}, instructions...)
+	}
+	sender, err := opts.Sender.sender(client, opts.MEV)
+	if err != nil {
+		return nil, err
+	}
+	tip, err := sender.TipInstruction(owner)
+	if err != nil {
+		return nil, err
+	}
+	if tip != nil {
+		instructions = append(instructions, tip)
 	}
-	instructions = append(instructions, swapIx)
 
-	// For WSOL output, close the account to unwrap
-	if destinationMint.Equals(WSOL_MINT) && side == "sell" {
-		closeIx := token.NewCloseAccountInstruction(
-			destinationATA,
-			wallet.PublicKey(),
-			wallet.PublicKey(),
-			[]solana.PublicKey{},
-		).Build()
-		instructions = append(instructions, closeIx)
+	// A separate fee payer is reimbursed last, once a sell's proceeds are in
+	// the wallet; the amount is settled after the compute limit is known
+	payer := opts.FeePayer.payer(owner)
+	var sponsoredRent uint64
+	if opts.FeePayer.sponsored(owner) {
+		sponsoredRent, err = opts.FeePayer.rentSponsored(ctx, client, accounts)
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, opts.FeePayer.reimbursementInstruction(owner, instructions, sponsoredRent))
 	}
 
 	// Get latest blockhash
 	latestBlockhash, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
 	if err != nil {
-		return "", fmt.Errorf("failed to get latest blockhash: %w", err)
+		return nil, fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+	instructions = opts.Compute.withComputeLimit(ctx, client, instructions, latestBlockhash.Value.Blockhash, payer)
+	if opts.FeePayer.sponsored(owner) {
+		last := len(instructions) - 1
+		instructions[last] = opts.FeePayer.reimbursementInstruction(owner, instructions[:last], sponsoredRent)
 	}
 
 	// Build transaction
-	tx, err := solana.NewTransaction(
-		instructions,
-		latestBlockhash.Value.Blockhash,
-		solana.TransactionPayer(wallet.PublicKey()),
-	)
+	tx, err := fitTransaction(ctx, client, instructions, latestBlockhash.Value.Blockhash, payer)
 	if err != nil {
-		return "", fmt.Errorf("failed to create transaction: %w", err)
+		return nil, err
 	}
 
 	// Debug transaction info
 	fmt.Printf("\n=== DEBUG - Transaction Info ===\n")
 	fmt.Printf("Instructions count: %d\n", len(instructions))
 	fmt.Printf("Blockhash: %s\n", latestBlockhash.Value.Blockhash)
-	fmt.Printf("Fee payer: %s\n", wallet.PublicKey())
-	fmt.Printf("Signers: %d\n", len(signers))
+	fmt.Printf("Fee payer: %s\n", payer)
+	fmt.Printf("Required signers: %d\n", tx.Message.Header.NumRequiredSignatures)
 	for i, ix := range instructions {
 		fmt.Printf("Instruction %d: Program %s\n", i, ix.ProgramID())
 	}
 	fmt.Printf("================================\n")
 
-	// Sign transaction
-	_, err = tx.Sign(
+	return tx, nil
+}
+
+// signTransaction checks the transaction fits in a packet and signs it with
+// the given signers
+func signTransaction(tx *solana.Transaction, signers ...solana.PrivateKey) error {
+	if err := checkTransactionSize(tx); err != nil {
+		return err
+	}
+	_, err := tx.Sign(
 		func(key solana.PublicKey) *solana.PrivateKey {
 			for _, signer := range signers {
 				if signer.PublicKey().Equals(key) {
@@ -612,49 +786,127 @@ func executeSwap(
 		},
 	)
 	if err != nil {
-		return "", fmt.Errorf("failed to sign transaction: %w", err)
+		return fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	return nil
+}
+
+// executeSwap builds and executes the swap transaction
+func executeSwap(
+	ctx context.Context,
+	client ChainClient,
+	wallet solana.PrivateKey,
+	poolAddress string,
+	side string,
+	amountIn float64,
+	minAmountOut uint64,
+	opts BuildOptions,
+) (string, error) {
+	tx, err := buildSwapTransaction(ctx, client, wallet.PublicKey(), poolAddress, side, amountIn, minAmountOut, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if err := signTransaction(tx, opts.FeePayer.signers(wallet)...); err != nil {
+		return "", err
+	}
+
+	sig, err := sendAndConfirmTransaction(ctx, client, tx, opts)
+	if err != nil {
+		return "", deadLetterSwap(wallet.PublicKey(), poolAddress, side, amountIn, minAmountOut, tx, err)
+	}
+
+	return sig.String(), nil
+}
+
+// sendAndConfirmTransaction broadcasts a signed transaction and waits for
+// confirmation. The signature is recorded as pending before the send and
+// cleared once confirmed, so an interrupted run can be resumed with tx status.
+// A transaction confirmed with an error returns its signature and
+// ErrTransactionFailed.
+func sendAndConfirmTransaction(ctx context.Context, client ChainClient, tx *solana.Transaction, opts BuildOptions) (solana.Signature, error) {
+	if len(tx.Signatures) > 0 {
+		if err := savePendingTx(tx.Signatures[0]); err != nil {
+			fmt.Printf("Warning: Could not record pending transaction: %v\n", err)
+		}
 	}
+	defer trackInFlight()()
 
 	// Send transaction with more detailed error handling
 	fmt.Println("\nSending transaction...")
 
-	// First try with preflight to get better error messages
-	sig, err := client.SendTransactionWithOpts(
-		ctx,
-		tx,
-		rpc.TransactionOpts{
-			SkipPreflight:       false,
-			PreflightCommitment: rpc.CommitmentFinalized,
-		},
-	)
-
+	sender, err := opts.Sender.sender(client, opts.MEV)
 	if err != nil {
-		// If preflight fails, try without it to get the actual on-chain error
-		if strings.Contains(err.Error(), "Transaction signature verification failure") {
-			fmt.Println("Preflight failed, trying without preflight to get on-chain error...")
-			sig, err = client.SendTransactionWithOpts(
-				ctx,
-				tx,
-				rpc.TransactionOpts{
-					SkipPreflight:       true,
-					PreflightCommitment: rpc.CommitmentFinalized,
-				},
-			)
-			if err != nil {
-				return "", fmt.Errorf("failed to send transaction: %w", err)
-			}
-		} else {
-			return "", fmt.Errorf("failed to send transaction: %w", err)
+		forgetUnsentTx(tx)
+		return solana.Signature{}, err
+	}
+	if sender.Name() != SENDER_RPC {
+		fmt.Printf("Sending through %s\n", sender.Name())
+	}
+	// A refused transaction is never resent through the public RPC: it would
+	// expose a private or bundled swap to the mempool it was meant to avoid
+	sig, err := sender.Send(ctx, tx)
+	if err != nil {
+		forgetUnsentTx(tx)
+		return solana.Signature{}, fmt.Errorf("failed to send transaction: %w", err)
+	}
+
+	// Only a transaction the sender accepted is spammed, so failed preflights
+	// are never broadcast. Private relays and bundles are never spammed.
+	if opts.Spam.Enabled() && sender.Name() == SENDER_RPC {
+		stop := opts.Spam.spam(ctx, client, tx)
+		defer stop()
+	}
+
+	confirmed, err := waitForConfirmation(ctx, client, sig)
+	if confirmed {
+		if err := removePendingTx(sig); err != nil {
+			fmt.Printf("Warning: Could not clear pending transaction: %v\n", err)
+		}
+		if err != nil {
+			return sig, err
 		}
+	} else {
+		fmt.Printf("Transaction %s is not confirmed yet; check it later with: go run . tx status %s\n", sig, sig)
+	}
+
+	return sig, nil
+}
+
+// forgetUnsentTx drops the pending record of a transaction the RPC node refused
+func forgetUnsentTx(tx *solana.Transaction) {
+	if len(tx.Signatures) == 0 {
+		return
 	}
+	if err := removePendingTx(tx.Signatures[0]); err != nil {
+		fmt.Printf("Warning: Could not clear pending transaction: %v\n", err)
+	}
+}
 
-	// Wait for confirmation
+// waitForConfirmation polls the signature status until it is confirmed or
+// retries run out, and reports whether it was confirmed. A transaction that
+// was confirmed with an error returns ErrTransactionFailed. When ctx is
+// cancelled the wait is cut to TX_SHUTDOWN_GRACE and polling continues
+// detached from ctx.
+func waitForConfirmation(ctx context.Context, client ChainClient, sig solana.Signature) (bool, error) {
 	fmt.Println("Waiting for confirmation...")
+	pollCtx := context.WithoutCancel(ctx)
+	interrupted := ctx.Done()
+	var deadline time.Time
 	maxRetries := 30
 	for i := 0; i < maxRetries; i++ {
-		time.Sleep(1 * time.Second)
+		select {
+		case <-time.After(1 * time.Second):
+		case <-interrupted:
+			interrupted = nil
+			deadline = time.Now().Add(TX_SHUTDOWN_GRACE)
+			fmt.Printf("\nInterrupted; waiting up to %s for %s to confirm...\n", TX_SHUTDOWN_GRACE, sig)
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return false, nil
+		}
 
-		status, err := client.GetSignatureStatuses(ctx, false, sig)
+		status, err := client.GetSignatureStatuses(pollCtx, false, sig)
 		if err != nil {
 			continue
 		}
@@ -662,24 +914,91 @@ func executeSwap(
 		if status != nil && len(status.Value) > 0 && status.Value[0] != nil {
 			if status.Value[0].ConfirmationStatus == rpc.ConfirmationStatusConfirmed ||
 				status.Value[0].ConfirmationStatus == rpc.ConfirmationStatusFinalized {
-				break
+				if status.Value[0].Err != nil {
+					return true, fmt.Errorf("%w: %v", ErrTransactionFailed, status.Value[0].Err)
+				}
+				return true, nil
 			}
 		}
 	}
+	return false, nil
+}
 
-	return sig.String(), nil
+// dryRunSwap builds, signs and simulates the swap transaction without broadcasting it
+func dryRunSwap(
+	ctx context.Context,
+	client ChainClient,
+	wallet solana.PrivateKey,
+	poolAddress string,
+	side string,
+	amountIn float64,
+	minAmountOut uint64,
+	opts BuildOptions,
+) error {
+	tx, err := buildSwapTransaction(ctx, client, wallet.PublicKey(), poolAddress, side, amountIn, minAmountOut, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := signTransaction(tx, opts.FeePayer.signers(wallet)...); err != nil {
+		return err
+	}
+
+	return simulateTransaction(ctx, client, tx)
+}
+
+// simulateTransaction simulates a signed transaction and prints its base64 form and logs
+func simulateTransaction(ctx context.Context, client ChainClient, tx *solana.Transaction) error {
+	encoded, err := tx.ToBase64()
+	if err != nil {
+		return fmt.Errorf("failed to serialize transaction: %w", err)
+	}
+
+	fmt.Println("\nSimulating transaction (dry run, nothing will be sent)...")
+	sim, err := client.SimulateTransactionWithOpts(
+		ctx,
+		tx,
+		&rpc.SimulateTransactionOpts{
+			SigVerify:  true,
+			Commitment: rpc.CommitmentConfirmed,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to simulate transaction: %w", err)
+	}
+
+	fmt.Printf("\n=== DRY RUN RESULT ===\n")
+	fmt.Printf("Signature: %s\n", tx.Signatures[0])
+	fmt.Printf("Transaction (base64): %s\n", encoded)
+	if sim.Value.Err != nil {
+		fmt.Printf("Simulation: FAILED (%v)\n", sim.Value.Err)
+	} else {
+		fmt.Printf("Simulation: SUCCESS\n")
+	}
+	if sim.Value.UnitsConsumed != nil {
+		fmt.Printf("Compute Units Consumed: %d\n", *sim.Value.UnitsConsumed)
+	}
+	fmt.Printf("\nLogs:\n")
+	for _, line := range sim.Value.Logs {
+		fmt.Printf("  %s\n", line)
+	}
+	fmt.Printf("======================\n")
+
+	return nil
 }
 
-// parseSwapResult fetches transaction details and extracts swap amounts
+// parseSwapResult fetches transaction details and extracts the swap amounts and
+// the pool fee (in input token units), from the Raydium ray_log when present
 func parseSwapResult(
 	ctx context.Context,
-	client *rpc.Client,
+	client ChainClient,
 	txHash string,
 	wallet solana.PublicKey,
-) (actualIn float64, actualOut float64, err error) {
+	pool *OnChainPool,
+) (actualIn float64, actualOut float64, fee float64, err error) {
 	sig, err := solana.SignatureFromBase58(txHash)
 	if err != nil {
-		return 0, 0, fmt.Errorf("invalid transaction hash: %w", err)
+		return 0, 0, 0, fmt.Errorf("invalid transaction hash: %w", err)
 	}
 
 	// Get transaction details
@@ -692,20 +1011,33 @@ func parseSwapResult(
 		},
 	)
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to get transaction: %w", err)
+		return 0, 0, 0, fmt.Errorf("failed to get transaction: %w", err)
 	}
 
 	if tx == nil || tx.Meta == nil {
-		return 0, 0, fmt.Errorf("transaction not found or no metadata")
+		return 0, 0, 0, fmt.Errorf("transaction not found or no metadata")
 	}
 
 	// Check if transaction was successful
 	if tx.Meta.Err != nil {
-		return 0, 0, fmt.Errorf("transaction failed: %v", tx.Meta.Err)
+		return 0, 0, 0, fmt.Errorf("transaction failed: %v", tx.Meta.Err)
+	}
+
+	// The program's own log has the exact amounts, unaffected by other balance
+	// changes in the transaction or by unwrapping SOL
+	if swapLog, err := raylog.FindSwap(tx.Meta.LogMessages); err == nil {
+		inDecimals, outDecimals := int(pool.QuoteDecimals), int(pool.BaseDecimals)
+		if swapLog.BaseIn() {
+			inDecimals, outDecimals = outDecimals, inDecimals
+		}
+		numerator, denominator := swapFeeRate(pool)
+		feeRaw := atoms.MulDiv(swapLog.AmountIn, numerator, denominator, true)
+		return atoms.ToAmount(swapLog.AmountIn, inDecimals),
+			atoms.ToAmount(swapLog.AmountOut, outDecimals),
+			atoms.ToAmount(feeRaw, inDecimals), nil
 	}
 
-	// For simplicity, we'll use the pre/post token balances
-	// In a real implementation, you would parse the logs for exact swap amounts
+	// Fall back to the wallet's pre/post token balances
 	preBalances := tx.Meta.PreTokenBalances
 	postBalances := tx.Meta.PostTokenBalances
 
@@ -742,28 +1074,143 @@ func parseSwapResult(
 		// SOL balance parsing would require decoding the transaction which is complex
 		if len(preBalances) == 0 {
 			// Fallback values if we can't parse
-			return 0, 0, fmt.Errorf("could not parse transaction balances")
+			return 0, 0, 0, fmt.Errorf("could not parse transaction balances")
+		}
+	}
+
+	return tokenIn, tokenOut, 0, nil
+}
+
+// fetchTransaction fetches a confirmed transaction with its metadata
+func fetchTransaction(ctx context.Context, client ChainClient, txHash string) (*rpc.GetTransactionResult, error) {
+	sig, err := solana.SignatureFromBase58(txHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction hash: %w", err)
+	}
+
+	version := uint64(0)
+	tx, err := client.GetTransaction(
+		ctx,
+		sig,
+		&rpc.GetTransactionOpts{
+			Encoding:                       solana.EncodingBase64,
+			Commitment:                     rpc.CommitmentConfirmed,
+			MaxSupportedTransactionVersion: &version,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	if tx == nil || tx.Meta == nil {
+		return nil, fmt.Errorf("transaction not found or no metadata")
+	}
+	return tx, nil
+}
+
+// applyTransactionCosts fills in what the transaction cost wallet beyond the
+// swap itself (base and priority fees, rent deposited into or recovered from
+// token accounts) and the wallet's net SOL change, plus slot and block time
+func applyTransactionCosts(report *TransactionReport, tx *rpc.GetTransactionResult, wallet solana.PublicKey) {
+	report.NetworkFee, report.PriorityFee = transactionFees(tx)
+	rentSpent, rentRecovered := transactionRent(tx.Meta, wallet)
+	report.RentSpent = atoms.ToAmount(rentSpent, SOL_DECIMALS)
+	report.RentRecovered = atoms.ToAmount(rentRecovered, SOL_DECIMALS)
+
+	if parsed, err := tx.Transaction.GetTransaction(); err == nil {
+		for i, key := range parsed.Message.AccountKeys {
+			if key.Equals(wallet) && i < len(tx.Meta.PreBalances) && i < len(tx.Meta.PostBalances) {
+				delta := int64(tx.Meta.PostBalances[i]) - int64(tx.Meta.PreBalances[i])
+				report.NetSOLChange = float64(delta) / math.Pow(10, SOL_DECIMALS)
+				break
+			}
+		}
+	}
+
+	report.Slot = tx.Slot
+	if tx.BlockTime != nil {
+		report.Timestamp = tx.BlockTime.Time()
+	}
+}
+
+// transactionRent returns the lamports wallet deposited as rent into token
+// accounts the transaction opened, and recovered from ones it closed. WSOL
+// balances are not rent; accounts opened and closed within the transaction,
+// like a temporary WSOL account, net to zero and do not appear.
+func transactionRent(meta *rpc.TransactionMeta, wallet solana.PublicKey) (spent uint64, recovered uint64) {
+	// rentOf returns an account's lamports without its wrapped SOL
+	rentOf := func(lamports uint64, balance rpc.TokenBalance) uint64 {
+		if balance.Mint.Equals(WSOL_MINT) && balance.UiTokenAmount != nil {
+			if wrapped, err := strconv.ParseUint(balance.UiTokenAmount.Amount, 10, 64); err == nil && wrapped <= lamports {
+				return lamports - wrapped
+			}
 		}
+		return lamports
 	}
 
-	return tokenIn, tokenOut, nil
+	for _, balance := range meta.PostTokenBalances {
+		i := int(balance.AccountIndex)
+		if balance.Owner == nil || !balance.Owner.Equals(wallet) || i >= len(meta.PreBalances) || i >= len(meta.PostBalances) {
+			continue
+		}
+		if meta.PreBalances[i] == 0 && meta.PostBalances[i] > 0 {
+			spent += rentOf(meta.PostBalances[i], balance)
+		}
+	}
+	for _, balance := range meta.PreTokenBalances {
+		i := int(balance.AccountIndex)
+		if balance.Owner == nil || !balance.Owner.Equals(wallet) || i >= len(meta.PreBalances) || i >= len(meta.PostBalances) {
+			continue
+		}
+		if meta.PostBalances[i] == 0 && meta.PreBalances[i] > 0 {
+			recovered += rentOf(meta.PreBalances[i], balance)
+		}
+	}
+	return spent, recovered
+}
+
+// transactionFees splits a fetched transaction's fee into the base fee and the prioritization fee, in SOL
+func transactionFees(tx *rpc.GetTransactionResult) (networkFee float64, priorityFee float64) {
+	// The base fee is charged per signature; anything above it is the prioritization fee
+	numSignatures := uint64(1)
+	if parsed, err := tx.Transaction.GetTransaction(); err == nil && len(parsed.Signatures) > 0 {
+		numSignatures = uint64(len(parsed.Signatures))
+	}
+
+	baseFee := numSignatures * LAMPORTS_PER_SIGNATURE
+	if baseFee > tx.Meta.Fee {
+		baseFee = tx.Meta.Fee
+	}
+
+	networkFee = float64(baseFee) / math.Pow(10, SOL_DECIMALS)
+	priorityFee = float64(tx.Meta.Fee-baseFee) / math.Pow(10, SOL_DECIMALS)
+	return networkFee, priorityFee
 }
 
 // generateReport creates a detailed transaction report
 func generateReport(
 	ctx context.Context,
-	client *rpc.Client,
+	client ChainClient,
 	wallet solana.PublicKey,
 	txHash string,
+	pool *OnChainPool,
+	tokenMint solana.PublicKey,
 	side string,
 	expectedIn float64,
 	expectedOut float64,
 	slippageTolerance float64,
+	priceImpact float64,
+	minAmountOut uint64,
+	opts BuildOptions,
 ) (*TransactionReport, error) {
+	// A delegate's swap moves the holder's tokens while the delegate pays the fees
+	holder := opts.Delegation.holder(wallet)
+
 	// Parse transaction to get actual amounts
-	actualIn, actualOut, err := parseSwapResult(ctx, client, txHash, wallet)
+	actualIn, actualOut, swapFee, err := parseSwapResult(ctx, client, txHash, holder, pool)
 	if err != nil {
 		// If we can't parse, use expected values
+		fmt.Printf("Warning: Could not read executed amounts (%v); realized slippage is unavailable\n", err)
 		actualIn = expectedIn
 		actualOut = expectedOut
 	}
@@ -786,60 +1233,469 @@ func generateReport(
 		}
 	}
 
-	// Calculate actual slippage
-	var slippage float64
+	// Realized slippage: paying more per token on a buy, or receiving less on a sell, is worse
+	var realizedSlippage float64
 	if expectedPrice > 0 {
-		slippage = math.Abs((actualPrice-expectedPrice)/expectedPrice) * 100
+		realizedSlippage = (actualPrice - expectedPrice) / expectedPrice * 100
+		if side == "sell" {
+			realizedSlippage = -realizedSlippage
+		}
 	}
 
 	report := &TransactionReport{
-		TxHash:        txHash,
-		Status:        "Success",
-		AmountIn:      actualIn,
-		AmountOut:     actualOut,
-		ExpectedPrice: expectedPrice,
-		ActualPrice:   actualPrice,
-		Slippage:      slippage,
-		ExplorerURL:   fmt.Sprintf("https://solscan.io/tx/%s", txHash),
-		InputToken:    getInputToken(side),
-		OutputToken:   getOutputToken(side),
+		TxHash:            txHash,
+		Status:            "Success",
+		AmountIn:          actualIn,
+		AmountOut:         actualOut,
+		ExpectedPrice:     expectedPrice,
+		ActualPrice:       actualPrice,
+		PriceImpact:       priceImpact,
+		SlippageTolerance: slippageTolerance,
+		RealizedSlippage:  realizedSlippage,
+		ExplorerURL:       explorerTxURL(txHash),
+		PoolURL:           explorerAccountURL(pool.Address.String()),
+		TokenURL:          explorerAccountURL(tokenMint.String()),
+		InputToken:        getInputToken(side),
+		OutputToken:       getOutputToken(side),
+		Side:              side,
+		PoolAddress:       pool.Address.String(),
+		SwapFee:           swapFee,
+		TokenMint:         tokenMint.String(),
+		TokenSymbol:       tokenSymbol(ctx, client, tokenMint),
+		Timestamp:         time.Now(),
+	}
+	if !holder.Equals(wallet) {
+		report.Delegate = wallet.String()
+	}
+	// The platform fee was fixed when the swap was built
+	_, _, inputDecimals := swapMints(pool, side)
+	if amountInRaw, err := atoms.FromAmount(expectedIn, inputDecimals); err == nil {
+		report.PlatformFee = atoms.ToAmount(opts.PlatformFee.rawFee(side, amountInRaw, minAmountOut), currencyDecimals(pool))
+	}
+	// So was the burn
+	if burned := opts.Burn.rawBurn(side, minAmountOut); burned > 0 {
+		report.Burned = atoms.ToAmount(burned, int(tokenDecimals(pool, tokenMint)))
+	}
+
+	// Stable-paired pools trade the token against their stablecoin instead of SOL
+	if isStablePaired(pool) {
+		if side == "buy" {
+			report.InputToken = currencyName(currencyMint(pool))
+		} else {
+			report.OutputToken = currencyName(currencyMint(pool))
+		}
+	}
+
+	tx, err := fetchTransaction(ctx, client, txHash)
+	if err != nil {
+		fmt.Printf("Warning: Could not fetch transaction fees: %v\n", err)
+	} else {
+		applyTransactionCosts(report, tx, wallet)
+		verifyWalletBalances(report, tx.Meta, holder, pool, side, minAmountOut)
 	}
 
 	return report, nil
 }
 
+// walletMintDelta returns the raw change of wallet's balance of mint summed
+// over its token accounts. Accounts opened and closed within the transaction
+// have no token balances and do not count.
+func walletMintDelta(meta *rpc.TransactionMeta, wallet solana.PublicKey, mint solana.PublicKey) int64 {
+	var delta int64
+	sum := func(balances []rpc.TokenBalance, sign int64) {
+		for _, balance := range balances {
+			if balance.Owner == nil || !balance.Owner.Equals(wallet) || !balance.Mint.Equals(mint) || balance.UiTokenAmount == nil {
+				continue
+			}
+			amount, err := strconv.ParseInt(balance.UiTokenAmount.Amount, 10, 64)
+			if err == nil {
+				delta += sign * amount
+			}
+		}
+	}
+	sum(meta.PreTokenBalances, -1)
+	sum(meta.PostTokenBalances, 1)
+	return delta
+}
+
+// verifyWalletBalances checks what the wallet actually received against the
+// minimum out the swap was sent with. The pool's swap log reports what left
+// the pool; a Token-2022 transfer fee or another instruction can make the
+// wallet receive less, which is flagged as a discrepancy. Needs the costs
+// from applyTransactionCosts to separate the SOL leg from fees and rent.
+func verifyWalletBalances(report *TransactionReport, meta *rpc.TransactionMeta, wallet solana.PublicKey, pool *OnChainPool, side string, minAmountOut uint64) {
+	sourceMint, destinationMint, inputDecimals := swapMints(pool, side)
+	outputDecimals := int(pool.BaseDecimals)
+	if destinationMint.Equals(pool.QuoteMint) {
+		outputDecimals = int(pool.QuoteDecimals)
+	}
+
+	// SOL moves through a temporary WSOL account; what is left of the wallet's
+	// lamport change after fees and rent is the swapped SOL. A delegate trades
+	// wrapped SOL and pays the fees itself, so only the WSOL balance moves.
+	change := func(mint solana.PublicKey, decimals int) float64 {
+		amount := atoms.ToSignedAmount(walletMintDelta(meta, wallet, mint), decimals)
+		if mint.Equals(WSOL_MINT) && report.Delegate == "" {
+			amount += report.NetSOLChange + report.NetworkFee + report.PriorityFee + report.RentSpent - report.RentRecovered
+		}
+		return amount
+	}
+	report.WalletSent = -change(sourceMint, inputDecimals)
+	report.WalletReceived = change(destinationMint, outputDecimals)
+	// The platform fee leaves the wallet separately from the swap
+	if side == "buy" {
+		report.WalletSent -= report.PlatformFee
+	} else {
+		report.WalletReceived += report.PlatformFee
+	}
+	// Burned tokens reached the wallet before the burn destroyed them
+	report.WalletReceived += report.Burned
+	report.MinAmountOut = atoms.ToAmount(minAmountOut, outputDecimals)
+
+	// Float rounding in the SOL leg stays well below one raw unit
+	tolerance := 0.5 / math.Pow(10, float64(outputDecimals))
+	if report.WalletReceived+tolerance < report.MinAmountOut {
+		report.Status = "Discrepancy"
+		report.Discrepancy = fmt.Sprintf(tr("the wallet received %.9f, below the minimum out of %.9f although the pool paid out %.9f; a token transfer fee or another instruction took the difference"),
+			report.WalletReceived, report.MinAmountOut, report.AmountOut)
+	}
+}
+
 // printReport displays the transaction report
 func printReport(report *TransactionReport) {
-	fmt.Printf("\n=== TRANSACTION REPORT ===\n")
-	fmt.Printf("Status: %s\n", report.Status)
-	fmt.Printf("Transaction: %s\n", report.TxHash)
-	fmt.Printf("Explorer: %s\n", report.ExplorerURL)
-	fmt.Printf("\nSwap Details:\n")
-	fmt.Printf("  Amount In: %.9f %s\n", report.AmountIn, report.InputToken)
-	fmt.Printf("  Amount Out: %.9f %s\n", report.AmountOut, report.OutputToken)
-	fmt.Printf("\nPrice Analysis:\n")
-	fmt.Printf("  Expected Price: %.9f SOL per token\n", report.ExpectedPrice)
-	fmt.Printf("  Actual Price: %.9f SOL per token\n", report.ActualPrice)
-	fmt.Printf("  Price Impact: %.4f%%\n", report.Slippage)
+	fmt.Print(tr("\n=== TRANSACTION REPORT ===\n"))
+	fmt.Printf(tr("Status: %s\n"), tr(report.Status))
+	fmt.Printf(tr("Transaction: %s\n"), report.TxHash)
+	fmt.Printf(tr("Explorer: %s\n"), report.ExplorerURL)
+	if report.Slot > 0 {
+		fmt.Printf(tr("Slot: %d (%s)\n"), report.Slot, report.Timestamp.UTC().Format(time.RFC3339))
+	}
+	if report.PoolAddress != "" {
+		fmt.Printf(tr("Pool: %s\n"), report.PoolAddress)
+	}
+	if report.PoolURL != "" {
+		fmt.Printf(tr("Pool Explorer: %s\n"), report.PoolURL)
+	}
+	if report.TokenURL != "" {
+		fmt.Printf(tr("Token Explorer: %s\n"), report.TokenURL)
+	}
+	if report.Delegate != "" {
+		fmt.Printf(tr("Delegate: %s\n"), report.Delegate)
+	}
+	fmt.Print(tr("\nSwap Details:\n"))
+	fmt.Printf(tr("  Amount In: %.9f %s\n"), report.AmountIn, tokenLabel(report.InputToken, report.TokenSymbol))
+	fmt.Printf(tr("  Amount Out: %.9f %s\n"), report.AmountOut, tokenLabel(report.OutputToken, report.TokenSymbol))
+	if report.Burned > 0 {
+		fmt.Printf(tr("  Burned: %.9f %s\n"), report.Burned, tokenLabel(report.OutputToken, report.TokenSymbol))
+	}
+	fmt.Print(tr("\nPrice Analysis:\n"))
+	// Reports rebuilt from past signatures have no quote to compare against
+	if report.ExpectedPrice > 0 {
+		fmt.Printf(tr("  Expected Price: %.9f SOL per token\n"), report.ExpectedPrice)
+	}
+	fmt.Printf(tr("  Actual Price: %.9f SOL per token\n"), report.ActualPrice)
+	if report.ExpectedPrice > 0 {
+		fmt.Printf(tr("  Price Impact (quoted): %.4f%%\n"), report.PriceImpact)
+		fmt.Printf(tr("  Slippage Tolerance: %.2f%%\n"), report.SlippageTolerance)
+		fmt.Printf(tr("  Realized Slippage: %+.4f%%\n"), report.RealizedSlippage)
+	}
+	fmt.Print(tr("\nFees:\n"))
+	if report.SwapFee > 0 {
+		fmt.Printf(tr("  LP Fee: %.9f %s\n"), report.SwapFee, tokenLabel(report.InputToken, report.TokenSymbol))
+	}
+	fmt.Printf(tr("  Network Fee: %.9f SOL\n"), report.NetworkFee)
+	fmt.Printf(tr("  Priority Fee: %.9f SOL\n"), report.PriorityFee)
+	if report.PlatformFee > 0 {
+		fmt.Printf(tr("  Platform Fee: %.9f %s\n"), report.PlatformFee, report.platformFeeToken())
+	}
+	if report.RentSpent > 0 {
+		fmt.Printf(tr("  Rent Deposited: %.9f SOL\n"), report.RentSpent)
+	}
+	if report.RentRecovered > 0 {
+		fmt.Printf(tr("  Rent Recovered: %.9f SOL\n"), report.RentRecovered)
+	}
+	fmt.Printf(tr("  Net SOL Change: %+.9f SOL\n"), report.NetSOLChange)
+	if report.Discrepancy != "" {
+		fmt.Print(tr("\n⚠️  Balance Discrepancy:\n"))
+		fmt.Printf(tr("  Wallet Sent: %.9f %s\n"), report.WalletSent, tokenLabel(report.InputToken, report.TokenSymbol))
+		fmt.Printf(tr("  Wallet Received: %.9f %s\n"), report.WalletReceived, tokenLabel(report.OutputToken, report.TokenSymbol))
+		fmt.Printf(tr("  Minimum Out: %.9f %s\n"), report.MinAmountOut, tokenLabel(report.OutputToken, report.TokenSymbol))
+		fmt.Printf("  %s\n", report.Discrepancy)
+	}
+	if report.ValueUSD > 0 {
+		fmt.Printf(tr("\nValue: $%.2f\n"), report.ValueUSD)
+	}
 	fmt.Printf("========================\n")
 }
 
+// runCommand dispatches a named subcommand with its remaining arguments and
+// the build options chosen by the environment
+func runCommand(name string, args []string, opts BuildOptions) {
+	switch name {
+	case "doctor":
+		runDoctor(args)
+	case "broadcast":
+		runBroadcast(args, opts)
+	case "watch":
+		runWatch(args)
+	case "lp":
+		runLp(args, opts)
+	case "grpc":
+		runGRPC(args, opts)
+	case "bot":
+		runBot(args, opts)
+	case "e2e":
+		runE2E(args, opts)
+	case "portfolio", "balance":
+		runPortfolio(args)
+	case "daemon":
+		runDaemon(args, opts)
+	case "template":
+		runTemplate(args, opts)
+	case "limits":
+		runLimits(args)
+	case "tx":
+		runTx(args)
+	case "copy":
+		runCopy(args, opts)
+	case "depth":
+		runDepth(args)
+	case "price":
+		runPrice(args)
+	case "candles":
+		runCandles(args)
+	case "lookup-table":
+		runLookupTable(args, opts)
+	case "pool", "pools":
+		runPool(args, opts)
+	case "launch":
+		runLaunch(args, opts)
+	case "token":
+		runToken(args)
+	case "atas":
+		runAtas(args, opts)
+	case "approve":
+		runApprove(args, opts)
+	case "revoke":
+		runRevoke(args, opts)
+	case "backtest":
+		runBacktest(args)
+	case "alert":
+		runAlert(args)
+	case "receipts":
+		runReceipts(args)
+	case "rug-guard":
+		runRugGuard(args, opts)
+	case "jobs":
+		runJobs(args)
+	case "size":
+		runSize(args)
+	case "watchlist":
+		runWatchlist(args)
+	case "retry":
+		runRetry(args, opts)
+	case "overrides":
+		runOverrides(args)
+	case "bracket":
+		runBracket(args, opts)
+	case "ledger":
+		runLedger(args)
+	case "completion":
+		runCompletion(args)
+	default:
+		log.Fatalf("Unknown command %q (available: doctor, broadcast, watch, lp, grpc, bot, e2e, portfolio, daemon, template, limits, tx, copy, depth, price, candles, lookup-table, pool, launch, token, atas, approve, revoke, backtest, alert, rug-guard, receipts, jobs, size, watchlist, retry, overrides, bracket, ledger, completion)", name)
+	}
+}
+
 func main() {
+	// Subcommands select their cluster from the environment
+	if err := selectCluster(os.Getenv(CLUSTER_ENV_VAR)); err != nil {
+		log.Fatalf("Invalid %s: %v", CLUSTER_ENV_VAR, err)
+	}
+	if err := loadRetryPolicy(); err != nil {
+		log.Fatal(err)
+	}
+	swapOptions, err := loadBuildOptions()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// An unknown locale in the environment falls back to English
+	selectLanguage(os.Getenv(LANG_ENV_VAR))
+	if err := selectExplorer(os.Getenv(EXPLORER_ENV_VAR)); err != nil {
+		log.Fatalf("Invalid %s: %v", EXPLORER_ENV_VAR, err)
+	}
+
+	// Completing the swap mode's flags falls through to its flag set with -h
+	if len(os.Args) > 1 && os.Args[1] == COMPLETE_COMMAND {
+		if !runComplete(os.Args[2:]) {
+			return
+		}
+		os.Args = []string{os.Args[0], "-h"}
+	}
+
+	// Subcommands take precedence over the flag-driven quote/swap mode
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		if err := swapOptions.PlatformFee.Validate(); err != nil {
+			log.Fatal(err)
+		}
+		if err := swapOptions.Burn.Validate(); err != nil {
+			log.Fatal(err)
+		}
+		runCommand(os.Args[1], os.Args[2:], swapOptions)
+		printRPCStats()
+		return
+	}
+
 	var poolAddr string
 	var tokenAddr string
-	var amount float64
+	var amountArg string
 	var side string
 	var execute bool
+	var dryRun bool
+	var reportFormat string
+	var reportFile string
+	var exportPath string
+	var exportEncoding string
+	var ownerAddr string
+	var multisigAddr string
+	var vaultIndex uint
+	var notifyURL string
+	var clusterName string
+	var currency string
+	var notifySecret string
+	var obfuscation ObfuscationConfig
+	var override bool
+	var interactive bool
+	var maxPoolIdle time.Duration
+	var spamRPCs string
+	var duplicateWindow time.Duration
+	var quoteCurrency string
+	var skipHoneypotCheck bool
+	var slippageArg string
+	var solanaPayURL string
+	var solanaPayListen string
+	var solanaPayQR string
 
 	flag.StringVar(&poolAddr, "pool", "", "Pool address")
 	flag.StringVar(&tokenAddr, "token", "", "Token address (finds best pool)")
-	flag.Float64Var(&amount, "amount", 0, "Amount to swap")
+	flag.StringVar(&amountArg, "amount", "", "Amount to swap: 0.5, 1.5k, 0.5sol, 5000000lamports or 25% of the balance")
 	flag.StringVar(&side, "side", "", "buy or sell")
+	flag.StringVar(&slippageArg, "slippage", "", "Slippage tolerance in percent, or auto to derive it from the pool's recent volatility and the trade size (default: ask)")
 	flag.BoolVar(&execute, "execute", false, "Execute the swap (requires SOLANA_PRIVATE_KEY)")
+	flag.BoolVar(&dryRun, "dry-run", false, "Build, sign and simulate the swap without sending it (requires SOLANA_PRIVATE_KEY)")
+	flag.StringVar(&reportFormat, "report", "", "Append executed swap reports to a file (csv)")
+	flag.StringVar(&currency, "currency", CURRENCY_SOL, "Units for quote and report valuations: sol or usd")
+	flag.StringVar(&quoteCurrency, "quote-currency", QUOTE_CURRENCY_SOL, "With -token -side sell, the currency to sell into: sol, usdc (USDC or USDT pools) or auto (best USD value)")
+	flag.StringVar(&reportFile, "report-file", DEFAULT_REPORT_FILE, "Path of the report file used with -report")
+	flag.StringVar(&exportPath, "export-tx", "", "Write the unsigned swap transaction to a file for offline/multisig signing")
+	flag.StringVar(&exportEncoding, "export-encoding", "base64", "Encoding used with -export-tx (base64 or base58)")
+	flag.StringVar(&solanaPayURL, "solana-pay", "", "Serve the swap as a Solana Pay transaction request at this public https URL, for a mobile wallet to sign instead of this machine")
+	flag.StringVar(&solanaPayListen, "solana-pay-listen", DEFAULT_SOLANA_PAY_LISTEN, "Local address the -solana-pay URL forwards to")
+	flag.StringVar(&solanaPayQR, "solana-pay-qr", "", "Also write the -solana-pay QR code to this PNG file")
+	flag.StringVar(&ownerAddr, "address", "", "Watch-only wallet public key for quotes and -export-tx (or "+WALLET_ADDRESS_ENV_VAR+", defaults to the "+PRIVATE_KEY_ENV_VAR+" wallet)")
+	flag.StringVar(&ownerAddr, "owner", "", "Same as -address")
+	flag.StringVar(&multisigAddr, "multisig", "", "Squads v4 multisig address; with -execute the swap is proposed from its vault")
+	flag.UintVar(&vaultIndex, "vault-index", 0, "Squads vault index used with -multisig")
+	flag.StringVar(&clusterName, "cluster", activeCluster.Name, "Solana cluster: mainnet, devnet, testnet or custom (or "+CLUSTER_ENV_VAR+"); custom requires "+RPC_URL_ENV_VAR)
+	flag.StringVar(&notifyURL, "notify-url", "", "POST a JSON report of executed or failed swaps to this webhook URL")
+	flag.StringVar(&notifySecret, "notify-secret", os.Getenv(NOTIFY_SECRET_ENV_VAR), "HMAC-SHA256 key used to sign -notify-url payloads (or "+NOTIFY_SECRET_ENV_VAR+")")
+	flag.Float64Var(&obfuscation.SizeJitterPct, "size-jitter", 0, "Randomize the trade size by up to ±N percent")
+	flag.DurationVar(&obfuscation.TimingJitter, "send-jitter", 0, "Wait a random delay up to this duration before sending (e.g. 5s)")
+	flag.BoolVar(&obfuscation.AlternatePools, "alternate-pools", false, "With -token, pick randomly among pools of comparable liquidity")
+	flag.StringVar(&swapOptions.Sender.Name, "sender", swapOptions.Sender.Name, "Transaction sender: rpc, jito, helius or nozomi")
+	flag.StringVar(&swapOptions.Sender.URL, "sender-url", swapOptions.Sender.URL, "Endpoint of the -sender backend, including any API key (or "+SENDER_URL_ENV_VAR+")")
+	flag.Float64Var(&swapOptions.Sender.Tip, "tip", swapOptions.Sender.Tip, "Tip in SOL paid to jito, helius or nozomi")
+	flag.StringVar(&swapOptions.Sender.TipAccount, "tip-account", swapOptions.Sender.TipAccount, "Tip account for helius or nozomi, from the provider's docs (or "+SENDER_TIP_ACCOUNT_ENV_VAR+")")
+	flag.StringVar(&spamRPCs, "spam-rpcs", os.Getenv(SPAM_RPC_URLS_ENV_VAR), "Comma-separated extra RPC endpoints every sent transaction is broadcast to (or "+SPAM_RPC_URLS_ENV_VAR+")")
+	flag.DurationVar(&swapOptions.Spam.Interval, "rebroadcast", swapOptions.Spam.Interval, "With -spam-rpcs, resend to every endpoint at this interval until confirmed, 0 to send once")
+	flag.BoolVar(&swapOptions.MEV.Enabled, "anti-mev", false, "Cap slippage, randomize the compute price and send through a private relay (or "+PRIVATE_RELAY_URL_ENV_VAR+")")
+	flag.Float64Var(&swapOptions.MEV.Epsilon, "mev-epsilon", swapOptions.MEV.Epsilon, "Maximum slippage in percent used with -anti-mev")
+	flag.Uint64Var(&swapOptions.MEV.MinComputePrice, "mev-min-compute-price", swapOptions.MEV.MinComputePrice, "Lowest compute unit price in micro-lamports used with -anti-mev")
+	flag.Uint64Var(&swapOptions.MEV.MaxComputePrice, "mev-max-compute-price", swapOptions.MEV.MaxComputePrice, "Highest compute unit price in micro-lamports used with -anti-mev")
+	flag.BoolVar(&swapOptions.Compute.Auto, "auto-cu-limit", swapOptions.Compute.Auto, "Simulate the swap and set its compute unit limit to the units used plus -cu-margin")
+	flag.Float64Var(&swapOptions.Compute.MarginPct, "cu-margin", swapOptions.Compute.MarginPct, "Percent added to the simulated compute units with -auto-cu-limit")
+	flag.Uint64Var(&swapOptions.Compute.Price, "cu-price", swapOptions.Compute.Price, "Compute unit price in micro-lamports paid as a priority fee; -anti-mev randomizes its own")
+	flag.Uint64Var(&swapOptions.PlatformFee.Bps, "fee-bps", swapOptions.PlatformFee.Bps, "Platform fee in basis points charged on the SOL or stablecoin leg of swaps (or "+PLATFORM_FEE_BPS_ENV_VAR+")")
+	flag.StringVar(&swapOptions.PlatformFee.Recipient, "fee-recipient", swapOptions.PlatformFee.Recipient, "Wallet receiving the platform fee (or "+PLATFORM_FEE_RECIPIENT_ENV_VAR+")")
+	flag.Float64Var(&swapOptions.Burn.Pct, "burn-pct", swapOptions.Burn.Pct, "Percent of every buy's minimum out burned in the same transaction, for buyback-and-burn programs (or "+BUYBACK_BURN_PCT_ENV_VAR+")")
+	delegateFor := flag.String("delegate-for", "", "Trade this wallet's token accounts as its approved delegate, signing with "+PRIVATE_KEY_ENV_VAR)
+	feePayerPath := flag.String("fee-payer", "", "Keypair file of a separate account paying fees and rent, reimbursed from the swap (or "+FEE_PAYER_KEY_ENV_VAR+")")
+	explorer := flag.String("explorer", "", "Block explorer of printed links: solscan, solanafm, xray or explorer (or "+EXPLORER_ENV_VAR+")")
+	lang := flag.String("lang", "", "Language of prompts and reports: en or ru (defaults to "+LANG_ENV_VAR+")")
+	flag.StringVar(&lookupTableAddress, "lookup-table", lookupTableAddress, "Address lookup table used when a swap exceeds the transaction size limit (or "+LOOKUP_TABLE_ENV_VAR+")")
+	flag.DurationVar(&maxPoolIdle, "max-pool-idle", DEFAULT_POOL_MAX_IDLE, "Warn when the pool's last transaction is older than this, 0 to skip the check")
+	flag.BoolVar(&skipHoneypotCheck, "skip-honeypot-check", false, "Buy without first simulating a sell of the token")
+	flag.BoolVar(&interactive, "interactive", false, "With -token, choose the pool from the comparison table instead of taking the best output")
+	flag.BoolVar(&override, "override", false, "Trade even when the wallet's spend limits would refuse it (see the limits command)")
+	flag.DurationVar(&duplicateWindow, "duplicate-window", DEFAULT_DUPLICATE_WINDOW, "Ask before repeating a trade on the same pool, side and amount executed this recently, 0 to skip the check")
+	flag.IntVar(&rpcPolicy.MaxRetries, "rpc-retries", rpcPolicy.MaxRetries, "Retries for failed or rate-limited RPC requests (or "+RPC_MAX_RETRIES_ENV_VAR+")")
+	flag.Float64Var(&rpcPolicy.RateLimit, "rpc-rate-limit", rpcPolicy.RateLimit, "Maximum RPC requests per second, 0 for unlimited (or "+RPC_RATE_LIMIT_ENV_VAR+")")
+	flag.DurationVar(&rpcPolicy.Timeout, "rpc-timeout", rpcPolicy.Timeout, "Give up on an RPC request attempt after this long and retry it (or "+RPC_TIMEOUT_ENV_VAR+")")
+	flag.DurationVar(&rpcPolicy.ScanTimeout, "rpc-scan-timeout", rpcPolicy.ScanTimeout, "-rpc-timeout for the getProgramAccounts scans of pool discovery (or "+RPC_SCAN_TIMEOUT_ENV_VAR+")")
+	flag.Int64Var(&rpcPolicy.Budget, "rpc-budget", rpcPolicy.Budget, "Estimated RPC credits the run may spend, 0 for unlimited; pool discovery falls back to cheaper searches to stay within it (or "+RPC_BUDGET_ENV_VAR+")")
+	flag.BoolVar(&rpcPolicy.Verbose, "verbose", rpcPolicy.Verbose, "Print RPC retry statistics at the end of the run (or "+RPC_VERBOSE_ENV_VAR+")")
+	flag.Usage = func() { printCommandUsage(flag.CommandLine, "") }
 	flag.Parse()
+	defer printRPCStats()
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	if *lang != "" {
+		if err := selectLanguage(*lang); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if rpcPolicy.Timeout <= 0 || rpcPolicy.ScanTimeout <= 0 {
+		log.Fatal("-rpc-timeout and -rpc-scan-timeout must be positive")
+	}
+	if rpcPolicy.Budget < 0 {
+		log.Fatal("-rpc-budget must not be negative")
+	}
+	if *explorer != "" {
+		if err := selectExplorer(*explorer); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *feePayerPath != "" {
+		feePayer, err := loadFeePayerFile(*feePayerPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		swapOptions.FeePayer = feePayer
+	}
+	if solanaPayURL != "" && (execute || dryRun || exportPath != "" || multisigAddr != "") {
+		log.Fatal("-solana-pay cannot be combined with -execute, -dry-run, -export-tx or -multisig")
+	}
+	if solanaPayURL != "" {
+		if _, err := solanaPayBaseURL(solanaPayURL); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// The wallet that scans the link signs for itself
+	unsigned := exportPath != "" || solanaPayURL != ""
+	if swapOptions.FeePayer.Enabled() && (unsigned || multisigAddr != "") {
+		log.Fatal("A separate fee payer cannot be combined with -export-tx, -solana-pay or -multisig")
+	}
+	if *delegateFor != "" {
+		owner, err := solana.PublicKeyFromBase58(*delegateFor)
+		if err != nil {
+			log.Fatalf("Invalid -delegate-for wallet: %v", err)
+		}
+		swapOptions.Delegation.Owner = owner
+		if unsigned || multisigAddr != "" {
+			log.Fatal("-delegate-for cannot be combined with -export-tx, -solana-pay or -multisig")
+		}
+		// The delegate has no allowance over the wallet's new tokens to burn
+		if swapOptions.Burn.Enabled() {
+			log.Fatal("-delegate-for cannot be combined with -burn-pct")
+		}
+		if err := swapOptions.validateDelegation(); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	if amount == 0 || side == "" {
-		fmt.Println("Usage: go run main.go [-pool POOL | -token TOKEN] -amount AMOUNT -side buy|sell [-execute]")
+	if amountArg == "" || side == "" {
+		fmt.Println("Usage: go run main.go [-pool POOL | -token TOKEN] -amount AMOUNT -side buy|sell [-execute | -dry-run]")
 		flag.PrintDefaults()
 		return
 	}
@@ -852,47 +1708,251 @@ func main() {
 		log.Fatal("Side must be 'buy' or 'sell'")
 	}
 
-	// Validate minimum amount for safety
-	if amount < MIN_SWAP_AMOUNT {
-		log.Fatalf("Amount too small. Minimum swap amount is %.3f", MIN_SWAP_AMOUNT)
+	amountSpec, err := parseAmountSpec(amountArg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if reportFormat != "" && reportFormat != "csv" {
+		log.Fatalf("Unsupported report format %q (supported: csv)", reportFormat)
+	}
+	if currency != CURRENCY_SOL && currency != CURRENCY_USD {
+		log.Fatalf("Unsupported currency %q (supported: sol, usd)", currency)
+	}
+	currencyMints, err := quoteCurrencyMints(quoteCurrency)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if quoteCurrency != QUOTE_CURRENCY_SOL && (side != "sell" || tokenAddr == "") {
+		log.Fatal("-quote-currency usdc and auto only apply to sells with -token")
 	}
 
-	// Load wallet if execute flag is set
+	var multisig solana.PublicKey
+	if multisigAddr != "" {
+		if !execute {
+			log.Fatal("-multisig requires -execute")
+		}
+		if vaultIndex > math.MaxUint8 {
+			log.Fatal("-vault-index must be between 0 and 255")
+		}
+		var err error
+		multisig, err = solana.PublicKeyFromBase58(multisigAddr)
+		if err != nil {
+			log.Fatalf("Invalid multisig address: %v", err)
+		}
+	}
+
+	if err := selectCluster(clusterName); err != nil {
+		log.Fatalf("Invalid cluster: %v", err)
+	}
+	if activeCluster.Name != "mainnet" {
+		fmt.Printf("Cluster: %s (%s)\n", activeCluster.Name, resolveRPCURL())
+	}
+
+	notifier := newWebhookNotifier(notifyURL, notifySecret)
+
+	if err := obfuscation.Validate(); err != nil {
+		log.Fatalf("Invalid obfuscation options: %v", err)
+	}
+	if err := swapOptions.MEV.Validate(); err != nil {
+		log.Fatalf("Invalid anti-MEV options: %v", err)
+	}
+	if err := swapOptions.PlatformFee.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if err := swapOptions.Burn.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if slippageArg != "" && slippageArg != SLIPPAGE_AUTO {
+		if _, err := parseSlippage(slippageArg); err != nil {
+			log.Fatalf("Invalid -slippage: %v", err)
+		}
+	}
+	if err := swapOptions.Compute.Validate(); err != nil {
+		log.Fatalf("Invalid compute unit options: %v", err)
+	}
+	if _, err := swapOptions.Sender.sender(nil, swapOptions.MEV); err != nil {
+		log.Fatalf("Invalid sender options: %v", err)
+	}
+	swapOptions.Spam.Endpoints = parseEndpointList(spamRPCs)
+	if err := swapOptions.validateBroadcast(); err != nil {
+		log.Fatal(err)
+	}
+	// A short random delay keeps sends from landing at a predictable moment
+	if swapOptions.MEV.Enabled && obfuscation.TimingJitter == 0 {
+		obfuscation.TimingJitter = DEFAULT_MEV_SEND_JITTER
+	}
+
+	// Load wallet if execute or dry-run flag is set
 	var wallet solana.PrivateKey
-	if execute {
+	if execute || dryRun {
 		var err error
 		wallet, err = loadWallet()
 		if err != nil {
-			log.Fatalf("Failed to load wallet: %v", err)
+			log.Fatalf(tr("Failed to load wallet: %v"), err)
 		}
-		fmt.Printf("Wallet loaded: %s\n", wallet.PublicKey())
+		fmt.Printf(tr("Wallet loaded: %s\n"), wallet.PublicKey())
 	}
 
-	ctx := context.Background()
+	// Interrupts after a transaction is sent leave it pending for tx status
+	ctx := interruptContext()
+
+	client := newChainClient()
 
-	// Use RPC endpoint from environment or default
-	rpcURL := os.Getenv("SOLANA_RPC_URL")
-	if rpcURL == "" {
-		rpcURL = "https://mainnet.helius-rpc.com/?api-key=4a5313a6-8380-4882-ad4e-e745ec00d629"
+	// Spend limits only apply to trades that are actually sent
+	var guard *SpendGuard
+	if execute && !dryRun {
+		var err error
+		guard, err = loadSpendGuard(client, wallet.PublicKey())
+		if err != nil {
+			log.Fatalf(tr("Failed to load spend limits: %v"), err)
+		}
+		guard.Override = override
+	}
+
+	// Quotes and exported transactions only need the wallet's address, so
+	// watch-only runs never load a private key
+	var owner solana.PublicKey
+	if wallet != nil && ownerAddr == "" {
+		owner = wallet.PublicKey()
+	} else if key, err := loadWalletAddress(ownerAddr); err == nil {
+		owner = key
+		if wallet == nil {
+			fmt.Printf(tr("Watch-only wallet: %s\n"), owner)
+		}
+	} else if exportPath != "" || ownerAddr != "" {
+		log.Fatalf(tr("-export-tx requires -address, %s or %s: %v"), WALLET_ADDRESS_ENV_VAR, PRIVATE_KEY_ENV_VAR, err)
 	}
 
-	client := rpc.New(rpcURL)
+	// Percentages are of the signing wallet, or else of the watched one; a
+	// delegate's are of the wallet it trades for
+	holder := swapOptions.Delegation.holder(owner)
+	if wallet != nil {
+		holder = swapOptions.Delegation.holder(wallet.PublicKey())
+	}
+	amount, err := amountSpec.Resolve(ctx, client, side, tokenAddr, poolAddr, holder)
+	if err != nil {
+		log.Fatalf(tr("Invalid amount: %v"), err)
+	}
+	if amountSpec.Unit != "" {
+		fmt.Printf(tr("Amount: %s = %.9f %s\n"), amountSpec, amount, getInputToken(side))
+	}
+
+	if obfuscation.SizeJitterPct > 0 {
+		amount = obfuscation.randomizeSize(amount)
+		fmt.Printf(tr("Randomized amount: %.9f\n"), amount)
+	}
+
+	// Validate minimum amount for safety
+	if amount < MIN_SWAP_AMOUNT {
+		log.Fatalf(tr("Amount too small. Minimum swap amount is %.3f"), MIN_SWAP_AMOUNT)
+	}
+
+	// Tokens still on their pump.fun bonding curve have no Raydium pool yet
+	if tokenAddr != "" {
+		mint, err := solana.PublicKeyFromBase58(tokenAddr)
+		if err != nil {
+			log.Fatalf(tr("Invalid token address: %v"), err)
+		}
+		curve, err := fetchPumpCurve(ctx, client, mint)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if curve != nil && !curve.Complete {
+			if unsigned || multisigAddr != "" || swapOptions.Delegation.Enabled() {
+				log.Fatal("-export-tx, -solana-pay, -multisig and -delegate-for are not supported for tokens on the pump.fun bonding curve")
+			}
+			if swapOptions.Burn.Enabled() && side == "buy" {
+				log.Fatal("-burn-pct is not supported for tokens on the pump.fun bonding curve")
+			}
+			fmt.Printf(tr("Token %s is still on the pump.fun bonding curve\n"), tokenAddr)
+			if err := runPumpSwap(ctx, client, wallet, curve, side, amount, execute, dryRun, guard, swapOptions); err != nil {
+				log.Fatalf(tr("Swap failed: %v"), err)
+			}
+			return
+		}
+		if curve != nil {
+			fmt.Printf(tr("Token %s has migrated off the pump.fun bonding curve\n"), tokenAddr)
+		}
+
+		// Raydium LaunchLab curves switch over to the AMM pool once migrated
+		launch, err := fetchLaunchLabPool(ctx, client, mint)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if launch != nil && launch.Trading() {
+			if unsigned || multisigAddr != "" || swapOptions.Delegation.Enabled() {
+				log.Fatal("-export-tx, -solana-pay, -multisig and -delegate-for are not supported for tokens on a LaunchLab bonding curve")
+			}
+			if swapOptions.Burn.Enabled() && side == "buy" {
+				log.Fatal("-burn-pct is not supported for tokens on a LaunchLab bonding curve")
+			}
+			fmt.Printf(tr("Token %s is still on its Raydium LaunchLab bonding curve\n"), tokenAddr)
+			if err := runLaunchLabSwap(ctx, client, wallet, launch, side, amount, execute, dryRun, guard, swapOptions); err != nil {
+				log.Fatalf(tr("Swap failed: %v"), err)
+			}
+			return
+		}
+		if launch != nil && launch.MigrateType == LAUNCHLAB_MIGRATE_AMM {
+			fmt.Printf(tr("Token %s has migrated off its LaunchLab bonding curve\n"), tokenAddr)
+		} else if launch != nil {
+			fmt.Printf(tr("Token %s has migrated off its LaunchLab bonding curve to a CPMM pool, which is not supported\n"), tokenAddr)
+		}
+	}
 
 	var poolAddress string
+	var stableOut solana.PublicKey // the stablecoin a sell pays out in, zero for SOL
+
+	oracle := newPoolPriceOracle(client)
 
 	// If token address is provided, find pools
 	if tokenAddr != "" {
-		pool, err := findPoolsOnChain(ctx, client, tokenAddr)
+		pools, err := discoverPairedPools(ctx, client, tokenAddr, currencyMints)
+		if err != nil && quoteCurrency == QUOTE_CURRENCY_USDC {
+			log.Fatal(err)
+		}
+		if err != nil {
+			// Fall back to Meteora for tokens without Raydium liquidity
+			pair, dlmmErr := findDlmmPair(ctx, client, tokenAddr)
+			if dlmmErr != nil {
+				log.Fatalf("%v; %v", err, dlmmErr)
+			}
+			if unsigned || multisigAddr != "" || swapOptions.Delegation.Enabled() {
+				log.Fatal("-export-tx, -solana-pay, -multisig and -delegate-for are not supported for Meteora DLMM pairs")
+			}
+			if swapOptions.Burn.Enabled() && side == "buy" {
+				log.Fatal("-burn-pct is not supported for Meteora DLMM pairs")
+			}
+			if err := runDlmmSwap(ctx, client, wallet, pair, side, amount, execute, dryRun, guard, swapOptions); err != nil {
+				log.Fatalf(tr("Swap failed: %v"), err)
+			}
+			return
+		}
+		// Sells may weigh pools paired with different currencies by their USD value
+		pool, err := selectCurrencyPool(ctx, oracle, pools, side, amount, func(pools []*OnChainPool) *OnChainPool {
+			return obfuscation.pickPool(pools, side, amount)
+		})
 		if err != nil {
 			log.Fatal(err)
 		}
+		candidates := quoteCandidatePools(pools, side, amount)
+		printCandidateQuotes(candidates, pool)
+		if interactive {
+			pool = chooseCandidateQuote(candidates, pool)
+		}
+		if pool == nil {
+			log.Fatalf(tr("No pools with liquidity found for token %s"), tokenAddr)
+		}
 		poolAddress = pool.Address.String()
-		fmt.Printf("Found pool: %s\n", poolAddress)
+		fmt.Printf(tr("Found pool: %s\n"), poolAddress)
+		if isStablePaired(pool) {
+			stableOut = currencyMint(pool)
+		}
 	} else {
 		poolAddress = poolAddr
 	}
 
-	quote, err := calculateQuoteOnChain(ctx, client, QuoteParams{
+	quoteRaw, quoteDecimals, err := calculateQuoteOnChain(ctx, client, QuoteParams{
 		PoolAddress: poolAddress,
 		Amount:      amount,
 		Side:        side,
@@ -900,216 +1960,553 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	quote := atoms.ToAmount(quoteRaw, quoteDecimals)
+
+	var symbol string
+	tokenMintKey, err := poolTokenMint(ctx, client, tokenAddr, poolAddress)
+	if err == nil {
+		symbol = tokenSymbol(ctx, client, tokenMintKey)
+	} else if guard != nil {
+		log.Fatalf(tr("Failed to resolve the pool's token for spend limits: %v"), err)
+	}
 
-	fmt.Printf("\n=== QUOTE RESULT ===\n")
-	fmt.Printf("Protocol: %s\n", PROTOCOL)
-	fmt.Printf("Pool: %s\n", poolAddress)
-	fmt.Printf("Operation: %s\n", strings.ToUpper(side))
-	fmt.Printf("Amount In: %.9f\n", amount)
-	fmt.Printf("Expected Out: %.9f\n", quote)
+	// Per-token and per-pool overrides replace defaults, never flags given on the command line
+	_, overrides := openTradeOverrides()
+	tradeOverride, overridden := overrides.For(tokenMintKey, poolAddress)
+	if overridden {
+		tradeOverride.apply(given, &slippageArg, &swapOptions)
+		fmt.Printf(tr("Overrides: %s\n"), tradeOverride.describe())
+		if _, err := swapOptions.Sender.sender(nil, swapOptions.MEV); err != nil {
+			log.Fatalf("Invalid sender options after overrides: %v", err)
+		}
+		if err := swapOptions.validateBroadcast(); err != nil {
+			log.Fatalf("Invalid sender options after overrides: %v", err)
+		}
+		if execute || dryRun || unsigned {
+			if err := tradeOverride.checkSize(side, amount, quote); err != nil {
+				log.Fatalf(tr("Refusing to trade: %v"), err)
+			}
+		}
+	}
+
+	fmt.Print(tr("\n=== QUOTE RESULT ===\n"))
+	fmt.Printf(tr("Protocol: %s\n"), PROTOCOL)
+	fmt.Printf(tr("Pool: %s\n"), poolAddress)
+	if symbol != "" {
+		fmt.Printf(tr("Token: %s\n"), symbol)
+	}
+	fmt.Printf(tr("Operation: %s\n"), strings.ToUpper(side))
+	fmt.Printf(tr("Amount In: %.9f\n"), amount)
+	fmt.Printf(tr("Expected Out: %.9f\n"), quote)
+	if !stableOut.IsZero() {
+		fmt.Printf(tr("Paid Out In: %s\n"), currencyName(stableOut))
+	}
+	if currency == CURRENCY_USD && !stableOut.IsZero() {
+		// Valuations price the token against SOL; a stablecoin is its own USD value
+		fmt.Printf(tr("Value Out: $%.2f\n"), quote)
+	} else if currency == CURRENCY_USD {
+		if pool, err := loadPool(ctx, client, poolAddress); err != nil {
+			fmt.Printf(tr("Warning: Could not value quote: %v\n"), err)
+		} else if valuation, err := newValuation(ctx, oracle, currency, pool); err != nil {
+			fmt.Printf(tr("Warning: Could not value quote: %v\n"), err)
+		} else {
+			fmt.Printf(tr("Value In: %s\n"), valuation.Format(valuation.Value(amount, getInputToken(side))))
+			fmt.Printf(tr("Value Out: %s\n"), valuation.Format(valuation.Value(quote, getOutputToken(side))))
+		}
+	}
 	fmt.Printf("====================\n")
 
-	// If execute flag is set, proceed with swap execution
-	if execute {
-		// Confirm the quote with the user
-		if !confirmQuote(poolAddress, side, amount, quote) {
-			fmt.Println("\nSwap cancelled by user.")
-			return
+	// Disabled, unopened or drained pools are refused before anything is signed
+	if err := checkPoolFreshness(ctx, client, poolAddress, maxPoolIdle); err != nil {
+		if execute || dryRun || unsigned {
+			log.Fatalf(tr("Refusing to trade: %v"), err)
 		}
+		fmt.Printf(tr("⚠️  Warning: %v\n"), err)
+	}
 
-		// Get slippage tolerance
-		slippage, err := getSlippageFromUser()
-		if err != nil {
-			log.Fatalf("Failed to get slippage: %v", err)
+	// Buys of tokens that cannot be sold back are refused before anything is signed
+	if side == "buy" && !skipHoneypotCheck && !owner.IsZero() && (execute || dryRun || unsigned) {
+		fmt.Print(tr("Simulating a sell after the buy (honeypot check)...\n"))
+		if err := checkHoneypot(ctx, client, swapOptions.Delegation.holder(owner), poolAddress, amount, swapOptions); errors.Is(err, ErrHoneypot) {
+			log.Fatalf(tr("Refusing to buy: %v; pass -skip-honeypot-check to buy anyway"), err)
+		} else if err != nil {
+			fmt.Printf(tr("⚠️  Warning: honeypot check inconclusive: %v\n"), err)
 		}
+	}
 
-		// Get pool data to determine correct output decimals
-		poolPubkey, _ := solana.PublicKeyFromBase58(poolAddress)
-		accountInfo, err := client.GetAccountInfo(ctx, poolPubkey)
-		if err != nil {
-			log.Fatalf("Failed to get pool account: %v", err)
+	if swapOptions.MEV.Enabled {
+		warnSandwiching(ctx, client, poolAddress)
+	}
+
+	guardedTrade := newGuardedTrade(tokenMintKey, side, amount, quote, swapOptions.Burn)
+	if guard != nil && exportPath == "" {
+		if !stableOut.IsZero() {
+			solPrice, err := oracle.SOLPriceUSD(ctx)
+			if err != nil {
+				log.Fatalf(tr("Spend limits: %v"), err)
+			}
+			guardedTrade.SOL = quote / solPrice
 		}
+		if err := guard.Enforce(ctx, guardedTrade, true); err != nil {
+			log.Fatalf(tr("Spend limits: %v"), err)
+		}
+	}
 
-		pool, err := parsePoolAccount(poolPubkey, accountInfo.Value.Data.GetBinary())
-		if err != nil {
-			log.Fatalf("Failed to parse pool data: %v", err)
+	// Every exit below that does not record the trade releases its reservation
+	if err := func() error {
+		defer guard.Release(guardedTrade)
+
+		// Order book venues may fill better than the AMM for the same size; they
+		// quote in SOL, so stablecoin sells are not compared
+		if tokenAddr != "" && stableOut.IsZero() {
+			mint, _ := solana.PublicKeyFromBase58(tokenAddr)
+			venues := findVenueQuotes(ctx, client, mint, side, amount)
+			for _, venue := range venues {
+				fmt.Printf(tr("%s %s Expected Out: %.9f\n"), venue.Venue, venue.Market, venue.ExpectedOut)
+			}
+
+			best := bestVenueQuote(venues, quote)
+			if best != nil {
+				fmt.Printf(tr("%s fills better than the AMM (%.9f vs %.9f)\n"), best.Venue, best.ExpectedOut, quote)
+			}
+
+			// Venue swaps cannot burn, so buybacks stay on the AMM
+			if best != nil && (execute || dryRun) && exportPath == "" && multisigAddr == "" && !swapOptions.Delegation.Enabled() && !(swapOptions.Burn.Enabled() && side == "buy") {
+				if execute && !dryRun && !confirmQuote(best.Market.String(), side, amount, best.ExpectedOut, symbol, swapOptions) {
+					fmt.Println(tr("\nSwap cancelled by user."))
+					return nil
+				}
+				if execute && !dryRun && !confirmDuplicateTrade(best.Market.String(), side, amount, duplicateWindow) {
+					fmt.Println(tr("\nSwap cancelled by user."))
+					return nil
+				}
+
+				slippage, err := resolveSlippage(ctx, client, slippageArg, poolAddress, side, amount)
+				if err != nil {
+					return fmt.Errorf(tr("Failed to get slippage: %v"), err)
+				}
+
+				txHash, err := executeVenueSwap(ctx, client, wallet, best, slippage, dryRun, swapOptions)
+				if err != nil {
+					if !dryRun {
+						notifySwap(ctx, notifier, SwapNotification{Pool: best.Market.String(), Side: side, Amount: amount, Error: err.Error()})
+					}
+					return fmt.Errorf(tr("%s swap failed: %v"), best.Venue, err)
+				}
+				if !dryRun {
+					if err := guard.Record(newGuardedTrade(tokenMintKey, side, amount, best.ExpectedOut, swapOptions.Burn)); err != nil {
+						fmt.Printf(tr("Warning: Could not record trade for spend limits: %v\n"), err)
+					}
+					if err := recordRecentTrade(best.Market.String(), side, amount, txHash.String()); err != nil {
+						fmt.Printf(tr("Warning: Could not record trade for the duplicate check: %v\n"), err)
+					}
+					fmt.Printf(tr("\n✅ Swap executed successfully on %s!\n"), best.Venue)
+					fmt.Printf(tr("Transaction: %s\n"), txHash)
+					fmt.Printf(tr("Explorer: %s\n"), explorerTxURL(txHash.String()))
+					notifySwap(ctx, notifier, SwapNotification{TxHash: txHash.String(), Pool: best.Market.String(), Side: side, Amount: amount})
+				}
+				return nil
+			}
 		}
 
-		// Get decimals
-		pool.BaseDecimals, _ = getTokenDecimals(ctx, client, pool.BaseMint.String())
-		pool.QuoteDecimals, _ = getTokenDecimals(ctx, client, pool.QuoteMint.String())
+		// If execute, dry-run or export is requested, proceed with swap execution
+		if execute || dryRun || unsigned {
+			// Confirm the quote with the user; dry runs and exports never send, so no confirmation is needed
+			if execute && !dryRun && exportPath == "" && !confirmQuote(poolAddress, side, amount, quote, symbol, swapOptions) {
+				fmt.Println(tr("\nSwap cancelled by user."))
+				return nil
+			}
+			if execute && !dryRun && exportPath == "" && multisigAddr == "" && !confirmDuplicateTrade(poolAddress, side, amount, duplicateWindow) {
+				fmt.Println(tr("\nSwap cancelled by user."))
+				return nil
+			}
+
+			// Get slippage tolerance
+			slippage, err := resolveSlippage(ctx, client, slippageArg, poolAddress, side, amount)
+			if err != nil {
+				return fmt.Errorf(tr("Failed to get slippage: %v"), err)
+			}
 
-		// Fetch actual vault balances
-		_ = fetchVaultBalances(ctx, client, pool)
+			// Get pool data to determine correct output decimals
+			pool, err := loadPool(ctx, client, poolAddress)
+			if err != nil {
+				return fmt.Errorf(tr("Failed to load pool: %v"), err)
+			}
 
-		// Calculate minimum amount out with correct decimals
-		var outputDecimals int
-		isBaseSol := pool.BaseMint.Equals(WSOL_MINT) || pool.BaseMint.Equals(SOL_MINT)
+			// Calculate minimum amount out with correct decimals
+			var outputDecimals int
+			isBaseSol := isBaseCurrency(pool)
 
-		if side == "buy" {
-			// Buying token, output is token
+			tokenMint := pool.BaseMint
 			if isBaseSol {
-				outputDecimals = int(pool.QuoteDecimals)
+				tokenMint = pool.QuoteMint
+			}
+
+			if side == "buy" {
+				// Buying token, output is token
+				if isBaseSol {
+					outputDecimals = int(pool.QuoteDecimals)
+				} else {
+					outputDecimals = int(pool.BaseDecimals)
+				}
 			} else {
-				outputDecimals = int(pool.BaseDecimals)
+				// Selling token, output is SOL or the pool's stablecoin
+				outputDecimals = currencyDecimals(pool)
 			}
-		} else {
-			// Selling token, output is SOL
-			outputDecimals = SOL_DECIMALS
-		}
 
-		minAmountOut := calculateMinAmountOut(quote, slippage, outputDecimals)
+			if tightened := swapOptions.MEV.tightenSlippage(slippage); tightened != slippage {
+				fmt.Printf(tr("Anti-MEV: slippage capped at %.2f%%\n"), tightened)
+				slippage = tightened
+			}
 
-		fmt.Printf("\n=== SWAP PARAMETERS ===\n")
-		fmt.Printf("Slippage Tolerance: %.2f%%\n", slippage)
-		fmt.Printf("Expected Out: %.9f\n", quote)
-		fmt.Printf("Minimum Out: %.9f\n", float64(minAmountOut)/math.Pow(10, float64(outputDecimals)))
-		fmt.Printf("======================\n")
+			minAmountOut := calculateMinAmountOut(quoteRaw, slippage)
+			priceImpact := quotedPriceImpact(pool, side, amount)
 
-		// Execute the swap
-		txHash, err := executeSwap(ctx, client, wallet, poolAddress, side, amount, minAmountOut)
-		if err != nil {
-			log.Fatalf("Swap failed: %v", err)
-		}
+			fmt.Print(tr("\n=== SWAP PARAMETERS ===\n"))
+			fmt.Printf(tr("Slippage Tolerance: %.2f%%\n"), slippage)
+			fmt.Printf(tr("Price Impact: %.4f%%\n"), priceImpact)
+			fmt.Printf(tr("Expected Out: %.9f\n"), quote)
+			fmt.Printf(tr("Minimum Out: %s\n"), atoms.Format(minAmountOut, outputDecimals))
+			fmt.Printf("======================\n")
 
-		fmt.Printf("\n✅ Swap executed successfully!\n")
-		fmt.Printf("Transaction: %s\n", txHash)
+			if exportPath != "" {
+				tx, err := buildSwapTransaction(ctx, client, owner, poolAddress, side, amount, minAmountOut, swapOptions)
+				if err != nil {
+					return fmt.Errorf(tr("Failed to build transaction: %v"), err)
+				}
+				if err := exportTransaction(tx, exportPath, exportEncoding); err != nil {
+					return fmt.Errorf(tr("Export failed: %v"), err)
+				}
+				fmt.Printf(tr("\nUnsigned transaction written to %s (%s)\n"), exportPath, exportEncoding)
+				fmt.Printf(tr("Blockhash expires in ~60-90 seconds; sign and run `broadcast -file %s` promptly\n"), exportPath)
+				return nil
+			}
 
-		// Wait a moment for transaction to be fully confirmed
-		fmt.Println("\nFetching transaction details...")
-		time.Sleep(2 * time.Second)
+			if solanaPayURL != "" {
+				request := &SolanaPaySwap{
+					Pool:         poolAddress,
+					Side:         side,
+					Amount:       amount,
+					MinAmountOut: minAmountOut,
+					Label:        DEFAULT_PROGRAM_NAME,
+					Message:      fmt.Sprintf(tr("%s %.9f on %s, minimum out %s"), strings.ToUpper(side), amount, poolAddress, atoms.Format(minAmountOut, outputDecimals)),
+				}
+				if err := serveSolanaPay(ctx, client, request, solanaPayURL, solanaPayListen, solanaPayQR, swapOptions); err != nil {
+					return fmt.Errorf(tr("Solana Pay failed: %v"), err)
+				}
+				return nil
+			}
 
-		// Generate and display transaction report
-		report, err := generateReport(ctx, client, wallet.PublicKey(), txHash, side, amount, quote, slippage)
-		if err != nil {
-			fmt.Printf("Warning: Could not generate full report: %v\n", err)
-			fmt.Printf("Explorer: https://solscan.io/tx/%s\n", txHash)
-		} else {
-			printReport(report)
+			if dryRun {
+				if err := dryRunSwap(ctx, client, wallet, poolAddress, side, amount, minAmountOut, swapOptions); err != nil {
+					return fmt.Errorf(tr("Dry run failed: %v"), err)
+				}
+				return nil
+			}
+
+			if multisigAddr != "" {
+				txHash, err := proposeMultisigSwap(ctx, client, wallet, multisig, uint8(vaultIndex), poolAddress, side, amount, minAmountOut, swapOptions)
+				if err != nil {
+					return fmt.Errorf(tr("Multisig proposal failed: %v"), err)
+				}
+				fmt.Print(tr("\n✅ Swap proposed to multisig!\n"))
+				fmt.Printf(tr("Transaction: %s\n"), txHash)
+				fmt.Println(tr("Remaining members must approve and execute the proposal in Squads"))
+				return nil
+			}
+
+			if delay := obfuscation.randomDelay(); delay > 0 {
+				fmt.Printf(tr("Waiting %s before sending (send jitter)...\n"), delay.Round(time.Millisecond))
+				time.Sleep(delay)
+			}
+
+			// Reserves may have moved while the user confirmed or during send jitter
+			requoted, err := requoteBeforeSend(ctx, client, pool, side, amount, quoteRaw, slippage)
+			if err != nil {
+				return fmt.Errorf(tr("Swap aborted: %v"), err)
+			}
+			if requoted != quoteRaw {
+				quoteRaw, quote = requoted, atoms.ToAmount(requoted, outputDecimals)
+				minAmountOut = calculateMinAmountOut(quoteRaw, slippage)
+				fmt.Printf(tr("New Minimum Out: %s\n"), atoms.Format(minAmountOut, outputDecimals))
+			}
+
+			// Execute the swap
+			txHash, err := executeSwap(ctx, client, wallet, poolAddress, side, amount, minAmountOut, swapOptions)
+			notification := SwapNotification{TxHash: txHash, Pool: poolAddress, Side: side, Amount: amount}
+			if err != nil {
+				notification.Error = err.Error()
+				notifySwap(ctx, notifier, notification)
+				return fmt.Errorf(tr("Swap failed: %v"), err)
+			}
+
+			if err := guard.Record(guardedTrade); err != nil {
+				fmt.Printf(tr("Warning: Could not record trade for spend limits: %v\n"), err)
+			}
+			if err := recordRecentTrade(poolAddress, side, amount, txHash); err != nil {
+				fmt.Printf(tr("Warning: Could not record trade for the duplicate check: %v\n"), err)
+			}
+			fmt.Print(tr("\n✅ Swap executed successfully!\n"))
+			fmt.Printf(tr("Transaction: %s\n"), txHash)
+
+			// Wait a moment for transaction to be fully confirmed
+			fmt.Println(tr("\nFetching transaction details..."))
+			time.Sleep(2 * time.Second)
+
+			// Generate and display transaction report
+			report, err := generateReport(ctx, client, wallet.PublicKey(), txHash, pool, tokenMint, side, amount, quote, slippage, priceImpact, minAmountOut, swapOptions)
+			if err != nil {
+				fmt.Printf(tr("Warning: Could not generate full report: %v\n"), err)
+				fmt.Printf(tr("Explorer: %s\n"), explorerTxURL(txHash))
+			} else {
+				if !stableOut.IsZero() {
+					// A stablecoin leg is its own USD value
+					report.ValueUSD = report.AmountOut
+				} else if reportFormat == "csv" || currency == CURRENCY_USD {
+					// Value the SOL leg of the trade at execution time
+					solAmount := report.AmountIn
+					if side == "sell" {
+						solAmount = report.AmountOut
+					}
+					solPrice, err := oracle.SOLPriceUSD(ctx)
+					if err != nil {
+						fmt.Printf(tr("Warning: Could not fetch SOL/USD price: %v\n"), err)
+					} else {
+						report.ValueUSD = solAmount * solPrice
+					}
+				}
+
+				printReport(report)
+				recordSwapReceipt(wallet, report)
+				// Valuations price the token against SOL, which stablecoin pools do not hold
+				if stableOut.IsZero() {
+					if valuation, err := newValuation(ctx, oracle, currency, pool); err != nil {
+						fmt.Printf(tr("Warning: Could not value report: %v\n"), err)
+					} else {
+						printValuation(valuation, report)
+					}
+				}
+
+				if reportFormat == "csv" {
+					if err := appendReportCSV(reportFile, report); err != nil {
+						fmt.Printf(tr("Warning: Could not write CSV report: %v\n"), err)
+					} else {
+						fmt.Printf(tr("Report appended to %s\n"), reportFile)
+					}
+				}
+				notification.Report = report
+			}
+			notifySwap(ctx, notifier, notification)
 		}
+		return nil
+	}(); err != nil {
+		log.Fatal(err)
 	}
 }
 
-// findPoolsOnChain uses getProgramAccounts to find all pools for a token
-func findPoolsOnChain(ctx context.Context, client *rpc.Client, tokenAddress string) (*OnChainPool, error) {
-	tokenPubkey, err := solana.PublicKeyFromBase58(tokenAddress)
+// findPoolsOnChain uses getProgramAccounts to find the most liquid pool for a
+// token, for pricing. Trades should use findPoolForTrade.
+func findPoolsOnChain(ctx context.Context, client ChainClient, tokenAddress string) (*OnChainPool, error) {
+	pools, err := discoverPoolsOnChain(ctx, client, tokenAddress)
 	if err != nil {
-		return nil, fmt.Errorf("invalid token address: %w", err)
+		return nil, err
+	}
+
+	bestPool := selectBestPool(pools)
+	if bestPool == nil {
+		return nil, fmt.Errorf("no pools with liquidity found for token %s", tokenAddress)
 	}
 
-	fmt.Println("Searching for pools on-chain using getProgramAccounts...")
-	fmt.Println("This may take 10-30 seconds...")
+	return bestPool, nil
+}
 
-	// Create filters to find pools containing our token
-	// We'll search for pools where either baseMint or quoteMint matches our token
-	filters := []rpc.RPCFilter{
-		{
-			DataSize: 752, // Raydium V4 pool account size
-		},
+// findPoolForTrade uses getProgramAccounts to find the pool giving the most
+// output for a swap of amount
+func findPoolForTrade(ctx context.Context, client ChainClient, tokenAddress string, side string, amount float64) (*OnChainPool, error) {
+	pools, err := discoverPoolsOnChain(ctx, client, tokenAddress)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get all Raydium V4 accounts
-	accounts, err := client.GetProgramAccountsWithOpts(
-		ctx,
-		RAYDIUM_AMM_V4,
-		&rpc.GetProgramAccountsOpts{
-			Filters: filters,
-		},
-	)
+	bestPool := selectPoolForTrade(pools, side, amount)
+	if bestPool == nil {
+		return nil, fmt.Errorf("no pools with liquidity found for token %s", tokenAddress)
+	}
+
+	return bestPool, nil
+}
+
+// discoverPoolsOnChain uses getProgramAccounts to find all SOL-paired pools for a token
+func discoverPoolsOnChain(ctx context.Context, client ChainClient, tokenAddress string) ([]*OnChainPool, error) {
+	return discoverPairedPools(ctx, client, tokenAddress, []solana.PublicKey{WSOL_MINT, SOL_MINT})
+}
+
+// discoverPairedPools uses getProgramAccounts to find all pools pairing a
+// token with one of the currencies, in a single scan
+func discoverPairedPools(ctx context.Context, client ChainClient, tokenAddress string, currencies []solana.PublicKey) ([]*OnChainPool, error) {
+	tokenPubkey, err := solana.PublicKeyFromBase58(tokenAddress)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get program accounts: %w", err)
+		return nil, fmt.Errorf("invalid token address: %w", err)
 	}
 
-	fmt.Printf("Found %d Raydium V4 accounts, filtering for token %s...\n", len(accounts), tokenAddress)
+	accounts, err := scanPoolAccounts(ctx, client, tokenPubkey)
+	if err != nil {
+		return nil, err
+	}
 
-	var pools []*OnChainPool
+	var candidates []*OnChainPool
+	var found []solana.PublicKey
 	for _, account := range accounts {
 		pool, err := parsePoolAccount(account.Pubkey, account.Account.Data.GetBinary())
 		if err != nil {
 			continue // Skip invalid pools
 		}
 
-		// Check if this pool contains our token paired with SOL/WSOL
+		// Check if this pool contains our token paired with one of the currencies
 		hasOurToken := pool.BaseMint.Equals(tokenPubkey) || pool.QuoteMint.Equals(tokenPubkey)
-		hasSol := pool.BaseMint.Equals(WSOL_MINT) || pool.QuoteMint.Equals(WSOL_MINT) ||
-			pool.BaseMint.Equals(SOL_MINT) || pool.QuoteMint.Equals(SOL_MINT)
-
-		if hasOurToken && hasSol {
-			// Get decimals for the tokens
-			pool.BaseDecimals, err = getTokenDecimals(ctx, client, pool.BaseMint.String())
-			if err != nil {
-				fmt.Printf("Warning: Failed to get base decimals for pool %s: %v\n", account.Pubkey, err)
-				continue
-			}
-			pool.QuoteDecimals, err = getTokenDecimals(ctx, client, pool.QuoteMint.String())
-			if err != nil {
-				fmt.Printf("Warning: Failed to get quote decimals for pool %s: %v\n", account.Pubkey, err)
-				continue
-			}
+		hasCurrency := slices.ContainsFunc(currencies, func(mint solana.PublicKey) bool {
+			return pool.BaseMint.Equals(mint) || pool.QuoteMint.Equals(mint)
+		})
 
-			// Fetch actual vault balances
-			err = fetchVaultBalances(ctx, client, pool)
-			if err != nil {
-				fmt.Printf("Warning: Failed to fetch vault balances for pool %s: %v\n", account.Pubkey, err)
-				continue
-			}
-
-			pools = append(pools, pool)
+		if hasOurToken {
+			found = append(found, pool.Address)
 		}
+		if hasOurToken && hasCurrency {
+			candidates = append(candidates, pool)
+		}
+	}
+	if err := rememberPools(tokenPubkey, found); err != nil {
+		fmt.Printf("Warning: Could not remember the pools found: %v\n", err)
 	}
 
+	// Decimals and vault balances are loaded in batches rather than per pool
+	pools := enrichPools(ctx, client, candidates)
+
 	if len(pools) == 0 {
-		return nil, fmt.Errorf("no pools found for token %s paired with SOL/WSOL", tokenAddress)
+		return nil, fmt.Errorf("no pools found for token %s paired with %s", tokenAddress, currencyNames(currencies))
+	}
+
+	if mint, err := solana.PublicKeyFromBase58(tokenAddress); err == nil {
+		fmt.Printf("Found %d pools for token %s\n", len(pools), tokenDisplayName(ctx, client, mint))
+	} else {
+		fmt.Printf("Found %d pools for token %s\n", len(pools), tokenAddress)
 	}
 
-	fmt.Printf("Found %d pools for token %s\n", len(pools), tokenAddress)
+	return pools, nil
+}
+
+// solReserves returns the raw SOL-side reserves of a SOL-paired pool
+func solReserves(pool *OnChainPool) uint64 {
+	if pool.BaseMint.Equals(WSOL_MINT) || pool.BaseMint.Equals(SOL_MINT) {
+		return pool.BaseAmount
+	}
+	return pool.QuoteAmount
+}
 
-	// Select pool with highest liquidity (approximated by reserves)
+// selectBestPool picks the pool with highest liquidity (approximated by SOL reserves)
+func selectBestPool(pools []*OnChainPool) *OnChainPool {
 	var bestPool *OnChainPool
 	var maxLiquidity uint64
 
 	for _, pool := range pools {
-		// Approximate liquidity by the SOL reserves
-		var solReserves uint64
-		if pool.BaseMint.Equals(WSOL_MINT) || pool.BaseMint.Equals(SOL_MINT) {
-			solReserves = pool.BaseAmount
-		} else {
-			solReserves = pool.QuoteAmount
+		if reserves := solReserves(pool); reserves > maxLiquidity {
+			maxLiquidity = reserves
+			bestPool = pool
 		}
+	}
+
+	return bestPool
+}
+
+// selectPoolForTrade quotes the swap against every pool and picks the one
+// with the highest output net of its fee, breaking ties by SOL reserves. A
+// deep pool with a higher fee tier or a worse price loses to one that pays
+// more. Pools that cannot be traded are skipped.
+func selectPoolForTrade(pools []*OnChainPool, side string, amount float64) *OnChainPool {
+	var bestPool *OnChainPool
+	var bestOut uint64
 
-		if solReserves > maxLiquidity {
-			maxLiquidity = solReserves
+	for _, pool := range pools {
+		out := poolNetOutput(pool, side, amount)
+		if out == 0 {
+			continue
+		}
+		if out > bestOut || (out == bestOut && solReserves(pool) > solReserves(bestPool)) {
+			bestOut = out
 			bestPool = pool
 		}
 	}
 
-	return bestPool, nil
+	return bestPool
+}
+
+// poolNetOutput returns the raw output of a swap of amount against the
+// pool's loaded reserves, after the pool's fee; 0 when it cannot be quoted
+func poolNetOutput(pool *OnChainPool, side string, amount float64) uint64 {
+	if pool.checkTradable(time.Now()) != nil {
+		return 0
+	}
+	sourceMint, _, inputDecimals := swapMints(pool, side)
+	amountIn, err := atoms.FromAmount(amount, inputDecimals)
+	if err != nil {
+		return 0
+	}
+	amountOut, _ := raydiumSwapBaseIn(pool, amountIn, sourceMint.Equals(pool.BaseMint))
+	return amountOut
+}
+
+// checkPoolProgram refuses pools of other programs, whose accounts would parse
+// as garbage. Stable pools are named, since pricing them as constant product
+// would badly misquote correlated pairs: Saber pools are marked with
+// ErrStableSwapPool for the stable quote, Raydium's stable AMM prices from an
+// on-chain lookup table that is not modelled and is refused.
+func checkPoolProgram(owner solana.PublicKey) error {
+	switch {
+	case owner.Equals(RAYDIUM_AMM_V4):
+		return nil
+	case owner.Equals(SABER_STABLE_SWAP):
+		return fmt.Errorf("%w: Saber pools can be quoted but not swapped through; use a Raydium V4 pool", ErrStableSwapPool)
+	case owner.Equals(RAYDIUM_STABLE_AMM):
+		return fmt.Errorf("%w v5 (Raydium stable AMM): its StableSwap curve is not supported; use a Raydium V4 pool", ErrUnsupportedPoolLayout)
+	}
+	return fmt.Errorf("not a Raydium V4 pool: the account is owned by %s", owner)
 }
 
 // parsePoolAccount parses the raw pool account data
 func parsePoolAccount(address solana.PublicKey, data []byte) (*OnChainPool, error) {
-	if len(data) < 752 {
-		return nil, fmt.Errorf("invalid pool data size: %d", len(data))
+	layout, err := detectPoolLayout(data)
+	if err != nil {
+		return nil, err
+	}
+	// Only initialized pools are checked to hold a swap fee
+	if layout.u64(data, layout.Status) == AMM_STATUS_UNINITIALIZED {
+		return nil, fmt.Errorf("pool %s is not initialized", address)
 	}
 
 	pool := &OnChainPool{
 		Address: address,
 	}
 
-	// Raydium V4 AMM pool layout - verified working offsets
+	// Fields are read at the offsets of the detected layout
+	pool.Status = layout.u64(data, layout.Status)
+	pool.Nonce = data[layout.Nonce]
 
-	// offset 8: nonce (1 byte within status u64)
-	pool.Nonce = data[8]
+	pool.BaseVault = layout.key(data, layout.BaseVault)         // coin_vault
+	pool.QuoteVault = layout.key(data, layout.QuoteVault)       // pc_vault
+	pool.BaseMint = layout.key(data, layout.BaseMint)           // coin_mint
+	pool.QuoteMint = layout.key(data, layout.QuoteMint)         // pc_mint
+	pool.LpMint = layout.key(data, layout.LpMint)               // lp_mint
+	pool.OpenOrders = layout.key(data, layout.OpenOrders)       // open_orders
+	pool.Market = layout.key(data, layout.Market)               // market
+	pool.MarketProgram = layout.key(data, layout.MarketProgram) // market_program
+	pool.TargetOrders = layout.key(data, layout.TargetOrders)   // target_orders
 
-	// PublicKey fields start at offset 336
-	pool.BaseVault = solana.PublicKeyFromBytes(data[336:368])     // coin_vault
-	pool.QuoteVault = solana.PublicKeyFromBytes(data[368:400])    // pc_vault
-	pool.BaseMint = solana.PublicKeyFromBytes(data[400:432])      // coin_mint
-	pool.QuoteMint = solana.PublicKeyFromBytes(data[432:464])     // pc_mint
-	pool.OpenOrders = solana.PublicKeyFromBytes(data[464:496])    // open_orders
-	pool.TargetOrders = solana.PublicKeyFromBytes(data[592:624])  // target_orders
-	pool.Market = solana.PublicKeyFromBytes(data[656:688])        // market
-	pool.MarketProgram = solana.PublicKeyFromBytes(data[688:720]) // market_program
+	pool.NeedTakePnlBase = layout.u64(data, layout.NeedTakePnlBase)
+	pool.NeedTakePnlQuote = layout.u64(data, layout.NeedTakePnlQuote)
+	pool.SwapFeeNumerator = layout.u64(data, layout.SwapFeeNumerator)
+	pool.SwapFeeDenominator = layout.u64(data, layout.SwapFeeDenominator)
+	pool.OpenTime = layout.u64(data, layout.OpenTime)
+	pool.LpAmount = layout.u64(data, layout.LpAmount)
 
 	// Get pool amounts - these need to be fetched from vault accounts
 	// Initialize to 0, will be populated by fetchVaultBalances
@@ -1139,8 +2536,75 @@ func parsePoolAccount(address solana.PublicKey, data []byte) (*OnChainPool, erro
 	return pool, nil
 }
 
+// poolTokenMint returns the non-SOL mint of a pool, using tokenAddress when
+// it is already known
+func poolTokenMint(ctx context.Context, client ChainClient, tokenAddress string, poolAddress string) (solana.PublicKey, error) {
+	if tokenAddress != "" {
+		return solana.PublicKeyFromBase58(tokenAddress)
+	}
+
+	poolPubkey, err := solana.PublicKeyFromBase58(poolAddress)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("invalid pool address: %w", err)
+	}
+	accountInfo, err := client.GetAccountInfo(ctx, poolPubkey)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to get pool account: %w", err)
+	}
+	pool, err := parsePoolAccount(poolPubkey, accountInfo.Value.Data.GetBinary())
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to parse pool data: %w", err)
+	}
+	if isBaseCurrency(pool) {
+		return pool.QuoteMint, nil
+	}
+	return pool.BaseMint, nil
+}
+
+// loadPool fetches and parses a pool account, including decimals and vault
+// balances. It is the one on-chain pool resolver: the vaults the pool names
+// are checked against the vault accounts before anything is read from them.
+func loadPool(ctx context.Context, client ChainClient, poolAddress string) (*OnChainPool, error) {
+	poolPubkey, err := solana.PublicKeyFromBase58(poolAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool address: %w", err)
+	}
+
+	accountInfo, err := client.GetAccountInfo(ctx, poolPubkey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool account: %w", err)
+	}
+	if err := checkPoolProgram(accountInfo.Value.Owner); err != nil {
+		return nil, err
+	}
+
+	pool, err := parsePoolAccount(poolPubkey, accountInfo.Value.Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pool data: %w", err)
+	}
+	if err := checkPoolVaults(ctx, client, pool); err != nil {
+		return nil, err
+	}
+
+	pool.BaseDecimals, err = getTokenDecimals(ctx, client, pool.BaseMint.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base decimals: %w", err)
+	}
+	pool.QuoteDecimals, err = getTokenDecimals(ctx, client, pool.QuoteMint.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quote decimals: %w", err)
+	}
+
+	err = fetchVaultBalances(ctx, client, pool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch vault balances: %w", err)
+	}
+
+	return pool, nil
+}
+
 // fetchMarketData fetches the OpenBook/Serum market data
-func fetchMarketData(ctx context.Context, client *rpc.Client, pool *OnChainPool) error {
+func fetchMarketData(ctx context.Context, client ChainClient, pool *OnChainPool) error {
 	// Check if market is zero (some pools don't have external markets)
 	if pool.Market.IsZero() {
 		// Use pool vaults as market vaults for pools without external market
@@ -1161,7 +2625,7 @@ func fetchMarketData(ctx context.Context, client *rpc.Client, pool *OnChainPool)
 	}
 
 	marketData := marketInfo.Value.Data.GetBinary()
-	if len(marketData) < 388 { // Minimum size for OpenBook market
+	if len(marketData) < MARKET_MIN_SIZE {
 		// This might be a different type of market or invalid
 		// Use pool vaults as fallback
 		pool.MarketBaseVault = pool.BaseVault
@@ -1173,23 +2637,28 @@ func fetchMarketData(ctx context.Context, client *rpc.Client, pool *OnChainPool)
 		return nil
 	}
 
-	// Parse market data (OpenBook/Serum V3 layout)
-	pool.MarketBaseVault = solana.PublicKeyFromBytes(marketData[84:116])   // Base vault
-	pool.MarketQuoteVault = solana.PublicKeyFromBytes(marketData[116:148]) // Quote vault
-	pool.MarketBids = solana.PublicKeyFromBytes(marketData[316:348])       // Bids
-	pool.MarketAsks = solana.PublicKeyFromBytes(marketData[348:380])       // Asks
-	pool.MarketEventQueue = solana.PublicKeyFromBytes(marketData[252:284]) // Event queue
-
-	// Get vault signer nonce (at offset 45 in Serum V3)
-	if len(marketData) > 45 {
-		pool.MarketNonce = marketData[45]
+	// The market records its own address; anything else is not the pool's market
+	ownAddress := solana.PublicKeyFromBytes(marketData[MARKET_OWN_ADDRESS_OFFSET : MARKET_OWN_ADDRESS_OFFSET+32])
+	if !ownAddress.Equals(pool.Market) {
+		return fmt.Errorf("market account %s records address %s", pool.Market, ownAddress)
 	}
+	if err := checkPoolMarket(pool, marketInfo.Value.Owner, marketData); err != nil {
+		return err
+	}
+
+	// Parse market data (OpenBook/Serum V3 layout)
+	pool.MarketNonce = binary.LittleEndian.Uint64(marketData[MARKET_VAULT_SIGNER_NONCE_OFFSET:])
+	pool.MarketBaseVault = solana.PublicKeyFromBytes(marketData[MARKET_BASE_VAULT_OFFSET : MARKET_BASE_VAULT_OFFSET+32])
+	pool.MarketQuoteVault = solana.PublicKeyFromBytes(marketData[MARKET_QUOTE_VAULT_OFFSET : MARKET_QUOTE_VAULT_OFFSET+32])
+	pool.MarketEventQueue = solana.PublicKeyFromBytes(marketData[MARKET_EVENT_QUEUE_OFFSET : MARKET_EVENT_QUEUE_OFFSET+32])
+	pool.MarketBids = solana.PublicKeyFromBytes(marketData[MARKET_BIDS_OFFSET : MARKET_BIDS_OFFSET+32])
+	pool.MarketAsks = solana.PublicKeyFromBytes(marketData[MARKET_ASKS_OFFSET : MARKET_ASKS_OFFSET+32])
 
 	return nil
 }
 
 // getTokenDecimals fetches the decimals for a token from on-chain
-func getTokenDecimals(ctx context.Context, client *rpc.Client, mintAddress string) (uint8, error) {
+func getTokenDecimals(ctx context.Context, client ChainClient, mintAddress string) (uint8, error) {
 	// SOL/WSOL always has 9 decimals
 	if mintAddress == WSOL_MINT.String() || mintAddress == SOL_MINT.String() {
 		return SOL_DECIMALS, nil
@@ -1218,15 +2687,20 @@ func getTokenDecimals(ctx context.Context, client *rpc.Client, mintAddress strin
 }
 
 // fetchVaultBalances fetches the actual token balances from vault accounts
-func fetchVaultBalances(ctx context.Context, client *rpc.Client, pool *OnChainPool) error {
+func fetchVaultBalances(ctx context.Context, client ChainClient, pool *OnChainPool) error {
+	return fetchVaultBalancesAt(ctx, client, pool, rpc.CommitmentFinalized)
+}
+
+// fetchVaultBalancesAt loads the vault balances at the given commitment
+func fetchVaultBalancesAt(ctx context.Context, client ChainClient, pool *OnChainPool, commitment rpc.CommitmentType) error {
 	// Get base vault balance
-	baseVaultInfo, err := client.GetTokenAccountBalance(ctx, pool.BaseVault, rpc.CommitmentFinalized)
+	baseVaultInfo, err := client.GetTokenAccountBalance(ctx, pool.BaseVault, commitment)
 	if err != nil {
 		return fmt.Errorf("failed to get base vault balance: %w", err)
 	}
 
 	// Get quote vault balance
-	quoteVaultInfo, err := client.GetTokenAccountBalance(ctx, pool.QuoteVault, rpc.CommitmentFinalized)
+	quoteVaultInfo, err := client.GetTokenAccountBalance(ctx, pool.QuoteVault, commitment)
 	if err != nil {
 		return fmt.Errorf("failed to get quote vault balance: %w", err)
 	}
@@ -1245,22 +2719,16 @@ func fetchVaultBalances(ctx context.Context, client *rpc.Client, pool *OnChainPo
 	return nil
 }
 
-func calculateQuoteOnChain(ctx context.Context, client *rpc.Client, params QuoteParams) (float64, error) {
-	poolPubkey, err := solana.PublicKeyFromBase58(params.PoolAddress)
-	if err != nil {
-		return 0, fmt.Errorf("invalid pool address: %w", err)
+// calculateQuoteOnChain quotes a swap against the pool's on-chain reserves,
+// on the stable invariant for Saber pools. It returns the raw output and the
+// output token's decimals.
+func calculateQuoteOnChain(ctx context.Context, client ChainClient, params QuoteParams) (uint64, int, error) {
+	pool, err := loadPool(ctx, client, params.PoolAddress)
+	if errors.Is(err, ErrStableSwapPool) {
+		return calculateStableQuote(ctx, client, params)
 	}
-
-	// Fetch pool account info
-	accountInfo, err := client.GetAccountInfo(ctx, poolPubkey)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get pool account: %w", err)
-	}
-
-	// Parse pool data
-	pool, err := parsePoolAccount(poolPubkey, accountInfo.Value.Data.GetBinary())
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse pool data: %w", err)
+		return 0, 0, err
 	}
 
 	// Debug mints
@@ -1268,26 +2736,10 @@ func calculateQuoteOnChain(ctx context.Context, client *rpc.Client, params Quote
 	fmt.Printf("BaseMint: %s\n", pool.BaseMint)
 	fmt.Printf("QuoteMint: %s\n", pool.QuoteMint)
 
-	// Get decimals
-	pool.BaseDecimals, err = getTokenDecimals(ctx, client, pool.BaseMint.String())
-	if err != nil {
-		return 0, fmt.Errorf("failed to get base decimals: %w", err)
-	}
-	pool.QuoteDecimals, err = getTokenDecimals(ctx, client, pool.QuoteMint.String())
-	if err != nil {
-		return 0, fmt.Errorf("failed to get quote decimals: %w", err)
-	}
-
-	// Fetch actual vault balances
-	err = fetchVaultBalances(ctx, client, pool)
-	if err != nil {
-		return 0, fmt.Errorf("failed to fetch vault balances: %w", err)
-	}
-
 	fmt.Printf("\n=== Pool Information (On-Chain) ===\n")
 	fmt.Printf("Pool Address: %s\n", pool.Address)
-	fmt.Printf("Base Token: %s (decimals: %d)\n", pool.BaseMint, pool.BaseDecimals)
-	fmt.Printf("Quote Token: %s (decimals: %d)\n", pool.QuoteMint, pool.QuoteDecimals)
+	fmt.Printf("Base Token: %s %s (decimals: %d)\n", tokenSymbol(ctx, client, pool.BaseMint), pool.BaseMint, pool.BaseDecimals)
+	fmt.Printf("Quote Token: %s %s (decimals: %d)\n", tokenSymbol(ctx, client, pool.QuoteMint), pool.QuoteMint, pool.QuoteDecimals)
 	fmt.Printf("Base Amount (raw): %d\n", pool.BaseAmount)
 	fmt.Printf("Quote Amount (raw): %d\n", pool.QuoteAmount)
 
@@ -1301,12 +2753,12 @@ func calculateQuoteOnChain(ctx context.Context, client *rpc.Client, params Quote
 	var inputDecimals, outputDecimals int
 	var isBaseToQuote bool
 
-	// Check if base or quote is SOL/WSOL
-	isBaseSol := pool.BaseMint.Equals(WSOL_MINT) || pool.BaseMint.Equals(SOL_MINT)
+	// Check if base or quote is SOL/WSOL, or the stablecoin of a stable-paired pool
+	isBaseSol := isBaseCurrency(pool)
 
 	if params.Side == "buy" {
 		// Buying: SOL in -> Token out
-		inputDecimals = SOL_DECIMALS
+		inputDecimals = currencyDecimals(pool)
 		if isBaseSol {
 			// SOL is base, token is quote
 			outputDecimals = int(pool.QuoteDecimals)
@@ -1318,7 +2770,7 @@ func calculateQuoteOnChain(ctx context.Context, client *rpc.Client, params Quote
 		}
 	} else {
 		// Selling: Token in -> SOL out
-		outputDecimals = SOL_DECIMALS
+		outputDecimals = currencyDecimals(pool)
 		if isBaseSol {
 			// SOL is base, token is quote
 			inputDecimals = int(pool.QuoteDecimals)
@@ -1331,48 +2783,68 @@ func calculateQuoteOnChain(ctx context.Context, client *rpc.Client, params Quote
 	}
 
 	// Calculate quote using constant product formula
-	amountIn := uint64(params.Amount * math.Pow(10, float64(inputDecimals)))
-
-	var amountOut uint64
-	if isBaseToQuote {
-		// base -> quote swap
-		amountOut = calculateSwapAmount(pool.QuoteAmount, pool.BaseAmount, amountIn)
-	} else {
-		// quote -> base swap
-		amountOut = calculateSwapAmount(pool.BaseAmount, pool.QuoteAmount, amountIn)
+	amountIn, err := atoms.FromAmount(params.Amount, inputDecimals)
+	if err != nil {
+		return 0, 0, err
 	}
 
-	// Apply trading fee (0.25%)
-	fee := float64(amountOut) * 0.0025
-	amountOutAfterFee := float64(amountOut) - fee
-
-	// Convert back to decimal format
-	result := amountOutAfterFee / math.Pow(10, float64(outputDecimals))
+	amountOut, fee := raydiumSwapBaseIn(pool, amountIn, isBaseToQuote)
+	numerator, denominator := swapFeeRate(pool)
 
 	fmt.Printf("\n=== Calculation Details ===\n")
 	fmt.Printf("Amount in (raw): %d\n", amountIn)
+	fmt.Printf("Fee (%.2f%% of input): %d\n", float64(numerator)*100/float64(denominator), fee)
+	fmt.Printf("Amount in after fee: %d\n", amountIn-fee)
 	fmt.Printf("Amount out (raw): %d\n", amountOut)
-	fmt.Printf("Fee (0.25%%): %.0f\n", fee)
-	fmt.Printf("Amount out after fee: %.0f\n", amountOutAfterFee)
 
-	return result, nil
+	return amountOut, outputDecimals, nil
 }
 
-func calculateSwapAmount(reserveOut, reserveIn, amountIn uint64) uint64 {
-	// Constant product AMM formula: x * y = k
-	// amountOut = (reserveOut * amountIn) / (reserveIn + amountIn)
+// swapFeeRate returns the pool's swap fee as a fraction; parsePoolAccount
+// only accepts pools whose fee fields hold one
+func swapFeeRate(pool *OnChainPool) (numerator, denominator uint64) {
+	return pool.SwapFeeNumerator, pool.SwapFeeDenominator
+}
 
-	numerator := new(big.Int).Mul(
-		new(big.Int).SetUint64(reserveOut),
-		new(big.Int).SetUint64(amountIn),
-	)
+// quotedPriceImpact returns how far a swap of amount moves the execution price
+// from the pool's spot price, in percent. For a constant product pool this is
+// in/(reserveIn+in) for the input left after the fee.
+func quotedPriceImpact(pool *OnChainPool, side string, amount float64) float64 {
+	isBaseSol := isBaseCurrency(pool)
+	isBaseToQuote := (side == "buy") == isBaseSol
 
-	denominator := new(big.Int).Add(
-		new(big.Int).SetUint64(reserveIn),
-		new(big.Int).SetUint64(amountIn),
-	)
+	inputDecimals := currencyDecimals(pool)
+	if side == "sell" {
+		inputDecimals = int(pool.BaseDecimals)
+		if isBaseSol {
+			inputDecimals = int(pool.QuoteDecimals)
+		}
+	}
+	amountIn, err := atoms.FromAmount(amount, inputDecimals)
+	if err != nil {
+		return 0
+	}
 
-	result := new(big.Int).Div(numerator, denominator)
+	numerator, denominator := swapFeeRate(pool)
+	afterFee := amountIn - atoms.MulDiv(amountIn, numerator, denominator, true)
+	baseReserve, quoteReserve := poolReserves(pool)
+	reserveIn := quoteReserve
+	if isBaseToQuote {
+		reserveIn = baseReserve
+	}
+	if reserveIn+afterFee == 0 {
+		return 0
+	}
+	return float64(afterFee) / float64(reserveIn+afterFee) * 100
+}
 
-	return result.Uint64()
+// raydiumSwapBaseIn mirrors the program's swap_base_in on the pool's
+// reserves net of protocol PnL
+func raydiumSwapBaseIn(pool *OnChainPool, amountIn uint64, isBaseToQuote bool) (amountOut uint64, fee uint64) {
+	numerator, denominator := swapFeeRate(pool)
+	baseReserve, quoteReserve := poolReserves(pool)
+	if isBaseToQuote {
+		return amm.SwapBaseIn(baseReserve, quoteReserve, numerator, denominator, amountIn)
+	}
+	return amm.SwapBaseIn(quoteReserve, baseReserve, numerator, denominator, amountIn)
 }
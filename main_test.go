@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Accounts recorded in testdata/raydium-v4.json
+var (
+	FIXTURE_SOL_TOKEN6_POOL  = solana.MustPublicKeyFromBase58("4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi")
+	FIXTURE_TOKEN18_SOL_POOL = solana.MustPublicKeyFromBase58("8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR")
+	FIXTURE_UNINITIALIZED    = solana.MustPublicKeyFromBase58("CktRuQ2mttgRGkXJtyksdKHjUdc2C4TgDzyB98oEzy8")
+	FIXTURE_NOT_A_POOL       = solana.MustPublicKeyFromBase58("GgBaCs3NCBuZN12kCJgAW63ydqohFkHEdfdEXBPzLHq")
+	FIXTURE_TRUNCATED_POOL   = solana.MustPublicKeyFromBase58("LbUiWL3xVV8hTFYBVdbTNrpDo41NKS6o3LHHuDzjfcY")
+	FIXTURE_TOKEN6_MINT      = solana.MustPublicKeyFromBase58("QWmroo4YnnMqYW3cnxWkFdaTxGD3P7vMSzwMHGbUzwF")
+	FIXTURE_TOKEN18_MINT     = solana.MustPublicKeyFromBase58("2d46SEBFCA8SMB1BUAq3z1XJrp3qAXUgQnzkQ85Nvzjy")
+	FIXTURE_SABER_USDC_USDT  = solana.MustPublicKeyFromBase58("6QWeT6FpJrm8AF1btu6WH2k2Xhq6t5vbheKVfQavmeoZ")
+)
+
+// fixtureTestClient replays the recorded pools, as RPC_REPLAY would
+func fixtureTestClient(t *testing.T) ChainClient {
+	t.Helper()
+	client, err := loadFixtureClient(filepath.Join("testdata", "raydium-v4.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// fixturePool loads a recorded pool with its decimals and reserves
+func fixturePool(t *testing.T, client ChainClient, address solana.PublicKey) *OnChainPool {
+	t.Helper()
+	pool, err := loadPool(context.Background(), client, address.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+func TestParsePoolAccount(t *testing.T) {
+	client := fixtureTestClient(t)
+	tests := []struct {
+		name      string
+		address   solana.PublicKey
+		baseMint  solana.PublicKey
+		quoteMint solana.PublicKey
+		pnlBase   uint64
+		wantErr   string
+	}{
+		{"SOL base", FIXTURE_SOL_TOKEN6_POOL, WSOL_MINT, FIXTURE_TOKEN6_MINT, 0, ""},
+		{"SOL quote with PnL owed", FIXTURE_TOKEN18_SOL_POOL, FIXTURE_TOKEN18_MINT, WSOL_MINT, 1_000_000_000_000_000_000, ""},
+		{"uninitialized", FIXTURE_UNINITIALIZED, solana.PublicKey{}, solana.PublicKey{}, 0, "is not initialized"},
+		{"unknown size", FIXTURE_TRUNCATED_POOL, solana.PublicKey{}, solana.PublicKey{}, 0, "unrecognized account size 637"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := client.GetAccountInfo(context.Background(), tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pool, err := parsePoolAccount(tt.address, info.Value.Data.GetBinary())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !pool.BaseMint.Equals(tt.baseMint) || !pool.QuoteMint.Equals(tt.quoteMint) {
+				t.Errorf("mints %s/%s, want %s/%s", pool.BaseMint, pool.QuoteMint, tt.baseMint, tt.quoteMint)
+			}
+			if pool.SwapFeeNumerator != 25 || pool.SwapFeeDenominator != 10_000 {
+				t.Errorf("swap fee %d/%d, want 25/10000", pool.SwapFeeNumerator, pool.SwapFeeDenominator)
+			}
+			if pool.NeedTakePnlBase != tt.pnlBase {
+				t.Errorf("base PnL %d, want %d", pool.NeedTakePnlBase, tt.pnlBase)
+			}
+			if !pool.MarketProgram.Equals(OPENBOOK_PROGRAM) {
+				t.Errorf("market program %s, want %s", pool.MarketProgram, OPENBOOK_PROGRAM)
+			}
+		})
+	}
+}
+
+// Expected outputs follow the program's swap_base_in: the fee is rounded up
+// from the input, the output down from the reserves net of PnL
+func TestRaydiumSwapBaseIn(t *testing.T) {
+	client := fixtureTestClient(t)
+	solToken6 := fixturePool(t, client, FIXTURE_SOL_TOKEN6_POOL)
+	token18Sol := fixturePool(t, client, FIXTURE_TOKEN18_SOL_POOL)
+	tests := []struct {
+		name          string
+		pool          *OnChainPool
+		amountIn      uint64
+		isBaseToQuote bool
+		wantOut       uint64
+		wantFee       uint64
+	}{
+		{"1 SOL for a 6-decimal token", solToken6, 1_000_000_000, true, 149_475_897, 2_500_000},
+		{"1000 of a 6-decimal token for SOL", solToken6, 1_000_000_000, false, 6_606_069_636, 2_500_000},
+		{"1 SOL for an 18-decimal token", token18Sol, 1_000_000_000, false, 19_910_278_993_408_150, 2_500_000},
+		{"0.5 of an 18-decimal token for SOL", token18Sol, 500_000_000_000_000_000, true, 23_752_827_717, 1_250_000_000_000_000},
+		{"one atom pays a whole atom of fee", solToken6, 1, true, 0, 1},
+		{"nothing in", solToken6, 0, true, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, fee := raydiumSwapBaseIn(tt.pool, tt.amountIn, tt.isBaseToQuote)
+			if out != tt.wantOut || fee != tt.wantFee {
+				t.Errorf("raydiumSwapBaseIn(%d) = %d, fee %d; want %d, fee %d", tt.amountIn, out, fee, tt.wantOut, tt.wantFee)
+			}
+		})
+	}
+}
+
+func TestCalculateQuoteOnChain(t *testing.T) {
+	client := fixtureTestClient(t)
+	tests := []struct {
+		name         string
+		pool         solana.PublicKey
+		side         string
+		amount       float64
+		wantOut      uint64
+		wantDecimals int
+		wantErr      string
+	}{
+		{"buy a 6-decimal token", FIXTURE_SOL_TOKEN6_POOL, "buy", 1, 149_475_897, 6, ""},
+		{"sell a 6-decimal token", FIXTURE_SOL_TOKEN6_POOL, "sell", 1000, 6_606_069_636, 9, ""},
+		{"buy an 18-decimal token", FIXTURE_TOKEN18_SOL_POOL, "buy", 1, 19_910_278_993_408_150, 18, ""},
+		{"sell an 18-decimal token", FIXTURE_TOKEN18_SOL_POOL, "sell", 0.5, 23_752_827_717, 9, ""},
+		{"buy USDT on a Saber stable pool", FIXTURE_SABER_USDC_USDT, "buy", 1000, 991_296_479, 6, ""},
+		{"sell USDT on a Saber stable pool", FIXTURE_SABER_USDC_USDT, "sell", 1000, 1_007_923_197, 6, ""},
+		{"uninitialized pool", FIXTURE_UNINITIALIZED, "buy", 1, 0, 0, "is not initialized"},
+		{"not a Raydium account", FIXTURE_NOT_A_POOL, "buy", 1, 0, 0, "not a Raydium V4 pool"},
+		{"unrecorded pool", solana.SystemProgramID, "buy", 1, 0, 0, "no recorded RPC response"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, decimals, err := calculateQuoteOnChain(context.Background(), client, QuoteParams{
+				PoolAddress: tt.pool.String(),
+				Side:        tt.side,
+				Amount:      tt.amount,
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.wantOut || decimals != tt.wantDecimals {
+				t.Errorf("got %d at %d decimals, want %d at %d", out, decimals, tt.wantOut, tt.wantDecimals)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var METAPLEX_METADATA_PROGRAM = solana.MustPublicKeyFromBase58("metaqbxxUerdq28cj1RbAWkYQm3ybzjb6a8bt518x1s")
+
+// Token-2022 mint extensions follow the 165-byte base account and a 1-byte
+// account type as type(u16) + length(u16) + value entries
+const (
+	TOKEN_2022_EXTENSIONS_OFFSET   = 166
+	TOKEN_2022_METADATA_EXTENSION  = 19
+	TOKEN_2022_METADATA_KEYS_BYTES = 64 // update_authority + mint before the name
+)
+
+// TokenMetadata is the display name and symbol of a mint
+type TokenMetadata struct {
+	Name   string
+	Symbol string
+}
+
+// deriveMetaplexMetadata returns the Metaplex metadata PDA of a mint
+func deriveMetaplexMetadata(mint solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress(
+		[][]byte{[]byte("metadata"), METAPLEX_METADATA_PROGRAM.Bytes(), mint.Bytes()},
+		METAPLEX_METADATA_PROGRAM,
+	)
+	return address, err
+}
+
+// parseMetaplexMetadata reads name and symbol from a Metaplex metadata account:
+// key(1) + update_authority(32) + mint(32) + name + symbol
+func parseMetaplexMetadata(data []byte) (*TokenMetadata, error) {
+	return parseNameSymbol(data, 65)
+}
+
+// parseToken2022Metadata reads the metadata extension embedded in a Token-2022
+// mint, returning nil when the mint has none
+func parseToken2022Metadata(data []byte) *TokenMetadata {
+	for offset := TOKEN_2022_EXTENSIONS_OFFSET; offset+4 <= len(data); {
+		extension := binary.LittleEndian.Uint16(data[offset:])
+		length := int(binary.LittleEndian.Uint16(data[offset+2:]))
+		offset += 4
+		if offset+length > len(data) {
+			return nil
+		}
+		if extension == TOKEN_2022_METADATA_EXTENSION {
+			metadata, err := parseNameSymbol(data[offset:offset+length], TOKEN_2022_METADATA_KEYS_BYTES)
+			if err != nil {
+				return nil
+			}
+			return metadata
+		}
+		offset += length
+	}
+	return nil
+}
+
+// parseNameSymbol reads the borsh name and symbol strings at offset, trimming
+// the NUL padding Metaplex uses
+func parseNameSymbol(data []byte, offset int) (*TokenMetadata, error) {
+	readString := func() (string, error) {
+		if len(data) < offset+4 {
+			return "", fmt.Errorf("metadata account too short")
+		}
+		length := int(binary.LittleEndian.Uint32(data[offset:]))
+		offset += 4
+		if len(data) < offset+length {
+			return "", fmt.Errorf("metadata string overruns account")
+		}
+		value := strings.TrimRight(string(data[offset:offset+length]), "\x00 ")
+		offset += length
+		return value, nil
+	}
+
+	name, err := readString()
+	if err != nil {
+		return nil, err
+	}
+	symbol, err := readString()
+	if err != nil {
+		return nil, err
+	}
+	return &TokenMetadata{Name: name, Symbol: symbol}, nil
+}
+
+// fetchTokenMetadata batch-loads metadata for mints, preferring the Token-2022
+// metadata extension and falling back to Metaplex. Mints without metadata are
+// missing from the result.
+func fetchTokenMetadata(ctx context.Context, client ChainClient, mints []solana.PublicKey) (map[solana.PublicKey]*TokenMetadata, error) {
+	result := make(map[solana.PublicKey]*TokenMetadata)
+
+	for start := 0; start < len(mints); start += MAX_MULTIPLE_ACCOUNTS {
+		end := min(start+MAX_MULTIPLE_ACCOUNTS, len(mints))
+		accounts, err := client.GetMultipleAccounts(ctx, mints[start:end]...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch mints: %w", err)
+		}
+		for i, account := range accounts.Value {
+			if account == nil || !account.Owner.Equals(TOKEN_2022_PROGRAM) {
+				continue
+			}
+			if metadata := parseToken2022Metadata(account.Data.GetBinary()); metadata != nil {
+				result[mints[start+i]] = metadata
+			}
+		}
+	}
+
+	addresses := make([]solana.PublicKey, len(mints))
+	for i, mint := range mints {
+		address, err := deriveMetaplexMetadata(mint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive metadata address: %w", err)
+		}
+		addresses[i] = address
+	}
+
+	for start := 0; start < len(addresses); start += MAX_MULTIPLE_ACCOUNTS {
+		end := min(start+MAX_MULTIPLE_ACCOUNTS, len(addresses))
+		accounts, err := client.GetMultipleAccounts(ctx, addresses[start:end]...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch token metadata: %w", err)
+		}
+		for i, account := range accounts.Value {
+			if account == nil {
+				continue
+			}
+			if _, ok := result[mints[start+i]]; ok {
+				continue
+			}
+			if metadata, err := parseMetaplexMetadata(account.Data.GetBinary()); err == nil {
+				result[mints[start+i]] = metadata
+			}
+		}
+	}
+	return result, nil
+}
+
+// Per-run cache of resolved metadata; a nil entry means the mint has none
+var (
+	tokenMetadataMu    sync.Mutex
+	tokenMetadataCache = make(map[solana.PublicKey]*TokenMetadata)
+)
+
+// lookupTokenMetadata returns the cached metadata of a mint, fetching it on
+// first use. SOL is known without a lookup.
+func lookupTokenMetadata(ctx context.Context, client ChainClient, mint solana.PublicKey) *TokenMetadata {
+	if mint.Equals(WSOL_MINT) || mint.Equals(SOL_MINT) {
+		return &TokenMetadata{Name: "Solana", Symbol: "SOL"}
+	}
+
+	tokenMetadataMu.Lock()
+	metadata, ok := tokenMetadataCache[mint]
+	tokenMetadataMu.Unlock()
+	if ok {
+		return metadata
+	}
+
+	found, err := fetchTokenMetadata(ctx, client, []solana.PublicKey{mint})
+	if err != nil {
+		// Leave uncached so a later call can retry
+		return nil
+	}
+	metadata = found[mint]
+
+	tokenMetadataMu.Lock()
+	tokenMetadataCache[mint] = metadata
+	tokenMetadataMu.Unlock()
+	return metadata
+}
+
+// tokenSymbol returns the mint's symbol, or a shortened mint address when it has none
+func tokenSymbol(ctx context.Context, client ChainClient, mint solana.PublicKey) string {
+	if metadata := lookupTokenMetadata(ctx, client, mint); metadata != nil && metadata.Symbol != "" {
+		return metadata.Symbol
+	}
+	address := mint.String()
+	return address[:4] + "…" + address[len(address)-4:]
+}
+
+// tokenDisplayName returns "SYMBOL (Name)" when metadata is available, or the mint address
+func tokenDisplayName(ctx context.Context, client ChainClient, mint solana.PublicKey) string {
+	metadata := lookupTokenMetadata(ctx, client, mint)
+	if metadata == nil || metadata.Symbol == "" {
+		return mint.String()
+	}
+	if metadata.Name == "" || metadata.Name == metadata.Symbol {
+		return metadata.Symbol
+	}
+	return fmt.Sprintf("%s (%s)", metadata.Symbol, metadata.Name)
+}
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+// METEORA_DLMM_PROGRAM is the Meteora dynamic liquidity market maker program
+var METEORA_DLMM_PROGRAM = solana.MustPublicKeyFromBase58("LBUZKhRxPF3XUpBCjp4YzTKgLccjZhTSDM9YuVaPwxo")
+
+// Meteora DLMM account layouts
+const (
+	DLMM_LB_PAIR_SIZE        = 904
+	DLMM_BINS_PER_ARRAY      = 70
+	DLMM_BIN_SIZE            = 144
+	DLMM_BIN_ARRAY_HEADER    = 56 // discriminator + index + version + padding + lb_pair
+	DLMM_FEE_PRECISION       = 1_000_000_000
+	DLMM_MAX_FEE_RATE        = 100_000_000 // 10%
+	DLMM_MAX_BIN_ARRAYS      = 3           // bin arrays walked per swap
+	DLMM_TOKEN_X_MINT_OFFSET = 88
+	DLMM_TOKEN_Y_MINT_OFFSET = 120
+)
+
+// DlmmPair is a parsed Meteora LbPair account
+type DlmmPair struct {
+	Address               solana.PublicKey
+	BaseFactor            uint16
+	VariableFeeControl    uint32
+	BaseFeePowerFactor    uint8
+	VolatilityAccumulator uint32
+	ActiveID              int32
+	BinStep               uint16
+	TokenXMint            solana.PublicKey
+	TokenYMint            solana.PublicKey
+	ReserveX              solana.PublicKey
+	ReserveY              solana.PublicKey
+	Oracle                solana.PublicKey
+	TokenXDecimals        uint8
+	TokenYDecimals        uint8
+	ReserveXAmount        uint64
+	ReserveYAmount        uint64
+}
+
+// DlmmBin is the liquidity held at one price bin
+type DlmmBin struct {
+	ID      int32
+	AmountX uint64
+	AmountY uint64
+	Price   *big.Int // Q64.64 price of X in Y
+}
+
+// parseDlmmPair decodes an LbPair account
+func parseDlmmPair(address solana.PublicKey, data []byte) (*DlmmPair, error) {
+	if len(data) < DLMM_LB_PAIR_SIZE {
+		return nil, fmt.Errorf("invalid LbPair data size: %d", len(data))
+	}
+
+	return &DlmmPair{
+		Address:               address,
+		BaseFactor:            binary.LittleEndian.Uint16(data[8:10]),
+		VariableFeeControl:    binary.LittleEndian.Uint32(data[16:20]),
+		BaseFeePowerFactor:    data[34],
+		VolatilityAccumulator: binary.LittleEndian.Uint32(data[40:44]),
+		ActiveID:              int32(binary.LittleEndian.Uint32(data[76:80])),
+		BinStep:               binary.LittleEndian.Uint16(data[80:82]),
+		TokenXMint:            solana.PublicKeyFromBytes(data[88:120]),
+		TokenYMint:            solana.PublicKeyFromBytes(data[120:152]),
+		ReserveX:              solana.PublicKeyFromBytes(data[152:184]),
+		ReserveY:              solana.PublicKeyFromBytes(data[184:216]),
+		Oracle:                solana.PublicKeyFromBytes(data[552:584]),
+	}, nil
+}
+
+// dlmmBinArrayIndex returns the index of the bin array holding a bin
+func dlmmBinArrayIndex(binID int32) int64 {
+	index := int64(binID) / DLMM_BINS_PER_ARRAY
+	if binID < 0 && int64(binID)%DLMM_BINS_PER_ARRAY != 0 {
+		index--
+	}
+	return index
+}
+
+// deriveDlmmBinArray returns the bin array PDA for an index
+func deriveDlmmBinArray(pair solana.PublicKey, index int64) (solana.PublicKey, error) {
+	indexBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(indexBytes, uint64(index))
+
+	address, _, err := solana.FindProgramAddress(
+		[][]byte{[]byte("bin_array"), pair.Bytes(), indexBytes},
+		METEORA_DLMM_PROGRAM,
+	)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to derive bin array %d: %w", index, err)
+	}
+	return address, nil
+}
+
+// dlmmFeeRate returns the current total fee rate in DLMM_FEE_PRECISION units
+func dlmmFeeRate(pair *DlmmPair) uint64 {
+	baseFee := uint64(pair.BaseFactor) * uint64(pair.BinStep) * 10 * uint64(math.Pow10(int(pair.BaseFeePowerFactor)))
+
+	var variableFee uint64
+	if pair.VariableFeeControl > 0 {
+		// ((volatility * bin_step)^2 * control) / 1e11, rounded up
+		scaled := uint64(pair.VolatilityAccumulator) * uint64(pair.BinStep)
+		product := new(big.Int).Mul(new(big.Int).SetUint64(scaled), new(big.Int).SetUint64(scaled))
+		product.Mul(product, big.NewInt(int64(pair.VariableFeeControl)))
+		product.Add(product, big.NewInt(99_999_999_999))
+		variableFee = product.Div(product, big.NewInt(100_000_000_000)).Uint64()
+	}
+
+	return min(baseFee+variableFee, DLMM_MAX_FEE_RATE)
+}
+
+// dlmmBinPrice computes the Q64.64 price of a bin from the bin step
+func dlmmBinPrice(binStep uint16, binID int32) *big.Int {
+	price := new(big.Float).SetFloat64(math.Pow(1+float64(binStep)/10000, float64(binID)))
+	price.Mul(price, new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), 64)))
+	result, _ := price.Int(nil)
+	return result
+}
+
+// fetchDlmmBins loads the bin arrays a swap walks through, starting at the
+// active bin and moving down (X in) or up (Y in). It returns the bins in
+// traversal order and the bin array accounts the swap instruction needs.
+func fetchDlmmBins(ctx context.Context, client ChainClient, pair *DlmmPair, swapForY bool) ([]DlmmBin, []solana.PublicKey, error) {
+	step := int64(1)
+	if swapForY {
+		step = -1
+	}
+
+	start := dlmmBinArrayIndex(pair.ActiveID)
+	var addresses []solana.PublicKey
+	var indexes []int64
+	for i := int64(0); i < DLMM_MAX_BIN_ARRAYS; i++ {
+		address, err := deriveDlmmBinArray(pair.Address, start+i*step)
+		if err != nil {
+			return nil, nil, err
+		}
+		addresses = append(addresses, address)
+		indexes = append(indexes, start+i*step)
+	}
+
+	accounts, err := client.GetMultipleAccounts(ctx, addresses...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get bin arrays: %w", err)
+	}
+
+	var bins []DlmmBin
+	var binArrays []solana.PublicKey
+	for i, account := range accounts.Value {
+		// Bin arrays are created lazily; stop at the first gap
+		if account == nil {
+			break
+		}
+		data := account.Data.GetBinary()
+		if len(data) < DLMM_BIN_ARRAY_HEADER+DLMM_BINS_PER_ARRAY*DLMM_BIN_SIZE {
+			return nil, nil, fmt.Errorf("invalid bin array data size: %d", len(data))
+		}
+		binArrays = append(binArrays, addresses[i])
+
+		lowest := int32(indexes[i] * DLMM_BINS_PER_ARRAY)
+		for j := 0; j < DLMM_BINS_PER_ARRAY; j++ {
+			// Walk bins in swap direction
+			k := j
+			if swapForY {
+				k = DLMM_BINS_PER_ARRAY - 1 - j
+			}
+			id := lowest + int32(k)
+			if (swapForY && id > pair.ActiveID) || (!swapForY && id < pair.ActiveID) {
+				continue
+			}
+
+			offset := DLMM_BIN_ARRAY_HEADER + k*DLMM_BIN_SIZE
+			bin := DlmmBin{
+				ID:      id,
+				AmountX: binary.LittleEndian.Uint64(data[offset : offset+8]),
+				AmountY: binary.LittleEndian.Uint64(data[offset+8 : offset+16]),
+			}
+
+			// u128 little endian price
+			priceBytes := make([]byte, 16)
+			for b := 0; b < 16; b++ {
+				priceBytes[15-b] = data[offset+16+b]
+			}
+			bin.Price = new(big.Int).SetBytes(priceBytes)
+			if bin.Price.Sign() == 0 {
+				bin.Price = dlmmBinPrice(pair.BinStep, id)
+			}
+
+			bins = append(bins, bin)
+		}
+	}
+
+	if len(binArrays) == 0 {
+		return nil, nil, fmt.Errorf("no initialized bin array around active bin %d", pair.ActiveID)
+	}
+
+	return bins, binArrays, nil
+}
+
+// quoteDlmm walks bins in swap direction and returns the output for amountIn
+func quoteDlmm(pair *DlmmPair, bins []DlmmBin, amountIn uint64, swapForY bool) (uint64, error) {
+	fee := atoms.MulDiv(amountIn, dlmmFeeRate(pair), DLMM_FEE_PRECISION, true)
+	remaining := new(big.Int).SetUint64(amountIn - fee)
+	out := new(big.Int)
+
+	for _, bin := range bins {
+		if remaining.Sign() == 0 {
+			break
+		}
+
+		var available uint64
+		var maxIn *big.Int
+		if swapForY {
+			// X in, Y out: in = out / price
+			available = bin.AmountY
+			maxIn = new(big.Int).Lsh(new(big.Int).SetUint64(available), 64)
+			maxIn.Add(maxIn, new(big.Int).Sub(bin.Price, big.NewInt(1)))
+			maxIn.Div(maxIn, bin.Price)
+		} else {
+			// Y in, X out: in = out * price
+			available = bin.AmountX
+			maxIn = new(big.Int).Mul(new(big.Int).SetUint64(available), bin.Price)
+			maxIn.Add(maxIn, new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 64), big.NewInt(1)))
+			maxIn.Rsh(maxIn, 64)
+		}
+		if available == 0 {
+			continue
+		}
+
+		if remaining.Cmp(maxIn) >= 0 {
+			out.Add(out, new(big.Int).SetUint64(available))
+			remaining.Sub(remaining, maxIn)
+			continue
+		}
+
+		if swapForY {
+			out.Add(out, new(big.Int).Rsh(new(big.Int).Mul(remaining, bin.Price), 64))
+		} else {
+			out.Add(out, new(big.Int).Div(new(big.Int).Lsh(remaining, 64), bin.Price))
+		}
+		remaining.SetUint64(0)
+	}
+
+	if remaining.Sign() > 0 {
+		return 0, fmt.Errorf("insufficient liquidity in loaded bins")
+	}
+	return out.Uint64(), nil
+}
+
+// createDlmmSwapInstruction creates a Meteora DLMM swap instruction
+func createDlmmSwapInstruction(
+	pair *DlmmPair,
+	binArrays []solana.PublicKey,
+	userTokenIn solana.PublicKey,
+	userTokenOut solana.PublicKey,
+	user solana.PublicKey,
+	amountIn uint64,
+	minAmountOut uint64,
+) (solana.Instruction, error) {
+	eventAuthority, _, err := solana.FindProgramAddress([][]byte{[]byte("__event_authority")}, METEORA_DLMM_PROGRAM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive event authority: %w", err)
+	}
+
+	data := make([]byte, 24)
+	copy(data[0:8], anchorDiscriminator("swap"))
+	binary.LittleEndian.PutUint64(data[8:16], amountIn)
+	binary.LittleEndian.PutUint64(data[16:24], minAmountOut)
+
+	accounts := []*solana.AccountMeta{
+		// 0. LbPair
+		{PublicKey: pair.Address, IsSigner: false, IsWritable: true},
+		// 1. Bin array bitmap extension (none; optional accounts are passed as the program ID)
+		{PublicKey: METEORA_DLMM_PROGRAM, IsSigner: false, IsWritable: false},
+		// 2. Reserve X
+		{PublicKey: pair.ReserveX, IsSigner: false, IsWritable: true},
+		// 3. Reserve Y
+		{PublicKey: pair.ReserveY, IsSigner: false, IsWritable: true},
+		// 4. User token in
+		{PublicKey: userTokenIn, IsSigner: false, IsWritable: true},
+		// 5. User token out
+		{PublicKey: userTokenOut, IsSigner: false, IsWritable: true},
+		// 6. Token X mint
+		{PublicKey: pair.TokenXMint, IsSigner: false, IsWritable: false},
+		// 7. Token Y mint
+		{PublicKey: pair.TokenYMint, IsSigner: false, IsWritable: false},
+		// 8. Oracle
+		{PublicKey: pair.Oracle, IsSigner: false, IsWritable: true},
+		// 9. Host fee account (none)
+		{PublicKey: METEORA_DLMM_PROGRAM, IsSigner: false, IsWritable: false},
+		// 10. User (signer)
+		{PublicKey: user, IsSigner: true, IsWritable: false},
+		// 11. Token X program
+		{PublicKey: token.ProgramID, IsSigner: false, IsWritable: false},
+		// 12. Token Y program
+		{PublicKey: token.ProgramID, IsSigner: false, IsWritable: false},
+		// 13. Event authority
+		{PublicKey: eventAuthority, IsSigner: false, IsWritable: false},
+		// 14. Program
+		{PublicKey: METEORA_DLMM_PROGRAM, IsSigner: false, IsWritable: false},
+	}
+
+	// Remaining accounts: the bin arrays crossed by the swap
+	for _, binArray := range binArrays {
+		accounts = append(accounts, &solana.AccountMeta{PublicKey: binArray, IsSigner: false, IsWritable: true})
+	}
+
+	return solana.NewInstruction(METEORA_DLMM_PROGRAM, accounts, data), nil
+}
+
+// dlmmSolReserve returns the raw SOL-side reserve of a SOL-paired DLMM pair
+func dlmmSolReserve(pair *DlmmPair) uint64 {
+	if pair.TokenXMint.Equals(WSOL_MINT) {
+		return pair.ReserveXAmount
+	}
+	return pair.ReserveYAmount
+}
+
+// findDlmmPair finds the most liquid SOL-paired Meteora DLMM pair for a token
+func findDlmmPair(ctx context.Context, client ChainClient, tokenAddress string) (*DlmmPair, error) {
+	tokenPubkey, err := solana.PublicKeyFromBase58(tokenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token address: %w", err)
+	}
+
+	fmt.Println("Searching for Meteora DLMM pairs...")
+
+	var pairs []*DlmmPair
+	for _, mints := range [][2]solana.PublicKey{{tokenPubkey, WSOL_MINT}, {WSOL_MINT, tokenPubkey}} {
+		accounts, err := client.GetProgramAccountsWithOpts(
+			ctx,
+			METEORA_DLMM_PROGRAM,
+			&rpc.GetProgramAccountsOpts{
+				Filters: []rpc.RPCFilter{
+					{DataSize: DLMM_LB_PAIR_SIZE},
+					{Memcmp: &rpc.RPCFilterMemcmp{Offset: DLMM_TOKEN_X_MINT_OFFSET, Bytes: mints[0].Bytes()}},
+					{Memcmp: &rpc.RPCFilterMemcmp{Offset: DLMM_TOKEN_Y_MINT_OFFSET, Bytes: mints[1].Bytes()}},
+				},
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get DLMM pairs: %w", err)
+		}
+
+		for _, account := range accounts {
+			pair, err := parseDlmmPair(account.Pubkey, account.Account.Data.GetBinary())
+			if err != nil {
+				continue
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+
+	var best *DlmmPair
+	for _, pair := range pairs {
+		if err := loadDlmmReserves(ctx, client, pair); err != nil {
+			fmt.Printf("Warning: Failed to load reserves for pair %s: %v\n", pair.Address, err)
+			continue
+		}
+		if best == nil || dlmmSolReserve(pair) > dlmmSolReserve(best) {
+			best = pair
+		}
+	}
+
+	if best == nil || dlmmSolReserve(best) == 0 {
+		return nil, fmt.Errorf("no Meteora DLMM pairs found for token %s paired with SOL", tokenAddress)
+	}
+
+	best.TokenXDecimals, err = getTokenDecimals(ctx, client, best.TokenXMint.String())
+	if err != nil {
+		return nil, err
+	}
+	best.TokenYDecimals, err = getTokenDecimals(ctx, client, best.TokenYMint.String())
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Found %d DLMM pairs, using %s\n", len(pairs), best.Address)
+	return best, nil
+}
+
+// loadDlmmReserves fetches the reserve token balances of a pair
+func loadDlmmReserves(ctx context.Context, client ChainClient, pair *DlmmPair) error {
+	accounts, err := client.GetMultipleAccounts(ctx, pair.ReserveX, pair.ReserveY)
+	if err != nil {
+		return err
+	}
+	if len(accounts.Value) != 2 || accounts.Value[0] == nil || accounts.Value[1] == nil {
+		return fmt.Errorf("reserve accounts not found")
+	}
+
+	pair.ReserveXAmount, err = decodeTokenAmount(accounts.Value[0].Data.GetBinary())
+	if err != nil {
+		return err
+	}
+	pair.ReserveYAmount, err = decodeTokenAmount(accounts.Value[1].Data.GetBinary())
+	return err
+}
+
+// runDlmmSwap quotes and optionally executes a swap against a Meteora DLMM pair
+func runDlmmSwap(
+	ctx context.Context,
+	client ChainClient,
+	wallet solana.PrivateKey,
+	pair *DlmmPair,
+	side string,
+	amount float64,
+	execute bool,
+	dryRun bool,
+	guard *SpendGuard,
+	opts BuildOptions,
+) error {
+	isXSol := pair.TokenXMint.Equals(WSOL_MINT)
+	tokenMint, tokenDecimals := pair.TokenXMint, pair.TokenXDecimals
+	if isXSol {
+		tokenMint, tokenDecimals = pair.TokenYMint, pair.TokenYDecimals
+	}
+
+	// Buying spends SOL; the swap goes towards Y when SOL is X
+	inDecimals, outDecimals := uint8(SOL_DECIMALS), tokenDecimals
+	if side == "sell" {
+		inDecimals, outDecimals = tokenDecimals, SOL_DECIMALS
+	}
+	swapForY := (side == "buy") == isXSol
+
+	bins, binArrays, err := fetchDlmmBins(ctx, client, pair, swapForY)
+	if err != nil {
+		return err
+	}
+
+	amountIn, err := atoms.FromAmount(amount, int(inDecimals))
+	if err != nil {
+		return err
+	}
+	rawOut, err := quoteDlmm(pair, bins, amountIn, swapForY)
+	if err != nil {
+		return err
+	}
+	quote := atoms.ToAmount(rawOut, int(outDecimals))
+
+	fmt.Printf("\n=== QUOTE RESULT ===\n")
+	fmt.Printf("Protocol: Meteora DLMM\n")
+	fmt.Printf("Pair: %s\n", pair.Address)
+	fmt.Printf("Active Bin: %d (bin step %d bps, fee %.4f%%)\n", pair.ActiveID, pair.BinStep, float64(dlmmFeeRate(pair))/DLMM_FEE_PRECISION*100)
+	fmt.Printf("Operation: %s\n", strings.ToUpper(side))
+	fmt.Printf("Amount In: %.9f\n", amount)
+	fmt.Printf("Expected Out: %.9f\n", quote)
+	fmt.Printf("====================\n")
+
+	if !execute && !dryRun {
+		return nil
+	}
+
+	trade := newGuardedTrade(tokenMint, side, amount, quote, opts.Burn)
+	if execute && !dryRun {
+		if err := guard.Enforce(ctx, trade, true); err != nil {
+			return err
+		}
+		defer guard.Release(trade)
+	}
+
+	if execute && !dryRun && !confirmQuote(pair.Address.String(), side, amount, quote, tokenSymbol(ctx, client, tokenMint), opts) {
+		fmt.Println("\nSwap cancelled by user.")
+		return nil
+	}
+
+	slippage, err := getSlippageFromUser()
+	if err != nil {
+		return fmt.Errorf("failed to get slippage: %w", err)
+	}
+	minAmountOut := calculateMinAmountOut(rawOut, slippage)
+
+	owner := wallet.PublicKey()
+	var instructions []solana.Instruction
+
+	wsolATA, createIx, err := getOrCreateATA(ctx, client, owner, WSOL_MINT)
+	if err != nil {
+		return err
+	}
+	if createIx != nil {
+		instructions = append(instructions, createIx)
+	}
+	tokenATA, createIx, err := getOrCreateATA(ctx, client, owner, tokenMint)
+	if err != nil {
+		return err
+	}
+	if createIx != nil {
+		fmt.Printf("Creating ATA for token %s\n", tokenMint)
+		instructions = append(instructions, createIx)
+	}
+
+	userIn, userOut := tokenATA, wsolATA
+	if side == "buy" {
+		userIn, userOut = wsolATA, tokenATA
+		instructions = append(instructions, wrapSOLInstructions(owner, wsolATA, amountIn)...)
+	}
+
+	swapIx, err := createDlmmSwapInstruction(pair, binArrays, userIn, userOut, owner, amountIn, minAmountOut)
+	if err != nil {
+		return err
+	}
+	instructions = append(instructions, swapIx, closeWSOLInstruction(owner, wsolATA))
+
+	latestBlockhash, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+
+	tx, err := solana.NewTransaction(instructions, latestBlockhash.Value.Blockhash, solana.TransactionPayer(owner))
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if err := signTransaction(tx, wallet); err != nil {
+		return err
+	}
+
+	if dryRun {
+		return simulateTransaction(ctx, client, tx)
+	}
+
+	sig, err := sendAndConfirmTransaction(ctx, client, tx, opts)
+	if err != nil {
+		return err
+	}
+	if err := guard.Record(trade); err != nil {
+		fmt.Printf("Warning: Could not record trade for spend limits: %v\n", err)
+	}
+
+	fmt.Printf("\n✅ Swap executed successfully!\n")
+	fmt.Printf("Transaction: %s\n", sig)
+	fmt.Printf("Explorer: %s\n", explorerTxURL(sig.String()))
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// PRIVATE_RELAY_URL_ENV_VAR overrides the relay anti-MEV sends go through
+const PRIVATE_RELAY_URL_ENV_VAR = "SOLANA_PRIVATE_RELAY_URL"
+
+const (
+	DEFAULT_PRIVATE_RELAY_URL     = "https://mainnet.block-engine.jito.wtf/api/v1/transactions"
+	DEFAULT_MEV_EPSILON           = 0.1     // percent below the quote accepted in anti-MEV mode
+	DEFAULT_MEV_MIN_COMPUTE_PRICE = 10_000  // micro-lamports per compute unit
+	DEFAULT_MEV_MAX_COMPUTE_PRICE = 100_000 // micro-lamports per compute unit
+	DEFAULT_MEV_SEND_JITTER       = 2 * time.Second
+	SANDWICH_SCAN_SIGNATURES      = 100  // recent pool transactions analyzed
+	SANDWICH_SCAN_MAX_FETCHES     = 40   // transactions fetched to resolve fee payers
+	SANDWICH_WARN_RATE            = 0.05 // warn when this fraction of pool swaps looks sandwiched
+)
+
+// MEVProtection configures the anti-MEV mode: a tight minimum out, a random
+// compute price and sending through a private relay instead of the public RPC
+type MEVProtection struct {
+	Enabled         bool
+	Epsilon         float64 // slippage cap in percent
+	MinComputePrice uint64
+	MaxComputePrice uint64
+	RelayURL        string // empty sends through the regular RPC
+}
+
+// envOrDefault returns the environment variable, or fallback when it is unset
+func envOrDefault(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Validate checks the configuration is within sane bounds
+func (m MEVProtection) Validate() error {
+	if m.Epsilon < 0 || m.Epsilon >= 100 {
+		return fmt.Errorf("MEV epsilon must be between 0 and 100 percent")
+	}
+	if m.MinComputePrice > m.MaxComputePrice {
+		return fmt.Errorf("MEV compute price band is inverted (%d > %d)", m.MinComputePrice, m.MaxComputePrice)
+	}
+	return nil
+}
+
+// tightenSlippage caps the slippage tolerance at Epsilon so a sandwich has
+// almost no room between the quote and the minimum out
+func (m MEVProtection) tightenSlippage(slippage float64) float64 {
+	if !m.Enabled || slippage <= m.Epsilon {
+		return slippage
+	}
+	return m.Epsilon
+}
+
+// computePriceInstruction returns a compute price instruction with a random
+// price in the configured band, or nil when anti-MEV mode is off. A varying
+// price keeps the priority fee from fingerprinting the wallet.
+func (m MEVProtection) computePriceInstruction() solana.Instruction {
+	if !m.Enabled {
+		return nil
+	}
+	price := m.MinComputePrice + rand.N(m.MaxComputePrice-m.MinComputePrice+1)
+	fmt.Printf("Compute unit price: %d micro-lamports\n", price)
+	return computebudget.NewSetComputeUnitPriceInstruction(price).Build()
+}
+
+// relaySender returns the private relay anti-MEV sends go through, or nil to
+// use the regular RPC. Relays only exist on mainnet and are skipped when
+// replaying fixtures.
+func (m MEVProtection) relaySender() Sender {
+	if !m.Enabled || m.RelayURL == "" || activeCluster.Name != "mainnet" || os.Getenv(RPC_REPLAY_ENV_VAR) != "" {
+		return nil
+	}
+	return &relaySender{name: "private relay " + m.RelayURL, url: m.RelayURL}
+}
+
+// SandwichStats summarizes suspected sandwiches among recent pool transactions
+type SandwichStats struct {
+	Scanned    int // pool transactions looked at
+	Sandwiched int // transactions bracketed by another fee payer in the same slot
+}
+
+// Rate returns the fraction of scanned transactions that look sandwiched
+func (s SandwichStats) Rate() float64 {
+	if s.Scanned == 0 {
+		return 0
+	}
+	return float64(s.Sandwiched) / float64(s.Scanned)
+}
+
+// scanSandwiches analyzes the pool's recent transactions. A transaction counts
+// as sandwiched when, within its slot, the same fee payer traded on the pool
+// both before and after it. Only slots with three or more pool transactions
+// are fetched, so quiet pools cost a single signature lookup.
+func scanSandwiches(ctx context.Context, client ChainClient, pool solana.PublicKey) (SandwichStats, error) {
+	limit := SANDWICH_SCAN_SIGNATURES
+	signatures, err := client.GetSignaturesForAddressWithOpts(ctx, pool, &rpc.GetSignaturesForAddressOpts{
+		Limit:      &limit,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return SandwichStats{}, fmt.Errorf("failed to get pool signatures: %w", err)
+	}
+
+	// Signatures come newest first; reverse each slot into execution order
+	var slots []uint64
+	bySlot := make(map[uint64][]solana.Signature)
+	for _, sig := range signatures {
+		if sig.Err != nil {
+			continue
+		}
+		if _, ok := bySlot[sig.Slot]; !ok {
+			slots = append(slots, sig.Slot)
+		}
+		bySlot[sig.Slot] = append([]solana.Signature{sig.Signature}, bySlot[sig.Slot]...)
+	}
+
+	stats := SandwichStats{}
+	fetches := 0
+	for _, slot := range slots {
+		sigs := bySlot[slot]
+		stats.Scanned += len(sigs)
+		if len(sigs) < 3 || fetches+len(sigs) > SANDWICH_SCAN_MAX_FETCHES {
+			continue
+		}
+		fetches += len(sigs)
+
+		payers := make([]solana.PublicKey, 0, len(sigs))
+		for _, sig := range sigs {
+			tx, err := fetchTransaction(ctx, client, sig.String())
+			if err != nil {
+				return stats, err
+			}
+			parsed, err := tx.Transaction.GetTransaction()
+			if err != nil {
+				return stats, fmt.Errorf("failed to decode transaction %s: %w", sig, err)
+			}
+			if len(parsed.Message.AccountKeys) == 0 {
+				continue
+			}
+			payers = append(payers, parsed.Message.AccountKeys[0])
+		}
+		stats.Sandwiched += countSandwiched(payers)
+	}
+	return stats, nil
+}
+
+// countSandwiched counts transactions whose fee payer differs from a payer
+// that appears both earlier and later in the same slot
+func countSandwiched(payers []solana.PublicKey) int {
+	count := 0
+	for i := 1; i < len(payers)-1; i++ {
+		if bracketed(payers, i) {
+			count++
+		}
+	}
+	return count
+}
+
+// bracketed reports whether another payer trades both before and after index i
+func bracketed(payers []solana.PublicKey, i int) bool {
+	for before := 0; before < i; before++ {
+		if payers[before].Equals(payers[i]) {
+			continue
+		}
+		for after := i + 1; after < len(payers); after++ {
+			if payers[after].Equals(payers[before]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// warnSandwiching prints a warning when the pool's recent history shows a
+// high incidence of sandwiching
+func warnSandwiching(ctx context.Context, client ChainClient, poolAddress string) {
+	poolPubkey, err := solana.PublicKeyFromBase58(poolAddress)
+	if err != nil {
+		return
+	}
+	stats, err := scanSandwiches(ctx, client, poolPubkey)
+	if err != nil {
+		fmt.Printf("Warning: Could not analyze pool for sandwiches: %v\n", err)
+		return
+	}
+	if stats.Rate() >= SANDWICH_WARN_RATE {
+		fmt.Printf("⚠️  Warning: %d of the last %d pool transactions look sandwiched (%.1f%%)\n",
+			stats.Sandwiched, stats.Scanned, stats.Rate()*100)
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Squads v4 constants
+const (
+	SQUADS_SEED_PREFIX      = "multisig"
+	SQUADS_SEED_VAULT       = "vault"
+	SQUADS_SEED_TRANSACTION = "transaction"
+	SQUADS_SEED_PROPOSAL    = "proposal"
+	// Offset of transaction_index in the Multisig account:
+	// discriminator(8) + create_key(32) + config_authority(32) + threshold(2) + time_lock(4)
+	SQUADS_TX_INDEX_OFFSET = 78
+)
+
+var SQUADS_V4_PROGRAM = solana.MustPublicKeyFromBase58("SQDS4ep65T869zMMBKyuUq6aD6EgTu8psMjkvj52pCf")
+
+// anchorDiscriminator returns the 8-byte Anchor instruction discriminator
+func anchorDiscriminator(name string) []byte {
+	hash := sha256.Sum256([]byte("global:" + name))
+	return hash[:8]
+}
+
+// deriveSquadsVault derives the vault PDA that owns assets for a multisig
+func deriveSquadsVault(multisig solana.PublicKey, vaultIndex uint8) (solana.PublicKey, error) {
+	vault, _, err := solana.FindProgramAddress(
+		[][]byte{
+			[]byte(SQUADS_SEED_PREFIX),
+			multisig.Bytes(),
+			[]byte(SQUADS_SEED_VAULT),
+			{vaultIndex},
+		},
+		SQUADS_V4_PROGRAM,
+	)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to derive vault PDA: %w", err)
+	}
+	return vault, nil
+}
+
+// deriveSquadsTransaction derives the vault transaction and proposal PDAs for a transaction index
+func deriveSquadsTransaction(multisig solana.PublicKey, index uint64) (transaction solana.PublicKey, proposal solana.PublicKey, err error) {
+	indexBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(indexBytes, index)
+
+	transaction, _, err = solana.FindProgramAddress(
+		[][]byte{
+			[]byte(SQUADS_SEED_PREFIX),
+			multisig.Bytes(),
+			[]byte(SQUADS_SEED_TRANSACTION),
+			indexBytes,
+		},
+		SQUADS_V4_PROGRAM,
+	)
+	if err != nil {
+		return solana.PublicKey{}, solana.PublicKey{}, fmt.Errorf("failed to derive transaction PDA: %w", err)
+	}
+
+	proposal, _, err = solana.FindProgramAddress(
+		[][]byte{
+			[]byte(SQUADS_SEED_PREFIX),
+			multisig.Bytes(),
+			[]byte(SQUADS_SEED_TRANSACTION),
+			indexBytes,
+			[]byte(SQUADS_SEED_PROPOSAL),
+		},
+		SQUADS_V4_PROGRAM,
+	)
+	if err != nil {
+		return solana.PublicKey{}, solana.PublicKey{}, fmt.Errorf("failed to derive proposal PDA: %w", err)
+	}
+
+	return transaction, proposal, nil
+}
+
+// fetchSquadsTransactionIndex reads the last used transaction index of a multisig
+func fetchSquadsTransactionIndex(ctx context.Context, client ChainClient, multisig solana.PublicKey) (uint64, error) {
+	accountInfo, err := client.GetAccountInfo(ctx, multisig)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get multisig account: %w", err)
+	}
+
+	if !accountInfo.Value.Owner.Equals(SQUADS_V4_PROGRAM) {
+		return 0, fmt.Errorf("%s is not a Squads v4 multisig (owner %s)", multisig, accountInfo.Value.Owner)
+	}
+
+	data := accountInfo.Value.Data.GetBinary()
+	if len(data) < SQUADS_TX_INDEX_OFFSET+8 {
+		return 0, fmt.Errorf("invalid multisig data size: %d", len(data))
+	}
+
+	return binary.LittleEndian.Uint64(data[SQUADS_TX_INDEX_OFFSET : SQUADS_TX_INDEX_OFFSET+8]), nil
+}
+
+// encodeSquadsMessage serializes a compiled message in the Squads
+// TransactionMessage format (u8-prefixed vectors, u16-prefixed instruction data)
+func encodeSquadsMessage(message solana.Message) ([]byte, error) {
+	header := message.Header
+	numKeys := len(message.AccountKeys)
+	if numKeys > 255 {
+		return nil, fmt.Errorf("too many accounts for a vault transaction: %d", numKeys)
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteByte(header.NumRequiredSignatures)
+	buf.WriteByte(header.NumRequiredSignatures - header.NumReadonlySignedAccounts)
+	buf.WriteByte(uint8(numKeys - int(header.NumRequiredSignatures) - int(header.NumReadonlyUnsignedAccounts)))
+
+	buf.WriteByte(uint8(numKeys))
+	for _, key := range message.AccountKeys {
+		buf.Write(key.Bytes())
+	}
+
+	buf.WriteByte(uint8(len(message.Instructions)))
+	for _, ix := range message.Instructions {
+		buf.WriteByte(uint8(ix.ProgramIDIndex))
+
+		buf.WriteByte(uint8(len(ix.Accounts)))
+		for _, index := range ix.Accounts {
+			buf.WriteByte(uint8(index))
+		}
+
+		dataLen := make([]byte, 2)
+		binary.LittleEndian.PutUint16(dataLen, uint16(len(ix.Data)))
+		buf.Write(dataLen)
+		buf.Write(ix.Data)
+	}
+
+	// No address lookup tables
+	buf.WriteByte(0)
+
+	return buf.Bytes(), nil
+}
+
+// buildSquadsProposal wraps an inner vault transaction in Squads v4
+// vaultTransactionCreate, proposalCreate and proposalApprove instructions
+func buildSquadsProposal(
+	multisig solana.PublicKey,
+	vaultIndex uint8,
+	transactionIndex uint64,
+	creator solana.PublicKey,
+	inner *solana.Transaction,
+) ([]solana.Instruction, error) {
+	transactionPDA, proposalPDA, err := deriveSquadsTransaction(multisig, transactionIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := encodeSquadsMessage(inner.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	// vault_transaction_create(VaultTransactionCreateArgs)
+	createData := new(bytes.Buffer)
+	createData.Write(anchorDiscriminator("vault_transaction_create"))
+	createData.WriteByte(vaultIndex)
+	createData.WriteByte(0) // ephemeral_signers
+	messageLen := make([]byte, 4)
+	binary.LittleEndian.PutUint32(messageLen, uint32(len(message)))
+	createData.Write(messageLen)
+	createData.Write(message)
+	createData.WriteByte(0) // memo: None
+
+	createIx := solana.NewInstruction(
+		SQUADS_V4_PROGRAM,
+		[]*solana.AccountMeta{
+			{PublicKey: multisig, IsSigner: false, IsWritable: true},
+			{PublicKey: transactionPDA, IsSigner: false, IsWritable: true},
+			{PublicKey: creator, IsSigner: true, IsWritable: false},
+			{PublicKey: creator, IsSigner: true, IsWritable: true}, // rent payer
+			{PublicKey: solana.SystemProgramID, IsSigner: false, IsWritable: false},
+		},
+		createData.Bytes(),
+	)
+
+	// proposal_create(ProposalCreateArgs)
+	proposalData := new(bytes.Buffer)
+	proposalData.Write(anchorDiscriminator("proposal_create"))
+	indexBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(indexBytes, transactionIndex)
+	proposalData.Write(indexBytes)
+	proposalData.WriteByte(0) // draft: false
+
+	proposalIx := solana.NewInstruction(
+		SQUADS_V4_PROGRAM,
+		[]*solana.AccountMeta{
+			{PublicKey: multisig, IsSigner: false, IsWritable: false},
+			{PublicKey: proposalPDA, IsSigner: false, IsWritable: true},
+			{PublicKey: creator, IsSigner: true, IsWritable: false},
+			{PublicKey: creator, IsSigner: true, IsWritable: true}, // rent payer
+			{PublicKey: solana.SystemProgramID, IsSigner: false, IsWritable: false},
+		},
+		proposalData.Bytes(),
+	)
+
+	// proposal_approve(ProposalVoteArgs) - the creator votes for its own proposal
+	approveData := new(bytes.Buffer)
+	approveData.Write(anchorDiscriminator("proposal_approve"))
+	approveData.WriteByte(0) // memo: None
+
+	approveIx := solana.NewInstruction(
+		SQUADS_V4_PROGRAM,
+		[]*solana.AccountMeta{
+			{PublicKey: multisig, IsSigner: false, IsWritable: false},
+			{PublicKey: creator, IsSigner: true, IsWritable: true},
+			{PublicKey: proposalPDA, IsSigner: false, IsWritable: true},
+		},
+		approveData.Bytes(),
+	)
+
+	return []solana.Instruction{createIx, proposalIx, approveIx}, nil
+}
+
+// proposeMultisigSwap builds the swap with the Squads vault as token owner and
+// submits it as a multisig proposal signed by the local wallet as creator
+func proposeMultisigSwap(
+	ctx context.Context,
+	client ChainClient,
+	wallet solana.PrivateKey,
+	multisig solana.PublicKey,
+	vaultIndex uint8,
+	poolAddress string,
+	side string,
+	amountIn float64,
+	minAmountOut uint64,
+	opts BuildOptions,
+) (string, error) {
+	vault, err := deriveSquadsVault(multisig, vaultIndex)
+	if err != nil {
+		return "", err
+	}
+
+	inner, err := buildSwapTransaction(ctx, client, vault, poolAddress, side, amountIn, minAmountOut, opts)
+	if err != nil {
+		return "", err
+	}
+
+	lastIndex, err := fetchSquadsTransactionIndex(ctx, client, multisig)
+	if err != nil {
+		return "", err
+	}
+	transactionIndex := lastIndex + 1
+
+	instructions, err := buildSquadsProposal(multisig, vaultIndex, transactionIndex, wallet.PublicKey(), inner)
+	if err != nil {
+		return "", err
+	}
+
+	latestBlockhash, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return "", fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+
+	tx, err := solana.NewTransaction(
+		instructions,
+		latestBlockhash.Value.Blockhash,
+		solana.TransactionPayer(wallet.PublicKey()),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if err := signTransaction(tx, wallet); err != nil {
+		return "", err
+	}
+
+	_, proposalPDA, err := deriveSquadsTransaction(multisig, transactionIndex)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("\n=== SQUADS PROPOSAL ===\n")
+	fmt.Printf("Multisig: %s\n", multisig)
+	fmt.Printf("Vault: %s (index %d)\n", vault, vaultIndex)
+	fmt.Printf("Transaction Index: %d\n", transactionIndex)
+	fmt.Printf("Proposal: %s\n", proposalPDA)
+	fmt.Printf("=======================\n")
+
+	sig, err := sendAndConfirmTransaction(ctx, client, tx, opts)
+	if err != nil {
+		return "", err
+	}
+
+	return sig.String(), nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	NOTIFY_SECRET_ENV_VAR = "NOTIFY_SECRET"
+	NOTIFY_MAX_ATTEMPTS   = 5
+	NOTIFY_BASE_BACKOFF   = 500 * time.Millisecond
+	NOTIFY_TIMEOUT        = 10 * time.Second
+)
+
+// SwapNotification is the JSON body POSTed to the webhook
+type SwapNotification struct {
+	Event  string             `json:"event"`  // swap.completed or swap.failed
+	Status string             `json:"status"` // success or failed
+	Error  string             `json:"error,omitempty"`
+	TxHash string             `json:"tx_hash,omitempty"`
+	Pool   string             `json:"pool_address"`
+	Side   string             `json:"side"`
+	Amount float64            `json:"amount"`
+	Report *TransactionReport `json:"report,omitempty"`
+	SentAt time.Time          `json:"sent_at"`
+}
+
+// WebhookNotifier POSTs signed swap notifications to a webhook URL
+type WebhookNotifier struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// newWebhookNotifier returns nil when url is empty so callers can notify unconditionally
+func newWebhookNotifier(url, secret string) *WebhookNotifier {
+	if url == "" {
+		return nil
+	}
+	return &WebhookNotifier{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: NOTIFY_TIMEOUT},
+	}
+}
+
+// signWebhook computes the hex HMAC-SHA256 of "timestamp.body"
+func signWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notify delivers the notification, retrying network errors, 429 and 5xx
+// responses with exponential backoff
+func (n *WebhookNotifier) Notify(ctx context.Context, notification SwapNotification) error {
+	if n == nil {
+		return nil
+	}
+
+	notification.SentAt = time.Now().UTC()
+	return n.deliver(ctx, notification)
+}
+
+// deliver POSTs a JSON payload with the retry policy of Notify
+func (n *WebhookNotifier) deliver(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	backoff := NOTIFY_BASE_BACKOFF
+	var lastErr error
+	for attempt := 1; attempt <= NOTIFY_MAX_ATTEMPTS; attempt++ {
+		retry, err := n.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry || attempt == NOTIFY_MAX_ATTEMPTS {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return fmt.Errorf("failed to deliver webhook: %w", lastErr)
+}
+
+// post sends one signed request and reports whether a failure is retryable
+func (n *WebhookNotifier) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	if len(n.secret) > 0 {
+		req.Header.Set("X-Webhook-Signature", "sha256="+signWebhook(n.secret, timestamp, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook returned %s", resp.Status)
+}
+
+// notifySwap sends a swap outcome, printing delivery failures as warnings
+func notifySwap(ctx context.Context, notifier *WebhookNotifier, notification SwapNotification) {
+	if notifier == nil {
+		return
+	}
+	notification.Status, notification.Event = "success", "swap.completed"
+	if notification.Error != "" {
+		notification.Status, notification.Event = "failed", "swap.failed"
+	}
+	if err := notifier.Notify(ctx, notification); err != nil {
+		fmt.Printf("Warning: Could not send webhook notification: %v\n", err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// ALTERNATE_POOL_MIN_LIQUIDITY is the fraction of the best pool's SOL reserves
+// a pool needs to be considered equivalent for alternation
+const ALTERNATE_POOL_MIN_LIQUIDITY = 0.5
+
+// ObfuscationConfig controls trade fingerprint randomization. Each strategy
+// carries its own config so aggressive and passive strategies can differ.
+type ObfuscationConfig struct {
+	SizeJitterPct  float64       // randomize size by up to ±SizeJitterPct percent
+	TimingJitter   time.Duration // wait a random delay in [0, TimingJitter) before sending
+	AlternatePools bool          // rotate between pools of comparable liquidity
+}
+
+// Enabled reports whether any randomization is configured
+func (c ObfuscationConfig) Enabled() bool {
+	return c.SizeJitterPct > 0 || c.TimingJitter > 0 || c.AlternatePools
+}
+
+// Validate checks the configuration is within sane bounds
+func (c ObfuscationConfig) Validate() error {
+	if c.SizeJitterPct < 0 || c.SizeJitterPct >= 100 {
+		return fmt.Errorf("size jitter must be between 0 and 100 percent")
+	}
+	if c.TimingJitter < 0 {
+		return fmt.Errorf("timing jitter must not be negative")
+	}
+	return nil
+}
+
+// randomizeSize returns amount scaled by a uniform random factor in ±SizeJitterPct
+func (c ObfuscationConfig) randomizeSize(amount float64) float64 {
+	if c.SizeJitterPct <= 0 {
+		return amount
+	}
+	factor := 1 + (rand.Float64()*2-1)*c.SizeJitterPct/100
+	return amount * factor
+}
+
+// randomDelay returns a random delay in [0, TimingJitter)
+func (c ObfuscationConfig) randomDelay() time.Duration {
+	if c.TimingJitter <= 0 {
+		return 0
+	}
+	return rand.N(c.TimingJitter)
+}
+
+// pickPool chooses the pool to trade on. Without alternation it is the pool
+// paying the most for the trade; with alternation it is a random pool among
+// those holding at least ALTERNATE_POOL_MIN_LIQUIDITY of that pool's SOL reserves.
+func (c ObfuscationConfig) pickPool(pools []*OnChainPool, side string, amount float64) *OnChainPool {
+	best := selectPoolForTrade(pools, side, amount)
+	if !c.AlternatePools || best == nil {
+		return best
+	}
+
+	threshold := float64(solReserves(best)) * ALTERNATE_POOL_MIN_LIQUIDITY
+	var equivalent []*OnChainPool
+	for _, pool := range pools {
+		if float64(solReserves(pool)) >= threshold {
+			equivalent = append(equivalent, pool)
+		}
+	}
+
+	return equivalent[rand.IntN(len(equivalent))]
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+// OPENBOOK_V2_PROGRAM is the OpenBook v2 order book program
+var OPENBOOK_V2_PROGRAM = solana.MustPublicKeyFromBase58("opnb2LAfJYbRMAHHvqjCwQxanZn7ReEHp1k81EohpZb")
+
+// OpenBook v2 account layouts
+const (
+	OBV2_MARKET_MIN_SIZE    = 1000
+	OBV2_BASE_MINT_OFFSET   = 864
+	OBV2_QUOTE_MINT_OFFSET  = 896
+	OBV2_FEES_SCALE         = 1_000_000
+	OBV2_BOOKSIDE_ROOT      = 8   // fixed-price order tree root (maybe_node, leaf_count)
+	OBV2_BOOKSIDE_NODES     = 840 // first order tree node
+	OBV2_NODE_SIZE          = 88
+	OBV2_MAX_NODES          = 1024
+	OBV2_NODE_TAG_INNER     = uint8(1)
+	OBV2_NODE_TAG_LEAF      = uint8(2)
+	OBV2_SIDE_BID           = uint8(0)
+	OBV2_SIDE_ASK           = uint8(1)
+	OBV2_ORDER_TYPE_IOC     = uint8(1)
+	OBV2_TAKE_ORDER_MATCHES = uint8(50)
+)
+
+// OpenbookV2Market is a parsed OpenBook v2 market with its order book
+type OpenbookV2Market struct {
+	Address          solana.PublicKey
+	BaseDecimals     uint8
+	QuoteDecimals    uint8
+	BidsAccount      solana.PublicKey
+	AsksAccount      solana.PublicKey
+	EventHeap        solana.PublicKey
+	QuoteLotSize     int64
+	BaseLotSize      int64
+	TakerFee         int64 // in OBV2_FEES_SCALE units
+	BaseMint         solana.PublicKey
+	QuoteMint        solana.PublicKey
+	MarketBaseVault  solana.PublicKey
+	MarketQuoteVault solana.PublicKey
+	Bids             []BookLevel // best first
+	Asks             []BookLevel // best first
+}
+
+// parseOpenbookV2Market decodes the market fields needed for taking liquidity
+func parseOpenbookV2Market(address solana.PublicKey, data []byte) (*OpenbookV2Market, error) {
+	if len(data) < OBV2_MARKET_MIN_SIZE {
+		return nil, fmt.Errorf("invalid OpenBook v2 market data size: %d", len(data))
+	}
+
+	return &OpenbookV2Market{
+		Address:          address,
+		BaseDecimals:     data[9],
+		QuoteDecimals:    data[10],
+		BidsAccount:      solana.PublicKeyFromBytes(data[200:232]),
+		AsksAccount:      solana.PublicKeyFromBytes(data[232:264]),
+		EventHeap:        solana.PublicKeyFromBytes(data[264:296]),
+		QuoteLotSize:     int64(binary.LittleEndian.Uint64(data[736:744])),
+		BaseLotSize:      int64(binary.LittleEndian.Uint64(data[744:752])),
+		TakerFee:         int64(binary.LittleEndian.Uint64(data[776:784])),
+		BaseMint:         solana.PublicKeyFromBytes(data[864:896]),
+		QuoteMint:        solana.PublicKeyFromBytes(data[896:928]),
+		MarketBaseVault:  solana.PublicKeyFromBytes(data[928:960]),
+		MarketQuoteVault: solana.PublicKeyFromBytes(data[968:1000]),
+	}, nil
+}
+
+// parseOpenbookV2BookSide collects the live fixed-price orders of a book side by
+// walking its crit-bit tree. Oracle-pegged orders live in a second tree and are ignored.
+func parseOpenbookV2BookSide(m *OpenbookV2Market, data []byte, now time.Time) ([]BookLevel, error) {
+	if len(data) < OBV2_BOOKSIDE_NODES+OBV2_MAX_NODES*OBV2_NODE_SIZE {
+		return nil, fmt.Errorf("invalid book side data size: %d", len(data))
+	}
+
+	root := binary.LittleEndian.Uint32(data[OBV2_BOOKSIDE_ROOT : OBV2_BOOKSIDE_ROOT+4])
+	leafCount := binary.LittleEndian.Uint32(data[OBV2_BOOKSIDE_ROOT+4 : OBV2_BOOKSIDE_ROOT+8])
+	if leafCount == 0 {
+		return nil, nil
+	}
+
+	node := func(index uint32) []byte {
+		start := OBV2_BOOKSIDE_NODES + int(index)*OBV2_NODE_SIZE
+		return data[start : start+OBV2_NODE_SIZE]
+	}
+
+	var orders []BookLevel
+	stack := []uint32{root}
+	for len(stack) > 0 && len(orders) < int(leafCount) {
+		index := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if index >= OBV2_MAX_NODES {
+			return nil, fmt.Errorf("invalid order tree node index %d", index)
+		}
+
+		n := node(index)
+		switch n[0] {
+		case OBV2_NODE_TAG_INNER:
+			stack = append(stack, binary.LittleEndian.Uint32(n[24:28]), binary.LittleEndian.Uint32(n[28:32]))
+		case OBV2_NODE_TAG_LEAF:
+			// Skip orders whose time in force has elapsed
+			timeInForce := binary.LittleEndian.Uint16(n[2:4])
+			timestamp := binary.LittleEndian.Uint64(n[64:72])
+			if timeInForce > 0 && int64(timestamp)+int64(timeInForce) < now.Unix() {
+				continue
+			}
+
+			// The high 64 bits of the u128 key hold the price in lots
+			priceLots := binary.LittleEndian.Uint64(n[16:24])
+			quantity := int64(binary.LittleEndian.Uint64(n[56:64]))
+			orders = append(orders, BookLevel{
+				Price: float64(priceLots) * float64(m.QuoteLotSize) / float64(m.BaseLotSize),
+				Size:  float64(quantity * m.BaseLotSize),
+			})
+		default:
+			return nil, fmt.Errorf("unexpected order tree node tag %d", n[0])
+		}
+	}
+
+	return orders, nil
+}
+
+// findOpenbookV2Market finds the OpenBook v2 market pairing token with SOL and
+// loads its book, returning nil if none exists
+func findOpenbookV2Market(ctx context.Context, client ChainClient, tokenMint solana.PublicKey) (*OpenbookV2Market, error) {
+	var markets []*OpenbookV2Market
+	for _, mints := range [][2]solana.PublicKey{{tokenMint, WSOL_MINT}, {WSOL_MINT, tokenMint}} {
+		accounts, err := client.GetProgramAccountsWithOpts(
+			ctx,
+			OPENBOOK_V2_PROGRAM,
+			&rpc.GetProgramAccountsOpts{
+				Filters: []rpc.RPCFilter{
+					{Memcmp: &rpc.RPCFilterMemcmp{Offset: OBV2_BASE_MINT_OFFSET, Bytes: mints[0].Bytes()}},
+					{Memcmp: &rpc.RPCFilterMemcmp{Offset: OBV2_QUOTE_MINT_OFFSET, Bytes: mints[1].Bytes()}},
+				},
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get OpenBook v2 markets: %w", err)
+		}
+
+		for _, account := range accounts {
+			market, err := parseOpenbookV2Market(account.Pubkey, account.Account.Data.GetBinary())
+			if err != nil {
+				continue
+			}
+			markets = append(markets, market)
+		}
+	}
+
+	var best *OpenbookV2Market
+	for _, market := range markets {
+		sides, err := client.GetMultipleAccounts(ctx, market.BidsAccount, market.AsksAccount)
+		if err != nil || len(sides.Value) != 2 || sides.Value[0] == nil || sides.Value[1] == nil {
+			fmt.Printf("Warning: Could not load OpenBook v2 book for market %s\n", market.Address)
+			continue
+		}
+
+		now := time.Now()
+		bids, err := parseOpenbookV2BookSide(market, sides.Value[0].Data.GetBinary(), now)
+		if err != nil {
+			fmt.Printf("Warning: Skipping OpenBook v2 market %s: %v\n", market.Address, err)
+			continue
+		}
+		asks, err := parseOpenbookV2BookSide(market, sides.Value[1].Data.GetBinary(), now)
+		if err != nil {
+			fmt.Printf("Warning: Skipping OpenBook v2 market %s: %v\n", market.Address, err)
+			continue
+		}
+		market.Bids = aggregateLevels(bids, true)
+		market.Asks = aggregateLevels(asks, false)
+
+		if best == nil || len(market.Bids)+len(market.Asks) > len(best.Bids)+len(best.Asks) {
+			best = market
+		}
+	}
+
+	return best, nil
+}
+
+// createOpenbookV2TakeOrderInstruction creates a place_take_order instruction,
+// which fills against the book without an open orders account
+func createOpenbookV2TakeOrderInstruction(
+	m *OpenbookV2Market,
+	owner solana.PublicKey,
+	userBase solana.PublicKey,
+	userQuote solana.PublicKey,
+	side uint8,
+	priceLots int64,
+	maxBaseLots int64,
+	maxQuoteLots int64,
+) (solana.Instruction, error) {
+	marketAuthority, _, err := solana.FindProgramAddress([][]byte{[]byte("Market"), m.Address.Bytes()}, OPENBOOK_V2_PROGRAM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive market authority: %w", err)
+	}
+
+	data := make([]byte, 0, 34)
+	data = append(data, anchorDiscriminator("place_take_order")...)
+	data = append(data, side)
+	data = binary.LittleEndian.AppendUint64(data, uint64(priceLots))
+	data = binary.LittleEndian.AppendUint64(data, uint64(maxBaseLots))
+	data = binary.LittleEndian.AppendUint64(data, uint64(maxQuoteLots))
+	data = append(data, OBV2_ORDER_TYPE_IOC, OBV2_TAKE_ORDER_MATCHES)
+
+	accounts := []*solana.AccountMeta{
+		// 0. Signer
+		{PublicKey: owner, IsSigner: true, IsWritable: true},
+		// 1. Penalty payer
+		{PublicKey: owner, IsSigner: true, IsWritable: true},
+		// 2. Market
+		{PublicKey: m.Address, IsSigner: false, IsWritable: true},
+		// 3. Market authority
+		{PublicKey: marketAuthority, IsSigner: false, IsWritable: false},
+		// 4. Bids
+		{PublicKey: m.BidsAccount, IsSigner: false, IsWritable: true},
+		// 5. Asks
+		{PublicKey: m.AsksAccount, IsSigner: false, IsWritable: true},
+		// 6. Market base vault
+		{PublicKey: m.MarketBaseVault, IsSigner: false, IsWritable: true},
+		// 7. Market quote vault
+		{PublicKey: m.MarketQuoteVault, IsSigner: false, IsWritable: true},
+		// 8. Event heap
+		{PublicKey: m.EventHeap, IsSigner: false, IsWritable: true},
+		// 9. User base account
+		{PublicKey: userBase, IsSigner: false, IsWritable: true},
+		// 10. User quote account
+		{PublicKey: userQuote, IsSigner: false, IsWritable: true},
+		// 11. Oracle A (none; optional accounts are passed as the program ID)
+		{PublicKey: OPENBOOK_V2_PROGRAM, IsSigner: false, IsWritable: false},
+		// 12. Oracle B (none)
+		{PublicKey: OPENBOOK_V2_PROGRAM, IsSigner: false, IsWritable: false},
+		// 13. Token program
+		{PublicKey: token.ProgramID, IsSigner: false, IsWritable: false},
+		// 14. System program
+		{PublicKey: solana.SystemProgramID, IsSigner: false, IsWritable: false},
+		// 15. Open orders admin (none)
+		{PublicKey: OPENBOOK_V2_PROGRAM, IsSigner: false, IsWritable: false},
+	}
+
+	return solana.NewInstruction(OPENBOOK_V2_PROGRAM, accounts, data), nil
+}
+
+// openbookV2VenueQuote quotes a SOL/token swap against the market's book,
+// returning nil if the book cannot fill the size
+func openbookV2VenueQuote(m *OpenbookV2Market, side string, amount float64) *VenueQuote {
+	solIsBase := m.BaseMint.Equals(WSOL_MINT)
+	tokenMint, tokenDecimals := m.BaseMint, int(m.BaseDecimals)
+	if solIsBase {
+		tokenMint, tokenDecimals = m.QuoteMint, int(m.QuoteDecimals)
+	}
+
+	inDecimals, outDecimals := SOL_DECIMALS, tokenDecimals
+	if side == "sell" {
+		inDecimals, outDecimals = tokenDecimals, SOL_DECIMALS
+	}
+	amountIn, err := atoms.FromAmount(amount, inDecimals)
+	if err != nil {
+		return nil
+	}
+
+	// Spending SOL sells base when SOL is the base asset
+	inIsBase := (side == "buy") == solIsBase
+	levels := m.Asks
+	if inIsBase {
+		levels = m.Bids
+	}
+
+	takerFeeBps := uint64(max(m.TakerFee, 0)) * 10000 / OBV2_FEES_SCALE
+	out := walkBook(levels, float64(amountIn), inIsBase, takerFeeBps)
+	if out <= 0 {
+		return nil
+	}
+
+	return &VenueQuote{
+		Venue:          "OpenBook v2",
+		Market:         m.Address,
+		ExpectedOut:    atoms.ToAmount(uint64(out), outDecimals),
+		ExpectedOutRaw: uint64(out),
+		OutDecimals:    outDecimals,
+		buildInstructions: func(ctx context.Context, client ChainClient, owner solana.PublicKey, minAmountOut uint64) ([]solana.Instruction, error) {
+			if minAmountOut == 0 {
+				return nil, fmt.Errorf("minimum output rounds to zero")
+			}
+
+			var solIn uint64
+			if side == "buy" {
+				solIn = amountIn
+			}
+			wsolATA, tokenATA, wrap, err := swapTokenAccounts(ctx, client, owner, tokenMint, solIn)
+			if err != nil {
+				return nil, err
+			}
+
+			userBase, userQuote := tokenATA, wsolATA
+			if solIsBase {
+				userBase, userQuote = wsolATA, tokenATA
+			}
+
+			// The IOC limit price enforces the minimum output: no level worse
+			// than the average price implied by minAmountOut is taken
+			var takeIx solana.Instruction
+			if inIsBase {
+				minPrice := float64(minAmountOut) / float64(amountIn)
+				priceLots := int64(math.Ceil(minPrice * float64(m.BaseLotSize) / float64(m.QuoteLotSize)))
+				takeIx, err = createOpenbookV2TakeOrderInstruction(m, owner, userBase, userQuote,
+					OBV2_SIDE_ASK, max(priceLots, 1), int64(amountIn)/m.BaseLotSize, math.MaxInt64)
+			} else {
+				maxPrice := float64(amountIn) / float64(minAmountOut)
+				priceLots := int64(maxPrice * float64(m.BaseLotSize) / float64(m.QuoteLotSize))
+				takeIx, err = createOpenbookV2TakeOrderInstruction(m, owner, userBase, userQuote,
+					OBV2_SIDE_BID, max(priceLots, 1), math.MaxInt64, int64(amountIn)/m.QuoteLotSize)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			return wrap(takeIx), nil
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+)
+
+// PriceOracle provides reference prices used to value trades in fiat
+type PriceOracle interface {
+	SOLPriceUSD(ctx context.Context) (float64, error)
+}
+
+// poolPriceOracle prices SOL from the reserves of a Raydium V4 SOL/USDC pool
+type poolPriceOracle struct {
+	client      ChainClient
+	poolAddress string
+	cached      float64
+}
+
+// newPoolPriceOracle creates an oracle backed by the SOL/USDC pool from the
+// SOL_USDC_POOL environment variable, or by on-chain discovery when unset
+func newPoolPriceOracle(client ChainClient) *poolPriceOracle {
+	return &poolPriceOracle{
+		client:      client,
+		poolAddress: os.Getenv(SOL_USDC_POOL_ENV_VAR),
+	}
+}
+
+// SOLPriceUSD returns the mid price of SOL in USDC from pool reserves
+func (o *poolPriceOracle) SOLPriceUSD(ctx context.Context) (float64, error) {
+	if o.cached > 0 {
+		return o.cached, nil
+	}
+
+	var pool *OnChainPool
+	if o.poolAddress == "" {
+		found, err := findPoolsOnChain(ctx, o.client, USDC_MINT.String())
+		if err != nil {
+			return 0, fmt.Errorf("failed to find SOL/USDC pool: %w", err)
+		}
+		pool = found
+		o.poolAddress = pool.Address.String()
+	} else {
+		loaded, err := loadPool(ctx, o.client, o.poolAddress)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load SOL/USDC pool: %w", err)
+		}
+		pool = loaded
+	}
+
+	baseReserve := float64(pool.BaseAmount) / math.Pow(10, float64(pool.BaseDecimals))
+	quoteReserve := float64(pool.QuoteAmount) / math.Pow(10, float64(pool.QuoteDecimals))
+	if baseReserve == 0 || quoteReserve == 0 {
+		return 0, fmt.Errorf("SOL/USDC pool %s has empty reserves", pool.Address)
+	}
+
+	// Price is USDC per SOL regardless of which side SOL sits on
+	if pool.BaseMint.Equals(WSOL_MINT) || pool.BaseMint.Equals(SOL_MINT) {
+		o.cached = quoteReserve / baseReserve
+	} else {
+		o.cached = baseReserve / quoteReserve
+	}
+
+	return o.cached, nil
+}
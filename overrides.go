@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"gopkg.in/yaml.v3"
+)
+
+// OVERRIDES_FILE in the state directory holds the per-token and per-pool
+// trade settings
+const OVERRIDES_FILE = "overrides.yaml"
+
+// TradeOverrides are trade settings keyed by token mint or pool address, so
+// volatile tokens get different defaults than blue chips
+type TradeOverrides struct {
+	Tokens map[string]TradeOverride `yaml:"tokens"`
+	Pools  map[string]TradeOverride `yaml:"pools"`
+}
+
+// TradeOverride replaces the defaults of the flags of the same name. Unset
+// fields keep them.
+type TradeOverride struct {
+	Slippage string   `yaml:"slippage" json:"slippage,omitempty"` // percent or auto
+	CUPrice  *uint64  `yaml:"cu_price" json:"cu_price,omitempty"` // micro-lamports
+	Sender   string   `yaml:"sender" json:"sender,omitempty"`
+	Tip      *float64 `yaml:"tip" json:"tip,omitempty"`           // SOL
+	MaxSize  float64  `yaml:"max_size" json:"max_size,omitempty"` // SOL or stablecoin per trade
+}
+
+// overridesPath returns the overrides file in the state directory
+func overridesPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, OVERRIDES_FILE), nil
+}
+
+// loadTradeOverrides reads the overrides file; a missing file has no overrides
+func loadTradeOverrides(path string) (*TradeOverrides, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &TradeOverrides{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var overrides TradeOverrides
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&overrides); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := overrides.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return &overrides, nil
+}
+
+// validate checks every entry's key and settings
+func (o *TradeOverrides) validate() error {
+	for kind, entries := range map[string]map[string]TradeOverride{"token": o.Tokens, "pool": o.Pools} {
+		for key, entry := range entries {
+			if _, err := solana.PublicKeyFromBase58(key); err != nil {
+				return fmt.Errorf("%s %q: invalid address: %w", kind, key, err)
+			}
+			if err := entry.validate(); err != nil {
+				return fmt.Errorf("%s %s: %w", kind, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (o TradeOverride) validate() error {
+	if o.Slippage != "" && o.Slippage != SLIPPAGE_AUTO {
+		if _, err := parseSlippage(o.Slippage); err != nil {
+			return err
+		}
+	}
+	switch o.Sender {
+	case "", SENDER_RPC, SENDER_JITO, SENDER_HELIUS, SENDER_NOZOMI:
+	default:
+		return fmt.Errorf("unknown sender %q (supported: rpc, jito, helius, nozomi)", o.Sender)
+	}
+	if o.Tip != nil && (*o.Tip < 0 || math.IsNaN(*o.Tip)) {
+		return fmt.Errorf("tip must not be negative")
+	}
+	if o.MaxSize < 0 || math.IsNaN(o.MaxSize) {
+		return fmt.Errorf("max_size must not be negative")
+	}
+	return nil
+}
+
+// For merges the token's entry with the pool's, which wins where both are set
+func (o *TradeOverrides) For(mint solana.PublicKey, pool string) (TradeOverride, bool) {
+	var merged TradeOverride
+	token, tokenOK := o.Tokens[mint.String()]
+	if tokenOK {
+		merged = token
+	}
+	entry, poolOK := o.Pools[pool]
+	if poolOK {
+		if entry.Slippage != "" {
+			merged.Slippage = entry.Slippage
+		}
+		if entry.CUPrice != nil {
+			merged.CUPrice = entry.CUPrice
+		}
+		if entry.Sender != "" {
+			merged.Sender = entry.Sender
+		}
+		if entry.Tip != nil {
+			merged.Tip = entry.Tip
+		}
+		if entry.MaxSize > 0 {
+			merged.MaxSize = entry.MaxSize
+		}
+	}
+	return merged, tokenOK || poolOK
+}
+
+// apply replaces the settings of flags not given on the command line
+func (o TradeOverride) apply(given map[string]bool, slippage *string, opts *BuildOptions) {
+	if o.Slippage != "" && !given["slippage"] {
+		*slippage = o.Slippage
+	}
+	if o.CUPrice != nil && !given["cu-price"] {
+		opts.Compute.Price = *o.CUPrice
+	}
+	if o.Sender != "" && !given["sender"] {
+		opts.Sender.Name = o.Sender
+	}
+	if o.Tip != nil && !given["tip"] {
+		opts.Sender.Tip = *o.Tip
+	}
+}
+
+// checkSize refuses trades above the entry's max size: the amount a buy
+// spends, or what a sell is expected to return
+func (o TradeOverride) checkSize(side string, amount float64, expectedOut float64) error {
+	size := amount
+	if side == "sell" {
+		size = expectedOut
+	}
+	if o.MaxSize > 0 && size > o.MaxSize {
+		return fmt.Errorf("trade of %.9f is above the max_size of %g set for this token or pool", size, o.MaxSize)
+	}
+	return nil
+}
+
+// runOverrides dispatches the overrides subcommands
+func runOverrides(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "list":
+			runOverridesList(args[1:])
+			return
+		case "show":
+			runOverridesShow(args[1:])
+			return
+		}
+	}
+	fmt.Println("Usage: go run . overrides list [-json]")
+	fmt.Println("       go run . overrides show [-token MINT] [-pool POOL]")
+	os.Exit(1)
+}
+
+// openTradeOverrides loads the overrides file or exits
+func openTradeOverrides() (string, *TradeOverrides) {
+	path, err := overridesPath()
+	if err != nil {
+		log.Fatal(err)
+	}
+	overrides, err := loadTradeOverrides(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return path, overrides
+}
+
+// describe lists an entry's settings
+func (o TradeOverride) describe() string {
+	var parts []string
+	if o.Slippage != "" {
+		parts = append(parts, "slippage "+o.Slippage)
+	}
+	if o.CUPrice != nil {
+		parts = append(parts, fmt.Sprintf("cu-price %d", *o.CUPrice))
+	}
+	if o.Sender != "" {
+		parts = append(parts, "sender "+o.Sender)
+	}
+	if o.Tip != nil {
+		parts = append(parts, fmt.Sprintf("tip %g", *o.Tip))
+	}
+	if o.MaxSize > 0 {
+		parts = append(parts, fmt.Sprintf("max-size %g", o.MaxSize))
+	}
+	if len(parts) == 0 {
+		return "(none)"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// runOverridesList validates the overrides file and prints its entries
+func runOverridesList(args []string) {
+	fs := newCommandFlagSet("overrides list")
+	asJSON := fs.Bool("json", false, "Print the overrides as JSON")
+	fs.Parse(args)
+
+	path, overrides := openTradeOverrides()
+	if *asJSON {
+		data, _ := json.MarshalIndent(map[string]map[string]TradeOverride{"tokens": overrides.Tokens, "pools": overrides.Pools}, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	if len(overrides.Tokens) == 0 && len(overrides.Pools) == 0 {
+		fmt.Printf("No overrides in %s\n", path)
+		return
+	}
+	fmt.Printf("Overrides in %s:\n", path)
+	for _, kind := range []string{"token", "pool"} {
+		entries := overrides.Tokens
+		if kind == "pool" {
+			entries = overrides.Pools
+		}
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("  %-5s %-44s %s\n", kind, key, entries[key].describe())
+		}
+	}
+}
+
+// runOverridesShow prints the settings a trade of the token or pool would use
+func runOverridesShow(args []string) {
+	fs := newCommandFlagSet("overrides show")
+	tokenAddr := fs.String("token", "", "Token mint")
+	poolAddr := fs.String("pool", "", "Pool address")
+	fs.Parse(args)
+	if *tokenAddr == "" && *poolAddr == "" {
+		log.Fatal("Usage: go run . overrides show [-token MINT] [-pool POOL]")
+	}
+	var mint solana.PublicKey
+	if *tokenAddr != "" {
+		var err error
+		if mint, err = solana.PublicKeyFromBase58(*tokenAddr); err != nil {
+			log.Fatalf("Invalid token address: %v", err)
+		}
+	}
+
+	_, overrides := openTradeOverrides()
+	merged, ok := overrides.For(mint, *poolAddr)
+	if !ok {
+		fmt.Println("No overrides apply; trades use the flag defaults")
+		return
+	}
+	fmt.Printf("Overrides: %s\n", merged.describe())
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	// File in the state directory listing sent transactions not yet confirmed
+	PENDING_TX_FILE = "pending-tx.json"
+	// How long confirmation continues after an interrupt once a transaction is sent
+	TX_SHUTDOWN_GRACE = 10 * time.Second
+	// After this long an unknown signature has outlived its blockhash and cannot land
+	TX_EXPIRY = 2 * time.Minute
+)
+
+// PendingTx is a transaction that was sent but not seen confirmed
+type PendingTx struct {
+	Signature string    `json:"signature"`
+	Cluster   string    `json:"cluster"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// inFlight counts transactions between send and confirmation; inFlightDone
+// lets the interrupt handler wait for them
+var (
+	inFlight     atomic.Int32
+	inFlightDone sync.WaitGroup
+)
+
+// pendingTxPath returns the path of the pending transaction file
+func pendingTxPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, PENDING_TX_FILE), nil
+}
+
+// loadPendingTxs reads the pending transactions, oldest first
+func loadPendingTxs() ([]PendingTx, error) {
+	path, err := pendingTxPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var pending []PendingTx
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return pending, nil
+}
+
+// updatePendingTxs applies update to the pending transactions and writes them back
+func updatePendingTxs(update func([]PendingTx) []PendingTx) error {
+	path, err := pendingTxPath()
+	if err != nil {
+		return err
+	}
+	var pending []PendingTx
+	return updateJSONFile(path, &pending, func() error {
+		pending = update(pending)
+		return nil
+	})
+}
+
+// savePendingTx records a signature before it is sent, so an interrupt or
+// crash at any point afterwards leaves a record to resume from
+func savePendingTx(sig solana.Signature) error {
+	return updatePendingTxs(func(pending []PendingTx) []PendingTx {
+		return append(pending, PendingTx{Signature: sig.String(), Cluster: activeCluster.Name, SentAt: time.Now().UTC()})
+	})
+}
+
+// removePendingTx drops a signature once its outcome is known
+func removePendingTx(sig solana.Signature) error {
+	return updatePendingTxs(func(pending []PendingTx) []PendingTx {
+		return slices.DeleteFunc(pending, func(p PendingTx) bool { return p.Signature == sig.String() })
+	})
+}
+
+// trackInFlight marks a transaction as in flight until the returned func is called
+func trackInFlight() func() {
+	inFlightDone.Add(1)
+	inFlight.Add(1)
+	return func() {
+		inFlight.Add(-1)
+		inFlightDone.Done()
+	}
+}
+
+// interruptContext returns a context cancelled by SIGINT or SIGTERM. With no
+// transaction in flight an interrupt exits at once. Otherwise the context is
+// cancelled, confirmation continues for TX_SHUTDOWN_GRACE, and the process
+// exits when the sender is done. A second interrupt exits immediately; the
+// pending record written before the send survives either way.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		if inFlight.Load() == 0 {
+			os.Exit(130)
+		}
+		cancel()
+
+		done := make(chan struct{})
+		go func() {
+			inFlightDone.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-signals:
+			fmt.Println("\nExiting; check pending transactions with: go run . tx status")
+		}
+		printRPCStats()
+		os.Exit(130)
+	}()
+
+	return ctx
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+// PHOENIX_PROGRAM is the Phoenix order book program
+var PHOENIX_PROGRAM = solana.MustPublicKeyFromBase58("PhoeNiXZ8ByJGLkxNfZRnkUfjvmuYqLR89jKMHgj1iK")
+
+// Phoenix market account layout
+const (
+	PHOENIX_HEADER_SIZE       = 576
+	PHOENIX_BASE_MINT_OFFSET  = 48
+	PHOENIX_QUOTE_MINT_OFFSET = 128
+	PHOENIX_TAKER_FEE_OFFSET  = PHOENIX_HEADER_SIZE + 256 + 24
+	PHOENIX_BIDS_OFFSET       = PHOENIX_HEADER_SIZE + 256 + 48
+	PHOENIX_TREE_HEADER_SIZE  = 32  // root + padding + allocator size, bump index and free list head
+	PHOENIX_ORDER_NODE_SIZE   = 64  // registers + FIFOOrderId + FIFORestingOrder
+	PHOENIX_TRADER_NODE_SIZE  = 144 // registers + Pubkey + TraderState
+	PHOENIX_SWAP_INSTRUCTION  = uint8(0)
+	PHOENIX_ORDER_IOC         = uint8(2)
+	PHOENIX_SIDE_BID          = uint8(0)
+	PHOENIX_SIDE_ASK          = uint8(1)
+)
+
+// PhoenixMarket is a parsed Phoenix market with its order book
+type PhoenixMarket struct {
+	Address                 solana.PublicKey
+	BaseMint                solana.PublicKey
+	QuoteMint               solana.PublicKey
+	BaseVault               solana.PublicKey
+	QuoteVault              solana.PublicKey
+	BaseDecimals            uint32
+	QuoteDecimals           uint32
+	BaseLotSize             uint64
+	QuoteLotSize            uint64
+	TickSize                uint64 // quote atoms per base unit per tick
+	RawBaseUnitsPerBaseUnit uint32
+	TakerFeeBps             uint64
+	Bids                    []BookLevel // best first
+	Asks                    []BookLevel // best first
+	Traders                 []solana.PublicKey
+}
+
+// rbTreeNodes returns the key+value payload of every node reachable from the
+// root of a sokoban red-black tree, in key order
+func rbTreeNodes(data []byte, offset int, maxSize uint64, nodeSize int) ([][]byte, error) {
+	end := offset + PHOENIX_TREE_HEADER_SIZE + int(maxSize)*nodeSize
+	if len(data) < end {
+		return nil, fmt.Errorf("tree at offset %d exceeds account data", offset)
+	}
+
+	nodes := data[offset+PHOENIX_TREE_HEADER_SIZE : end]
+	node := func(index uint32) []byte {
+		// Node indexes are 1-based; 0 is the sentinel
+		start := int(index-1) * nodeSize
+		return nodes[start : start+nodeSize]
+	}
+	register := func(index uint32, r int) uint32 {
+		return binary.LittleEndian.Uint32(node(index)[r*4 : r*4+4])
+	}
+
+	var payloads [][]byte
+	var stack []uint32
+	current := binary.LittleEndian.Uint32(data[offset : offset+4])
+	for (current != 0 || len(stack) > 0) && len(payloads) <= int(maxSize) {
+		for current != 0 {
+			if uint64(current) > maxSize {
+				return nil, fmt.Errorf("invalid tree node index %d", current)
+			}
+			stack = append(stack, current)
+			current = register(current, 0) // left
+		}
+		current = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		payloads = append(payloads, node(current)[16:])
+		current = register(current, 1) // right
+	}
+
+	return payloads, nil
+}
+
+// parsePhoenixMarket decodes a market header and its bids, asks and seats
+func parsePhoenixMarket(address solana.PublicKey, data []byte) (*PhoenixMarket, error) {
+	if len(data) < PHOENIX_BIDS_OFFSET {
+		return nil, fmt.Errorf("invalid Phoenix market data size: %d", len(data))
+	}
+
+	m := &PhoenixMarket{
+		Address:                 address,
+		BaseDecimals:            binary.LittleEndian.Uint32(data[40:44]),
+		BaseMint:                solana.PublicKeyFromBytes(data[48:80]),
+		BaseVault:               solana.PublicKeyFromBytes(data[80:112]),
+		BaseLotSize:             binary.LittleEndian.Uint64(data[112:120]),
+		QuoteDecimals:           binary.LittleEndian.Uint32(data[120:124]),
+		QuoteMint:               solana.PublicKeyFromBytes(data[128:160]),
+		QuoteVault:              solana.PublicKeyFromBytes(data[160:192]),
+		QuoteLotSize:            binary.LittleEndian.Uint64(data[192:200]),
+		TickSize:                binary.LittleEndian.Uint64(data[200:208]),
+		RawBaseUnitsPerBaseUnit: binary.LittleEndian.Uint32(data[312:316]),
+		TakerFeeBps:             binary.LittleEndian.Uint64(data[PHOENIX_TAKER_FEE_OFFSET : PHOENIX_TAKER_FEE_OFFSET+8]),
+	}
+	if m.RawBaseUnitsPerBaseUnit == 0 {
+		m.RawBaseUnitsPerBaseUnit = 1
+	}
+
+	bidsSize := binary.LittleEndian.Uint64(data[16:24])
+	asksSize := binary.LittleEndian.Uint64(data[24:32])
+	numSeats := binary.LittleEndian.Uint64(data[32:40])
+
+	// Price per base atom in quote atoms for one tick
+	tickPrice := float64(m.TickSize) / (float64(m.RawBaseUnitsPerBaseUnit) * math.Pow(10, float64(m.BaseDecimals)))
+
+	orders := func(offset int, size uint64) ([]BookLevel, error) {
+		payloads, err := rbTreeNodes(data, offset, size, PHOENIX_ORDER_NODE_SIZE)
+		if err != nil {
+			return nil, err
+		}
+		levels := make([]BookLevel, 0, len(payloads))
+		for _, p := range payloads {
+			// FIFOOrderId{price_in_ticks, sequence} + FIFORestingOrder{trader_index, num_base_lots, ...}
+			ticks := binary.LittleEndian.Uint64(p[0:8])
+			lots := binary.LittleEndian.Uint64(p[24:32])
+			levels = append(levels, BookLevel{
+				Price: float64(ticks) * tickPrice,
+				Size:  float64(lots * m.BaseLotSize),
+			})
+		}
+		return levels, nil
+	}
+
+	bids, err := orders(PHOENIX_BIDS_OFFSET, bidsSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bids: %w", err)
+	}
+	asksOffset := PHOENIX_BIDS_OFFSET + PHOENIX_TREE_HEADER_SIZE + int(bidsSize)*PHOENIX_ORDER_NODE_SIZE
+	asks, err := orders(asksOffset, asksSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse asks: %w", err)
+	}
+	m.Bids = aggregateLevels(bids, true)
+	m.Asks = aggregateLevels(asks, false)
+
+	tradersOffset := asksOffset + PHOENIX_TREE_HEADER_SIZE + int(asksSize)*PHOENIX_ORDER_NODE_SIZE
+	traders, err := rbTreeNodes(data, tradersOffset, numSeats, PHOENIX_TRADER_NODE_SIZE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse seats: %w", err)
+	}
+	for _, p := range traders {
+		m.Traders = append(m.Traders, solana.PublicKeyFromBytes(p[0:32]))
+	}
+
+	return m, nil
+}
+
+// hasSeat reports whether trader holds a seat on the market. Seats are only
+// needed to rest orders or trade deposited funds; IOC swaps settle directly
+// from the trader's token accounts.
+func (m *PhoenixMarket) hasSeat(trader solana.PublicKey) bool {
+	for _, t := range m.Traders {
+		if t.Equals(trader) {
+			return true
+		}
+	}
+	return false
+}
+
+// findPhoenixMarket finds the Phoenix market pairing token with SOL, returning nil if none exists
+func findPhoenixMarket(ctx context.Context, client ChainClient, tokenMint solana.PublicKey) (*PhoenixMarket, error) {
+	var best *PhoenixMarket
+	for _, mints := range [][2]solana.PublicKey{{tokenMint, WSOL_MINT}, {WSOL_MINT, tokenMint}} {
+		accounts, err := client.GetProgramAccountsWithOpts(
+			ctx,
+			PHOENIX_PROGRAM,
+			&rpc.GetProgramAccountsOpts{
+				Filters: []rpc.RPCFilter{
+					{Memcmp: &rpc.RPCFilterMemcmp{Offset: PHOENIX_BASE_MINT_OFFSET, Bytes: mints[0].Bytes()}},
+					{Memcmp: &rpc.RPCFilterMemcmp{Offset: PHOENIX_QUOTE_MINT_OFFSET, Bytes: mints[1].Bytes()}},
+				},
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Phoenix markets: %w", err)
+		}
+
+		for _, account := range accounts {
+			market, err := parsePhoenixMarket(account.Pubkey, account.Account.Data.GetBinary())
+			if err != nil {
+				fmt.Printf("Warning: Skipping Phoenix market %s: %v\n", account.Pubkey, err)
+				continue
+			}
+			if best == nil || len(market.Bids)+len(market.Asks) > len(best.Bids)+len(best.Asks) {
+				best = market
+			}
+		}
+	}
+
+	return best, nil
+}
+
+// encodePhoenixIOC encodes a Swap instruction carrying an immediate-or-cancel order packet
+func encodePhoenixIOC(side uint8, numBaseLots uint64, numQuoteLots uint64, minBaseLots uint64, minQuoteLots uint64) []byte {
+	data := []byte{PHOENIX_SWAP_INSTRUCTION, PHOENIX_ORDER_IOC, side}
+	u64 := func(v uint64) {
+		data = binary.LittleEndian.AppendUint64(data, v)
+	}
+
+	data = append(data, 0) // price_in_ticks: None (market order)
+	u64(numBaseLots)
+	u64(numQuoteLots)
+	u64(minBaseLots)
+	u64(minQuoteLots)
+	data = append(data, 2)                   // self_trade_behavior: DecrementTake
+	data = append(data, 0)                   // match_limit: None
+	data = append(data, make([]byte, 16)...) // client_order_id
+	data = append(data, 0)                   // use_only_deposited_funds
+	data = append(data, 0)                   // last_valid_slot: None
+	data = append(data, 0)                   // last_valid_unix_timestamp_in_seconds: None
+	return data
+}
+
+// createPhoenixSwapInstruction creates a Phoenix Swap instruction
+func createPhoenixSwapInstruction(m *PhoenixMarket, trader solana.PublicKey, baseAccount solana.PublicKey, quoteAccount solana.PublicKey, data []byte) (solana.Instruction, error) {
+	logAuthority, _, err := solana.FindProgramAddress([][]byte{[]byte("log")}, PHOENIX_PROGRAM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive log authority: %w", err)
+	}
+
+	accounts := []*solana.AccountMeta{
+		// 0. Phoenix program
+		{PublicKey: PHOENIX_PROGRAM, IsSigner: false, IsWritable: false},
+		// 1. Log authority
+		{PublicKey: logAuthority, IsSigner: false, IsWritable: false},
+		// 2. Market
+		{PublicKey: m.Address, IsSigner: false, IsWritable: true},
+		// 3. Trader (signer)
+		{PublicKey: trader, IsSigner: true, IsWritable: false},
+		// 4. Trader base account
+		{PublicKey: baseAccount, IsSigner: false, IsWritable: true},
+		// 5. Trader quote account
+		{PublicKey: quoteAccount, IsSigner: false, IsWritable: true},
+		// 6. Base vault
+		{PublicKey: m.BaseVault, IsSigner: false, IsWritable: true},
+		// 7. Quote vault
+		{PublicKey: m.QuoteVault, IsSigner: false, IsWritable: true},
+		// 8. Token program
+		{PublicKey: token.ProgramID, IsSigner: false, IsWritable: false},
+	}
+
+	return solana.NewInstruction(PHOENIX_PROGRAM, accounts, data), nil
+}
+
+// phoenixVenueQuote quotes a SOL/token swap against the market's book,
+// returning nil if the book cannot fill the size
+func phoenixVenueQuote(m *PhoenixMarket, side string, amount float64) *VenueQuote {
+	solIsBase := m.BaseMint.Equals(WSOL_MINT)
+	tokenMint, tokenDecimals := m.BaseMint, int(m.BaseDecimals)
+	if solIsBase {
+		tokenMint, tokenDecimals = m.QuoteMint, int(m.QuoteDecimals)
+	}
+
+	inDecimals, outDecimals := SOL_DECIMALS, tokenDecimals
+	if side == "sell" {
+		inDecimals, outDecimals = tokenDecimals, SOL_DECIMALS
+	}
+	amountIn, err := atoms.FromAmount(amount, inDecimals)
+	if err != nil {
+		return nil
+	}
+
+	// Spending SOL sells base when SOL is the base asset
+	inIsBase := (side == "buy") == solIsBase
+	levels := m.Asks
+	if inIsBase {
+		levels = m.Bids
+	}
+
+	out := walkBook(levels, float64(amountIn), inIsBase, m.TakerFeeBps)
+	if out <= 0 {
+		return nil
+	}
+
+	return &VenueQuote{
+		Venue:          "Phoenix",
+		Market:         m.Address,
+		ExpectedOut:    atoms.ToAmount(uint64(out), outDecimals),
+		ExpectedOutRaw: uint64(out),
+		OutDecimals:    outDecimals,
+		buildInstructions: func(ctx context.Context, client ChainClient, owner solana.PublicKey, minAmountOut uint64) ([]solana.Instruction, error) {
+			var solIn uint64
+			if side == "buy" {
+				solIn = amountIn
+			}
+			wsolATA, tokenATA, wrap, err := swapTokenAccounts(ctx, client, owner, tokenMint, solIn)
+			if err != nil {
+				return nil, err
+			}
+
+			baseAccount, quoteAccount := tokenATA, wsolATA
+			if solIsBase {
+				baseAccount, quoteAccount = wsolATA, tokenATA
+			}
+
+			var data []byte
+			if inIsBase {
+				data = encodePhoenixIOC(PHOENIX_SIDE_ASK, amountIn/m.BaseLotSize, 0, 0, minAmountOut/m.QuoteLotSize)
+			} else {
+				data = encodePhoenixIOC(PHOENIX_SIDE_BID, 0, amountIn/m.QuoteLotSize, minAmountOut/m.BaseLotSize, 0)
+			}
+
+			if m.hasSeat(owner) {
+				fmt.Println("Phoenix seat: held (not needed for IOC swaps)")
+			} else {
+				fmt.Println("Phoenix seat: none (not needed for IOC swaps)")
+			}
+
+			swapIx, err := createPhoenixSwapInstruction(m, owner, baseAccount, quoteAccount, data)
+			if err != nil {
+				return nil, err
+			}
+			return wrap(swapIx), nil
+		},
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+
+	"awesomeProject/internal/atoms"
+)
+
+// Environment variables configuring the platform fee; the main flags override them
+const (
+	PLATFORM_FEE_BPS_ENV_VAR       = "PLATFORM_FEE_BPS"
+	PLATFORM_FEE_RECIPIENT_ENV_VAR = "PLATFORM_FEE_RECIPIENT"
+)
+
+const MAX_PLATFORM_FEE_BPS = 1000 // 10%
+
+// PlatformFeeConfig is a fee integrators charge on every swap, paid to
+// Recipient in the swap's SOL or stablecoin leg: on the amount in for buys
+// and on the minimum out for sells, so it never exceeds what a sell receives
+type PlatformFeeConfig struct {
+	Bps       uint64
+	Recipient string
+}
+
+// loadPlatformFee reads the platform fee from the environment; it is
+// validated once the main flags had their say
+func loadPlatformFee() (PlatformFeeConfig, error) {
+	fee := PlatformFeeConfig{Recipient: os.Getenv(PLATFORM_FEE_RECIPIENT_ENV_VAR)}
+	if value := os.Getenv(PLATFORM_FEE_BPS_ENV_VAR); value != "" {
+		bps, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fee, fmt.Errorf("invalid %s %q", PLATFORM_FEE_BPS_ENV_VAR, value)
+		}
+		fee.Bps = bps
+	}
+	return fee, nil
+}
+
+// Validate checks the fee is within bounds and has a recipient
+func (c PlatformFeeConfig) Validate() error {
+	if c.Bps > MAX_PLATFORM_FEE_BPS {
+		return fmt.Errorf("platform fee of %d bps exceeds the maximum of %d", c.Bps, MAX_PLATFORM_FEE_BPS)
+	}
+	if c.Bps == 0 {
+		return nil
+	}
+	if c.Recipient == "" {
+		return fmt.Errorf("a platform fee requires a recipient (-fee-recipient or %s)", PLATFORM_FEE_RECIPIENT_ENV_VAR)
+	}
+	if _, err := solana.PublicKeyFromBase58(c.Recipient); err != nil {
+		return fmt.Errorf("invalid platform fee recipient: %w", err)
+	}
+	return nil
+}
+
+// Enabled reports whether swaps carry a fee
+func (c PlatformFeeConfig) Enabled() bool {
+	return c.Bps > 0 && c.Recipient != ""
+}
+
+// rawFee returns the fee in raw units of the swap's currency leg
+func (c PlatformFeeConfig) rawFee(side string, amountInRaw uint64, minAmountOut uint64) uint64 {
+	if !c.Enabled() {
+		return 0
+	}
+	if side == "buy" {
+		return atoms.MulDiv(amountInRaw, c.Bps, 10_000, false)
+	}
+	return atoms.MulDiv(minAmountOut, c.Bps, 10_000, false)
+}
+
+// Estimate returns the fee in UI units of the currency leg of a swap. Sells
+// are charged on the minimum out; passing the expected out overestimates it.
+func (c PlatformFeeConfig) Estimate(side string, amountIn float64, out float64) float64 {
+	if !c.Enabled() {
+		return 0
+	}
+	if side == "buy" {
+		return amountIn * float64(c.Bps) / 10_000
+	}
+	return out * float64(c.Bps) / 10_000
+}
+
+// feeInstruction transfers the fee to the recipient, or returns nil without a
+// fee. SOL is sent from the wallet, after a sell has unwrapped its output;
+// stablecoins go from the swap's token account to the recipient's ATA, which
+// must already exist.
+func (c PlatformFeeConfig) feeInstruction(pool *OnChainPool, owner solana.PublicKey, side string, amountInRaw uint64, minAmountOut uint64, accounts swapAccounts) (solana.Instruction, error) {
+	fee := c.rawFee(side, amountInRaw, minAmountOut)
+	if fee == 0 {
+		return nil, nil
+	}
+	recipient, err := solana.PublicKeyFromBase58(c.Recipient)
+	if err != nil {
+		return nil, fmt.Errorf("invalid platform fee recipient: %w", err)
+	}
+
+	mint := currencyMint(pool)
+	if isSolMint(mint) {
+		return system.NewTransferInstruction(fee, owner, recipient).Build(), nil
+	}
+	recipientATA, _, err := solana.FindAssociatedTokenAddress(recipient, mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find platform fee ATA: %w", err)
+	}
+	source := accounts.Source
+	if side == "sell" {
+		source = accounts.Destination
+	}
+	return token.NewTransferInstruction(fee, source, recipientATA, owner, []solana.PublicKey{}).Build(), nil
+}
+
+// print itemizes the fee in a confirmation
+func (c PlatformFeeConfig) print(side string, amountIn float64, expectedOut float64) {
+	if !c.Enabled() {
+		return
+	}
+	label := getInputToken(side)
+	if side == "sell" {
+		label = getOutputToken(side)
+	}
+	fmt.Printf(tr("Platform Fee: ~%.9f %s (%d bps to %s)\n"), c.Estimate(side, amountIn, expectedOut), label, c.Bps, c.Recipient)
+}
+
+// platformFeeToken labels the leg a report's platform fee was paid in
+func (r *TransactionReport) platformFeeToken() string {
+	if r.Side == "sell" {
+		return r.OutputToken
+	}
+	return r.InputToken
+}
@@ -0,0 +1,539 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+const (
+	RAYDIUM_INITIALIZE2_INSTRUCTION = uint8(1)
+	OPENBOOK_INITIALIZE_MARKET      = uint32(0)
+
+	// Smallest queues and orderbooks Raydium pools accept, which keep the market's rent low
+	MARKET_REQUEST_QUEUE_SIZE   = 764   // 9 requests
+	MARKET_EVENT_QUEUE_SIZE     = 11308 // 128 events
+	MARKET_ORDERBOOK_SIZE       = 14524 // 201 orders per side
+	MARKET_QUOTE_DUST_THRESHOLD = 500
+	DEFAULT_MARKET_LOT_SIZE     = 1.0
+	DEFAULT_MARKET_TICK_SIZE    = 0.000001
+
+	// Accounts Raydium creates for a new pool, used for the rent estimate
+	TOKEN_ACCOUNT_SIZE         = 165
+	MINT_ACCOUNT_SIZE          = 82
+	RAYDIUM_POOL_SIZE          = 752
+	RAYDIUM_OPEN_ORDERS_SIZE   = 3228
+	RAYDIUM_TARGET_ORDERS_SIZE = 2208
+
+	RAYDIUM_CONFIG_POOL_FEE_OFFSET = 536 // AmmConfig.create_pool_fee
+)
+
+// RAYDIUM_POOL_FEE_WALLET receives the pool creation fee on the active cluster
+var RAYDIUM_POOL_FEE_WALLET = solana.MustPublicKeyFromBase58("7YttLkHDoNj9wyDur5pM1ejNaAvT9X4eqaYcHQqtj2G5")
+
+// creationStep is one transaction of a multi-transaction creation flow
+type creationStep struct {
+	Label        string
+	Instructions []solana.Instruction
+	Signers      []solana.PrivateKey // new accounts that sign besides the wallet
+	Rent         uint64              // lamports locked in the accounts the step creates
+	Spend        uint64              // other lamports leaving the wallet: fees and deposits
+}
+
+// PoolCreateParams describes a new Raydium V4 pool
+type PoolCreateParams struct {
+	BaseMint      solana.PublicKey
+	QuoteMint     solana.PublicKey
+	BaseDecimals  uint8
+	QuoteDecimals uint8
+	BaseAmount    float64
+	QuoteAmount   float64
+	Market        solana.PublicKey // existing OpenBook market; zero creates one
+	LotSize       float64          // base tokens per order lot
+	TickSize      float64          // quote price increment
+	OpenTime      uint64           // unix seconds swaps open, 0 for immediately
+}
+
+// raydiumPoolAccounts are the accounts Raydium derives for a pool from its market
+type raydiumPoolAccounts struct {
+	Pool         solana.PublicKey
+	Authority    solana.PublicKey
+	Nonce        uint8
+	OpenOrders   solana.PublicKey
+	TargetOrders solana.PublicKey
+	LpMint       solana.PublicKey
+	BaseVault    solana.PublicKey
+	QuoteVault   solana.PublicKey
+	Config       solana.PublicKey
+}
+
+// deriveRaydiumPoolAccounts derives the PDAs initialize2 creates for a market.
+// The seeds tie each account to the market, so a market backs one pool.
+func deriveRaydiumPoolAccounts(market solana.PublicKey) (raydiumPoolAccounts, error) {
+	var accounts raydiumPoolAccounts
+	var err error
+	associated := func(seed string) solana.PublicKey {
+		if err != nil {
+			return solana.PublicKey{}
+		}
+		var key solana.PublicKey
+		key, _, err = solana.FindProgramAddress([][]byte{RAYDIUM_AMM_V4.Bytes(), market.Bytes(), []byte(seed)}, RAYDIUM_AMM_V4)
+		return key
+	}
+	accounts.Pool = associated("amm_associated_seed")
+	accounts.OpenOrders = associated("open_order_associated_seed")
+	accounts.TargetOrders = associated("target_associated_seed")
+	accounts.LpMint = associated("lp_mint_associated_seed")
+	accounts.BaseVault = associated("coin_vault_associated_seed")
+	accounts.QuoteVault = associated("pc_vault_associated_seed")
+	if err != nil {
+		return accounts, fmt.Errorf("failed to derive pool accounts: %w", err)
+	}
+	if accounts.Authority, accounts.Nonce, err = solana.FindProgramAddress([][]byte{[]byte(AUTHORITY_AMM_SEED)}, RAYDIUM_AMM_V4); err != nil {
+		return accounts, fmt.Errorf("failed to derive pool authority: %w", err)
+	}
+	if accounts.Config, _, err = solana.FindProgramAddress([][]byte{[]byte("amm_config_account_seed")}, RAYDIUM_AMM_V4); err != nil {
+		return accounts, fmt.Errorf("failed to derive AMM config: %w", err)
+	}
+	return accounts, nil
+}
+
+// createInitialize2Instruction creates a Raydium V4 initialize2 instruction,
+// which creates the pool and deposits its initial liquidity
+func createInitialize2Instruction(
+	accounts raydiumPoolAccounts,
+	params PoolCreateParams,
+	owner solana.PublicKey,
+	userBase solana.PublicKey,
+	userQuote solana.PublicKey,
+	baseRaw uint64,
+	quoteRaw uint64,
+) (solana.Instruction, error) {
+	userLp, _, err := solana.FindAssociatedTokenAddress(owner, accounts.LpMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find LP ATA: %w", err)
+	}
+
+	data := []byte{RAYDIUM_INITIALIZE2_INSTRUCTION, accounts.Nonce}
+	data = binary.LittleEndian.AppendUint64(data, params.OpenTime)
+	data = binary.LittleEndian.AppendUint64(data, quoteRaw)
+	data = binary.LittleEndian.AppendUint64(data, baseRaw)
+
+	metas := []*solana.AccountMeta{
+		// 0. Token program
+		{PublicKey: token.ProgramID},
+		// 1. Associated token program
+		{PublicKey: solana.SPLAssociatedTokenAccountProgramID},
+		// 2. System program
+		{PublicKey: solana.SystemProgramID},
+		// 3. Rent sysvar
+		{PublicKey: solana.SysVarRentPubkey},
+		// 4. AMM pool
+		{PublicKey: accounts.Pool, IsWritable: true},
+		// 5. AMM authority
+		{PublicKey: accounts.Authority},
+		// 6. AMM open orders
+		{PublicKey: accounts.OpenOrders, IsWritable: true},
+		// 7. LP mint
+		{PublicKey: accounts.LpMint, IsWritable: true},
+		// 8. Base mint
+		{PublicKey: params.BaseMint},
+		// 9. Quote mint
+		{PublicKey: params.QuoteMint},
+		// 10. Pool base vault
+		{PublicKey: accounts.BaseVault, IsWritable: true},
+		// 11. Pool quote vault
+		{PublicKey: accounts.QuoteVault, IsWritable: true},
+		// 12. AMM target orders
+		{PublicKey: accounts.TargetOrders, IsWritable: true},
+		// 13. AMM config
+		{PublicKey: accounts.Config},
+		// 14. Pool creation fee wallet
+		{PublicKey: RAYDIUM_POOL_FEE_WALLET, IsWritable: true},
+		// 15. Market program
+		{PublicKey: OPENBOOK_PROGRAM},
+		// 16. Market
+		{PublicKey: params.Market},
+		// 17. User owner (signer)
+		{PublicKey: owner, IsSigner: true, IsWritable: true},
+		// 18. User base token account
+		{PublicKey: userBase, IsWritable: true},
+		// 19. User quote token account
+		{PublicKey: userQuote, IsWritable: true},
+		// 20. User LP token account, created by the program
+		{PublicKey: userLp, IsWritable: true},
+	}
+	return solana.NewInstruction(RAYDIUM_AMM_V4, metas, data), nil
+}
+
+// findVaultSignerNonce finds the first nonce that derives a valid vault
+// signer for a new market, the reverse of deriveMarketVaultSigner
+func findVaultSignerNonce(market solana.PublicKey) (solana.PublicKey, uint64, error) {
+	for nonce := uint64(0); nonce < 256; nonce++ {
+		signer, err := solana.CreateProgramAddress([][]byte{market.Bytes(), binary.LittleEndian.AppendUint64(nil, nonce)}, OPENBOOK_PROGRAM)
+		if err == nil {
+			return signer, nonce, nil
+		}
+	}
+	return solana.PublicKey{}, 0, fmt.Errorf("no vault signer nonce for market %s", market)
+}
+
+// createInitializeMarketInstruction creates an OpenBook (Serum V3) InitializeMarket instruction
+func createInitializeMarketInstruction(
+	market, requestQueue, eventQueue, bids, asks, baseVault, quoteVault, baseMint, quoteMint solana.PublicKey,
+	baseLotSize uint64,
+	quoteLotSize uint64,
+	vaultSignerNonce uint64,
+) solana.Instruction {
+	data := []byte{0} // layout version
+	data = binary.LittleEndian.AppendUint32(data, OPENBOOK_INITIALIZE_MARKET)
+	data = binary.LittleEndian.AppendUint64(data, baseLotSize)
+	data = binary.LittleEndian.AppendUint64(data, quoteLotSize)
+	data = binary.LittleEndian.AppendUint16(data, 0) // fee rate bps, unused by fee tiers
+	data = binary.LittleEndian.AppendUint64(data, vaultSignerNonce)
+	data = binary.LittleEndian.AppendUint64(data, MARKET_QUOTE_DUST_THRESHOLD)
+
+	metas := []*solana.AccountMeta{
+		{PublicKey: market, IsWritable: true},
+		{PublicKey: requestQueue, IsWritable: true},
+		{PublicKey: eventQueue, IsWritable: true},
+		{PublicKey: bids, IsWritable: true},
+		{PublicKey: asks, IsWritable: true},
+		{PublicKey: baseVault, IsWritable: true},
+		{PublicKey: quoteVault, IsWritable: true},
+		{PublicKey: baseMint},
+		{PublicKey: quoteMint},
+		{PublicKey: solana.SysVarRentPubkey},
+	}
+	return solana.NewInstruction(OPENBOOK_PROGRAM, metas, data)
+}
+
+// planMarketCreation plans the two transactions creating an OpenBook market
+// for the pair: the vaults first, then the market with its queues and
+// orderbook, which together would exceed the transaction size limit
+func planMarketCreation(ctx context.Context, client ChainClient, payer solana.PublicKey, params PoolCreateParams) (solana.PublicKey, []creationStep, error) {
+	rent := make(map[uint64]uint64)
+	for _, size := range []uint64{TOKEN_ACCOUNT_SIZE, MARKET_MIN_SIZE, MARKET_REQUEST_QUEUE_SIZE, MARKET_EVENT_QUEUE_SIZE, MARKET_ORDERBOOK_SIZE} {
+		lamports, err := client.GetMinimumBalanceForRentExemption(ctx, size, rpc.CommitmentConfirmed)
+		if err != nil {
+			return solana.PublicKey{}, nil, fmt.Errorf("failed to get rent for %d bytes: %w", size, err)
+		}
+		rent[size] = lamports
+	}
+
+	keys := make([]solana.PrivateKey, 7)
+	for i := range keys {
+		key, err := solana.NewRandomPrivateKey()
+		if err != nil {
+			return solana.PublicKey{}, nil, fmt.Errorf("failed to generate account key: %w", err)
+		}
+		keys[i] = key
+	}
+	market, requestQueue, eventQueue, bids, asks, baseVault, quoteVault := keys[0], keys[1], keys[2], keys[3], keys[4], keys[5], keys[6]
+
+	vaultSigner, nonce, err := findVaultSignerNonce(market.PublicKey())
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+
+	baseLotSize := uint64(math.Round(params.LotSize * math.Pow10(int(params.BaseDecimals))))
+	quoteLotSize := uint64(math.Round(params.LotSize * params.TickSize * math.Pow10(int(params.QuoteDecimals))))
+	if baseLotSize == 0 || quoteLotSize == 0 {
+		return solana.PublicKey{}, nil, fmt.Errorf("lot size %g and tick size %g round to zero lots for %d/%d decimals",
+			params.LotSize, params.TickSize, params.BaseDecimals, params.QuoteDecimals)
+	}
+
+	vaults := creationStep{Label: "Create market vaults", Signers: []solana.PrivateKey{baseVault, quoteVault}}
+	for _, vault := range []struct {
+		key  solana.PrivateKey
+		mint solana.PublicKey
+	}{{baseVault, params.BaseMint}, {quoteVault, params.QuoteMint}} {
+		vaults.Instructions = append(vaults.Instructions,
+			system.NewCreateAccountInstruction(rent[TOKEN_ACCOUNT_SIZE], TOKEN_ACCOUNT_SIZE, token.ProgramID, payer, vault.key.PublicKey()).Build(),
+			token.NewInitializeAccountInstruction(vault.key.PublicKey(), vault.mint, vaultSigner, solana.SysVarRentPubkey).Build(),
+		)
+		vaults.Rent += rent[TOKEN_ACCOUNT_SIZE]
+	}
+
+	create := creationStep{Label: "Create OpenBook market", Signers: []solana.PrivateKey{market, requestQueue, eventQueue, bids, asks}}
+	for _, account := range []struct {
+		key  solana.PrivateKey
+		size uint64
+	}{{market, MARKET_MIN_SIZE}, {requestQueue, MARKET_REQUEST_QUEUE_SIZE}, {eventQueue, MARKET_EVENT_QUEUE_SIZE}, {bids, MARKET_ORDERBOOK_SIZE}, {asks, MARKET_ORDERBOOK_SIZE}} {
+		create.Instructions = append(create.Instructions,
+			system.NewCreateAccountInstruction(rent[account.size], account.size, OPENBOOK_PROGRAM, payer, account.key.PublicKey()).Build())
+		create.Rent += rent[account.size]
+	}
+	create.Instructions = append(create.Instructions, createInitializeMarketInstruction(
+		market.PublicKey(), requestQueue.PublicKey(), eventQueue.PublicKey(), bids.PublicKey(), asks.PublicKey(),
+		baseVault.PublicKey(), quoteVault.PublicKey(), params.BaseMint, params.QuoteMint,
+		baseLotSize, quoteLotSize, nonce,
+	))
+
+	return market.PublicKey(), []creationStep{vaults, create}, nil
+}
+
+// raydiumPoolCreationFee reads the fee Raydium charges for a new pool from its AMM config
+func raydiumPoolCreationFee(ctx context.Context, client ChainClient, config solana.PublicKey) (uint64, error) {
+	info, err := client.GetAccountInfo(ctx, config)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get AMM config: %w", err)
+	}
+	data := info.Value.Data.GetBinary()
+	if len(data) < RAYDIUM_CONFIG_POOL_FEE_OFFSET+8 {
+		return 0, fmt.Errorf("AMM config is too short: %d bytes", len(data))
+	}
+	return binary.LittleEndian.Uint64(data[RAYDIUM_CONFIG_POOL_FEE_OFFSET:]), nil
+}
+
+// planPoolCreation plans creating a Raydium V4 pool and depositing its
+// initial liquidity, creating an OpenBook market first when params.Market is
+// zero. A WSOL side is wrapped from the wallet's SOL; any other side must be
+// in the wallet's ATA by the time the last step runs.
+func planPoolCreation(ctx context.Context, client ChainClient, owner solana.PublicKey, params PoolCreateParams) (solana.PublicKey, []creationStep, error) {
+	var steps []creationStep
+	if params.Market.IsZero() {
+		market, marketSteps, err := planMarketCreation(ctx, client, owner, params)
+		if err != nil {
+			return solana.PublicKey{}, nil, err
+		}
+		params.Market = market
+		steps = append(steps, marketSteps...)
+	}
+
+	accounts, err := deriveRaydiumPoolAccounts(params.Market)
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	baseRaw, err := atoms.FromAmount(params.BaseAmount, int(params.BaseDecimals))
+	if err != nil {
+		return solana.PublicKey{}, nil, fmt.Errorf("invalid base amount: %w", err)
+	}
+	quoteRaw, err := atoms.FromAmount(params.QuoteAmount, int(params.QuoteDecimals))
+	if err != nil {
+		return solana.PublicKey{}, nil, fmt.Errorf("invalid quote amount: %w", err)
+	}
+	fee, err := raydiumPoolCreationFee(ctx, client, accounts.Config)
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+
+	pool := creationStep{Label: "Create Raydium pool", Spend: fee}
+	for _, size := range []uint64{RAYDIUM_POOL_SIZE, RAYDIUM_OPEN_ORDERS_SIZE, RAYDIUM_TARGET_ORDERS_SIZE, MINT_ACCOUNT_SIZE, TOKEN_ACCOUNT_SIZE, TOKEN_ACCOUNT_SIZE, TOKEN_ACCOUNT_SIZE} {
+		lamports, err := client.GetMinimumBalanceForRentExemption(ctx, size, rpc.CommitmentConfirmed)
+		if err != nil {
+			return solana.PublicKey{}, nil, fmt.Errorf("failed to get rent for %d bytes: %w", size, err)
+		}
+		pool.Rent += lamports
+	}
+
+	userAccounts := make([]solana.PublicKey, 2)
+	var closeWSOL []solana.Instruction
+	for i, side := range []struct {
+		mint solana.PublicKey
+		raw  uint64
+	}{{params.BaseMint, baseRaw}, {params.QuoteMint, quoteRaw}} {
+		if !side.mint.Equals(WSOL_MINT) {
+			if userAccounts[i], _, err = solana.FindAssociatedTokenAddress(owner, side.mint); err != nil {
+				return solana.PublicKey{}, nil, fmt.Errorf("failed to find ATA: %w", err)
+			}
+			continue
+		}
+		ata, createIx, err := getOrCreateATA(ctx, client, owner, side.mint)
+		if err != nil {
+			return solana.PublicKey{}, nil, err
+		}
+		if createIx != nil {
+			pool.Instructions = append(pool.Instructions, createIx)
+			closeWSOL = append(closeWSOL, closeWSOLInstruction(owner, ata))
+		}
+		pool.Instructions = append(pool.Instructions, wrapSOLInstructions(owner, ata, side.raw)...)
+		pool.Spend += side.raw
+		userAccounts[i] = ata
+	}
+
+	initialize, err := createInitialize2Instruction(accounts, params, owner, userAccounts[0], userAccounts[1], baseRaw, quoteRaw)
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	pool.Instructions = append(pool.Instructions, initialize)
+	pool.Instructions = append(pool.Instructions, closeWSOL...)
+	steps = append(steps, pool)
+
+	return accounts.Pool, steps, nil
+}
+
+// printCreationPlan lists the steps with what each costs and returns the
+// total in lamports
+func printCreationPlan(steps []creationStep) uint64 {
+	var total uint64
+	fmt.Println("Plan:")
+	for i, step := range steps {
+		fees := uint64(1+len(step.Signers)) * LAMPORTS_PER_SIGNATURE
+		cost := step.Rent + step.Spend + fees
+		total += cost
+		fmt.Printf("  %d. %-26s %2d instructions  rent %s SOL, other %s SOL, network fee %s SOL\n", i+1, step.Label, len(step.Instructions),
+			atoms.Format(step.Rent, SOL_DECIMALS), atoms.Format(step.Spend, SOL_DECIMALS), atoms.Format(fees, SOL_DECIMALS))
+	}
+	fmt.Printf("Estimated cost: %s SOL, before priority fees\n", atoms.Format(total, SOL_DECIMALS))
+	return total
+}
+
+// executeCreationSteps sends the steps in order, each once the previous one
+// confirmed. A failed step leaves the earlier ones in place.
+func executeCreationSteps(ctx context.Context, client ChainClient, wallet solana.PrivateKey, steps []creationStep, opts BuildOptions) error {
+	for i, step := range steps {
+		latestBlockhash, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+		if err != nil {
+			return fmt.Errorf("failed to get latest blockhash: %w", err)
+		}
+		tx, err := solana.NewTransaction(step.Instructions, latestBlockhash.Value.Blockhash, solana.TransactionPayer(wallet.PublicKey()))
+		if err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+		if err := signTransaction(tx, append([]solana.PrivateKey{wallet}, step.Signers...)...); err != nil {
+			return err
+		}
+		sig, err := sendAndConfirmTransaction(ctx, client, tx, opts)
+		if err != nil {
+			return fmt.Errorf("step %d (%s) failed: %w", i+1, step.Label, err)
+		}
+		fmt.Printf("✅ %s: %s\n", step.Label, explorerTxURL(sig.String()))
+	}
+	return nil
+}
+
+// runPool dispatches the pool subcommands
+func runPool(args []string, opts BuildOptions) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "create":
+			runPoolCreate(args[1:], opts)
+			return
+		case "audit":
+			runPoolAudit(args[1:])
+			return
+		case "stats":
+			runPoolStats(args[1:])
+			return
+		}
+	}
+	fmt.Println("Usage: go run . pool create -base MINT -base-amount N -quote-amount N [-quote MINT] [-market MARKET] [-execute]")
+	fmt.Println("       go run . pool audit -pool POOL [-json]")
+	fmt.Println("       go run . pool stats -pool POOL [-hours 24] [-max-tx 5000] [-json]")
+	os.Exit(1)
+}
+
+// runPoolCreate creates a Raydium V4 pool for a mint pair and seeds its liquidity
+func runPoolCreate(args []string, opts BuildOptions) {
+	fs := newCommandFlagSet("pool create")
+	baseAddr := fs.String("base", "", "Base token mint")
+	quoteAddr := fs.String("quote", WSOL_MINT.String(), "Quote token mint")
+	marketAddr := fs.String("market", "", "Existing OpenBook market for the pair (default: create one)")
+	params := PoolCreateParams{LotSize: DEFAULT_MARKET_LOT_SIZE, TickSize: DEFAULT_MARKET_TICK_SIZE}
+	fs.Float64Var(&params.BaseAmount, "base-amount", 0, "Initial base liquidity")
+	fs.Float64Var(&params.QuoteAmount, "quote-amount", 0, "Initial quote liquidity")
+	fs.Float64Var(&params.LotSize, "lot-size", params.LotSize, "Market lot size in base tokens")
+	fs.Float64Var(&params.TickSize, "tick-size", params.TickSize, "Market price increment in quote tokens")
+	openIn := fs.Duration("open-in", 0, "Delay before swaps open (default: immediately)")
+	execute := fs.Bool("execute", false, "Send the transactions; without it only the plan is printed")
+	fs.Parse(args)
+
+	if *baseAddr == "" && *marketAddr == "" || params.BaseAmount <= 0 || params.QuoteAmount <= 0 {
+		fmt.Println("Usage: go run . pool create -base MINT -base-amount N -quote-amount N [-quote MINT] [-market MARKET] [-execute]")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+
+	ctx := interruptContext()
+	client := newChainClient()
+	wallet, err := loadWallet()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *marketAddr != "" {
+		if params.Market, err = solana.PublicKeyFromBase58(*marketAddr); err != nil {
+			log.Fatalf("Invalid market address: %v", err)
+		}
+		// The market fixes the pair
+		info, err := client.GetAccountInfo(ctx, params.Market)
+		if err != nil {
+			log.Fatalf("Failed to get market: %v", err)
+		}
+		data := info.Value.Data.GetBinary()
+		if !info.Value.Owner.Equals(OPENBOOK_PROGRAM) || len(data) < MARKET_MIN_SIZE {
+			log.Fatalf("%s is not an OpenBook market", params.Market)
+		}
+		params.BaseMint = solana.PublicKeyFromBytes(data[MARKET_BASE_MINT_OFFSET : MARKET_BASE_MINT_OFFSET+32])
+		params.QuoteMint = solana.PublicKeyFromBytes(data[MARKET_QUOTE_MINT_OFFSET : MARKET_QUOTE_MINT_OFFSET+32])
+	} else {
+		if params.BaseMint, err = solana.PublicKeyFromBase58(*baseAddr); err != nil {
+			log.Fatalf("Invalid base mint: %v", err)
+		}
+		if params.QuoteMint, err = solana.PublicKeyFromBase58(*quoteAddr); err != nil {
+			log.Fatalf("Invalid quote mint: %v", err)
+		}
+	}
+	if params.BaseMint.Equals(params.QuoteMint) {
+		log.Fatal("Base and quote mints must differ")
+	}
+	if params.BaseDecimals, err = getTokenDecimals(ctx, client, params.BaseMint.String()); err != nil {
+		log.Fatalf("Failed to get base decimals: %v", err)
+	}
+	if params.QuoteDecimals, err = getTokenDecimals(ctx, client, params.QuoteMint.String()); err != nil {
+		log.Fatalf("Failed to get quote decimals: %v", err)
+	}
+	if *openIn > 0 {
+		params.OpenTime = uint64(time.Now().Add(*openIn).Unix())
+	}
+
+	// Fail before anything is created when a token side is not funded
+	for _, side := range []struct {
+		mint     solana.PublicKey
+		amount   float64
+		decimals uint8
+	}{{params.BaseMint, params.BaseAmount, params.BaseDecimals}, {params.QuoteMint, params.QuoteAmount, params.QuoteDecimals}} {
+		if side.mint.Equals(WSOL_MINT) {
+			continue
+		}
+		ata, _, err := solana.FindAssociatedTokenAddress(wallet.PublicKey(), side.mint)
+		if err != nil {
+			log.Fatalf("Failed to find ATA: %v", err)
+		}
+		balance, err := client.GetTokenAccountBalance(ctx, ata, rpc.CommitmentConfirmed)
+		if err != nil {
+			log.Fatalf("Wallet has no %s token account: %v", side.mint, err)
+		}
+		if balance.Value.UiAmount == nil || *balance.Value.UiAmount < side.amount {
+			log.Fatalf("Wallet holds %s of %s, less than %g", balance.Value.UiAmountString, side.mint, side.amount)
+		}
+	}
+
+	pool, steps, err := planPoolCreation(ctx, client, wallet.PublicKey(), params)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Pool: %s (%s/%s)\n", pool, params.BaseMint, params.QuoteMint)
+	printCreationPlan(steps)
+	if !*execute {
+		fmt.Println("Pass -execute to create the pool")
+		return
+	}
+
+	if err := executeCreationSteps(ctx, client, wallet, steps, opts); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("\n✅ Pool created: %s\n", explorerAccountURL(pool.String()))
+}
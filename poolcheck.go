@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// ErrPoolMismatch marks a pool whose account disagrees with the vault or
+// market accounts it names. Swapping through it could move funds through
+// accounts the pool does not control, so it is refused.
+var ErrPoolMismatch = errors.New("pool accounts disagree")
+
+// checkPoolVaults cross-validates the vaults a pool account names: each must
+// be a token account of that side's mint, held by the AMM authority
+func checkPoolVaults(ctx context.Context, client ChainClient, pool *OnChainPool) error {
+	accounts, err := client.GetMultipleAccounts(ctx, pool.BaseVault, pool.QuoteVault)
+	if err != nil {
+		return fmt.Errorf("failed to get pool vaults: %w", err)
+	}
+	if len(accounts.Value) != 2 {
+		return fmt.Errorf("failed to get pool vaults: %d accounts returned", len(accounts.Value))
+	}
+
+	sides := []struct {
+		name  string
+		vault solana.PublicKey
+		mint  solana.PublicKey
+	}{
+		{"base", pool.BaseVault, pool.BaseMint},
+		{"quote", pool.QuoteVault, pool.QuoteMint},
+	}
+	for i, side := range sides {
+		account := accounts.Value[i]
+		if account == nil {
+			return fmt.Errorf("%w: %s vault %s does not exist", ErrPoolMismatch, side.name, side.vault)
+		}
+		data := account.Data.GetBinary()
+		if len(data) < TOKEN_ACCOUNT_SIZE {
+			return fmt.Errorf("%w: %s vault %s is not a token account", ErrPoolMismatch, side.name, side.vault)
+		}
+		mint := solana.PublicKeyFromBytes(data[:32])
+		if !mint.Equals(side.mint) {
+			return fmt.Errorf("%w: %s vault %s holds %s, not the pool's %s mint %s", ErrPoolMismatch, side.name, side.vault, mint, side.name, side.mint)
+		}
+		owner := solana.PublicKeyFromBytes(data[TOKEN_ACCOUNT_OWNER_OFFSET : TOKEN_ACCOUNT_OWNER_OFFSET+32])
+		if !owner.Equals(pool.Authority) {
+			return fmt.Errorf("%w: %s vault %s is held by %s, not the AMM authority %s", ErrPoolMismatch, side.name, side.vault, owner, pool.Authority)
+		}
+	}
+	return nil
+}
+
+// checkPoolMarket cross-validates a pool's OpenBook market: it must belong
+// to the market program the pool names and trade the pool's two mints
+func checkPoolMarket(pool *OnChainPool, owner solana.PublicKey, data []byte) error {
+	if !owner.Equals(pool.MarketProgram) {
+		return fmt.Errorf("%w: market %s is owned by %s, not the pool's market program %s", ErrPoolMismatch, pool.Market, owner, pool.MarketProgram)
+	}
+	baseMint := solana.PublicKeyFromBytes(data[MARKET_BASE_MINT_OFFSET : MARKET_BASE_MINT_OFFSET+32])
+	quoteMint := solana.PublicKeyFromBytes(data[MARKET_QUOTE_MINT_OFFSET : MARKET_QUOTE_MINT_OFFSET+32])
+	if !baseMint.Equals(pool.BaseMint) || !quoteMint.Equals(pool.QuoteMint) {
+		return fmt.Errorf("%w: market %s trades %s/%s, the pool %s/%s", ErrPoolMismatch, pool.Market, baseMint, quoteMint, pool.BaseMint, pool.QuoteMint)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Pools in testdata/raydium-v4.json naming vaults they do not control
+var (
+	FIXTURE_FOREIGN_VAULT_POOL = solana.MustPublicKeyFromBase58("US517G5965aydkZ46HS38QLi7UQiSojurfbQfKCELFx")
+	FIXTURE_WRONG_MINT_POOL    = solana.MustPublicKeyFromBase58("YMN9Qj5jPNp7j14VPcML1B6xGgcPWVZUGLFU3Mnyfaf")
+)
+
+func TestCheckPoolVaults(t *testing.T) {
+	client := fixtureTestClient(t)
+	tests := []struct {
+		name    string
+		pool    solana.PublicKey
+		wantErr string // empty for consistent pools
+	}{
+		{"SOL base", FIXTURE_SOL_TOKEN6_POOL, ""},
+		{"SOL quote", FIXTURE_TOKEN18_SOL_POOL, ""},
+		{"vault held by another owner", FIXTURE_FOREIGN_VAULT_POOL, "base vault 5PjDJaGfSPJj4tFzMRCiuuAasKg5n8dJKXKenhuwZexx is held by US517G5965aydkZ46HS38QLi7UQiSojurfbQfKCELFx"},
+		{"vault of another mint", FIXTURE_WRONG_MINT_POOL, "quote vault 5bV6jUfhDHCQVA1WfKBUnXUsboJgoKgkzkKcxr3joew5 holds 2d46SEBFCA8SMB1BUAq3z1XJrp3qAXUgQnzkQ85Nvzjy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadPool(context.Background(), client, tt.pool.String())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrPoolMismatch) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want a mismatch containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPoolMarket(t *testing.T) {
+	client := fixtureTestClient(t)
+	tests := []struct {
+		name     string
+		pool     solana.PublicKey
+		mismatch bool
+	}{
+		{"market trades the pool's mints", FIXTURE_SOL_TOKEN6_POOL, false},
+		{"market trades the mints the other way round", FIXTURE_TOKEN18_SOL_POOL, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := fixturePool(t, client, tt.pool)
+			err := fetchMarketData(context.Background(), client, pool)
+			if tt.mismatch {
+				if !errors.Is(err, ErrPoolMismatch) {
+					t.Fatalf("got error %v, want a mismatch", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pool.MarketBids.IsZero() || pool.MarketBids.Equals(solana.SystemProgramID) {
+				t.Errorf("market bids not read: %s", pool.MarketBids)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// ErrUnsupportedPoolLayout is returned for pool accounts no known layout
+// describes, rather than reading their fields at the wrong offsets
+var ErrUnsupportedPoolLayout = errors.New("unsupported pool layout")
+
+// PoolLayout is one version of the Raydium AMM pool account (AmmInfo): its
+// size and the offsets of the fields the swap reads
+type PoolLayout struct {
+	Version string
+	Size    int
+
+	Status             int // u64
+	Nonce              int // u8 of a u64
+	SwapFeeNumerator   int // u64
+	SwapFeeDenominator int // u64
+	NeedTakePnlBase    int // u64
+	NeedTakePnlQuote   int // u64
+	OpenTime           int // u64, unix seconds
+	BaseVault          int // public keys
+	QuoteVault         int
+	BaseMint           int
+	QuoteMint          int
+	LpMint             int
+	OpenOrders         int
+	Market             int
+	MarketProgram      int
+	TargetOrders       int
+	LpAmount           int // u64, LP tokens issued, tracked by the program
+}
+
+// RAYDIUM_V4_LAYOUT is the AmmInfo of the Raydium AMM V4 program
+var RAYDIUM_V4_LAYOUT = &PoolLayout{
+	Version:            "v4",
+	Size:               752,
+	Status:             0,
+	Nonce:              8,
+	SwapFeeNumerator:   176,
+	SwapFeeDenominator: 184,
+	NeedTakePnlBase:    192,
+	NeedTakePnlQuote:   200,
+	OpenTime:           224,
+	BaseVault:          336,
+	QuoteVault:         368,
+	BaseMint:           400,
+	QuoteMint:          432,
+	LpMint:             464,
+	OpenOrders:         496,
+	Market:             528,
+	MarketProgram:      560,
+	TargetOrders:       592,
+	LpAmount:           720,
+}
+
+// POOL_LAYOUTS are the layouts of accounts owned by RAYDIUM_AMM_V4, told
+// apart by size. A layout Raydium adds is a new entry here.
+var POOL_LAYOUTS = []*PoolLayout{RAYDIUM_V4_LAYOUT}
+
+// detectPoolLayout returns the layout of a V4 pool account, checking that
+// the fields read as a pool would
+func detectPoolLayout(data []byte) (*PoolLayout, error) {
+	var known []string
+	for _, layout := range POOL_LAYOUTS {
+		if len(data) != layout.Size {
+			known = append(known, fmt.Sprintf("%s (%d bytes)", layout.Version, layout.Size))
+			continue
+		}
+		if err := layout.check(data); err != nil {
+			return nil, fmt.Errorf("%w %s (size %d): %v", ErrUnsupportedPoolLayout, layout.Version, len(data), err)
+		}
+		return layout, nil
+	}
+	return nil, fmt.Errorf("%w: unrecognized account size %d; known layouts are %s", ErrUnsupportedPoolLayout, len(data), strings.Join(known, ", "))
+}
+
+// check refuses data whose status or fees no pool of the layout would hold,
+// the sign of a layout change keeping the same size
+func (l *PoolLayout) check(data []byte) error {
+	status := l.u64(data, l.Status)
+	if _, ok := ammStatusNames[status]; !ok {
+		return fmt.Errorf("unknown status %d", status)
+	}
+	if status == AMM_STATUS_UNINITIALIZED {
+		return nil
+	}
+	numerator, denominator := l.u64(data, l.SwapFeeNumerator), l.u64(data, l.SwapFeeDenominator)
+	if denominator == 0 || numerator >= denominator {
+		return fmt.Errorf("swap fee %d/%d is not a fraction", numerator, denominator)
+	}
+	return nil
+}
+
+func (l *PoolLayout) u64(data []byte, offset int) uint64 {
+	return binary.LittleEndian.Uint64(data[offset : offset+8])
+}
+
+func (l *PoolLayout) key(data []byte, offset int) solana.PublicKey {
+	return solana.PublicKeyFromBytes(data[offset : offset+32])
+}
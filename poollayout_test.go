@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// v4PoolData returns a V4 pool account with the given status and swap fee
+func v4PoolData(status, feeNumerator, feeDenominator uint64) []byte {
+	l := RAYDIUM_V4_LAYOUT
+	data := make([]byte, l.Size)
+	binary.LittleEndian.PutUint64(data[l.Status:], status)
+	binary.LittleEndian.PutUint64(data[l.SwapFeeNumerator:], feeNumerator)
+	binary.LittleEndian.PutUint64(data[l.SwapFeeDenominator:], feeDenominator)
+	return data
+}
+
+func TestDetectPoolLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string // empty for the V4 layout
+	}{
+		{"v4 pool", v4PoolData(AMM_STATUS_INITIALIZED, 25, 10_000), ""},
+		{"uninitialized v4 account", v4PoolData(AMM_STATUS_UNINITIALIZED, 0, 0), ""},
+		{"unknown size", make([]byte, 637), "unrecognized account size 637; known layouts are v4 (752 bytes)"},
+		{"unknown status", v4PoolData(42, 25, 10_000), "v4 (size 752): unknown status 42"},
+		{"fee is not a fraction", v4PoolData(AMM_STATUS_INITIALIZED, 0, 0), "v4 (size 752): swap fee 0/0 is not a fraction"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout, err := detectPoolLayout(tt.data)
+			if tt.wantErr == "" {
+				if err != nil || layout != RAYDIUM_V4_LAYOUT {
+					t.Fatalf("got %v, %v; want the V4 layout", layout, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnsupportedPoolLayout) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
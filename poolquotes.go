@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"awesomeProject/internal/atoms"
+)
+
+// CandidateQuote is the requested swap quoted against one discovered pool
+type CandidateQuote struct {
+	Pool        *OnChainPool
+	Out         float64 // UI units of OutToken, 0 when the pool cannot fill the swap
+	OutToken    string
+	PriceImpact float64 // percent
+	FeePct      float64
+	Reason      string // why the pool cannot be traded, if it cannot
+}
+
+// quoteCandidatePools quotes the swap against every pool from the reserves
+// discovery loaded, best output first within each output currency
+func quoteCandidatePools(pools []*OnChainPool, side string, amount float64) []CandidateQuote {
+	quotes := make([]CandidateQuote, 0, len(pools))
+	for _, pool := range pools {
+		_, destinationMint, _ := swapMints(pool, side)
+		outDecimals := int(pool.BaseDecimals)
+		if destinationMint.Equals(pool.QuoteMint) {
+			outDecimals = int(pool.QuoteDecimals)
+		}
+		numerator, denominator := swapFeeRate(pool)
+
+		quote := CandidateQuote{
+			Pool:        pool,
+			Out:         atoms.ToAmount(poolNetOutput(pool, side, amount), outDecimals),
+			OutToken:    getOutputToken(side),
+			PriceImpact: quotedPriceImpact(pool, side, amount),
+			FeePct:      float64(numerator) / float64(denominator) * 100,
+		}
+		if side == "sell" {
+			quote.OutToken = currencyName(currencyMint(pool))
+		}
+		if err := pool.checkTradable(time.Now()); err != nil {
+			quote.Reason = err.Error()
+		} else if quote.Out == 0 {
+			quote.Reason = "too little liquidity"
+		}
+		quotes = append(quotes, quote)
+	}
+
+	sort.SliceStable(quotes, func(i, j int) bool {
+		if quotes[i].OutToken != quotes[j].OutToken {
+			return quotes[i].OutToken < quotes[j].OutToken
+		}
+		return quotes[i].Out > quotes[j].Out
+	})
+	return quotes
+}
+
+// printCandidateQuotes shows the pools side by side, marking the selected one
+func printCandidateQuotes(quotes []CandidateQuote, selected *OnChainPool) {
+	fmt.Print(tr("\n=== POOL COMPARISON ===\n"))
+	fmt.Printf("%-3s %-44s %-10s %20s %-6s %10s %7s\n", "#", "Pool", "Protocol", "Output", "", "Impact", "Fee")
+	for i, quote := range quotes {
+		marker := fmt.Sprintf("%d", i+1)
+		if selected != nil && quote.Pool.Address.Equals(selected.Address) {
+			marker += "*"
+		}
+		if quote.Reason != "" {
+			fmt.Printf("%-3s %-44s %-10s %s\n", marker, quote.Pool.Address, "Raydium V4", quote.Reason)
+			continue
+		}
+		fmt.Printf("%-3s %-44s %-10s %20.9f %-6s %9.4f%% %6.2f%%\n",
+			marker, quote.Pool.Address, "Raydium V4", quote.Out, quote.OutToken, quote.PriceImpact, quote.FeePct)
+	}
+	fmt.Printf("=======================\n")
+}
+
+// chooseCandidateQuote asks which pool to trade, defaulting to the selected one
+func chooseCandidateQuote(quotes []CandidateQuote, selected *OnChainPool) *OnChainPool {
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Printf(tr("Choose a pool [1-%d, Enter for the selected one]: "), len(quotes))
+		if !scanner.Scan() {
+			return selected
+		}
+		input := strings.TrimSpace(scanner.Text())
+		if input == "" {
+			return selected
+		}
+		n, err := strconv.Atoi(input)
+		if err != nil || n < 1 || n > len(quotes) {
+			fmt.Printf(tr("Enter a number between 1 and %d\n"), len(quotes))
+			continue
+		}
+		if quotes[n-1].Reason != "" {
+			fmt.Printf(tr("Pool %d cannot be traded: %s\n"), n, quotes[n-1].Reason)
+			continue
+		}
+		return quotes[n-1].Pool
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// DEFAULT_POOL_STATS_HOURS is the window pool stats aggregate swaps over
+const DEFAULT_POOL_STATS_HOURS = 24
+
+// PoolStats is a pool's volume and fee revenue over a window, decoded from its
+// own swap transactions, and its current TVL
+type PoolStats struct {
+	Pool          string    `json:"pool"`
+	Since         time.Time `json:"since"`
+	Swaps         int       `json:"swaps"`
+	Buys          int       `json:"buys"`
+	Sells         int       `json:"sells"`
+	VolumeSOL     float64   `json:"volume_sol"`
+	FeePct        float64   `json:"fee_pct"`
+	FeesSOL       float64   `json:"fees_sol"`
+	PriceSOL      float64   `json:"price_sol"`
+	ReserveSOL    float64   `json:"reserve_sol"`
+	ReserveTokens float64   `json:"reserve_tokens"`
+	TVLSOL        float64   `json:"tvl_sol"`
+	SOLPriceUSD   float64   `json:"sol_price_usd,omitempty"`
+	VolumeUSD     float64   `json:"volume_usd,omitempty"`
+	FeesUSD       float64   `json:"fees_usd,omitempty"`
+	TVLUSD        float64   `json:"tvl_usd,omitempty"`
+}
+
+// newPoolStats aggregates decoded swaps and the pool's reserves. The swap
+// fee is taken from the input: a buy's SOL volume includes it, while a sell's
+// SOL volume is what the input was worth after it.
+func newPoolStats(pool *OnChainPool, trades []PoolTrade, since time.Time) PoolStats {
+	numerator, denominator := swapFeeRate(pool)
+	rate := float64(numerator) / float64(denominator)
+
+	stats := PoolStats{Pool: pool.Address.String(), Since: since, Swaps: len(trades), FeePct: rate * 100}
+	for _, trade := range trades {
+		stats.VolumeSOL += trade.VolumeSOL
+		if trade.Side == "buy" {
+			stats.Buys++
+			stats.FeesSOL += trade.VolumeSOL * rate
+		} else {
+			stats.Sells++
+			stats.FeesSOL += trade.VolumeSOL * rate / (1 - rate)
+		}
+	}
+	stats.PriceSOL, stats.ReserveSOL, stats.ReserveTokens = poolPrice(pool)
+	stats.TVLSOL = stats.ReserveSOL + stats.ReserveTokens*stats.PriceSOL
+	return stats
+}
+
+// withUSD values the SOL figures at a SOL/USD price
+func (s *PoolStats) withUSD(solPriceUSD float64) {
+	s.SOLPriceUSD = solPriceUSD
+	s.VolumeUSD = s.VolumeSOL * solPriceUSD
+	s.FeesUSD = s.FeesSOL * solPriceUSD
+	s.TVLUSD = s.TVLSOL * solPriceUSD
+}
+
+// printPoolStats shows the stats, with USD figures when SOL was priced
+func printPoolStats(s PoolStats) {
+	usd := func(value float64) string {
+		if s.SOLPriceUSD == 0 {
+			return ""
+		}
+		return fmt.Sprintf(" ($%.2f)", value)
+	}
+	fmt.Printf("\n=== Pool Stats ===\n")
+	fmt.Printf("Pool: %s\n", s.Pool)
+	fmt.Printf("Since: %s\n", s.Since.Format(time.RFC3339))
+	fmt.Printf("Swaps: %d (%d buys, %d sells)\n", s.Swaps, s.Buys, s.Sells)
+	fmt.Printf("Volume: %.6f SOL%s\n", s.VolumeSOL, usd(s.VolumeUSD))
+	fmt.Printf("Fees (%.2f%%): %.6f SOL%s\n", s.FeePct, s.FeesSOL, usd(s.FeesUSD))
+	fmt.Printf("Price: %.9f SOL\n", s.PriceSOL)
+	fmt.Printf("Reserves: %.6f SOL + %.6f tokens\n", s.ReserveSOL, s.ReserveTokens)
+	fmt.Printf("TVL: %.6f SOL%s\n", s.TVLSOL, usd(s.TVLUSD))
+	if s.SOLPriceUSD > 0 {
+		fmt.Printf("SOL/USD: $%.2f\n", s.SOLPriceUSD)
+	}
+	fmt.Printf("==================\n")
+}
+
+// runPoolStats reports a pool's volume, fee revenue and TVL from on-chain data
+func runPoolStats(args []string) {
+	fs := newCommandFlagSet("pool stats")
+	poolAddr := fs.String("pool", "", "Pool address")
+	hours := fs.Float64("hours", DEFAULT_POOL_STATS_HOURS, "Window to aggregate swaps over")
+	maxTx := fs.Int("max-tx", DEFAULT_HISTORY_MAX_TX, "Maximum pool transactions to decode")
+	asJSON := fs.Bool("json", false, "Print the stats as JSON")
+	fs.Parse(args)
+	if *poolAddr == "" {
+		log.Fatal("Usage: go run . pool stats -pool POOL [-hours 24] [-max-tx 5000] [-json]")
+	}
+	if *hours <= 0 || *maxTx <= 0 {
+		log.Fatal("-hours and -max-tx must be positive")
+	}
+
+	ctx := context.Background()
+	client := newChainClient()
+	pool, err := loadPool(ctx, client, *poolAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !pool.BaseMint.Equals(WSOL_MINT) && !pool.QuoteMint.Equals(WSOL_MINT) {
+		log.Fatalf("pool stats need a SOL-paired pool, got %s/%s", pool.BaseMint, pool.QuoteMint)
+	}
+
+	since := time.Now().Add(-time.Duration(*hours * float64(time.Hour)))
+	trades, err := fetchPoolTrades(ctx, client, pool, since, *maxTx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	stats := newPoolStats(pool, trades, since)
+	if solPrice, err := newPoolPriceOracle(client).SOLPriceUSD(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: USD unavailable: %v\n", err)
+	} else {
+		stats.withUSD(solPrice)
+	}
+
+	if *asJSON {
+		data, _ := json.MarshalIndent(stats, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	printPoolStats(stats)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Raydium V4 AmmStatus values, stored in the first u64 of the pool account
+const (
+	AMM_STATUS_UNINITIALIZED  = 0
+	AMM_STATUS_INITIALIZED    = 1
+	AMM_STATUS_DISABLED       = 2
+	AMM_STATUS_WITHDRAW_ONLY  = 3
+	AMM_STATUS_LIQUIDITY_ONLY = 4
+	AMM_STATUS_ORDERBOOK_ONLY = 5
+	AMM_STATUS_SWAP_ONLY      = 6
+	AMM_STATUS_WAITING_TRADE  = 7
+)
+
+// A pool without a transaction for this long gets a warning before trading
+const DEFAULT_POOL_MAX_IDLE = 24 * time.Hour
+
+var ammStatusNames = map[uint64]string{
+	AMM_STATUS_UNINITIALIZED:  "uninitialized",
+	AMM_STATUS_INITIALIZED:    "initialized",
+	AMM_STATUS_DISABLED:       "disabled",
+	AMM_STATUS_WITHDRAW_ONLY:  "withdraw-only",
+	AMM_STATUS_LIQUIDITY_ONLY: "liquidity-only",
+	AMM_STATUS_ORDERBOOK_ONLY: "order-book-only",
+	AMM_STATUS_SWAP_ONLY:      "swap-only",
+	AMM_STATUS_WAITING_TRADE:  "waiting for trading",
+}
+
+// ammStatusName returns a readable name for an AmmStatus value
+func ammStatusName(status uint64) string {
+	if name, ok := ammStatusNames[status]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown status %d", status)
+}
+
+// checkTradable returns why the program would reject a swap on the pool at
+// now: a status without swap permission, an open time still ahead, or all
+// liquidity withdrawn, as happens when a pool has migrated
+func (p *OnChainPool) checkTradable(now time.Time) error {
+	switch p.Status {
+	case AMM_STATUS_INITIALIZED, AMM_STATUS_SWAP_ONLY, AMM_STATUS_WAITING_TRADE:
+	default:
+		return fmt.Errorf("pool %s is %s and does not accept swaps", p.Address, ammStatusName(p.Status))
+	}
+	if p.OpenTime > uint64(now.Unix()) {
+		return fmt.Errorf("pool %s opens for trading at %s", p.Address, time.Unix(int64(p.OpenTime), 0).UTC().Format(time.RFC3339))
+	}
+	if p.LpAmount == 0 {
+		return fmt.Errorf("pool %s has no liquidity left; it may have migrated", p.Address)
+	}
+	return nil
+}
+
+// lastPoolActivity returns the block time of the newest transaction touching the pool
+func lastPoolActivity(ctx context.Context, client ChainClient, pool solana.PublicKey) (time.Time, error) {
+	limit := 1
+	signatures, err := client.GetSignaturesForAddressWithOpts(ctx, pool, &rpc.GetSignaturesForAddressOpts{
+		Limit:      &limit,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get pool signatures: %w", err)
+	}
+	if len(signatures) == 0 || signatures[0].BlockTime == nil {
+		return time.Time{}, nil
+	}
+	return signatures[0].BlockTime.Time(), nil
+}
+
+// checkPoolFreshness loads the pool's current state and returns an error when
+// it cannot be traded. It only warns when the last transaction on the pool is
+// older than maxIdle; a maxIdle of zero skips that lookup.
+func checkPoolFreshness(ctx context.Context, client ChainClient, poolAddress string, maxIdle time.Duration) error {
+	poolPubkey, err := solana.PublicKeyFromBase58(poolAddress)
+	if err != nil {
+		return fmt.Errorf("invalid pool address: %w", err)
+	}
+	accountInfo, err := client.GetAccountInfo(ctx, poolPubkey)
+	if err != nil {
+		return fmt.Errorf("failed to get pool account: %w", err)
+	}
+	if err := checkPoolProgram(accountInfo.Value.Owner); err != nil {
+		return err
+	}
+	pool, err := parsePoolAccount(poolPubkey, accountInfo.Value.Data.GetBinary())
+	if err != nil {
+		return fmt.Errorf("failed to parse pool data: %w", err)
+	}
+	if err := pool.checkTradable(time.Now()); err != nil {
+		return err
+	}
+
+	if maxIdle <= 0 {
+		return nil
+	}
+	last, err := lastPoolActivity(ctx, client, poolPubkey)
+	switch {
+	case err != nil:
+		fmt.Printf("Warning: Could not check pool activity: %v\n", err)
+	case last.IsZero():
+		fmt.Println("⚠️  Warning: No transactions found for this pool; it may be dead")
+	case time.Since(last) > maxIdle:
+		fmt.Printf("⚠️  Warning: Last pool transaction was %s ago; it may be dead\n", time.Since(last).Round(time.Minute))
+	}
+	return nil
+}
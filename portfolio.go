@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+// TOKEN_2022_PROGRAM owns Token-2022 mints and accounts
+var TOKEN_2022_PROGRAM = solana.MustPublicKeyFromBase58("TokenzQdBNbLqP5VEhdkAS5EnNmuaYt3HKQmtWSs3Sr")
+
+// Holding is one token position in a wallet
+type Holding struct {
+	Mint     solana.PublicKey
+	Symbol   string
+	Name     string
+	Raw      uint64
+	Decimals int
+	PriceSOL float64 // 0 when no priced pool was found
+	Pool     string
+}
+
+// Balance returns the holding in UI units
+func (h *Holding) Balance() float64 {
+	return atoms.ToAmount(h.Raw, h.Decimals)
+}
+
+// ValueSOL returns the holding's value at its pool mid price
+func (h *Holding) ValueSOL() float64 {
+	return h.Balance() * h.PriceSOL
+}
+
+// runPortfolio lists the wallet's SOL and token balances with their value
+func runPortfolio(args []string) {
+	fs := newCommandFlagSet("portfolio")
+	ownerAddr := fs.String("address", "", "Wallet address (or "+WALLET_ADDRESS_ENV_VAR+", defaults to the "+PRIVATE_KEY_ENV_VAR+" wallet)")
+	fs.StringVar(ownerAddr, "owner", "", "Same as -address")
+	showAll := fs.Bool("all", false, "Include empty token accounts")
+	noPrice := fs.Bool("no-price", false, "Skip pool discovery and pricing (much faster)")
+	currency := fs.String("currency", CURRENCY_SOL, "Units for the total: sol or usd")
+	fs.Parse(args)
+
+	if *currency != CURRENCY_SOL && *currency != CURRENCY_USD {
+		log.Fatalf("Unsupported currency %q (supported: sol, usd)", *currency)
+	}
+
+	owner, err := loadWalletAddress(*ownerAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	client := newChainClient()
+
+	balance, err := client.GetBalance(ctx, owner, rpc.CommitmentConfirmed)
+	if err != nil {
+		log.Fatalf("Failed to get SOL balance: %v", err)
+	}
+	solBalance := atoms.ToAmount(balance.Value, SOL_DECIMALS)
+
+	holdings, err := fetchHoldings(ctx, client, owner, *showAll)
+	if err != nil {
+		log.Fatalf("Failed to list token accounts: %v", err)
+	}
+
+	if !*noPrice {
+		for _, holding := range holdings {
+			if holding.Raw == 0 {
+				continue
+			}
+			if holding.Mint.Equals(WSOL_MINT) {
+				holding.PriceSOL = 1
+				continue
+			}
+			pool, err := findPoolsOnChain(ctx, client, holding.Mint.String())
+			if err != nil {
+				continue
+			}
+			holding.PriceSOL, _, _ = poolPrice(pool)
+			holding.Pool = pool.Address.String()
+		}
+	}
+
+	sort.Slice(holdings, func(i, j int) bool {
+		return holdings[i].ValueSOL() > holdings[j].ValueSOL()
+	})
+
+	total := solBalance
+	fmt.Printf("\n=== PORTFOLIO ===\n")
+	fmt.Printf("Owner: %s\n", owner)
+	fmt.Printf("SOL: %.9f\n\n", solBalance)
+	fmt.Printf("%-10s %-24s %-44s %20s %16s %16s\n", "Symbol", "Name", "Mint", "Balance", "Price (SOL)", "Value (SOL)")
+	for _, h := range holdings {
+		price, value := "-", "-"
+		if h.PriceSOL > 0 {
+			price = fmt.Sprintf("%.9f", h.PriceSOL)
+			value = fmt.Sprintf("%.6f", h.ValueSOL())
+			total += h.ValueSOL()
+		}
+		fmt.Printf("%-10s %-24s %-44s %20s %16s %16s\n",
+			truncate(h.Symbol, 10), truncate(h.Name, 24), h.Mint, atoms.Format(h.Raw, h.Decimals), price, value)
+	}
+
+	fmt.Printf("\nTotal: %.6f SOL", total)
+	if *currency == CURRENCY_USD {
+		solPrice, err := newPoolPriceOracle(client).SOLPriceUSD(ctx)
+		if err != nil {
+			fmt.Printf(" (USD unavailable: %v)", err)
+		} else {
+			fmt.Printf(" ($%.2f)", total*solPrice)
+		}
+	}
+	fmt.Println()
+	if *noPrice {
+		fmt.Println("Token holdings were not priced (-no-price)")
+	}
+	fmt.Printf("=================\n")
+}
+
+// fetchHoldings lists the owner's SPL Token and Token-2022 accounts with mint
+// decimals and token metadata names
+func fetchHoldings(ctx context.Context, client ChainClient, owner solana.PublicKey, includeEmpty bool) ([]*Holding, error) {
+	byMint := make(map[solana.PublicKey]*Holding)
+	var mints []solana.PublicKey
+
+	for _, program := range []solana.PublicKey{solana.TokenProgramID, TOKEN_2022_PROGRAM} {
+		accounts, err := client.GetTokenAccountsByOwner(ctx, owner,
+			&rpc.GetTokenAccountsConfig{ProgramId: &program},
+			&rpc.GetTokenAccountsOpts{Encoding: solana.EncodingBase64, Commitment: rpc.CommitmentConfirmed},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Token account layout: mint(32) + owner(32) + amount(u64)
+		for _, account := range accounts.Value {
+			data := account.Account.Data.GetBinary()
+			if len(data) < TOKEN_ACCOUNT_AMOUNT_OFFSET+8 {
+				continue
+			}
+			mint := solana.PublicKeyFromBytes(data[0:32])
+			amount := binary.LittleEndian.Uint64(data[TOKEN_ACCOUNT_AMOUNT_OFFSET:])
+			if amount == 0 && !includeEmpty {
+				continue
+			}
+
+			if holding, ok := byMint[mint]; ok {
+				holding.Raw += amount
+				continue
+			}
+			byMint[mint] = &Holding{Mint: mint, Raw: amount}
+			mints = append(mints, mint)
+		}
+	}
+
+	// Decimals live at offset 44 of both mint layouts
+	for start := 0; start < len(mints); start += MAX_MULTIPLE_ACCOUNTS {
+		end := min(start+MAX_MULTIPLE_ACCOUNTS, len(mints))
+		accounts, err := client.GetMultipleAccounts(ctx, mints[start:end]...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch mints: %w", err)
+		}
+		for i, account := range accounts.Value {
+			if account == nil {
+				continue
+			}
+			if data := account.Data.GetBinary(); len(data) > 44 {
+				byMint[mints[start+i]].Decimals = int(data[44])
+			}
+		}
+	}
+
+	metadata, err := fetchTokenMetadata(ctx, client, mints)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
+	holdings := make([]*Holding, 0, len(mints))
+	for _, mint := range mints {
+		holding := byMint[mint]
+		if meta, ok := metadata[mint]; ok {
+			holding.Symbol, holding.Name = meta.Symbol, meta.Name
+		}
+		if holding.Mint.Equals(WSOL_MINT) {
+			holding.Symbol, holding.Name = "WSOL", "Wrapped SOL"
+		}
+		holdings = append(holdings, holding)
+	}
+	return holdings, nil
+}
+
+// truncate shortens s to n runes for table output
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
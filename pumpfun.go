@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+// pump.fun program accounts
+var (
+	PUMPFUN_PROGRAM         = solana.MustPublicKeyFromBase58("6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P")
+	PUMPFUN_GLOBAL          = solana.MustPublicKeyFromBase58("4wTV1YmiEkRvAtNtsSGPtUrqRYQMe5SKy2uB4Jjaxnjf")
+	PUMPFUN_FEE_RECIPIENT   = solana.MustPublicKeyFromBase58("CebN5WGQ4jvEPvsVU4EoHEpgzq1VV7AbicfhtW4xC9iM")
+	PUMPFUN_EVENT_AUTHORITY = solana.MustPublicKeyFromBase58("Ce6TQqeHC9p8KetsN6JsjHK7UTZk7nasjjnr7XxXp9F1")
+)
+
+// pump.fun constants
+const (
+	PUMPFUN_TOKEN_DECIMALS     = 6
+	PUMPFUN_FEE_BPS            = 100 // protocol (0.95%) + creator (0.05%) fee
+	PUMPFUN_CURVE_MIN_SIZE     = 81
+	PUMPFUN_CURVE_SEED         = "bonding-curve"
+	PUMPFUN_CREATOR_VAULT_SEED = "creator-vault"
+)
+
+// Anchor instruction discriminators
+var (
+	PUMPFUN_BUY_DISCRIMINATOR  = []byte{102, 6, 61, 18, 1, 218, 235, 234}
+	PUMPFUN_SELL_DISCRIMINATOR = []byte{51, 230, 133, 164, 1, 127, 131, 173}
+)
+
+// PumpCurve is the state of a pump.fun bonding curve
+type PumpCurve struct {
+	Address                solana.PublicKey
+	Mint                   solana.PublicKey
+	VirtualTokenReserves   uint64
+	VirtualSolReserves     uint64
+	RealTokenReserves      uint64
+	RealSolReserves        uint64
+	TokenTotalSupply       uint64
+	Complete               bool // the curve has filled and the token migrated off pump.fun
+	Creator                solana.PublicKey
+	AssociatedBondingCurve solana.PublicKey
+}
+
+// derivePumpBondingCurve returns the bonding curve PDA of a mint
+func derivePumpBondingCurve(mint solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress(
+		[][]byte{[]byte(PUMPFUN_CURVE_SEED), mint.Bytes()},
+		PUMPFUN_PROGRAM,
+	)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to derive bonding curve: %w", err)
+	}
+	return address, nil
+}
+
+// derivePumpCreatorVault returns the PDA collecting creator fees for a curve
+func derivePumpCreatorVault(creator solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress(
+		[][]byte{[]byte(PUMPFUN_CREATOR_VAULT_SEED), creator.Bytes()},
+		PUMPFUN_PROGRAM,
+	)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to derive creator vault: %w", err)
+	}
+	return address, nil
+}
+
+// parsePumpCurve decodes a bonding curve account
+func parsePumpCurve(address solana.PublicKey, mint solana.PublicKey, data []byte) (*PumpCurve, error) {
+	if len(data) < PUMPFUN_CURVE_MIN_SIZE {
+		return nil, fmt.Errorf("invalid bonding curve data size: %d", len(data))
+	}
+
+	curve := &PumpCurve{
+		Address:              address,
+		Mint:                 mint,
+		VirtualTokenReserves: binary.LittleEndian.Uint64(data[8:16]),
+		VirtualSolReserves:   binary.LittleEndian.Uint64(data[16:24]),
+		RealTokenReserves:    binary.LittleEndian.Uint64(data[24:32]),
+		RealSolReserves:      binary.LittleEndian.Uint64(data[32:40]),
+		TokenTotalSupply:     binary.LittleEndian.Uint64(data[40:48]),
+		Complete:             data[48] != 0,
+		Creator:              solana.PublicKeyFromBytes(data[49:81]),
+	}
+
+	associated, _, err := solana.FindAssociatedTokenAddress(address, mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive associated bonding curve: %w", err)
+	}
+	curve.AssociatedBondingCurve = associated
+
+	return curve, nil
+}
+
+// fetchPumpCurve loads the bonding curve of a mint, returning nil if the mint
+// was never launched on pump.fun
+func fetchPumpCurve(ctx context.Context, client ChainClient, mint solana.PublicKey) (*PumpCurve, error) {
+	address, err := derivePumpBondingCurve(mint)
+	if err != nil {
+		return nil, err
+	}
+
+	accountInfo, err := client.GetAccountInfo(ctx, address)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get bonding curve account: %w", err)
+	}
+	if accountInfo == nil || accountInfo.Value == nil || !accountInfo.Value.Owner.Equals(PUMPFUN_PROGRAM) {
+		return nil, nil
+	}
+
+	return parsePumpCurve(address, mint, accountInfo.Value.Data.GetBinary())
+}
+
+// quotePumpBuy returns the tokens received for solIn lamports, fee included
+func quotePumpBuy(curve *PumpCurve, solIn uint64) uint64 {
+	// The fee is charged on top of the SOL that enters the curve
+	solToCurve := atoms.MulDiv(solIn, 10000, 10000+PUMPFUN_FEE_BPS, false)
+	tokensOut := atoms.MulDiv(curve.VirtualTokenReserves, solToCurve, curve.VirtualSolReserves+solToCurve, false)
+	return min(tokensOut, curve.RealTokenReserves)
+}
+
+// quotePumpSell returns the lamports received for tokensIn, fee deducted
+func quotePumpSell(curve *PumpCurve, tokensIn uint64) uint64 {
+	solOut := atoms.MulDiv(curve.VirtualSolReserves, tokensIn, curve.VirtualTokenReserves+tokensIn, false)
+	solOut -= atoms.MulDiv(solOut, PUMPFUN_FEE_BPS, 10000, true)
+	return min(solOut, curve.RealSolReserves)
+}
+
+// pumpAccounts returns the account list shared by buy and sell; the two
+// instructions only differ in where the creator vault sits
+func pumpAccounts(curve *PumpCurve, creatorVault solana.PublicKey, userATA solana.PublicKey, user solana.PublicKey, sell bool) []*solana.AccountMeta {
+	accounts := []*solana.AccountMeta{
+		// 0. Global config
+		{PublicKey: PUMPFUN_GLOBAL, IsSigner: false, IsWritable: false},
+		// 1. Fee recipient
+		{PublicKey: PUMPFUN_FEE_RECIPIENT, IsSigner: false, IsWritable: true},
+		// 2. Mint
+		{PublicKey: curve.Mint, IsSigner: false, IsWritable: false},
+		// 3. Bonding curve
+		{PublicKey: curve.Address, IsSigner: false, IsWritable: true},
+		// 4. Bonding curve token account
+		{PublicKey: curve.AssociatedBondingCurve, IsSigner: false, IsWritable: true},
+		// 5. User token account
+		{PublicKey: userATA, IsSigner: false, IsWritable: true},
+		// 6. User (signer)
+		{PublicKey: user, IsSigner: true, IsWritable: true},
+		// 7. System program
+		{PublicKey: solana.SystemProgramID, IsSigner: false, IsWritable: false},
+	}
+
+	tokenProgram := &solana.AccountMeta{PublicKey: token.ProgramID, IsSigner: false, IsWritable: false}
+	vault := &solana.AccountMeta{PublicKey: creatorVault, IsSigner: false, IsWritable: true}
+	if sell {
+		accounts = append(accounts, vault, tokenProgram)
+	} else {
+		accounts = append(accounts, tokenProgram, vault)
+	}
+
+	return append(accounts,
+		&solana.AccountMeta{PublicKey: PUMPFUN_EVENT_AUTHORITY, IsSigner: false, IsWritable: false},
+		&solana.AccountMeta{PublicKey: PUMPFUN_PROGRAM, IsSigner: false, IsWritable: false},
+	)
+}
+
+// createPumpBuyInstruction buys tokenAmount tokens spending at most maxSolCost lamports
+func createPumpBuyInstruction(curve *PumpCurve, creatorVault solana.PublicKey, userATA solana.PublicKey, user solana.PublicKey, tokenAmount uint64, maxSolCost uint64) solana.Instruction {
+	data := make([]byte, 24)
+	copy(data[0:8], PUMPFUN_BUY_DISCRIMINATOR)
+	binary.LittleEndian.PutUint64(data[8:16], tokenAmount)
+	binary.LittleEndian.PutUint64(data[16:24], maxSolCost)
+
+	return solana.NewInstruction(PUMPFUN_PROGRAM, pumpAccounts(curve, creatorVault, userATA, user, false), data)
+}
+
+// createPumpSellInstruction sells tokenAmount tokens for at least minSolOutput lamports
+func createPumpSellInstruction(curve *PumpCurve, creatorVault solana.PublicKey, userATA solana.PublicKey, user solana.PublicKey, tokenAmount uint64, minSolOutput uint64) solana.Instruction {
+	data := make([]byte, 24)
+	copy(data[0:8], PUMPFUN_SELL_DISCRIMINATOR)
+	binary.LittleEndian.PutUint64(data[8:16], tokenAmount)
+	binary.LittleEndian.PutUint64(data[16:24], minSolOutput)
+
+	return solana.NewInstruction(PUMPFUN_PROGRAM, pumpAccounts(curve, creatorVault, userATA, user, true), data)
+}
+
+// buildPumpTransaction builds a bonding curve buy or sell for owner. For buys
+// amountIn is SOL and the token amount is fixed at the quote reduced by
+// slippage; for sells amountIn is tokens.
+func buildPumpTransaction(
+	ctx context.Context,
+	client ChainClient,
+	owner solana.PublicKey,
+	curve *PumpCurve,
+	side string,
+	amountIn float64,
+	slippage float64,
+) (*solana.Transaction, error) {
+	creatorVault, err := derivePumpCreatorVault(curve.Creator)
+	if err != nil {
+		return nil, err
+	}
+
+	userATA, createIx, err := getOrCreateATA(ctx, client, owner, curve.Mint)
+	if err != nil {
+		return nil, err
+	}
+
+	var instructions []solana.Instruction
+	if createIx != nil {
+		fmt.Printf("Creating ATA for token %s\n", curve.Mint)
+		instructions = append(instructions, createIx)
+	}
+
+	if side == "buy" {
+		solIn, err := atoms.FromAmount(amountIn, SOL_DECIMALS)
+		if err != nil {
+			return nil, err
+		}
+		minTokens := atoms.ApplySlippage(quotePumpBuy(curve, solIn), slippage)
+		instructions = append(instructions, createPumpBuyInstruction(curve, creatorVault, userATA, owner, minTokens, solIn))
+	} else {
+		tokensIn, err := atoms.FromAmount(amountIn, PUMPFUN_TOKEN_DECIMALS)
+		if err != nil {
+			return nil, err
+		}
+		minSol := atoms.ApplySlippage(quotePumpSell(curve, tokensIn), slippage)
+		instructions = append(instructions, createPumpSellInstruction(curve, creatorVault, userATA, owner, tokensIn, minSol))
+	}
+
+	latestBlockhash, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+
+	tx, err := solana.NewTransaction(
+		instructions,
+		latestBlockhash.Value.Blockhash,
+		solana.TransactionPayer(owner),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	return tx, nil
+}
+
+// runPumpSwap quotes and optionally executes a swap against a live bonding curve
+func runPumpSwap(
+	ctx context.Context,
+	client ChainClient,
+	wallet solana.PrivateKey,
+	curve *PumpCurve,
+	side string,
+	amount float64,
+	execute bool,
+	dryRun bool,
+	guard *SpendGuard,
+	opts BuildOptions,
+) error {
+	var quote float64
+	if side == "buy" {
+		solIn, err := atoms.FromAmount(amount, SOL_DECIMALS)
+		if err != nil {
+			return err
+		}
+		quote = atoms.ToAmount(quotePumpBuy(curve, solIn), PUMPFUN_TOKEN_DECIMALS)
+	} else {
+		tokensIn, err := atoms.FromAmount(amount, PUMPFUN_TOKEN_DECIMALS)
+		if err != nil {
+			return err
+		}
+		quote = atoms.ToAmount(quotePumpSell(curve, tokensIn), SOL_DECIMALS)
+	}
+
+	fmt.Printf("\n=== QUOTE RESULT ===\n")
+	fmt.Printf("Protocol: pump.fun bonding curve\n")
+	fmt.Printf("Bonding Curve: %s\n", curve.Address)
+	fmt.Printf("Curve Progress: %.2f SOL raised\n", float64(curve.RealSolReserves)/math.Pow(10, SOL_DECIMALS))
+	fmt.Printf("Operation: %s\n", strings.ToUpper(side))
+	fmt.Printf("Amount In: %.9f\n", amount)
+	fmt.Printf("Expected Out: %.9f\n", quote)
+	fmt.Printf("====================\n")
+
+	if !execute && !dryRun {
+		return nil
+	}
+
+	if quote <= 0 {
+		return fmt.Errorf("bonding curve returns nothing for this amount")
+	}
+
+	trade := newGuardedTrade(curve.Mint, side, amount, quote, opts.Burn)
+	if execute && !dryRun {
+		if err := guard.Enforce(ctx, trade, true); err != nil {
+			return err
+		}
+		defer guard.Release(trade)
+	}
+
+	if execute && !dryRun && !confirmQuote(curve.Address.String(), side, amount, quote, tokenSymbol(ctx, client, curve.Mint), opts) {
+		fmt.Println("\nSwap cancelled by user.")
+		return nil
+	}
+
+	slippage, err := getSlippageFromUser()
+	if err != nil {
+		return fmt.Errorf("failed to get slippage: %w", err)
+	}
+
+	tx, err := buildPumpTransaction(ctx, client, wallet.PublicKey(), curve, side, amount, slippage)
+	if err != nil {
+		return err
+	}
+
+	if err := signTransaction(tx, wallet); err != nil {
+		return err
+	}
+
+	if dryRun {
+		return simulateTransaction(ctx, client, tx)
+	}
+
+	sig, err := sendAndConfirmTransaction(ctx, client, tx, opts)
+	if err != nil {
+		return err
+	}
+	if err := guard.Record(trade); err != nil {
+		fmt.Printf("Warning: Could not record trade for spend limits: %v\n", err)
+	}
+
+	fmt.Printf("\n✅ Swap executed successfully!\n")
+	fmt.Printf("Transaction: %s\n", sig)
+	fmt.Printf("Explorer: %s\n", explorerTxURL(sig.String()))
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+// SwapQuote is a quote resolved to a concrete pool, ready to execute
+type SwapQuote struct {
+	PoolAddress    string
+	Side           string
+	AmountIn       float64
+	ExpectedOut    float64 // UI units of the output token, for display and pricing
+	ExpectedOutRaw uint64  // raw atoms of the output token, for minimum-out bounds
+	OutputDecimals int
+	TokenMint      solana.PublicKey
+	TokenSymbol    string
+}
+
+// Price returns the quoted price in SOL per token
+func (q *SwapQuote) Price() float64 {
+	if q.Side == "buy" {
+		if q.ExpectedOut == 0 {
+			return 0
+		}
+		return q.AmountIn / q.ExpectedOut
+	}
+	return q.ExpectedOut / q.AmountIn
+}
+
+// SOLAmount returns the SOL leg of the swap
+func (q *SwapQuote) SOLAmount() float64 {
+	if q.Side == "buy" {
+		return q.AmountIn
+	}
+	return q.ExpectedOut
+}
+
+// MinAmountOut returns the raw minimum output for a slippage tolerance in percent
+func (q *SwapQuote) MinAmountOut(slippage float64) uint64 {
+	return calculateMinAmountOut(q.ExpectedOutRaw, slippage)
+}
+
+// resolveSwapQuote validates params, resolves TokenAddress to its best pool
+// when no pool is given, and quotes the swap
+func resolveSwapQuote(ctx context.Context, client ChainClient, params QuoteParams) (*SwapQuote, error) {
+	if params.Side != "buy" && params.Side != "sell" {
+		return nil, fmt.Errorf("side must be 'buy' or 'sell'")
+	}
+	if params.Amount < MIN_SWAP_AMOUNT {
+		return nil, fmt.Errorf("minimum swap amount is %.3f", MIN_SWAP_AMOUNT)
+	}
+
+	if params.PoolAddress == "" {
+		if params.TokenAddress == "" {
+			return nil, fmt.Errorf("either pool or token must be specified")
+		}
+		pool, err := findPoolForTrade(ctx, client, params.TokenAddress, params.Side, params.Amount)
+		if err != nil {
+			return nil, err
+		}
+		params.PoolAddress = pool.Address.String()
+	}
+
+	expectedOutRaw, outputDecimals, err := calculateQuoteOnChain(ctx, client, params)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := loadPool(ctx, client, params.PoolAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenMint := pool.BaseMint
+	if isBaseCurrency(pool) {
+		tokenMint = pool.QuoteMint
+	}
+
+	return &SwapQuote{
+		PoolAddress:    params.PoolAddress,
+		Side:           params.Side,
+		AmountIn:       params.Amount,
+		ExpectedOut:    atoms.ToAmount(expectedOutRaw, outputDecimals),
+		ExpectedOutRaw: expectedOutRaw,
+		OutputDecimals: outputDecimals,
+		TokenMint:      tokenMint,
+		TokenSymbol:    tokenSymbol(ctx, client, tokenMint),
+	}, nil
+}
+
+// requoteBeforeSend re-reads the pool's vaults at confirmed commitment right
+// before the swap is built and reprices it. While the fresh quote is within
+// slippage of the confirmed one, the confirmed quote is returned unchanged.
+// Beyond it the user is asked whether to swap at the fresh quote, which is
+// then returned; declining aborts the swap. Quotes are raw output atoms.
+func requoteBeforeSend(ctx context.Context, client ChainClient, pool *OnChainPool, side string, amount float64, confirmed uint64, slippage float64) (uint64, error) {
+	if err := fetchVaultBalancesAt(ctx, client, pool, rpc.CommitmentConfirmed); err != nil {
+		return 0, fmt.Errorf("failed to re-read reserves: %w", err)
+	}
+
+	_, destinationMint, _ := swapMints(pool, side)
+	outputDecimals := int(pool.BaseDecimals)
+	if destinationMint.Equals(pool.QuoteMint) {
+		outputDecimals = int(pool.QuoteDecimals)
+	}
+	fresh := poolNetOutput(pool, side, amount)
+	if confirmed == 0 {
+		return fresh, nil
+	}
+	if fresh >= atoms.ApplySlippage(confirmed, slippage) {
+		return confirmed, nil
+	}
+
+	drift := float64(confirmed-fresh) / float64(confirmed) * 100
+	fmt.Printf("\n⚠️  Reserves moved since the quote: expected out %s is now %s (%.4f%% worse, tolerance %.2f%%)\n",
+		atoms.Format(confirmed, outputDecimals), atoms.Format(fresh, outputDecimals), drift, slippage)
+	if fresh == 0 || !confirmPrompt("Swap at the new quote?") {
+		return 0, fmt.Errorf("quote moved beyond the slippage tolerance")
+	}
+	return fresh, nil
+}
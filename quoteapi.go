@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"awesomeProject/internal/atoms"
+)
+
+// The server also answers Jupiter's v6 quote API, so frontends and bots built
+// against Jupiter can point at it for Raydium-only quotes. Routes are always
+// a single direct Raydium V4 hop between SOL and a token.
+const (
+	JUPITER_QUOTE_PATH         = "/v6/quote"
+	JUPITER_SWAP_MODE_EXACT_IN = "ExactIn"
+	JUPITER_DEFAULT_SLIPPAGE   = 50 // bps
+	JUPITER_ROUTE_LABEL        = "Raydium"
+)
+
+// JupiterQuoteResponse mirrors the fields of Jupiter's /v6/quote response,
+// without contextSlot since quotes may come from the cache. Amounts are raw
+// integer strings; priceImpactPct is a fraction, not percent.
+type JupiterQuoteResponse struct {
+	InputMint            string             `json:"inputMint"`
+	InAmount             string             `json:"inAmount"`
+	OutputMint           string             `json:"outputMint"`
+	OutAmount            string             `json:"outAmount"`
+	OtherAmountThreshold string             `json:"otherAmountThreshold"`
+	SwapMode             string             `json:"swapMode"`
+	SlippageBps          uint64             `json:"slippageBps"`
+	PlatformFee          any                `json:"platformFee"`
+	PriceImpactPct       string             `json:"priceImpactPct"`
+	RoutePlan            []JupiterRoutePlan `json:"routePlan"`
+	TimeTaken            float64            `json:"timeTaken"`
+}
+
+// JupiterRoutePlan is one hop of a route
+type JupiterRoutePlan struct {
+	SwapInfo JupiterSwapInfo `json:"swapInfo"`
+	Percent  int             `json:"percent"`
+}
+
+// JupiterSwapInfo describes the pool a hop trades through
+type JupiterSwapInfo struct {
+	AmmKey     string `json:"ammKey"`
+	Label      string `json:"label"`
+	InputMint  string `json:"inputMint"`
+	OutputMint string `json:"outputMint"`
+	InAmount   string `json:"inAmount"`
+	OutAmount  string `json:"outAmount"`
+	FeeAmount  string `json:"feeAmount"`
+	FeeMint    string `json:"feeMint"`
+}
+
+// writeJupiterError answers with Jupiter's error shape
+func writeJupiterError(w http.ResponseWriter, status int, format string, args ...any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// serveJupiterQuote answers GET /v6/quote?inputMint=&outputMint=&amount=&slippageBps=
+func (s *grpcServer) serveJupiterQuote(w http.ResponseWriter, r *http.Request) {
+	started := time.Now()
+	if r.Method != http.MethodGet {
+		writeJupiterError(w, http.StatusMethodNotAllowed, "quotes are requested with GET")
+		return
+	}
+	query := r.URL.Query()
+	if mode := query.Get("swapMode"); mode != "" && mode != JUPITER_SWAP_MODE_EXACT_IN {
+		writeJupiterError(w, http.StatusBadRequest, "swapMode %s is not supported, only %s", mode, JUPITER_SWAP_MODE_EXACT_IN)
+		return
+	}
+	inputMint, err := solana.PublicKeyFromBase58(query.Get("inputMint"))
+	if err != nil {
+		writeJupiterError(w, http.StatusBadRequest, "invalid inputMint: %v", err)
+		return
+	}
+	outputMint, err := solana.PublicKeyFromBase58(query.Get("outputMint"))
+	if err != nil {
+		writeJupiterError(w, http.StatusBadRequest, "invalid outputMint: %v", err)
+		return
+	}
+	amountRaw, err := strconv.ParseUint(query.Get("amount"), 10, 64)
+	if err != nil || amountRaw == 0 {
+		writeJupiterError(w, http.StatusBadRequest, "amount must be a positive integer in raw units")
+		return
+	}
+	slippageBps := uint64(JUPITER_DEFAULT_SLIPPAGE)
+	if value := query.Get("slippageBps"); value != "" {
+		if slippageBps, err = strconv.ParseUint(value, 10, 64); err != nil || slippageBps > 10_000 {
+			writeJupiterError(w, http.StatusBadRequest, "slippageBps must be between 0 and 10000")
+			return
+		}
+	}
+
+	// One side must be SOL: buys spend it, sells receive it
+	var side string
+	var token solana.PublicKey
+	switch {
+	case inputMint.Equals(WSOL_MINT) && !outputMint.Equals(WSOL_MINT):
+		side, token = "buy", outputMint
+	case outputMint.Equals(WSOL_MINT) && !inputMint.Equals(WSOL_MINT):
+		side, token = "sell", inputMint
+	default:
+		writeJupiterError(w, http.StatusBadRequest, "one of inputMint and outputMint must be %s; only direct SOL routes are quoted", WSOL_MINT)
+		return
+	}
+
+	ctx := r.Context()
+	inputDecimals, err := getTokenDecimals(ctx, s.client, inputMint.String())
+	if err != nil {
+		writeJupiterError(w, http.StatusBadRequest, "failed to get decimals of %s: %v", inputMint, err)
+		return
+	}
+	quote, err := cachedSwapQuote(ctx, s.client, s.quotes, QuoteParams{
+		TokenAddress: token.String(),
+		Amount:       atoms.ToAmount(amountRaw, int(inputDecimals)),
+		Side:         side,
+	})
+	if err != nil {
+		writeJupiterError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	if !quote.TokenMint.Equals(token) {
+		writeJupiterError(w, http.StatusBadRequest, "no SOL route found for %s", token)
+		return
+	}
+	pool, err := loadPool(ctx, s.client, quote.PoolAddress)
+	if err != nil {
+		writeJupiterError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	if !currencyMint(pool).Equals(WSOL_MINT) {
+		writeJupiterError(w, http.StatusBadRequest, "no SOL route found for %s", token)
+		return
+	}
+
+	outRaw := quote.ExpectedOutRaw
+	feeNumerator, feeDenominator := swapFeeRate(pool)
+	impact := quotedPriceImpact(pool, side, quote.AmountIn) / 100
+
+	resp := JupiterQuoteResponse{
+		InputMint:            inputMint.String(),
+		InAmount:             strconv.FormatUint(amountRaw, 10),
+		OutputMint:           outputMint.String(),
+		OutAmount:            strconv.FormatUint(outRaw, 10),
+		OtherAmountThreshold: strconv.FormatUint(atoms.ApplySlippage(outRaw, float64(slippageBps)/100), 10),
+		SwapMode:             JUPITER_SWAP_MODE_EXACT_IN,
+		SlippageBps:          slippageBps,
+		PriceImpactPct:       strconv.FormatFloat(impact, 'f', -1, 64),
+		RoutePlan: []JupiterRoutePlan{{
+			SwapInfo: JupiterSwapInfo{
+				AmmKey:     quote.PoolAddress,
+				Label:      JUPITER_ROUTE_LABEL,
+				InputMint:  inputMint.String(),
+				OutputMint: outputMint.String(),
+				InAmount:   strconv.FormatUint(amountRaw, 10),
+				OutAmount:  strconv.FormatUint(outRaw, 10),
+				FeeAmount:  strconv.FormatUint(atoms.MulDiv(amountRaw, feeNumerator, feeDenominator, false), 10),
+				FeeMint:    inputMint.String(),
+			},
+			Percent: 100,
+		}},
+		TimeTaken: time.Since(started).Seconds(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
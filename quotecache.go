@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"awesomeProject/internal/atoms"
+)
+
+// Quote cache settings. Amounts within QUOTE_CACHE_BUCKET_PCT of each other
+// share an entry; the cached output is scaled to the requested amount, which
+// is exact up to the price impact difference across the bucket.
+const (
+	DEFAULT_QUOTE_CACHE_TTL = 500 * time.Millisecond
+	QUOTE_CACHE_BUCKET_PCT  = 0.1
+)
+
+// quoteCacheKey identifies quotes of the same swap: target is the requested
+// pool, or the token when the pool is resolved by the router
+type quoteCacheKey struct {
+	target string
+	side   string
+	bucket int64
+}
+
+type cachedQuote struct {
+	quote   *SwapQuote
+	expires time.Time
+}
+
+// QuoteCache serves repeated quotes from memory for a short TTL so bursts of
+// identical requests cost one quote. With a stream, entries of a pool are
+// dropped as soon as its vaults change. A nil cache caches nothing.
+type QuoteCache struct {
+	ttl    time.Duration
+	stream UpdateStream // nil when the owner invalidates entries itself
+	done   chan struct{}
+
+	mu      sync.Mutex
+	entries map[quoteCacheKey]cachedQuote
+	watched map[string]bool // pools whose vaults invalidate their entries
+	hits    uint64
+	misses  uint64
+}
+
+// newQuoteCache creates a cache, or returns nil when ttl is not positive
+func newQuoteCache(ttl time.Duration, stream UpdateStream) *QuoteCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &QuoteCache{
+		ttl:     ttl,
+		stream:  stream,
+		done:    make(chan struct{}),
+		entries: make(map[quoteCacheKey]cachedQuote),
+		watched: make(map[string]bool),
+	}
+}
+
+// quoteAmountBucket maps an amount to its bucket on a logarithmic scale, so
+// every bucket spans the same relative width
+func quoteAmountBucket(amount float64) int64 {
+	return int64(math.Round(math.Log(amount) / math.Log1p(QUOTE_CACHE_BUCKET_PCT/100)))
+}
+
+// Get returns a fresh quote of the same swap scaled to amount, or nil
+func (c *QuoteCache) Get(target string, side string, amount float64) *SwapQuote {
+	if c == nil || amount <= 0 {
+		return nil
+	}
+	key := quoteCacheKey{target: target, side: side, bucket: quoteAmountBucket(amount)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		c.misses++
+		return nil
+	}
+	c.hits++
+
+	quote := *entry.quote
+	quote.ExpectedOutRaw = atoms.Scale(quote.ExpectedOutRaw, amount, quote.AmountIn)
+	quote.ExpectedOut = atoms.ToAmount(quote.ExpectedOutRaw, quote.OutputDecimals)
+	quote.AmountIn = amount
+	return &quote
+}
+
+// Put caches a quote requested for target
+func (c *QuoteCache) Put(target string, quote *SwapQuote) {
+	if c == nil || quote.AmountIn <= 0 {
+		return
+	}
+	key := quoteCacheKey{target: target, side: quote.Side, bucket: quoteAmountBucket(quote.AmountIn)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cachedQuote{quote: quote, expires: time.Now().Add(c.ttl)}
+}
+
+// Invalidate drops every cached quote priced from a pool
+func (c *QuoteCache) Invalidate(poolAddress string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.entries {
+		if entry.quote.PoolAddress == poolAddress {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Stats reports cache hits and misses
+func (c *QuoteCache) Stats() (hits uint64, misses uint64) {
+	if c == nil {
+		return 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Run keeps the vault subscriptions alive until ctx is cancelled
+func (c *QuoteCache) Run(ctx context.Context) error {
+	if c == nil || c.stream == nil {
+		return nil
+	}
+	defer close(c.done)
+	return c.stream.Run(ctx)
+}
+
+// watching reports whether a pool's vaults already invalidate its entries,
+// or cannot because the cache has no stream
+func (c *QuoteCache) watching(poolAddress string) bool {
+	if c == nil || c.stream == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.watched[poolAddress]
+}
+
+// watch invalidates a pool's entries on every update of its vaults
+func (c *QuoteCache) watch(pool *OnChainPool) {
+	address := pool.Address.String()
+	c.mu.Lock()
+	if c.watched[address] {
+		c.mu.Unlock()
+		return
+	}
+	c.watched[address] = true
+	c.mu.Unlock()
+
+	baseUpdates := c.stream.SubscribeAccount(pool.BaseVault)
+	quoteUpdates := c.stream.SubscribeAccount(pool.QuoteVault)
+	go func() {
+		defer c.stream.Unsubscribe(baseUpdates)
+		defer c.stream.Unsubscribe(quoteUpdates)
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-baseUpdates:
+			case <-quoteUpdates:
+			}
+			c.Invalidate(address)
+		}
+	}()
+}
+
+// cachedSwapQuote quotes params through the cache. On a miss the quote is
+// resolved over RPC and the quoted pool's vaults are watched, so later
+// updates invalidate it.
+func cachedSwapQuote(ctx context.Context, client ChainClient, cache *QuoteCache, params QuoteParams) (*SwapQuote, error) {
+	target := params.PoolAddress
+	if target == "" {
+		target = "token:" + params.TokenAddress
+	}
+	if quote := cache.Get(target, params.Side, params.Amount); quote != nil {
+		return quote, nil
+	}
+
+	quote, err := resolveSwapQuote(ctx, client, params)
+	if err != nil {
+		return nil, err
+	}
+	cache.Put(target, quote)
+
+	if !cache.watching(quote.PoolAddress) {
+		// The vault addresses cost one pool read, once per pool
+		pool, err := loadPool(ctx, client, quote.PoolAddress)
+		if err != nil {
+			fmt.Printf("Warning: quotes of %s only expire: %v\n", quote.PoolAddress, err)
+		} else {
+			cache.watch(pool)
+		}
+	}
+	return quote, nil
+}
+
+// printQuoteCacheStats prints the cache hit rate at shutdown
+func printQuoteCacheStats(cache *QuoteCache) {
+	hits, misses := cache.Stats()
+	if total := hits + misses; total > 0 {
+		fmt.Printf("Quote cache: %d of %d quotes served from cache (%.1f%%)\n", hits, total, float64(hits)/float64(total)*100)
+	}
+}
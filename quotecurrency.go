@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+
+	"awesomeProject/internal/atoms"
+)
+
+// Currencies a sell can be paid out in, for -quote-currency. usdc also
+// covers USDT pools; auto picks whichever pays out more in USD.
+const (
+	QUOTE_CURRENCY_SOL  = "sol"
+	QUOTE_CURRENCY_USDC = "usdc"
+	QUOTE_CURRENCY_AUTO = "auto"
+)
+
+// USDT_MINT is only deployed on mainnet
+var USDT_MINT = solana.MustPublicKeyFromBase58("Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYb")
+
+// stableMints returns the USD stablecoins pools may be paired with on the active cluster
+func stableMints() []solana.PublicKey {
+	return []solana.PublicKey{USDC_MINT, USDT_MINT}
+}
+
+// isStableMint reports whether mint is a USD stablecoin, valued at $1
+func isStableMint(mint solana.PublicKey) bool {
+	return mint.Equals(USDC_MINT) || mint.Equals(USDT_MINT)
+}
+
+// isSolMint reports whether mint is native or wrapped SOL
+func isSolMint(mint solana.PublicKey) bool {
+	return mint.Equals(WSOL_MINT) || mint.Equals(SOL_MINT)
+}
+
+// isBaseCurrency reports whether the pool's currency side, SOL or a
+// stablecoin, is its base. SOL wins when a pool pairs SOL with a stablecoin.
+func isBaseCurrency(pool *OnChainPool) bool {
+	if isSolMint(pool.BaseMint) {
+		return true
+	}
+	return isStableMint(pool.BaseMint) && !isSolMint(pool.QuoteMint)
+}
+
+// currencyMint returns the mint of the pool's currency side
+func currencyMint(pool *OnChainPool) solana.PublicKey {
+	if isBaseCurrency(pool) {
+		return pool.BaseMint
+	}
+	return pool.QuoteMint
+}
+
+// currencyDecimals returns the decimals of the pool's currency side. SOL
+// needs no loaded decimals.
+func currencyDecimals(pool *OnChainPool) int {
+	if isSolMint(currencyMint(pool)) {
+		return SOL_DECIMALS
+	}
+	if isBaseCurrency(pool) {
+		return int(pool.BaseDecimals)
+	}
+	return int(pool.QuoteDecimals)
+}
+
+// isStablePaired reports whether the pool trades its token against a stablecoin rather than SOL
+func isStablePaired(pool *OnChainPool) bool {
+	return isStableMint(currencyMint(pool))
+}
+
+// quoteCurrencyMints returns the mints whose pools a -quote-currency may trade in
+func quoteCurrencyMints(currency string) ([]solana.PublicKey, error) {
+	switch currency {
+	case QUOTE_CURRENCY_SOL:
+		return []solana.PublicKey{WSOL_MINT, SOL_MINT}, nil
+	case QUOTE_CURRENCY_USDC:
+		return stableMints(), nil
+	case QUOTE_CURRENCY_AUTO:
+		return append([]solana.PublicKey{WSOL_MINT, SOL_MINT}, stableMints()...), nil
+	}
+	return nil, fmt.Errorf("unsupported quote currency %q (supported: sol, usdc, auto)", currency)
+}
+
+// currencyRoute is the best pool of one currency for a sell and what it pays out
+type currencyRoute struct {
+	Currency solana.PublicKey
+	Pool     *OnChainPool
+	Out      float64 // in the currency
+	ValueUSD float64
+}
+
+// selectCurrencyPool picks among pools paired with different currencies: the
+// best pool of each currency is chosen by pick, and the routes are compared by
+// their output valued in USD, SOL at the oracle price and stablecoins at $1.
+// With a single currency the oracle is never asked.
+func selectCurrencyPool(ctx context.Context, oracle PriceOracle, pools []*OnChainPool, side string, amount float64, pick func([]*OnChainPool) *OnChainPool) (*OnChainPool, error) {
+	byCurrency := make(map[solana.PublicKey][]*OnChainPool)
+	var currencies []solana.PublicKey
+	for _, pool := range pools {
+		currency := currencyMint(pool)
+		if isSolMint(currency) {
+			currency = WSOL_MINT
+		}
+		if _, ok := byCurrency[currency]; !ok {
+			currencies = append(currencies, currency)
+		}
+		byCurrency[currency] = append(byCurrency[currency], pool)
+	}
+	if len(currencies) == 1 {
+		return pick(pools), nil
+	}
+
+	var routes []currencyRoute
+	for _, currency := range currencies {
+		pool := pick(byCurrency[currency])
+		if pool == nil {
+			continue
+		}
+		route := currencyRoute{Currency: currency, Pool: pool}
+		route.Out = atoms.ToAmount(poolNetOutput(pool, side, amount), currencyDecimals(pool))
+		route.ValueUSD = route.Out
+		if currency.Equals(WSOL_MINT) {
+			price, err := oracle.SOLPriceUSD(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compare quote currencies: %w", err)
+			}
+			route.ValueUSD *= price
+		}
+		routes = append(routes, route)
+	}
+
+	var best *currencyRoute
+	fmt.Printf("\n=== QUOTE CURRENCIES ===\n")
+	for i, route := range routes {
+		fmt.Printf("%s: %.9f %s ($%.2f)\n", route.Pool.Address, route.Out, currencyName(route.Currency), route.ValueUSD)
+		if best == nil || route.ValueUSD > best.ValueUSD {
+			best = &routes[i]
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	fmt.Printf("Best: %s\n", currencyName(best.Currency))
+	return best.Pool, nil
+}
+
+// currencyName labels a currency mint for output
+func currencyName(mint solana.PublicKey) string {
+	switch {
+	case isSolMint(mint):
+		return "SOL"
+	case mint.Equals(USDC_MINT):
+		return "USDC"
+	case mint.Equals(USDT_MINT):
+		return "USDT"
+	}
+	return mint.String()
+}
+
+// currencyNames joins the labels of currency mints, listing SOL once
+func currencyNames(mints []solana.PublicKey) string {
+	var names []string
+	for _, mint := range mints {
+		if name := currencyName(mint); !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "/")
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	// RECEIPTS_FILE in the state directory holds the signed report of every executed swap
+	RECEIPTS_FILE = "receipts.json"
+	// RECEIPT_DOMAIN prefixes the signed bytes so a receipt signature can
+	// never double as a transaction or another message signature
+	RECEIPT_DOMAIN = "raydium-swap-receipt:v1\n"
+)
+
+// SwapReceipt is a transaction report signed by the wallet that made the
+// swap. Report holds the exact bytes that were signed: the compact JSON of the
+// report, so a receipt still verifies after the report type gains fields.
+type SwapReceipt struct {
+	TxHash    string          `json:"tx_hash"`
+	Signer    string          `json:"signer"`
+	Signature string          `json:"signature"` // ed25519, base58
+	Report    json.RawMessage `json:"report"`
+}
+
+// receiptMessage is what a receipt signs: the domain and the compact report
+func receiptMessage(report []byte) ([]byte, error) {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, report); err != nil {
+		return nil, fmt.Errorf("invalid report JSON: %w", err)
+	}
+	return append([]byte(RECEIPT_DOMAIN), compact.Bytes()...), nil
+}
+
+// signReceipt signs a report with the wallet key
+func signReceipt(wallet solana.PrivateKey, report *TransactionReport) (*SwapReceipt, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode report: %w", err)
+	}
+	message, err := receiptMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := wallet.Sign(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign receipt: %w", err)
+	}
+	return &SwapReceipt{
+		TxHash:    report.TxHash,
+		Signer:    wallet.PublicKey().String(),
+		Signature: signature.String(),
+		Report:    data,
+	}, nil
+}
+
+// Verify checks the signature over the report and that the receipt's
+// transaction is the one the report describes
+func (r *SwapReceipt) Verify() error {
+	signer, err := solana.PublicKeyFromBase58(r.Signer)
+	if err != nil {
+		return fmt.Errorf("invalid signer: %w", err)
+	}
+	signature, err := solana.SignatureFromBase58(r.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	message, err := receiptMessage(r.Report)
+	if err != nil {
+		return err
+	}
+	if !signature.Verify(signer, message) {
+		return fmt.Errorf("signature does not match the report")
+	}
+	var report TransactionReport
+	if err := json.Unmarshal(r.Report, &report); err != nil {
+		return fmt.Errorf("invalid report: %w", err)
+	}
+	if report.TxHash != r.TxHash {
+		return fmt.Errorf("receipt is for %s but the report is for %s", r.TxHash, report.TxHash)
+	}
+	return nil
+}
+
+// storeReceipt appends a receipt to the receipts file
+func storeReceipt(receipt *SwapReceipt) error {
+	var receipts []*SwapReceipt
+	return updateStateFile(RECEIPTS_FILE, &receipts, func() {
+		receipts = append(receipts, receipt)
+	})
+}
+
+// readReceipts loads the stored receipts
+func readReceipts() ([]*SwapReceipt, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, RECEIPTS_FILE)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var receipts []*SwapReceipt
+	if err := json.Unmarshal(data, &receipts); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return receipts, nil
+}
+
+// readReceiptFile loads a file holding one receipt or a list of them
+func readReceiptFile(path string) ([]*SwapReceipt, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var receipts []*SwapReceipt
+		if err := json.Unmarshal(trimmed, &receipts); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return receipts, nil
+	}
+	var receipt SwapReceipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return []*SwapReceipt{&receipt}, nil
+}
+
+// recordSwapReceipt signs an executed swap's report and stores it; failures
+// only warn, since the swap itself already went through
+func recordSwapReceipt(wallet solana.PrivateKey, report *TransactionReport) {
+	receipt, err := signReceipt(wallet, report)
+	if err == nil {
+		err = storeReceipt(receipt)
+	}
+	if err != nil {
+		fmt.Printf(tr("Warning: Could not store signed receipt: %v\n"), err)
+		return
+	}
+	fmt.Print(tr("Signed receipt stored (verify with `receipts verify`)\n"))
+}
+
+// runReceipts dispatches the receipt subcommands
+func runReceipts(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "list":
+			runReceiptsList(args[1:])
+			return
+		case "show":
+			runReceiptsShow(args[1:])
+			return
+		case "verify":
+			runReceiptsVerify(args[1:])
+			return
+		}
+	}
+	fmt.Println("Usage: go run . receipts list")
+	fmt.Println("       go run . receipts show SIGNATURE")
+	fmt.Println("       go run . receipts verify [-file RECEIPT.json] [-signer WALLET]")
+	os.Exit(1)
+}
+
+// runReceiptsList prints the stored receipts
+func runReceiptsList(args []string) {
+	fs := newCommandFlagSet("receipts list")
+	fs.Parse(args)
+
+	receipts, err := readReceipts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(receipts) == 0 {
+		fmt.Println("No receipts stored")
+		return
+	}
+	fmt.Printf("%-20s %-5s %-44s %s\n", "Time", "Side", "Signer", "Transaction")
+	for _, receipt := range receipts {
+		var report TransactionReport
+		if err := json.Unmarshal(receipt.Report, &report); err != nil {
+			fmt.Printf("%-20s %-5s %-44s %s\n", "?", "?", receipt.Signer, receipt.TxHash)
+			continue
+		}
+		fmt.Printf("%-20s %-5s %-44s %s\n", report.Timestamp.UTC().Format("2006-01-02 15:04:05"), report.Side, receipt.Signer, receipt.TxHash)
+	}
+}
+
+// runReceiptsShow prints one stored receipt as JSON, to hand to a third party
+func runReceiptsShow(args []string) {
+	fs := newCommandFlagSet("receipts show")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal("Usage: go run . receipts show SIGNATURE")
+	}
+
+	receipts, err := readReceipts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, receipt := range receipts {
+		if receipt.TxHash == fs.Arg(0) {
+			data, err := json.MarshalIndent(receipt, "", "  ")
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(string(data))
+			return
+		}
+	}
+	log.Fatalf("No receipt for %s", fs.Arg(0))
+}
+
+// runReceiptsVerify checks the signatures of a receipt file or of the stored
+// receipts, and exits non-zero when any fails
+func runReceiptsVerify(args []string) {
+	fs := newCommandFlagSet("receipts verify")
+	file := fs.String("file", "", "Receipt file to verify, holding one receipt or a list (default: the stored receipts)")
+	signer := fs.String("signer", "", "Wallet the receipts must be signed by; without it any valid signature passes")
+	fs.Parse(args)
+
+	var receipts []*SwapReceipt
+	var err error
+	if *file != "" {
+		receipts, err = readReceiptFile(*file)
+	} else {
+		receipts, err = readReceipts()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(receipts) == 0 {
+		fmt.Println("No receipts to verify")
+		return
+	}
+
+	failed := 0
+	for _, receipt := range receipts {
+		err := receipt.Verify()
+		if err == nil && *signer != "" && receipt.Signer != *signer {
+			err = fmt.Errorf("signed by %s, not %s", receipt.Signer, *signer)
+		}
+		if err != nil {
+			failed++
+			fmt.Printf("FAILED %s: %v\n", receipt.TxHash, err)
+			continue
+		}
+		fmt.Printf("OK     %s (signed by %s)\n", receipt.TxHash, receipt.Signer)
+	}
+	fmt.Printf("\n%d of %d receipts verified\n", len(receipts)-failed, len(receipts))
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
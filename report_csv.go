@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Column layout follows the generic "universal" CSV format accepted by
+// common crypto tax importers (Koinly, CoinTracking, CoinLedger)
+var reportCSVHeader = []string{
+	"Date",
+	"Sent Amount",
+	"Sent Currency",
+	"Received Amount",
+	"Received Currency",
+	"Fee Amount",
+	"Fee Currency",
+	"Net Worth Amount",
+	"Net Worth Currency",
+	"Label",
+	"Description",
+	"TxHash",
+}
+
+// appendReportCSV appends a completed swap report to a CSV file,
+// writing the header first if the file is new or empty
+func appendReportCSV(path string, report *TransactionReport) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open report file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat report file: %w", err)
+	}
+
+	writer := csv.NewWriter(file)
+	if info.Size() == 0 {
+		if err := writer.Write(reportCSVHeader); err != nil {
+			return fmt.Errorf("failed to write report header: %w", err)
+		}
+	}
+
+	// Tax tools identify SPL tokens by mint, so use it instead of the generic label
+	sentCurrency := report.InputToken
+	receivedCurrency := report.OutputToken
+	if report.TokenMint != "" {
+		if sentCurrency == "TOKEN" {
+			sentCurrency = report.TokenMint
+		}
+		if receivedCurrency == "TOKEN" {
+			receivedCurrency = report.TokenMint
+		}
+	}
+
+	var netWorth, netWorthCurrency string
+	if report.ValueUSD > 0 {
+		netWorth = strconv.FormatFloat(report.ValueUSD, 'f', 2, 64)
+		netWorthCurrency = "USD"
+	}
+
+	costs := fmt.Sprintf("network fee %.9f SOL, priority fee %.9f SOL, net rent %+.9f SOL",
+		report.NetworkFee, report.PriorityFee, report.RentRecovered-report.RentSpent)
+	if report.PlatformFee > 0 {
+		costs += fmt.Sprintf(", platform fee %.9f %s", report.PlatformFee, report.platformFeeToken())
+	}
+	if report.Burned > 0 {
+		costs += fmt.Sprintf(", burned %.9f %s", report.Burned, receivedCurrency)
+	}
+	description := fmt.Sprintf("Raydium V4 %s via pool %s (%s)", report.Side, report.PoolAddress, costs)
+
+	record := []string{
+		report.Timestamp.UTC().Format(time.DateTime + " UTC"),
+		strconv.FormatFloat(report.AmountIn, 'f', -1, 64),
+		sentCurrency,
+		strconv.FormatFloat(report.AmountOut, 'f', -1, 64),
+		receivedCurrency,
+		strconv.FormatFloat(report.NetworkFee+report.PriorityFee, 'f', 9, 64),
+		"SOL",
+		netWorth,
+		netWorthCurrency,
+		"swap",
+		description,
+		report.TxHash,
+	}
+
+	if err := writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write report record: %w", err)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+const (
+	// LEDGER_FILE in the state directory holds every guarded trade, the source of open positions
+	LEDGER_FILE = "ledger.json"
+	// Signature pages read to date a mint before it counts as young regardless
+	MINT_AGE_MAX_PAGES = 10
+)
+
+// GuardedTrade is a trade checked against the limits and recorded in the ledger
+type GuardedTrade struct {
+	Mint    solana.PublicKey
+	Side    string
+	SOL     float64 // SOL leg
+	Tokens  float64 // token leg
+	Bracket int     // bracket order the trade opened or exited, 0 for none
+}
+
+// newGuardedTrade describes a swap of amount in for an expected out. Tokens
+// a buyback burns never join the position, though their SOL is its cost.
+func newGuardedTrade(mint solana.PublicKey, side string, amount float64, out float64, burn BuybackBurnConfig) GuardedTrade {
+	if side == "buy" {
+		return GuardedTrade{Mint: mint, Side: side, SOL: amount, Tokens: burn.kept(side, out)}
+	}
+	return GuardedTrade{Mint: mint, Side: side, SOL: out, Tokens: amount}
+}
+
+// GuardedTrade describes the quoted swap for the spend guard
+func (q *SwapQuote) GuardedTrade(burn BuybackBurnConfig) GuardedTrade {
+	return newGuardedTrade(q.TokenMint, q.Side, q.AmountIn, q.ExpectedOut, burn)
+}
+
+// LedgerEntry is a recorded trade, at its quoted amounts
+type LedgerEntry struct {
+	At      time.Time `json:"at"`
+	Mint    string    `json:"mint"`
+	Side    string    `json:"side"`
+	SOL     float64   `json:"sol"`
+	Tokens  float64   `json:"tokens"`
+	Bracket int       `json:"bracket,omitempty"`
+	// Signature is set on entries the indexer read from the chain
+	Signature string `json:"signature,omitempty"`
+}
+
+// Position is a token held through recorded trades, at average cost
+type Position struct {
+	Mint      string  `json:"mint"`
+	Tokens    float64 `json:"tokens"`
+	CostSOL   float64 `json:"cost_sol"`
+	LastPrice float64 `json:"last_price"` // SOL per token of the latest trade
+}
+
+// ValueSOL marks the position at its latest trade price
+func (p *Position) ValueSOL() float64 {
+	return p.Tokens * p.LastPrice
+}
+
+// riskEnabled reports whether any portfolio rule is set
+func (l SpendLimits) riskEnabled() bool {
+	return l.MaxTokenPct > 0 || l.MaxYoungPct > 0 || l.MaxDailyLossSOL > 0
+}
+
+// readLedger loads the recorded trades of every wallet
+func readLedger() (map[string][]LedgerEntry, error) {
+	ledger := make(map[string][]LedgerEntry)
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, LEDGER_FILE)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &ledger); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return ledger, nil
+}
+
+// appendLedger records a trade of a wallet. A trade the indexer already read
+// from the chain keeps the indexed amounts and only takes the bracket tag.
+func appendLedger(wallet string, trade GuardedTrade) error {
+	ledger := make(map[string][]LedgerEntry)
+	return updateStateFile(LEDGER_FILE, &ledger, func() {
+		entry := LedgerEntry{
+			At:      time.Now().UTC(),
+			Mint:    trade.Mint.String(),
+			Side:    trade.Side,
+			SOL:     trade.SOL,
+			Tokens:  trade.Tokens,
+			Bracket: trade.Bracket,
+		}
+		entries := ledger[wallet]
+		for i := len(entries) - 1; i >= 0; i-- {
+			if indexedMatch(entries[i], entry) {
+				entries[i].Bracket = trade.Bracket
+				return
+			}
+		}
+		ledger[wallet] = append(entries, entry)
+	})
+}
+
+// replayLedger rebuilds the open positions from a wallet's trades and sums the
+// PnL realized by sells since dayStart. Tokens sold beyond a recorded position
+// were acquired elsewhere; their cost is unknown, so they realize nothing.
+// Tokens sent away or deposited as liquidity leave at average cost without
+// realizing; tokens received or withdrawn are not tracked.
+func replayLedger(entries []LedgerEntry, dayStart time.Time) (map[string]*Position, float64) {
+	positions := make(map[string]*Position)
+	var realizedToday float64
+	for _, entry := range entries {
+		if entry.Tokens <= 0 {
+			continue
+		}
+		position := positions[entry.Mint]
+		if position == nil {
+			position = &Position{Mint: entry.Mint}
+			positions[entry.Mint] = position
+		}
+
+		switch entry.Side {
+		case "buy":
+			position.LastPrice = entry.SOL / entry.Tokens
+			position.Tokens += entry.Tokens
+			position.CostSOL += entry.SOL
+			continue
+		case LEDGER_TRANSFER_IN, LEDGER_LP_REMOVE:
+			if position.Tokens <= 0 {
+				delete(positions, entry.Mint)
+			}
+			continue
+		}
+		realized := entry.Side != LEDGER_TRANSFER_OUT && entry.Side != LEDGER_LP_ADD
+		if realized {
+			position.LastPrice = entry.SOL / entry.Tokens
+		}
+		tracked := min(entry.Tokens, position.Tokens)
+		if tracked > 0 {
+			basis := position.CostSOL * tracked / position.Tokens
+			if realized && !entry.At.Before(dayStart) {
+				realizedToday += entry.SOL*tracked/entry.Tokens - basis
+			}
+			position.CostSOL -= basis
+			position.Tokens -= tracked
+		}
+		if position.Tokens <= 0 {
+			delete(positions, entry.Mint)
+		}
+	}
+	return positions, realizedToday
+}
+
+// startOfDay is midnight UTC of the day containing t
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// checkRisk applies the portfolio rules to a trade: the daily loss circuit
+// breaker refuses every trade, the exposure limits only buys
+func (g *SpendGuard) checkRisk(ctx context.Context, trade GuardedTrade, reserved []reservation) error {
+	l := g.limits
+	ledger, err := readLedger()
+	if err != nil {
+		return err
+	}
+	// Reserved trades count at their quoted amounts, as if already recorded
+	entries := ledger[g.wallet]
+	var reservedSOL float64
+	for _, r := range reserved {
+		entries = append(entries, r.LedgerEntry)
+		if r.Side == "buy" {
+			reservedSOL -= r.SOL
+		} else {
+			reservedSOL += r.SOL
+		}
+	}
+	now := time.Now()
+	positions, realizedToday := replayLedger(entries, startOfDay(now))
+
+	if l.MaxDailyLossSOL > 0 && -realizedToday >= l.MaxDailyLossSOL {
+		return fmt.Errorf("circuit breaker: %.4f SOL realized loss today reaches the daily limit of %.4f SOL; trading resumes at %s",
+			-realizedToday, l.MaxDailyLossSOL, startOfDay(now).Add(24*time.Hour).Format(time.RFC3339))
+	}
+	if trade.Side != "buy" || (l.MaxTokenPct <= 0 && l.MaxYoungPct <= 0) || trade.Tokens <= 0 {
+		return nil
+	}
+
+	// The portfolio after the trade: SOL balance net of reserved trades plus
+	// recorded and reserved positions, the traded token at the trade's price
+	balance, err := g.client.GetBalance(ctx, g.owner, rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to get SOL balance: %w", err)
+	}
+	mint := trade.Mint.String()
+	position := positions[mint]
+	if position == nil {
+		position = &Position{Mint: mint}
+		positions[mint] = position
+	}
+	position.Tokens += trade.Tokens
+	position.LastPrice = trade.SOL / trade.Tokens
+
+	total := atoms.ToAmount(balance.Value, SOL_DECIMALS) + reservedSOL - trade.SOL
+	for _, p := range positions {
+		total += p.ValueSOL()
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	if share := position.ValueSOL() / total * 100; l.MaxTokenPct > 0 && share > l.MaxTokenPct {
+		return fmt.Errorf("%s would be %.1f%% of the portfolio, above the %.1f%% limit per token", mint, share, l.MaxTokenPct)
+	}
+	if l.MaxYoungPct > 0 {
+		var young float64
+		for _, p := range positions {
+			isYoung, err := g.isYoungMint(ctx, p.Mint)
+			if err != nil {
+				return err
+			}
+			if isYoung {
+				young += p.ValueSOL()
+			}
+		}
+		if share := young / total * 100; share > l.MaxYoungPct {
+			return fmt.Errorf("tokens younger than %d days would be %.1f%% of the portfolio, above the %.1f%% limit", l.YoungTokenDays, share, l.MaxYoungPct)
+		}
+	}
+	return nil
+}
+
+// isYoungMint reports whether a mint's first transaction is within the young
+// token age. Mints busier than MINT_AGE_MAX_PAGES pages in that time count as young.
+func (g *SpendGuard) isYoungMint(ctx context.Context, mint string) (bool, error) {
+	g.mu.Lock()
+	young, ok := g.youngMints[mint]
+	g.mu.Unlock()
+	if ok {
+		return young, nil
+	}
+
+	key, err := solana.PublicKeyFromBase58(mint)
+	if err != nil {
+		return false, fmt.Errorf("invalid mint %q in the ledger: %w", mint, err)
+	}
+	cutoff := time.Now().Add(-time.Duration(g.limits.YoungTokenDays) * 24 * time.Hour)
+	limit := HISTORY_SIGNATURE_PAGE
+	opts := &rpc.GetSignaturesForAddressOpts{Limit: &limit, Commitment: rpc.CommitmentConfirmed}
+	young = true
+	for range MINT_AGE_MAX_PAGES {
+		page, err := g.client.GetSignaturesForAddressWithOpts(ctx, key, opts)
+		if err != nil {
+			return false, fmt.Errorf("failed to date mint %s: %w", mint, err)
+		}
+		if len(page) > 0 {
+			if last := page[len(page)-1].BlockTime; last != nil && last.Time().Before(cutoff) {
+				young = false
+				break
+			}
+		}
+		if len(page) < limit {
+			break
+		}
+		opts.Before = page[len(page)-1].Signature
+	}
+
+	g.mu.Lock()
+	g.youngMints[mint] = young
+	g.mu.Unlock()
+	return young, nil
+}
+
+// printPositions lists a wallet's recorded positions and today's realized PnL
+func printPositions(wallet string, limits SpendLimits) error {
+	ledger, err := readLedger()
+	if err != nil {
+		return err
+	}
+	positions, realizedToday := replayLedger(ledger[wallet], startOfDay(time.Now()))
+
+	mints := make([]string, 0, len(positions))
+	for mint := range positions {
+		mints = append(mints, mint)
+	}
+	sort.Slice(mints, func(i, j int) bool { return positions[mints[i]].ValueSOL() > positions[mints[j]].ValueSOL() })
+
+	fmt.Printf("\n=== POSITIONS ===\n")
+	fmt.Printf("Wallet: %s\n\n", wallet)
+	if len(mints) == 0 {
+		fmt.Println("No open positions in the ledger")
+	} else {
+		fmt.Printf("%-44s %20s %14s %16s %14s\n", "Mint", "Tokens", "Cost (SOL)", "Last Price", "Value (SOL)")
+		for _, mint := range mints {
+			p := positions[mint]
+			fmt.Printf("%-44s %20.6f %14.9f %16.9f %14.9f\n", mint, p.Tokens, p.CostSOL, p.LastPrice, p.ValueSOL())
+		}
+	}
+	fmt.Printf("\nRealized PnL today: %+.9f SOL\n", realizedToday)
+	if limits.MaxDailyLossSOL > 0 {
+		status := "armed"
+		if -realizedToday >= limits.MaxDailyLossSOL {
+			status = "TRIPPED, trading resumes at " + startOfDay(time.Now()).Add(24*time.Hour).Format(time.RFC3339)
+		}
+		fmt.Printf("Circuit breaker: %s (limit %.4f SOL)\n", status, limits.MaxDailyLossSOL)
+	}
+	fmt.Printf("=================\n")
+	return nil
+}
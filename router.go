@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// BookLevel is an aggregated order book price level in atoms
+type BookLevel struct {
+	Price float64 // quote atoms per base atom
+	Size  float64 // base atoms
+}
+
+// VenueQuote is a quote from an order book venue that can fill the swap
+// instead of the AMM pool
+type VenueQuote struct {
+	Venue          string
+	Market         solana.PublicKey
+	ExpectedOut    float64 // UI units of the output token
+	ExpectedOutRaw uint64  // raw atoms of the output token
+	OutDecimals    int
+
+	// buildInstructions returns the instructions filling the swap for owner
+	buildInstructions func(ctx context.Context, client ChainClient, owner solana.PublicKey, minAmountOut uint64) ([]solana.Instruction, error)
+}
+
+// aggregateLevels merges orders at the same price and sorts best first
+func aggregateLevels(orders []BookLevel, descending bool) []BookLevel {
+	byPrice := make(map[float64]float64)
+	for _, order := range orders {
+		byPrice[order.Price] += order.Size
+	}
+
+	levels := make([]BookLevel, 0, len(byPrice))
+	for price, size := range byPrice {
+		levels = append(levels, BookLevel{Price: price, Size: size})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if descending {
+			return levels[i].Price > levels[j].Price
+		}
+		return levels[i].Price < levels[j].Price
+	})
+	return levels
+}
+
+// walkBook fills amountIn atoms against levels sorted best first and returns
+// the output atoms. Selling base consumes bids; spending quote consumes asks.
+// The taker fee is charged on the quote side, as order book programs do.
+func walkBook(levels []BookLevel, amountIn float64, inIsBase bool, takerFeeBps uint64) float64 {
+	fee := float64(takerFeeBps) / 10000
+	remaining := amountIn
+	if !inIsBase {
+		remaining = amountIn / (1 + fee)
+	}
+
+	var out float64
+	for _, level := range levels {
+		if remaining <= 0 {
+			break
+		}
+		if inIsBase {
+			fill := math.Min(remaining, level.Size)
+			out += fill * level.Price
+			remaining -= fill
+		} else {
+			cost := level.Size * level.Price
+			if remaining >= cost {
+				out += level.Size
+				remaining -= cost
+			} else {
+				out += remaining / level.Price
+				remaining = 0
+			}
+		}
+	}
+
+	// Not enough depth to fill the whole size
+	if remaining > 0 {
+		return 0
+	}
+
+	if inIsBase {
+		out *= 1 - fee
+	}
+	return out
+}
+
+// findVenueQuotes quotes the swap on every order book venue listing the token
+// against SOL. Venues that fail or cannot fill the size are skipped.
+func findVenueQuotes(ctx context.Context, client ChainClient, token solana.PublicKey, side string, amount float64) []*VenueQuote {
+	// The venues are looked up concurrently; each lookup scans its program
+	var phoenixQuote, openbookQuote *VenueQuote
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		phoenix, err := findPhoenixMarket(ctx, client, token)
+		if err != nil {
+			fmt.Printf("Warning: Phoenix lookup failed: %v\n", err)
+		} else if phoenix != nil {
+			phoenixQuote = phoenixVenueQuote(phoenix, side, amount)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		openbook, err := findOpenbookV2Market(ctx, client, token)
+		if err != nil {
+			fmt.Printf("Warning: OpenBook v2 lookup failed: %v\n", err)
+		} else if openbook != nil {
+			openbookQuote = openbookV2VenueQuote(openbook, side, amount)
+		}
+	}()
+	wg.Wait()
+
+	var quotes []*VenueQuote
+	for _, quote := range []*VenueQuote{phoenixQuote, openbookQuote} {
+		if quote != nil {
+			quotes = append(quotes, quote)
+		}
+	}
+	return quotes
+}
+
+// bestVenueQuote returns the venue beating the AMM output, or nil if the AMM fills better
+func bestVenueQuote(quotes []*VenueQuote, ammOut float64) *VenueQuote {
+	var best *VenueQuote
+	for _, quote := range quotes {
+		if quote.ExpectedOut > ammOut && (best == nil || quote.ExpectedOut > best.ExpectedOut) {
+			best = quote
+		}
+	}
+	return best
+}
+
+// executeVenueSwap builds, signs and sends (or simulates) a venue fill
+func executeVenueSwap(
+	ctx context.Context,
+	client ChainClient,
+	wallet solana.PrivateKey,
+	quote *VenueQuote,
+	slippage float64,
+	dryRun bool,
+	opts BuildOptions,
+) (solana.Signature, error) {
+	minAmountOut := calculateMinAmountOut(quote.ExpectedOutRaw, slippage)
+
+	instructions, err := quote.buildInstructions(ctx, client, wallet.PublicKey(), minAmountOut)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+
+	latestBlockhash, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+
+	tx, err := solana.NewTransaction(instructions, latestBlockhash.Value.Blockhash, solana.TransactionPayer(wallet.PublicKey()))
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if err := signTransaction(tx, wallet); err != nil {
+		return solana.Signature{}, err
+	}
+
+	if dryRun {
+		return solana.Signature{}, simulateTransaction(ctx, client, tx)
+	}
+
+	return sendAndConfirmTransaction(ctx, client, tx, opts)
+}
+
+// swapTokenAccounts prepares the owner's SOL (wrapped) and token accounts for a
+// venue fill: SOL spent is wrapped up front and the WSOL account is closed after
+// the fill to unwrap any SOL received. wrap returns the instructions around fill.
+func swapTokenAccounts(
+	ctx context.Context,
+	client ChainClient,
+	owner solana.PublicKey,
+	token solana.PublicKey,
+	solIn uint64,
+) (wsolATA solana.PublicKey, tokenATA solana.PublicKey, wrap func(fill ...solana.Instruction) []solana.Instruction, err error) {
+	var setup []solana.Instruction
+
+	wsolATA, createIx, err := getOrCreateATA(ctx, client, owner, WSOL_MINT)
+	if err != nil {
+		return wsolATA, tokenATA, nil, err
+	}
+	if createIx != nil {
+		setup = append(setup, createIx)
+	}
+
+	tokenATA, createIx, err = getOrCreateATA(ctx, client, owner, token)
+	if err != nil {
+		return wsolATA, tokenATA, nil, err
+	}
+	if createIx != nil {
+		fmt.Printf("Creating ATA for token %s\n", token)
+		setup = append(setup, createIx)
+	}
+
+	if solIn > 0 {
+		setup = append(setup, wrapSOLInstructions(owner, wsolATA, solIn)...)
+	}
+
+	wrap = func(fill ...solana.Instruction) []solana.Instruction {
+		instructions := append(setup, fill...)
+		return append(instructions, closeWSOLInstruction(owner, wsolATA))
+	}
+	return wsolATA, tokenATA, wrap, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// RPC_BUDGET_ENV_VAR caps the estimated RPC credits of a run; -rpc-budget overrides it
+const RPC_BUDGET_ENV_VAR = "RPC_BUDGET"
+
+// Estimated credits per call. Providers bill heavy methods at a multiple of a
+// standard call, and a getProgramAccounts scan without a memcmp filter
+// returns every account of the program.
+const (
+	RPC_CREDITS_CALL          = 1
+	RPC_CREDITS_FILTERED_SCAN = 10  // getProgramAccounts with a memcmp filter
+	RPC_CREDITS_SCAN          = 100 // getProgramAccounts over the whole program
+)
+
+// ErrRPCBudget is returned for calls the run's RPC budget cannot pay for
+var ErrRPCBudget = errors.New("RPC budget exceeded")
+
+// RPCMethodCost is what one method cost the run
+type RPCMethodCost struct {
+	Method  string
+	Calls   int64
+	Credits int64
+}
+
+// RPCCosts counts calls and estimated credits by method across the run and
+// enforces the budget
+type RPCCosts struct {
+	mu      sync.Mutex
+	methods map[string]*RPCMethodCost
+	spent   int64
+	refused int64
+}
+
+var rpcCosts RPCCosts
+
+// rpcCallInfo reads the JSON-RPC method of a request without consuming its
+// body, and whether a getProgramAccounts call filters by memcmp
+func rpcCallInfo(req *http.Request) (string, bool) {
+	if req.GetBody == nil {
+		return "", false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return "", false
+	}
+	defer body.Close()
+	var call struct {
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	json.NewDecoder(body).Decode(&call)
+	if call.Method != "getProgramAccounts" || len(call.Params) < 2 {
+		return call.Method, false
+	}
+	var opts struct {
+		Filters []struct {
+			Memcmp *json.RawMessage `json:"memcmp"`
+		} `json:"filters"`
+	}
+	json.Unmarshal(call.Params[1], &opts)
+	for _, filter := range opts.Filters {
+		if filter.Memcmp != nil {
+			return call.Method, true
+		}
+	}
+	return call.Method, false
+}
+
+// rpcCallCredits estimates the credits of one call
+func rpcCallCredits(method string, filtered bool) int64 {
+	switch {
+	case method == "getProgramAccounts" && filtered:
+		return RPC_CREDITS_FILTERED_SCAN
+	case method == "getProgramAccounts":
+		return RPC_CREDITS_SCAN
+	}
+	return RPC_CREDITS_CALL
+}
+
+// charge books a call, refusing it when it would take the run past budget
+func (c *RPCCosts) charge(method string, credits int64, budget int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if budget > 0 && c.spent+credits > budget {
+		c.refused++
+		return fmt.Errorf("%w: %s needs ~%d credits and %d of %d are left", ErrRPCBudget, method, credits, budget-c.spent, budget)
+	}
+	if c.methods == nil {
+		c.methods = make(map[string]*RPCMethodCost)
+	}
+	if method == "" {
+		method = "unknown"
+	}
+	cost := c.methods[method]
+	if cost == nil {
+		cost = &RPCMethodCost{Method: method}
+		c.methods[method] = cost
+	}
+	cost.Calls++
+	cost.Credits += credits
+	c.spent += credits
+	return nil
+}
+
+// Allows reports whether the budget has the credits left; without a budget
+// everything is allowed
+func (c *RPCCosts) Allows(credits int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return rpcPolicy.Budget <= 0 || c.spent+credits <= rpcPolicy.Budget
+}
+
+// Remaining returns the credits left in the budget
+func (c *RPCCosts) Remaining() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return rpcPolicy.Budget - c.spent
+}
+
+// printRPCCosts shows the calls and credits by method, most expensive first
+func printRPCCosts() {
+	rpcCosts.mu.Lock()
+	defer rpcCosts.mu.Unlock()
+	costs := make([]*RPCMethodCost, 0, len(rpcCosts.methods))
+	for _, cost := range rpcCosts.methods {
+		costs = append(costs, cost)
+	}
+	sort.Slice(costs, func(i, j int) bool {
+		if costs[i].Credits != costs[j].Credits {
+			return costs[i].Credits > costs[j].Credits
+		}
+		return costs[i].Method < costs[j].Method
+	})
+
+	fmt.Printf("\n=== RPC COSTS ===\n")
+	for _, cost := range costs {
+		fmt.Printf("%-34s %6d calls %8d credits\n", cost.Method, cost.Calls, cost.Credits)
+	}
+	fmt.Printf("Total: ~%d credits", rpcCosts.spent)
+	if rpcPolicy.Budget > 0 {
+		fmt.Printf(" of a %d budget", rpcPolicy.Budget)
+	}
+	fmt.Println()
+	if rpcCosts.refused > 0 {
+		fmt.Printf("Refused Over Budget: %d\n", rpcCosts.refused)
+	}
+	fmt.Printf("=================\n")
+}
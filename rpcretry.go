@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Environment variables tuning the RPC retry policy; the main flags override them
+const (
+	RPC_MAX_RETRIES_ENV_VAR  = "RPC_MAX_RETRIES"
+	RPC_RATE_LIMIT_ENV_VAR   = "RPC_RATE_LIMIT"
+	RPC_VERBOSE_ENV_VAR      = "RPC_VERBOSE"
+	RPC_TIMEOUT_ENV_VAR      = "RPC_TIMEOUT"
+	RPC_SCAN_TIMEOUT_ENV_VAR = "RPC_SCAN_TIMEOUT"
+)
+
+const (
+	DEFAULT_RPC_MAX_RETRIES  = 5
+	RPC_BASE_BACKOFF         = 250 * time.Millisecond
+	RPC_MAX_BACKOFF          = 30 * time.Second
+	DEFAULT_RPC_TIMEOUT      = 30 * time.Second
+	DEFAULT_RPC_SCAN_TIMEOUT = 2 * time.Minute // getProgramAccounts scans the whole program
+)
+
+// RetryPolicy controls how RPC requests are retried and paced
+type RetryPolicy struct {
+	MaxRetries int
+	RateLimit  float64 // requests per second, 0 for unlimited
+	Verbose    bool    // print retry statistics when the run ends
+	// Each attempt, including reading the response, gives up after its
+	// timeout and is retried like a network error
+	Timeout     time.Duration
+	ScanTimeout time.Duration // for getProgramAccounts
+	Budget      int64         // estimated credits the run may spend, 0 for unlimited
+}
+
+// rpcPolicy is the policy used by newChainClient
+var rpcPolicy = RetryPolicy{MaxRetries: DEFAULT_RPC_MAX_RETRIES, Timeout: DEFAULT_RPC_TIMEOUT, ScanTimeout: DEFAULT_RPC_SCAN_TIMEOUT}
+
+// loadRetryPolicy reads the retry policy from the environment
+func loadRetryPolicy() error {
+	if value := os.Getenv(RPC_MAX_RETRIES_ENV_VAR); value != "" {
+		retries, err := strconv.Atoi(value)
+		if err != nil || retries < 0 {
+			return fmt.Errorf("invalid %s %q", RPC_MAX_RETRIES_ENV_VAR, value)
+		}
+		rpcPolicy.MaxRetries = retries
+	}
+	if value := os.Getenv(RPC_RATE_LIMIT_ENV_VAR); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 {
+			return fmt.Errorf("invalid %s %q", RPC_RATE_LIMIT_ENV_VAR, value)
+		}
+		rpcPolicy.RateLimit = rate
+	}
+	for name, timeout := range map[string]*time.Duration{RPC_TIMEOUT_ENV_VAR: &rpcPolicy.Timeout, RPC_SCAN_TIMEOUT_ENV_VAR: &rpcPolicy.ScanTimeout} {
+		if value := os.Getenv(name); value != "" {
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration <= 0 {
+				return fmt.Errorf("invalid %s %q", name, value)
+			}
+			*timeout = duration
+		}
+	}
+	if value := os.Getenv(RPC_BUDGET_ENV_VAR); value != "" {
+		budget, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || budget < 0 {
+			return fmt.Errorf("invalid %s %q", RPC_BUDGET_ENV_VAR, value)
+		}
+		rpcPolicy.Budget = budget
+	}
+	rpcPolicy.Verbose, _ = strconv.ParseBool(os.Getenv(RPC_VERBOSE_ENV_VAR))
+	return nil
+}
+
+// RPCStats counts requests and retries across every client in the run
+type RPCStats struct {
+	Requests    atomic.Int64
+	Retries     atomic.Int64
+	RateLimited atomic.Int64 // 429 responses
+	TimedOut    atomic.Int64 // attempts that hit the policy timeout
+	Failures    atomic.Int64 // requests that gave up
+	waitNanos   atomic.Int64 // time spent in backoff and the rate limiter
+}
+
+var rpcStats RPCStats
+
+// printRPCStats shows the aggregate retry statistics when the policy is
+// verbose, and the costs by method when it is verbose or has a budget
+func printRPCStats() {
+	if rpcPolicy.Verbose || rpcPolicy.Budget > 0 {
+		printRPCCosts()
+	}
+	if !rpcPolicy.Verbose {
+		return
+	}
+	fmt.Printf("\n=== RPC STATS ===\n")
+	fmt.Printf("Requests: %d\n", rpcStats.Requests.Load())
+	fmt.Printf("Retries: %d\n", rpcStats.Retries.Load())
+	fmt.Printf("Rate Limited (429): %d\n", rpcStats.RateLimited.Load())
+	fmt.Printf("Timed Out: %d\n", rpcStats.TimedOut.Load())
+	fmt.Printf("Failed After Retries: %d\n", rpcStats.Failures.Load())
+	fmt.Printf("Time Waiting: %s\n", time.Duration(rpcStats.waitNanos.Load()).Round(time.Millisecond))
+	fmt.Printf("=================\n")
+}
+
+// rateLimiter spaces requests evenly at a fixed rate
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until the next request slot
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleepContext(ctx, slot.Sub(now))
+}
+
+// retryHTTPClient is the HTTP transport of the RPC client. It paces requests
+// through the rate limiter and retries network errors, 429 and 5xx responses.
+// Resending a signed transaction is safe: it keeps its signature, so the
+// cluster processes it at most once.
+type retryHTTPClient struct {
+	client     *http.Client
+	scanClient *http.Client // longer timeout for getProgramAccounts
+	policy     RetryPolicy
+	limiter    *rateLimiter
+}
+
+func newRetryHTTPClient(policy RetryPolicy) *retryHTTPClient {
+	return &retryHTTPClient{
+		client:     &http.Client{Timeout: policy.Timeout},
+		scanClient: &http.Client{Timeout: policy.ScanTimeout},
+		policy:     policy,
+		limiter:    newRateLimiter(policy.RateLimit),
+	}
+}
+
+func (c *retryHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	backoff := RPC_BASE_BACKOFF
+	client := c.client
+	method, filtered := rpcCallInfo(req)
+	if method == "getProgramAccounts" {
+		client = c.scanClient
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			// The previous attempt consumed the body
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		// Providers bill every attempt, retries included
+		if err := rpcCosts.charge(method, rpcCallCredits(method, filtered), c.policy.Budget); err != nil {
+			return nil, err
+		}
+		rpcStats.Requests.Add(1)
+		resp, err := client.Do(req)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil {
+			rpcStats.TimedOut.Add(1)
+		}
+
+		retry, delay := c.shouldRetry(ctx, resp, err)
+		if !retry {
+			return resp, err
+		}
+		if attempt >= c.policy.MaxRetries {
+			rpcStats.Failures.Add(1)
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		// Jitter spreads out clients that failed together
+		if delay == 0 {
+			delay = time.Duration(rand.Int63n(int64(backoff))) + backoff/2
+			backoff = min(backoff*2, RPC_MAX_BACKOFF)
+		}
+		rpcStats.Retries.Add(1)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *retryHTTPClient) CloseIdleConnections() {
+	c.client.CloseIdleConnections()
+	c.scanClient.CloseIdleConnections()
+}
+
+// shouldRetry reports whether a response is retryable and how long the server
+// asked to wait through Retry-After, if at all
+func (c *retryHTTPClient) shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, time.Duration) {
+	if err != nil {
+		return ctx.Err() == nil && !errors.Is(err, context.Canceled), 0
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		rpcStats.RateLimited.Add(1)
+		return true, parseRetryAfter(resp.Header.Get("Retry-After"))
+	case resp.StatusCode == http.StatusBadGateway,
+		resp.StatusCode == http.StatusServiceUnavailable,
+		resp.StatusCode == http.StatusGatewayTimeout:
+		return true, parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+	return false, 0
+}
+
+// parseRetryAfter reads a Retry-After value in seconds or as an HTTP date,
+// capped at RPC_MAX_BACKOFF. It returns 0 when the header is absent or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = time.Until(date)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return min(delay, RPC_MAX_BACKOFF)
+}
+
+// sleepContext waits for d, returning early with the context's error
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	start := time.Now()
+	defer func() { rpcStats.waitNanos.Add(int64(time.Since(start))) }()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
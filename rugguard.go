@@ -0,0 +1,450 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"awesomeProject/internal/atoms"
+)
+
+const (
+	// RUG_GUARD_FILE in the state directory holds the per-token rug guard settings
+	RUG_GUARD_FILE = "rugguard.json"
+	// How far back an LP withdrawal is measured from the pool's peak
+	RUG_GUARD_WINDOW = time.Hour
+)
+
+// Rug events
+const (
+	RUG_EVENT_LP_WITHDRAWAL    = "lp_withdrawal"
+	RUG_EVENT_MINT_AUTHORITY   = "mint_authority_changed"
+	RUG_EVENT_FREEZE_AUTHORITY = "freeze_authority_changed"
+	RUG_EVENT_FROZEN           = "account_frozen"
+)
+
+// RugGuardRule decides what happens when a held token shows signs of a rug
+type RugGuardRule struct {
+	AutoSell     bool    `json:"auto_sell"`     // sell on an LP withdrawal or authority change
+	LPDropPct    float64 `json:"lp_drop_pct"`   // liquidity withdrawn from the pool's peak that counts as a rug
+	SellPct      float64 `json:"sell_pct"`      // of the balance sold
+	Slippage     float64 `json:"slippage"`      // percent
+	ComputePrice uint64  `json:"compute_price"` // micro-lamports per compute unit
+}
+
+// rugGuardConfig is the settings file: defaults plus per-mint replacements
+type rugGuardConfig struct {
+	Default RugGuardRule            `json:"default"`
+	Tokens  map[string]RugGuardRule `json:"tokens,omitempty"`
+}
+
+// DEFAULT_RUG_GUARD_RULE alerts on a 30% withdrawal without selling; an exit
+// pays a high priority fee and slippage to land ahead of the crowd
+var DEFAULT_RUG_GUARD_RULE = RugGuardRule{LPDropPct: 30, SellPct: 100, Slippage: 25, ComputePrice: 1_000_000}
+
+// RugEvent is printed and POSTed to the monitor's webhook
+type RugEvent struct {
+	Event   string    `json:"event"` // rug.detected
+	Kind    string    `json:"kind"`
+	Mint    string    `json:"mint"`
+	Pool    string    `json:"pool_address,omitempty"`
+	Detail  string    `json:"detail"`
+	Exit    string    `json:"exit,omitempty"` // transaction of the emergency sell
+	ExitErr string    `json:"exit_error,omitempty"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// loadRugGuardConfig reads the settings file, returning the defaults when it does not exist
+func loadRugGuardConfig() (*rugGuardConfig, string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, "", err
+	}
+	path := filepath.Join(dir, RUG_GUARD_FILE)
+
+	config := &rugGuardConfig{Default: DEFAULT_RUG_GUARD_RULE, Tokens: make(map[string]RugGuardRule)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, path, nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if config.Tokens == nil {
+		config.Tokens = make(map[string]RugGuardRule)
+	}
+	return config, path, nil
+}
+
+// rule returns the settings of a mint
+func (c *rugGuardConfig) rule(mint solana.PublicKey) RugGuardRule {
+	if rule, ok := c.Tokens[mint.String()]; ok {
+		return rule
+	}
+	return c.Default
+}
+
+// runRugGuard shows or updates the settings, or runs the monitor
+func runRugGuard(args []string, opts BuildOptions) {
+	if len(args) > 0 && args[0] == "set" {
+		runRugGuardSet(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "monitor" {
+		runRugGuardMonitor(args[1:], opts)
+		return
+	}
+	config, path, err := loadRugGuardConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	data, _ := json.MarshalIndent(config, "", "  ")
+	fmt.Printf("Rug guard (%s):\n%s\n", path, data)
+}
+
+// runRugGuardSet updates the default or a token's settings
+func runRugGuardSet(args []string) {
+	fs := newCommandFlagSet("rug-guard set")
+	mintAddr := fs.String("mint", "", "Token the settings apply to (default: all tokens without their own)")
+	autoSell := fs.String("auto-sell", "", "Sell on an LP withdrawal or authority change: true or false")
+	lpDrop := fs.Float64("lp-drop", -1, "Percent of the pool's liquidity withdrawn within an hour that counts as a rug")
+	sellPct := fs.Float64("sell-pct", -1, "Percent of the balance an emergency exit sells")
+	slippage := fs.Float64("slippage", -1, "Slippage tolerance in percent of an emergency exit")
+	computePrice := fs.Int64("compute-price", -1, "Compute unit price in micro-lamports of an emergency exit")
+	fs.Parse(args)
+
+	// Held until the settings are written, so concurrent edits are not lost
+	unlock, err := lockStateFile(RUG_GUARD_FILE)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer unlock()
+	config, path, err := loadRugGuardConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	rule := config.Default
+	if *mintAddr != "" {
+		if _, err := solana.PublicKeyFromBase58(*mintAddr); err != nil {
+			log.Fatalf("Invalid mint address: %v", err)
+		}
+		if existing, ok := config.Tokens[*mintAddr]; ok {
+			rule = existing
+		}
+	}
+
+	// Only flags that were given change the stored settings
+	switch *autoSell {
+	case "":
+	case "true":
+		rule.AutoSell = true
+	case "false":
+		rule.AutoSell = false
+	default:
+		log.Fatal("-auto-sell must be true or false")
+	}
+	if *lpDrop >= 0 {
+		rule.LPDropPct = *lpDrop
+	}
+	if *sellPct >= 0 {
+		rule.SellPct = *sellPct
+	}
+	if *slippage >= 0 {
+		rule.Slippage = *slippage
+	}
+	if *computePrice >= 0 {
+		rule.ComputePrice = uint64(*computePrice)
+	}
+	if rule.LPDropPct <= 0 || rule.LPDropPct > 100 || rule.SellPct <= 0 || rule.SellPct > 100 || rule.Slippage <= 0 || rule.Slippage > MAX_SLIPPAGE {
+		log.Fatal("-lp-drop and -sell-pct must be between 0 and 100, and -slippage between 0 and 100")
+	}
+
+	if *mintAddr != "" {
+		config.Tokens[*mintAddr] = rule
+	} else {
+		config.Default = rule
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Rug guard saved to %s\n", path)
+}
+
+// rugWatch is one held token being monitored
+type rugWatch struct {
+	account *ownedTokenAccount
+	pool    *OnChainPool // nil when the token has no pool to exit through
+	rule    RugGuardRule
+
+	mintAuthority   *solana.PublicKey
+	freezeAuthority *solana.PublicKey
+	exited          bool
+
+	// LP withdrawals are measured on sqrt(base*quote), which swaps never lower,
+	// once both vaults reported the same slot
+	baseSlot, quoteSlot uint64
+	depth               []rugDepthSample
+}
+
+// rugDepthSample is the pool's invariant depth at a time
+type rugDepthSample struct {
+	Time  time.Time
+	Depth float64
+}
+
+// rugMonitor delivers events and runs emergency exits one at a time, since
+// they share the compute price setting
+type rugMonitor struct {
+	client   ChainClient
+	wallet   solana.PrivateKey
+	guard    *SpendGuard
+	notifier *WebhookNotifier
+	opts     BuildOptions
+	mu       sync.Mutex
+}
+
+// runRugGuardMonitor watches the wallet's tokens for rug signs
+func runRugGuardMonitor(args []string, opts BuildOptions) {
+	fs := newCommandFlagSet("rug-guard monitor")
+	webhook := fs.String("webhook", "", "POST detected rugs and exits to this URL")
+	secret := fs.String("notify-secret", os.Getenv(NOTIFY_SECRET_ENV_VAR), "HMAC-SHA256 key used to sign webhook payloads (or "+NOTIFY_SECRET_ENV_VAR+")")
+	fs.Parse(args)
+
+	wallet, err := loadWallet()
+	if err != nil {
+		log.Fatalf("Failed to load wallet: %v", err)
+	}
+	config, _, err := loadRugGuardConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := interruptContext()
+	client := newChainClient()
+	guard, err := loadSpendGuard(client, wallet.PublicKey())
+	if err != nil {
+		log.Fatalf("Failed to load spend limits: %v", err)
+	}
+	monitor := &rugMonitor{client: client, wallet: wallet, guard: guard, notifier: newWebhookNotifier(*webhook, *secret), opts: opts}
+
+	accounts, err := fetchOwnedTokenAccounts(ctx, client, wallet.PublicKey())
+	if err != nil {
+		log.Fatalf("Failed to list token accounts: %v", err)
+	}
+
+	mux := newUpdateStream(resolveWSURL())
+	var watched int
+	for _, account := range accounts {
+		if account.Raw == 0 || account.Mint.Equals(WSOL_MINT) {
+			continue
+		}
+		watch := &rugWatch{account: account, rule: config.rule(account.Mint)}
+		mintInfo, err := client.GetAccountInfo(ctx, account.Mint)
+		if err != nil {
+			log.Fatalf("Failed to get mint %s: %v", account.Mint, err)
+		}
+		if watch.mintAuthority, watch.freezeAuthority, err = parseMintAuthorities(mintInfo.Value.Data.GetBinary()); err != nil {
+			log.Fatalf("Failed to parse mint %s: %v", account.Mint, err)
+		}
+		if pool, err := findPoolsOnChain(ctx, client, account.Mint.String()); err == nil {
+			watch.pool = pool
+		}
+
+		poolLabel := "no pool, alerts only"
+		if watch.pool != nil {
+			poolLabel = watch.pool.Address.String()
+		}
+		action := "alert"
+		if watch.rule.AutoSell && watch.pool != nil {
+			action = fmt.Sprintf("sell %.0f%%", watch.rule.SellPct)
+		}
+		fmt.Printf("Watching %s (%s): %s, %s at %.0f%% LP withdrawn\n",
+			tokenLabel("TOKEN", account.Symbol), account.Mint, poolLabel, action, watch.rule.LPDropPct)
+		go monitor.watch(ctx, mux, watch)
+		watched++
+	}
+	if watched == 0 {
+		log.Fatal("The wallet holds no tokens to guard")
+	}
+
+	go func() {
+		if err := mux.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Fatalf("Websocket stream failed: %v", err)
+		}
+	}()
+	<-ctx.Done()
+	fmt.Println("\nStopped the rug guard")
+}
+
+// watch follows a token's mint, the wallet's account and its pool's vaults
+func (m *rugMonitor) watch(ctx context.Context, mux UpdateStream, w *rugWatch) {
+	mintUpdates := mux.SubscribeAccount(w.account.Mint)
+	accountUpdates := mux.SubscribeAccount(w.account.Address)
+	var baseUpdates, quoteUpdates <-chan StreamUpdate
+	if w.pool != nil {
+		baseUpdates = mux.SubscribeAccount(w.pool.BaseVault)
+		quoteUpdates = mux.SubscribeAccount(w.pool.QuoteVault)
+		w.recordDepth(time.Now())
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case u := <-mintUpdates:
+			mintAuthority, freezeAuthority, err := parseMintAuthorities(u.Data)
+			if err != nil {
+				continue
+			}
+			if !sameAuthority(mintAuthority, w.mintAuthority) {
+				m.detected(ctx, w, RUG_EVENT_MINT_AUTHORITY, fmt.Sprintf("mint authority changed from %s to %s", authorityLabel(w.mintAuthority), authorityLabel(mintAuthority)), true)
+			}
+			if !sameAuthority(freezeAuthority, w.freezeAuthority) {
+				m.detected(ctx, w, RUG_EVENT_FREEZE_AUTHORITY, fmt.Sprintf("freeze authority changed from %s to %s", authorityLabel(w.freezeAuthority), authorityLabel(freezeAuthority)), true)
+			}
+			w.mintAuthority, w.freezeAuthority = mintAuthority, freezeAuthority
+		case u := <-accountUpdates:
+			if len(u.Data) < TOKEN_ACCOUNT_SIZE {
+				continue
+			}
+			w.account.Raw = binary.LittleEndian.Uint64(u.Data[TOKEN_ACCOUNT_AMOUNT_OFFSET:])
+			if u.Data[TOKEN_ACCOUNT_STATE_OFFSET] == TOKEN_ACCOUNT_STATE_FROZEN && w.account.Skip != "frozen" {
+				w.account.Skip = "frozen"
+				m.detected(ctx, w, RUG_EVENT_FROZEN, "the wallet's token account was frozen; it can no longer be sold", false)
+			}
+		case u := <-baseUpdates:
+			if amount, err := decodeTokenAmount(u.Data); err == nil {
+				w.pool.BaseAmount, w.baseSlot = amount, u.Slot
+				m.checkWithdrawal(ctx, w)
+			}
+		case u := <-quoteUpdates:
+			if amount, err := decodeTokenAmount(u.Data); err == nil {
+				w.pool.QuoteAmount, w.quoteSlot = amount, u.Slot
+				m.checkWithdrawal(ctx, w)
+			}
+		}
+	}
+}
+
+// recordDepth adds the pool's invariant depth and forgets samples outside the window
+func (w *rugWatch) recordDepth(now time.Time) {
+	base := atoms.ToAmount(w.pool.BaseAmount, int(w.pool.BaseDecimals))
+	quote := atoms.ToAmount(w.pool.QuoteAmount, int(w.pool.QuoteDecimals))
+	w.depth = append(w.depth, rugDepthSample{Time: now, Depth: math.Sqrt(base * quote)})
+	cutoff := now.Add(-RUG_GUARD_WINDOW)
+	for len(w.depth) > 1 && w.depth[0].Time.Before(cutoff) {
+		w.depth = w.depth[1:]
+	}
+}
+
+// checkWithdrawal compares the pool's depth with its peak in the window once
+// both vaults are at the same slot
+func (m *rugMonitor) checkWithdrawal(ctx context.Context, w *rugWatch) {
+	if w.baseSlot != w.quoteSlot {
+		return
+	}
+	w.recordDepth(time.Now())
+	var peak float64
+	for _, s := range w.depth {
+		peak = max(peak, s.Depth)
+	}
+	current := w.depth[len(w.depth)-1].Depth
+	if peak == 0 {
+		return
+	}
+	if drop := (peak - current) / peak * 100; drop >= w.rule.LPDropPct {
+		m.detected(ctx, w, RUG_EVENT_LP_WITHDRAWAL, fmt.Sprintf("%.1f%% of the pool's liquidity was withdrawn within %s", drop, RUG_GUARD_WINDOW), true)
+		// Measure further withdrawals from here
+		w.depth = w.depth[len(w.depth)-1:]
+	}
+}
+
+// detected reports an event and, for events that leave the token sellable,
+// runs the emergency exit when the token's rule asks for it
+func (m *rugMonitor) detected(ctx context.Context, w *rugWatch, kind string, detail string, sellable bool) {
+	event := RugEvent{Event: "rug.detected", Kind: kind, Mint: w.account.Mint.String(), Detail: detail}
+	if w.pool != nil {
+		event.Pool = w.pool.Address.String()
+	}
+	fmt.Printf("[%s] 🚨 %s (%s): %s\n", time.Now().Format(time.TimeOnly), tokenLabel("TOKEN", w.account.Symbol), w.account.Mint, detail)
+
+	if sellable && w.rule.AutoSell && w.pool != nil && !w.exited && w.account.Raw > 0 {
+		w.exited = true
+		txHash, err := m.exit(ctx, w)
+		if err != nil {
+			event.ExitErr = err.Error()
+			fmt.Printf("❌ Emergency exit failed: %v\n", err)
+		} else {
+			event.Exit = txHash
+			fmt.Printf("✅ Emergency exit: %s\n", explorerTxURL(txHash))
+		}
+	}
+
+	event.SentAt = time.Now().UTC()
+	if m.notifier == nil {
+		return
+	}
+	if err := m.notifier.deliver(ctx, event); err != nil {
+		fmt.Printf("Warning: Could not send webhook notification: %v\n", err)
+	}
+}
+
+// exit market-sells the rule's share of the balance at its compute price.
+// It bypasses the spend limits, which could otherwise hold the exit back,
+// but records the sale in the ledger.
+func (m *rugMonitor) exit(ctx context.Context, w *rugWatch) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	amount := atoms.ToAmount(w.account.Raw, w.account.Decimals) * w.rule.SellPct / 100
+	expectedRaw := poolNetOutput(w.pool, "sell", amount)
+	expected := atoms.ToAmount(expectedRaw, currencyDecimals(w.pool))
+	minAmountOut := calculateMinAmountOut(expectedRaw, w.rule.Slippage)
+
+	opts := m.opts
+	opts.Compute.Price = w.rule.ComputePrice
+
+	fmt.Printf("Selling %.9f %s for at least %.9f (priority %d micro-lamports/CU)...\n",
+		amount, tokenLabel("TOKEN", w.account.Symbol), atoms.ToAmount(minAmountOut, currencyDecimals(w.pool)), w.rule.ComputePrice)
+	txHash, err := executeSwap(ctx, m.client, m.wallet, w.pool.Address.String(), "sell", amount, minAmountOut, opts)
+	if err != nil {
+		return "", err
+	}
+	if err := m.guard.Record(newGuardedTrade(w.account.Mint, "sell", amount, expected, opts.Burn)); err != nil {
+		fmt.Printf("Warning: Could not record trade for spend limits: %v\n", err)
+	}
+	return txHash, nil
+}
+
+// sameAuthority compares optional authorities
+func sameAuthority(a, b *solana.PublicKey) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equals(*b)
+}
+
+// authorityLabel prints an optional authority
+func authorityLabel(authority *solana.PublicKey) string {
+	if authority == nil {
+		return "none"
+	}
+	return authority.String()
+}
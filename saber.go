@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"awesomeProject/internal/amm"
+	"awesomeProject/internal/atoms"
+)
+
+// SABER_STABLE_SWAP is Saber's StableSwap program; its pools trade correlated
+// pairs like USDC/USDT on the stable invariant, not x*y=k
+var SABER_STABLE_SWAP = solana.MustPublicKeyFromBase58("SSwpkEEcbUqx4vtoEByFjSkhKdCT862DNVb52nZg1UZ")
+
+// ErrStableSwapPool marks a pool of a StableSwap program. Such pools are
+// quoted on the stable invariant but cannot be swapped through yet.
+var ErrStableSwapPool = errors.New("StableSwap pool")
+
+// Saber SwapInfo account layout
+const (
+	SABER_SWAP_INFO_SIZE         = 395
+	SABER_INITIAL_AMP_OFFSET     = 3
+	SABER_TARGET_AMP_OFFSET      = 11
+	SABER_START_RAMP_OFFSET      = 19
+	SABER_STOP_RAMP_OFFSET       = 27
+	SABER_TOKEN_A_OFFSET         = 107
+	SABER_TOKEN_B_OFFSET         = 139
+	SABER_TOKEN_A_MINT_OFFSET    = 203
+	SABER_TOKEN_B_MINT_OFFSET    = 235
+	SABER_TRADE_FEE_NUM_OFFSET   = 363
+	SABER_TRADE_FEE_DENOM_OFFSET = 371
+)
+
+// SaberSwap is a parsed Saber SwapInfo account
+type SaberSwap struct {
+	Address             solana.PublicKey
+	Paused              bool
+	InitialAmp          uint64
+	TargetAmp           uint64
+	StartRamp           int64
+	StopRamp            int64
+	TokenA              solana.PublicKey // reserve token accounts
+	TokenB              solana.PublicKey
+	TokenAMint          solana.PublicKey
+	TokenBMint          solana.PublicKey
+	TradeFeeNumerator   uint64
+	TradeFeeDenominator uint64
+	TokenAAmount        uint64
+	TokenBAmount        uint64
+	TokenADecimals      uint8
+	TokenBDecimals      uint8
+}
+
+// parseSaberSwap decodes a SwapInfo account
+func parseSaberSwap(address solana.PublicKey, data []byte) (*SaberSwap, error) {
+	if len(data) < SABER_SWAP_INFO_SIZE {
+		return nil, fmt.Errorf("invalid SwapInfo data size: %d", len(data))
+	}
+	if data[0] == 0 {
+		return nil, fmt.Errorf("swap %s is not initialized", address)
+	}
+
+	key := func(offset int) solana.PublicKey {
+		return solana.PublicKeyFromBytes(data[offset : offset+32])
+	}
+	u64 := func(offset int) uint64 {
+		return binary.LittleEndian.Uint64(data[offset : offset+8])
+	}
+	return &SaberSwap{
+		Address:             address,
+		Paused:              data[1] != 0,
+		InitialAmp:          u64(SABER_INITIAL_AMP_OFFSET),
+		TargetAmp:           u64(SABER_TARGET_AMP_OFFSET),
+		StartRamp:           int64(u64(SABER_START_RAMP_OFFSET)),
+		StopRamp:            int64(u64(SABER_STOP_RAMP_OFFSET)),
+		TokenA:              key(SABER_TOKEN_A_OFFSET),
+		TokenB:              key(SABER_TOKEN_B_OFFSET),
+		TokenAMint:          key(SABER_TOKEN_A_MINT_OFFSET),
+		TokenBMint:          key(SABER_TOKEN_B_MINT_OFFSET),
+		TradeFeeNumerator:   u64(SABER_TRADE_FEE_NUM_OFFSET),
+		TradeFeeDenominator: u64(SABER_TRADE_FEE_DENOM_OFFSET),
+	}, nil
+}
+
+// loadSaberSwap fetches a swap account with its reserve balances and decimals
+func loadSaberSwap(ctx context.Context, client ChainClient, address solana.PublicKey) (*SaberSwap, error) {
+	accountInfo, err := client.GetAccountInfo(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get swap account: %w", err)
+	}
+	swap, err := parseSaberSwap(address, accountInfo.Value.Data.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := client.GetMultipleAccounts(ctx, swap.TokenA, swap.TokenB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get swap reserves: %w", err)
+	}
+	if len(accounts.Value) != 2 || accounts.Value[0] == nil || accounts.Value[1] == nil {
+		return nil, fmt.Errorf("reserve accounts not found")
+	}
+	swap.TokenAAmount, err = decodeTokenAmount(accounts.Value[0].Data.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+	swap.TokenBAmount, err = decodeTokenAmount(accounts.Value[1].Data.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+
+	swap.TokenADecimals, err = getTokenDecimals(ctx, client, swap.TokenAMint.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token A decimals: %w", err)
+	}
+	swap.TokenBDecimals, err = getTokenDecimals(ctx, client, swap.TokenBMint.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token B decimals: %w", err)
+	}
+	return swap, nil
+}
+
+// calculateStableQuote quotes a swap through a Saber pool on the stable
+// invariant. A buy spends the pool's currency side, as for V4 pools.
+func calculateStableQuote(ctx context.Context, client ChainClient, params QuoteParams) (uint64, int, error) {
+	address, err := solana.PublicKeyFromBase58(params.PoolAddress)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid pool address: %w", err)
+	}
+	swap, err := loadSaberSwap(ctx, client, address)
+	if err != nil {
+		return 0, 0, err
+	}
+	if swap.Paused {
+		fmt.Printf("Warning: swap %s is paused\n", swap.Address)
+	}
+
+	amp := amm.StableAmp(swap.InitialAmp, swap.TargetAmp, swap.StartRamp, swap.StopRamp, time.Now().Unix())
+
+	fmt.Printf("\n=== Pool Information (On-Chain) ===\n")
+	fmt.Printf("Pool Address: %s (Saber StableSwap)\n", swap.Address)
+	fmt.Printf("Token A: %s %s (decimals: %d)\n", tokenSymbol(ctx, client, swap.TokenAMint), swap.TokenAMint, swap.TokenADecimals)
+	fmt.Printf("Token B: %s %s (decimals: %d)\n", tokenSymbol(ctx, client, swap.TokenBMint), swap.TokenBMint, swap.TokenBDecimals)
+	fmt.Printf("Token A Reserve: %s\n", atoms.Format(swap.TokenAAmount, int(swap.TokenADecimals)))
+	fmt.Printf("Token B Reserve: %s\n", atoms.Format(swap.TokenBAmount, int(swap.TokenBDecimals)))
+	fmt.Printf("Amplification: %d\n", amp)
+
+	// Token A is the currency side when it is SOL or a stablecoin
+	aIsCurrency := isBaseCurrency(&OnChainPool{BaseMint: swap.TokenAMint, QuoteMint: swap.TokenBMint})
+	reserveIn, reserveOut := swap.TokenAAmount, swap.TokenBAmount
+	inputDecimals, outputDecimals := int(swap.TokenADecimals), int(swap.TokenBDecimals)
+	if (params.Side == "buy") != aIsCurrency {
+		reserveIn, reserveOut = reserveOut, reserveIn
+		inputDecimals, outputDecimals = outputDecimals, inputDecimals
+	}
+
+	amountIn, err := atoms.FromAmount(params.Amount, inputDecimals)
+	if err != nil {
+		return 0, 0, err
+	}
+	amountOut, fee := amm.StableSwapOut(reserveIn, reserveOut, amp, swap.TradeFeeNumerator, swap.TradeFeeDenominator, amountIn)
+
+	fmt.Printf("\n=== Calculation Details ===\n")
+	fmt.Printf("Amount in (raw): %d\n", amountIn)
+	fmt.Printf("Fee (of output): %d\n", fee)
+	fmt.Printf("Amount out (raw): %d\n", amountOut)
+
+	return amountOut, outputDecimals, nil
+}
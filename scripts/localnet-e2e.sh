@@ -0,0 +1,59 @@
+#!/usr/bin/env bash
+# Runs the end-to-end swap checks against a local solana-test-validator with the
+# Raydium V4 and OpenBook programs and a fixture pool cloned from mainnet.
+#
+#   scripts/localnet-e2e.sh [POOL_ADDRESS]
+#
+# Requires the Solana CLI (solana-test-validator, solana-keygen, solana) and Go.
+set -euo pipefail
+
+POOL="${1:-58oQChx4yWmvKdwLLZzBi4ChoCc2fqCUWBkwMihLYQo2}"
+SOURCE_RPC="${SOURCE_RPC_URL:-https://api.mainnet-beta.solana.com}"
+RPC_PORT="${RPC_PORT:-8899}"
+LOCAL_RPC="http://127.0.0.1:${RPC_PORT}"
+WORKDIR="$(mktemp -d)"
+RAYDIUM_AMM_V4="675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8"
+OPENBOOK="srmqPvymJeFKQ4zGQed1GFppgkRHL9kaELCbyksJtPX"
+
+cd "$(dirname "$0")/.."
+go build -o "${WORKDIR}/raydium" .
+
+cleanup() {
+	if [[ -n "${VALIDATOR_PID:-}" ]]; then
+		kill "${VALIDATOR_PID}" 2>/dev/null || true
+		wait "${VALIDATOR_PID}" 2>/dev/null || true
+	fi
+	rm -rf "${WORKDIR}"
+}
+trap cleanup EXIT
+
+echo "Resolving fixture accounts for pool ${POOL}..."
+CLONE_ARGS=()
+while read -r account; do
+	CLONE_ARGS+=(--clone "${account}")
+done < <(SOLANA_RPC_URL="${SOURCE_RPC}" "${WORKDIR}/raydium" e2e accounts -pool "${POOL}")
+
+echo "Starting solana-test-validator..."
+solana-test-validator \
+	--reset \
+	--quiet \
+	--ledger "${WORKDIR}/ledger" \
+	--rpc-port "${RPC_PORT}" \
+	--url "${SOURCE_RPC}" \
+	--clone-upgradeable-program "${RAYDIUM_AMM_V4}" \
+	--clone-upgradeable-program "${OPENBOOK}" \
+	"${CLONE_ARGS[@]}" &
+VALIDATOR_PID=$!
+
+for _ in $(seq 1 60); do
+	if solana --url "${LOCAL_RPC}" cluster-version >/dev/null 2>&1; then
+		break
+	fi
+	sleep 1
+done
+
+solana-keygen new --no-bip39-passphrase --silent --outfile "${WORKDIR}/wallet.json"
+solana --url "${LOCAL_RPC}" airdrop 10 "${WORKDIR}/wallet.json" >/dev/null
+
+SOLANA_CLUSTER=custom SOLANA_RPC_URL="${LOCAL_RPC}" \
+	"${WORKDIR}/raydium" e2e run -pool "${POOL}" -keypair "${WORKDIR}/wallet.json"
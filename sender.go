@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+// Environment variables configuring the -sender backend; the flags override them
+const (
+	SENDER_URL_ENV_VAR         = "SENDER_URL"
+	SENDER_TIP_ACCOUNT_ENV_VAR = "SENDER_TIP_ACCOUNT"
+)
+
+const (
+	SENDER_RPC    = "rpc"
+	SENDER_JITO   = "jito"
+	SENDER_HELIUS = "helius"
+	SENDER_NOZOMI = "nozomi"
+
+	DEFAULT_JITO_BUNDLE_URL   = "https://mainnet.block-engine.jito.wtf/api/v1/bundles"
+	DEFAULT_HELIUS_SENDER_URL = "https://sender.helius-rpc.com/fast"
+	DEFAULT_SENDER_TIP        = 0.001 // SOL
+	JITO_MIN_TIP_LAMPORTS     = 1000
+)
+
+// JITO_TIP_ACCOUNTS are Jito's published tip payment accounts; one is picked
+// at random per transaction to spread write locks
+var JITO_TIP_ACCOUNTS = []solana.PublicKey{
+	solana.MustPublicKeyFromBase58("96gYZGLnJYVFmbjzopPSU6QiEV5fGqZNyN9nmNhvrZU5"),
+	solana.MustPublicKeyFromBase58("HFqU5x63VTqvQss8hp11i4wVV8bD44PvwucfZ2bU7gRe"),
+	solana.MustPublicKeyFromBase58("Cw8CFyM9FkoMi7K7Crf6HNQqf4uEMzpKw6QNghXLvLkY"),
+	solana.MustPublicKeyFromBase58("ADaUMid9yfUytqMBgopwjb2DTLSokTSzL1zt6iGPaS49"),
+	solana.MustPublicKeyFromBase58("DfXygSm4jCyNCybVYYK6DwvWqjKee8pbDmJGcLWNDXjh"),
+	solana.MustPublicKeyFromBase58("ADuUkR4vqLUMWXxW9gh6D6L8pMSawimctcNZ5pGwDcEt"),
+	solana.MustPublicKeyFromBase58("DttWaMuVvTiduZRnguLF7jNxTgiMBZ1hyAumKUiL2KRL"),
+	solana.MustPublicKeyFromBase58("3AVi9Tg9Uo68tJfuvoKvqKNWKkC5wPdSSdeBnizKZ6jT"),
+}
+
+// Sender submits signed transactions. Senders that are paid through a tip
+// return the tip transfer, which is added when the transaction is built.
+type Sender interface {
+	Name() string
+	TipInstruction(payer solana.PublicKey) (solana.Instruction, error) // nil when no tip is paid
+	Send(ctx context.Context, tx *solana.Transaction) (solana.Signature, error)
+}
+
+// SenderConfig selects and configures the sender backend
+type SenderConfig struct {
+	Name       string
+	URL        string  // endpoint, defaulting per backend
+	Tip        float64 // SOL
+	TipAccount string  // required by backends without built-in tip accounts
+}
+
+// sender returns the configured backend. In anti-MEV mode the default RPC
+// backend is replaced by the private relay.
+func (c SenderConfig) sender(client ChainClient, mev MEVProtection) (Sender, error) {
+	tip, err := atoms.FromAmount(c.Tip, SOL_DECIMALS)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tip: %w", err)
+	}
+
+	switch c.Name {
+	case SENDER_RPC, "":
+		if relay := mev.relaySender(); relay != nil {
+			return relay, nil
+		}
+		return &rpcSender{client: client}, nil
+
+	case SENDER_JITO:
+		if tip < JITO_MIN_TIP_LAMPORTS {
+			return nil, fmt.Errorf("jito bundles need a tip of at least %d lamports", JITO_MIN_TIP_LAMPORTS)
+		}
+		return &jitoSender{url: c.urlOr(DEFAULT_JITO_BUNDLE_URL), tip: tip, tipAccounts: JITO_TIP_ACCOUNTS}, nil
+
+	case SENDER_HELIUS, SENDER_NOZOMI:
+		url := c.URL
+		if url == "" && c.Name == SENDER_HELIUS {
+			url = DEFAULT_HELIUS_SENDER_URL
+		}
+		if url == "" {
+			return nil, fmt.Errorf("%s needs its endpoint including the API key in -sender-url or %s", c.Name, SENDER_URL_ENV_VAR)
+		}
+		if c.TipAccount == "" {
+			return nil, fmt.Errorf("%s needs a tip account from its documentation in -tip-account or %s", c.Name, SENDER_TIP_ACCOUNT_ENV_VAR)
+		}
+		tipAccount, err := solana.PublicKeyFromBase58(c.TipAccount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tip account: %w", err)
+		}
+		if tip == 0 {
+			return nil, fmt.Errorf("%s only forwards transactions that pay a tip", c.Name)
+		}
+		return &relaySender{name: c.Name, url: url, tip: tip, tipAccounts: []solana.PublicKey{tipAccount}}, nil
+	}
+	return nil, fmt.Errorf("unknown sender %q (available: rpc, jito, helius, nozomi)", c.Name)
+}
+
+func (c SenderConfig) urlOr(fallback string) string {
+	if c.URL != "" {
+		return c.URL
+	}
+	return fallback
+}
+
+// tipTransfer pays lamports to one of accounts, chosen at random
+func tipTransfer(payer solana.PublicKey, lamports uint64, accounts []solana.PublicKey) solana.Instruction {
+	account := accounts[rand.N(len(accounts))]
+	fmt.Printf("Tip: %s SOL to %s\n", atoms.Format(lamports, SOL_DECIMALS), account)
+	return system.NewTransferInstruction(lamports, payer, account).Build()
+}
+
+// rpcSender sends through the regular RPC, with preflight first for better errors
+type rpcSender struct {
+	client ChainClient
+}
+
+func (s *rpcSender) Name() string { return SENDER_RPC }
+
+func (s *rpcSender) TipInstruction(payer solana.PublicKey) (solana.Instruction, error) {
+	return nil, nil
+}
+
+func (s *rpcSender) Send(ctx context.Context, tx *solana.Transaction) (solana.Signature, error) {
+	sig, err := s.client.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{
+		SkipPreflight:       false,
+		PreflightCommitment: rpc.CommitmentFinalized,
+	})
+	// If preflight fails, try without it to get the actual on-chain error
+	if err != nil && strings.Contains(err.Error(), "Transaction signature verification failure") {
+		fmt.Println("Preflight failed, trying without preflight to get on-chain error...")
+		sig, err = s.client.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{
+			SkipPreflight:       true,
+			PreflightCommitment: rpc.CommitmentFinalized,
+		})
+	}
+	return sig, err
+}
+
+// relaySender forwards transactions with sendTransaction to a landing service
+// or private relay. Relays do not simulate, so preflight is skipped.
+type relaySender struct {
+	name        string
+	url         string
+	tip         uint64
+	tipAccounts []solana.PublicKey
+}
+
+func (s *relaySender) Name() string { return s.name }
+
+func (s *relaySender) TipInstruction(payer solana.PublicKey) (solana.Instruction, error) {
+	if s.tip == 0 {
+		return nil, nil
+	}
+	return tipTransfer(payer, s.tip, s.tipAccounts), nil
+}
+
+func (s *relaySender) Send(ctx context.Context, tx *solana.Transaction) (solana.Signature, error) {
+	return rpc.New(s.url).SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{SkipPreflight: true})
+}
+
+// jitoSender submits single-transaction bundles to a Jito block engine. The
+// bundle only lands with its tip, and never lands partially or reverted.
+type jitoSender struct {
+	url         string
+	tip         uint64
+	tipAccounts []solana.PublicKey
+}
+
+func (s *jitoSender) Name() string { return SENDER_JITO }
+
+func (s *jitoSender) TipInstruction(payer solana.PublicKey) (solana.Instruction, error) {
+	return tipTransfer(payer, s.tip, s.tipAccounts), nil
+}
+
+func (s *jitoSender) Send(ctx context.Context, tx *solana.Transaction) (solana.Signature, error) {
+	if len(tx.Signatures) == 0 {
+		return solana.Signature{}, fmt.Errorf("transaction is not signed")
+	}
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to serialize transaction: %w", err)
+	}
+
+	var bundleID string
+	params := []any{[]string{base64.StdEncoding.EncodeToString(data)}, map[string]string{"encoding": "base64"}}
+	if err := rpc.New(s.url).RPCCallForInto(ctx, &bundleID, "sendBundle", params); err != nil {
+		return solana.Signature{}, err
+	}
+	fmt.Printf("Bundle: %s\n", bundleID)
+	return tx.Signatures[0], nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SESSIONS_DIR is the state subdirectory holding session history
+const SESSIONS_DIR = "sessions"
+
+// SessionFailure records a failed attempt within an automated session
+type SessionFailure struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+// Session tracks the fills of one automated run (DCA, TWAP, guard...) so a
+// consolidated exit report can be produced when it ends
+type Session struct {
+	ID        string               `json:"id"`
+	Strategy  string               `json:"strategy"`
+	Side      string               `json:"side"`
+	Pool      string               `json:"pool"`
+	StartedAt time.Time            `json:"started_at"`
+	EndedAt   time.Time            `json:"ended_at,omitempty"`
+	Fills     []*TransactionReport `json:"fills"`
+	Failures  []SessionFailure     `json:"failures"`
+	Summary   *SessionSummary      `json:"summary,omitempty"`
+
+	mu sync.Mutex
+}
+
+// SessionSummary is the consolidated result of a session
+type SessionSummary struct {
+	FillCount    int     `json:"fill_count"`
+	FailureCount int     `json:"failure_count"`
+	TotalIn      float64 `json:"total_in"`
+	TotalOut     float64 `json:"total_out"`
+	InputToken   string  `json:"input_token"`
+	OutputToken  string  `json:"output_token"`
+	VWAP         float64 `json:"vwap"` // SOL per token
+	TotalFees    float64 `json:"total_fees"`
+	BestFill     string  `json:"best_fill,omitempty"`
+	BestPrice    float64 `json:"best_price,omitempty"`
+	WorstFill    string  `json:"worst_fill,omitempty"`
+	WorstPrice   float64 `json:"worst_price,omitempty"`
+	Duration     string  `json:"duration"`
+}
+
+// newSession starts a session for a strategy
+func newSession(strategy string, side string, pool string) *Session {
+	now := time.Now()
+	return &Session{
+		ID:        fmt.Sprintf("%s-%s", strategy, now.UTC().Format("20060102T150405")),
+		Strategy:  strategy,
+		Side:      side,
+		Pool:      pool,
+		StartedAt: now,
+	}
+}
+
+// RecordFill links an executed swap to the session
+func (s *Session) RecordFill(report *TransactionReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Fills = append(s.Fills, report)
+}
+
+// RecordFailure notes a failed attempt
+func (s *Session) RecordFailure(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Failures = append(s.Failures, SessionFailure{Time: time.Now(), Error: err.Error()})
+}
+
+// Finish closes the session, computes its summary and stores it in history
+func (s *Session) Finish() (*SessionSummary, error) {
+	s.mu.Lock()
+	s.EndedAt = time.Now()
+	s.Summary = s.summarize()
+	s.mu.Unlock()
+
+	return s.Summary, s.save()
+}
+
+// summarize aggregates fills; callers must hold the lock
+func (s *Session) summarize() *SessionSummary {
+	summary := &SessionSummary{
+		FillCount:    len(s.Fills),
+		FailureCount: len(s.Failures),
+		InputToken:   getInputToken(s.Side),
+		OutputToken:  getOutputToken(s.Side),
+		Duration:     s.EndedAt.Sub(s.StartedAt).Round(time.Second).String(),
+	}
+
+	for _, fill := range s.Fills {
+		summary.TotalIn += fill.AmountIn
+		summary.TotalOut += fill.AmountOut
+		summary.TotalFees += fill.NetworkFee + fill.PriorityFee
+
+		// Buyers want the lowest SOL-per-token price, sellers the highest
+		better := func(a, b float64) bool {
+			if s.Side == "buy" {
+				return a < b
+			}
+			return a > b
+		}
+		if summary.BestFill == "" || better(fill.ActualPrice, summary.BestPrice) {
+			summary.BestFill = fill.TxHash
+			summary.BestPrice = fill.ActualPrice
+		}
+		if summary.WorstFill == "" || better(summary.WorstPrice, fill.ActualPrice) {
+			summary.WorstFill = fill.TxHash
+			summary.WorstPrice = fill.ActualPrice
+		}
+	}
+
+	// Volume-weighted price in SOL per token across all fills
+	if s.Side == "buy" && summary.TotalOut > 0 {
+		summary.VWAP = summary.TotalIn / summary.TotalOut
+	} else if s.Side == "sell" && summary.TotalIn > 0 {
+		summary.VWAP = summary.TotalOut / summary.TotalIn
+	}
+
+	return summary
+}
+
+// save writes the session with its linked fills to the state directory
+func (s *Session) save() error {
+	dir, err := stateDir()
+	if err != nil {
+		return err
+	}
+
+	sessionsDir := filepath.Join(dir, SESSIONS_DIR)
+	if err := os.MkdirAll(sessionsDir, 0700); err != nil {
+		return fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	path := filepath.Join(sessionsDir, s.ID+".json")
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save session %s: %w", s.ID, err)
+	}
+
+	return nil
+}
+
+// printSessionReport displays the consolidated exit report of a session
+func printSessionReport(s *Session) {
+	summary := s.Summary
+	if summary == nil {
+		return
+	}
+
+	fmt.Printf("\n=== SESSION REPORT ===\n")
+	fmt.Printf("Session: %s\n", s.ID)
+	fmt.Printf("Strategy: %s\n", s.Strategy)
+	fmt.Printf("Operation: %s\n", strings.ToUpper(s.Side))
+	fmt.Printf("Pool: %s\n", s.Pool)
+	fmt.Printf("Duration: %s\n", summary.Duration)
+	fmt.Printf("\nFills: %d (failures: %d)\n", summary.FillCount, summary.FailureCount)
+	fmt.Printf("  Total In: %.9f %s\n", summary.TotalIn, summary.InputToken)
+	fmt.Printf("  Total Out: %.9f %s\n", summary.TotalOut, summary.OutputToken)
+	fmt.Printf("  VWAP: %.9f SOL per token\n", summary.VWAP)
+	fmt.Printf("  Total Fees: %.9f SOL\n", summary.TotalFees)
+	if summary.BestFill != "" {
+		fmt.Printf("  Best Fill: %.9f (%s)\n", summary.BestPrice, summary.BestFill)
+		fmt.Printf("  Worst Fill: %.9f (%s)\n", summary.WorstPrice, summary.WorstFill)
+	}
+	for _, failure := range s.Failures {
+		fmt.Printf("  Failed at %s: %s\n", failure.Time.Format(time.DateTime), failure.Error)
+	}
+	fmt.Printf("======================\n")
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	DEFAULT_SIZE_MAX_IMPACT = 1.0 // percent
+	DEFAULT_SIZE_HORIZON    = time.Hour
+	DEFAULT_SIZE_SIGMAS     = 2.0
+	// SIZE_MIN_CANDLES is the fewest candles volatility is estimated from
+	SIZE_MIN_CANDLES = 10
+)
+
+// SizeSuggestion is the output of `size suggest`. Buy sizes are in SOL and
+// sell sizes in tokens; the volatility fields are zero without candles.
+type SizeSuggestion struct {
+	Pool           string  `json:"pool"`
+	Price          float64 `json:"price"` // SOL per token
+	MaxImpactPct   float64 `json:"max_impact_pct"`
+	MaxBuySOL      float64 `json:"max_buy_sol"`
+	MaxSellTokens  float64 `json:"max_sell_tokens"`
+	Candles        int     `json:"candles,omitempty"`
+	VolatilityPct  float64 `json:"volatility_pct,omitempty"` // over the horizon
+	AdverseMovePct float64 `json:"adverse_move_pct,omitempty"`
+	RiskSOL        float64 `json:"risk_sol,omitempty"`
+	RiskSizeSOL    float64 `json:"risk_size_sol,omitempty"`
+	SuggestedSOL   float64 `json:"suggested_sol"`
+}
+
+// maxAmountForImpact inverts quotedPriceImpact: the largest input of a side
+// whose impact stays at or below maxImpact percent, in UI units
+func maxAmountForImpact(pool *OnChainPool, side string, maxImpact float64) float64 {
+	isBaseToQuote := (side == "buy") == isBaseCurrency(pool)
+	baseReserve, quoteReserve := poolReserves(pool)
+	reserveIn := quoteReserve
+	if isBaseToQuote {
+		reserveIn = baseReserve
+	}
+	_, _, inputDecimals := swapMints(pool, side)
+
+	p := maxImpact / 100
+	afterFee := float64(reserveIn) * p / (1 - p)
+	numerator, denominator := swapFeeRate(pool)
+	amountIn := afterFee * float64(denominator) / float64(denominator-numerator)
+	return amountIn / math.Pow(10, float64(inputDecimals))
+}
+
+// candleVolatility returns the standard deviation of the log returns between
+// consecutive candle closes
+func candleVolatility(candles []Candle) float64 {
+	var returns []float64
+	for i := 1; i < len(candles); i++ {
+		if candles[i-1].Close > 0 && candles[i].Close > 0 {
+			returns = append(returns, math.Log(candles[i].Close/candles[i-1].Close))
+		}
+	}
+	if len(returns) < 2 {
+		return 0
+	}
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	return math.Sqrt(variance / float64(len(returns)-1))
+}
+
+// runSize dispatches the position sizing subcommands
+func runSize(args []string) {
+	if len(args) > 0 && args[0] == "suggest" {
+		runSizeSuggest(args[1:])
+		return
+	}
+	fmt.Println("Usage: go run . size suggest [-pool POOL | -token TOKEN] [-max-impact 1%] [-risk SOL] [-horizon 1h] [-json]")
+	os.Exit(1)
+}
+
+// runSizeSuggest sizes a position by the pool's depth and, with -risk, by the
+// volatility of the candles the daemon recorded for it
+func runSizeSuggest(args []string) {
+	fs := newCommandFlagSet("size suggest")
+	poolAddress := fs.String("pool", "", "Pool address")
+	tokenAddress := fs.String("token", "", "Token address (uses its deepest pool)")
+	maxImpactArg := fs.String("max-impact", fmt.Sprintf("%g%%", DEFAULT_SIZE_MAX_IMPACT), "Largest acceptable price impact, in percent")
+	risk := fs.Float64("risk", 0, "SOL you accept losing on the trade; sizes the position by volatility")
+	horizon := fs.Duration("horizon", DEFAULT_SIZE_HORIZON, "How long the position is held, scaling the candle volatility")
+	sigmas := fs.Float64("sigmas", DEFAULT_SIZE_SIGMAS, "Adverse move the risk must cover, in standard deviations over -horizon")
+	interval := fs.String("interval", "5m", "Candle interval volatility is measured on: "+strings.Join(candleIntervalNames, ", "))
+	hours := fs.Float64("hours", 24, "How far back candles are read")
+	jsonOutput := fs.Bool("json", false, "Output the suggestion as JSON")
+	fs.Parse(args)
+
+	if *poolAddress == "" && *tokenAddress == "" {
+		fmt.Println("Usage: go run . size suggest [-pool POOL | -token TOKEN] [-max-impact 1%] [-risk SOL] [-horizon 1h] [-json]")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	maxImpact, err := parseFloat(*maxImpactArg)
+	if err != nil || maxImpact <= 0 || maxImpact >= 100 {
+		log.Fatal("-max-impact must be a percent between 0 and 100")
+	}
+	if *risk < 0 || *horizon <= 0 || *sigmas <= 0 {
+		log.Fatal("-risk must not be negative, and -horizon and -sigmas must be positive")
+	}
+	candleInterval, ok := candleIntervals[*interval]
+	if !ok {
+		log.Fatalf("-interval must be one of %s", strings.Join(candleIntervalNames, ", "))
+	}
+
+	ctx := context.Background()
+	client := newChainClient()
+	if *poolAddress == "" {
+		pool, err := findPoolsOnChain(ctx, client, *tokenAddress)
+		if err != nil {
+			log.Fatalf("Failed to find pool: %v", err)
+		}
+		*poolAddress = pool.Address.String()
+	}
+	pool, err := loadPool(ctx, client, *poolAddress)
+	if err != nil {
+		log.Fatal(err)
+	}
+	price, _, _ := poolPrice(pool)
+
+	suggestion := SizeSuggestion{
+		Pool:          pool.Address.String(),
+		Price:         price,
+		MaxImpactPct:  maxImpact,
+		MaxBuySOL:     maxAmountForImpact(pool, "buy", maxImpact),
+		MaxSellTokens: maxAmountForImpact(pool, "sell", maxImpact),
+		RiskSOL:       *risk,
+	}
+	suggestion.SuggestedSOL = suggestion.MaxBuySOL
+
+	var volatilityErr error
+	if *risk > 0 {
+		store, err := openCandleStore()
+		if err != nil {
+			log.Fatal(err)
+		}
+		candles, err := store.Query(pool.Address, *interval, time.Now().Add(-time.Duration(*hours*float64(time.Hour))))
+		if err != nil {
+			log.Fatal(err)
+		}
+		suggestion.Candles = len(candles)
+		if len(candles) < SIZE_MIN_CANDLES {
+			volatilityErr = fmt.Errorf("only %d %s candles recorded in the last %gh, %d needed; record them with `daemon -pools %s -candles`", len(candles), *interval, *hours, SIZE_MIN_CANDLES, pool.Address)
+		} else {
+			volatility := candleVolatility(candles) * math.Sqrt(float64(*horizon)/float64(candleInterval))
+			adverse := 1 - math.Exp(-*sigmas*volatility)
+			suggestion.VolatilityPct = volatility * 100
+			suggestion.AdverseMovePct = adverse * 100
+			if adverse > 0 {
+				suggestion.RiskSizeSOL = *risk / adverse
+				suggestion.SuggestedSOL = min(suggestion.RiskSizeSOL, suggestion.MaxBuySOL)
+			}
+		}
+	}
+
+	if *jsonOutput {
+		data, err := json.MarshalIndent(suggestion, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode suggestion: %v", err)
+		}
+		fmt.Println(string(data))
+		if volatilityErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", volatilityErr)
+		}
+		return
+	}
+
+	fmt.Printf("\n=== POSITION SIZE ===\n")
+	fmt.Printf("Pool: %s\n", suggestion.Pool)
+	fmt.Printf("Price: %.12f SOL per token\n", suggestion.Price)
+	fmt.Printf("Max Buy at %.2f%% impact: %.4f SOL\n", maxImpact, suggestion.MaxBuySOL)
+	fmt.Printf("Max Sell at %.2f%% impact: %.4f tokens (~%.4f SOL)\n", maxImpact, suggestion.MaxSellTokens, suggestion.MaxSellTokens*price)
+	if volatilityErr != nil {
+		fmt.Printf("⚠️  Warning: %v\n", volatilityErr)
+	}
+	if suggestion.RiskSizeSOL > 0 {
+		fmt.Printf("Volatility over %s: %.2f%% (%d %s candles)\n", *horizon, suggestion.VolatilityPct, suggestion.Candles, *interval)
+		fmt.Printf("Adverse Move (%.1f sigma): %.2f%%\n", *sigmas, suggestion.AdverseMovePct)
+		fmt.Printf("Size for %.4f SOL at risk: %.4f SOL\n", *risk, suggestion.RiskSizeSOL)
+	}
+	fmt.Printf("Suggested Buy: %.4f SOL\n", suggestion.SuggestedSOL)
+	fmt.Printf("=====================\n")
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"awesomeProject/internal/atoms"
+)
+
+// SLIPPAGE_AUTO is the -slippage value that derives the tolerance from the pool
+const SLIPPAGE_AUTO = "auto"
+
+// -slippage auto settings. The tolerance is k standard deviations of the
+// pool's price over the confirmation window, estimated from the realized
+// variance of its recent swaps.
+const (
+	AUTO_SLIPPAGE_K               = 3.0
+	AUTO_SLIPPAGE_LOOKBACK        = 30 * time.Minute
+	AUTO_SLIPPAGE_MAX_TX          = 300
+	AUTO_SLIPPAGE_CONFIRM_WINDOW  = 30 * time.Second
+	AUTO_SLIPPAGE_FLOOR           = 0.3 // percent
+	AUTO_SLIPPAGE_CEILING         = 10.0
+	AUTO_SLIPPAGE_MIN_PRICE_MOVES = 5
+)
+
+// resolveSlippage returns the tolerance for -slippage: asked for when unset,
+// derived from the pool with auto, or the given percent
+func resolveSlippage(ctx context.Context, client ChainClient, setting string, poolAddress string, side string, amount float64) (float64, error) {
+	switch setting {
+	case "":
+		return getSlippageFromUser()
+	case SLIPPAGE_AUTO:
+		pool, err := loadPool(ctx, client, poolAddress)
+		if err != nil {
+			return 0, err
+		}
+		trades, err := fetchPoolTrades(ctx, client, pool, time.Now().Add(-AUTO_SLIPPAGE_LOOKBACK), AUTO_SLIPPAGE_MAX_TX)
+		if err != nil {
+			return 0, err
+		}
+		return autoSlippage(pool, trades, side, amount, time.Now()), nil
+	}
+	return parseSlippage(setting)
+}
+
+// parseSlippage parses a tolerance in percent
+func parseSlippage(value string) (float64, error) {
+	slippage, err := parseFloat(value)
+	if err != nil {
+		return 0, fmt.Errorf(tr("invalid slippage value: %w"), err)
+	}
+	if slippage < 0 || slippage > MAX_SLIPPAGE {
+		return 0, fmt.Errorf(tr("slippage must be between 0 and %.0f"), MAX_SLIPPAGE)
+	}
+	return slippage, nil
+}
+
+// priceVolatility returns the standard deviation of the log price over the
+// window, in percent, from the realized variance of consecutive swap prices
+// since the first one. It reports false with too few price moves to tell.
+func priceVolatility(trades []PoolTrade, now time.Time, window time.Duration) (float64, int, bool) {
+	var variance float64
+	var moves int
+	for i := 1; i < len(trades); i++ {
+		if trades[i].Price <= 0 || trades[i-1].Price <= 0 {
+			continue
+		}
+		move := math.Log(trades[i].Price / trades[i-1].Price)
+		variance += move * move
+		moves++
+	}
+	if moves < AUTO_SLIPPAGE_MIN_PRICE_MOVES {
+		return 0, moves, false
+	}
+	span := now.Sub(trades[0].Time)
+	if span <= 0 {
+		return 0, moves, false
+	}
+	return math.Sqrt(variance*window.Seconds()/span.Seconds()) * 100, moves, true
+}
+
+// autoSlippage derives a tolerance from the pool's recent volatility and the
+// trade's size. When the price moves by d before the swap lands, a swap of a
+// into reserve r gets (1 + r/(r+a))/2 of d less out, so larger trades need
+// less room. The result is kept between the floor and the ceiling.
+func autoSlippage(pool *OnChainPool, trades []PoolTrade, side string, amount float64, now time.Time) float64 {
+	sigma, moves, ok := priceVolatility(trades, now, AUTO_SLIPPAGE_CONFIRM_WINDOW)
+	if !ok {
+		slippage := min(max(DEFAULT_SLIPPAGE, AUTO_SLIPPAGE_FLOOR), AUTO_SLIPPAGE_CEILING)
+		fmt.Printf(tr("Auto slippage: only %d price moves in the last %s, using %.2f%%\n"), moves, AUTO_SLIPPAGE_LOOKBACK, slippage)
+		return slippage
+	}
+
+	// A buy spends the currency side, a sell the token
+	reserve := atoms.ToAmount(pool.BaseAmount, int(pool.BaseDecimals))
+	if isBaseCurrency(pool) != (side == "buy") {
+		reserve = atoms.ToAmount(pool.QuoteAmount, int(pool.QuoteDecimals))
+	}
+	sensitivity := 1.0
+	if reserve+amount > 0 {
+		sensitivity = (1 + reserve/(reserve+amount)) / 2
+	}
+
+	slippage := min(max(AUTO_SLIPPAGE_K*sigma*sensitivity, AUTO_SLIPPAGE_FLOOR), AUTO_SLIPPAGE_CEILING)
+	fmt.Printf(tr("Auto slippage: %.2f%% (%.1fσ of %.3f%% over %s from %d price moves, size factor %.2f)\n"),
+		slippage, AUTO_SLIPPAGE_K, sigma, AUTO_SLIPPAGE_CONFIRM_WINDOW, moves, sensitivity)
+	return slippage
+}
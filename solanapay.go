@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	DEFAULT_SOLANA_PAY_LISTEN = "127.0.0.1:8787"
+	// The link stops being served after this long
+	SOLANA_PAY_TIMEOUT       = 10 * time.Minute
+	SOLANA_PAY_POLL_INTERVAL = 3 * time.Second
+	SOLANA_PAY_QR_SIZE       = 512 // pixels of -solana-pay-qr images
+	SOLANA_PAY_ICON_PATH     = "/icon.svg"
+)
+
+// SOLANA_PAY_ICON is the icon wallets show next to the request's label
+const SOLANA_PAY_ICON = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 64 64"><rect width="64" height="64" rx="14" fill="#1c243e"/><path d="M18 40 32 16l14 24H18z" fill="none" stroke="#58f3d1" stroke-width="5" stroke-linejoin="round"/></svg>`
+
+// SolanaPaySwap serves one swap as a Solana Pay transaction request: the
+// wallet that opens the link posts its address and gets the swap built for
+// it, unsigned, to sign and send itself. The private key never leaves the
+// wallet. Every transaction served carries the request's reference key, which
+// is how the landed swap is found.
+type SolanaPaySwap struct {
+	Pool         string
+	Side         string
+	Amount       float64
+	MinAmountOut uint64
+	Label        string // shown by the wallet before it asks for the address
+	Message      string // shown with the transaction
+
+	client    ChainClient
+	opts      BuildOptions // without a fee payer or delegation, which the wallet cannot sign for
+	publicURL string
+	path      string
+}
+
+// serveSolanaPay prints the Solana Pay link of the swap as text and QR code,
+// serves it on listen behind publicURL, and waits until a wallet that
+// fetched it lands the swap
+func serveSolanaPay(ctx context.Context, client ChainClient, swap *SolanaPaySwap, publicURL string, listen string, qrPath string, opts BuildOptions) error {
+	base, err := solanaPayBaseURL(publicURL)
+	if err != nil {
+		return err
+	}
+	swap.client = client
+	swap.opts = opts.walletSigned()
+	swap.opts.Reference = solana.NewWallet().PublicKey()
+	swap.publicURL = base
+	swap.path = "/swap/" + newPendingID()
+	link := "solana:" + url.QueryEscape(swap.publicURL+swap.path)
+
+	qr, err := qrcode.New(link, qrcode.Medium)
+	if err != nil {
+		return fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	if qrPath != "" {
+		if err := qr.WriteFile(SOLANA_PAY_QR_SIZE, qrPath); err != nil {
+			return fmt.Errorf("failed to write QR code: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", listen, err)
+	}
+	httpServer := &http.Server{Handler: swap}
+	go httpServer.Serve(listener)
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("\n=== SOLANA PAY ===\n")
+	fmt.Print(qr.ToSmallString(false))
+	fmt.Printf("Link: %s\n", link)
+	if qrPath != "" {
+		fmt.Printf("QR code: %s\n", qrPath)
+	}
+	fmt.Printf("Serving on %s for %s\n", listen, swap.publicURL)
+	fmt.Printf("Reference: %s\n", swap.opts.Reference)
+	fmt.Printf("==================\n")
+	fmt.Println("Scan the QR code or open the link with Phantom, Solflare or another Solana Pay wallet; it builds, signs and sends the swap.")
+
+	ctx, cancel := context.WithTimeout(ctx, SOLANA_PAY_TIMEOUT)
+	defer cancel()
+	seen := make(map[solana.Signature]bool)
+	ticker := time.NewTicker(SOLANA_PAY_POLL_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("no swap landed within %s", SOLANA_PAY_TIMEOUT)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		sig, account, err := findSolanaPaySwap(ctx, client, swap.opts.Reference, swap.Pool, seen)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			continue
+		}
+		if sig == nil {
+			continue
+		}
+		if sig.Err != nil {
+			fmt.Printf("❌ The swap sent by %s failed: %v\n", account, sig.Err)
+			fmt.Printf("Explorer: %s\n", explorerTxURL(sig.Signature.String()))
+			continue
+		}
+		fmt.Printf("\n✅ Swap signed and sent by %s\n", account)
+		fmt.Printf("Transaction: %s\n", sig.Signature)
+		fmt.Printf("Explorer: %s\n", explorerTxURL(sig.Signature.String()))
+		return nil
+	}
+}
+
+// solanaPayBaseURL checks the URL wallets reach this machine at, usually a
+// tunnel or reverse proxy to the listen address; wallets only fetch https
+func solanaPayBaseURL(publicURL string) (string, error) {
+	base, err := url.Parse(strings.TrimSuffix(publicURL, "/"))
+	if err != nil || base.Scheme != "https" || base.Host == "" {
+		return "", fmt.Errorf("-solana-pay must be an https URL that forwards to -solana-pay-listen, such as a tunnel")
+	}
+	return base.String(), nil
+}
+
+// findSolanaPaySwap looks up the transactions carrying the request's
+// reference for one on the pool, which is a wallet's swap, and returns it
+// with the wallet that signed it. The reference is handed to every wallet
+// that asks, so a transaction not on the pool is skipped.
+func findSolanaPaySwap(ctx context.Context, client ChainClient, reference solana.PublicKey, pool string, seen map[solana.Signature]bool) (*rpc.TransactionSignature, solana.PublicKey, error) {
+	poolKey, err := solana.PublicKeyFromBase58(pool)
+	if err != nil {
+		return nil, solana.PublicKey{}, fmt.Errorf("invalid pool address: %w", err)
+	}
+	limit := 20
+	signatures, err := client.GetSignaturesForAddressWithOpts(ctx, reference, &rpc.GetSignaturesForAddressOpts{Limit: &limit, Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return nil, solana.PublicKey{}, fmt.Errorf("failed to get the reference's signatures: %w", err)
+	}
+	for _, sig := range signatures {
+		if seen[sig.Signature] {
+			continue
+		}
+		tx, err := fetchTransaction(ctx, client, sig.Signature.String())
+		if err != nil {
+			return nil, solana.PublicKey{}, err
+		}
+		seen[sig.Signature] = true
+		parsed, err := tx.Transaction.GetTransaction()
+		if err != nil || len(parsed.Message.AccountKeys) == 0 {
+			continue
+		}
+		keys := append(solana.PublicKeySlice{}, parsed.Message.AccountKeys...)
+		if tx.Meta != nil {
+			keys = append(keys, tx.Meta.LoadedAddresses.Writable...)
+			keys = append(keys, tx.Meta.LoadedAddresses.ReadOnly...)
+		}
+		if keys.Contains(poolKey) {
+			return sig, parsed.Message.AccountKeys[0], nil
+		}
+	}
+	return nil, solana.PublicKey{}, nil
+}
+
+// writeSolanaPayError answers with the message wallets show on failure
+func writeSolanaPayError(w http.ResponseWriter, status int, format string, args ...any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf(format, args...)})
+}
+
+// ServeHTTP answers the transaction request: GET for the label and icon,
+// POST with the wallet's account for the transaction
+func (s *SolanaPaySwap) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Wallets fetch from web views, which need CORS
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.URL.Path == SOLANA_PAY_ICON_PATH {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		fmt.Fprint(w, SOLANA_PAY_ICON)
+		return
+	}
+	if r.URL.Path != s.path {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"label": s.Label, "icon": s.publicURL + SOLANA_PAY_ICON_PATH})
+	case http.MethodPost:
+		var body struct {
+			Account string `json:"account"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeSolanaPayError(w, http.StatusBadRequest, "invalid request: %v", err)
+			return
+		}
+		account, err := solana.PublicKeyFromBase58(body.Account)
+		if err != nil {
+			writeSolanaPayError(w, http.StatusBadRequest, "invalid account: %v", err)
+			return
+		}
+		fmt.Printf("\nBuilding the swap for %s...\n", account)
+		tx, err := buildSwapTransaction(r.Context(), s.client, account, s.Pool, s.Side, s.Amount, s.MinAmountOut, s.opts)
+		if err != nil {
+			fmt.Printf("Warning: Failed to build the swap for %s: %v\n", account, err)
+			writeSolanaPayError(w, http.StatusInternalServerError, "failed to build the swap: %v", err)
+			return
+		}
+		raw, err := marshalUnsigned(tx)
+		if err != nil {
+			writeSolanaPayError(w, http.StatusInternalServerError, "%v", err)
+			return
+		}
+
+		fmt.Printf("Sent the unsigned swap to %s; waiting for it to land...\n", account)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"transaction": base64.StdEncoding.EncodeToString(raw), "message": s.Message})
+	default:
+		writeSolanaPayError(w, http.StatusMethodNotAllowed, "use GET or POST")
+	}
+}
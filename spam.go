@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// SPAM_RPC_URLS_ENV_VAR lists extra RPC endpoints signed transactions are broadcast to
+const SPAM_RPC_URLS_ENV_VAR = "SPAM_RPC_URLS"
+
+const DEFAULT_REBROADCAST_INTERVAL = 500 * time.Millisecond
+
+// SpamConfig broadcasts a sent transaction to extra RPC endpoints and keeps
+// rebroadcasting it until it confirms or expires. Every copy carries the same
+// signature, so the network executes it at most once and confirmation by
+// signature needs no deduplication.
+type SpamConfig struct {
+	Endpoints []string
+	Interval  time.Duration // 0 broadcasts once
+}
+
+// parseEndpointList splits a comma-separated list of URLs, skipping blanks
+func parseEndpointList(list string) []string {
+	var endpoints []string
+	for _, endpoint := range strings.Split(list, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// Enabled reports whether extra endpoints are configured. Replayed fixtures
+// never broadcast.
+func (c SpamConfig) Enabled() bool {
+	return len(c.Endpoints) > 0 && os.Getenv(RPC_REPLAY_ENV_VAR) == ""
+}
+
+// broadcast sends tx to every client concurrently, without preflight or
+// node-side retries, and returns how many accepted it
+func broadcast(ctx context.Context, clients []ChainClient, tx *solana.Transaction) int {
+	maxRetries := uint(0)
+	var accepted atomic.Int32
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Endpoints that already saw the transaction may refuse it; only acceptance counts
+			_, err := client.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{SkipPreflight: true, MaxRetries: &maxRetries})
+			if err == nil {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	return int(accepted.Load())
+}
+
+// spam fans a transaction the primary RPC accepted out to the extra
+// endpoints, then rebroadcasts it to them and to primary every Interval
+// until the returned stop is called or TX_EXPIRY passes
+func (c SpamConfig) spam(ctx context.Context, primary ChainClient, tx *solana.Transaction) (stop func()) {
+	clients := make([]ChainClient, 0, len(c.Endpoints)+1)
+	for _, endpoint := range c.Endpoints {
+		clients = append(clients, rpc.New(endpoint))
+	}
+	accepted := broadcast(ctx, clients, tx)
+	fmt.Printf("Broadcast to %d/%d extra RPC endpoints\n", accepted, len(clients))
+	if c.Interval <= 0 {
+		return func() {}
+	}
+
+	// Keep going through an interrupt while the sender waits for confirmation
+	spamCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), TX_EXPIRY)
+	clients = append(clients, primary)
+	go func() {
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+		rounds := 0
+		for {
+			select {
+			case <-spamCtx.Done():
+				if rounds > 0 {
+					fmt.Printf("Rebroadcast %d times\n", rounds)
+				}
+				return
+			case <-ticker.C:
+				broadcast(spamCtx, clients, tx)
+				rounds++
+			}
+		}
+	}()
+	return cancel
+}
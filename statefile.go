@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// STATE_LOCK_SUFFIX names the lock file kept next to each state file. The
+// state file itself is replaced on every write, so it cannot hold the lock.
+const STATE_LOCK_SUFFIX = ".lock"
+
+// lockFile takes an exclusive lock on path shared by every process using the
+// same state directory, waiting for the holder to release it
+func lockFile(path string) (unlock func(), err error) {
+	f, err := os.OpenFile(path+STATE_LOCK_SUFFIX, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock for %s: %w", path, err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return func() { f.Close() }, nil
+}
+
+// lockStateFile locks the named file in the state directory
+func lockStateFile(name string) (unlock func(), err error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	return lockFile(filepath.Join(dir, name))
+}
+
+// writeFileAtomic replaces path with data through a synced temporary file
+// in the same directory, so a crash leaves the old or the new file, never a
+// truncated one
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// readJSONFile reads the JSON file at path into value, leaving value as is
+// when the file does not exist. Callers sharing the file hold its lock.
+func readJSONFile(path string, value any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeJSONFile atomically replaces the file at path with value as JSON
+func writeJSONFile(path string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// updateJSONFile reads the JSON file at path into value, applies update and
+// replaces the file, holding the file's lock throughout so concurrent
+// processes never overwrite each other's changes. A missing file leaves
+// value as is; when update fails the file is left unchanged.
+func updateJSONFile(path string, value any, update func() error) error {
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := readJSONFile(path, value); err != nil {
+		return err
+	}
+	if err := update(); err != nil {
+		return err
+	}
+	return writeJSONFile(path, value)
+}
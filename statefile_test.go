@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+const (
+	stateHelperEnvVar  = "STATE_FILE_TEST_HELPER"
+	stateHelperUpdates = 50
+)
+
+// TestStateFileHelper increments the counter file when run as a helper
+// process of TestUpdateJSONFileAcrossProcesses
+func TestStateFileHelper(t *testing.T) {
+	path := os.Getenv(stateHelperEnvVar)
+	if path == "" {
+		t.Skip("helper process only")
+	}
+	for range stateHelperUpdates {
+		var counter int
+		if err := updateJSONFile(path, &counter, func() error { counter++; return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestUpdateJSONFileAcrossProcesses(t *testing.T) {
+	const processes = 4
+	path := filepath.Join(t.TempDir(), "counter.json")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, processes)
+	for range processes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestStateFileHelper$")
+			cmd.Env = append(os.Environ(), stateHelperEnvVar+"="+path)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				errs <- fmt.Errorf("helper failed: %v\n%s", err, out)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var counter int
+	if err := json.Unmarshal(data, &counter); err != nil {
+		t.Fatal(err)
+	}
+	if want := processes * stateHelperUpdates; counter != want {
+		t.Errorf("counter = %d after %d updates; updates were lost", counter, want)
+	}
+}
+
+func TestUpdateJSONFileKeepsFileOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	values := map[string]int{"a": 1}
+	if err := updateJSONFile(path, &values, func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	err := updateJSONFile(path, &values, func() error {
+		values["a"] = 2
+		return fmt.Errorf("refused")
+	})
+	if err == nil {
+		t.Fatal("update error not returned")
+	}
+	stored := make(map[string]int)
+	data, _ := os.ReadFile(path)
+	json.Unmarshal(data, &stored)
+	if stored["a"] != 1 {
+		t.Errorf("failed update was written: %v", stored)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	for _, entry := range entries {
+		if entry.Name() != "state.json" && entry.Name() != "state.json"+STATE_LOCK_SUFFIX {
+			t.Errorf("left %s behind", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+// Strategy names accepted by -strategy
+const (
+	STRATEGY_DCA   = "dca"
+	STRATEGY_TWAP  = "twap"
+	STRATEGY_LIMIT = "limit"
+	STRATEGY_STOP  = "stop"
+)
+
+// MarketTick is a pool price observation driving a strategy
+type MarketTick struct {
+	Time  time.Time
+	Price float64 // SOL per token
+}
+
+// Strategy decides when and how much to trade from a stream of pool prices.
+// Amounts are in the input token of the side: SOL for buys, tokens for sells.
+// The same implementations are driven by recorded candles in backtests.
+type Strategy interface {
+	Name() string
+	Side() string
+	// Next returns the amount to trade at the tick, 0 to wait
+	Next(tick MarketTick) float64
+	Done() bool
+}
+
+// DCAStrategy trades a fixed amount every interval until it placed its orders.
+// An order missed during a gap in prices is skipped, not caught up.
+type DCAStrategy struct {
+	side   string
+	amount float64
+	every  time.Duration
+	orders int
+
+	placed int
+	next   time.Time
+}
+
+func (s *DCAStrategy) Name() string { return STRATEGY_DCA }
+func (s *DCAStrategy) Side() string { return s.side }
+func (s *DCAStrategy) Done() bool   { return s.placed >= s.orders }
+
+func (s *DCAStrategy) Next(tick MarketTick) float64 {
+	if s.Done() || tick.Time.Before(s.next) {
+		return 0
+	}
+	s.placed++
+	s.next = tick.Time.Add(s.every)
+	return s.amount
+}
+
+// TWAPStrategy spreads a total amount over a duration in equal slices, starting
+// at the first tick. Slices due during a gap in prices are traded together at
+// the next tick, so the schedule finishes on time.
+type TWAPStrategy struct {
+	side     string
+	total    float64
+	duration time.Duration
+	slices   int
+
+	start  time.Time
+	traded int
+}
+
+func (s *TWAPStrategy) Name() string { return STRATEGY_TWAP }
+func (s *TWAPStrategy) Side() string { return s.side }
+func (s *TWAPStrategy) Done() bool   { return s.traded >= s.slices }
+
+func (s *TWAPStrategy) Next(tick MarketTick) float64 {
+	if s.Done() {
+		return 0
+	}
+	if s.start.IsZero() {
+		s.start = tick.Time
+	}
+	due := s.slices
+	if interval := s.duration / time.Duration(s.slices); interval > 0 {
+		due = min(int(tick.Time.Sub(s.start)/interval)+1, s.slices)
+	}
+	if due <= s.traded {
+		return 0
+	}
+	amount := s.total * float64(due-s.traded) / float64(s.slices)
+	s.traded = due
+	return amount
+}
+
+// LimitStrategy trades its amount once the price reaches the limit: at or
+// below it for buys, at or above it for sells
+type LimitStrategy struct {
+	side   string
+	amount float64
+	limit  float64
+
+	filled bool
+}
+
+func (s *LimitStrategy) Name() string { return STRATEGY_LIMIT }
+func (s *LimitStrategy) Side() string { return s.side }
+func (s *LimitStrategy) Done() bool   { return s.filled }
+
+func (s *LimitStrategy) Next(tick MarketTick) float64 {
+	if s.filled {
+		return 0
+	}
+	if (s.side == "buy" && tick.Price > s.limit) || (s.side == "sell" && tick.Price < s.limit) {
+		return 0
+	}
+	s.filled = true
+	return s.amount
+}
+
+// StopStrategy sells its amount once the price falls to the stop or below
+type StopStrategy struct {
+	amount float64
+	stop   float64
+
+	filled bool
+}
+
+func (s *StopStrategy) Name() string { return STRATEGY_STOP }
+func (s *StopStrategy) Side() string { return "sell" }
+func (s *StopStrategy) Done() bool   { return s.filled }
+
+func (s *StopStrategy) Next(tick MarketTick) float64 {
+	if s.filled || tick.Price > s.stop {
+		return 0
+	}
+	s.filled = true
+	return s.amount
+}
+
+// strategyFlags are the flags configuring a strategy
+type strategyFlags struct {
+	name     string
+	side     string
+	amount   float64
+	every    time.Duration
+	orders   int
+	duration time.Duration
+	slices   int
+	limit    float64
+	stop     float64
+}
+
+// register adds the strategy flags to a flag set
+func (f *strategyFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.name, "strategy", "", "Strategy: dca, twap, limit or stop")
+	fs.StringVar(&f.side, "side", "buy", "Trade side: buy or sell")
+	fs.Float64Var(&f.amount, "amount", 0, "SOL to buy or tokens to sell: per order for dca, in total for twap, once for limit")
+	fs.DurationVar(&f.every, "every", time.Hour, "Time between dca orders")
+	fs.IntVar(&f.orders, "orders", 10, "Number of dca orders")
+	fs.DurationVar(&f.duration, "duration", 24*time.Hour, "Time the twap schedule is spread over")
+	fs.IntVar(&f.slices, "slices", 24, "Number of twap slices")
+	fs.Float64Var(&f.limit, "limit", 0, "Limit price in SOL per token")
+	fs.Float64Var(&f.stop, "stop", 0, "Stop price in SOL per token; stop orders always sell")
+}
+
+// build returns the configured strategy
+func (f *strategyFlags) build() (Strategy, error) {
+	if f.side != "buy" && f.side != "sell" {
+		return nil, fmt.Errorf("-side must be buy or sell")
+	}
+	if f.amount <= 0 {
+		return nil, fmt.Errorf("-amount must be positive")
+	}
+	switch f.name {
+	case STRATEGY_DCA:
+		if f.orders <= 0 || f.every <= 0 {
+			return nil, fmt.Errorf("-orders and -every must be positive")
+		}
+		return &DCAStrategy{side: f.side, amount: f.amount, every: f.every, orders: f.orders}, nil
+	case STRATEGY_TWAP:
+		if f.slices <= 0 || f.duration <= 0 {
+			return nil, fmt.Errorf("-slices and -duration must be positive")
+		}
+		return &TWAPStrategy{side: f.side, total: f.amount, duration: f.duration, slices: f.slices}, nil
+	case STRATEGY_LIMIT:
+		if f.limit <= 0 {
+			return nil, fmt.Errorf("-limit must be positive")
+		}
+		return &LimitStrategy{side: f.side, amount: f.amount, limit: f.limit}, nil
+	case STRATEGY_STOP:
+		if f.side != "sell" {
+			return nil, fmt.Errorf("stop orders sell; use -side sell")
+		}
+		if f.stop <= 0 {
+			return nil, fmt.Errorf("-stop must be positive")
+		}
+		return &StopStrategy{amount: f.amount, stop: f.stop}, nil
+	}
+	return nil, fmt.Errorf("unsupported strategy %q (supported: %s, %s, %s, %s)", f.name, STRATEGY_DCA, STRATEGY_TWAP, STRATEGY_LIMIT, STRATEGY_STOP)
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+// Stream multiplexer settings
+const (
+	STREAM_BUFFER_SIZE   = 64
+	STREAM_RECONNECT_MIN = 1 * time.Second
+	STREAM_RECONNECT_MAX = 30 * time.Second
+)
+
+// StreamUpdate is a notification delivered by the StreamMux
+type StreamUpdate struct {
+	Kind      string // "account" or "logs"
+	Key       solana.PublicKey
+	Slot      uint64
+	Data      []byte           // account data for "account" updates
+	Signature solana.Signature // transaction signature for "logs" updates
+	Logs      []string
+	Failed    bool // the logged transaction failed
+}
+
+// UpdateStream delivers account and transaction log updates. StreamMux
+// implements it over the RPC websocket and GeyserStream over Yellowstone gRPC.
+// Every subscriber gets its own channel and sees every update.
+type UpdateStream interface {
+	SubscribeAccount(key solana.PublicKey) <-chan StreamUpdate
+	SubscribeLogs(key solana.PublicKey) <-chan StreamUpdate
+	Unsubscribe(updates <-chan StreamUpdate)
+	Run(ctx context.Context) error
+	Stats() (reconnects int, dropped map[string]uint64)
+}
+
+// newUpdateStream streams from Geyser when GEYSER_URL is set and from the
+// websocket endpoint otherwise
+func newUpdateStream(wsURL string) UpdateStream {
+	if url := os.Getenv(GEYSER_URL_ENV_VAR); url != "" {
+		return newGeyserStream(url, os.Getenv(GEYSER_TOKEN_ENV_VAR))
+	}
+	return newStreamMux(wsURL)
+}
+
+// streamSub is a registered subscription that survives reconnects. Its
+// updates fan out to the channel of every subscriber.
+type streamSub struct {
+	kind    string
+	key     solana.PublicKey
+	dropped atomic.Uint64
+	done    chan struct{} // closed when the last subscriber leaves
+
+	mu        sync.Mutex
+	listeners []chan StreamUpdate
+}
+
+func newStreamSub(kind string, key solana.PublicKey) *streamSub {
+	return &streamSub{kind: kind, key: key, done: make(chan struct{})}
+}
+
+// listen adds a subscriber and returns its channel
+func (sub *streamSub) listen() <-chan StreamUpdate {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	updates := make(chan StreamUpdate, STREAM_BUFFER_SIZE)
+	sub.listeners = append(sub.listeners, updates)
+	return updates
+}
+
+// remove drops a subscriber's channel, reporting whether it was one of
+// sub's and whether any subscriber is left
+func (sub *streamSub) remove(updates <-chan StreamUpdate) (found bool, empty bool) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	for i, listener := range sub.listeners {
+		if listener == updates {
+			sub.listeners = append(sub.listeners[:i], sub.listeners[i+1:]...)
+			found = true
+			break
+		}
+	}
+	if found && len(sub.listeners) == 0 {
+		close(sub.done)
+	}
+	return found, len(sub.listeners) == 0
+}
+
+// StreamMux multiplexes account and log subscriptions for many watchers over a
+// single websocket connection. Subscriptions are re-established automatically
+// after a reconnect, and slow consumers never block the connection: when a
+// subscriber's buffer is full the oldest update is dropped, since for account
+// state only the latest value matters.
+type StreamMux struct {
+	url string
+
+	mu         sync.Mutex
+	client     *ws.Client
+	generation uint64 // incremented on every (re)connect
+	subs       map[string]*streamSub
+	lost       chan struct{} // closed when the current connection fails
+	reconnects int
+}
+
+// newStreamMux creates a multiplexer for the given websocket endpoint
+func newStreamMux(url string) *StreamMux {
+	return &StreamMux{
+		url:  url,
+		subs: make(map[string]*streamSub),
+	}
+}
+
+// SubscribeAccount registers interest in an account and returns a new update
+// channel. Subscribers of the same account share one subscription.
+func (m *StreamMux) SubscribeAccount(key solana.PublicKey) <-chan StreamUpdate {
+	return m.subscribe("account", key)
+}
+
+// SubscribeLogs registers interest in transaction logs mentioning an address
+func (m *StreamMux) SubscribeLogs(key solana.PublicKey) <-chan StreamUpdate {
+	return m.subscribe("logs", key)
+}
+
+func (m *StreamMux) subscribe(kind string, key solana.PublicKey) <-chan StreamUpdate {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := kind + ":" + key.String()
+	if sub, ok := m.subs[id]; ok {
+		return sub.listen()
+	}
+
+	sub := newStreamSub(kind, key)
+	m.subs[id] = sub
+
+	// Attach immediately when already connected; otherwise Run attaches it on connect
+	if m.client != nil {
+		go m.attach(m.client, m.generation, m.lost, sub)
+	}
+
+	return sub.listen()
+}
+
+// Unsubscribe stops delivering to a channel returned by SubscribeAccount or
+// SubscribeLogs. The subscription ends with its last subscriber.
+func (m *StreamMux) Unsubscribe(updates <-chan StreamUpdate) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	removeListener(m.subs, updates)
+}
+
+// removeListener drops the channel from whichever subscription holds it,
+// and the subscription once nobody listens. It reports whether a
+// subscription ended.
+func removeListener(subs map[string]*streamSub, updates <-chan StreamUpdate) bool {
+	for id, sub := range subs {
+		found, empty := sub.remove(updates)
+		if !found {
+			continue
+		}
+		if empty {
+			delete(subs, id)
+		}
+		return empty
+	}
+	return false
+}
+
+// Stats reports reconnect count and updates dropped due to backpressure per subscription
+func (m *StreamMux) Stats() (reconnects int, dropped map[string]uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dropped = make(map[string]uint64)
+	for id, sub := range m.subs {
+		if n := sub.dropped.Load(); n > 0 {
+			dropped[id] = n
+		}
+	}
+	return m.reconnects, dropped
+}
+
+// Run maintains the connection until ctx is cancelled, reconnecting with
+// exponential backoff and resubscribing everything after each failure
+func (m *StreamMux) Run(ctx context.Context) error {
+	backoff := STREAM_RECONNECT_MIN
+
+	for {
+		client, err := ws.Connect(ctx, m.url)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Printf("Warning: websocket connect failed: %v (retrying in %s)\n", err, backoff)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, STREAM_RECONNECT_MAX)
+			continue
+		}
+		backoff = STREAM_RECONNECT_MIN
+
+		lost := make(chan struct{})
+
+		m.mu.Lock()
+		if m.generation > 0 {
+			m.reconnects++
+		}
+		m.generation++
+		generation := m.generation
+		m.client = client
+		m.lost = lost
+		for _, sub := range m.subs {
+			go m.attach(client, generation, lost, sub)
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			client.Close()
+			return ctx.Err()
+		case <-lost:
+			fmt.Println("Warning: websocket connection lost, reconnecting...")
+		}
+
+		m.mu.Lock()
+		m.client = nil
+		m.mu.Unlock()
+		client.Close()
+	}
+}
+
+// connectionLost signals the Run loop once per connection generation
+func (m *StreamMux) connectionLost(generation uint64, lost chan struct{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if generation == m.generation {
+		select {
+		case <-lost:
+		default:
+			close(lost)
+		}
+	}
+}
+
+// deliver hands an update to every subscriber without ever blocking the reader
+func (sub *streamSub) deliver(update StreamUpdate) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	for _, updates := range sub.listeners {
+		sub.deliverTo(updates, update)
+	}
+}
+
+func (sub *streamSub) deliverTo(updates chan StreamUpdate, update StreamUpdate) {
+	for {
+		select {
+		case updates <- update:
+			return
+		default:
+		}
+
+		// Buffer full: drop the oldest update to make room
+		select {
+		case <-updates:
+			sub.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+// attach subscribes on the given connection and pumps notifications until it fails
+func (m *StreamMux) attach(client *ws.Client, generation uint64, lost chan struct{}, sub *streamSub) {
+	// Stop waiting for notifications as soon as any subscription reports the connection lost
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-lost:
+			cancel()
+		case <-sub.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	// An unsubscribed subscription ends without taking the connection down
+	connectionLost := func() {
+		select {
+		case <-sub.done:
+		default:
+			m.connectionLost(generation, lost)
+		}
+	}
+
+	switch sub.kind {
+	case "account":
+		subscription, err := client.AccountSubscribe(sub.key, rpc.CommitmentConfirmed)
+		if err != nil {
+			connectionLost()
+			return
+		}
+		defer subscription.Unsubscribe()
+
+		for {
+			result, err := subscription.Recv(ctx)
+			if err != nil {
+				connectionLost()
+				return
+			}
+			sub.deliver(StreamUpdate{
+				Kind: "account",
+				Key:  sub.key,
+				Slot: result.Context.Slot,
+				Data: result.Value.Data.GetBinary(),
+			})
+		}
+
+	case "logs":
+		subscription, err := client.LogsSubscribeMentions(sub.key, rpc.CommitmentConfirmed)
+		if err != nil {
+			connectionLost()
+			return
+		}
+		defer subscription.Unsubscribe()
+
+		for {
+			result, err := subscription.Recv(ctx)
+			if err != nil {
+				connectionLost()
+				return
+			}
+			sub.deliver(StreamUpdate{
+				Kind:      "logs",
+				Key:       sub.key,
+				Slot:      result.Context.Slot,
+				Signature: result.Value.Signature,
+				Logs:      result.Value.Logs,
+				Failed:    result.Value.Err != nil,
+			})
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestStreamMuxFanOut(t *testing.T) {
+	vault := solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qNrmqrfWwzkQfD6h2t9P1aHA8v")
+	mux := newStreamMux("ws://127.0.0.1:0")
+	first := mux.SubscribeAccount(vault)
+	second := mux.SubscribeAccount(vault)
+	if first == second {
+		t.Fatal("subscribers share a channel")
+	}
+	if len(mux.subs) != 1 {
+		t.Fatalf("%d subscriptions for one account, want 1", len(mux.subs))
+	}
+	sub := mux.subs["account:"+vault.String()]
+
+	sub.deliver(StreamUpdate{Kind: "account", Key: vault, Slot: 7})
+	for i, updates := range []<-chan StreamUpdate{first, second} {
+		select {
+		case u := <-updates:
+			if u.Slot != 7 {
+				t.Errorf("subscriber %d got slot %d, want 7", i, u.Slot)
+			}
+		default:
+			t.Errorf("subscriber %d got no update", i)
+		}
+	}
+
+	mux.Unsubscribe(first)
+	sub.deliver(StreamUpdate{Kind: "account", Key: vault, Slot: 8})
+	select {
+	case u := <-first:
+		t.Errorf("unsubscribed channel got slot %d", u.Slot)
+	default:
+	}
+	if u := <-second; u.Slot != 8 {
+		t.Errorf("remaining subscriber got slot %d, want 8", u.Slot)
+	}
+
+	mux.Unsubscribe(second)
+	if len(mux.subs) != 0 {
+		t.Errorf("subscription kept after its last subscriber left")
+	}
+	select {
+	case <-sub.done:
+	default:
+		t.Errorf("subscription not stopped after its last subscriber left")
+	}
+}
+
+func TestStreamSubDropsOldest(t *testing.T) {
+	sub := newStreamSub("account", solana.PublicKey{})
+	updates := sub.listen()
+	for slot := uint64(1); slot <= STREAM_BUFFER_SIZE+2; slot++ {
+		sub.deliver(StreamUpdate{Slot: slot})
+	}
+	if got := sub.dropped.Load(); got != 2 {
+		t.Errorf("dropped %d updates, want 2", got)
+	}
+	if u := <-updates; u.Slot != 3 {
+		t.Errorf("oldest kept update is slot %d, want 3", u.Slot)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+)
+
+// RAYDIUM_SWAP_DATA_LEN is the swap instruction's data: the discriminator,
+// the amount in and the minimum out
+const RAYDIUM_SWAP_DATA_LEN = 17
+
+// swapAccountSpec is what the Raydium V4 program requires of one account of
+// its swap instruction
+type swapAccountSpec struct {
+	Name     string
+	Writable bool
+	Signer   bool
+}
+
+// raydiumSwapAccounts is the 18-account layout of the swap instruction. The
+// program also accepts 17 accounts without the AMM target orders, which it
+// no longer reads.
+var raydiumSwapAccounts = []swapAccountSpec{
+	{Name: "token program"},
+	{Name: "amm", Writable: true},
+	{Name: "amm authority"},
+	{Name: "amm open orders", Writable: true},
+	{Name: "amm target orders", Writable: true},
+	{Name: "pool base vault", Writable: true},
+	{Name: "pool quote vault", Writable: true},
+	{Name: "market program"},
+	{Name: "market", Writable: true},
+	{Name: "market bids", Writable: true},
+	{Name: "market asks", Writable: true},
+	{Name: "market event queue", Writable: true},
+	{Name: "market base vault", Writable: true},
+	{Name: "market quote vault", Writable: true},
+	{Name: "market vault signer"},
+	{Name: "user source", Writable: true},
+	{Name: "user destination", Writable: true},
+	{Name: "user owner", Signer: true},
+}
+
+// RAYDIUM_SWAP_TARGET_ORDERS_INDEX is the account the 17-account layout leaves out
+const RAYDIUM_SWAP_TARGET_ORDERS_INDEX = 4
+
+// validateSwapInstruction checks a Raydium V4 swap instruction against the
+// program's requirements before it is sent: the data layout, the account
+// count, every account's writable and signer flags, and the pool and market
+// accounts it must pass. Account-order mistakes otherwise only surface as
+// opaque on-chain errors.
+func validateSwapInstruction(ix solana.Instruction, pool *OnChainPool, owner solana.PublicKey) error {
+	if !ix.ProgramID().Equals(RAYDIUM_AMM_V4) {
+		return fmt.Errorf("program is %s, not Raydium AMM V4", ix.ProgramID())
+	}
+	data, err := ix.Data()
+	if err != nil {
+		return fmt.Errorf("failed to encode instruction data: %w", err)
+	}
+	if len(data) != RAYDIUM_SWAP_DATA_LEN || data[0] != RAYDIUM_SWAP_INSTRUCTION {
+		return fmt.Errorf("instruction data %x is not a %d-byte swap (instruction %d)", data, RAYDIUM_SWAP_DATA_LEN, RAYDIUM_SWAP_INSTRUCTION)
+	}
+
+	specs := raydiumSwapAccounts
+	accounts := ix.Accounts()
+	switch len(accounts) {
+	case len(raydiumSwapAccounts):
+	case len(raydiumSwapAccounts) - 1:
+		specs = append(append([]swapAccountSpec{}, raydiumSwapAccounts[:RAYDIUM_SWAP_TARGET_ORDERS_INDEX]...), raydiumSwapAccounts[RAYDIUM_SWAP_TARGET_ORDERS_INDEX+1:]...)
+	default:
+		return fmt.Errorf("%d accounts; the swap takes %d, or %d without the target orders", len(accounts), len(raydiumSwapAccounts), len(raydiumSwapAccounts)-1)
+	}
+
+	vaultSigner, err := deriveMarketVaultSigner(pool)
+	if err != nil {
+		return err
+	}
+	expected := map[string]solana.PublicKey{
+		"token program":       token.ProgramID,
+		"amm":                 pool.Address,
+		"amm authority":       pool.Authority,
+		"amm open orders":     pool.OpenOrders,
+		"amm target orders":   pool.TargetOrders,
+		"pool base vault":     pool.BaseVault,
+		"pool quote vault":    pool.QuoteVault,
+		"market program":      pool.MarketProgram,
+		"market":              pool.Market,
+		"market bids":         pool.MarketBids,
+		"market asks":         pool.MarketAsks,
+		"market event queue":  pool.MarketEventQueue,
+		"market base vault":   pool.MarketBaseVault,
+		"market quote vault":  pool.MarketQuoteVault,
+		"market vault signer": vaultSigner,
+		"user owner":          owner,
+	}
+
+	for i, spec := range specs {
+		account := accounts[i]
+		if want, ok := expected[spec.Name]; ok && !account.PublicKey.Equals(want) {
+			return fmt.Errorf("account %d (%s) is %s, want %s", i, spec.Name, account.PublicKey, want)
+		}
+		if spec.Writable && !account.IsWritable {
+			return fmt.Errorf("account %d (%s) must be writable", i, spec.Name)
+		}
+		if spec.Signer != account.IsSigner {
+			if spec.Signer {
+				return fmt.Errorf("account %d (%s) must sign", i, spec.Name)
+			}
+			return fmt.Errorf("account %d (%s) must not sign", i, spec.Name)
+		}
+	}
+
+	// The user's token accounts are the two before the owner
+	source, destination := accounts[len(accounts)-3].PublicKey, accounts[len(accounts)-2].PublicKey
+	if source.IsZero() || destination.IsZero() {
+		return fmt.Errorf("a user token account is unset")
+	}
+	if source.Equals(destination) {
+		return fmt.Errorf("user source and destination are the same account %s", source)
+	}
+	for _, vault := range []solana.PublicKey{pool.BaseVault, pool.QuoteVault} {
+		if source.Equals(vault) || destination.Equals(vault) {
+			return fmt.Errorf("a user token account is the pool vault %s", vault)
+		}
+	}
+	return nil
+}
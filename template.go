@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+)
+
+// System program nonce account layout: version(u32) + state(u32) +
+// authority(32) + blockhash(32) + fee calculator(u64)
+const (
+	NONCE_ACCOUNT_SIZE       = 80
+	NONCE_AUTHORITY_OFFSET   = 8
+	NONCE_BLOCKHASH_OFFSET   = 40
+	SYSTEM_TRANSFER_DATA_LEN = 12 // instruction(u32) + lamports(u64)
+)
+
+// SwapTemplate is a swap transaction built ahead of time against a durable
+// nonce. Durable nonce transactions never expire, so everything except the
+// amounts is settled up front; at trigger time Fire patches amount and
+// minimum out in place, re-signs and sends, with no RPC call but the send.
+// Sending advances the nonce, so a template fires once.
+type SwapTemplate struct {
+	Pool           string `json:"pool"`
+	Side           string `json:"side"`
+	NonceAccount   string `json:"nonce_account"`
+	InputDecimals  int    `json:"input_decimals"`
+	OutputDecimals int    `json:"output_decimals"`
+	SwapIndex      int    `json:"swap_index"`     // instruction holding amount_in and minimum_out
+	TransferIndex  int    `json:"transfer_index"` // SOL wrap transfer, -1 when selling
+	Transaction    string `json:"transaction"`    // unsigned, base64
+
+	tx *solana.Transaction
+}
+
+// readNonce returns the stored blockhash and authority of a nonce account
+func readNonce(ctx context.Context, client ChainClient, nonceAccount solana.PublicKey) (solana.Hash, solana.PublicKey, error) {
+	info, err := client.GetAccountInfo(ctx, nonceAccount)
+	if err != nil {
+		return solana.Hash{}, solana.PublicKey{}, fmt.Errorf("failed to get nonce account: %w", err)
+	}
+	data := info.Value.Data.GetBinary()
+	if !info.Value.Owner.Equals(solana.SystemProgramID) || len(data) < NONCE_ACCOUNT_SIZE {
+		return solana.Hash{}, solana.PublicKey{}, fmt.Errorf("%s is not a nonce account", nonceAccount)
+	}
+	authority := solana.PublicKeyFromBytes(data[NONCE_AUTHORITY_OFFSET : NONCE_AUTHORITY_OFFSET+32])
+	return solana.HashFromBytes(data[NONCE_BLOCKHASH_OFFSET : NONCE_BLOCKHASH_OFFSET+32]), authority, nil
+}
+
+// buildSwapTemplate builds a swap on a durable nonce with placeholder amounts.
+// The wallet must be the nonce authority.
+func buildSwapTemplate(ctx context.Context, client ChainClient, owner solana.PublicKey, poolAddress string, side string, nonceAccount solana.PublicKey, opts BuildOptions) (*SwapTemplate, error) {
+	nonce, authority, err := readNonce(ctx, client, nonceAccount)
+	if err != nil {
+		return nil, err
+	}
+	if !authority.Equals(owner) {
+		return nil, fmt.Errorf("nonce authority is %s, not the wallet %s", authority, owner)
+	}
+
+	pool, err := loadPool(ctx, client, poolAddress)
+	if err != nil {
+		return nil, err
+	}
+	if err := fetchMarketData(ctx, client, pool); err != nil {
+		return nil, fmt.Errorf("failed to fetch market data: %w", err)
+	}
+
+	sourceMint, destinationMint, inputDecimals := swapMints(pool, side)
+	var accounts swapAccounts
+	accounts.Source, accounts.CreateSource, err = getOrCreateATA(ctx, client, owner, sourceMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source ATA: %w", err)
+	}
+	accounts.Destination, accounts.CreateDestination, err = getOrCreateATA(ctx, client, owner, destinationMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get destination ATA: %w", err)
+	}
+
+	// Patch only rewrites the swap and SOL transfer amounts
+	if opts.Burn.Enabled() && side == "buy" {
+		return nil, fmt.Errorf("swap templates do not support -burn-pct")
+	}
+
+	// Amounts are placeholders until Patch
+	swapIxs, err := swapInstructions(pool, owner, side, 0, 0, accounts, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Advancing the nonce must be the first instruction
+	instructions := []solana.Instruction{
+		system.NewAdvanceNonceAccountInstruction(nonceAccount, solana.SysVarRecentBlockHashesPubkey, owner).Build(),
+	}
+	instructions = append(instructions, swapIxs...)
+
+	template := &SwapTemplate{
+		Pool:           poolAddress,
+		Side:           side,
+		NonceAccount:   nonceAccount.String(),
+		InputDecimals:  inputDecimals,
+		OutputDecimals: int(pool.BaseDecimals),
+		SwapIndex:      -1,
+		TransferIndex:  -1,
+	}
+	if destinationMint.Equals(pool.QuoteMint) {
+		template.OutputDecimals = int(pool.QuoteDecimals)
+	}
+	for i, ix := range instructions {
+		if ix.ProgramID().Equals(RAYDIUM_AMM_V4) {
+			template.SwapIndex = i
+		}
+		if ix.ProgramID().Equals(solana.SystemProgramID) && i > 0 {
+			template.TransferIndex = i
+		}
+	}
+	if template.SwapIndex < 0 {
+		return nil, fmt.Errorf("template has no swap instruction")
+	}
+
+	template.tx, err = solana.NewTransaction(instructions, nonce, solana.TransactionPayer(owner))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	template.Transaction, err = template.tx.ToBase64()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize transaction: %w", err)
+	}
+	return template, nil
+}
+
+// loadSwapTemplate reads a template file written by "template build"
+func loadSwapTemplate(path string) (*SwapTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	var template SwapTemplate
+	if err := json.Unmarshal(data, &template); err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	template.tx, err = decodeTransaction(template.Transaction)
+	if err != nil {
+		return nil, err
+	}
+	if template.SwapIndex < 0 || template.SwapIndex >= len(template.tx.Message.Instructions) {
+		return nil, fmt.Errorf("template swap index %d out of range", template.SwapIndex)
+	}
+	return &template, nil
+}
+
+// Patch writes the raw input amount and minimum output into the template
+func (t *SwapTemplate) Patch(amountIn uint64, minAmountOut uint64) error {
+	// Raydium swap data: instruction(u8) + amount_in(u64) + minimum_out(u64)
+	swap := t.tx.Message.Instructions[t.SwapIndex].Data
+	if len(swap) < 17 {
+		return fmt.Errorf("unexpected swap instruction data length %d", len(swap))
+	}
+	binary.LittleEndian.PutUint64(swap[1:9], amountIn)
+	binary.LittleEndian.PutUint64(swap[9:17], minAmountOut)
+
+	// Buys wrap exactly the input amount of SOL
+	if t.TransferIndex >= 0 {
+		transfer := t.tx.Message.Instructions[t.TransferIndex].Data
+		if len(transfer) != SYSTEM_TRANSFER_DATA_LEN {
+			return fmt.Errorf("unexpected transfer instruction data length %d", len(transfer))
+		}
+		binary.LittleEndian.PutUint64(transfer[4:12], amountIn)
+	}
+	return nil
+}
+
+// Fire patches the amounts, signs and sends the template without preflight
+func (t *SwapTemplate) Fire(ctx context.Context, client ChainClient, wallet solana.PrivateKey, amountIn float64, minAmountOut float64) (solana.Signature, error) {
+	amountInRaw, err := atoms.FromAmount(amountIn, t.InputDecimals)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	minOutRaw, err := atoms.FromAmount(minAmountOut, t.OutputDecimals)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	if err := t.Patch(amountInRaw, minOutRaw); err != nil {
+		return solana.Signature{}, err
+	}
+
+	t.tx.Signatures = nil
+	if err := signTransaction(t.tx, wallet); err != nil {
+		return solana.Signature{}, err
+	}
+	sig, err := client.SendTransactionWithOpts(ctx, t.tx, rpc.TransactionOpts{SkipPreflight: true})
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to send transaction: %w", err)
+	}
+	return sig, nil
+}
+
+// createNonceAccount creates a durable nonce account with the wallet as authority
+func createNonceAccount(ctx context.Context, client ChainClient, wallet solana.PrivateKey, opts BuildOptions) (solana.PublicKey, solana.Signature, error) {
+	nonceKey, err := solana.NewRandomPrivateKey()
+	if err != nil {
+		return solana.PublicKey{}, solana.Signature{}, err
+	}
+	rent, err := client.GetMinimumBalanceForRentExemption(ctx, NONCE_ACCOUNT_SIZE, rpc.CommitmentConfirmed)
+	if err != nil {
+		return solana.PublicKey{}, solana.Signature{}, fmt.Errorf("failed to get rent exemption: %w", err)
+	}
+	latest, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return solana.PublicKey{}, solana.Signature{}, fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+
+	owner := wallet.PublicKey()
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewCreateAccountInstruction(rent, NONCE_ACCOUNT_SIZE, solana.SystemProgramID, owner, nonceKey.PublicKey()).Build(),
+		system.NewInitializeNonceAccountInstruction(owner, nonceKey.PublicKey(), solana.SysVarRecentBlockHashesPubkey, solana.SysVarRentPubkey).Build(),
+	}, latest.Value.Blockhash, solana.TransactionPayer(owner))
+	if err != nil {
+		return solana.PublicKey{}, solana.Signature{}, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	if err := signTransaction(tx, wallet, nonceKey); err != nil {
+		return solana.PublicKey{}, solana.Signature{}, err
+	}
+	sig, err := sendAndConfirmTransaction(ctx, client, tx, opts)
+	if err != nil {
+		return solana.PublicKey{}, solana.Signature{}, err
+	}
+	return nonceKey.PublicKey(), sig, nil
+}
+
+// runTemplate handles "template nonce|build|fire"
+func runTemplate(args []string, opts BuildOptions) {
+	if len(args) == 0 {
+		fmt.Println("Usage: go run . template nonce | build -pool POOL -side buy|sell -nonce NONCE_ACCOUNT [-out FILE] | fire -file FILE -amount AMOUNT -min-out AMOUNT")
+		os.Exit(1)
+	}
+
+	wallet, err := loadWallet()
+	if err != nil {
+		log.Fatalf("%s is required: %v", PRIVATE_KEY_ENV_VAR, err)
+	}
+	ctx := interruptContext()
+	client := newChainClient()
+
+	switch args[0] {
+	case "nonce":
+		nonceAccount, sig, err := createNonceAccount(ctx, client, wallet, opts)
+		if err != nil {
+			log.Fatalf("Failed to create nonce account: %v", err)
+		}
+		fmt.Printf("Nonce account: %s\n", nonceAccount)
+		fmt.Printf("Explorer: %s\n", explorerTxURL(sig.String()))
+
+	case "build":
+		fs := newCommandFlagSet("template build")
+		poolAddr := fs.String("pool", "", "Pool address")
+		side := fs.String("side", "", "buy or sell")
+		nonceAddr := fs.String("nonce", "", "Durable nonce account (create one with 'template nonce')")
+		out := fs.String("out", "swap-template.json", "Template file to write")
+		fs.Parse(args[1:])
+
+		if *poolAddr == "" || *nonceAddr == "" || (*side != "buy" && *side != "sell") {
+			log.Fatal("-pool, -nonce and -side buy|sell are required")
+		}
+		nonceAccount, err := solana.PublicKeyFromBase58(*nonceAddr)
+		if err != nil {
+			log.Fatalf("Invalid nonce account: %v", err)
+		}
+
+		template, err := buildSwapTemplate(ctx, client, wallet.PublicKey(), *poolAddr, *side, nonceAccount, opts)
+		if err != nil {
+			log.Fatalf("Failed to build template: %v", err)
+		}
+		data, err := json.MarshalIndent(template, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode template: %v", err)
+		}
+		if err := os.WriteFile(*out, data, 0600); err != nil {
+			log.Fatalf("Failed to write template: %v", err)
+		}
+		fmt.Printf("Template written to %s\n", *out)
+
+	case "fire":
+		fs := newCommandFlagSet("template fire")
+		file := fs.String("file", "swap-template.json", "Template file written by 'template build'")
+		amount := fs.Float64("amount", 0, "Amount to swap")
+		minOut := fs.Float64("min-out", 0, "Minimum amount out; the swap fails below it")
+		fs.Parse(args[1:])
+
+		if *amount <= 0 || *minOut <= 0 {
+			log.Fatal("-amount and -min-out are required")
+		}
+		template, err := loadSwapTemplate(*file)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		start := time.Now()
+		sig, err := template.Fire(ctx, client, wallet, *amount, *minOut)
+		if err != nil {
+			log.Fatalf("Failed to fire template: %v", err)
+		}
+		fmt.Printf("Sent %s in %s\n", sig, time.Since(start).Round(time.Microsecond))
+		fmt.Printf("Explorer: %s\n", explorerTxURL(sig.String()))
+		fmt.Println("The nonce has advanced; rebuild the template before firing again.")
+
+	default:
+		log.Fatalf("Unknown template command %q (available: nonce, build, fire)", args[0])
+	}
+}
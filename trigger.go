@@ -0,0 +1,426 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Trigger is a compiled trigger condition such as
+// `price < 0.9*ema(5m) && liquidity_sol > 50`.
+//
+// The language supports numbers, percentages (30% == 0.3), duration literals
+// (30s, 5m, 1h), variables, function calls, arithmetic (+ - * /), comparisons
+// (< <= > >= == !=), logical operators (&& || !) and parentheses.
+type Trigger struct {
+	Source string
+	root   triggerNode
+}
+
+// TriggerEnv supplies variables and functions (typically indicators) to a trigger
+type TriggerEnv interface {
+	Var(name string) (float64, bool)
+	Call(name string, args []TriggerValue) (float64, error)
+}
+
+// TriggerValue is a runtime value: a number, a boolean or a duration
+type TriggerValue struct {
+	Kind     string // "number", "bool" or "duration"
+	Number   float64
+	Bool     bool
+	Duration time.Duration
+}
+
+type triggerNode interface {
+	eval(env TriggerEnv) (TriggerValue, error)
+}
+
+// compileTrigger parses a trigger expression
+func compileTrigger(source string) (*Trigger, error) {
+	tokens, err := lexTrigger(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &triggerParser{tokens: tokens}
+	root, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != "eof" {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+
+	return &Trigger{Source: source, root: root}, nil
+}
+
+// Eval evaluates the trigger and reports whether it fired
+func (t *Trigger) Eval(env TriggerEnv) (bool, error) {
+	value, err := t.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	if value.Kind != "bool" {
+		return false, fmt.Errorf("trigger %q does not evaluate to a condition", t.Source)
+	}
+	return value.Bool, nil
+}
+
+// mapTriggerEnv is a TriggerEnv backed by plain maps
+type mapTriggerEnv struct {
+	vars  map[string]float64
+	funcs map[string]func(args []TriggerValue) (float64, error)
+}
+
+func (e mapTriggerEnv) Var(name string) (float64, bool) {
+	value, ok := e.vars[name]
+	return value, ok
+}
+
+func (e mapTriggerEnv) Call(name string, args []TriggerValue) (float64, error) {
+	fn, ok := e.funcs[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown function %s()", name)
+	}
+	return fn(args)
+}
+
+// Lexer
+
+type triggerToken struct {
+	kind string // "number", "percent", "duration", "ident", "op", "(", ")", ",", "eof"
+	text string
+	pos  int
+}
+
+func lexTrigger(source string) ([]triggerToken, error) {
+	var tokens []triggerToken
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			// A trailing unit turns the number into a duration literal (5m, 30s, 1h)
+			unitStart := i
+			for i < len(runes) && unicode.IsLetter(runes[i]) {
+				i++
+			}
+			if unitStart == i && i < len(runes) && runes[i] == '%' {
+				// Percent literals are stored as fractions (30% == 0.3)
+				i++
+				tokens = append(tokens, triggerToken{kind: "percent", text: string(runes[start : i-1]), pos: start})
+			} else if unitStart == i {
+				tokens = append(tokens, triggerToken{kind: "number", text: string(runes[start:i]), pos: start})
+			} else {
+				tokens = append(tokens, triggerToken{kind: "duration", text: string(runes[start:i]), pos: start})
+			}
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, triggerToken{kind: "ident", text: string(runes[start:i]), pos: start})
+		case r == '(' || r == ')' || r == ',':
+			tokens = append(tokens, triggerToken{kind: string(r), text: string(r), pos: i})
+			i++
+		default:
+			start := i
+			two := ""
+			if i+1 < len(runes) {
+				two = string(runes[i : i+2])
+			}
+			switch two {
+			case "&&", "||", "<=", ">=", "==", "!=":
+				tokens = append(tokens, triggerToken{kind: "op", text: two, pos: start})
+				i += 2
+				continue
+			}
+			if strings.ContainsRune("+-*/<>!", r) {
+				tokens = append(tokens, triggerToken{kind: "op", text: string(r), pos: start})
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+		}
+	}
+
+	return append(tokens, triggerToken{kind: "eof", pos: len(runes)}), nil
+}
+
+// Parser (precedence climbing)
+
+var triggerPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6,
+}
+
+type triggerParser struct {
+	tokens []triggerToken
+	pos    int
+}
+
+func (p *triggerParser) peek() triggerToken {
+	return p.tokens[p.pos]
+}
+
+func (p *triggerParser) next() triggerToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != "eof" {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *triggerParser) parseExpr(minPrecedence int) (triggerNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		precedence, ok := triggerPrecedence[tok.text]
+		if tok.kind != "op" || !ok || precedence <= minPrecedence {
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.parseExpr(precedence)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryTriggerNode{op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *triggerParser) parseUnary() (triggerNode, error) {
+	tok := p.peek()
+	if tok.kind == "op" && (tok.text == "!" || tok.text == "-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryTriggerNode{op: tok.text, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *triggerParser) parsePrimary() (triggerNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case "number":
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return literalTriggerNode{value: TriggerValue{Kind: "number", Number: value}}, nil
+
+	case "percent":
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid percentage %q at position %d", tok.text, tok.pos)
+		}
+		return literalTriggerNode{value: TriggerValue{Kind: "number", Number: value / 100}}, nil
+
+	case "duration":
+		value, err := time.ParseDuration(tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration %q at position %d", tok.text, tok.pos)
+		}
+		return literalTriggerNode{value: TriggerValue{Kind: "duration", Duration: value}}, nil
+
+	case "ident":
+		switch tok.text {
+		case "true", "false":
+			return literalTriggerNode{value: TriggerValue{Kind: "bool", Bool: tok.text == "true"}}, nil
+		}
+		if p.peek().kind != "(" {
+			return varTriggerNode{name: tok.text}, nil
+		}
+		p.next()
+
+		var args []triggerNode
+		if p.peek().kind != ")" {
+			for {
+				arg, err := p.parseExpr(0)
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+				if p.peek().kind != "," {
+					break
+				}
+				p.next()
+			}
+		}
+		if closing := p.next(); closing.kind != ")" {
+			return nil, fmt.Errorf("expected ')' at position %d", closing.pos)
+		}
+		return &callTriggerNode{name: tok.text, args: args}, nil
+
+	case "(":
+		inner, err := p.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != ")" {
+			return nil, fmt.Errorf("expected ')' at position %d", closing.pos)
+		}
+		return inner, nil
+
+	case "eof":
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+// AST nodes
+
+type literalTriggerNode struct {
+	value TriggerValue
+}
+
+func (n literalTriggerNode) eval(env TriggerEnv) (TriggerValue, error) {
+	return n.value, nil
+}
+
+type varTriggerNode struct {
+	name string
+}
+
+func (n varTriggerNode) eval(env TriggerEnv) (TriggerValue, error) {
+	value, ok := env.Var(n.name)
+	if !ok {
+		return TriggerValue{}, fmt.Errorf("unknown variable %s", n.name)
+	}
+	return TriggerValue{Kind: "number", Number: value}, nil
+}
+
+type callTriggerNode struct {
+	name string
+	args []triggerNode
+}
+
+func (n *callTriggerNode) eval(env TriggerEnv) (TriggerValue, error) {
+	args := make([]TriggerValue, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(env)
+		if err != nil {
+			return TriggerValue{}, err
+		}
+		args[i] = value
+	}
+
+	result, err := env.Call(n.name, args)
+	if err != nil {
+		return TriggerValue{}, fmt.Errorf("%s(): %w", n.name, err)
+	}
+	return TriggerValue{Kind: "number", Number: result}, nil
+}
+
+type unaryTriggerNode struct {
+	op      string
+	operand triggerNode
+}
+
+func (n *unaryTriggerNode) eval(env TriggerEnv) (TriggerValue, error) {
+	value, err := n.operand.eval(env)
+	if err != nil {
+		return TriggerValue{}, err
+	}
+
+	if n.op == "!" {
+		if value.Kind != "bool" {
+			return TriggerValue{}, fmt.Errorf("operator ! requires a condition")
+		}
+		return TriggerValue{Kind: "bool", Bool: !value.Bool}, nil
+	}
+
+	if value.Kind != "number" {
+		return TriggerValue{}, fmt.Errorf("operator - requires a number")
+	}
+	return TriggerValue{Kind: "number", Number: -value.Number}, nil
+}
+
+type binaryTriggerNode struct {
+	op    string
+	left  triggerNode
+	right triggerNode
+}
+
+func (n *binaryTriggerNode) eval(env TriggerEnv) (TriggerValue, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return TriggerValue{}, err
+	}
+
+	// Short-circuit logical operators so guarded indicators aren't evaluated needlessly
+	if n.op == "&&" || n.op == "||" {
+		if left.Kind != "bool" {
+			return TriggerValue{}, fmt.Errorf("operator %s requires conditions", n.op)
+		}
+		if (n.op == "&&" && !left.Bool) || (n.op == "||" && left.Bool) {
+			return left, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return TriggerValue{}, err
+		}
+		if right.Kind != "bool" {
+			return TriggerValue{}, fmt.Errorf("operator %s requires conditions", n.op)
+		}
+		return right, nil
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil {
+		return TriggerValue{}, err
+	}
+	if left.Kind != "number" || right.Kind != "number" {
+		return TriggerValue{}, fmt.Errorf("operator %s requires numbers", n.op)
+	}
+
+	a, b := left.Number, right.Number
+	switch n.op {
+	case "+":
+		return TriggerValue{Kind: "number", Number: a + b}, nil
+	case "-":
+		return TriggerValue{Kind: "number", Number: a - b}, nil
+	case "*":
+		return TriggerValue{Kind: "number", Number: a * b}, nil
+	case "/":
+		if b == 0 {
+			return TriggerValue{}, fmt.Errorf("division by zero")
+		}
+		return TriggerValue{Kind: "number", Number: a / b}, nil
+	case "<":
+		return TriggerValue{Kind: "bool", Bool: a < b}, nil
+	case "<=":
+		return TriggerValue{Kind: "bool", Bool: a <= b}, nil
+	case ">":
+		return TriggerValue{Kind: "bool", Bool: a > b}, nil
+	case ">=":
+		return TriggerValue{Kind: "bool", Bool: a >= b}, nil
+	case "==":
+		return TriggerValue{Kind: "bool", Bool: a == b}, nil
+	case "!=":
+		return TriggerValue{Kind: "bool", Bool: a != b}, nil
+	}
+
+	return TriggerValue{}, fmt.Errorf("unknown operator %s", n.op)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTriggerEnv prices at 0.8 against a 5m EMA of 1 with 60 SOL of liquidity
+var testTriggerEnv = mapTriggerEnv{
+	vars: map[string]float64{"price": 0.8, "liquidity_sol": 60},
+	funcs: map[string]func(args []TriggerValue) (float64, error){
+		"ema": func(args []TriggerValue) (float64, error) {
+			if len(args) != 1 || args[0].Kind != "duration" {
+				return 0, fmt.Errorf("expects a window")
+			}
+			if args[0].Duration != 5*time.Minute {
+				return 0, fmt.Errorf("no %s window", args[0].Duration)
+			}
+			return 1, nil
+		},
+		"max": func(args []TriggerValue) (float64, error) {
+			if len(args) != 2 {
+				return 0, fmt.Errorf("expects 2 numbers")
+			}
+			return max(args[0].Number, args[1].Number), nil
+		},
+	},
+}
+
+func TestLexTrigger(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    []string // kind:text
+		wantErr string
+	}{
+		{"number and comparison", "price<=0.5", []string{"ident:price", "op:<=", "number:0.5"}, ""},
+		{"percent", "30%", []string{"percent:30"}, ""},
+		{"duration", "ema(5m)", []string{"ident:ema", "(:(", "duration:5m", "):)"}, ""},
+		{"two-character operators", "a&&b||!c!=d==e>=f", []string{
+			"ident:a", "op:&&", "ident:b", "op:||", "op:!", "ident:c", "op:!=", "ident:d", "op:==", "ident:e", "op:>=", "ident:f",
+		}, ""},
+		{"call arguments", "max(1, x_2)", []string{"ident:max", "(:(", "number:1", ",:,", "ident:x_2", "):)"}, ""},
+		{"whitespace", " \t1 \n", []string{"number:1"}, ""},
+		{"unknown character", "price $ 1", nil, `unexpected character '$' at position 6`},
+		{"single ampersand", "a & b", nil, `unexpected character '&' at position 2`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := lexTrigger(tt.source)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if last := tokens[len(tokens)-1]; last.kind != "eof" {
+				t.Fatalf("last token is %s, want eof", last.kind)
+			}
+			var got []string
+			for _, tok := range tokens[:len(tokens)-1] {
+				got = append(got, tok.kind+":"+tok.text)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTriggerEval(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   bool
+	}{
+		{"example", "price < 0.9*ema(5m) && liquidity_sol > 50", true},
+		{"percent", "price == 80%", true},
+		{"multiplication before addition", "1 + 2 * 3 == 7", true},
+		{"parentheses first", "(1 + 2) * 3 == 9", true},
+		{"subtraction is left-associative", "10 - 4 - 3 == 3", true},
+		{"division is left-associative", "8 / 4 / 2 == 1", true},
+		{"unary minus", "-2 * 3 == -6", true},
+		{"double negation", "--2 == 2", true},
+		{"and before or", "true || false && false", true},
+		{"parenthesized or", "(true || false) && false", false},
+		{"not binds to its operand", "!false && false", false},
+		{"comparison before and", "1 < 2 && 3 > 2", true},
+		{"nested calls", "max(price, max(1, 2)) == 2", true},
+		{"and short-circuits", "false && unknown > 1", false},
+		{"or short-circuits", "true || unknown > 1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trigger, err := compileTrigger(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := trigger.Eval(testTriggerEnv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTriggerErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		compileErr string // empty when the error comes from evaluating
+		evalErr    string
+	}{
+		{"empty", "", "unexpected end of expression", ""},
+		{"dangling operator", "price <", "unexpected end of expression", ""},
+		{"unclosed parenthesis", "(price < 1", "expected ')' at position 10", ""},
+		{"unclosed call", "max(1, 2", "expected ')' at position 8", ""},
+		{"trailing comma", "max(1,", "unexpected end of expression", ""},
+		{"extra closing parenthesis", "price < 1)", `unexpected ")" at position 9`, ""},
+		{"missing operator", "price 1", `unexpected "1" at position 6`, ""},
+		{"leading binary operator", "* 2", `unexpected "*" at position 0`, ""},
+		{"invalid number", "1.2.3 > 0", `invalid number "1.2.3" at position 0`, ""},
+		{"invalid duration", "ema(5q) > 0", `invalid duration "5q" at position 4`, ""},
+		{"unknown variable", "volume > 1", "", "unknown variable volume"},
+		{"unknown function", "rsi(14) > 70", "", "rsi(): unknown function rsi()"},
+		{"function error", "ema(1h) > 0", "", "ema(): no 1h0m0s window"},
+		{"number as condition", "price", "", `trigger "price" does not evaluate to a condition`},
+		{"not of a number", "!price", "", "operator ! requires a condition"},
+		{"negated condition", "-true", "", "operator - requires a number"},
+		{"and of numbers", "price && true", "", "operator && requires conditions"},
+		{"or with a number", "false || 1", "", "operator || requires conditions"},
+		{"comparing conditions", "1 < 2 == true", "", "operator == requires numbers"},
+		{"arithmetic on a duration", "5m > 1", "", "operator > requires numbers"},
+		{"division by zero", "price / 0 > 1", "", "division by zero"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trigger, err := compileTrigger(tt.source)
+			if tt.compileErr != "" {
+				if err == nil || err.Error() != tt.compileErr {
+					t.Fatalf("got compile error %v, want %q", err, tt.compileErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = trigger.Eval(testTriggerEnv)
+			if err == nil || err.Error() != tt.evalErr {
+				t.Errorf("got eval error %v, want %q", err, tt.evalErr)
+			}
+		})
+	}
+}
+
+// TestTriggerMalformedNoPanic feeds truncations of valid triggers and stray
+// tokens through the compiler and evaluator; they must fail with an error
+func TestTriggerMalformedNoPanic(t *testing.T) {
+	sources := []string{
+		"price < 0.9*ema(5m) && liquidity_sol > 50",
+		"!(max(price, 1) >= 30%) || -price != 2",
+	}
+	var inputs []string
+	for _, source := range sources {
+		for i := range source {
+			inputs = append(inputs, source[:i], source[i:])
+		}
+	}
+	inputs = append(inputs, "(", ")", ",", ".", "%", "!", "-", "()", "f(,)", "((((", "))))", "1 2 3", "&&", "== ==", "5%%", "ema()()")
+
+	for _, input := range inputs {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%q panicked: %v", input, r)
+				}
+			}()
+			trigger, err := compileTrigger(input)
+			if err != nil {
+				return
+			}
+			if _, err := trigger.Eval(testTriggerEnv); err != nil && strings.TrimSpace(err.Error()) == "" {
+				t.Errorf("%q failed with an empty error", input)
+			}
+		}()
+	}
+}
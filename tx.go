@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"awesomeProject/internal/atoms"
+	"awesomeProject/internal/raylog"
+)
+
+// Raydium V4 swap instructions and the index of the pool among their accounts
+const (
+	RAYDIUM_SWAP_BASE_OUT_INSTRUCTION = uint8(11)
+	RAYDIUM_SWAP_POOL_ACCOUNT         = 1
+)
+
+// runTx inspects transactions sent earlier, by this or any other tool
+func runTx(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "status":
+			runTxStatus(args[1:])
+			return
+		case "report":
+			runTxReport(args[1:])
+			return
+		}
+	}
+	fmt.Println("Usage: go run . tx status [SIGNATURE...] | tx report [-json] [-raw] SIGNATURE...")
+	fmt.Println("Without signatures, status checks every transaction left pending by an interrupted run.")
+	os.Exit(1)
+}
+
+// runTxReport rebuilds and prints the swap report of past signatures
+func runTxReport(args []string) {
+	fs := newCommandFlagSet("tx report")
+	asJSON := fs.Bool("json", false, "Print reports as JSON")
+	heliusKey := fs.String("helius-key", heliusAPIKey(), "Helius API key for enhanced transaction parsing (or "+HELIUS_API_KEY_ENV_VAR+", or the key of a Helius RPC URL)")
+	raw := fs.Bool("raw", false, "Parse the transfers and instructions from RPC data even when a Helius key is set")
+	fs.Parse(args)
+	if *raw {
+		*heliusKey = ""
+	}
+	if fs.NArg() == 0 {
+		log.Fatal("Usage: go run . tx report [-json] [-raw] SIGNATURE...")
+	}
+
+	ctx := context.Background()
+	client := newChainClient()
+	for _, arg := range fs.Args() {
+		sig, err := solana.SignatureFromBase58(arg)
+		if err != nil {
+			log.Fatalf("Invalid signature %q: %v", arg, err)
+		}
+		report, err := reportFromSignature(ctx, client, sig)
+		if err != nil {
+			log.Fatalf("%s: %v", sig, err)
+		}
+		if report.Details, err = transactionDetails(ctx, client, *heliusKey, sig); err != nil {
+			fmt.Printf("Warning: Could not break down %s: %v\n", sig, err)
+		}
+		if *asJSON {
+			data, _ := json.MarshalIndent(report, "", "  ")
+			fmt.Println(string(data))
+			continue
+		}
+		printReport(report)
+		if report.Details != nil {
+			printTransactionDetails(ctx, client, report.Details)
+		}
+	}
+}
+
+// reportFromSignature fetches a transaction and rebuilds its swap report from
+// the Raydium ray_log and the fee payer's costs, slot and block time. Swaps
+// routed through Raydium by other programs are found in the inner
+// instructions. Quote-based fields (expected price, tolerance, slippage) stay zero.
+func reportFromSignature(ctx context.Context, client ChainClient, sig solana.Signature) (*TransactionReport, error) {
+	tx, err := fetchTransaction(ctx, client, sig.String())
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := tx.Transaction.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+
+	poolAddress, err := findRaydiumSwapPool(parsed, tx.Meta)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := loadPool(ctx, client, poolAddress.String())
+	if err != nil {
+		return nil, err
+	}
+
+	isBaseSol := pool.BaseMint.Equals(WSOL_MINT) || pool.BaseMint.Equals(SOL_MINT)
+	tokenMint := pool.BaseMint
+	if isBaseSol {
+		tokenMint = pool.QuoteMint
+	}
+
+	report := &TransactionReport{
+		TxHash:      sig.String(),
+		Status:      "Success",
+		ExplorerURL: explorerTxURL(sig.String()),
+		PoolAddress: poolAddress.String(),
+		PoolURL:     explorerAccountURL(poolAddress.String()),
+		TokenURL:    explorerAccountURL(tokenMint.String()),
+		TokenMint:   tokenMint.String(),
+		TokenSymbol: tokenSymbol(ctx, client, tokenMint),
+	}
+	// The fee payer is the wallet the swap is reported for
+	applyTransactionCosts(report, tx, parsed.Message.AccountKeys[0])
+	if tx.Meta.Err != nil {
+		report.Status = fmt.Sprintf("Failed (%v)", tx.Meta.Err)
+		return report, nil
+	}
+
+	swapLog, err := raylog.FindSwap(tx.Meta.LogMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Side = swapSide(pool, swapLog)
+	report.InputToken, report.OutputToken = getInputToken(report.Side), getOutputToken(report.Side)
+
+	inDecimals, outDecimals := int(pool.QuoteDecimals), int(pool.BaseDecimals)
+	if swapLog.BaseIn() {
+		inDecimals, outDecimals = outDecimals, inDecimals
+	}
+	numerator, denominator := swapFeeRate(pool)
+	report.AmountIn = atoms.ToAmount(swapLog.AmountIn, inDecimals)
+	report.AmountOut = atoms.ToAmount(swapLog.AmountOut, outDecimals)
+	report.SwapFee = atoms.ToAmount(atoms.MulDiv(swapLog.AmountIn, numerator, denominator, true), inDecimals)
+
+	if report.Side == "buy" && report.AmountOut > 0 {
+		report.ActualPrice = report.AmountIn / report.AmountOut
+	} else if report.Side == "sell" && report.AmountIn > 0 {
+		report.ActualPrice = report.AmountOut / report.AmountIn
+	}
+	return report, nil
+}
+
+// swapSide returns the side of a logged swap: spending SOL is a buy, whichever
+// side of the pool SOL is on
+func swapSide(pool *OnChainPool, swapLog *raylog.Swap) string {
+	isBaseSol := pool.BaseMint.Equals(WSOL_MINT) || pool.BaseMint.Equals(SOL_MINT)
+	if swapLog.BaseIn() == isBaseSol {
+		return "buy"
+	}
+	return "sell"
+}
+
+// findRaydiumSwapPool returns the pool of the first Raydium V4 swap in a
+// transaction, whether called directly or through another program
+func findRaydiumSwapPool(tx *solana.Transaction, meta *rpc.TransactionMeta) (solana.PublicKey, error) {
+	// Lookup table accounts follow the static keys, writable before read-only
+	keys := slices.Clone(tx.Message.AccountKeys)
+	keys = append(keys, meta.LoadedAddresses.Writable...)
+	keys = append(keys, meta.LoadedAddresses.ReadOnly...)
+
+	pool := func(programIndex int, accounts []int, data []byte) (solana.PublicKey, bool) {
+		if programIndex >= len(keys) || !keys[programIndex].Equals(RAYDIUM_AMM_V4) || len(data) == 0 {
+			return solana.PublicKey{}, false
+		}
+		if data[0] != RAYDIUM_SWAP_INSTRUCTION && data[0] != RAYDIUM_SWAP_BASE_OUT_INSTRUCTION {
+			return solana.PublicKey{}, false
+		}
+		if len(accounts) <= RAYDIUM_SWAP_POOL_ACCOUNT || accounts[RAYDIUM_SWAP_POOL_ACCOUNT] >= len(keys) {
+			return solana.PublicKey{}, false
+		}
+		return keys[accounts[RAYDIUM_SWAP_POOL_ACCOUNT]], true
+	}
+
+	for _, ix := range tx.Message.Instructions {
+		accounts := make([]int, len(ix.Accounts))
+		for i, a := range ix.Accounts {
+			accounts[i] = int(a)
+		}
+		if address, ok := pool(int(ix.ProgramIDIndex), accounts, ix.Data); ok {
+			return address, nil
+		}
+	}
+	for _, inner := range meta.InnerInstructions {
+		for _, ix := range inner.Instructions {
+			accounts := make([]int, len(ix.Accounts))
+			for i, a := range ix.Accounts {
+				accounts[i] = int(a)
+			}
+			if address, ok := pool(int(ix.ProgramIDIndex), accounts, ix.Data); ok {
+				return address, nil
+			}
+		}
+	}
+	return solana.PublicKey{}, fmt.Errorf("not a Raydium V4 swap")
+}
+
+// runTxStatus prints the status of the given signatures, or of the pending
+// ones, and clears pending records whose outcome is known
+func runTxStatus(args []string) {
+	pending, err := loadPendingTxs()
+	if err != nil {
+		log.Fatal(err)
+	}
+	sentAt := make(map[string]time.Time)
+	for _, p := range pending {
+		sentAt[p.Signature] = p.SentAt
+	}
+
+	if len(args) == 0 {
+		if len(pending) == 0 {
+			fmt.Println("No pending transactions.")
+			return
+		}
+		for _, p := range pending {
+			if p.Cluster != "" && p.Cluster != activeCluster.Name {
+				fmt.Printf("Skipping %s: sent on %s (set %s=%s)\n", p.Signature, p.Cluster, CLUSTER_ENV_VAR, p.Cluster)
+				continue
+			}
+			args = append(args, p.Signature)
+		}
+	}
+
+	ctx := context.Background()
+	client := newChainClient()
+	for _, arg := range args {
+		sig, err := solana.SignatureFromBase58(arg)
+		if err != nil {
+			log.Fatalf("Invalid signature %q: %v", arg, err)
+		}
+
+		statuses, err := client.GetSignatureStatuses(ctx, true, sig)
+		if err != nil {
+			log.Fatalf("Failed to get status of %s: %v", sig, err)
+		}
+		var status *rpc.SignatureStatusesResult
+		if statuses != nil && len(statuses.Value) > 0 {
+			status = statuses.Value[0]
+		}
+
+		fmt.Printf("\nSignature: %s\n", sig)
+		settled := true
+		switch {
+		case status == nil && !sentAt[arg].IsZero() && time.Since(sentAt[arg]) > TX_EXPIRY:
+			fmt.Printf("Status: expired (sent %s ago and never landed)\n", time.Since(sentAt[arg]).Round(time.Second))
+		case status == nil:
+			fmt.Println("Status: not found")
+			settled = sentAt[arg].IsZero()
+		case status.Err != nil:
+			fmt.Printf("Status: failed (%v)\n", status.Err)
+			fmt.Printf("Slot: %d\n", status.Slot)
+		default:
+			fmt.Printf("Status: %s\n", status.ConfirmationStatus)
+			fmt.Printf("Slot: %d\n", status.Slot)
+			settled = status.ConfirmationStatus != rpc.ConfirmationStatusProcessed
+		}
+		fmt.Printf("Explorer: %s\n", explorerTxURL(sig.String()))
+
+		if settled && !sentAt[arg].IsZero() {
+			if err := removePendingTx(sig); err != nil {
+				fmt.Printf("Warning: Could not clear pending transaction: %v\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+
+	"awesomeProject/internal/raylog"
+)
+
+func TestSwapSide(t *testing.T) {
+	usdc := solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qNrmqrfWwzkQfD6h2t9P1aHA8v")
+	token := solana.MustPublicKeyFromBase58("4k3Dyjzvzp8eMZWUXbBCjEvwSkkk59S5iCNLY3QrkX6R")
+	tests := []struct {
+		name      string
+		base      string
+		quote     string
+		direction uint64
+		want      string
+	}{
+		{"SOL base, SOL in", "sol", "usdc", raylog.DIRECTION_COIN2PC, "buy"},
+		{"SOL base, SOL out", "sol", "usdc", raylog.DIRECTION_PC2COIN, "sell"},
+		{"SOL quote, SOL in", "token", "sol", raylog.DIRECTION_PC2COIN, "buy"},
+		{"SOL quote, SOL out", "token", "sol", raylog.DIRECTION_COIN2PC, "sell"},
+	}
+	mints := map[string]solana.PublicKey{"sol": WSOL_MINT, "usdc": usdc, "token": token}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &OnChainPool{BaseMint: mints[tt.base], QuoteMint: mints[tt.quote]}
+			if got := swapSide(pool, &raylog.Swap{Direction: tt.direction}); got != tt.want {
+				t.Errorf("swapSide = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// A routed transaction in testdata/raydium-v4.json swaps through Raydium twice
+func TestFindRaySwapLogsInFixture(t *testing.T) {
+	client := fixtureTestClient(t)
+	tx, err := fetchTransaction(context.Background(), client, "99eUso3aSbE9tqGSTXzo3TLfKb9RkMTURrHKQ1K7Zh3BbeqPevr5E1iCbpTjqHuTFLtfxTTD5ekfVuZFzQyEQf8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	swaps, err := raylog.FindSwaps(tx.Meta.LogMessages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		logType   uint8
+		amountIn  uint64
+		amountOut uint64
+	}{
+		{raylog.SWAP_BASE_IN, 1_000_000_000, 143_906_523},
+		{raylog.SWAP_BASE_OUT, 693_999_871, 100_000_000},
+	}
+	if len(swaps) != len(tests) {
+		t.Fatalf("found %d swaps, want %d", len(swaps), len(tests))
+	}
+	for i, tt := range tests {
+		if got := swaps[i]; got.LogType != tt.logType || got.AmountIn != tt.amountIn || got.AmountOut != tt.amountOut {
+			t.Errorf("swap %d: got %+v, want type %d, in %d, out %d", i, got, tt.logType, tt.amountIn, tt.amountOut)
+		}
+	}
+	first, err := raylog.FindSwap(tx.Meta.LogMessages)
+	if err != nil || *first != *swaps[0] {
+		t.Errorf("raylog.FindSwap = %+v, %v; want the first swap", first, err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Display currencies for -currency
+const (
+	CURRENCY_SOL = "sol"
+	CURRENCY_USD = "usd"
+)
+
+// Valuation prices both legs of a swap in the display currency. Tokens are
+// valued at the pool's mid price before the trade, so the difference between
+// the legs is the execution cost (fee plus price impact).
+type Valuation struct {
+	Currency      string
+	SOLPriceUSD   float64 // 0 when Currency is sol
+	TokenPriceSOL float64
+}
+
+// newValuation prices the pool's token, fetching SOL/USD from the oracle for usd
+func newValuation(ctx context.Context, oracle PriceOracle, currency string, pool *OnChainPool) (*Valuation, error) {
+	v := &Valuation{Currency: currency}
+	v.TokenPriceSOL, _, _ = poolPrice(pool)
+
+	if currency == CURRENCY_USD {
+		price, err := oracle.SOLPriceUSD(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch SOL/USD price: %w", err)
+		}
+		v.SOLPriceUSD = price
+	}
+	return v, nil
+}
+
+// Value converts an amount of "SOL" or "TOKEN" to the display currency
+func (v *Valuation) Value(amount float64, token string) float64 {
+	value := amount
+	if token != "SOL" {
+		value = amount * v.TokenPriceSOL
+	}
+	if v.Currency == CURRENCY_USD {
+		value *= v.SOLPriceUSD
+	}
+	return value
+}
+
+// Format renders a display-currency value
+func (v *Valuation) Format(value float64) string {
+	if v.Currency == CURRENCY_USD {
+		return fmt.Sprintf("$%.2f", value)
+	}
+	return fmt.Sprintf("%.9f SOL", value)
+}
+
+// printValuation shows both legs of a swap, the fees and the resulting PnL
+// against the mid price in the display currency
+func printValuation(v *Valuation, report *TransactionReport) {
+	valueIn := v.Value(report.AmountIn, report.InputToken)
+	valueOut := v.Value(report.AmountOut, report.OutputToken)
+	fees := v.Value(report.NetworkFee+report.PriorityFee, "SOL")
+
+	fmt.Printf("\nValuation (%s, token at mid %.9f SOL):\n", v.Currency, v.TokenPriceSOL)
+	fmt.Printf("  Amount In: %s\n", v.Format(valueIn))
+	fmt.Printf("  Amount Out: %s\n", v.Format(valueOut))
+	fmt.Printf("  Network Fees: %s\n", v.Format(fees))
+	fmt.Printf("  PnL vs Mid: %s\n", v.Format(valueOut-valueIn-fees))
+}
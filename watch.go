@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// SPL token account layout: mint(32) + owner(32) + amount(u64)
+const TOKEN_ACCOUNT_AMOUNT_OFFSET = 64
+
+// Price moves used for the implied depth readout
+var watchDepthLevels = []float64{0.01, 0.02, 0.05}
+
+// poolPrice returns the SOL-per-token price and UI reserves of a SOL-paired pool
+func poolPrice(pool *OnChainPool) (price float64, solReserve float64, tokenReserve float64) {
+	baseReserve := float64(pool.BaseAmount) / math.Pow(10, float64(pool.BaseDecimals))
+	quoteReserve := float64(pool.QuoteAmount) / math.Pow(10, float64(pool.QuoteDecimals))
+
+	if pool.BaseMint.Equals(WSOL_MINT) || pool.BaseMint.Equals(SOL_MINT) {
+		solReserve, tokenReserve = baseReserve, quoteReserve
+	} else {
+		solReserve, tokenReserve = quoteReserve, baseReserve
+	}
+
+	if tokenReserve > 0 {
+		price = solReserve / tokenReserve
+	}
+	return price, solReserve, tokenReserve
+}
+
+// impliedDepth returns the SOL needed to move a constant-product pool's price
+// up by move (buy side) and the SOL received moving it down by move (sell side)
+func impliedDepth(solReserve float64, move float64) (buySOL float64, sellSOL float64) {
+	buySOL = solReserve * (math.Sqrt(1+move) - 1)
+	sellSOL = solReserve * (1 - math.Sqrt(1-move))
+	return buySOL, sellSOL
+}
+
+// decodeTokenAmount reads the amount field of an SPL token account
+func decodeTokenAmount(data []byte) (uint64, error) {
+	if len(data) < TOKEN_ACCOUNT_AMOUNT_OFFSET+8 {
+		return 0, fmt.Errorf("invalid token account data size: %d", len(data))
+	}
+	return binary.LittleEndian.Uint64(data[TOKEN_ACCOUNT_AMOUNT_OFFSET : TOKEN_ACCOUNT_AMOUNT_OFFSET+8]), nil
+}
+
+// watchEnv exposes pool state and indicators to watch triggers
+type watchEnv struct {
+	indicators *IndicatorEngine
+	pool       *OnChainPool
+}
+
+func (e watchEnv) Var(name string) (float64, bool) {
+	price, solReserve, tokenReserve := poolPrice(e.pool)
+	switch name {
+	case "price":
+		return price, price > 0
+	case "liquidity_sol":
+		return solReserve, true
+	case "liquidity_token":
+		return tokenReserve, true
+	}
+	return e.indicators.Var(name)
+}
+
+func (e watchEnv) Call(name string, args []TriggerValue) (float64, error) {
+	return e.indicators.Call(name, args)
+}
+
+// runWatch streams live price, reserves and depth for a pool via vault account subscriptions
+func runWatch(args []string) {
+	fs := newCommandFlagSet("watch")
+	var poolAddress string
+	var triggerExpr string
+	fs.StringVar(&poolAddress, "pool", "", "Pool address to watch")
+	fs.StringVar(&triggerExpr, "trigger", "", "Condition to alert on, e.g. \"price < 0.9*ema(5m) && liquidity_sol > 50\"")
+	fs.Parse(args)
+
+	if poolAddress == "" {
+		fmt.Println("Usage: go run . watch -pool POOL [-trigger EXPR]")
+		fs.PrintDefaults()
+		return
+	}
+
+	var trigger *Trigger
+	if triggerExpr != "" {
+		var err error
+		trigger, err = compileTrigger(triggerExpr)
+		if err != nil {
+			log.Fatalf("Invalid trigger: %v", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := newChainClient()
+	pool, err := loadPool(ctx, client, poolAddress)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("\n=== WATCH ===\n")
+	fmt.Printf("Pool: %s\n", pool.Address)
+	fmt.Printf("Base Token: %s %s (decimals: %d)\n", tokenSymbol(ctx, client, pool.BaseMint), pool.BaseMint, pool.BaseDecimals)
+	fmt.Printf("Quote Token: %s %s (decimals: %d)\n", tokenSymbol(ctx, client, pool.QuoteMint), pool.QuoteMint, pool.QuoteDecimals)
+	if trigger != nil {
+		fmt.Printf("Trigger: %s\n", trigger.Source)
+	}
+	fmt.Printf("=============\n")
+	fmt.Println("Streaming vault updates (Ctrl-C to stop)...")
+
+	indicators := newIndicatorEngine(DEFAULT_INDICATOR_HISTORY)
+	env := watchEnv{indicators: indicators, pool: pool}
+
+	symbol := tokenSymbol(ctx, client, pool.BaseMint)
+	if pool.BaseMint.Equals(WSOL_MINT) || pool.BaseMint.Equals(SOL_MINT) {
+		symbol = tokenSymbol(ctx, client, pool.QuoteMint)
+	}
+
+	// A swap updates both vaults in the same slot: the slot's second update
+	// replaces its sample, and the trigger fires when its condition becomes true
+	sampled := false
+	var sampledSlot uint64
+	firing := false
+	update := func(slot uint64) {
+		price, solReserve, tokenReserve := poolPrice(pool)
+		sample := PriceSample{Time: time.Now(), Price: price}
+		if sampled && slot == sampledSlot {
+			indicators.ReplaceLast(sample)
+		} else {
+			indicators.Add(sample)
+		}
+		sampled, sampledSlot = true, slot
+
+		fmt.Printf("[%s] slot %d | price %.12f SOL | reserves %.4f SOL / %.4f %s | depth",
+			time.Now().Format(time.TimeOnly), slot, price, solReserve, tokenReserve, symbol)
+		for _, move := range watchDepthLevels {
+			buySOL, sellSOL := impliedDepth(solReserve, move)
+			fmt.Printf(" ±%.0f%%: +%.2f/-%.2f", move*100, buySOL, sellSOL)
+		}
+		fmt.Println()
+
+		if trigger != nil {
+			fired, err := trigger.Eval(env)
+			if err != nil {
+				fmt.Printf("  trigger not evaluated: %v\n", err)
+			} else {
+				if fired && !firing {
+					fmt.Printf("  🔔 TRIGGER FIRED: %s\n", trigger.Source)
+				}
+				firing = fired
+			}
+		}
+	}
+	update(0)
+
+	mux := newUpdateStream(resolveWSURL())
+	baseUpdates := mux.SubscribeAccount(pool.BaseVault)
+	quoteUpdates := mux.SubscribeAccount(pool.QuoteVault)
+
+	go func() {
+		if err := mux.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Fatalf("Websocket stream failed: %v", err)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			reconnects, dropped := mux.Stats()
+			var droppedTotal uint64
+			for _, n := range dropped {
+				droppedTotal += n
+			}
+			fmt.Printf("\nStopped watching (reconnects: %d, dropped updates: %d)\n", reconnects, droppedTotal)
+			return
+		case u := <-baseUpdates:
+			amount, err := decodeTokenAmount(u.Data)
+			if err != nil {
+				fmt.Printf("Warning: %v\n", err)
+				continue
+			}
+			pool.BaseAmount = amount
+			update(u.Slot)
+		case u := <-quoteUpdates:
+			amount, err := decodeTokenAmount(u.Data)
+			if err != nil {
+				fmt.Printf("Warning: %v\n", err)
+				continue
+			}
+			pool.QuoteAmount = amount
+			update(u.Slot)
+		}
+	}
+}
@@ -0,0 +1,695 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// WATCHLIST_FILE in the state directory is the default watchlist
+	WATCHLIST_FILE = "watchlist.yaml"
+	// WATCHLIST_STATUS_FILE in the state directory is where the daemon
+	// reports the watchlist's monitors for `watchlist status`
+	WATCHLIST_STATUS_FILE = "watchlist_status.json"
+
+	WATCHLIST_TICK                 = 5 * time.Second
+	DEFAULT_WATCHLIST_COOLDOWN     = time.Hour
+	DEFAULT_WATCHLIST_SLIPPAGE     = 1.0 // percent
+	WATCHLIST_STATUS_STALE_AFTER   = 4 * WATCHLIST_TICK
+	WATCHLIST_MAX_ACTION_FAILURES  = 3
+	WATCHLIST_FAILURE_RETRY_PERIOD = time.Minute
+)
+
+// Watchlist is the YAML file of tokens the daemon monitors with their actions
+type Watchlist struct {
+	Slippage float64          `yaml:"slippage"` // percent, for every auto-action
+	Webhook  string           `yaml:"webhook"`  // default webhook of the alerts
+	Tokens   []WatchlistToken `yaml:"tokens"`
+}
+
+// WatchlistToken is a watched mint. Without a pool, its deepest pool is used.
+type WatchlistToken struct {
+	Mint       string               `yaml:"mint"`
+	Pool       string               `yaml:"pool"`
+	Label      string               `yaml:"label"`
+	Alerts     *WatchlistAlerts     `yaml:"alerts"`
+	BuyDip     *WatchlistBuyDip     `yaml:"buy_dip"`
+	TakeProfit *WatchlistTakeProfit `yaml:"take_profit"`
+}
+
+// WatchlistAlerts notify when the price crosses above or below a threshold,
+// in SOL per token
+type WatchlistAlerts struct {
+	Above   float64 `yaml:"above"`
+	Below   float64 `yaml:"below"`
+	Webhook string  `yaml:"webhook"`
+}
+
+// WatchlistBuyDip buys Amount SOL when the price falls Drop percent below
+// the highest price seen since the monitor started or last bought
+type WatchlistBuyDip struct {
+	Drop     string  `yaml:"drop"`
+	Amount   float64 `yaml:"amount"`
+	Cooldown string  `yaml:"cooldown"`
+
+	drop     float64
+	cooldown time.Duration
+}
+
+// WatchlistTakeProfit sells Sell percent of the ledger's position once the
+// price is Rise percent above its average cost
+type WatchlistTakeProfit struct {
+	Rise     string `yaml:"rise"`
+	Sell     string `yaml:"sell"`
+	Cooldown string `yaml:"cooldown"`
+
+	rise     float64
+	sell     float64
+	cooldown time.Duration
+}
+
+// WatchlistStatus is what the daemon last reported about the watchlist
+type WatchlistStatus struct {
+	Path        string                 `json:"path"`
+	LoadedAt    time.Time              `json:"loaded_at"`
+	ConfigError string                 `json:"config_error,omitempty"` // the file failed to load; the previous one is still used
+	UpdatedAt   time.Time              `json:"updated_at"`
+	Tokens      []WatchlistTokenStatus `json:"tokens"`
+}
+
+// WatchlistTokenStatus is the state of one token's monitor
+type WatchlistTokenStatus struct {
+	Mint         string    `json:"mint"`
+	Label        string    `json:"label,omitempty"`
+	Pool         string    `json:"pool,omitempty"`
+	Actions      []string  `json:"actions"`
+	Price        float64   `json:"price,omitempty"`         // SOL per token
+	DipReference float64   `json:"dip_reference,omitempty"` // the high a dip is measured from
+	CostPrice    float64   `json:"cost_price,omitempty"`    // average cost of the ledger's position
+	LastEvent    string    `json:"last_event,omitempty"`
+	LastEventAt  time.Time `json:"last_event_at,omitempty"`
+	LastBuyAt    time.Time `json:"last_buy_at,omitempty"`
+	LastSellAt   time.Time `json:"last_sell_at,omitempty"`
+	Failures     int       `json:"failures,omitempty"` // consecutive failed actions
+	Error        string    `json:"error,omitempty"`
+}
+
+// defaultWatchlistPath returns the watchlist in the state directory
+func defaultWatchlistPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, WATCHLIST_FILE), nil
+}
+
+// loadWatchlist reads and validates a watchlist file. Unknown keys are
+// rejected so a misspelled action is never silently ignored.
+func loadWatchlist(path string) (*Watchlist, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var watchlist Watchlist
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&watchlist); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := watchlist.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return &watchlist, nil
+}
+
+// validate checks the watchlist and parses its percentages and durations
+func (w *Watchlist) validate() error {
+	if w.Slippage == 0 {
+		w.Slippage = DEFAULT_WATCHLIST_SLIPPAGE
+	}
+	if w.Slippage < 0 || w.Slippage > MAX_SLIPPAGE {
+		return fmt.Errorf("slippage must be between 0 and %.0f", MAX_SLIPPAGE)
+	}
+	seen := make(map[string]bool)
+	for i := range w.Tokens {
+		token := &w.Tokens[i]
+		if _, err := solana.PublicKeyFromBase58(token.Mint); err != nil {
+			return fmt.Errorf("token %d: invalid mint %q", i+1, token.Mint)
+		}
+		if seen[token.Mint] {
+			return fmt.Errorf("token %s is listed twice", token.Mint)
+		}
+		seen[token.Mint] = true
+		if token.Pool != "" {
+			if _, err := solana.PublicKeyFromBase58(token.Pool); err != nil {
+				return fmt.Errorf("token %s: invalid pool %q", token.Mint, token.Pool)
+			}
+		}
+		if err := token.validate(); err != nil {
+			return fmt.Errorf("token %s: %w", token.Mint, err)
+		}
+	}
+	return nil
+}
+
+// validate checks a token's actions
+func (t *WatchlistToken) validate() error {
+	if t.Alerts == nil && t.BuyDip == nil && t.TakeProfit == nil {
+		return fmt.Errorf("no alerts, buy_dip or take_profit")
+	}
+	if a := t.Alerts; a != nil {
+		if a.Above < 0 || a.Below < 0 || (a.Above == 0 && a.Below == 0) {
+			return fmt.Errorf("alerts need a positive above or below price")
+		}
+	}
+	var err error
+	if d := t.BuyDip; d != nil {
+		if d.drop, err = watchlistPercent(d.Drop); err != nil || d.drop <= 0 || d.drop >= 100 {
+			return fmt.Errorf("buy_dip drop must be a percent between 0 and 100")
+		}
+		if d.Amount <= 0 {
+			return fmt.Errorf("buy_dip amount must be a positive SOL amount")
+		}
+		if d.cooldown, err = watchlistCooldown(d.Cooldown); err != nil {
+			return fmt.Errorf("buy_dip cooldown: %w", err)
+		}
+	}
+	if p := t.TakeProfit; p != nil {
+		if p.rise, err = watchlistPercent(p.Rise); err != nil || p.rise <= 0 {
+			return fmt.Errorf("take_profit rise must be a positive percent")
+		}
+		if p.sell, err = watchlistPercent(p.Sell); err != nil || p.sell <= 0 || p.sell > 100 {
+			return fmt.Errorf("take_profit sell must be a percent between 0 and 100")
+		}
+		if p.cooldown, err = watchlistCooldown(p.Cooldown); err != nil {
+			return fmt.Errorf("take_profit cooldown: %w", err)
+		}
+	}
+	return nil
+}
+
+// watchlistPercent parses a percentage such as 20% or 20
+func watchlistPercent(value string) (float64, error) {
+	if value == "" {
+		return 0, fmt.Errorf("missing percent")
+	}
+	return parseFloat(value)
+}
+
+// watchlistCooldown parses an action's cooldown, defaulting to an hour
+func watchlistCooldown(value string) (time.Duration, error) {
+	if value == "" {
+		return DEFAULT_WATCHLIST_COOLDOWN, nil
+	}
+	cooldown, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if cooldown < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return cooldown, nil
+}
+
+// actions names the token's configured actions
+func (t *WatchlistToken) actions() []string {
+	var actions []string
+	if t.Alerts != nil {
+		actions = append(actions, "alerts")
+	}
+	if t.BuyDip != nil {
+		actions = append(actions, "buy_dip")
+	}
+	if t.TakeProfit != nil {
+		actions = append(actions, "take_profit")
+	}
+	return actions
+}
+
+// watchMonitor is the running state of one watched token. It outlives
+// reloads of the watchlist, so editing a token's actions keeps its dip
+// reference, alert edges and cooldowns.
+type watchMonitor struct {
+	token        WatchlistToken
+	pool         string
+	high         float64
+	aboveActive  bool
+	belowActive  bool
+	lastBuy      time.Time
+	lastSell     time.Time
+	failures     int
+	lastFailure  time.Time
+	lastEvent    string
+	lastEventAt  time.Time
+	price        float64
+	costPrice    float64
+	err          error
+	resolvedPool string // the pool resolved for token.Pool, to notice edits
+}
+
+// watchlistMonitor runs the watchlist in the daemon, reloading the file
+// whenever it changes
+type watchlistMonitor struct {
+	engine *Engine
+	guard  *SpendGuard
+	wallet solana.PrivateKey // nil disables buy_dip and take_profit
+	path   string
+	secret string
+
+	watchlist *Watchlist
+	modTime   time.Time
+	size      int64
+	loadedAt  time.Time
+	loadErr   error
+	monitors  map[string]*watchMonitor
+}
+
+// newWatchlistMonitor loads the watchlist and restores the cooldowns the
+// daemon last reported, so a restart cannot repeat an action right away
+func newWatchlistMonitor(engine *Engine, guard *SpendGuard, wallet solana.PrivateKey, path string) (*watchlistMonitor, error) {
+	m := &watchlistMonitor{
+		engine:   engine,
+		guard:    guard,
+		wallet:   wallet,
+		path:     path,
+		secret:   os.Getenv(NOTIFY_SECRET_ENV_VAR),
+		monitors: make(map[string]*watchMonitor),
+	}
+	if _, err := m.reload(); err != nil {
+		return nil, err
+	}
+	if status, err := readWatchlistStatus(); err == nil && status != nil {
+		for _, previous := range status.Tokens {
+			if monitor := m.monitors[previous.Mint]; monitor != nil {
+				monitor.lastBuy, monitor.lastSell = previous.LastBuyAt, previous.LastSellAt
+			}
+		}
+	}
+	return m, nil
+}
+
+// reload reads the watchlist when its file changed since the last load,
+// reporting whether it did. A file that fails to load returns its error once
+// and the previous watchlist is kept.
+func (m *watchlistMonitor) reload() (bool, error) {
+	info, err := os.Stat(m.path)
+	if err != nil {
+		// A missing file is reported once, until it is back
+		if m.watchlist != nil && m.modTime.IsZero() {
+			return false, nil
+		}
+		m.modTime, m.size = time.Time{}, 0
+		m.loadErr = fmt.Errorf("failed to read %s: %w", m.path, err)
+		return false, m.loadErr
+	}
+	if m.watchlist != nil && info.ModTime().Equal(m.modTime) && info.Size() == m.size {
+		return false, nil
+	}
+	m.modTime, m.size = info.ModTime(), info.Size()
+	watchlist, err := loadWatchlist(m.path)
+	if err != nil {
+		m.loadErr = err
+		return false, err
+	}
+	m.watchlist, m.loadErr, m.loadedAt = watchlist, nil, time.Now()
+
+	monitors := make(map[string]*watchMonitor)
+	for _, token := range watchlist.Tokens {
+		monitor := m.monitors[token.Mint]
+		if monitor == nil {
+			monitor = &watchMonitor{}
+		}
+		monitor.token = token
+		if token.Pool != monitor.resolvedPool {
+			monitor.pool, monitor.resolvedPool, monitor.high = "", token.Pool, 0
+		}
+		monitors[token.Mint] = monitor
+	}
+	m.monitors = monitors
+	return true, nil
+}
+
+// Run evaluates the watchlist every tick until ctx is done
+func (m *watchlistMonitor) Run(ctx context.Context) {
+	m.tick(ctx)
+	ticker := time.NewTicker(WATCHLIST_TICK)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		reloaded, err := m.reload()
+		if err != nil {
+			fmt.Printf("Warning: watchlist not reloaded, keeping the previous one: %v\n", err)
+		} else if reloaded {
+			fmt.Printf("Reloaded watchlist %s (%d tokens)\n", m.path, len(m.watchlist.Tokens))
+		}
+		m.tick(ctx)
+	}
+}
+
+// tick evaluates every watched token and reports the monitors' status
+func (m *watchlistMonitor) tick(ctx context.Context) {
+	for _, token := range m.watchlist.Tokens {
+		if ctx.Err() != nil {
+			return
+		}
+		monitor := m.monitors[token.Mint]
+		monitor.err = m.evaluate(ctx, monitor)
+	}
+	if err := m.writeStatus(); err != nil {
+		fmt.Printf("Warning: Could not write watchlist status: %v\n", err)
+	}
+}
+
+// evaluate resolves and loads the token's pool, then runs its actions on
+// the current price
+func (m *watchlistMonitor) evaluate(ctx context.Context, monitor *watchMonitor) error {
+	token := monitor.token
+	if monitor.pool == "" {
+		monitor.pool = token.Pool
+		if monitor.pool == "" {
+			pool, err := findPoolsOnChain(ctx, m.engine.client, token.Mint)
+			if err != nil {
+				return err
+			}
+			monitor.pool = pool.Address.String()
+		}
+	}
+	pool, err := m.engine.pool(monitor.pool)
+	if err != nil {
+		if err := m.engine.AddPool(ctx, monitor.pool); err != nil {
+			return fmt.Errorf("failed to load pool %s: %w", monitor.pool, err)
+		}
+		fmt.Printf("Loaded pool %s for watched token %s\n", monitor.pool, token.Mint)
+		if pool, err = m.engine.pool(monitor.pool); err != nil {
+			return err
+		}
+	}
+	price, _, _ := poolPrice(pool)
+	if price <= 0 {
+		return fmt.Errorf("pool %s has no price", monitor.pool)
+	}
+	monitor.price = price
+	monitor.high = max(monitor.high, price)
+	now := time.Now()
+
+	if alerts := token.Alerts; alerts != nil {
+		above := alerts.Above > 0 && price >= alerts.Above
+		if above && !monitor.aboveActive {
+			m.alert(ctx, monitor, pool, fmt.Sprintf("price >= %.12f", alerts.Above))
+		}
+		below := alerts.Below > 0 && price <= alerts.Below
+		if below && !monitor.belowActive {
+			m.alert(ctx, monitor, pool, fmt.Sprintf("price <= %.12f", alerts.Below))
+		}
+		monitor.aboveActive, monitor.belowActive = above, below
+	}
+
+	if token.BuyDip == nil && token.TakeProfit == nil {
+		return nil
+	}
+	if m.wallet == nil {
+		return fmt.Errorf("no wallet loaded; buy_dip and take_profit are disabled")
+	}
+	// Repeated failures back off instead of retrying on every tick
+	if monitor.failures >= WATCHLIST_MAX_ACTION_FAILURES && now.Sub(monitor.lastFailure) < WATCHLIST_FAILURE_RETRY_PERIOD {
+		return fmt.Errorf("%d actions failed in a row; retrying after %s", monitor.failures, WATCHLIST_FAILURE_RETRY_PERIOD)
+	}
+
+	if dip := token.BuyDip; dip != nil && now.Sub(monitor.lastBuy) >= dip.cooldown && price <= monitor.high*(1-dip.drop/100) {
+		event := fmt.Sprintf("buy_dip: %.2f%% below %.12f, buying %g SOL", (1-price/monitor.high)*100, monitor.high, dip.Amount)
+		if err := m.trade(ctx, monitor, "buy", dip.Amount, event); err != nil {
+			return err
+		}
+		monitor.lastBuy, monitor.high = now, price
+	}
+
+	if profit := token.TakeProfit; profit != nil {
+		position, err := m.position(token.Mint)
+		if err != nil {
+			return err
+		}
+		monitor.costPrice = 0
+		if position == nil || position.Tokens <= 0 {
+			return nil
+		}
+		monitor.costPrice = position.CostSOL / position.Tokens
+		if now.Sub(monitor.lastSell) >= profit.cooldown && price >= monitor.costPrice*(1+profit.rise/100) {
+			amount := position.Tokens * profit.sell / 100
+			event := fmt.Sprintf("take_profit: %.2f%% above cost %.12f, selling %g tokens", (price/monitor.costPrice-1)*100, monitor.costPrice, amount)
+			if err := m.trade(ctx, monitor, "sell", amount, event); err != nil {
+				return err
+			}
+			monitor.lastSell = now
+		}
+	}
+	return nil
+}
+
+// position returns the wallet's ledger position in a mint, nil without one
+func (m *watchlistMonitor) position(mint string) (*Position, error) {
+	ledger, err := readLedger()
+	if err != nil {
+		return nil, err
+	}
+	positions, _ := replayLedger(ledger[m.wallet.PublicKey().String()], startOfDay(time.Now()))
+	return positions[mint], nil
+}
+
+// trade quotes and sends an auto-action's swap within the spend limits
+func (m *watchlistMonitor) trade(ctx context.Context, monitor *watchMonitor, side string, amount float64, event string) error {
+	fmt.Printf("[%s] 👀 %s: %s\n", time.Now().Format(time.TimeOnly), monitor.name(), event)
+	sig, err := func() (solana.Signature, error) {
+		quote, err := m.engine.Quote(monitor.pool, side, amount)
+		if err != nil {
+			return solana.Signature{}, err
+		}
+		if err := m.guard.Enforce(ctx, quote.GuardedTrade(m.engine.opts.Burn), false); err != nil {
+			return solana.Signature{}, fmt.Errorf("spend limits: %w", err)
+		}
+		defer m.guard.Release(quote.GuardedTrade(m.engine.opts.Burn))
+		sig, err := m.engine.Swap(ctx, quote, m.watchlist.Slippage)
+		if err != nil {
+			return solana.Signature{}, err
+		}
+		if err := m.guard.Record(quote.GuardedTrade(m.engine.opts.Burn)); err != nil {
+			fmt.Printf("Warning: Could not record trade for spend limits: %v\n", err)
+		}
+		return sig, nil
+	}()
+	if err != nil {
+		monitor.failures++
+		monitor.lastFailure = time.Now()
+		return fmt.Errorf("%s failed: %w", side, err)
+	}
+	monitor.failures = 0
+	monitor.lastEvent, monitor.lastEventAt = fmt.Sprintf("%s (%s)", event, sig), time.Now()
+	fmt.Printf("Sent %s\n", explorerTxURL(sig.String()))
+	return nil
+}
+
+// alert prints a crossed threshold and delivers it to the token's webhook
+func (m *watchlistMonitor) alert(ctx context.Context, monitor *watchMonitor, pool *OnChainPool, when string) {
+	price, solReserve, _ := poolPrice(pool)
+	fmt.Printf("[%s] 🔔 %s: %s (price %.12f SOL)\n", time.Now().Format(time.TimeOnly), monitor.name(), when, price)
+	monitor.lastEvent, monitor.lastEventAt = "alert: "+when, time.Now()
+
+	webhook := monitor.token.Alerts.Webhook
+	if webhook == "" {
+		webhook = m.watchlist.Webhook
+	}
+	if webhook == "" {
+		return
+	}
+	notification := AlertNotification{
+		Event:        "watchlist.alert",
+		Pool:         monitor.pool,
+		When:         when,
+		Price:        price,
+		LiquiditySOL: solReserve,
+		SentAt:       time.Now().UTC(),
+	}
+	if err := newWebhookNotifier(webhook, m.secret).deliver(ctx, notification); err != nil {
+		fmt.Printf("Warning: Could not send the %s alert to its webhook: %v\n", monitor.name(), err)
+	}
+}
+
+// name is the token's label, or its mint
+func (w *watchMonitor) name() string {
+	if w.token.Label != "" {
+		return w.token.Label
+	}
+	return w.token.Mint
+}
+
+// writeStatus reports every monitor for `watchlist status`
+func (m *watchlistMonitor) writeStatus() error {
+	status := WatchlistStatus{Path: m.path, LoadedAt: m.loadedAt, UpdatedAt: time.Now().UTC()}
+	if m.loadErr != nil {
+		status.ConfigError = m.loadErr.Error()
+	}
+	for _, token := range m.watchlist.Tokens {
+		monitor := m.monitors[token.Mint]
+		tokenStatus := WatchlistTokenStatus{
+			Mint:        token.Mint,
+			Label:       token.Label,
+			Pool:        monitor.pool,
+			Actions:     token.actions(),
+			Price:       monitor.price,
+			CostPrice:   monitor.costPrice,
+			LastEvent:   monitor.lastEvent,
+			LastEventAt: monitor.lastEventAt,
+			LastBuyAt:   monitor.lastBuy,
+			LastSellAt:  monitor.lastSell,
+			Failures:    monitor.failures,
+		}
+		if token.BuyDip != nil {
+			tokenStatus.DipReference = monitor.high
+		}
+		if monitor.err != nil {
+			tokenStatus.Error = monitor.err.Error()
+		}
+		status.Tokens = append(status.Tokens, tokenStatus)
+	}
+	var stored WatchlistStatus
+	return updateStateFile(WATCHLIST_STATUS_FILE, &stored, func() {
+		stored = status
+	})
+}
+
+// readWatchlistStatus loads the daemon's last report, nil if it never ran
+func readWatchlistStatus() (*WatchlistStatus, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, WATCHLIST_STATUS_FILE)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var status WatchlistStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &status, nil
+}
+
+// runWatchlist dispatches the watchlist subcommands
+func runWatchlist(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "status":
+			runWatchlistStatus(args[1:])
+			return
+		case "check":
+			runWatchlistCheck(args[1:])
+			return
+		}
+	}
+	fmt.Println("Usage: go run . watchlist status [-json]")
+	fmt.Println("       go run . watchlist check [-file FILE]")
+	fmt.Println("The watchlist runs in `daemon -watchlist FILE`.")
+	os.Exit(1)
+}
+
+// runWatchlistCheck validates a watchlist file without running it
+func runWatchlistCheck(args []string) {
+	fs := newCommandFlagSet("watchlist check")
+	path := fs.String("file", "", "Watchlist file (defaults to "+WATCHLIST_FILE+" in the state directory)")
+	fs.Parse(args)
+
+	if *path == "" {
+		defaultPath, err := defaultWatchlistPath()
+		if err != nil {
+			log.Fatal(err)
+		}
+		*path = defaultPath
+	}
+	watchlist, err := loadWatchlist(*path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s: %d tokens, %.2f%% slippage\n", *path, len(watchlist.Tokens), watchlist.Slippage)
+	for _, token := range watchlist.Tokens {
+		fmt.Printf("  %s %s: %v\n", token.Mint, token.Label, token.actions())
+	}
+}
+
+// runWatchlistStatus prints what the daemon last reported about each watched token
+func runWatchlistStatus(args []string) {
+	fs := newCommandFlagSet("watchlist status")
+	jsonOutput := fs.Bool("json", false, "Output the status as JSON")
+	fs.Parse(args)
+
+	status, err := readWatchlistStatus()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if status == nil {
+		log.Fatal("No watchlist status; start the daemon with -watchlist")
+	}
+	if *jsonOutput {
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode status: %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	fmt.Printf("Watchlist: %s (loaded %s)\n", status.Path, status.LoadedAt.Local().Format(time.DateTime))
+	age := time.Since(status.UpdatedAt)
+	fmt.Printf("Updated: %s ago\n", age.Round(time.Second))
+	if age > WATCHLIST_STATUS_STALE_AFTER {
+		fmt.Println("⚠️  Warning: the daemon has not reported recently; it may have stopped")
+	}
+	if status.ConfigError != "" {
+		fmt.Printf("⚠️  Config error, previous watchlist still running: %s\n", status.ConfigError)
+	}
+	if len(status.Tokens) == 0 {
+		fmt.Println("No watched tokens")
+		return
+	}
+	for _, token := range status.Tokens {
+		name := token.Mint
+		if token.Label != "" {
+			name = fmt.Sprintf("%s (%s)", token.Label, token.Mint)
+		}
+		fmt.Printf("\n%s\n", name)
+		fmt.Printf("  Pool: %s\n", token.Pool)
+		fmt.Printf("  Actions: %v\n", token.Actions)
+		if token.Price > 0 {
+			fmt.Printf("  Price: %.12f SOL\n", token.Price)
+		}
+		if token.DipReference > 0 {
+			fmt.Printf("  Dip Reference: %.12f SOL\n", token.DipReference)
+		}
+		if token.CostPrice > 0 {
+			fmt.Printf("  Position Cost: %.12f SOL per token\n", token.CostPrice)
+		}
+		if token.LastEvent != "" {
+			fmt.Printf("  Last Event: %s (%s)\n", token.LastEvent, token.LastEventAt.Local().Format(time.DateTime))
+		}
+		if token.Error != "" {
+			fmt.Printf("  ⚠️  Error: %s\n", token.Error)
+		}
+	}
+}